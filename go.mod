@@ -3,6 +3,8 @@ module github.com/openkruise/kruise-tools
 go 1.16
 
 require (
+	github.com/evanphx/json-patch v4.9.0+incompatible
+	github.com/google/cel-go v0.7.3
 	github.com/lithammer/dedent v1.1.0
 	github.com/moby/term v0.0.0-20200312100748-672ec06f55cd
 	github.com/openkruise/kruise-api v0.10.0
@@ -10,14 +12,20 @@ require (
 	github.com/spf13/cobra v1.1.3
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.6.1
+	golang.org/x/sys v0.0.0-20201112073958-5cba982894dd
+	google.golang.org/genproto v0.0.0-20201110150050-8816d57aaa9a
+	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776
 	k8s.io/api v0.21.6
+	k8s.io/apiextensions-apiserver v0.20.1
 	k8s.io/apimachinery v0.21.6
 	k8s.io/cli-runtime v0.21.6
 	k8s.io/client-go v0.21.6
 	k8s.io/component-base v0.21.6
 	k8s.io/klog/v2 v2.4.0
 	k8s.io/kubectl v0.21.6
+	k8s.io/utils v0.0.0-20210111153108-fddb29f9d009
 	sigs.k8s.io/controller-runtime v0.8.3
+	sigs.k8s.io/yaml v1.2.0
 )
 
 // Replace to match K8s 1.20.12
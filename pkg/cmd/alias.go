@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// expandAlias returns args, the command line without the binary name, with the alias named by
+// its first argument expanded, and false if it is not an alias. Root flags may be placed before
+// the alias name. The default flags of the alias are added unless the same flags are given.
+func expandAlias(root *cobra.Command, aliases map[string]config.Alias, args []string) ([]string, bool) {
+	flags, err := parseRootFlags(root, args)
+	if err != nil || flags.NArg() == 0 {
+		return args, false
+	}
+	alias, ok := aliases[flags.Arg(0)]
+	if !ok {
+		return args, false
+	}
+
+	leading := args[:len(args)-flags.NArg()]
+	rest := flags.Args()[1:]
+	// flags can not be added after "--"
+	var positional []string
+	for i, arg := range rest {
+		if arg == "--" {
+			rest, positional = rest[:i], rest[i:]
+			break
+		}
+	}
+
+	given := sets.NewString()
+	for _, arg := range rest {
+		if name := flagName(arg); len(name) > 0 {
+			given.Insert(name)
+		}
+	}
+	expanded := append(append([]string{}, leading...), alias.Command...)
+	expanded = append(expanded, rest...)
+	for _, flag := range alias.DefaultFlags {
+		if !given.Has(flagName(flag)) {
+			expanded = append(expanded, flag)
+		}
+	}
+	return append(expanded, positional...), true
+}
+
+// flagName returns the name of the flag in arg, e.g. "container" for "--container=app", or ""
+// if arg is not a flag.
+func flagName(arg string) string {
+	if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+		return ""
+	}
+	return strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alias
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/config"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	aliasLong = templates.LongDesc(`
+		Manage the aliases of kubectl-kruise commands.
+
+		Aliases are stored in the config file ~/.kube/kubectl-kruise.yaml, or the file set by
+		the KUBECTL_KRUISE_CONFIG environment variable. Running an alias runs its command with
+		the arguments given after the alias name, and with its default flags unless the same
+		flags are given.`)
+
+	aliasExample = templates.Examples(`
+		# Add the alias cs-image, so that 'kubectl-kruise cs-image demo app=nginx:1.21' runs
+		# 'kubectl-kruise set image cloneset demo app=nginx:1.21 --record'
+		kubectl-kruise alias add cs-image --default-flag=--record -- set image cloneset
+
+		# List the aliases
+		kubectl-kruise alias list
+
+		# Remove the alias cs-image
+		kubectl-kruise alias remove cs-image`)
+)
+
+// AliasOptions holds the command-line options for 'alias' sub commands
+type AliasOptions struct {
+	ConfigPath   string
+	Name         string
+	Command      []string
+	DefaultFlags []string
+	Overwrite    bool
+
+	// commandExists reports whether name is an existing command, which an alias can not shadow
+	commandExists func(name string) bool
+
+	genericclioptions.IOStreams
+}
+
+// NewAliasOptions returns an initialized AliasOptions instance
+func NewAliasOptions(streams genericclioptions.IOStreams) *AliasOptions {
+	return &AliasOptions{
+		ConfigPath: config.Path(),
+		IOStreams:  streams,
+	}
+}
+
+// NewCmdAlias returns a Command instance for 'alias' sub command
+func NewCmdAlias(streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "alias SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Manage the aliases of kubectl-kruise commands"),
+		Long:                  aliasLong,
+		Example:               aliasExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(newCmdAliasList(streams))
+	cmd.AddCommand(newCmdAliasAdd(streams))
+	cmd.AddCommand(newCmdAliasRemove(streams))
+	return cmd
+}
+
+func newCmdAliasList(streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewAliasOptions(streams)
+	return &cobra.Command{
+		Use:                   "list",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("List the aliases"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.RunList())
+		},
+	}
+}
+
+func newCmdAliasAdd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewAliasOptions(streams)
+	cmd := &cobra.Command{
+		Use:                   "add NAME [--default-flag=FLAG]... [--overwrite] -- COMMAND [ARG]...",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Add an alias"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.CompleteAdd(cmd, args))
+			cmdutil.CheckErr(o.RunAdd())
+		},
+	}
+	cmd.Flags().StringArrayVar(&o.DefaultFlags, "default-flag", o.DefaultFlags, "A flag added to the command unless it is given, in the --name=value form. May be repeated.")
+	cmd.Flags().BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "If true, replace an existing alias of the same name.")
+	return cmd
+}
+
+func newCmdAliasRemove(streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewAliasOptions(streams)
+	return &cobra.Command{
+		Use:                   "remove NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Remove an alias"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.CompleteRemove(args))
+			cmdutil.CheckErr(o.RunRemove())
+		},
+	}
+}
+
+// CompleteAdd completes the options of 'alias add'
+func (o *AliasOptions) CompleteAdd(cmd *cobra.Command, args []string) error {
+	if len(args) < 2 {
+		return cmdutil.UsageErrorf(cmd, "an alias name and a command are required")
+	}
+	o.Name, o.Command = args[0], args[1:]
+	o.commandExists = func(name string) bool {
+		found, _, err := cmd.Root().Find([]string{name})
+		return err == nil && found != cmd.Root()
+	}
+	for _, flag := range o.DefaultFlags {
+		if !strings.HasPrefix(flag, "-") {
+			return fmt.Errorf("invalid default flag %q, must start with -", flag)
+		}
+	}
+	return nil
+}
+
+// RunAdd adds the alias to the config file
+func (o *AliasOptions) RunAdd() error {
+	if strings.HasPrefix(o.Name, "-") || strings.ContainsAny(o.Name, " \t/") {
+		return fmt.Errorf("invalid alias name %q", o.Name)
+	}
+	if o.commandExists != nil && o.commandExists(o.Name) {
+		return fmt.Errorf("%q is a kubectl-kruise command and can not be used as an alias", o.Name)
+	}
+
+	cfg, err := config.Load(o.ConfigPath)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Aliases[o.Name]; ok && !o.Overwrite {
+		return fmt.Errorf("alias %q already exists, use --overwrite to replace it", o.Name)
+	}
+	if cfg.Aliases == nil {
+		cfg.Aliases = map[string]config.Alias{}
+	}
+	cfg.Aliases[o.Name] = config.Alias{Command: o.Command, DefaultFlags: o.DefaultFlags}
+	if err := config.Save(o.ConfigPath, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "alias %q added\n", o.Name)
+	return nil
+}
+
+// CompleteRemove completes the options of 'alias remove'
+func (o *AliasOptions) CompleteRemove(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("exactly one alias name is required")
+	}
+	o.Name = args[0]
+	return nil
+}
+
+// RunRemove removes the alias from the config file
+func (o *AliasOptions) RunRemove() error {
+	cfg, err := config.Load(o.ConfigPath)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Aliases[o.Name]; !ok {
+		return fmt.Errorf("alias %q not found", o.Name)
+	}
+	delete(cfg.Aliases, o.Name)
+	if err := config.Save(o.ConfigPath, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "alias %q removed\n", o.Name)
+	return nil
+}
+
+// RunList prints the aliases in the config file
+func (o *AliasOptions) RunList() error {
+	cfg, err := config.Load(o.ConfigPath)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Aliases) == 0 {
+		fmt.Fprintf(o.Out, "No aliases found in %s\n", o.ConfigPath)
+		return nil
+	}
+
+	var names []string
+	for name := range cfg.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := internalcmdutil.NewTableWriter(o.Out)
+	fmt.Fprintln(w, "NAME\tCOMMAND\tDEFAULT FLAGS")
+	for _, name := range names {
+		alias := cfg.Aliases[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, strings.Join(alias.Command, " "), strings.Join(alias.DefaultFlags, " "))
+	}
+	return w.Flush()
+}
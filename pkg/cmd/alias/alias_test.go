@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alias
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestAddListRemove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alias")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kubectl-kruise.yaml")
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewAliasOptions(streams)
+	o.ConfigPath = path
+	o.Name = "cs-image"
+	o.Command = []string{"set", "image", "cloneset"}
+	o.DefaultFlags = []string{"--record"}
+	o.commandExists = func(name string) bool { return name == "set" }
+	if err := o.RunAdd(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.RunAdd(); err == nil {
+		t.Errorf("expected an error adding an existing alias without --overwrite")
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := config.Alias{Command: []string{"set", "image", "cloneset"}, DefaultFlags: []string{"--record"}}
+	if !reflect.DeepEqual(cfg.Aliases["cs-image"], expected) {
+		t.Errorf("expected alias %+v, got %+v", expected, cfg.Aliases["cs-image"])
+	}
+
+	out.Reset()
+	if err := o.RunList(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "cs-image   set image cloneset   --record") {
+		t.Errorf("unexpected list output:\n%s", out.String())
+	}
+
+	o.Name = "set"
+	if err := o.RunAdd(); err == nil {
+		t.Errorf("expected an error adding an alias that shadows a command")
+	}
+
+	o.Name = "cs-image"
+	if err := o.RunRemove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.RunRemove(); err == nil {
+		t.Errorf("expected an error removing a missing alias")
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func TestExpandAlias(t *testing.T) {
+	root := &cobra.Command{Use: "kubectl-kruise"}
+	root.PersistentFlags().StringP("namespace", "n", "", "")
+	aliases := map[string]config.Alias{
+		"cs-image": {Command: []string{"set", "image", "cloneset"}, DefaultFlags: []string{"--record", "--all=false"}},
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+		alias    bool
+	}{
+		{
+			name:     "with default flags",
+			args:     []string{"cs-image", "demo", "app=nginx:1.21"},
+			expected: []string{"set", "image", "cloneset", "demo", "app=nginx:1.21", "--record", "--all=false"},
+			alias:    true,
+		},
+		{
+			name:     "given flags override default flags",
+			args:     []string{"-n", "prod", "cs-image", "demo", "--all=true"},
+			expected: []string{"-n", "prod", "set", "image", "cloneset", "demo", "--all=true", "--record"},
+			alias:    true,
+		},
+		{
+			name:     "default flags before --",
+			args:     []string{"cs-image", "demo", "--", "--not-a-flag"},
+			expected: []string{"set", "image", "cloneset", "demo", "--record", "--all=false", "--", "--not-a-flag"},
+			alias:    true,
+		},
+		{
+			name:     "not an alias",
+			args:     []string{"unknown", "demo"},
+			expected: []string{"unknown", "demo"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expanded, ok := expandAlias(root, aliases, test.args)
+			if ok != test.alias || !reflect.DeepEqual(expanded, test.expected) {
+				t.Errorf("expected %v %v, got %v %v", test.expected, test.alias, expanded, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apidocs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/component-base/version"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	rootName = "kubectl-kruise"
+
+	// FormatJSONSchema prints the commands as JSON Schema definitions
+	FormatJSONSchema = "jsonschema"
+	// FormatOpenAPI prints the commands as the operations of an OpenAPI 3 document
+	FormatOpenAPI = "openapi"
+
+	globalFlagsName = "globalFlags"
+)
+
+var (
+	apiDocsLong = templates.LongDesc(`
+		Print a machine-readable description of the commands of kubectl-kruise.
+
+		Each runnable command is described by the schema of its invocations: its positional
+		arguments and its flags, with their types, defaults and descriptions. The global flags
+		are described once. With --format jsonschema the commands are JSON Schema definitions;
+		with --format openapi each command is an OpenAPI 3 operation whose request body is its
+		invocation and whose response is what it prints, so that wrappers can be generated
+		from the description and kept in sync with kubectl-kruise.`)
+
+	apiDocsExample = templates.Examples(`
+		# Print the JSON Schema of the commands
+		kubectl-kruise api-docs
+
+		# Print an OpenAPI 3 document of the commands
+		kubectl-kruise api-docs --format openapi`)
+)
+
+// APIDocsOptions holds the command-line options for 'api-docs' command
+type APIDocsOptions struct {
+	Format  string
+	Version string
+
+	Root *cobra.Command
+
+	genericclioptions.IOStreams
+}
+
+// NewAPIDocsOptions returns an initialized APIDocsOptions instance
+func NewAPIDocsOptions(streams genericclioptions.IOStreams) *APIDocsOptions {
+	return &APIDocsOptions{
+		Format:    FormatJSONSchema,
+		IOStreams: streams,
+	}
+}
+
+// NewCmdAPIDocs returns a Command instance for 'api-docs' command
+func NewCmdAPIDocs(streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewAPIDocsOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "api-docs [--format=jsonschema|openapi]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print a machine-readable description of the commands"),
+		Long:                  apiDocsLong,
+		Example:               apiDocsExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVar(&o.Format, "format", o.Format, "The format of the description, one of: jsonschema, openapi.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *APIDocsOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args)
+	}
+	o.Root = cmd.Root()
+	o.Version = version.Get().GitVersion
+	return nil
+}
+
+// Validate makes sure the format is supported
+func (o *APIDocsOptions) Validate() error {
+	if o.Format != FormatJSONSchema && o.Format != FormatOpenAPI {
+		return fmt.Errorf("invalid format %q, must be one of: %s, %s", o.Format, FormatJSONSchema, FormatOpenAPI)
+	}
+	return nil
+}
+
+// Run prints the description of the commands of the root command
+func (o *APIDocsOptions) Run() error {
+	commands, global := Commands(o.Root)
+	var doc interface{}
+	if o.Format == FormatOpenAPI {
+		doc = OpenAPI(commands, global, o.Version)
+	} else {
+		doc = JSONSchema(commands, global)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(o.Out, string(data))
+	return err
+}
+
+// JSONSchema returns a JSON Schema document with a definition for each command, and one for
+// the global flags.
+func JSONSchema(commands []Command, global *Schema) map[string]interface{} {
+	definitions := map[string]*Schema{globalFlagsName: global}
+	for _, c := range commands {
+		definitions[c.Name()] = c.Schema()
+	}
+	return map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       rootName,
+		"definitions": definitions,
+	}
+}
+
+// OpenAPI returns an OpenAPI 3 document with an operation for each command, at the path of
+// its command line, e.g. /rollout/approve.
+func OpenAPI(commands []Command, global *Schema, version string) map[string]interface{} {
+	schemas := map[string]*Schema{globalFlagsName: global}
+	paths := map[string]interface{}{}
+	for _, c := range commands {
+		schemas[c.Name()] = c.Schema()
+		paths["/"+strings.Join(c.Path, "/")] = map[string]interface{}{
+			"post": map[string]interface{}{
+				"operationId": c.Name(),
+				"summary":     c.Short,
+				"description": c.Long,
+				"requestBody": map[string]interface{}{
+					"required": true,
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": &Schema{Ref: "#/components/schemas/" + c.Name()},
+						},
+					},
+				},
+				"responses": responsesFor(c),
+			},
+		}
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       rootName,
+			"description": "The commands of " + rootName + ", the global flags are described by the " + globalFlagsName + " schema.",
+			"version":     version,
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}
+}
+
+// responsesFor returns the responses of the operation of a command: the printed objects for
+// commands that support --output, and text otherwise.
+func responsesFor(c Command) map[string]interface{} {
+	content := map[string]interface{}{
+		"text/plain": map[string]interface{}{"schema": &Schema{Type: "string"}},
+	}
+	if c.Printer {
+		content["application/json"] = map[string]interface{}{
+			"schema": &Schema{
+				Type:        "object",
+				Description: "The printed Kubernetes object, or a List of them, with --output=json.",
+			},
+		}
+	}
+	return map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": "The command succeeded and exited with code 0.",
+			"content":     content,
+		},
+		"default": map[string]interface{}{
+			"description": "The command failed, the error is printed to the standard error.",
+		},
+	}
+}
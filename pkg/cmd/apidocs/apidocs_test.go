@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apidocs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func newTestRoot() *cobra.Command {
+	run := func(cmd *cobra.Command, args []string) {}
+	root := &cobra.Command{Use: rootName}
+	root.PersistentFlags().StringP("namespace", "n", "", "The namespace.")
+
+	rollout := &cobra.Command{Use: "rollout SUBCOMMAND", Run: run}
+	schedule := &cobra.Command{Use: "schedule RESOURCE", Short: "Schedule a rollout", Run: run}
+	schedule.Flags().StringSlice("steps", []string{"100%"}, "The steps.")
+	schedule.Flags().Duration("step-interval", time.Minute, "The step interval.")
+	schedule.Flags().Bool("follow", false, "Follow.")
+	schedule.Flags().Int32("windows", 0, "The windows.")
+	schedule.Flags().StringP("output", "o", "", "The output format.")
+	rollout.AddCommand(schedule)
+	rollout.AddCommand(&cobra.Command{Use: "hidden", Hidden: true, Run: run})
+
+	scale := &cobra.Command{Use: "scale RESOURCE", Run: run}
+	scale.Flags().Int("replicas", 1, "The replicas.")
+
+	root.AddCommand(rollout, scale)
+	return root
+}
+
+func TestCommands(t *testing.T) {
+	commands, global := Commands(newTestRoot())
+
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.Name())
+	}
+	if expected := []string{"rollout.schedule", "scale"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected commands %v, got %v", expected, names)
+	}
+	if _, ok := global.Properties["namespace"]; !ok || len(global.Properties) != 1 {
+		t.Errorf("expected the namespace global flag, got %v", global.Properties)
+	}
+
+	schedule := commands[0]
+	if !schedule.Printer {
+		t.Errorf("expected rollout schedule to print objects")
+	}
+	expected := map[string]*Schema{
+		"steps":         {Description: "The steps.", Type: "array", Items: &Schema{Type: "string"}, Default: []string{"100%"}},
+		"step-interval": {Description: "The step interval.", Type: "string", Format: "duration", Default: "1m0s"},
+		"follow":        {Description: "Follow.", Type: "boolean"},
+		"windows":       {Description: "The windows.", Type: "integer"},
+		"output":        {Description: "The output format.", Type: "string", XShorthand: "o"},
+	}
+	if !reflect.DeepEqual(schedule.Flags.Properties, expected) {
+		got, _ := json.Marshal(schedule.Flags.Properties)
+		t.Errorf("unexpected flags of rollout schedule: %s", got)
+	}
+	if scale := commands[1]; scale.Printer || scale.Flags.Properties["replicas"].Default != int64(1) {
+		t.Errorf("unexpected scale command %+v", scale)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	commands, global := Commands(newTestRoot())
+	doc := OpenAPI(commands, global, "v1.0.0")
+
+	paths := doc["paths"].(map[string]interface{})
+	if len(paths) != 2 || paths["/rollout/schedule"] == nil || paths["/scale"] == nil {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]*Schema)
+	for _, name := range []string{globalFlagsName, "rollout.schedule", "scale"} {
+		if schemas[name] == nil {
+			t.Errorf("expected schema %s", name)
+		}
+	}
+	if _, err := json.Marshal(doc); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	o := NewAPIDocsOptions(genericclioptions.NewTestIOStreamsDiscard())
+	if err := o.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	o.Format = "yaml"
+	if err := o.Validate(); err == nil {
+		t.Errorf("expected an error for format yaml")
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apidocs
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Schema is the subset of JSON Schema, shared with OpenAPI 3, that describes commands.
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Default     interface{}        `json:"default,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	// XShorthand is the one-letter shorthand of a flag
+	XShorthand string `json:"x-shorthand,omitempty"`
+}
+
+// Command describes a runnable command.
+type Command struct {
+	// Path is the command line of the command after kubectl-kruise, e.g. [rollout approve].
+	Path []string
+	Use  string
+	// Short and Long are the descriptions of the command.
+	Short string
+	Long  string
+	// Flags are the flags of the command, without the global ones.
+	Flags *Schema
+	// Printer is true if the command prints objects in the format given by --output.
+	Printer bool
+}
+
+// Name returns the name the schema of the command is defined with, e.g. rollout.approve.
+func (c Command) Name() string {
+	return strings.Join(c.Path, ".")
+}
+
+// Schema returns the schema of the invocations of the command.
+func (c Command) Schema() *Schema {
+	return &Schema{
+		Title:       strings.Join(append([]string{rootName}, c.Path...), " "),
+		Description: c.Short,
+		Type:        "object",
+		Properties: map[string]*Schema{
+			"args": {
+				Description: "The positional arguments: " + c.Use,
+				Type:        "array",
+				Items:       &Schema{Type: "string"},
+			},
+			"flags": c.Flags,
+		},
+	}
+}
+
+// Commands returns the runnable commands under root, sorted by path, and the schema of the
+// global flags of root. Groups of sub commands without flags of their own, and hidden,
+// deprecated, help and completion commands are left out.
+func Commands(root *cobra.Command) ([]Command, *Schema) {
+	var commands []Command
+	var walk func(cmd *cobra.Command, path []string)
+	walk = func(cmd *cobra.Command, path []string) {
+		for _, child := range cmd.Commands() {
+			if child.Hidden || len(child.Deprecated) > 0 || child.Name() == "help" || child.Name() == "completion" || strings.HasPrefix(child.Name(), "__") {
+				continue
+			}
+			childPath := append(append([]string{}, path...), child.Name())
+			if child.Runnable() && (!child.HasAvailableSubCommands() || child.HasAvailableLocalFlags()) {
+				commands = append(commands, commandFor(root, child, childPath))
+			}
+			walk(child, childPath)
+		}
+	}
+	walk(root, nil)
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name() < commands[j].Name() })
+	return commands, flagsSchema(root.PersistentFlags(), nil)
+}
+
+func commandFor(root, cmd *cobra.Command, path []string) Command {
+	global := root.PersistentFlags()
+	flags := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(cmd.LocalFlags())
+	flags.AddFlagSet(cmd.InheritedFlags())
+	return Command{
+		Path:    path,
+		Use:     cmd.Use,
+		Short:   cmd.Short,
+		Long:    cmd.Long,
+		Flags:   flagsSchema(flags, global),
+		Printer: flags.Lookup("output") != nil && global.Lookup("output") == nil,
+	}
+}
+
+// flagsSchema returns the schema of an object with a property for each flag of flags that is not
+// in exclude, named after the flag.
+func flagsSchema(flags, exclude *pflag.FlagSet) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || len(flag.Deprecated) > 0 || flag.Name == "help" {
+			return
+		}
+		if exclude != nil && exclude.Lookup(flag.Name) != nil {
+			return
+		}
+		schema.Properties[flag.Name] = flagSchema(flag)
+	})
+	return schema
+}
+
+// flagSchema returns the schema of the value of a flag, according to its type.
+func flagSchema(flag *pflag.Flag) *Schema {
+	schema := &Schema{Description: flag.Usage, XShorthand: flag.Shorthand}
+	typ := flag.Value.Type()
+	switch {
+	case typ == "bool":
+		schema.Type = "boolean"
+		if value, err := strconv.ParseBool(flag.DefValue); err == nil && value {
+			schema.Default = value
+		}
+	case strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint"):
+		if strings.HasSuffix(typ, "Slice") {
+			schema.Type, schema.Items = "array", &Schema{Type: "integer"}
+			break
+		}
+		schema.Type = "integer"
+		if value, err := strconv.ParseInt(flag.DefValue, 10, 64); err == nil && value != 0 {
+			schema.Default = value
+		}
+	case strings.HasPrefix(typ, "float"):
+		schema.Type = "number"
+		if value, err := strconv.ParseFloat(flag.DefValue, 64); err == nil && value != 0 {
+			schema.Default = value
+		}
+	case strings.HasSuffix(typ, "Slice") || strings.HasSuffix(typ, "Array"):
+		schema.Type, schema.Items = "array", &Schema{Type: "string"}
+		if values := strings.Trim(flag.DefValue, "[]"); len(values) > 0 {
+			schema.Default = strings.Split(values, ",")
+		}
+	case typ == "duration":
+		schema.Type, schema.Format = "string", "duration"
+		if flag.DefValue != "0s" {
+			schema.Default = flag.DefValue
+		}
+	default:
+		schema.Type = "string"
+		if len(flag.DefValue) > 0 {
+			schema.Default = flag.DefValue
+		}
+	}
+	return schema
+}
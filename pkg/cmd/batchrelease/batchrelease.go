@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batchrelease
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// batchReleaseResource is the resource of BatchReleases, qualified so that it is not mistaken
+// for another resource of the same name
+const batchReleaseResource = "batchreleases.rollouts.kruise.io"
+
+var (
+	batchReleaseLong = templates.LongDesc(`
+		Inspect and drive BatchReleases, which release the new revision of a workload in
+		batches without a Rollout.`)
+
+	batchReleaseExample = templates.Examples(`
+		# Show the batches of batchrelease demo
+		kubectl-kruise batchrelease status demo
+
+		# Release the next batch of batchrelease demo
+		kubectl-kruise batchrelease promote-batch demo`)
+)
+
+// NewCmdBatchRelease returns a Command instance for 'batchrelease' sub command
+func NewCmdBatchRelease(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "batchrelease SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Inspect and drive BatchReleases"),
+		Long:                  batchReleaseLong,
+		Example:               batchReleaseExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(NewCmdBatchReleaseStatus(f, streams))
+	cmd.AddCommand(NewCmdBatchReleasePromoteBatch(f, streams))
+	return cmd
+}
+
+// Batch is the state of one batch of a BatchRelease
+type Batch struct {
+	// CanaryReplicas is the planned number or percentage of canary pods
+	CanaryReplicas intstr.IntOrString
+	// Expected is the number of canary pods once the batch is released, including those of
+	// the batches before
+	Expected     int32
+	PauseSeconds int64
+	State        string
+	// Updated and Ready count the pods of the batch. The updated pods are assigned to the
+	// batches in the order they were created.
+	Updated int32
+	Ready   int32
+}
+
+// Batches returns the state of the batches of release, whose workload has replicas pods, of
+// which updated are of the update revision.
+func Batches(release *kruiserolloutsv1apha1.BatchRelease, replicas int32, updated []corev1.Pod) []Batch {
+	sort.SliceStable(updated, func(i, j int) bool {
+		if !updated[i].CreationTimestamp.Equal(&updated[j].CreationTimestamp) {
+			return updated[i].CreationTimestamp.Before(&updated[j].CreationTimestamp)
+		}
+		return updated[i].Name < updated[j].Name
+	})
+
+	plan := release.Spec.ReleasePlan
+	current := int(release.Status.CanaryStatus.CurrentBatch)
+	batches := make([]Batch, 0, len(plan.Batches))
+	var previous int32
+	for i, planned := range plan.Batches {
+		batch := Batch{CanaryReplicas: planned.CanaryReplicas, PauseSeconds: planned.PauseSeconds}
+
+		expected, _ := intstr.GetValueFromIntOrPercent(&planned.CanaryReplicas, int(replicas), true)
+		// the percentage of the last batch is ignored, it releases the rest
+		if i == len(plan.Batches)-1 && planned.CanaryReplicas.Type == intstr.String {
+			expected = int(replicas)
+		}
+		batch.Expected = int32(expected)
+		if batch.Expected > replicas {
+			batch.Expected = replicas
+		}
+
+		switch {
+		case i < current:
+			batch.State = "Released"
+		case i == current && len(release.Status.CanaryStatus.ReleasingBatchState) > 0:
+			batch.State = string(release.Status.CanaryStatus.ReleasingBatchState)
+		case i == current:
+			batch.State = "Releasing"
+		case plan.BatchPartition != nil && i > int(*plan.BatchPartition):
+			batch.State = "WaitingForPromotion"
+		default:
+			batch.State = "Pending"
+		}
+
+		for j := previous; j < batch.Expected && int(j) < len(updated); j++ {
+			batch.Updated++
+			if isPodReady(&updated[j]) {
+				batch.Ready++
+			}
+		}
+		if batch.Expected > previous {
+			previous = batch.Expected
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// IsUpdatedPod returns true if pod is of revision, by the revision labels of CloneSets and
+// Deployments.
+func IsUpdatedPod(pod *corev1.Pod, revision string) bool {
+	if len(revision) == 0 {
+		return false
+	}
+	for _, label := range []string{"controller-revision-hash", "pod-template-hash"} {
+		if value := pod.Labels[label]; value == revision || strings.HasSuffix(value, "-"+revision) {
+			return true
+		}
+	}
+	return false
+}
+
+// workloadResource returns the resource of the target of release, e.g. cloneset.v1alpha1.apps.kruise.io.
+func workloadResource(release *kruiserolloutsv1apha1.BatchRelease) (string, string, error) {
+	ref := release.Spec.TargetRef.WorkloadRef
+	if ref == nil {
+		return "", "", fmt.Errorf("batchrelease %s has no workload reference", release.Name)
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return "", "", err
+	}
+	resource := strings.ToLower(ref.Kind)
+	if len(gv.Group) > 0 {
+		resource = fmt.Sprintf("%s.%s.%s", resource, gv.Version, gv.Group)
+	}
+	return resource, ref.Name, nil
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batchrelease
+
+import (
+	"fmt"
+
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	promoteBatchLong = templates.LongDesc(`
+		Release the next batch of a BatchRelease.
+
+		The batch partition of the release plan is raised by one, so that the controller
+		releases the next batch. The current batch must be ready, unless --force is given.`)
+
+	promoteBatchExample = templates.Examples(`
+		# Release the next batch of batchrelease demo
+		kubectl-kruise batchrelease promote-batch demo
+
+		# Release the next batch of batchrelease demo, although the current one is not ready
+		kubectl-kruise batchrelease promote-batch demo --force`)
+)
+
+// PromoteBatchOptions holds the command-line options for 'batchrelease promote-batch' sub command
+type PromoteBatchOptions struct {
+	Name             string
+	Namespace        string
+	EnforceNamespace bool
+	Force            bool
+
+	Builder func() *resource.Builder
+
+	genericclioptions.IOStreams
+}
+
+// NewPromoteBatchOptions returns an initialized PromoteBatchOptions instance
+func NewPromoteBatchOptions(streams genericclioptions.IOStreams) *PromoteBatchOptions {
+	return &PromoteBatchOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdBatchReleasePromoteBatch returns a Command instance for 'batchrelease promote-batch' sub command
+func NewCmdBatchReleasePromoteBatch(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewPromoteBatchOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "promote-batch NAME [--force]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Release the next batch of a BatchRelease"),
+		Long:                  promoteBatchLong,
+		Example:               promoteBatchExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().BoolVar(&o.Force, "force", o.Force, "If true, promote even if the current batch is not ready.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *PromoteBatchOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmdutil.UsageErrorf(cmd, "exactly one BatchRelease name is required")
+	}
+	o.Name = args[0]
+
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Builder = f.NewBuilder
+	return nil
+}
+
+// Run raises the batch partition of the BatchRelease
+func (o *PromoteBatchOptions) Run() error {
+	info, release, err := getBatchRelease(o.Builder, o.Namespace, o.Name)
+	if err != nil {
+		return err
+	}
+	partition, err := polymorphichelpers.NextBatchPartition(release, o.Force)
+	if err != nil {
+		return err
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"releasePlan":{"batchPartition":%d}}}`, partition)
+	if _, err := resource.NewHelper(info.Client, info.Mapping).Patch(info.Namespace, info.Name, types.MergePatchType, []byte(patch), nil); err != nil {
+		return fmt.Errorf("cannot promote batchrelease %s: %v", o.Name, err)
+	}
+	fmt.Fprintf(o.Out, "batchrelease.rollouts.kruise.io/%s promoted to batch %d of %d\n", o.Name, partition+1, len(release.Spec.ReleasePlan.Batches))
+	if release.Spec.ReleasePlan.Paused {
+		fmt.Fprintf(o.ErrOut, "Warning: batchrelease %s is paused, the batch is released when it is resumed\n", o.Name)
+	}
+	return nil
+}
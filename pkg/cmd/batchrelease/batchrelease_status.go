@@ -0,0 +1,222 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batchrelease
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	statusLong = templates.LongDesc(`
+		Show the release plan of a BatchRelease and the progress of each batch.
+
+		The updated pods are the pods of the update revision, assigned to the batches in the
+		order they were created, to tell which batch is not ready.
+
+		With -o, the BatchRelease is printed in the given format instead.`)
+
+	statusExample = templates.Examples(`
+		# Show the batches of batchrelease demo
+		kubectl-kruise batchrelease status demo
+
+		# Print the status of batchrelease demo as yaml
+		kubectl-kruise batchrelease status demo -o yaml`)
+)
+
+// StatusOptions holds the command-line options for 'batchrelease status' sub command
+type StatusOptions struct {
+	PrintFlags *genericclioptions.PrintFlags
+
+	Name             string
+	Namespace        string
+	EnforceNamespace bool
+
+	Builder func() *resource.Builder
+	Client  kubernetes.Interface
+
+	genericclioptions.IOStreams
+}
+
+// NewStatusOptions returns an initialized StatusOptions instance
+func NewStatusOptions(streams genericclioptions.IOStreams) *StatusOptions {
+	return &StatusOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithTypeSetter(internalapi.GetScheme()),
+		IOStreams:  streams,
+	}
+}
+
+// NewCmdBatchReleaseStatus returns a Command instance for 'batchrelease status' sub command
+func NewCmdBatchReleaseStatus(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewStatusOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "status NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show the batches of a BatchRelease"),
+		Long:                  statusLong,
+		Example:               statusExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	o.PrintFlags.AddFlags(cmd)
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *StatusOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmdutil.UsageErrorf(cmd, "exactly one BatchRelease name is required")
+	}
+	o.Name = args[0]
+
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Builder = f.NewBuilder
+	o.Client, err = f.KubernetesClientSet()
+	return err
+}
+
+// Run prints the status of the BatchRelease
+func (o *StatusOptions) Run() error {
+	_, release, err := getBatchRelease(o.Builder, o.Namespace, o.Name)
+	if err != nil {
+		return err
+	}
+	if o.PrintFlags.OutputFormat != nil && len(*o.PrintFlags.OutputFormat) > 0 {
+		printer, err := o.PrintFlags.ToPrinter()
+		if err != nil {
+			return err
+		}
+		return printer.PrintObj(release, o.Out)
+	}
+
+	resourceName, name, err := workloadResource(release)
+	if err != nil {
+		return err
+	}
+	workload, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(release.Namespace).
+		ResourceNames(resourceName, name).
+		Latest().
+		Do().Object()
+	if err != nil {
+		return err
+	}
+	pods, err := polymorphichelpers.PodsForObject(o.Client.CoreV1(), workload)
+	if err != nil {
+		return err
+	}
+
+	var updated []corev1.Pod
+	for i := range pods {
+		if IsUpdatedPod(&pods[i], release.Status.UpdateRevision) {
+			updated = append(updated, pods[i])
+		}
+	}
+	replicas := release.Status.ObservedWorkloadReplicas
+	if replicas == 0 {
+		replicas = int32(len(pods))
+	}
+
+	return printStatus(o.Out, release, replicas, pods, updated)
+}
+
+func printStatus(out io.Writer, release *kruiserolloutsv1apha1.BatchRelease, replicas int32, pods, updated []corev1.Pod) error {
+	plan := release.Spec.ReleasePlan
+	status := release.Status
+	ready := 0
+	for i := range updated {
+		if isPodReady(&updated[i]) {
+			ready++
+		}
+	}
+
+	w := internalcmdutil.NewTableWriter(out)
+	ref := release.Spec.TargetRef.WorkloadRef
+	fmt.Fprintf(w, "Name:\t%s\n", release.Name)
+	if ref != nil {
+		fmt.Fprintf(w, "Target:\t%s/%s\n", ref.Kind, ref.Name)
+	}
+	fmt.Fprintf(w, "Phase:\t%s\n", valueOrNone(string(status.Phase)))
+	if len(plan.Batches) > 0 {
+		fmt.Fprintf(w, "Batch:\t%d of %d (%s)\n", status.CanaryStatus.CurrentBatch+1, len(plan.Batches), valueOrNone(string(status.CanaryStatus.ReleasingBatchState)))
+	}
+	if plan.BatchPartition != nil {
+		fmt.Fprintf(w, "Batch Partition:\t%d\n", *plan.BatchPartition+1)
+	}
+	fmt.Fprintf(w, "Paused:\t%t\n", plan.Paused)
+	fmt.Fprintf(w, "Revisions:\tstable %s, update %s\n", valueOrNone(status.StableRevision), valueOrNone(status.UpdateRevision))
+	fmt.Fprintf(w, "Replicas:\t%d, %d pods, %d updated, %d updated ready\n", replicas, len(pods), len(updated), ready)
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "BATCH\tCANARY REPLICAS\tEXPECTED\tPAUSE\tSTATE\tUPDATED\tREADY")
+	for i, batch := range Batches(release, replicas, updated) {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%d\t%d\n", i+1, batch.CanaryReplicas.String(), batch.Expected,
+			time.Duration(batch.PauseSeconds)*time.Second, batch.State, batch.Updated, batch.Ready)
+	}
+	return w.Flush()
+}
+
+// getBatchRelease returns the BatchRelease name in namespace.
+func getBatchRelease(builder func() *resource.Builder, namespace, name string) (*resource.Info, *kruiserolloutsv1apha1.BatchRelease, error) {
+	infos, err := builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(namespace).DefaultNamespace().
+		ResourceNames(batchReleaseResource, name).
+		Latest().
+		Do().Infos()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(infos) != 1 {
+		return nil, nil, fmt.Errorf("expected one batchrelease %s, got %d", name, len(infos))
+	}
+	release, ok := infos[0].Object.(*kruiserolloutsv1apha1.BatchRelease)
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected object %T of batchrelease %s", infos[0].Object, name)
+	}
+	return infos[0], release, nil
+}
+
+func valueOrNone(value string) string {
+	if len(value) == 0 {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batchrelease
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func newBatchRelease(currentBatch int32, state kruiserolloutsv1apha1.ReleasingBatchStateType, partition *int32) *kruiserolloutsv1apha1.BatchRelease {
+	release := &kruiserolloutsv1apha1.BatchRelease{ObjectMeta: metav1.ObjectMeta{Name: "demo"}}
+	release.Spec.ReleasePlan = kruiserolloutsv1apha1.ReleasePlan{
+		Batches: []kruiserolloutsv1apha1.ReleaseBatch{
+			{CanaryReplicas: intstr.FromInt(1)},
+			{CanaryReplicas: intstr.FromString("50%")},
+			{CanaryReplicas: intstr.FromString("90%")},
+		},
+		BatchPartition: partition,
+	}
+	release.Status.UpdateRevision = "abc"
+	release.Status.CanaryStatus = kruiserolloutsv1apha1.BatchReleaseCanaryStatus{CurrentBatch: currentBatch, ReleasingBatchState: state}
+	return release
+}
+
+func newPod(name string, created int, ready bool) corev1.Pod {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:              name,
+		CreationTimestamp: metav1.NewTime(time.Unix(int64(created), 0)),
+		Labels:            map[string]string{"controller-revision-hash": "demo-abc"},
+	}}
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}
+	return pod
+}
+
+func TestBatches(t *testing.T) {
+	release := newBatchRelease(1, kruiserolloutsv1apha1.VerifyBatchState, int32Ptr(1))
+	updated := []corev1.Pod{newPod("c", 3, false), newPod("a", 1, true), newPod("b", 2, true)}
+
+	var got []string
+	for _, batch := range Batches(release, 4, updated) {
+		got = append(got, fmt.Sprintf("%s %d %s %d/%d", batch.CanaryReplicas.String(), batch.Expected, batch.State, batch.Ready, batch.Updated))
+	}
+	expected := []string{
+		"1 1 Released 1/1",
+		"50% 2 VerifyInBatch 1/1",
+		// the percentage of the last batch is ignored
+		"90% 4 WaitingForPromotion 0/1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestIsUpdatedPod(t *testing.T) {
+	pod := newPod("a", 1, true)
+	if !IsUpdatedPod(&pod, "abc") || IsUpdatedPod(&pod, "bc") || IsUpdatedPod(&pod, "") {
+		t.Errorf("unexpected revision match of %v", pod.Labels)
+	}
+	pod.Labels = map[string]string{"pod-template-hash": "abc"}
+	if !IsUpdatedPod(&pod, "abc") {
+		t.Errorf("expected pod-template-hash to match")
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package check
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	checkLong = templates.LongDesc(`
+		Check workloads for the patterns that break the Kruise features before enabling them,
+		and nodes for the kruise-daemon running them.`)
+
+	checkExample = templates.Examples(`
+		# Check that the pods of deployment web can be updated in place
+		kubectl-kruise check conformance deployment/web
+
+		# Check the kruise-daemon of node worker-1
+		kubectl-kruise check daemon worker-1`)
+)
+
+// NewCmdCheck returns a Command instance for 'check' command
+func NewCmdCheck(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "check SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Check workloads before enabling Kruise features"),
+		Long:                  checkLong,
+		Example:               checkExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(NewCmdCheckConformance(f, streams))
+	cmd.AddCommand(NewCmdCheckDaemon(f, streams))
+	return cmd
+}
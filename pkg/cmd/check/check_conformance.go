@@ -0,0 +1,422 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package check
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	kruiseappspub "github.com/openkruise/kruise-api/apps/pub"
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// Severity is how likely a finding breaks in-place updates or hot upgrades
+type Severity string
+
+const (
+	SeverityHigh   Severity = "high"
+	SeverityMedium Severity = "medium"
+	SeverityLow    Severity = "low"
+)
+
+var severityRanks = map[Severity]int{SeverityHigh: 3, SeverityMedium: 2, SeverityLow: 1}
+
+// The rules of the conformance check
+const (
+	RuleMutableImageTag        = "mutable-image-tag"
+	RuleInitContainerVolume    = "init-container-volume"
+	RuleSharedWritableVolume   = "shared-writable-volume"
+	RulePostStartHook          = "post-start-hook"
+	RuleProbeOtherContainer    = "probe-other-container"
+	RuleLivenessWithoutStartup = "liveness-without-startup-probe"
+	RuleNoInPlaceGracePeriod   = "no-in-place-grace-period"
+)
+
+// Finding is a pattern of a pod template incompatible with in-place updates or hot upgrades
+type Finding struct {
+	Severity  Severity
+	Rule      string
+	Container string
+	Message   string
+}
+
+var (
+	conformanceLong = templates.LongDesc(i18n.T(`
+		Check that the pods of a workload can be updated in place, or hot-upgraded by a
+		SidecarSet, before enabling it, and report the patterns of its pod template that break
+		them, with their severities.
+
+		In-place updates restart the updated containers in the running pods: the init containers
+		are not run again, the other containers keep running, and the postStart hooks of the
+		restarted containers run again. The rules are:
+
+		  * mutable-image-tag (high): the image has no tag, or the latest tag, so pushing it
+		    again never changes the pod template and is never rolled out.
+		  * init-container-volume (high): an init container writes to a volume of the
+		    containers, e.g. files copied from its image, which is not refreshed in place.
+		  * shared-writable-volume (medium): containers write to the same volume, so an updated
+		    container shares it with the old versions of the others.
+		  * post-start-hook (medium): the postStart hook runs again on every in-place restart,
+		    not once per pod, and must be idempotent.
+		  * probe-other-container (medium): a probe checks a port of another container, tying
+		    it to the start order of the containers, which in-place restarts break.
+		  * liveness-without-startup-probe (low): a liveness probe without startup probe nor
+		    initial delay may kill a container restarted in place before it started.
+		  * no-in-place-grace-period (low): the pods of CloneSets and Advanced StatefulSets are
+		    updated as soon as they are marked not-ready, without time to drain their traffic.
+
+		The check exits with a non-zero code if --fail-on is given and a finding is at least
+		that severe.`))
+
+	conformanceExample = templates.Examples(i18n.T(`
+		# Check that the pods of deployment web can be updated in place
+		kubectl-kruise check conformance deployment/web
+
+		# Fail a CI job on the high severity findings of cloneset demo
+		kubectl-kruise check conformance cloneset/demo --fail-on high`))
+)
+
+// ConformanceOptions holds the options for 'check conformance' sub command
+type ConformanceOptions struct {
+	resource.FilenameOptions
+
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+	FailOn           string
+
+	Builder func() *resource.Builder
+
+	genericclioptions.IOStreams
+}
+
+// NewConformanceOptions returns an initialized ConformanceOptions instance
+func NewConformanceOptions(streams genericclioptions.IOStreams) *ConformanceOptions {
+	return &ConformanceOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdCheckConformance returns a Command instance for 'check conformance' sub command
+func NewCmdCheckConformance(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewConformanceOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "conformance (TYPE NAME | TYPE/NAME) [--fail-on=SEVERITY]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Check that the pods of workloads can be updated in place"),
+		Long:                  conformanceLong,
+		Example:               conformanceExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "identifying the workloads to check."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringVar(&o.FailOn, "fail-on", o.FailOn, "Exit with a non-zero code if a finding is at least this severe: high, medium or low.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *ConformanceOptions) Complete(f cmdutil.Factory, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+	return nil
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *ConformanceOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if _, ok := severityRanks[Severity(o.FailOn)]; len(o.FailOn) > 0 && !ok {
+		return fmt.Errorf("invalid --fail-on %q, must be high, medium or low", o.FailOn)
+	}
+	return nil
+}
+
+// Run performs the execution of 'check conformance' sub command
+func (o *ConformanceOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		ContinueOnError().
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i, info := range infos {
+		findings, err := CheckConformance(info.Object)
+		if err != nil {
+			return fmt.Errorf("%s %v", info.ObjectName(), err)
+		}
+		if i > 0 {
+			fmt.Fprintln(o.Out)
+		}
+		if err := printFindings(o.Out, info.ObjectName(), findings); err != nil {
+			return err
+		}
+		for _, finding := range findings {
+			if len(o.FailOn) > 0 && severityRanks[finding.Severity] >= severityRanks[Severity(o.FailOn)] {
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d findings are at least %s", failed, o.FailOn)
+	}
+	return nil
+}
+
+// CheckConformance returns the findings of the pod template of obj, sorted by severity.
+func CheckConformance(obj runtime.Object) ([]Finding, error) {
+	var template *corev1.PodTemplateSpec
+	ok, err := polymorphichelpers.UpdatePodTemplateForObjectFn(obj, func(t *corev1.PodTemplateSpec) error {
+		template = t.DeepCopy()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok || template == nil {
+		return nil, fmt.Errorf("has no pod template")
+	}
+
+	findings := checkPodSpec(&template.Spec)
+	if strategy, ok := inPlaceUpdateStrategy(obj); ok && (strategy == nil || strategy.GracePeriodSeconds == 0) {
+		findings = append(findings, Finding{
+			Severity: SeverityLow,
+			Rule:     RuleNoInPlaceGracePeriod,
+			Message:  "the pods are updated as soon as they are not-ready, set inPlaceUpdateStrategy.gracePeriodSeconds to drain their traffic",
+		})
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRanks[findings[i].Severity] > severityRanks[findings[j].Severity]
+	})
+	return findings, nil
+}
+
+// inPlaceUpdateStrategy returns the in-place update strategy of obj, and false if obj does not
+// update its pods in place.
+func inPlaceUpdateStrategy(obj runtime.Object) (*kruiseappspub.InPlaceUpdateStrategy, bool) {
+	switch t := obj.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		return t.Spec.UpdateStrategy.InPlaceUpdateStrategy, true
+	case *kruiseappsv1beta1.StatefulSet:
+		if t.Spec.UpdateStrategy.RollingUpdate == nil {
+			return nil, true
+		}
+		return t.Spec.UpdateStrategy.RollingUpdate.InPlaceUpdateStrategy, true
+	}
+	return nil, false
+}
+
+func checkPodSpec(spec *corev1.PodSpec) []Finding {
+	var findings []Finding
+
+	writers := map[string][]string{}
+	for _, c := range spec.Containers {
+		for _, mount := range c.VolumeMounts {
+			if !mount.ReadOnly {
+				writers[mount.Name] = append(writers[mount.Name], c.Name)
+			}
+		}
+	}
+	for _, c := range spec.InitContainers {
+		if image := c.Image; mutableImage(image) {
+			findings = append(findings, mutableImageFinding(c.Name, image))
+		}
+		for _, mount := range c.VolumeMounts {
+			if mount.ReadOnly || !mountedBy(spec.Containers, mount.Name) {
+				continue
+			}
+			findings = append(findings, Finding{
+				Severity:  SeverityHigh,
+				Rule:      RuleInitContainerVolume,
+				Container: c.Name,
+				Message:   fmt.Sprintf("writes to volume %s of the containers, which is not refreshed when they are updated in place since init containers are not run again", mount.Name),
+			})
+		}
+	}
+	for _, volume := range spec.Volumes {
+		if containers := writers[volume.Name]; len(containers) > 1 {
+			findings = append(findings, Finding{
+				Severity:  SeverityMedium,
+				Rule:      RuleSharedWritableVolume,
+				Container: strings.Join(containers, ","),
+				Message:   fmt.Sprintf("write to volume %s, which an updated container shares with the old versions of the others", volume.Name),
+			})
+		}
+	}
+
+	for _, c := range spec.Containers {
+		if mutableImage(c.Image) {
+			findings = append(findings, mutableImageFinding(c.Name, c.Image))
+		}
+		if c.Lifecycle != nil && c.Lifecycle.PostStart != nil {
+			findings = append(findings, Finding{
+				Severity:  SeverityMedium,
+				Rule:      RulePostStartHook,
+				Container: c.Name,
+				Message:   "the postStart hook runs again on every in-place restart of the container, not once per pod, it must be idempotent",
+			})
+		}
+		probes := []*corev1.Probe{c.StartupProbe, c.LivenessProbe, c.ReadinessProbe}
+		for i, kind := range []string{"startup", "liveness", "readiness"} {
+			if owner, port := probeOwner(spec, &c, probes[i]); len(owner) > 0 {
+				findings = append(findings, Finding{
+					Severity:  SeverityMedium,
+					Rule:      RuleProbeOtherContainer,
+					Container: c.Name,
+					Message:   fmt.Sprintf("the %s probe checks port %s of container %s, tying it to the start order of the containers", kind, port, owner),
+				})
+			}
+		}
+		if c.LivenessProbe != nil && c.StartupProbe == nil && c.LivenessProbe.InitialDelaySeconds == 0 {
+			findings = append(findings, Finding{
+				Severity:  SeverityLow,
+				Rule:      RuleLivenessWithoutStartup,
+				Container: c.Name,
+				Message:   "the liveness probe has no startup probe nor initial delay, it may kill the container restarted in place before it started",
+			})
+		}
+	}
+	return findings
+}
+
+func mutableImageFinding(container, image string) Finding {
+	return Finding{
+		Severity:  SeverityHigh,
+		Rule:      RuleMutableImageTag,
+		Container: container,
+		Message:   fmt.Sprintf("image %s has no tag or the latest tag, pushing it again does not change the pod template", image),
+	}
+}
+
+// mutableImage returns true if image has no digest and no tag, or the latest tag.
+func mutableImage(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return true
+	}
+	return image[i+1:] == "latest"
+}
+
+func mountedBy(containers []corev1.Container, volume string) bool {
+	for _, c := range containers {
+		for _, mount := range c.VolumeMounts {
+			if mount.Name == volume {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// probeOwner returns the other container of spec exposing the port probe checks on container c,
+// and the port, or an empty name if the port is not exposed by another container.
+func probeOwner(spec *corev1.PodSpec, c *corev1.Container, probe *corev1.Probe) (string, string) {
+	if probe == nil {
+		return "", ""
+	}
+	var port intstr.IntOrString
+	switch {
+	case probe.HTTPGet != nil:
+		port = probe.HTTPGet.Port
+	case probe.TCPSocket != nil:
+		port = probe.TCPSocket.Port
+	default:
+		return "", ""
+	}
+	if exposesPort(c, port) {
+		return "", ""
+	}
+	for i := range spec.Containers {
+		other := &spec.Containers[i]
+		if other.Name != c.Name && exposesPort(other, port) {
+			return other.Name, port.String()
+		}
+	}
+	return "", ""
+}
+
+func exposesPort(c *corev1.Container, port intstr.IntOrString) bool {
+	for _, p := range c.Ports {
+		if (port.Type == intstr.String && p.Name == port.StrVal) || (port.Type == intstr.Int && p.ContainerPort == port.IntVal) {
+			return true
+		}
+	}
+	return false
+}
+
+// printFindings prints the findings of the workload name, or that it conforms.
+func printFindings(out io.Writer, name string, findings []Finding) error {
+	if len(findings) == 0 {
+		fmt.Fprintf(out, "%s: no findings, its pods can be updated in place\n", name)
+		return nil
+	}
+	counts := map[Severity]int{}
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+	fmt.Fprintf(out, "%s: %d findings, %d high, %d medium, %d low\n", name, len(findings), counts[SeverityHigh], counts[SeverityMedium], counts[SeverityLow])
+	w := internalcmdutil.NewTableWriter(out)
+	fmt.Fprintln(w, "  SEVERITY\tRULE\tCONTAINER\tMESSAGE")
+	for _, finding := range findings {
+		container := finding.Container
+		if len(container) == 0 {
+			container = "<none>"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", finding.Severity, finding.Rule, container, finding.Message)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package check
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	kruiseappspub "github.com/openkruise/kruise-api/apps/pub"
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestCheckConformance(t *testing.T) {
+	shared := []corev1.VolumeMount{{Name: "shared", MountPath: "/shared"}}
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "copy", Image: "assets:1.0", VolumeMounts: shared}},
+		Containers: []corev1.Container{
+			{
+				Name:         "app",
+				Image:        "app",
+				VolumeMounts: shared,
+				Lifecycle:    &corev1.Lifecycle{PostStart: &corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"register"}}}},
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Port: intstr.FromString("proxy")}},
+				},
+			},
+			{
+				Name:         "proxy",
+				Image:        "envoy:1.22@sha256:0123",
+				VolumeMounts: shared,
+				Ports:        []corev1.ContainerPort{{Name: "proxy", ContainerPort: 15000}},
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(15000)}},
+				},
+			},
+		},
+		Volumes: []corev1.Volume{{Name: "shared"}},
+	}
+
+	tests := []struct {
+		name     string
+		obj      runtime.Object
+		expected []Finding
+	}{
+		{
+			name: "cloneset",
+			obj: &kruiseappsv1alpha1.CloneSet{Spec: kruiseappsv1alpha1.CloneSetSpec{
+				Template: corev1.PodTemplateSpec{Spec: spec},
+			}},
+			expected: []Finding{
+				{Severity: SeverityHigh, Rule: RuleInitContainerVolume, Container: "copy", Message: "writes to volume shared of the containers, which is not refreshed when they are updated in place since init containers are not run again"},
+				{Severity: SeverityHigh, Rule: RuleMutableImageTag, Container: "app", Message: "image app has no tag or the latest tag, pushing it again does not change the pod template"},
+				{Severity: SeverityMedium, Rule: RuleSharedWritableVolume, Container: "app,proxy", Message: "write to volume shared, which an updated container shares with the old versions of the others"},
+				{Severity: SeverityMedium, Rule: RulePostStartHook, Container: "app", Message: "the postStart hook runs again on every in-place restart of the container, not once per pod, it must be idempotent"},
+				{Severity: SeverityMedium, Rule: RuleProbeOtherContainer, Container: "app", Message: "the liveness probe checks port proxy of container proxy, tying it to the start order of the containers"},
+				{Severity: SeverityLow, Rule: RuleLivenessWithoutStartup, Container: "app", Message: "the liveness probe has no startup probe nor initial delay, it may kill the container restarted in place before it started"},
+				{Severity: SeverityLow, Rule: RuleNoInPlaceGracePeriod, Message: "the pods are updated as soon as they are not-ready, set inPlaceUpdateStrategy.gracePeriodSeconds to drain their traffic"},
+			},
+		},
+		{
+			name: "conforming cloneset",
+			obj: &kruiseappsv1alpha1.CloneSet{Spec: kruiseappsv1alpha1.CloneSetSpec{
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:1.0"}}}},
+				UpdateStrategy: kruiseappsv1alpha1.CloneSetUpdateStrategy{
+					InPlaceUpdateStrategy: &kruiseappspub.InPlaceUpdateStrategy{GracePeriodSeconds: 10},
+				},
+			}},
+		},
+		{
+			name: "deployment",
+			obj: &appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:1.0"}}}},
+			}},
+		},
+	}
+	for _, test := range tests {
+		findings, err := CheckConformance(test.obj)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(findings, test.expected) {
+			t.Errorf("%s: expected\n%v\ngot\n%v", test.name, test.expected, findings)
+		}
+	}
+
+	if _, err := CheckConformance(&corev1.Service{}); err == nil {
+		t.Errorf("expected an error checking a service")
+	}
+}
+
+func TestMutableImage(t *testing.T) {
+	tests := map[string]bool{
+		"nginx":                        true,
+		"nginx:latest":                 true,
+		"registry:5000/nginx":          true,
+		"nginx:1.21":                   false,
+		"registry:5000/nginx:1.21":     false,
+		"nginx@sha256:0123":            false,
+		"registry:5000/nginx@sha256:1": false,
+	}
+	for image, expected := range tests {
+		if got := mutableImage(image); got != expected {
+			t.Errorf("%s: expected %v, got %v", image, expected, got)
+		}
+	}
+}
+
+func TestPrintFindings(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := printFindings(buf, "cloneset.apps.kruise.io/demo", nil); err != nil {
+		t.Fatal(err)
+	}
+	err := printFindings(buf, "cloneset.apps.kruise.io/web", []Finding{
+		{Severity: SeverityHigh, Rule: RuleMutableImageTag, Container: "app", Message: "image app has no tag"},
+		{Severity: SeverityLow, Rule: RuleNoInPlaceGracePeriod, Message: "no grace period"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `cloneset.apps.kruise.io/demo: no findings, its pods can be updated in place
+cloneset.apps.kruise.io/web: 2 findings, 1 high, 0 medium, 1 low
+  SEVERITY   RULE                       CONTAINER   MESSAGE
+  high       mutable-image-tag          app         image app has no tag
+  low        no-in-place-grace-period   <none>      no grace period
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
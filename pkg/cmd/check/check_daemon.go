@@ -0,0 +1,378 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package check
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	kruiseclientset "github.com/openkruise/kruise-api/client/clientset/versioned"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// Status is the result of a check of the kruise-daemon of a node
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusWarning Status = "warning"
+	StatusError   Status = "error"
+)
+
+// The checks of the kruise-daemon of a node
+const (
+	CheckDaemonPod     = "daemon-pod"
+	CheckRuntimeSocket = "runtime-socket"
+	CheckNodeImage     = "node-image"
+	CheckImagePulls    = "image-pulls"
+	CheckRecreates     = "container-recreates"
+)
+
+// daemonLabelSelector selects the pods of the kruise-daemon DaemonSet
+const daemonLabelSelector = "control-plane=daemon"
+
+// runtimeSockets are the default sockets of the container runtimes, by the scheme of their versions
+var runtimeSockets = map[string][]string{
+	"containerd": {"/run/containerd/containerd.sock", "/var/run/containerd/containerd.sock"},
+	"docker":     {"/var/run/docker.sock", "/run/docker.sock"},
+	"cri-o":      {"/var/run/crio/crio.sock", "/run/crio/crio.sock"},
+	"pouch":      {"/var/run/pouchcri.sock", "/var/run/pouchd.sock"},
+}
+
+// klogError matches the error lines of klog, e.g. "E0612 10:00:00.000000       1 puller.go:123] ..."
+var klogError = regexp.MustCompile(`^E\d{4} `)
+
+var (
+	daemonLong = templates.LongDesc(i18n.T(`
+		Check the kruise-daemon of a node, which runs the in-place features of Kruise on it:
+		the image pre-download of ImagePullJobs and NodeImages, and the container restarts of
+		ContainerRecreateRequests and SidecarSet hot upgrades.
+
+		The checks are:
+
+		  * daemon-pod: the kruise-daemon pod of the node is running and ready.
+		  * runtime-socket: the kruise-daemon pod mounts the socket of the container runtime
+		    of the node, and did not log errors connecting to it.
+		  * node-image: the NodeImage of the node exists, kruise-daemon reports its images in it.
+		  * image-pulls: kruise-daemon did not log image pull errors.
+		  * container-recreates: kruise-daemon did not log ContainerRecreateRequest errors.
+
+		The logs are read since --since. The node is ready for the in-place features if no check
+		is in error, and the check exits with a non-zero code otherwise.`))
+
+	daemonExample = templates.Examples(i18n.T(`
+		# Check the kruise-daemon of node worker-1
+		kubectl-kruise check daemon worker-1
+
+		# Check the kruise-daemon of node worker-1 and its errors of the last 24 hours, in namespace kruise
+		kubectl-kruise check daemon worker-1 --since 24h --kruise-namespace kruise`))
+)
+
+// DaemonCheck is the result of a check of the kruise-daemon of a node
+type DaemonCheck struct {
+	Name    string
+	Status  Status
+	Message string
+}
+
+// DaemonOptions holds the options for 'check daemon' sub command
+type DaemonOptions struct {
+	Node            string
+	KruiseNamespace string
+	Since           time.Duration
+
+	Client       kubernetes.Interface
+	KruiseClient kruiseclientset.Interface
+
+	genericclioptions.IOStreams
+}
+
+// NewDaemonOptions returns an initialized DaemonOptions instance
+func NewDaemonOptions(streams genericclioptions.IOStreams) *DaemonOptions {
+	return &DaemonOptions{
+		KruiseNamespace: "kruise-system",
+		Since:           time.Hour,
+		IOStreams:       streams,
+	}
+}
+
+// NewCmdCheckDaemon returns a Command instance for 'check daemon' sub command
+func NewCmdCheckDaemon(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewDaemonOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "daemon NODE [--since=DURATION]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Check the kruise-daemon of a node"),
+		Long:                  daemonLong,
+		Example:               daemonExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.KruiseNamespace, "kruise-namespace", o.KruiseNamespace, "The namespace Kruise is installed in.")
+	cmd.Flags().DurationVar(&o.Since, "since", o.Since, "Read the logs of kruise-daemon since this duration, e.g. 30m.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *DaemonOptions) Complete(f cmdutil.Factory, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("exactly one NODE is required, got %d", len(args))
+	}
+	o.Node = args[0]
+
+	var err error
+	if o.Client, err = f.KubernetesClientSet(); err != nil {
+		return err
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.KruiseClient, err = kruiseclientset.NewForConfig(config)
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *DaemonOptions) Validate() error {
+	if o.Since <= 0 {
+		return fmt.Errorf("--since must be positive")
+	}
+	return nil
+}
+
+// Run performs the execution of 'check daemon' sub command
+func (o *DaemonOptions) Run() error {
+	node, err := o.Client.CoreV1().Nodes().Get(context.TODO(), o.Node, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	pods, err := o.Client.CoreV1().Pods(o.KruiseNamespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: daemonLabelSelector,
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", o.Node).String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	var pod *corev1.Pod
+	if len(pods.Items) > 0 {
+		pod = &pods.Items[0]
+	}
+	checks := []DaemonCheck{CheckDaemonPodStatus(pod)}
+	var logs []string
+	if pod != nil {
+		if logs, err = o.errorLogs(pod); err != nil {
+			return err
+		}
+	}
+	checks = append(checks, CheckDaemonRuntimeSocket(node, pod, logs))
+
+	nodeImage := DaemonCheck{Name: CheckNodeImage, Status: StatusOK, Message: "exists"}
+	if _, err := o.KruiseClient.AppsV1alpha1().NodeImages().Get(context.TODO(), o.Node, metav1.GetOptions{}); errors.IsNotFound(err) {
+		nodeImage.Status, nodeImage.Message = StatusWarning, "not found, kruise-daemon did not report the images of the node yet"
+	} else if err != nil {
+		return err
+	}
+	checks = append(checks, nodeImage)
+	checks = append(checks, CheckDaemonLogs(logs, o.Since)...)
+
+	if err := printDaemonChecks(o.Out, o.Node, checks); err != nil {
+		return err
+	}
+	for _, check := range checks {
+		if check.Status == StatusError {
+			return fmt.Errorf("node %s is not ready for the in-place features", o.Node)
+		}
+	}
+	return nil
+}
+
+// errorLogs returns the error lines logged by the kruise-daemon pod since --since.
+func (o *DaemonOptions) errorLogs(pod *corev1.Pod) ([]string, error) {
+	since := int64(o.Since.Seconds())
+	stream, err := o.Client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{SinceSeconds: &since}).Stream(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the logs of %s: %v", pod.Name, err)
+	}
+	defer stream.Close()
+	return readErrorLogs(stream)
+}
+
+func readErrorLogs(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); klogError.MatchString(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// CheckDaemonPodStatus checks the kruise-daemon pod of a node is running and ready, pod is nil if
+// the node has none.
+func CheckDaemonPodStatus(pod *corev1.Pod) DaemonCheck {
+	check := DaemonCheck{Name: CheckDaemonPod}
+	if pod == nil {
+		check.Status, check.Message = StatusError, "no kruise-daemon pod on the node, check the node selector and tolerations of the kruise-daemon DaemonSet"
+		return check
+	}
+	var restarts int32
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+	ready := false
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			ready = condition.Status == corev1.ConditionTrue
+		}
+	}
+	switch {
+	case pod.Status.Phase != corev1.PodRunning:
+		check.Status, check.Message = StatusError, fmt.Sprintf("%s is %s", pod.Name, pod.Status.Phase)
+	case !ready:
+		check.Status, check.Message = StatusError, fmt.Sprintf("%s is running but not ready", pod.Name)
+	case restarts > 0:
+		check.Status, check.Message = StatusWarning, fmt.Sprintf("%s is ready, restarted %d times", pod.Name, restarts)
+	default:
+		check.Status, check.Message = StatusOK, fmt.Sprintf("%s is ready", pod.Name)
+	}
+	return check
+}
+
+// CheckDaemonRuntimeSocket checks the kruise-daemon pod mounts the socket of the container runtime
+// of node from the host, and did not log errors connecting to it.
+func CheckDaemonRuntimeSocket(node *corev1.Node, pod *corev1.Pod, logs []string) DaemonCheck {
+	check := DaemonCheck{Name: CheckRuntimeSocket}
+	if pod == nil {
+		check.Status, check.Message = StatusError, "no kruise-daemon pod on the node"
+		return check
+	}
+	runtimeVersion := node.Status.NodeInfo.ContainerRuntimeVersion
+	runtime := strings.SplitN(runtimeVersion, "://", 2)[0]
+	sockets, ok := runtimeSockets[runtime]
+	if !ok {
+		check.Status, check.Message = StatusWarning, fmt.Sprintf("unknown container runtime %q, its socket can not be checked", runtimeVersion)
+		return check
+	}
+
+	socket := ""
+	for _, volume := range pod.Spec.Volumes {
+		if volume.HostPath == nil {
+			continue
+		}
+		for _, s := range sockets {
+			if s == volume.HostPath.Path || strings.HasPrefix(s, strings.TrimSuffix(volume.HostPath.Path, "/")+"/") {
+				socket = s
+			}
+		}
+	}
+	if len(socket) == 0 {
+		check.Status, check.Message = StatusError, fmt.Sprintf("no hostPath volume of %s mounts the %s socket %s", pod.Name, runtime, strings.Join(sockets, " or "))
+		return check
+	}
+	if errs := matchingLogs(logs, "runtime", ".sock", "dial "); len(errs) > 0 {
+		check.Status, check.Message = StatusError, fmt.Sprintf("%d errors connecting to %s, last: %s", len(errs), runtimeVersion, logMessage(errs[len(errs)-1]))
+		return check
+	}
+	check.Status, check.Message = StatusOK, fmt.Sprintf("%s mounted for %s", socket, runtimeVersion)
+	return check
+}
+
+// CheckDaemonLogs checks the image pull and ContainerRecreateRequest errors in the error logs of
+// the kruise-daemon pod since the given duration.
+func CheckDaemonLogs(logs []string, since time.Duration) []DaemonCheck {
+	checks := []DaemonCheck{
+		{Name: CheckImagePulls},
+		{Name: CheckRecreates},
+	}
+	keywords := [][]string{{"pull"}, {"containerrecreaterequest", "crr"}}
+	for i := range checks {
+		errs := matchingLogs(logs, keywords[i]...)
+		if len(errs) == 0 {
+			checks[i].Status, checks[i].Message = StatusOK, fmt.Sprintf("no errors in the last %s", since)
+			continue
+		}
+		checks[i].Status, checks[i].Message = StatusWarning, fmt.Sprintf("%d errors in the last %s, last: %s", len(errs), since, logMessage(errs[len(errs)-1]))
+	}
+	return checks
+}
+
+// matchingLogs returns the lines of logs containing any of the keywords, case-insensitively.
+func matchingLogs(logs []string, keywords ...string) []string {
+	var matching []string
+	for _, line := range logs {
+		lower := strings.ToLower(logMessage(line))
+		for _, keyword := range keywords {
+			if strings.Contains(lower, keyword) {
+				matching = append(matching, line)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// logMessage returns the message of a klog line, without its header.
+func logMessage(line string) string {
+	if i := strings.Index(line, "] "); i >= 0 {
+		return line[i+2:]
+	}
+	return line
+}
+
+func printDaemonChecks(out io.Writer, node string, checks []DaemonCheck) error {
+	errs := 0
+	for _, check := range checks {
+		if check.Status == StatusError {
+			errs++
+		}
+	}
+	if errs == 0 {
+		fmt.Fprintf(out, "node/%s: ready for the in-place features\n", node)
+	} else {
+		fmt.Fprintf(out, "node/%s: not ready for the in-place features, %d checks failed\n", node, errs)
+	}
+	w := internalcmdutil.NewTableWriter(out)
+	fmt.Fprintln(w, "  CHECK\tSTATUS\tMESSAGE")
+	for _, check := range checks {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", check.Name, check.Status, check.Message)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package check
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func daemonPod(phase corev1.PodPhase, ready corev1.ConditionStatus, restarts int32, socketDir string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "kruise-system",
+			Name:      "kruise-daemon-abcde",
+			Labels:    map[string]string{"control-plane": "daemon"},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "worker-1",
+			Volumes: []corev1.Volume{
+				{Name: "runtime-socket", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: socketDir}}},
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase:             phase,
+			Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "daemon", RestartCount: restarts}},
+		},
+	}
+}
+
+func runtimeNode(runtimeVersion string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+		Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{ContainerRuntimeVersion: runtimeVersion}},
+	}
+}
+
+func TestCheckDaemonPodStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		expected DaemonCheck
+	}{
+		{
+			name:     "no pod",
+			expected: DaemonCheck{CheckDaemonPod, StatusError, "no kruise-daemon pod on the node, check the node selector and tolerations of the kruise-daemon DaemonSet"},
+		},
+		{
+			name:     "pending",
+			pod:      daemonPod(corev1.PodPending, corev1.ConditionFalse, 0, "/var/run"),
+			expected: DaemonCheck{CheckDaemonPod, StatusError, "kruise-daemon-abcde is Pending"},
+		},
+		{
+			name:     "not ready",
+			pod:      daemonPod(corev1.PodRunning, corev1.ConditionFalse, 0, "/var/run"),
+			expected: DaemonCheck{CheckDaemonPod, StatusError, "kruise-daemon-abcde is running but not ready"},
+		},
+		{
+			name:     "restarted",
+			pod:      daemonPod(corev1.PodRunning, corev1.ConditionTrue, 3, "/var/run"),
+			expected: DaemonCheck{CheckDaemonPod, StatusWarning, "kruise-daemon-abcde is ready, restarted 3 times"},
+		},
+		{
+			name:     "ready",
+			pod:      daemonPod(corev1.PodRunning, corev1.ConditionTrue, 0, "/var/run"),
+			expected: DaemonCheck{CheckDaemonPod, StatusOK, "kruise-daemon-abcde is ready"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if check := CheckDaemonPodStatus(test.pod); check != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, check)
+			}
+		})
+	}
+}
+
+func TestCheckDaemonRuntimeSocket(t *testing.T) {
+	pod := daemonPod(corev1.PodRunning, corev1.ConditionTrue, 0, "/var/run")
+	tests := []struct {
+		name     string
+		node     *corev1.Node
+		pod      *corev1.Pod
+		logs     []string
+		expected DaemonCheck
+	}{
+		{
+			name:     "containerd socket mounted",
+			node:     runtimeNode("containerd://1.6.4"),
+			pod:      pod,
+			expected: DaemonCheck{CheckRuntimeSocket, StatusOK, "/var/run/containerd/containerd.sock mounted for containerd://1.6.4"},
+		},
+		{
+			name:     "docker socket not mounted",
+			node:     runtimeNode("docker://20.10.7"),
+			pod:      daemonPod(corev1.PodRunning, corev1.ConditionTrue, 0, "/var/run/containerd"),
+			expected: DaemonCheck{CheckRuntimeSocket, StatusError, "no hostPath volume of kruise-daemon-abcde mounts the docker socket /var/run/docker.sock or /run/docker.sock"},
+		},
+		{
+			name: "connection errors",
+			node: runtimeNode("containerd://1.6.4"),
+			pod:  pod,
+			logs: []string{
+				"E0612 10:00:00.000000       1 factory.go:80] failed to new runtime service: dial unix /var/run/containerd/containerd.sock: connect: permission denied",
+			},
+			expected: DaemonCheck{CheckRuntimeSocket, StatusError, "1 errors connecting to containerd://1.6.4, last: failed to new runtime service: dial unix /var/run/containerd/containerd.sock: connect: permission denied"},
+		},
+		{
+			name:     "unknown runtime",
+			node:     runtimeNode("kata://2.0"),
+			pod:      pod,
+			expected: DaemonCheck{CheckRuntimeSocket, StatusWarning, `unknown container runtime "kata://2.0", its socket can not be checked`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if check := CheckDaemonRuntimeSocket(test.node, test.pod, test.logs); check != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, check)
+			}
+		})
+	}
+}
+
+func TestCheckDaemonLogs(t *testing.T) {
+	logs, err := readErrorLogs(strings.NewReader(`I0612 09:59:00.000000       1 puller.go:50] Pulling image nginx:1.21
+E0612 10:00:00.000000       1 puller.go:123] Failed to pull image nginx:1.22: not found
+E0612 10:01:00.000000       1 puller.go:123] Failed to pull image redis:7: unauthorized
+W0612 10:02:00.000000       1 crr_controller.go:90] ContainerRecreateRequest default/web-0 is slow
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []DaemonCheck{
+		{CheckImagePulls, StatusWarning, "2 errors in the last 1h0m0s, last: Failed to pull image redis:7: unauthorized"},
+		{CheckRecreates, StatusOK, "no errors in the last 1h0m0s"},
+	}
+	if checks := CheckDaemonLogs(logs, time.Hour); !reflect.DeepEqual(checks, expected) {
+		t.Errorf("expected %+v, got %+v", expected, checks)
+	}
+}
+
+func TestDaemonRun(t *testing.T) {
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewDaemonOptions(streams)
+	o.Node = "worker-1"
+	o.Client = fake.NewSimpleClientset(runtimeNode("containerd://1.6.4"), daemonPod(corev1.PodRunning, corev1.ConditionTrue, 0, "/run/containerd"))
+	o.KruiseClient = kruisefake.NewSimpleClientset(&kruiseappsv1alpha1.NodeImage{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}})
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `node/worker-1: ready for the in-place features
+  CHECK                 STATUS   MESSAGE
+  daemon-pod            ok       kruise-daemon-abcde is ready
+  runtime-socket        ok       /run/containerd/containerd.sock mounted for containerd://1.6.4
+  node-image            ok       exists
+  image-pulls           ok       no errors in the last 1h0m0s
+  container-recreates   ok       no errors in the last 1h0m0s
+`
+	if out.String() != expected {
+		t.Errorf("expected output:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	o.Client = fake.NewSimpleClientset(runtimeNode("containerd://1.6.4"))
+	o.KruiseClient = kruisefake.NewSimpleClientset()
+	if err := o.Run(); err == nil || err.Error() != "node worker-1 is not ready for the in-place features" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("node-image            warning   not found")) {
+		t.Errorf("expected the NodeImage to be missing:\n%s", out.String())
+	}
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ci
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// The exit codes of the ci commands, besides 0 on success
+const (
+	// ExitFailed is returned when the step failed, e.g. the API server refused a change
+	ExitFailed = 1
+	// ExitInvalid is returned when the arguments or the manifests are invalid
+	ExitInvalid = 2
+	// ExitTimeout is returned when the resources are not ready before --timeout
+	ExitTimeout = 3
+)
+
+var (
+	ciLong = templates.LongDesc(`
+		Run the steps of a delivery pipeline, such as GitHub Actions or Tekton.
+
+		Unlike the interactive commands, the ci commands wait for the resources to be ready,
+		log structured entries, fail after --timeout, exit with a code that tells why they
+		failed, and write the final state of the resources, with their revisions, to the file
+		given by --artifact.
+
+		Exit codes: 1 when the step failed, 2 when the arguments or the manifests are invalid,
+		3 when the resources are not ready in time.`)
+
+	ciExample = templates.Examples(`
+		# Deploy the manifests and wait for them to be ready
+		kubectl-kruise ci deploy -f manifests/ --timeout 10m --artifact deploy.json
+
+		# Check that cloneset demo is ready
+		kubectl-kruise ci verify cloneset/demo
+
+		# Release the next batch of batchrelease demo
+		kubectl-kruise ci promote batchrelease/demo
+
+		# Roll back cloneset demo to its previous revision
+		kubectl-kruise ci rollback cloneset/demo`)
+)
+
+// CIOptions holds the command-line options shared by the 'ci' sub commands
+type CIOptions struct {
+	Resources []string
+	Timeout   time.Duration
+	LogFormat string
+	Artifact  string
+
+	Namespace        string
+	EnforceNamespace bool
+	Builder          func() *resource.Builder
+
+	Log *Logger
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// Artifact is written to --artifact with the final state of the resources of a step.
+type Artifact struct {
+	Step      string                          `json:"step"`
+	Resources []internalcmdutil.ResourceState `json:"resources"`
+}
+
+// invalidError is an error of the arguments or the manifests, with the ExitInvalid exit code
+type invalidError struct {
+	error
+}
+
+// NewCIOptions returns an initialized CIOptions instance
+func NewCIOptions(streams genericclioptions.IOStreams) *CIOptions {
+	return &CIOptions{
+		Timeout:   10 * time.Minute,
+		LogFormat: LogFormatText,
+		IOStreams: streams,
+	}
+}
+
+// NewCmdCI returns a Command instance for 'ci' command
+func NewCmdCI(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "ci SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Run the steps of a delivery pipeline"),
+		Long:                  ciLong,
+		Example:               ciExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(NewCmdCIDeploy(f, streams))
+	cmd.AddCommand(NewCmdCIVerify(f, streams))
+	cmd.AddCommand(NewCmdCIPromote(f, streams))
+	cmd.AddCommand(NewCmdCIRollback(f, streams))
+	return cmd
+}
+
+func (o *CIOptions) addFlags(cmd *cobra.Command) {
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, "identifying the resources of the step.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The time after which the step fails if the resources are not ready.")
+	cmd.Flags().StringVar(&o.LogFormat, "log-format", o.LogFormat, "The format of the log entries, one of: text, json.")
+	cmd.Flags().StringVar(&o.Artifact, "artifact", o.Artifact, "If set, the file the final state of the resources is written to, as JSON.")
+}
+
+// complete completes the shared options of the given step
+func (o *CIOptions) complete(f cmdutil.Factory, step string, args []string) error {
+	o.Resources = args
+	o.Log = &Logger{
+		Format:        o.LogFormat,
+		Step:          step,
+		GitHubActions: os.Getenv("GITHUB_ACTIONS") == "true",
+		Out:           o.Out,
+	}
+	if o.LogFormat != LogFormatText && o.LogFormat != LogFormatJSON {
+		return invalidError{fmt.Errorf("invalid --log-format %q, must be one of: %s, %s", o.LogFormat, LogFormatText, LogFormatJSON)}
+	}
+	if o.Timeout <= 0 {
+		return invalidError{fmt.Errorf("--timeout must be positive")}
+	}
+
+	var err error
+	if o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace(); err != nil {
+		return err
+	}
+	o.Builder = f.NewBuilder
+	return nil
+}
+
+// requireResources fails unless resources are given by arguments or files
+func (o *CIOptions) requireResources() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return invalidError{fmt.Errorf("required resource not specified")}
+	}
+	return nil
+}
+
+// resourceInfos returns the resources given by arguments or files, as they are on the server
+func (o *CIOptions) resourceInfos() ([]*resource.Info, error) {
+	infos, err := o.Builder().
+		Unstructured().
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		Latest().
+		Flatten().
+		Do().Infos()
+	if err != nil {
+		return nil, invalidError{err}
+	}
+	return infos, nil
+}
+
+// waitReady waits for the resources of infos to be ready, logs their state and writes the artifact
+func (o *CIOptions) waitReady(infos []*resource.Info) error {
+	o.Log.Info("waiting for the resources to be ready", "resources", len(infos), "timeout", o.Timeout.String())
+	states, err := internalcmdutil.ReadyStates(infos, true, o.Timeout)
+	for _, state := range states {
+		o.Log.Info("resource state", "resource", fmt.Sprintf("%s/%s", state.Kind, state.Name), "namespace", state.Namespace,
+			"ready", state.Ready, "revision", state.Revision, "generation", state.Generation, "observedGeneration", state.ObservedGeneration)
+	}
+	if artifactErr := o.writeArtifact(states); artifactErr != nil && err == nil {
+		err = artifactErr
+	}
+	return err
+}
+
+func (o *CIOptions) writeArtifact(states []internalcmdutil.ResourceState) error {
+	if len(o.Artifact) == 0 {
+		return nil
+	}
+	if states == nil {
+		states = []internalcmdutil.ResourceState{}
+	}
+	data, err := json.MarshalIndent(Artifact{Step: o.Log.Step, Resources: states}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(o.Artifact, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	o.Log.Info("artifact written", "path", o.Artifact)
+	return nil
+}
+
+// run completes the options and runs the step, logs its result and exits with the exit code of
+// its error, if any
+func (o *CIOptions) run(f cmdutil.Factory, name string, args []string, step func() error) {
+	err := o.complete(f, name, args)
+	if err == nil {
+		err = step()
+	}
+	if err == nil {
+		o.Log.Info("step succeeded")
+		return
+	}
+	o.Log.Error(err)
+	os.Exit(ExitCode(err))
+}
+
+// ExitCode returns the exit code of the error of a step.
+func ExitCode(err error) int {
+	var invalid invalidError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, internalcmdutil.ErrReadyTimeout):
+		return ExitTimeout
+	case errors.As(err, &invalid):
+		return ExitInvalid
+	default:
+		return ExitFailed
+	}
+}
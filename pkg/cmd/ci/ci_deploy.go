@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ci
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// FieldManager is the field manager of the changes applied by 'ci deploy'.
+const FieldManager = "kubectl-kruise-ci"
+
+var (
+	deployLong = templates.LongDesc(`
+		Apply manifests with server-side apply and wait for the resources to be ready.
+
+		The fields of the manifests are owned by the ` + FieldManager + ` field manager. Conflicts
+		with other field managers are resolved in favour of the manifests, unless
+		--force-conflicts=false is given.`)
+
+	deployExample = templates.Examples(`
+		# Deploy the manifests of manifests/ and write the deployed revisions to deploy.json
+		kubectl-kruise ci deploy -f manifests/ -R --artifact deploy.json`)
+)
+
+// DeployOptions holds the command-line options for 'ci deploy' sub command
+type DeployOptions struct {
+	*CIOptions
+	ForceConflicts bool
+}
+
+// NewCmdCIDeploy returns a Command instance for 'ci deploy' sub command
+func NewCmdCIDeploy(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := &DeployOptions{CIOptions: NewCIOptions(streams), ForceConflicts: true}
+
+	cmd := &cobra.Command{
+		Use:                   "deploy -f FILENAME",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Apply manifests and wait for them to be ready"),
+		Long:                  deployLong,
+		Example:               deployExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.run(f, "deploy", args, o.Run)
+		},
+	}
+	o.addFlags(cmd)
+	cmd.Flags().BoolVar(&o.ForceConflicts, "force-conflicts", o.ForceConflicts, "If true, take the ownership of the fields of the manifests owned by other field managers.")
+	return cmd
+}
+
+// Run applies the manifests and waits for them to be ready
+func (o *DeployOptions) Run() error {
+	if len(o.Resources) > 0 || cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return invalidError{fmt.Errorf("the manifests must be given with -f or -k, and no arguments")}
+	}
+	infos, err := o.Builder().
+		Unstructured().
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		Flatten().
+		Do().Infos()
+	if err != nil {
+		return invalidError{err}
+	}
+	if len(infos) == 0 {
+		return invalidError{fmt.Errorf("no objects found in the manifests")}
+	}
+
+	for _, info := range infos {
+		data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, info.Object)
+		if err != nil {
+			return invalidError{err}
+		}
+		force := o.ForceConflicts
+		obj, err := resource.NewHelper(info.Client, info.Mapping).
+			WithFieldManager(FieldManager).
+			Patch(info.Namespace, info.Name, types.ApplyPatchType, data, &metav1.PatchOptions{Force: &force})
+		if err != nil {
+			return fmt.Errorf("failed to apply %s: %v", info.ObjectName(), err)
+		}
+		if err := info.Refresh(obj, true); err != nil {
+			return err
+		}
+		o.Log.Info("applied", "resource", info.ObjectName(), "namespace", info.Namespace, "generation", info.Object.(metav1.Object).GetGeneration())
+	}
+	return o.waitReady(infos)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ci
+
+import (
+	"github.com/openkruise/kruise-tools/pkg/cmd/promote"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	ciPromoteLong = templates.LongDesc(`
+		Promote resources as 'kubectl-kruise promote' does, and wait for them to be ready.`)
+
+	ciPromoteExample = templates.Examples(`
+		# Release the next batch of batchrelease demo
+		kubectl-kruise ci promote batchrelease/demo
+
+		# Update all the pods of cloneset demo
+		kubectl-kruise ci promote cloneset/demo --full`)
+)
+
+// NewCmdCIPromote returns a Command instance for 'ci promote' sub command
+func NewCmdCIPromote(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewCIOptions(streams)
+	full := false
+
+	cmd := &cobra.Command{
+		Use:                   "promote (-f FILENAME | TYPE NAME | TYPE/NAME) [--full]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Promote resources and wait for them to be ready"),
+		Long:                  ciPromoteLong,
+		Example:               ciPromoteExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.run(f, "promote", args, func() error {
+				if err := o.requireResources(); err != nil {
+					return err
+				}
+				p := promote.NewPromoteOptions(genericclioptions.IOStreams{Out: &lineWriter{logger: o.Log}, ErrOut: o.ErrOut})
+				p.Resources = o.Resources
+				p.Full = full
+				p.FilenameOptions = o.FilenameOptions
+				p.Namespace, p.EnforceNamespace = o.Namespace, o.EnforceNamespace
+				p.Builder = o.Builder
+				p.Promoter = polymorphichelpers.ObjectPromoterFn
+				p.ToPrinter = func(operation string) (printers.ResourcePrinter, error) {
+					p.PrintFlags.NamePrintFlags.Operation = operation
+					return p.PrintFlags.ToPrinter()
+				}
+				if err := p.Run(); err != nil {
+					return err
+				}
+
+				infos, err := o.resourceInfos()
+				if err != nil {
+					return err
+				}
+				return o.waitReady(infos)
+			})
+		},
+	}
+	o.addFlags(cmd)
+	cmd.Flags().BoolVar(&full, "full", full, "If true, promote to the end instead of the next step.")
+	return cmd
+}
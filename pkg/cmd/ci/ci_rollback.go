@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ci
+
+import (
+	krollout "github.com/openkruise/kruise-tools/pkg/cmd/rollout"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	rollbackLong = templates.LongDesc(`
+		Roll back workloads as 'kubectl-kruise rollout undo' does, and wait for them to be ready.`)
+
+	rollbackExample = templates.Examples(`
+		# Roll back cloneset demo to its previous revision
+		kubectl-kruise ci rollback cloneset/demo
+
+		# Roll back advanced statefulset demo to revision 3
+		kubectl-kruise ci rollback asts/demo --to-revision 3`)
+)
+
+// NewCmdCIRollback returns a Command instance for 'ci rollback' sub command
+func NewCmdCIRollback(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewCIOptions(streams)
+	toRevision := int64(0)
+
+	cmd := &cobra.Command{
+		Use:                   "rollback (-f FILENAME | TYPE NAME | TYPE/NAME) [--to-revision=REVISION]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Roll back workloads and wait for them to be ready"),
+		Long:                  rollbackLong,
+		Example:               rollbackExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.run(f, "rollback", args, func() error {
+				if err := o.requireResources(); err != nil {
+					return err
+				}
+				u := krollout.NewRolloutUndoOptions(genericclioptions.IOStreams{Out: &lineWriter{logger: o.Log}, ErrOut: o.ErrOut})
+				u.Resources = o.Resources
+				u.ToRevision = toRevision
+				u.FilenameOptions = o.FilenameOptions
+				u.Namespace, u.EnforceNamespace = o.Namespace, o.EnforceNamespace
+				u.Builder = o.Builder
+				u.RESTClientGetter = f
+				u.DryRunStrategy = cmdutil.DryRunNone
+				u.ToPrinter = func(operation string) (printers.ResourcePrinter, error) {
+					u.PrintFlags.NamePrintFlags.Operation = operation
+					return u.PrintFlags.ToPrinter()
+				}
+				if err := u.RunUndo(); err != nil {
+					return err
+				}
+
+				infos, err := o.resourceInfos()
+				if err != nil {
+					return err
+				}
+				return o.waitReady(infos)
+			})
+		},
+	}
+	o.addFlags(cmd)
+	cmd.Flags().Int64Var(&toRevision, "to-revision", toRevision, "The revision to roll back to, 0 means the previous revision.")
+	return cmd
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ci
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+)
+
+func TestLogger(t *testing.T) {
+	now := func() time.Time { return time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name          string
+		format        string
+		gitHubActions bool
+		log           func(l *Logger)
+		expected      string
+	}{
+		{
+			name:     "text",
+			format:   LogFormatText,
+			log:      func(l *Logger) { l.Info("applied", "resource", "cloneset/demo", "generation", 2) },
+			expected: "time=2022-03-01T10:00:00Z level=info step=deploy msg=applied generation=2 resource=cloneset/demo\n",
+		},
+		{
+			name:     "json",
+			format:   LogFormatJSON,
+			log:      func(l *Logger) { l.Info("applied", "resource", "cloneset/demo") },
+			expected: `{"level":"info","msg":"applied","resource":"cloneset/demo","step":"deploy","time":"2022-03-01T10:00:00Z"}` + "\n",
+		},
+		{
+			name:          "github actions error",
+			format:        LogFormatText,
+			gitHubActions: true,
+			log:           func(l *Logger) { l.Error(errors.New("not ready")) },
+			expected:      "time=2022-03-01T10:00:00Z level=error step=deploy msg=\"not ready\"\n::error title=kubectl-kruise ci deploy::not ready\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			test.log(&Logger{Format: test.format, Step: "deploy", GitHubActions: test.gitHubActions, Out: out, now: now})
+			if out.String() != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := &lineWriter{logger: &Logger{Format: LogFormatJSON, Step: "promote", Out: out, now: time.Now}}
+	fmt.Fprint(w, "cloneset.apps.kruise.io/a promoted\ncloneset.apps.kruise.io/b")
+	if bytes.Count(out.Bytes(), []byte("\n")) != 1 {
+		t.Fatalf("expected one entry before the end of the second line, got %q", out.String())
+	}
+	fmt.Fprint(w, " promoted\n")
+	if !bytes.Contains(out.Bytes(), []byte(`"msg":"cloneset.apps.kruise.io/b promoted"`)) {
+		t.Errorf("expected an entry for the second line, got %q", out.String())
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{err: nil, expected: 0},
+		{err: errors.New("forbidden"), expected: ExitFailed},
+		{err: invalidError{errors.New("no objects")}, expected: ExitInvalid},
+		{err: fmt.Errorf("%w after 1m0s", internalcmdutil.ErrReadyTimeout), expected: ExitTimeout},
+	}
+	for _, test := range tests {
+		if got := ExitCode(test.err); got != test.expected {
+			t.Errorf("expected exit code %d for %v, got %d", test.expected, test.err, got)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ci
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	verifyLong = templates.LongDesc(`
+		Wait for resources to be ready.
+
+		Workloads are ready when their roll out is complete, as reported by 'rollout status',
+		other resources when their controller observed their last generation.`)
+
+	verifyExample = templates.Examples(`
+		# Check that cloneset demo is ready within 5 minutes
+		kubectl-kruise ci verify cloneset/demo --timeout 5m
+
+		# Check that the resources of manifests/ are ready
+		kubectl-kruise ci verify -f manifests/ -R`)
+)
+
+// NewCmdCIVerify returns a Command instance for 'ci verify' sub command
+func NewCmdCIVerify(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewCIOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "verify (-f FILENAME | TYPE NAME | TYPE/NAME)",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Wait for resources to be ready"),
+		Long:                  verifyLong,
+		Example:               verifyExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.run(f, "verify", args, func() error {
+				if err := o.requireResources(); err != nil {
+					return err
+				}
+				infos, err := o.resourceInfos()
+				if err != nil {
+					return err
+				}
+				return o.waitReady(infos)
+			})
+		},
+	}
+	o.addFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// LogFormatText prints log entries as logfmt key=value pairs
+	LogFormatText = "text"
+	// LogFormatJSON prints log entries as JSON objects, one per line
+	LogFormatJSON = "json"
+)
+
+// Logger prints the structured log entries of a ci step.
+type Logger struct {
+	Format string
+	Step   string
+	// GitHubActions also prints errors as workflow commands, so that they are annotated in the run
+	GitHubActions bool
+	Out           io.Writer
+
+	now func() time.Time
+}
+
+// Info logs msg with the given key and value pairs.
+func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
+	l.log("info", msg, keysAndValues...)
+}
+
+// Error logs err with the given key and value pairs.
+func (l *Logger) Error(err error, keysAndValues ...interface{}) {
+	l.log("error", err.Error(), keysAndValues...)
+	if l.GitHubActions {
+		fmt.Fprintf(l.Out, "::error title=kubectl-kruise ci %s::%s\n", l.Step, strings.Replace(err.Error(), "\n", "%0A", -1))
+	}
+}
+
+func (l *Logger) log(level, msg string, keysAndValues ...interface{}) {
+	now := time.Now
+	if l.now != nil {
+		now = l.now
+	}
+	entry := map[string]interface{}{
+		"time":  now().UTC().Format(time.RFC3339),
+		"level": level,
+		"step":  l.Step,
+		"msg":   msg,
+	}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		entry[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+
+	if l.Format == LogFormatJSON {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			data = []byte(fmt.Sprintf(`{"level":"error","msg":%q}`, err.Error()))
+		}
+		fmt.Fprintln(l.Out, string(data))
+		return
+	}
+
+	// logfmt, with the common keys first
+	keys := []string{"time", "level", "step", "msg"}
+	var extra []string
+	for key := range entry {
+		switch key {
+		case "time", "level", "step", "msg":
+		default:
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+	var fields []string
+	for _, key := range append(keys, extra...) {
+		fields = append(fields, fmt.Sprintf("%s=%s", key, logfmtValue(entry[key])))
+	}
+	fmt.Fprintln(l.Out, strings.Join(fields, " "))
+}
+
+func logfmtValue(value interface{}) string {
+	s := fmt.Sprint(value)
+	if strings.ContainsAny(s, " \t\"=") || len(s) == 0 {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+// lineWriter logs each line written to it, such as the output of the commands a step reuses.
+type lineWriter struct {
+	logger *Logger
+	buf    bytes.Buffer
+}
+
+// Write implements io.Writer
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// keep the incomplete line for the next write
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			w.logger.Info(line)
+		}
+	}
+}
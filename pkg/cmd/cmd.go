@@ -18,20 +18,59 @@ package cmd
 
 import (
 	"flag"
+	"fmt"
 	"io"
 	"os"
 
+	"github.com/openkruise/kruise-tools/pkg/cmd/alias"
+	"github.com/openkruise/kruise-tools/pkg/cmd/apidocs"
+	"github.com/openkruise/kruise-tools/pkg/cmd/batchrelease"
+	"github.com/openkruise/kruise-tools/pkg/cmd/check"
+	"github.com/openkruise/kruise-tools/pkg/cmd/ci"
+	"github.com/openkruise/kruise-tools/pkg/cmd/codegen"
+	"github.com/openkruise/kruise-tools/pkg/cmd/debugsidecar"
+	kdelete "github.com/openkruise/kruise-tools/pkg/cmd/delete"
+	"github.com/openkruise/kruise-tools/pkg/cmd/dev"
+	"github.com/openkruise/kruise-tools/pkg/cmd/diffrevision"
+	"github.com/openkruise/kruise-tools/pkg/cmd/doctor"
+	"github.com/openkruise/kruise-tools/pkg/cmd/events"
 	cmdexec "github.com/openkruise/kruise-tools/pkg/cmd/exec"
 	"github.com/openkruise/kruise-tools/pkg/cmd/expose"
+	"github.com/openkruise/kruise-tools/pkg/cmd/initnamespace"
+	"github.com/openkruise/kruise-tools/pkg/cmd/lifecycle"
 	"github.com/openkruise/kruise-tools/pkg/cmd/migrate"
+	knew "github.com/openkruise/kruise-tools/pkg/cmd/new"
+	kpod "github.com/openkruise/kruise-tools/pkg/cmd/pod"
+	"github.com/openkruise/kruise-tools/pkg/cmd/promote"
+	"github.com/openkruise/kruise-tools/pkg/cmd/pullimage"
+	"github.com/openkruise/kruise-tools/pkg/cmd/recreate"
+	"github.com/openkruise/kruise-tools/pkg/cmd/release"
+	"github.com/openkruise/kruise-tools/pkg/cmd/repair"
+	"github.com/openkruise/kruise-tools/pkg/cmd/restarts"
+	"github.com/openkruise/kruise-tools/pkg/cmd/revision"
 	krollout "github.com/openkruise/kruise-tools/pkg/cmd/rollout"
+	"github.com/openkruise/kruise-tools/pkg/cmd/sandbox"
 	"github.com/openkruise/kruise-tools/pkg/cmd/scaledown"
 	kset "github.com/openkruise/kruise-tools/pkg/cmd/set"
+	"github.com/openkruise/kruise-tools/pkg/cmd/sidecarset"
+	"github.com/openkruise/kruise-tools/pkg/cmd/spread"
+	"github.com/openkruise/kruise-tools/pkg/cmd/tree"
+	"github.com/openkruise/kruise-tools/pkg/cmd/upgrade"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/config"
+	"github.com/openkruise/kruise-tools/pkg/conventions"
+	"github.com/openkruise/kruise-tools/pkg/cost"
+	"github.com/openkruise/kruise-tools/pkg/freeze"
+	"github.com/openkruise/kruise-tools/pkg/offline"
+	"github.com/openkruise/kruise-tools/pkg/policy"
+	"github.com/openkruise/kruise-tools/pkg/readonly"
+	"github.com/openkruise/kruise-tools/pkg/writeback"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/tools/clientcmd"
 	cliflag "k8s.io/component-base/cli/flag"
+	kubectlcmd "k8s.io/kubectl/pkg/cmd"
 	"k8s.io/kubectl/pkg/cmd/apiresources"
 	"k8s.io/kubectl/pkg/cmd/apply"
 	cmdconfig "k8s.io/kubectl/pkg/cmd/config"
@@ -314,6 +353,9 @@ func NewKubectlCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 		Long: templates.LongDesc(`
       kubectl-kruise controls the OpenKruise manager.
 
+      The kubectl commands it does not have, such as get, describe or logs, are run
+      as with kubectl.
+
       Find more information at:
             https://openkruise.io/`),
 		Run: runHelp,
@@ -342,15 +384,20 @@ func NewKubectlCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 	addProfilingFlags(flags)
 
 	flags.BoolVar(&warningsAsErrors, "warnings-as-errors", warningsAsErrors, "Treat warnings received from the server as errors and exit with a non-zero exit code")
+	offline.AddFlags(flags)
+	readonly.AddFlags(flags)
+	freeze.AddFlags(flags)
+	internalcmdutil.AddTableFlags(flags)
 
 	kubeConfigFlags := genericclioptions.NewConfigFlags(true).WithDeprecatedPasswordFlag()
 	kubeConfigFlags.AddFlags(flags)
 	matchVersionKubeConfigFlags := cmdutil.NewMatchVersionFlags(kubeConfigFlags)
 	matchVersionKubeConfigFlags.AddFlags(cmds.PersistentFlags())
+	policy.SetIdentity(func() policy.Identity { return policyIdentity(kubeConfigFlags) })
 
 	cmds.PersistentFlags().AddGoFlagSet(flag.CommandLine)
 
-	f := cmdutil.NewFactory(matchVersionKubeConfigFlags)
+	f := cmdutil.NewFactory(freeze.WithFreeze(readonly.WithReadOnly(internalcmdutil.WithCompiledKruiseVersions(internalcmdutil.WithKruiseShortNames(matchVersionKubeConfigFlags)))))
 
 	// Sending in 'nil' for the getLanguageFn() results in using
 	// the LANG environment variable.
@@ -362,20 +409,33 @@ func NewKubectlCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 	// From this point and forward we get warnings on flags that contain "_" separators
 	cmds.SetGlobalNormalizationFunc(cliflag.WarnWordSepNormalizeFunc)
 
-	ioStreams := genericclioptions.IOStreams{In: in, Out: out, ErrOut: err}
+	stableOut := internalcmdutil.NewStableOutputWriter(out)
+	ioStreams := genericclioptions.IOStreams{In: in, Out: stableOut, ErrOut: err}
+	readyStateStreams := internalcmdutil.NewReadyStateStreams(ioStreams)
 
 	groups := templates.CommandGroups{
 		{
 			Message: "Basic Commands:",
 			Commands: []*cobra.Command{
 				expose.NewCmdExposeService(f, ioStreams),
-				cmdWithShortOverwrite(scale.NewCmdScale(f, ioStreams), "Set a new size for a Deployment, ReplicaSet, CloneSet, or Advanced StatefulSet"),
+				internalcmdutil.WithCostEstimate(f, internalcmdutil.WithCapacityCheck(f, cmdWithShortOverwrite(scale.NewCmdScale(f, ioStreams), "Set a new size for a Deployment, ReplicaSet, CloneSet, or Advanced StatefulSet"), ioStreams), ioStreams),
+				kdelete.NewCmdDelete(f, ioStreams),
 			},
 		},
 		{
 			Message: "Troubleshooting and Debugging Commands:",
 			Commands: []*cobra.Command{
 				cmdexec.NewCmdExec(f, ioStreams),
+				recreate.NewCmdRecreate(f, ioStreams),
+				kpod.NewCmdPod(f, ioStreams),
+				restarts.NewCmdRestarts(f, ioStreams),
+				spread.NewCmdSpread(f, ioStreams),
+				lifecycle.NewCmdLifecycle(f, ioStreams),
+				events.NewCmdEvents(f, ioStreams),
+				diffrevision.NewCmdDiffRevision(f, ioStreams),
+				revision.NewCmdRevision(f, ioStreams),
+				tree.NewCmdTree(f, ioStreams),
+				debugsidecar.NewCmdDebugSidecar(f, ioStreams),
 			},
 		},
 
@@ -383,7 +443,7 @@ func NewKubectlCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 			Message: "CloneSet Commands:",
 			Commands: []*cobra.Command{
 				krollout.NewCmdRollout(f, ioStreams),
-				kset.NewCmdSet(f, ioStreams),
+				writeback.WithWriteBack(internalcmdutil.WithSummary(f, internalcmdutil.WithReadyState(f, kset.NewCmdSet(f, readyStateStreams.IOStreams), readyStateStreams), readyStateStreams), ioStreams.Out),
 				migrate.NewCmdMigrate(f, ioStreams),
 			},
 		},
@@ -391,7 +451,20 @@ func NewKubectlCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 			Message: "AdvancedStatefulSet Commands:",
 			Commands: []*cobra.Command{
 				krollout.NewCmdRollout(f, ioStreams),
-				kset.NewCmdSet(f, ioStreams),
+				writeback.WithWriteBack(internalcmdutil.WithSummary(f, internalcmdutil.WithReadyState(f, kset.NewCmdSet(f, readyStateStreams.IOStreams), readyStateStreams), readyStateStreams), ioStreams.Out),
+			},
+		},
+		{
+			Message: "SidecarSet Commands:",
+			Commands: []*cobra.Command{
+				sidecarset.NewCmdSidecarSet(f, ioStreams),
+			},
+		},
+		{
+			Message: "Kruise Rollouts Commands:",
+			Commands: []*cobra.Command{
+				batchrelease.NewCmdBatchRelease(f, ioStreams),
+				promote.NewCmdPromote(f, ioStreams),
 			},
 		},
 		{
@@ -403,11 +476,19 @@ func NewKubectlCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 		{
 			Message: "Advanced Commands:",
 			Commands: []*cobra.Command{
-				diff.NewCmdDiff(f, ioStreams),
-				apply.NewCmdApply("kubectl-kruise", f, ioStreams),
+				sidecarset.WithNamespaceGuard(f, diff.NewCmdDiff(f, ioStreams), false),
+				internalcmdutil.WithSummary(f, internalcmdutil.WithReadyState(f, sidecarset.WithNamespaceGuard(f, apply.NewCmdApply("kubectl-kruise", f, readyStateStreams.IOStreams), true), readyStateStreams), readyStateStreams),
 				patch.NewCmdPatch(f, ioStreams),
 				replace.NewCmdReplace(f, ioStreams),
 				wait.NewCmdWait(f, ioStreams),
+				pullimage.NewCmdPullImage(f, ioStreams),
+				ci.NewCmdCI(f, ioStreams),
+				sandbox.NewCmdSandbox(f, ioStreams),
+				initnamespace.NewCmdInitNamespace(f, ioStreams),
+				check.NewCmdCheck(f, ioStreams),
+				codegen.NewCmdCodegen(f, ioStreams),
+				knew.NewCmdNew(f, ioStreams),
+				repair.NewCmdRepair(f, ioStreams),
 				kustomize.NewCmdKustomize(ioStreams),
 			},
 		},
@@ -437,13 +518,23 @@ func NewKubectlCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 	}
 
 	cmds.AddCommand(alpha)
+	cmds.AddCommand(alias.NewCmdAlias(ioStreams))
 	cmds.AddCommand(cmdconfig.NewCmdConfig(f, clientcmd.NewDefaultPathOptions(), ioStreams))
+	plugin.ValidPluginFilenamePrefixes = []string{PluginPrefix}
 	cmds.AddCommand(plugin.NewCmdPlugin(f, ioStreams))
 	cmds.AddCommand(version.NewCmdVersion(f, ioStreams))
+	cmds.AddCommand(upgrade.NewCmdUpgrade(ioStreams))
+	cmds.AddCommand(doctor.NewCmdDoctor(ioStreams))
+	cmds.AddCommand(release.NewCmdRelease(ioStreams))
+	cmds.AddCommand(apidocs.NewCmdAPIDocs(ioStreams))
+	cmds.AddCommand(dev.NewCmdDev(ioStreams))
 	cmds.AddCommand(apiresources.NewCmdAPIVersions(f, ioStreams))
 	cmds.AddCommand(apiresources.NewCmdAPIResources(f, ioStreams))
 	cmds.AddCommand(options.NewCmdOptions(ioStreams.Out))
 
+	internalcmdutil.WithOutputTemplates(cmds)
+	internalcmdutil.WithStableOutput(cmds, stableOut)
+
 	return cmds
 }
 
@@ -460,9 +551,66 @@ func NewDefaultKubectlCommand() *cobra.Command {
 func NewDefaultKubectlCommandWithArgs(args []string, in io.Reader, out, errout io.Writer) *cobra.Command {
 	cmd := NewKubectlCommand(in, out, errout)
 
+	if len(args) > 1 {
+		cfg, cfgErr := config.Load(config.Path())
+		if cfgErr != nil {
+			fmt.Fprintf(errout, "Warning: hooks, aliases and policies are ignored and the changes they gate are refused: %v\n", cfgErr)
+			cfg = &config.Config{}
+		}
+		addHooks(cmd, cfg.Hooks, errout)
+		if cfgErr != nil {
+			// the commands that only read run with the defaults, the changes are not let through
+			// without the policies
+			policy.ConfigureError(cfgErr)
+		} else {
+			policy.Configure(cfg.Policy)
+		}
+		cost.Configure(cfg.Cost)
+		conventions.Configure(cfg.Conventions)
+
+		// only look for aliases and plugins if the specified command does not already exist
+		if _, _, err := cmd.Find(args[1:]); err != nil {
+			if expanded, ok := expandAlias(cmd, cfg.Aliases, args[1:]); ok {
+				cmd.SetArgs(expanded)
+				return cmd
+			}
+			if err := handlePluginCommand(cmd, kubectlcmd.NewDefaultPluginHandler([]string{PluginPrefix}), args[1:]); err != nil {
+				fmt.Fprintf(errout, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if upstream := newKubectlFallthroughCommand(args[1:], in, out, errout); upstream != nil {
+				addHooks(upstream, cfg.Hooks, errout)
+				return upstream
+			}
+		}
+	}
 	return cmd
 }
 
+// policyIdentity returns the user and the context of the changes, as given by flags or else by
+// the kubeconfig file.
+func policyIdentity(flags *genericclioptions.ConfigFlags) policy.Identity {
+	identity := policy.Identity{}
+	if flags.Context != nil {
+		identity.Context = *flags.Context
+	}
+	if flags.AuthInfoName != nil {
+		identity.User = *flags.AuthInfoName
+	}
+	if flags.Impersonate != nil {
+		identity.Impersonate = *flags.Impersonate
+	}
+	if raw, err := flags.ToRawKubeConfigLoader().RawConfig(); err == nil {
+		if len(identity.Context) == 0 {
+			identity.Context = raw.CurrentContext
+		}
+		if context, ok := raw.Contexts[identity.Context]; ok && len(identity.User) == 0 {
+			identity.User = context.AuthInfo
+		}
+	}
+	return identity
+}
+
 func cmdWithShortOverwrite(cmd *cobra.Command, short string) *cobra.Command {
 	cmd.Short = i18n.T(short)
 	return cmd
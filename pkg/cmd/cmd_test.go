@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+	"github.com/openkruise/kruise-tools/pkg/policy"
+)
+
+func TestInvalidConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kubectl-kruise.yaml")
+	if err := ioutil.WriteFile(path, []byte("policy:\n  rego: policies/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv(config.PathEnv, os.Getenv(config.PathEnv))
+	os.Setenv(config.PathEnv, path)
+	defer policy.Configure(nil)
+
+	errOut := &bytes.Buffer{}
+	cmd := NewDefaultKubectlCommandWithArgs([]string{"kubectl-kruise", "version", "--client"}, &bytes.Buffer{}, &bytes.Buffer{}, errOut)
+	if cmd == nil {
+		t.Fatal("expected the command to run with the defaults")
+	}
+	if !strings.HasPrefix(errOut.String(), "Warning: hooks, aliases and policies are ignored") {
+		t.Errorf("expected a warning, got %q", errOut.String())
+	}
+	if err := policy.Check([]byte(`{"spec":{"replicas":1}}`), []byte(`{"spec":{"replicas":2}}`)); err == nil {
+		t.Errorf("expected the changes to be refused without the policies")
+	}
+}
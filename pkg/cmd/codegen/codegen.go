@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package codegen
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	codegenLong = templates.LongDesc(`
+		Generate code that does with the Kruise clients what was done with kubectl-kruise.`)
+
+	codegenExample = templates.Examples(`
+		# Print a Go program doing the last recorded change of cloneset demo
+		kubectl-kruise codegen snippet cloneset/demo --lang go`)
+)
+
+// NewCmdCodegen returns a Command instance for 'codegen' command
+func NewCmdCodegen(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "codegen SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Generate code using the Kruise clients"),
+		Long:                  codegenLong,
+		Example:               codegenExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(NewCmdSnippet(f, streams))
+	return cmd
+}
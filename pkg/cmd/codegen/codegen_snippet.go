@@ -0,0 +1,370 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"text/template"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// LangGo is the language of the Go snippets
+const LangGo = "go"
+
+var (
+	snippetLong = templates.LongDesc(i18n.T(`
+		Print a ready-to-compile program doing, with the kruise-api clientset, the last change
+		recorded on a CloneSet, Advanced StatefulSet or Advanced DaemonSet, to help moving from
+		experiments with kubectl-kruise to controllers.
+
+		The last change is the change cause recorded by running the command with --record. The
+		changes of set image, set partition and scale are translated to the fields they set,
+		with the current values of the workload; the program of any other change gets and
+		updates the workload, with a comment where the change is to be made.`))
+
+	snippetExample = templates.Examples(i18n.T(`
+		# Print a Go program setting the images set by the last command recorded on cloneset demo
+		kubectl-kruise set image cloneset/demo app=app:1.1 --record
+		kubectl-kruise codegen snippet cloneset/demo --lang go > main.go`))
+)
+
+// SnippetOptions holds the options for 'codegen snippet' sub command
+type SnippetOptions struct {
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+	Lang             string
+
+	Builder func() *resource.Builder
+
+	genericclioptions.IOStreams
+}
+
+// NewSnippetOptions returns an initialized SnippetOptions instance
+func NewSnippetOptions(streams genericclioptions.IOStreams) *SnippetOptions {
+	return &SnippetOptions{
+		Lang:      LangGo,
+		IOStreams: streams,
+	}
+}
+
+// NewCmdSnippet returns a Command instance for 'codegen snippet' sub command
+func NewCmdSnippet(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewSnippetOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "snippet (TYPE NAME | TYPE/NAME) [--lang=go]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print a program doing the last recorded change of a workload"),
+		Long:                  snippetLong,
+		Example:               snippetExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Lang, "lang", o.Lang, "The language of the snippet, only go is supported.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *SnippetOptions) Complete(f cmdutil.Factory, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+	return nil
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *SnippetOptions) Validate() error {
+	if len(o.Resources) == 0 {
+		return fmt.Errorf("required resource not specified")
+	}
+	if o.Lang != LangGo {
+		return fmt.Errorf("unsupported --lang %q, only %s is supported", o.Lang, LangGo)
+	}
+	return nil
+}
+
+// Run performs the execution of 'codegen snippet' sub command
+func (o *SnippetOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return err
+	}
+	if len(infos) != 1 {
+		return fmt.Errorf("a snippet is generated for exactly one workload, got %d", len(infos))
+	}
+
+	snippet, err := GoSnippet(infos[0].Object)
+	if err != nil {
+		return fmt.Errorf("%s %v", infos[0].ObjectName(), err)
+	}
+	_, err = o.Out.Write(snippet)
+	return err
+}
+
+// goSnippet is the data of goSnippetTemplate
+type goSnippet struct {
+	Cause      string
+	Kind       string
+	Version    string
+	Resource   string
+	Namespace  string
+	Name       string
+	Imports    []string
+	Statements []string
+}
+
+var goSnippetTemplate = template.Must(template.New("snippet").Parse(`package main
+
+import (
+	"context"
+	"fmt"
+
+	kruiseclientset "github.com/openkruise/kruise-api/client/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+
+// main does the change recorded on {{.Kind}} {{.Namespace}}/{{.Name}}: {{.Cause}}
+func main() {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		panic(err)
+	}
+	client := kruiseclientset.NewForConfigOrDie(config)
+
+	// retry on conflicts with the updates of the controllers
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := client.{{.Version}}().{{.Resource}}({{printf "%q" .Namespace}}).Get(context.TODO(), {{printf "%q" .Name}}, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+{{range .Statements}}
+		{{.}}
+{{- end}}
+
+		_, err = client.{{.Version}}().{{.Resource}}({{printf "%q" .Namespace}}).Update(context.TODO(), obj, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println({{printf "%q" .Kind}}, {{printf "%q" .Name}}, "updated")
+}
+`))
+
+// GoSnippet returns a gofmt-ed Go program doing the last change recorded on obj with the
+// kruise-api clientset, with the values of the fields of obj.
+func GoSnippet(obj runtime.Object) ([]byte, error) {
+	data := goSnippet{}
+	switch obj.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		data.Kind, data.Version, data.Resource = "CloneSet", "AppsV1alpha1", "CloneSets"
+	case *kruiseappsv1beta1.StatefulSet:
+		data.Kind, data.Version, data.Resource = "StatefulSet", "AppsV1beta1", "StatefulSets"
+	case *kruiseappsv1alpha1.DaemonSet:
+		data.Kind, data.Version, data.Resource = "DaemonSet", "AppsV1alpha1", "DaemonSets"
+	default:
+		return nil, fmt.Errorf("is not a CloneSet, Advanced StatefulSet or Advanced DaemonSet")
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	data.Namespace, data.Name = accessor.GetNamespace(), accessor.GetName()
+	data.Cause = accessor.GetAnnotations()[polymorphichelpers.ChangeCauseAnnotation]
+	if len(data.Cause) == 0 {
+		return nil, fmt.Errorf("has no recorded change, run the command changing it with --record")
+	}
+	// keep the change cause on the line of the comment
+	data.Cause = strings.Join(strings.Fields(data.Cause), " ")
+
+	action, args := recordedAction(data.Cause)
+	switch action {
+	case "set image":
+		data.Statements, err = imageStatements(obj, args)
+	case "set partition":
+		data.Statements, data.Imports = partitionStatements(obj)
+	case "scale":
+		data.Statements, err = replicasStatements(obj)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data.Statements) == 0 {
+		data.Statements = []string{"// TODO: change obj as recorded"}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := goSnippetTemplate.Execute(buf, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// recordedAction returns the kubectl-kruise command of the change cause, e.g. "set image", and
+// its arguments, or an empty action if the change was made by another command.
+func recordedAction(cause string) (string, []string) {
+	// drop the identities suffixed by ToRecorder
+	if i := strings.Index(cause, " [impersonated "); i >= 0 {
+		cause = cause[:i]
+	}
+	fields := strings.Fields(cause)
+	for i, field := range fields {
+		switch {
+		case field == "scale":
+			return field, fields[i+1:]
+		case field == "set" && i+1 < len(fields):
+			return field + " " + fields[i+1], fields[i+2:]
+		}
+	}
+	return "", nil
+}
+
+// imageStatements returns the statements setting the images of the containers named in args to
+// their images in obj.
+func imageStatements(obj runtime.Object, args []string) ([]string, error) {
+	names := map[string]bool{}
+	for _, arg := range args {
+		if kv := strings.SplitN(arg, "=", 2); len(kv) == 2 && !strings.HasPrefix(arg, "-") {
+			names[kv[0]] = true
+		}
+	}
+
+	var statements []string
+	_, err := polymorphichelpers.UpdatePodSpecForObjectFn(obj, func(spec *corev1.PodSpec) error {
+		for _, containers := range []struct {
+			field      string
+			containers []corev1.Container
+		}{{"InitContainers", spec.InitContainers}, {"Containers", spec.Containers}} {
+			var images []string
+			for _, c := range containers.containers {
+				if names[c.Name] || names["*"] {
+					images = append(images, fmt.Sprintf("%q: %q,", c.Name, c.Image))
+				}
+			}
+			if len(images) == 0 {
+				continue
+			}
+			sort.Strings(images)
+			statements = append(statements,
+				fmt.Sprintf("for i, c := range obj.Spec.Template.Spec.%s {", containers.field),
+				"if image, ok := map[string]string{"+strings.Join(images, " ")+"}[c.Name]; ok {",
+				fmt.Sprintf("obj.Spec.Template.Spec.%s[i].Image = image", containers.field),
+				"}",
+				"}",
+			)
+		}
+		return nil
+	})
+	return statements, err
+}
+
+// partitionStatements returns the statements setting the partition of obj to its value, and
+// the imports they need.
+func partitionStatements(obj runtime.Object) ([]string, []string) {
+	switch o := obj.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		if o.Spec.UpdateStrategy.Partition == nil {
+			return []string{"obj.Spec.UpdateStrategy.Partition = nil"}, nil
+		}
+		value := fmt.Sprintf("intstr.FromInt(%d)", o.Spec.UpdateStrategy.Partition.IntValue())
+		if o.Spec.UpdateStrategy.Partition.Type == intstr.String {
+			value = fmt.Sprintf("intstr.FromString(%q)", o.Spec.UpdateStrategy.Partition.StrVal)
+		}
+		return []string{
+			"partition := " + value,
+			"obj.Spec.UpdateStrategy.Partition = &partition",
+		}, []string{`"k8s.io/apimachinery/pkg/util/intstr"`}
+	case *kruiseappsv1beta1.StatefulSet:
+		if o.Spec.UpdateStrategy.RollingUpdate == nil || o.Spec.UpdateStrategy.RollingUpdate.Partition == nil {
+			return []string{"obj.Spec.UpdateStrategy.RollingUpdate.Partition = nil"}, nil
+		}
+		return int32Statements("partition", "obj.Spec.UpdateStrategy.RollingUpdate.Partition", *o.Spec.UpdateStrategy.RollingUpdate.Partition), nil
+	case *kruiseappsv1alpha1.DaemonSet:
+		if o.Spec.UpdateStrategy.RollingUpdate == nil || o.Spec.UpdateStrategy.RollingUpdate.Partition == nil {
+			return []string{"obj.Spec.UpdateStrategy.RollingUpdate.Partition = nil"}, nil
+		}
+		return int32Statements("partition", "obj.Spec.UpdateStrategy.RollingUpdate.Partition", *o.Spec.UpdateStrategy.RollingUpdate.Partition), nil
+	}
+	return nil, nil
+}
+
+// replicasStatements returns the statements setting the replicas of obj to its value.
+func replicasStatements(obj runtime.Object) ([]string, error) {
+	switch o := obj.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		if o.Spec.Replicas != nil {
+			return int32Statements("replicas", "obj.Spec.Replicas", *o.Spec.Replicas), nil
+		}
+	case *kruiseappsv1beta1.StatefulSet:
+		if o.Spec.Replicas != nil {
+			return int32Statements("replicas", "obj.Spec.Replicas", *o.Spec.Replicas), nil
+		}
+	case *kruiseappsv1alpha1.DaemonSet:
+		return nil, fmt.Errorf("has no replicas to scale")
+	}
+	return nil, nil
+}
+
+// int32Statements returns the statements setting the *int32 field to value.
+func int32Statements(variable, field string, value int32) []string {
+	return []string{
+		fmt.Sprintf("%s := int32(%d)", variable, value),
+		fmt.Sprintf("%s = &%s", field, variable),
+	}
+}
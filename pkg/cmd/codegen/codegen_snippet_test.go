@@ -0,0 +1,172 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const expectedCloneSetSnippet = `package main
+
+import (
+	"context"
+	"fmt"
+
+	kruiseclientset "github.com/openkruise/kruise-api/client/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
+)
+
+// main does the change recorded on CloneSet default/demo: kubectl-kruise set image cloneset/demo app=app:1.1 sidecar=envoy:1.22 --record=true
+func main() {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		panic(err)
+	}
+	client := kruiseclientset.NewForConfigOrDie(config)
+
+	// retry on conflicts with the updates of the controllers
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := client.AppsV1alpha1().CloneSets("default").Get(context.TODO(), "demo", metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		for i, c := range obj.Spec.Template.Spec.Containers {
+			if image, ok := map[string]string{"app": "app:1.1", "sidecar": "envoy:1.22"}[c.Name]; ok {
+				obj.Spec.Template.Spec.Containers[i].Image = image
+			}
+		}
+
+		_, err = client.AppsV1alpha1().CloneSets("default").Update(context.TODO(), obj, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("CloneSet", "demo", "updated")
+}
+`
+
+func withCause(cause string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:   "default",
+		Name:        "demo",
+		Annotations: map[string]string{"kubernetes.io/change-cause": cause},
+	}
+}
+
+func TestGoSnippet(t *testing.T) {
+	replicas, partition := int32(5), intstr.FromString("40%")
+	template := corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init", Image: "init:1.0"}},
+		Containers:     []corev1.Container{{Name: "app", Image: "app:1.1"}, {Name: "sidecar", Image: "envoy:1.22"}},
+	}}
+
+	snippet, err := GoSnippet(&kruiseappsv1alpha1.CloneSet{
+		ObjectMeta: withCause("kubectl-kruise set image cloneset/demo app=app:1.1 sidecar=envoy:1.22 --record=true"),
+		Spec:       kruiseappsv1alpha1.CloneSetSpec{Template: template},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(snippet) != expectedCloneSetSnippet {
+		t.Errorf("expected\n%s\ngot\n%s", expectedCloneSetSnippet, snippet)
+	}
+
+	tests := []struct {
+		name     string
+		obj      runtime.Object
+		expected []string
+	}{
+		{
+			name: "set image of all containers",
+			obj: &kruiseappsv1alpha1.CloneSet{
+				ObjectMeta: withCause("kubectl kruise set image cloneset/demo *=app:1.1 [impersonated user=alice; actual user=bob]"),
+				Spec:       kruiseappsv1alpha1.CloneSetSpec{Template: template},
+			},
+			expected: []string{
+				`map[string]string{"init": "init:1.0"}[c.Name]`,
+				`obj.Spec.Template.Spec.InitContainers[i].Image = image`,
+				`map[string]string{"app": "app:1.1", "sidecar": "envoy:1.22"}[c.Name]`,
+			},
+		},
+		{
+			name: "set partition of cloneset",
+			obj: &kruiseappsv1alpha1.CloneSet{
+				ObjectMeta: withCause("kubectl-kruise set partition cloneset/demo --updated=60%"),
+				Spec:       kruiseappsv1alpha1.CloneSetSpec{UpdateStrategy: kruiseappsv1alpha1.CloneSetUpdateStrategy{Partition: &partition}},
+			},
+			expected: []string{
+				`"k8s.io/apimachinery/pkg/util/intstr"`,
+				`partition := intstr.FromString("40%")`,
+				`obj.Spec.UpdateStrategy.Partition = &partition`,
+			},
+		},
+		{
+			name: "scale advanced statefulset",
+			obj: &kruiseappsv1beta1.StatefulSet{
+				ObjectMeta: withCause("kubectl-kruise scale asts/demo --replicas=5"),
+				Spec:       kruiseappsv1beta1.StatefulSetSpec{Replicas: &replicas},
+			},
+			expected: []string{
+				`client.AppsV1beta1().StatefulSets("default").Get(`,
+				`replicas := int32(5)`,
+				`obj.Spec.Replicas = &replicas`,
+			},
+		},
+		{
+			name: "other change of advanced daemonset",
+			obj: &kruiseappsv1alpha1.DaemonSet{
+				ObjectMeta: withCause("kubectl-kruise set env daemonset/demo LEVEL=debug"),
+			},
+			expected: []string{
+				`client.AppsV1alpha1().DaemonSets("default").Update(`,
+				`// TODO: change obj as recorded`,
+			},
+		},
+	}
+	for _, test := range tests {
+		snippet, err := GoSnippet(test.obj)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		for _, expected := range test.expected {
+			if !strings.Contains(string(snippet), expected) {
+				t.Errorf("%s: expected %s in\n%s", test.name, expected, snippet)
+			}
+		}
+	}
+
+	if _, err := GoSnippet(&kruiseappsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Name: "demo"}}); err == nil || !strings.Contains(err.Error(), "--record") {
+		t.Errorf("expected a no recorded change error, got %v", err)
+	}
+	if _, err := GoSnippet(&appsv1.Deployment{ObjectMeta: withCause("kubectl-kruise scale deployment/demo --replicas=5")}); err == nil {
+		t.Errorf("expected an error generating a snippet for a deployment")
+	}
+}
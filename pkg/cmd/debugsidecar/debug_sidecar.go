@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugsidecar
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	// DebugSidecarLabel is set on the SidecarSets of the debug sidecars, and on the objects
+	// created to delete them, to the namespace and the name of the workload, e.g. default.demo.
+	DebugSidecarLabel = "kubectl.kruise.io/debug-sidecar"
+	// ExpireAtAnnotation is set on the SidecarSets of the debug sidecars to the time they are
+	// deleted at, in RFC 3339.
+	ExpireAtAnnotation = "kubectl.kruise.io/debug-sidecar-expire-at"
+)
+
+var (
+	debugSidecarLong = templates.LongDesc(`
+		Inject debug sidecars into the pods of a workload with SidecarSets matching only its
+		pods, and remove them.`)
+
+	debugSidecarExample = templates.Examples(`
+		# Inject a netshoot sidecar into the pods of cloneset demo for an hour
+		kubectl-kruise debug-sidecar add cloneset/demo --image nicolaka/netshoot --ttl 1h
+
+		# Remove the debug sidecar of cloneset demo
+		kubectl-kruise debug-sidecar remove cloneset/demo`)
+)
+
+// NewCmdDebugSidecar returns a Command instance for 'debug-sidecar' command
+func NewCmdDebugSidecar(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "debug-sidecar SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Inject debug sidecars into the pods of workloads"),
+		Long:                  debugSidecarLong,
+		Example:               debugSidecarExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(NewCmdDebugSidecarAdd(f, streams))
+	cmd.AddCommand(NewCmdDebugSidecarRemove(f, streams))
+	return cmd
+}
+
+// SidecarSetName returns the name of the SidecarSet of the debug sidecar of a workload. SidecarSets
+// are cluster-scoped, so it includes the namespace of the workload.
+func SidecarSetName(namespace, name string) string {
+	return fmt.Sprintf("debug-%s-%s", namespace, name)
+}
+
+// workloadLabel returns the value of DebugSidecarLabel for a workload.
+func workloadLabel(namespace, name string) string {
+	return namespace + "." + name
+}
+
+// workload returns the workload named by args, which must be a single resource.
+func workload(f cmdutil.Factory, cmd *cobra.Command, args []string) (*resource.Info, error) {
+	if len(args) == 0 {
+		return nil, cmdutil.UsageErrorf(cmd, "a workload must be given as TYPE/NAME or TYPE NAME")
+	}
+	namespace, _, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := f.NewBuilder().
+		Unstructured().
+		NamespaceParam(namespace).DefaultNamespace().
+		ResourceTypeOrNameArgs(true, args...).
+		SingleResourceType().
+		Flatten().
+		Do().Infos()
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) != 1 {
+		return nil, fmt.Errorf("expected a single workload, got %d", len(infos))
+	}
+	if _, found, _ := unstructured.NestedMap(infos[0].Object.(*unstructured.Unstructured).Object, "spec", "template"); !found {
+		return nil, fmt.Errorf("%s has no pod template", infos[0].ObjectName())
+	}
+	return infos[0], nil
+}
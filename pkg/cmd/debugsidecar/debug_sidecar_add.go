@@ -0,0 +1,303 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugsidecar
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	addLong = templates.LongDesc(`
+		Inject a debug sidecar into the pods of a workload, and remove it after --ttl.
+
+		A SidecarSet is created in the namespace of the workload with its selector, so that it
+		matches only its pods. The sidecar is hot-upgraded: its image can be changed in the
+		SidecarSet without restarting the pods, with --empty-image standing in during the
+		upgrade. SidecarSets inject sidecars into the pods when they are created, so the
+		running pods must be recreated to get the sidecar, e.g. with kubectl-kruise rollout
+		restart.
+
+		The SidecarSet is deleted by a BroadcastJob of a single pod in the namespace of the
+		workload, which waits for the TTL and deletes it with the permission granted by a
+		ClusterRole and a ClusterRoleBinding. They are owned by the SidecarSet, so that nothing
+		is left behind once it is deleted. The SidecarSet is labeled ` + DebugSidecarLabel + `
+		and annotated with the time it expires at.`)
+
+	addExample = templates.Examples(`
+		# Inject a netshoot sidecar into the pods of cloneset demo for an hour
+		kubectl-kruise debug-sidecar add cloneset/demo --image nicolaka/netshoot --ttl 1h
+
+		# Inject a busybox sidecar named tools into the pods of deployment web for 30 minutes
+		kubectl-kruise debug-sidecar add deployment web --image busybox --container tools --ttl 30m`)
+)
+
+// AddOptions holds the command-line options for 'debug-sidecar add' sub command
+type AddOptions struct {
+	Image         string
+	EmptyImage    string
+	ContainerName string
+	TTL           time.Duration
+	CleanupImage  string
+
+	Workload *resource.Info
+
+	Client       kubernetes.Interface
+	KruiseClient kruiseclientsets.Interface
+
+	genericclioptions.IOStreams
+}
+
+// NewAddOptions returns an initialized AddOptions instance
+func NewAddOptions(streams genericclioptions.IOStreams) *AddOptions {
+	return &AddOptions{
+		EmptyImage:    "busybox:latest",
+		ContainerName: "debug",
+		TTL:           time.Hour,
+		CleanupImage:  "bitnami/kubectl:latest",
+		IOStreams:     streams,
+	}
+}
+
+// NewCmdDebugSidecarAdd returns a Command instance for 'debug-sidecar add' sub command
+func NewCmdDebugSidecarAdd(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewAddOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "add (TYPE NAME | TYPE/NAME) --image IMAGE [--ttl DURATION]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Inject a debug sidecar into the pods of a workload"),
+		Long:                  addLong,
+		Example:               addExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Image, "image", o.Image, "The image of the debug sidecar.")
+	cmd.Flags().StringVar(&o.EmptyImage, "empty-image", o.EmptyImage, "The image standing in for the sidecar during its hot upgrades, which must have sleep.")
+	cmd.Flags().StringVarP(&o.ContainerName, "container", "c", o.ContainerName, "The name of the debug sidecar.")
+	cmd.Flags().DurationVar(&o.TTL, "ttl", o.TTL, "The time after which the SidecarSet of the debug sidecar is deleted.")
+	cmd.Flags().StringVar(&o.CleanupImage, "cleanup-image", o.CleanupImage, "The image of the pod deleting the SidecarSet, which must have kubectl and sh.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *AddOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+	o.Workload, err = workload(f, cmd, args)
+	if err != nil {
+		return err
+	}
+
+	clientConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.Client, err = kubernetes.NewForConfig(clientConfig)
+	if err != nil {
+		return err
+	}
+	o.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig)
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *AddOptions) Validate() error {
+	if len(o.Image) == 0 {
+		return fmt.Errorf("the image of the debug sidecar must be given with --image")
+	}
+	if len(o.EmptyImage) == 0 {
+		return fmt.Errorf("--empty-image must not be empty, hot upgrades need it")
+	}
+	if errs := validation.IsDNS1123Label(o.ContainerName); len(errs) > 0 {
+		return fmt.Errorf("invalid --container %q: %s", o.ContainerName, strings.Join(errs, ", "))
+	}
+	if o.TTL < time.Minute {
+		return fmt.Errorf("invalid --ttl %s, must be at least 1m", o.TTL)
+	}
+	if o.Workload != nil {
+		if errs := validation.IsDNS1123Subdomain(SidecarSetName(o.Workload.Namespace, o.Workload.Name)); len(errs) > 0 {
+			return fmt.Errorf("the name of the SidecarSet of %s is invalid: %s", o.Workload.ObjectName(), strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// Run performs the execution of 'debug-sidecar add' sub command
+func (o *AddOptions) Run() error {
+	expireAt := time.Now().Add(o.TTL).UTC()
+	sidecarSet, err := DebugSidecarSet(o.Workload.Object.(*unstructured.Unstructured), o.ContainerName, o.Image, o.EmptyImage, o.TTL)
+	if err != nil {
+		return err
+	}
+	sidecarSet.Annotations = map[string]string{ExpireAtAnnotation: expireAt.Format(time.RFC3339)}
+
+	sidecarSet, err = o.KruiseClient.AppsV1alpha1().SidecarSets().Create(context.TODO(), sidecarSet, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	if err := o.scheduleCleanup(sidecarSet); err != nil {
+		// without cleanup, the debug sidecar would never be removed
+		_ = o.KruiseClient.AppsV1alpha1().SidecarSets().Delete(context.TODO(), sidecarSet.Name, metav1.DeleteOptions{})
+		return fmt.Errorf("failed to schedule the deletion of sidecarset %s: %v", sidecarSet.Name, err)
+	}
+	fmt.Fprintf(o.Out, "sidecarset.apps.kruise.io/%s created, deleted at %s\n", sidecarSet.Name, expireAt.Format(time.RFC3339))
+	fmt.Fprintf(o.Out, "the debug sidecar %s is injected into the pods of %s created from now on\n", o.ContainerName, o.Workload.ObjectName())
+	return nil
+}
+
+// DebugSidecarSet returns the SidecarSet injecting the debug sidecar into the pods of workload,
+// which sleeps for ttl.
+func DebugSidecarSet(workload *unstructured.Unstructured, container, image, emptyImage string, ttl time.Duration) (*kruiseappsv1alpha1.SidecarSet, error) {
+	selector := &metav1.LabelSelector{}
+	content, found, err := unstructured.NestedMap(workload.Object, "spec", "selector")
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, selector); err != nil {
+			return nil, err
+		}
+	}
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		// an empty selector would inject the sidecar into all the pods of the namespace
+		return nil, fmt.Errorf("%s/%s has no selector to match its pods", workload.GetKind(), workload.GetName())
+	}
+
+	return &kruiseappsv1alpha1.SidecarSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   SidecarSetName(workload.GetNamespace(), workload.GetName()),
+			Labels: map[string]string{DebugSidecarLabel: workloadLabel(workload.GetNamespace(), workload.GetName())},
+		},
+		Spec: kruiseappsv1alpha1.SidecarSetSpec{
+			Selector:  selector,
+			Namespace: workload.GetNamespace(),
+			Containers: []kruiseappsv1alpha1.SidecarContainer{{
+				Container: corev1.Container{
+					Name:    container,
+					Image:   image,
+					Command: []string{"sleep", strconv.FormatInt(int64(ttl.Seconds()), 10)},
+				},
+				PodInjectPolicy: kruiseappsv1alpha1.AfterAppContainerType,
+				UpgradeStrategy: kruiseappsv1alpha1.SidecarContainerUpgradeStrategy{
+					UpgradeType:          kruiseappsv1alpha1.SidecarContainerHotUpgrade,
+					HotUpgradeEmptyImage: emptyImage,
+				},
+			}},
+		},
+	}, nil
+}
+
+// scheduleCleanup creates the objects deleting sidecarSet after the TTL.
+func (o *AddOptions) scheduleCleanup(sidecarSet *kruiseappsv1alpha1.SidecarSet) error {
+	account, role, binding, job := CleanupObjects(sidecarSet, o.TTL, o.CleanupImage)
+	if _, err := o.Client.CoreV1().ServiceAccounts(account.Namespace).Create(context.TODO(), account, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	if _, err := o.Client.RbacV1().ClusterRoles().Create(context.TODO(), role, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	if _, err := o.Client.RbacV1().ClusterRoleBindings().Create(context.TODO(), binding, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	_, err := o.KruiseClient.AppsV1alpha1().BroadcastJobs(job.Namespace).Create(context.TODO(), job, metav1.CreateOptions{})
+	return err
+}
+
+// CleanupObjects returns the objects deleting sidecarSet after ttl, in the namespace of its
+// workload: the service account of the pod, the cluster role and binding allowing it to delete
+// the SidecarSet, and the BroadcastJob running the pod. They are all owned by the SidecarSet, so
+// that they are garbage collected with it.
+func CleanupObjects(sidecarSet *kruiseappsv1alpha1.SidecarSet, ttl time.Duration, image string) (*corev1.ServiceAccount, *rbacv1.ClusterRole, *rbacv1.ClusterRoleBinding, *kruiseappsv1alpha1.BroadcastJob) {
+	labels := map[string]string{DebugSidecarLabel: sidecarSet.Labels[DebugSidecarLabel]}
+	owner := metav1.OwnerReference{
+		APIVersion: kruiseappsv1alpha1.SchemeGroupVersion.String(),
+		Kind:       "SidecarSet",
+		Name:       sidecarSet.Name,
+		UID:        sidecarSet.UID,
+	}
+	meta := metav1.ObjectMeta{
+		Namespace:       sidecarSet.Spec.Namespace,
+		Name:            sidecarSet.Name + "-cleanup",
+		Labels:          labels,
+		OwnerReferences: []metav1.OwnerReference{owner},
+	}
+	clusterMeta := *meta.DeepCopy()
+	clusterMeta.Namespace = ""
+
+	account := &corev1.ServiceAccount{ObjectMeta: meta}
+	role := &rbacv1.ClusterRole{
+		ObjectMeta: clusterMeta,
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups:     []string{kruiseappsv1alpha1.GroupVersion.Group},
+			Resources:     []string{"sidecarsets"},
+			ResourceNames: []string{sidecarSet.Name},
+			Verbs:         []string{"get", "delete"},
+		}},
+	}
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: clusterMeta,
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: meta.Name},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: meta.Namespace, Name: meta.Name}},
+	}
+
+	parallelism := intstr.FromInt(1)
+	job := &kruiseappsv1alpha1.BroadcastJob{
+		ObjectMeta: meta,
+		Spec: kruiseappsv1alpha1.BroadcastJobSpec{
+			// a single pod, on any node, is enough to delete the SidecarSet
+			Parallelism: &parallelism,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: meta.Name,
+					RestartPolicy:      corev1.RestartPolicyOnFailure,
+					Containers: []corev1.Container{{
+						Name:    "cleanup",
+						Image:   image,
+						Command: []string{"/bin/sh", "-c", fmt.Sprintf("sleep %d && kubectl delete sidecarsets.apps.kruise.io %s --wait=false", int64(ttl.Seconds()), sidecarSet.Name)},
+					}},
+				},
+			},
+			CompletionPolicy: kruiseappsv1alpha1.CompletionPolicy{Type: kruiseappsv1alpha1.Never},
+		},
+	}
+	return account, role, binding, job
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugsidecar
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newWorkload(selector map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.kruise.io/v1alpha1",
+		"kind":       "CloneSet",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "demo"},
+		"spec":       map[string]interface{}{"template": map[string]interface{}{}},
+	}}
+	if selector != nil {
+		_ = unstructured.SetNestedMap(u.Object, selector, "spec", "selector")
+	}
+	return u
+}
+
+func TestDebugSidecarSet(t *testing.T) {
+	workload := newWorkload(map[string]interface{}{"matchLabels": map[string]interface{}{"app": "demo"}})
+	sidecarSet, err := DebugSidecarSet(workload, "debug", "nicolaka/netshoot", "busybox:latest", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sidecarSet.Name != "debug-default-demo" || sidecarSet.Labels[DebugSidecarLabel] != "default.demo" {
+		t.Errorf("unexpected metadata %+v", sidecarSet.ObjectMeta)
+	}
+	if sidecarSet.Spec.Namespace != "default" || sidecarSet.Spec.Selector.MatchLabels["app"] != "demo" {
+		t.Errorf("expected the SidecarSet to match only the pods of the workload, got %+v", sidecarSet.Spec)
+	}
+	container := sidecarSet.Spec.Containers[0]
+	if container.Image != "nicolaka/netshoot" || strings.Join(container.Command, " ") != "sleep 3600" {
+		t.Errorf("unexpected container %+v", container.Container)
+	}
+	if container.UpgradeStrategy.UpgradeType != kruiseappsv1alpha1.SidecarContainerHotUpgrade || container.UpgradeStrategy.HotUpgradeEmptyImage != "busybox:latest" {
+		t.Errorf("expected a hot upgraded sidecar, got %+v", container.UpgradeStrategy)
+	}
+
+	if _, err := DebugSidecarSet(newWorkload(nil), "debug", "nicolaka/netshoot", "busybox:latest", time.Hour); err == nil {
+		t.Errorf("expected an error for a workload without selector")
+	}
+	if _, err := DebugSidecarSet(newWorkload(map[string]interface{}{}), "debug", "nicolaka/netshoot", "busybox:latest", time.Hour); err == nil {
+		t.Errorf("expected an error for a workload with an empty selector")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   func(o *AddOptions)
+		expectErr string
+	}{
+		{name: "valid", options: func(o *AddOptions) {}},
+		{name: "no image", options: func(o *AddOptions) { o.Image = "" }, expectErr: "--image"},
+		{name: "no empty image", options: func(o *AddOptions) { o.EmptyImage = "" }, expectErr: "--empty-image"},
+		{name: "invalid container", options: func(o *AddOptions) { o.ContainerName = "Debug_1" }, expectErr: "invalid --container"},
+		{name: "short ttl", options: func(o *AddOptions) { o.TTL = 30 * time.Second }, expectErr: "invalid --ttl"},
+	}
+	for _, test := range tests {
+		o := NewAddOptions(genericclioptions.NewTestIOStreamsDiscard())
+		o.Image = "nicolaka/netshoot"
+		o.Workload = &resource.Info{Namespace: "default", Name: "demo", Object: newWorkload(nil)}
+		test.options(o)
+		err := o.Validate()
+		if len(test.expectErr) == 0 && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if len(test.expectErr) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectErr)) {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	out := &bytes.Buffer{}
+	o := NewAddOptions(genericclioptions.IOStreams{Out: out, ErrOut: out})
+	o.Image = "nicolaka/netshoot"
+	o.TTL = 30 * time.Minute
+	o.Workload = &resource.Info{
+		Namespace: "default",
+		Name:      "demo",
+		Object:    newWorkload(map[string]interface{}{"matchLabels": map[string]interface{}{"app": "demo"}}),
+	}
+	o.Client = fake.NewSimpleClientset()
+	o.KruiseClient = kruisefake.NewSimpleClientset()
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	sidecarSet, err := o.KruiseClient.AppsV1alpha1().SidecarSets().Get(context.TODO(), "debug-default-demo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := time.Parse(time.RFC3339, sidecarSet.Annotations[ExpireAtAnnotation]); err != nil {
+		t.Errorf("expected the expiry time to be annotated, got %v", sidecarSet.Annotations)
+	}
+	job, err := o.KruiseClient.AppsV1alpha1().BroadcastJobs("default").Get(context.TODO(), "debug-default-demo-cleanup", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := strings.Join(job.Spec.Template.Spec.Containers[0].Command, " ")
+	if !strings.Contains(command, "sleep 1800 && kubectl delete sidecarsets.apps.kruise.io debug-default-demo") {
+		t.Errorf("unexpected cleanup command %q", command)
+	}
+	if owners := job.OwnerReferences; len(owners) != 1 || owners[0].Kind != "SidecarSet" || owners[0].Name != "debug-default-demo" {
+		t.Errorf("expected the cleanup job to be owned by the SidecarSet, got %v", owners)
+	}
+	role, err := o.Client.RbacV1().ClusterRoles().Get(context.TODO(), "debug-default-demo-cleanup", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule := role.Rules[0]; rule.Resources[0] != "sidecarsets" || rule.ResourceNames[0] != "debug-default-demo" {
+		t.Errorf("expected the cleanup to be allowed to delete the SidecarSet only, got %+v", rule)
+	}
+	if _, err := o.Client.RbacV1().ClusterRoleBindings().Get(context.TODO(), "debug-default-demo-cleanup", metav1.GetOptions{}); err != nil {
+		t.Error(err)
+	}
+	if _, err := o.Client.CoreV1().ServiceAccounts("default").Get(context.TODO(), "debug-default-demo-cleanup", metav1.GetOptions{}); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(out.String(), "sidecarset.apps.kruise.io/debug-default-demo created") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	if err := o.Run(); err == nil {
+		t.Errorf("expected an error adding a second debug sidecar")
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugsidecar
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	removeLong = templates.LongDesc(`
+		Remove the debug sidecar of a workload before its TTL, deleting its SidecarSet and the
+		objects created to delete it.
+
+		The pods created from now on do not get the sidecar, but SidecarSets do not remove
+		their sidecars from the running pods, which keep it until they are recreated.`)
+
+	removeExample = templates.Examples(`
+		# Remove the debug sidecar of cloneset demo
+		kubectl-kruise debug-sidecar remove cloneset/demo`)
+)
+
+// RemoveOptions holds the command-line options for 'debug-sidecar remove' sub command
+type RemoveOptions struct {
+	Namespace string
+	Name      string
+
+	KruiseClient kruiseclientsets.Interface
+
+	genericclioptions.IOStreams
+}
+
+// NewRemoveOptions returns an initialized RemoveOptions instance
+func NewRemoveOptions(streams genericclioptions.IOStreams) *RemoveOptions {
+	return &RemoveOptions{IOStreams: streams}
+}
+
+// NewCmdDebugSidecarRemove returns a Command instance for 'debug-sidecar remove' sub command
+func NewCmdDebugSidecarRemove(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRemoveOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "remove (TYPE NAME | TYPE/NAME)",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Remove the debug sidecar of a workload"),
+		Long:                  removeLong,
+		Example:               removeExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return cmd
+}
+
+// Complete completes all the required options. The workload is not looked up, so that the debug
+// sidecar of a deleted workload can be removed as well.
+func (o *RemoveOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+	o.Name, err = workloadName(args)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, "%v", err)
+	}
+	o.Namespace, _, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	clientConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig)
+	return err
+}
+
+// Run performs the execution of 'debug-sidecar remove' sub command
+func (o *RemoveOptions) Run() error {
+	name := SidecarSetName(o.Namespace, o.Name)
+	// the objects deleting the SidecarSet after the TTL are garbage collected with it
+	policy := metav1.DeletePropagationBackground
+	err := o.KruiseClient.AppsV1alpha1().SidecarSets().Delete(context.TODO(), name, metav1.DeleteOptions{PropagationPolicy: &policy})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("no debug sidecar for %s in namespace %s", o.Name, o.Namespace)
+	} else if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "sidecarset.apps.kruise.io/%s deleted\n", name)
+	return nil
+}
+
+// workloadName returns the name of the workload given as TYPE NAME or TYPE/NAME.
+func workloadName(args []string) (string, error) {
+	switch {
+	case len(args) == 1 && strings.Count(args[0], "/") == 1 && !strings.HasSuffix(args[0], "/"):
+		return args[0][strings.Index(args[0], "/")+1:], nil
+	case len(args) == 2 && !strings.Contains(args[0], "/") && len(args[1]) > 0:
+		return args[1], nil
+	}
+	return "", fmt.Errorf("a single workload must be given as TYPE/NAME or TYPE NAME")
+}
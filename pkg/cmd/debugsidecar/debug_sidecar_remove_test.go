@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugsidecar
+
+import (
+	"bytes"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestWorkloadName(t *testing.T) {
+	tests := []struct {
+		args      []string
+		expected  string
+		expectErr bool
+	}{
+		{args: []string{"cloneset/demo"}, expected: "demo"},
+		{args: []string{"cloneset", "demo"}, expected: "demo"},
+		{args: []string{"cloneset"}, expectErr: true},
+		{args: []string{"cloneset/"}, expectErr: true},
+		{args: []string{"cloneset/demo", "cloneset/web"}, expectErr: true},
+		{args: nil, expectErr: true},
+	}
+	for _, test := range tests {
+		name, err := workloadName(test.args)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%v: expected error, got %q", test.args, name)
+			}
+		} else if err != nil || name != test.expected {
+			t.Errorf("%v: expected %q, got %q, %v", test.args, test.expected, name, err)
+		}
+	}
+}
+
+func TestRemoveRun(t *testing.T) {
+	out := &bytes.Buffer{}
+	o := NewRemoveOptions(genericclioptions.IOStreams{Out: out, ErrOut: out})
+	o.Namespace, o.Name = "default", "demo"
+	o.KruiseClient = kruisefake.NewSimpleClientset(&kruiseappsv1alpha1.SidecarSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "debug-default-demo"},
+	})
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "sidecarset.apps.kruise.io/debug-default-demo deleted\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if err := o.Run(); err == nil {
+		t.Errorf("expected an error removing a missing debug sidecar")
+	}
+}
@@ -0,0 +1,436 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delete
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+
+	policyv1alpha1 "github.com/openkruise/kruise-api/policy/v1alpha1"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	deleteLong = templates.LongDesc(i18n.T(`
+		Delete resources by file names, resources and names, label selector, or bundle.
+
+		Before anything is deleted, the deletion protection of Kruise is checked for every
+		resource: a resource labeled policy.kruise.io/delete-protection=Always cannot be deleted
+		until the label is removed, and a resource labeled Cascading cannot be deleted while it
+		still has replicas or, for a namespace, active pods. No resource is deleted if one of them
+		is protected.
+
+		--cascade selects what happens to the dependents of the deleted resources, such as the
+		pods and PVCs of a CloneSet, and its effect is printed for each deleted resource:
+
+		* background (default): the dependents are deleted by the garbage collector after the resource.
+		* foreground: the resource is deleted once the garbage collector deleted its dependents.
+		* orphan: the dependents are left without owner, and are adopted by a new workload with the same selector.
+
+		--bundle deletes the resources of a bundle written with --bundle-out, in dependency-safe order:
+		the workloads before the configuration and the accounts they use, and the namespaces last.`))
+
+	deleteExample = templates.Examples(`
+		# Delete cloneset demo and its pods
+		kubectl-kruise delete cloneset/demo
+
+		# Delete cloneset demo, leaving its pods and PVCs running for a new cloneset to adopt
+		kubectl-kruise delete cloneset/demo --cascade=orphan
+
+		# Delete the resources of the bundle written by 'pull-image --bundle-out prepull-demo.yaml'
+		kubectl-kruise delete --bundle prepull-demo
+
+		# Print what would be deleted from the bundle, and the effects, without deleting it
+		kubectl-kruise delete --bundle prepull-demo --dry-run=client`)
+)
+
+// bundleResources are the resources searched for the objects of a bundle when no type is given.
+var bundleResources = []string{
+	"imagepulljobs.apps.kruise.io",
+	"containerrecreaterequests.apps.kruise.io",
+	"broadcastjobs.apps.kruise.io",
+	"clonesets.apps.kruise.io",
+	"statefulsets.apps.kruise.io",
+	"daemonsets.apps.kruise.io",
+	"sidecarsets.apps.kruise.io",
+	"deployments.apps",
+	"services",
+	"configmaps",
+	"secrets",
+}
+
+// DeleteOptions holds the command-line options for 'delete' command
+type DeleteOptions struct {
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+
+	Selector       string
+	Bundle         string
+	Cascade        string
+	DryRunStrategy cmdutil.DryRunStrategy
+
+	propagationPolicy metav1.DeletionPropagation
+
+	Builder func() *resource.Builder
+	Client  kubernetes.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NewDeleteOptions returns an initialized DeleteOptions instance
+func NewDeleteOptions(streams genericclioptions.IOStreams) *DeleteOptions {
+	return &DeleteOptions{
+		Cascade:   "background",
+		IOStreams: streams,
+	}
+}
+
+// NewCmdDelete returns a Command instance for 'delete' command
+func NewCmdDelete(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewDeleteOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "delete ([-f FILENAME] | TYPE [(NAME | -l label)] | --bundle NAME) [--cascade=background|foreground|orphan]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Delete resources, checking their Kruise deletion protection"),
+		Long:                  deleteLong,
+		Example:               deleteExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "containing the resource to delete."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringVar(&o.Bundle, "bundle", o.Bundle, "Delete the resources of the bundle of this name, labeled "+internalcmdutil.BundleLabel+" by --bundle-out.")
+	cmd.Flags().StringVar(&o.Cascade, "cascade", o.Cascade, "Must be \"background\", \"orphan\", or \"foreground\". Selects the deletion cascading strategy for the dependents (e.g. Pods created by a CloneSet).")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *DeleteOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	o.Client, err = f.KubernetesClientSet()
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *DeleteOptions) Validate() error {
+	switch o.Cascade {
+	case "background":
+		o.propagationPolicy = metav1.DeletePropagationBackground
+	case "foreground":
+		o.propagationPolicy = metav1.DeletePropagationForeground
+	case "orphan":
+		o.propagationPolicy = metav1.DeletePropagationOrphan
+	default:
+		return fmt.Errorf("invalid --cascade %q, must be background, foreground or orphan", o.Cascade)
+	}
+	if len(o.Bundle) > 0 {
+		if len(o.Selector) > 0 {
+			return fmt.Errorf("--bundle and --selector cannot be used together")
+		}
+		if !cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+			return fmt.Errorf("--bundle and --filename cannot be used together, delete the bundle file with -f instead")
+		}
+		return nil
+	}
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	return nil
+}
+
+// Run performs the execution of 'delete' command
+func (o *DeleteOptions) Run() error {
+	infos, err := o.infos()
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		if len(o.Bundle) > 0 {
+			return fmt.Errorf("no resources found in bundle %s", o.Bundle)
+		}
+		return fmt.Errorf("no resources found")
+	}
+
+	var errs []error
+	for _, info := range infos {
+		if err := o.checkProtection(info); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	SortByDeleteOrder(infos)
+	suffix := ""
+	switch o.DryRunStrategy {
+	case cmdutil.DryRunClient:
+		suffix = " (dry run)"
+	case cmdutil.DryRunServer:
+		suffix = " (server dry run)"
+	}
+	for _, info := range infos {
+		name := fmt.Sprintf("%s/%s", strings.ToLower(info.Mapping.GroupVersionKind.Kind), info.Name)
+		if o.DryRunStrategy != cmdutil.DryRunClient {
+			options := &metav1.DeleteOptions{PropagationPolicy: &o.propagationPolicy}
+			if o.DryRunStrategy == cmdutil.DryRunServer {
+				options.DryRun = []string{metav1.DryRunAll}
+			}
+			_, err := resource.NewHelper(info.Client, info.Mapping).DeleteWithOptions(info.Namespace, info.Name, options)
+			if apierrors.IsNotFound(err) {
+				fmt.Fprintf(o.ErrOut, "warning: %s was already deleted\n", name)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to delete %s: %v", name, err)
+			}
+		}
+		fmt.Fprintf(o.Out, "%s deleted%s\n", name, suffix)
+		if effect := CascadeEffect(info.Mapping.GroupVersionKind.GroupKind(), o.propagationPolicy); len(effect) > 0 {
+			fmt.Fprintf(o.Out, "  %s\n", effect)
+		}
+	}
+	return nil
+}
+
+// infos returns the resources to delete, with their latest state for the protection checks.
+func (o *DeleteOptions) infos() ([]*resource.Info, error) {
+	if len(o.Bundle) == 0 {
+		return o.Builder().
+			Unstructured().
+			ContinueOnError().
+			NamespaceParam(o.Namespace).DefaultNamespace().
+			FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+			LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(false, o.Resources...).
+			Latest().
+			Flatten().
+			Do().
+			Infos()
+	}
+
+	resources := o.Resources
+	if len(resources) == 0 {
+		resources = bundleResources
+	}
+	selector := fmt.Sprintf("%s=%s", internalcmdutil.BundleLabel, o.Bundle)
+	var infos []*resource.Info
+	for _, r := range resources {
+		result := o.Builder().
+			Unstructured().
+			NamespaceParam(o.Namespace).DefaultNamespace().
+			LabelSelectorParam(selector).
+			ResourceTypeOrNameArgs(false, r).
+			Flatten().
+			Do()
+		// the resources whose definitions are not installed hold no objects of the bundle
+		if result.Err() != nil && len(o.Resources) == 0 {
+			continue
+		}
+		found, err := result.Infos()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, found...)
+	}
+	return infos, nil
+}
+
+// checkProtection returns an error if the deletion protection of the resource of info forbids
+// its deletion, as the Kruise webhook would.
+func (o *DeleteOptions) checkProtection(info *resource.Info) error {
+	u, ok := info.Object.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	name := fmt.Sprintf("%s/%s", strings.ToLower(info.Mapping.GroupVersionKind.Kind), info.Name)
+	switch u.GetLabels()[policyv1alpha1.DeletionProtectionKey] {
+	case policyv1alpha1.DeletionProtectionTypeAlways:
+		return fmt.Errorf("%s is protected by %s=%s, remove the label to delete it", name, policyv1alpha1.DeletionProtectionKey, policyv1alpha1.DeletionProtectionTypeAlways)
+	case policyv1alpha1.DeletionProtectionTypeCascading:
+		active, err := o.activeDependents(u)
+		if err != nil {
+			return err
+		}
+		if len(active) > 0 {
+			return fmt.Errorf("%s is protected by %s=%s and still has %s", name, policyv1alpha1.DeletionProtectionKey, policyv1alpha1.DeletionProtectionTypeCascading, active)
+		}
+	}
+	return nil
+}
+
+// activeDependents returns the active dependents that forbid the deletion of u under the Cascading
+// deletion protection, or an empty string if there are none or they are not known.
+func (o *DeleteOptions) activeDependents(u *unstructured.Unstructured) (string, error) {
+	if u.GroupVersionKind().GroupKind() == corev1.SchemeGroupVersion.WithKind("Namespace").GroupKind() {
+		pods, err := o.Client.CoreV1().Pods(u.GetName()).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return "", err
+		}
+		active := 0
+		for _, pod := range pods.Items {
+			if pod.DeletionTimestamp == nil && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+				active++
+			}
+		}
+		if active > 0 {
+			return fmt.Sprintf("%d active pod(s), delete them first", active), nil
+		}
+		return "", nil
+	}
+	if replicas, found, _ := unstructured.NestedInt64(u.Object, "spec", "replicas"); found && replicas > 0 {
+		return fmt.Sprintf("%d replica(s), scale it to 0 first", replicas), nil
+	}
+	return "", nil
+}
+
+var (
+	kruiseGroup = "apps.kruise.io"
+
+	// deleteOrders are the orders in which the kinds are deleted, lower first: the controllers of
+	// workloads, the policies applied to workloads, the workloads, the other resources, the
+	// resources used by workloads, then the namespaces and the definitions holding everything.
+	deleteOrders = map[schema.GroupKind]int{
+		{Group: kruiseGroup, Kind: "AdvancedCronJob"}:                     0,
+		{Group: kruiseGroup, Kind: "UnitedDeployment"}:                    0,
+		{Group: "batch", Kind: "CronJob"}:                                 0,
+		{Group: kruiseGroup, Kind: "SidecarSet"}:                          1,
+		{Group: kruiseGroup, Kind: "WorkloadSpread"}:                      1,
+		{Group: "policy.kruise.io", Kind: "PodUnavailableBudget"}:         1,
+		{Group: kruiseGroup, Kind: "CloneSet"}:                            2,
+		{Group: kruiseGroup, Kind: "StatefulSet"}:                         2,
+		{Group: kruiseGroup, Kind: "DaemonSet"}:                           2,
+		{Group: kruiseGroup, Kind: "BroadcastJob"}:                        2,
+		{Group: kruiseGroup, Kind: "ImagePullJob"}:                        2,
+		{Group: kruiseGroup, Kind: "ContainerRecreateRequest"}:            2,
+		{Group: "apps", Kind: "Deployment"}:                               2,
+		{Group: "apps", Kind: "StatefulSet"}:                              2,
+		{Group: "apps", Kind: "DaemonSet"}:                                2,
+		{Group: "apps", Kind: "ReplicaSet"}:                               2,
+		{Group: "batch", Kind: "Job"}:                                     2,
+		{Group: "", Kind: "Pod"}:                                          2,
+		{Group: "", Kind: "ConfigMap"}:                                    4,
+		{Group: "", Kind: "Secret"}:                                       4,
+		{Group: "", Kind: "ServiceAccount"}:                               4,
+		{Group: "", Kind: "PersistentVolumeClaim"}:                        4,
+		{Group: "rbac.authorization.k8s.io", Kind: "Role"}:                4,
+		{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:         4,
+		{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:         4,
+		{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:  4,
+		{Group: "", Kind: "Namespace"}:                                    5,
+		{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}: 6,
+	}
+	// defaultDeleteOrder is the order of the kinds not in deleteOrders, such as Services.
+	defaultDeleteOrder = 3
+)
+
+// SortByDeleteOrder sorts infos in dependency-safe order, keeping the given order of the
+// resources of the same order.
+func SortByDeleteOrder(infos []*resource.Info) {
+	order := func(info *resource.Info) int {
+		if order, ok := deleteOrders[info.Mapping.GroupVersionKind.GroupKind()]; ok {
+			return order
+		}
+		return defaultDeleteOrder
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return order(infos[i]) < order(infos[j])
+	})
+}
+
+// CascadeEffect returns the effect on its dependents of deleting a resource of kind gk with the
+// propagation policy, or an empty string if it has none worth noting.
+func CascadeEffect(gk schema.GroupKind, policy metav1.DeletionPropagation) string {
+	var dependents, kept string
+	switch gk {
+	case schema.GroupKind{Group: kruiseGroup, Kind: "CloneSet"}:
+		dependents = "its pods and the PVCs created from its volumeClaimTemplates"
+	case schema.GroupKind{Group: kruiseGroup, Kind: "StatefulSet"}, schema.GroupKind{Group: "apps", Kind: "StatefulSet"}:
+		dependents = "its pods"
+		kept = "its PVCs are kept, delete them to release the volumes"
+	case schema.GroupKind{Group: kruiseGroup, Kind: "DaemonSet"}, schema.GroupKind{Group: "apps", Kind: "DaemonSet"},
+		schema.GroupKind{Group: kruiseGroup, Kind: "BroadcastJob"}, schema.GroupKind{Group: "batch", Kind: "Job"},
+		schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}:
+		dependents = "its pods"
+	case schema.GroupKind{Group: "apps", Kind: "Deployment"}:
+		dependents = "its ReplicaSets and their pods"
+	case schema.GroupKind{Group: kruiseGroup, Kind: "UnitedDeployment"}:
+		dependents = "its subset workloads and their pods"
+	case schema.GroupKind{Group: kruiseGroup, Kind: "AdvancedCronJob"}, schema.GroupKind{Group: "batch", Kind: "CronJob"}:
+		dependents = "its jobs and their pods"
+	case schema.GroupKind{Group: kruiseGroup, Kind: "SidecarSet"}:
+		return "the sidecar containers already injected are kept in the pods until they are recreated"
+	case schema.GroupKind{Group: kruiseGroup, Kind: "ImagePullJob"}:
+		return "the images already pulled are kept on the nodes"
+	case schema.GroupKind{Group: "policy.kruise.io", Kind: "PodUnavailableBudget"}:
+		return "the pods it protected can be evicted and updated without limit"
+	case schema.GroupKind{Group: "", Kind: "Namespace"}:
+		return "all the resources in it are deleted, whatever the cascade"
+	default:
+		return ""
+	}
+
+	var effect string
+	switch policy {
+	case metav1.DeletePropagationOrphan:
+		effect = fmt.Sprintf("cascade=orphan: %s are left without owner, and adopted by a new %s with the same selector", dependents, gk.Kind)
+	case metav1.DeletePropagationForeground:
+		effect = fmt.Sprintf("cascade=foreground: %s are deleted first, then it is deleted", dependents)
+	default:
+		effect = fmt.Sprintf("cascade=background: %s are deleted in the background", dependents)
+	}
+	if len(kept) > 0 {
+		effect += "; " + kept
+	}
+	return effect
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delete
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newInfo(group, version, kind, name string, labels map[string]string, replicas int64) *resource.Info {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: group, Version: version, Kind: kind})
+	u.SetName(name)
+	u.SetLabels(labels)
+	if replicas >= 0 {
+		_ = unstructured.SetNestedField(u.Object, replicas, "spec", "replicas")
+	}
+	return &resource.Info{
+		Name:    name,
+		Object:  u,
+		Mapping: &meta.RESTMapping{GroupVersionKind: u.GroupVersionKind()},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   func(o *DeleteOptions)
+		expectErr string
+	}{
+		{name: "resource", options: func(o *DeleteOptions) { o.Resources = []string{"cloneset/demo"} }},
+		{name: "bundle", options: func(o *DeleteOptions) { o.Bundle = "prepull-demo" }},
+		{name: "nothing", options: func(o *DeleteOptions) {}, expectErr: "required resource not specified"},
+		{name: "invalid cascade", options: func(o *DeleteOptions) { o.Resources = []string{"cloneset/demo"}; o.Cascade = "true" }, expectErr: "invalid --cascade"},
+		{name: "bundle and selector", options: func(o *DeleteOptions) { o.Bundle = "prepull-demo"; o.Selector = "app=demo" }, expectErr: "cannot be used together"},
+	}
+	for _, test := range tests {
+		o := NewDeleteOptions(genericclioptions.NewTestIOStreamsDiscard())
+		test.options(o)
+		err := o.Validate()
+		if len(test.expectErr) == 0 && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if len(test.expectErr) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectErr)) {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestCheckProtection(t *testing.T) {
+	const key = "policy.kruise.io/delete-protection"
+	o := NewDeleteOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.Client = fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "busy", Name: "a"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "done", Name: "a"}, Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+	)
+
+	tests := []struct {
+		info      *resource.Info
+		expectErr string
+	}{
+		{info: newInfo("apps.kruise.io", "v1alpha1", "CloneSet", "unprotected", nil, 3)},
+		{info: newInfo("apps.kruise.io", "v1alpha1", "CloneSet", "always", map[string]string{key: "Always"}, 0), expectErr: "remove the label"},
+		{info: newInfo("apps.kruise.io", "v1alpha1", "CloneSet", "scaled", map[string]string{key: "Cascading"}, 3), expectErr: "3 replica(s)"},
+		{info: newInfo("apps.kruise.io", "v1alpha1", "CloneSet", "empty", map[string]string{key: "Cascading"}, 0)},
+		{info: newInfo("", "v1", "Namespace", "busy", map[string]string{key: "Cascading"}, -1), expectErr: "1 active pod(s)"},
+		{info: newInfo("", "v1", "Namespace", "done", map[string]string{key: "Cascading"}, -1)},
+	}
+	for _, test := range tests {
+		err := o.checkProtection(test.info)
+		if len(test.expectErr) == 0 && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.info.Name, err)
+		} else if len(test.expectErr) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectErr)) {
+			t.Errorf("%s: expected error %q, got %v", test.info.Name, test.expectErr, err)
+		}
+	}
+}
+
+func TestSortByDeleteOrder(t *testing.T) {
+	infos := []*resource.Info{
+		newInfo("", "v1", "Namespace", "demo", nil, -1),
+		newInfo("", "v1", "ConfigMap", "config", nil, -1),
+		newInfo("", "v1", "Service", "demo", nil, -1),
+		newInfo("apps.kruise.io", "v1alpha1", "CloneSet", "demo", nil, 1),
+		newInfo("apps.kruise.io", "v1alpha1", "ImagePullJob", "demo-app", nil, -1),
+		newInfo("apps.kruise.io", "v1alpha1", "SidecarSet", "log", nil, -1),
+	}
+	SortByDeleteOrder(infos)
+	var names []string
+	for _, info := range infos {
+		names = append(names, strings.ToLower(info.Mapping.GroupVersionKind.Kind)+"/"+info.Name)
+	}
+	expected := "sidecarset/log,cloneset/demo,imagepulljob/demo-app,service/demo,configmap/config,namespace/demo"
+	if got := strings.Join(names, ","); got != expected {
+		t.Errorf("expected order %s, got %s", expected, got)
+	}
+}
+
+func TestCascadeEffect(t *testing.T) {
+	cloneSet := schema.GroupKind{Group: "apps.kruise.io", Kind: "CloneSet"}
+	statefulSet := schema.GroupKind{Group: "apps.kruise.io", Kind: "StatefulSet"}
+	tests := []struct {
+		gk       schema.GroupKind
+		policy   metav1.DeletionPropagation
+		expected string
+	}{
+		{gk: cloneSet, policy: metav1.DeletePropagationBackground, expected: "cascade=background: its pods and the PVCs created from its volumeClaimTemplates are deleted in the background"},
+		{gk: cloneSet, policy: metav1.DeletePropagationOrphan, expected: "cascade=orphan: its pods and the PVCs created from its volumeClaimTemplates are left without owner, and adopted by a new CloneSet with the same selector"},
+		{gk: statefulSet, policy: metav1.DeletePropagationForeground, expected: "cascade=foreground: its pods are deleted first, then it is deleted; its PVCs are kept, delete them to release the volumes"},
+		{gk: schema.GroupKind{Group: "apps.kruise.io", Kind: "ImagePullJob"}, policy: metav1.DeletePropagationOrphan, expected: "the images already pulled are kept on the nodes"},
+		{gk: schema.GroupKind{Kind: "ConfigMap"}, policy: metav1.DeletePropagationBackground},
+	}
+	for _, test := range tests {
+		if got := CascadeEffect(test.gk, test.policy); got != test.expected {
+			t.Errorf("%s %s: expected %q, got %q", test.gk, test.policy, test.expected, got)
+		}
+	}
+}
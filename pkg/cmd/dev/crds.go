@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dev
+
+import (
+	"sort"
+	"strings"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	kruisepolicyv1alpha1 "github.com/openkruise/kruise-api/policy/v1alpha1"
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/utils/pointer"
+)
+
+// clusterScopedKinds are the Kruise kinds that are not namespaced.
+var clusterScopedKinds = map[string]bool{"SidecarSet": true, "NodeImage": true}
+
+// scaledKinds are the Kruise kinds with a scale subresource, so that 'scale' works on them.
+var scaledKinds = map[string]bool{"CloneSet": true, "StatefulSet": true}
+
+// KruiseCRDs returns the CRDs of the kinds of Kruise and Kruise Rollouts, served in all their
+// versions with the highest one stored. Their schemas accept any field: the sandbox runs no
+// Kruise webhook to validate the objects.
+func KruiseCRDs() []*apiextensionsv1.CustomResourceDefinition {
+	scheme := runtime.NewScheme()
+	_ = kruiseappsv1alpha1.AddToScheme(scheme)
+	_ = kruiseappsv1beta1.AddToScheme(scheme)
+	_ = kruisepolicyv1alpha1.AddToScheme(scheme)
+	_ = kruiserolloutsv1apha1.AddToScheme(scheme)
+
+	versions := map[schema.GroupKind][]string{}
+	for gvk := range scheme.AllKnownTypes() {
+		// the kinds without list, such as the options, are not resources
+		if strings.HasSuffix(gvk.Kind, "List") || !scheme.Recognizes(gvk.GroupVersion().WithKind(gvk.Kind+"List")) {
+			continue
+		}
+		versions[gvk.GroupKind()] = append(versions[gvk.GroupKind()], gvk.Version)
+	}
+
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for gk, served := range versions {
+		sort.Slice(served, func(i, j int) bool {
+			return version.CompareKubeAwareVersionStrings(served[i], served[j]) > 0
+		})
+		crds = append(crds, newCRD(gk, served))
+	}
+	sort.Slice(crds, func(i, j int) bool { return crds[i].Name < crds[j].Name })
+	return crds
+}
+
+// newCRD returns the CRD of gk served in versions, the first one being stored.
+func newCRD(gk schema.GroupKind, versions []string) *apiextensionsv1.CustomResourceDefinition {
+	plural, singular := meta.UnsafeGuessKindToResource(gk.WithVersion(versions[0]))
+	scope := apiextensionsv1.NamespaceScoped
+	if clusterScopedKinds[gk.Kind] {
+		scope = apiextensionsv1.ClusterScoped
+	}
+	subresources := &apiextensionsv1.CustomResourceSubresources{Status: &apiextensionsv1.CustomResourceSubresourceStatus{}}
+	if scaledKinds[gk.Kind] {
+		subresources.Scale = &apiextensionsv1.CustomResourceSubresourceScale{
+			SpecReplicasPath:   ".spec.replicas",
+			StatusReplicasPath: ".status.replicas",
+			LabelSelectorPath:  pointer.StringPtr(".status.labelSelector"),
+		}
+	}
+
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: plural.Resource + "." + gk.Group},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: gk.Group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind:     gk.Kind,
+				ListKind: gk.Kind + "List",
+				Plural:   plural.Resource,
+				Singular: singular.Resource,
+			},
+			Scope: scope,
+		},
+	}
+	for i, v := range versions {
+		crd.Spec.Versions = append(crd.Spec.Versions, apiextensionsv1.CustomResourceDefinitionVersion{
+			Name:    v,
+			Served:  true,
+			Storage: i == 0,
+			Schema: &apiextensionsv1.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+					Type:                   "object",
+					XPreserveUnknownFields: pointer.BoolPtr(true),
+				},
+			},
+			Subresources: subresources,
+		})
+	}
+	return crd
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dev
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+const sandboxContext = "kruise-sandbox"
+
+var (
+	devLong = templates.LongDesc(`
+		Commands for the developers of kubectl-kruise and of scripts using it.`)
+
+	sandboxLong = templates.LongDesc(`
+		Start a throwaway API server with the Kruise CRDs installed and point kubectl-kruise at it.
+
+		The API server and etcd are started with envtest, from the binaries in the directory of
+		KUBEBUILDER_ASSETS or --assets-dir, e.g. as installed by setup-envtest. The Kruise CRDs are
+		generated from the API types known to kubectl-kruise and accept any field; install the
+		released CRDs with --crd instead to validate the objects. No controller or webhook runs in
+		the sandbox: workloads create no pods, and their status only changes when written.
+
+		The given command is run with KUBECONFIG set to the sandbox, and the sandbox stopped once it
+		exits. Without command, the sandbox runs until interrupted.`)
+
+	sandboxExample = templates.Examples(`
+		# Run the tests of a script against a sandbox
+		kubectl-kruise dev sandbox -- ./test.sh
+
+		# Start a sandbox with the released Kruise CRDs, and use it from another shell
+		kubectl-kruise dev sandbox --crd kruise/charts/kruise/v1.0.0/templates/ --write-kubeconfig /tmp/sandbox.kubeconfig
+		KUBECONFIG=/tmp/sandbox.kubeconfig kubectl-kruise get clonesets`)
+)
+
+// SandboxOptions holds the command-line options for 'dev sandbox' sub command
+type SandboxOptions struct {
+	CRDPaths        []string
+	AssetsDir       string
+	WriteKubeconfig string
+	StartTimeout    time.Duration
+
+	Command []string
+
+	genericclioptions.IOStreams
+}
+
+// NewSandboxOptions returns an initialized SandboxOptions instance
+func NewSandboxOptions(streams genericclioptions.IOStreams) *SandboxOptions {
+	return &SandboxOptions{
+		StartTimeout: time.Minute,
+		IOStreams:    streams,
+	}
+}
+
+// NewCmdDev returns a Command instance for 'dev' sub command
+func NewCmdDev(streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "dev SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Commands to develop and test with kubectl-kruise"),
+		Long:                  devLong,
+		Hidden:                true,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+	cmd.AddCommand(NewCmdSandbox(streams))
+	return cmd
+}
+
+// NewCmdSandbox returns a Command instance for 'dev sandbox' sub command
+func NewCmdSandbox(streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewSandboxOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "sandbox [--crd PATH] [-- COMMAND [ARGS...]]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Start a throwaway API server with the Kruise CRDs"),
+		Long:                  sandboxLong,
+		Example:               sandboxExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.Command = args
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&o.CRDPaths, "crd", o.CRDPaths, "Files or directories of CRDs to install instead of the generated Kruise CRDs, e.g. the templates of the Kruise chart.")
+	cmd.Flags().StringVar(&o.AssetsDir, "assets-dir", o.AssetsDir, "The directory of the etcd and kube-apiserver binaries. KUBEBUILDER_ASSETS takes precedence.")
+	cmd.Flags().StringVar(&o.WriteKubeconfig, "write-kubeconfig", o.WriteKubeconfig, "Write the kubeconfig of the sandbox to this file. Defaults to a temporary file removed with the sandbox.")
+	cmd.Flags().DurationVar(&o.StartTimeout, "start-timeout", o.StartTimeout, "The time to wait for the API server to start.")
+	return cmd
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *SandboxOptions) Validate() error {
+	for _, path := range o.CRDPaths {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("invalid --crd: %v", err)
+		}
+	}
+	if o.StartTimeout <= 0 {
+		return fmt.Errorf("--start-timeout must be positive")
+	}
+	return nil
+}
+
+// Run performs the execution of 'dev sandbox' sub command
+func (o *SandboxOptions) Run() error {
+	env := &envtest.Environment{
+		CRDDirectoryPaths:        o.CRDPaths,
+		ErrorIfCRDPathMissing:    true,
+		BinaryAssetsDirectory:    o.AssetsDir,
+		ControlPlaneStartTimeout: o.StartTimeout,
+	}
+	if len(o.CRDPaths) == 0 {
+		for _, crd := range KruiseCRDs() {
+			env.CRDs = append(env.CRDs, client.Object(crd))
+		}
+	}
+
+	fmt.Fprintf(o.ErrOut, "Starting the sandbox API server...\n")
+	config, err := env.Start()
+	if err != nil {
+		return fmt.Errorf("failed to start the sandbox: %v, set KUBEBUILDER_ASSETS or --assets-dir to the directory of the etcd and kube-apiserver binaries", err)
+	}
+	defer func() {
+		if err := env.Stop(); err != nil {
+			fmt.Fprintf(o.ErrOut, "Warning: failed to stop the sandbox: %v\n", err)
+		}
+	}()
+
+	kubeconfig := o.WriteKubeconfig
+	if len(kubeconfig) == 0 {
+		dir, err := ioutil.TempDir("", "kruise-sandbox")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		kubeconfig = filepath.Join(dir, "kubeconfig")
+	}
+	if err := clientcmd.WriteToFile(*SandboxKubeconfig(config), kubeconfig); err != nil {
+		return fmt.Errorf("failed to write the kubeconfig of the sandbox: %v", err)
+	}
+
+	if len(o.Command) > 0 {
+		command := exec.Command(o.Command[0], o.Command[1:]...)
+		command.Env = append(os.Environ(), clientcmd.RecommendedConfigPathEnvVar+"="+kubeconfig)
+		command.Stdin = o.In
+		command.Stdout = o.Out
+		command.Stderr = o.ErrOut
+		if err := command.Run(); err != nil {
+			return fmt.Errorf("%s failed against the sandbox: %v", o.Command[0], err)
+		}
+		return nil
+	}
+
+	fmt.Fprintf(o.Out, "Sandbox API server running at %s\n", config.Host)
+	fmt.Fprintf(o.Out, "export %s=%s\n", clientcmd.RecommendedConfigPathEnvVar, kubeconfig)
+	fmt.Fprintf(o.ErrOut, "Press Ctrl-C to stop the sandbox.\n")
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	return nil
+}
+
+// SandboxKubeconfig returns the kubeconfig of the API server of config, with a single context
+// using the default namespace.
+func SandboxKubeconfig(config *rest.Config) *clientcmdapi.Config {
+	server := config.Host
+	if !strings.Contains(server, "://") {
+		server = "http://" + server
+	}
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[sandboxContext] = &clientcmdapi.Cluster{
+		Server:                   server,
+		CertificateAuthorityData: config.CAData,
+	}
+	kubeconfig.AuthInfos[sandboxContext] = &clientcmdapi.AuthInfo{
+		Token:                 config.BearerToken,
+		ClientCertificateData: config.CertData,
+		ClientKeyData:         config.KeyData,
+	}
+	kubeconfig.Contexts[sandboxContext] = &clientcmdapi.Context{
+		Cluster:   sandboxContext,
+		AuthInfo:  sandboxContext,
+		Namespace: "default",
+	}
+	kubeconfig.CurrentContext = sandboxContext
+	return kubeconfig
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dev
+
+import (
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+)
+
+func TestKruiseCRDs(t *testing.T) {
+	crds := map[string]*apiextensionsv1.CustomResourceDefinition{}
+	for _, crd := range KruiseCRDs() {
+		crds[crd.Name] = crd
+	}
+
+	cloneSet := crds["clonesets.apps.kruise.io"]
+	if cloneSet == nil {
+		t.Fatalf("expected the CRD of CloneSet, got %v", crds)
+	}
+	if cloneSet.Spec.Scope != apiextensionsv1.NamespaceScoped || cloneSet.Spec.Names.ListKind != "CloneSetList" || cloneSet.Spec.Versions[0].Subresources.Scale == nil {
+		t.Errorf("unexpected CloneSet CRD %+v", cloneSet.Spec)
+	}
+
+	statefulSet := crds["statefulsets.apps.kruise.io"]
+	if statefulSet == nil || len(statefulSet.Spec.Versions) != 2 {
+		t.Fatalf("expected the CRD of Advanced StatefulSet in 2 versions, got %+v", statefulSet)
+	}
+	if v := statefulSet.Spec.Versions[0]; v.Name != "v1beta1" || !v.Storage || statefulSet.Spec.Versions[1].Storage {
+		t.Errorf("expected v1beta1 to be the only stored version, got %+v", statefulSet.Spec.Versions)
+	}
+
+	if sidecarSet := crds["sidecarsets.apps.kruise.io"]; sidecarSet == nil || sidecarSet.Spec.Scope != apiextensionsv1.ClusterScoped {
+		t.Errorf("expected a cluster scoped CRD of SidecarSet, got %+v", sidecarSet)
+	}
+	if crds["podunavailablebudgets.policy.kruise.io"] == nil {
+		t.Errorf("expected the CRD of PodUnavailableBudget")
+	}
+	for name := range crds {
+		if name == "listoptions.apps.kruise.io" || name == "watchevents.apps.kruise.io" {
+			t.Errorf("unexpected CRD %s of a kind that is not a resource", name)
+		}
+	}
+}
+
+func TestSandboxKubeconfig(t *testing.T) {
+	kubeconfig := SandboxKubeconfig(&rest.Config{Host: "127.0.0.1:34567"})
+	if kubeconfig.CurrentContext != sandboxContext {
+		t.Errorf("expected current context %s, got %s", sandboxContext, kubeconfig.CurrentContext)
+	}
+	if server := kubeconfig.Clusters[sandboxContext].Server; server != "http://127.0.0.1:34567" {
+		t.Errorf("expected server http://127.0.0.1:34567, got %s", server)
+	}
+	if namespace := kubeconfig.Contexts[sandboxContext].Namespace; namespace != "default" {
+		t.Errorf("expected namespace default, got %s", namespace)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	o := NewSandboxOptions(genericclioptions.NewTestIOStreamsDiscard())
+	if err := o.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	o.CRDPaths = []string{"does/not/exist"}
+	if err := o.Validate(); err == nil {
+		t.Errorf("expected an error for a missing --crd")
+	}
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diffrevision
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/kubectl/pkg/cmd/diff"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"k8s.io/utils/exec"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	diffRevisionLong = templates.LongDesc(`
+		Diff the live pod template of a workload against the revision that was active at a given time.
+
+		The revision active at the time is the last ControllerRevision created before it. Like
+		'kubectl diff', the diff is printed by 'diff -u -N' or by the program set in the
+		KUBECTL_EXTERNAL_DIFF environment variable, and the exit status is 0 if there are no
+		differences, 1 if there are differences and greater than 1 if the command failed.`)
+
+	diffRevisionExample = templates.Examples(`
+		# Show what changed in cloneset demo since the revision that was active before an incident
+		kubectl-kruise diff-revision cloneset/demo --at 2024-05-01T12:00:00Z
+
+		# Show the same for advanced statefulset demo with another diff program
+		KUBECTL_EXTERNAL_DIFF=meld kubectl-kruise diff-revision asts/demo --at 2024-05-01T12:00:00Z`)
+)
+
+// DiffRevisionOptions holds the command-line options for 'diff-revision' command
+type DiffRevisionOptions struct {
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+	At               string
+
+	at time.Time
+
+	Builder      func() *resource.Builder
+	Client       kubernetes.Interface
+	KruiseClient kruiseclientsets.Interface
+	Diff         *diff.DiffProgram
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NewDiffRevisionOptions returns an initialized DiffRevisionOptions instance
+func NewDiffRevisionOptions(streams genericclioptions.IOStreams) *DiffRevisionOptions {
+	return &DiffRevisionOptions{
+		Diff:      &diff.DiffProgram{Exec: exec.New(), IOStreams: streams},
+		IOStreams: streams,
+	}
+}
+
+// NewCmdDiffRevision returns a Command instance for 'diff-revision' command
+func NewCmdDiffRevision(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewDiffRevisionOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "diff-revision (TYPE/NAME | TYPE NAME) --at=TIME",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Diff the live pod template of a workload against the revision active at a given time"),
+		Long:                  diffRevisionLong,
+		Example:               diffRevisionExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckDiffErr(o.Complete(f, args))
+			cmdutil.CheckDiffErr(o.Validate())
+			// as 'kubectl diff', exit with the status of the diff program if it found
+			// changes, without printing an error
+			if err := o.Run(); err != nil {
+				if exitErr, ok := err.(exec.ExitError); ok && exitErr.ExitStatus() <= 1 {
+					os.Exit(exitErr.ExitStatus())
+				}
+				cmdutil.CheckDiffErr(err)
+			}
+		},
+	}
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringVar(&o.At, "at", o.At, "The time to diff the revision active at, in RFC3339 format, e.g. 2024-05-01T12:00:00Z.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *DiffRevisionOptions) Complete(f cmdutil.Factory, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+
+	o.Client, err = f.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	clientConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig)
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *DiffRevisionOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if len(o.At) == 0 {
+		return fmt.Errorf("a time must be given with --at")
+	}
+	at, err := time.Parse(time.RFC3339, o.At)
+	if err != nil {
+		return fmt.Errorf("invalid --at %q, must be in RFC3339 format, e.g. 2024-05-01T12:00:00Z", o.At)
+	}
+	o.at = at
+	return nil
+}
+
+// Run performs the execution of 'diff-revision' command
+func (o *DiffRevisionOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return err
+	}
+
+	from, err := diff.CreateDirectory("REVISION")
+	if err != nil {
+		return err
+	}
+	defer from.Delete()
+	to, err := diff.CreateDirectory("LIVE")
+	if err != nil {
+		return err
+	}
+	defer to.Delete()
+
+	for _, info := range infos {
+		revisions, err := polymorphichelpers.RevisionTemplatesFor(info.Mapping.GroupVersionKind.GroupKind(), o.Client, o.KruiseClient, info.Namespace, info.Name)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%s/%s", info.Mapping.Resource.GroupResource().String(), info.Name)
+		revision, err := RevisionAt(revisions, o.at)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		live, err := LiveTemplate(info.Object)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		fmt.Fprintf(o.ErrOut, "%s: diffing revision %d (%s), created at %s, against the live pod template\n",
+			name, revision.Revision.Revision, revision.Revision.Name, revision.Revision.CreationTimestamp.UTC().Format(time.RFC3339))
+
+		file := fmt.Sprintf("%s.%s.%s", info.Mapping.Resource.GroupResource().String(), info.Namespace, info.Name)
+		if err := writeTemplate(from, file, revision.Template); err != nil {
+			return err
+		}
+		if err := writeTemplate(to, file, live); err != nil {
+			return err
+		}
+	}
+	return o.Diff.Run(from.Name, to.Name)
+}
+
+// RevisionAt returns the revision that was active at t, which is the revision created last before
+// or at t. Of the revisions created at the same time, the one with the highest revision number wins.
+func RevisionAt(revisions []polymorphichelpers.RevisionTemplate, t time.Time) (*polymorphichelpers.RevisionTemplate, error) {
+	var active *polymorphichelpers.RevisionTemplate
+	for i := range revisions {
+		revision := &revisions[i]
+		created := revision.Revision.CreationTimestamp.Time
+		if created.After(t) {
+			continue
+		}
+		if active == nil || created.After(active.Revision.CreationTimestamp.Time) ||
+			(created.Equal(active.Revision.CreationTimestamp.Time) && revision.Revision.Revision > active.Revision.Revision) {
+			active = revision
+		}
+	}
+	if active != nil {
+		return active, nil
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("no rollout history found")
+	}
+	oldest := revisions[0].Revision.CreationTimestamp.Time
+	for _, revision := range revisions[1:] {
+		if created := revision.Revision.CreationTimestamp.Time; created.Before(oldest) {
+			oldest = created
+		}
+	}
+	return nil, fmt.Errorf("no revision was active at %s, the oldest revision was created at %s", t.UTC().Format(time.RFC3339), oldest.UTC().Format(time.RFC3339))
+}
+
+// LiveTemplate returns the pod template in spec.template of workload.
+func LiveTemplate(workload runtime.Object) (*corev1.PodTemplateSpec, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(workload)
+	if err != nil {
+		return nil, err
+	}
+	raw, found, err := unstructured.NestedMap(content, "spec", "template")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no pod template found in spec.template")
+	}
+	template := &corev1.PodTemplateSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// writeTemplate writes template to the file name in dir in YAML.
+func writeTemplate(dir *diff.Directory, name string, template *corev1.PodTemplateSpec) error {
+	data, err := yaml.Marshal(template)
+	if err != nil {
+		return err
+	}
+	file, err := dir.NewFile(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(data)
+	return err
+}
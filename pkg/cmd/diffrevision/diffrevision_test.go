@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diffrevision
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRevision(name string, revision int64, created string) polymorphichelpers.RevisionTemplate {
+	t, _ := time.Parse(time.RFC3339, created)
+	return polymorphichelpers.RevisionTemplate{Revision: &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(t)},
+		Revision:   revision,
+	}}
+}
+
+func TestRevisionAt(t *testing.T) {
+	revisions := []polymorphichelpers.RevisionTemplate{
+		newRevision("demo-a", 1, "2024-05-01T10:00:00Z"),
+		newRevision("demo-b", 2, "2024-05-01T11:00:00Z"),
+		newRevision("demo-c", 3, "2024-05-01T13:00:00Z"),
+		newRevision("demo-d", 4, "2024-05-01T13:00:00Z"),
+	}
+	tests := []struct {
+		at       string
+		expected string
+		err      string
+	}{
+		{at: "2024-05-01T09:00:00Z", err: "no revision was active at 2024-05-01T09:00:00Z, the oldest revision was created at 2024-05-01T10:00:00Z"},
+		{at: "2024-05-01T10:00:00Z", expected: "demo-a"},
+		{at: "2024-05-01T12:00:00Z", expected: "demo-b"},
+		{at: "2024-05-01T14:00:00Z", expected: "demo-d"},
+	}
+	for _, test := range tests {
+		at, _ := time.Parse(time.RFC3339, test.at)
+		revision, err := RevisionAt(revisions, at)
+		if len(test.err) > 0 {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: expected error %q, got %v", test.at, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.at, err)
+			continue
+		}
+		if revision.Revision.Name != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.at, test.expected, revision.Revision.Name)
+		}
+	}
+
+	if _, err := RevisionAt(nil, time.Now()); err == nil || !strings.Contains(err.Error(), "no rollout history") {
+		t.Errorf("expected no rollout history error, got %v", err)
+	}
+}
+
+func TestLiveTemplate(t *testing.T) {
+	cs := &kruiseappsv1alpha1.CloneSet{}
+	cs.Spec.Template.Labels = map[string]string{"app": "demo"}
+	cs.Spec.Template.Spec.Containers = []corev1.Container{{Name: "main", Image: "nginx:2"}}
+
+	template, err := LiveTemplate(cs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if template.Labels["app"] != "demo" || template.Spec.Containers[0].Image != "nginx:2" {
+		t.Errorf("unexpected template %v", template)
+	}
+
+	if _, err := LiveTemplate(&corev1.ConfigMap{}); err == nil {
+		t.Errorf("expected an error for an object without pod template")
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/cmd/release"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/util/homedir"
+	"k8s.io/component-base/version"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/yaml"
+)
+
+const binaryName = "kubectl-kruise"
+
+var (
+	doctorLong = templates.LongDesc(`
+		Check the installation of kubectl-kruise.
+
+		Verify that kubectl finds kubectl-kruise on PATH as the kruise plugin, that it is the
+		binary running the check, and that it is the version installed by krew, if krew is used.`)
+
+	doctorExample = templates.Examples(`
+		# Check the installation of kubectl-kruise
+		kubectl kruise doctor`)
+)
+
+// Status is the result of a check
+type Status string
+
+const (
+	StatusOK   Status = "OK"
+	StatusWarn Status = "WARN"
+	StatusFail Status = "FAIL"
+)
+
+// Check is the result of one check of the installation
+type Check struct {
+	Name    string
+	Status  Status
+	Message string
+}
+
+// DoctorOptions holds the command-line options for 'doctor' command
+type DoctorOptions struct {
+	// Path is the PATH kubectl looks up plugins in
+	Path           string
+	KrewRoot       string
+	Executable     string
+	CurrentVersion string
+
+	genericclioptions.IOStreams
+}
+
+// NewDoctorOptions returns an initialized DoctorOptions instance
+func NewDoctorOptions(streams genericclioptions.IOStreams) *DoctorOptions {
+	return &DoctorOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdDoctor returns a Command instance for 'doctor' command
+func NewCmdDoctor(streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewDoctorOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "doctor",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Check the installation of kubectl-kruise"),
+		Long:                  doctorLong,
+		Example:               doctorExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *DoctorOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args)
+	}
+	o.Path = os.Getenv("PATH")
+	o.KrewRoot = os.Getenv("KREW_ROOT")
+	if len(o.KrewRoot) == 0 {
+		o.KrewRoot = filepath.Join(homedir.HomeDir(), ".krew")
+	}
+	o.CurrentVersion = version.Get().GitVersion
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	o.Executable, err = filepath.EvalSymlinks(executable)
+	return err
+}
+
+// Run prints the checks of the installation, and fails if any of them fails
+func (o *DoctorOptions) Run() error {
+	checks := o.Checks()
+
+	w := internalcmdutil.NewTableWriter(o.Out)
+	fmt.Fprintln(w, "CHECK\tSTATUS\tMESSAGE")
+	failed := 0
+	for _, check := range checks {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", check.Name, check.Status, check.Message)
+		if check.Status == StatusFail {
+			failed++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// Checks checks the installation
+func (o *DoctorOptions) Checks() []Check {
+	var checks []Check
+
+	binaries := lookPathAll(o.Path, binaryName)
+	if len(binaries) == 0 {
+		checks = append(checks, Check{"path", StatusFail, fmt.Sprintf("%s is not on PATH, kubectl can not find the kruise plugin", binaryName)})
+	} else if len(binaries) > 1 {
+		checks = append(checks, Check{"path", StatusWarn, fmt.Sprintf("%s is found, and shadows %s", binaries[0], strings.Join(binaries[1:], ", "))})
+	} else {
+		checks = append(checks, Check{"path", StatusOK, fmt.Sprintf("%s is found", binaries[0])})
+	}
+
+	if len(binaries) > 0 {
+		onPath := binaries[0]
+		if !isExecutable(onPath) {
+			checks = append(checks, Check{"executable", StatusFail, fmt.Sprintf("%s is not executable, kubectl ignores it", onPath)})
+		} else {
+			checks = append(checks, Check{"executable", StatusOK, fmt.Sprintf("%s is executable", onPath)})
+		}
+
+		if resolved, err := filepath.EvalSymlinks(onPath); err == nil && resolved != o.Executable {
+			checks = append(checks, Check{"binary", StatusWarn, fmt.Sprintf("running %s, but kubectl kruise runs %s", o.Executable, resolved)})
+		} else {
+			checks = append(checks, Check{"binary", StatusOK, fmt.Sprintf("kubectl kruise runs %s", o.Executable)})
+		}
+	}
+
+	checks = append(checks, o.krewCheck(binaries))
+
+	if len(lookPathAll(o.Path, "kubectl")) == 0 {
+		checks = append(checks, Check{"kubectl", StatusWarn, "kubectl is not on PATH, only kubectl-kruise can be run"})
+	} else {
+		checks = append(checks, Check{"kubectl", StatusOK, "kubectl is found"})
+	}
+	return checks
+}
+
+// krewCheck checks that kubectl-kruise on PATH is the one installed by krew, if any.
+func (o *DoctorOptions) krewCheck(binaries []string) Check {
+	data, err := ioutil.ReadFile(filepath.Join(o.KrewRoot, "receipts", release.KrewPluginName+".yaml"))
+	if os.IsNotExist(err) {
+		return Check{"krew", StatusOK, "not installed by krew"}
+	} else if err != nil {
+		return Check{"krew", StatusWarn, fmt.Sprintf("cannot read the krew receipt: %v", err)}
+	}
+	receipt := &release.KrewPlugin{}
+	if err := yaml.Unmarshal(data, receipt); err != nil {
+		return Check{"krew", StatusWarn, fmt.Sprintf("invalid krew receipt: %v", err)}
+	}
+
+	krewBin := filepath.Join(o.KrewRoot, "bin")
+	if len(binaries) > 0 && filepath.Dir(binaries[0]) != krewBin {
+		return Check{"krew", StatusWarn, fmt.Sprintf("krew installed %s in %s, but %s comes first on PATH", receipt.Spec.Version, krewBin, binaries[0])}
+	}
+	if receipt.Spec.Version != o.CurrentVersion {
+		return Check{"krew", StatusWarn, fmt.Sprintf("krew installed %s, but the running version is %s", receipt.Spec.Version, o.CurrentVersion)}
+	}
+	return Check{"krew", StatusOK, fmt.Sprintf("krew installed %s", receipt.Spec.Version)}
+}
+
+// lookPathAll returns the files named name, or name.exe on Windows, in the directories of
+// path, in order.
+func lookPathAll(path, name string) []string {
+	names := []string{name}
+	if runtime.GOOS == "windows" {
+		names = append(names, name+".exe")
+	}
+
+	var found []string
+	seen := map[string]bool{}
+	for _, dir := range filepath.SplitList(path) {
+		if len(dir) == 0 || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		for _, n := range names {
+			file := filepath.Join(dir, n)
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				found = append(found, file)
+			}
+		}
+	}
+	return found
+}
+
+func isExecutable(file string) bool {
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	info, err := os.Stat(file)
+	return err == nil && info.Mode()&0111 != 0
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func writeFile(t *testing.T, file, content string, mode os.FileMode) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChecks(t *testing.T) {
+	root, err := ioutil.TempDir("", "doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	// the temporary directory may be a symlink, as on macOS
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		t.Fatal(err)
+	}
+	krewRoot := filepath.Join(root, ".krew")
+	krewBin := filepath.Join(krewRoot, "bin", binaryName)
+	localBin := filepath.Join(root, "local", binaryName)
+	writeFile(t, krewBin, "binary", 0755)
+	writeFile(t, localBin, "binary", 0644)
+	writeFile(t, filepath.Join(root, "usr", "kubectl"), "kubectl", 0755)
+	writeFile(t, filepath.Join(krewRoot, "receipts", "kruise.yaml"), "apiVersion: krew.googlecontainertools.github.com/v1alpha2\nkind: Plugin\nmetadata:\n  name: kruise\nspec:\n  version: v1.0.0\nstatus:\n  source:\n    name: default\n", 0644)
+
+	tests := []struct {
+		name     string
+		path     []string
+		version  string
+		expected map[string]Status
+	}{
+		{
+			name:    "krew installation",
+			path:    []string{filepath.Dir(krewBin), filepath.Join(root, "usr")},
+			version: "v1.0.0",
+			expected: map[string]Status{
+				"path": StatusOK, "executable": StatusOK, "binary": StatusOK, "krew": StatusOK, "kubectl": StatusOK,
+			},
+		},
+		{
+			name:    "krew version mismatch",
+			path:    []string{filepath.Dir(krewBin), filepath.Join(root, "usr")},
+			version: "v0.9.0",
+			expected: map[string]Status{
+				"path": StatusOK, "executable": StatusOK, "binary": StatusOK, "krew": StatusWarn, "kubectl": StatusOK,
+			},
+		},
+		{
+			name:    "shadowed by a binary that is not executable",
+			path:    []string{filepath.Dir(localBin), filepath.Dir(krewBin)},
+			version: "v1.0.0",
+			expected: map[string]Status{
+				"path": StatusWarn, "executable": StatusFail, "binary": StatusWarn, "krew": StatusWarn, "kubectl": StatusWarn,
+			},
+		},
+		{
+			name:    "not on path",
+			path:    []string{filepath.Join(root, "usr")},
+			version: "v1.0.0",
+			expected: map[string]Status{
+				"path": StatusFail, "krew": StatusOK, "kubectl": StatusOK,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streams, _, _, _ := genericclioptions.NewTestIOStreams()
+			o := NewDoctorOptions(streams)
+			o.Path = strings.Join(test.path, string(os.PathListSeparator))
+			o.KrewRoot = krewRoot
+			o.Executable = krewBin
+			o.CurrentVersion = test.version
+
+			got := map[string]Status{}
+			for _, check := range o.Checks() {
+				got[check.Name] = check.Status
+			}
+			for name, status := range test.expected {
+				if got[name] != status {
+					t.Errorf("expected %s of check %s, got %s", status, name, got[name])
+				}
+			}
+			if len(got) != len(test.expected) {
+				t.Errorf("expected checks %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// RolloutEventReasons are the event reasons Kruise controllers record when they create,
+// delete or update pods during a rollout.
+var RolloutEventReasons = sets.NewString(
+	"SuccessfulCreate",
+	"FailedCreate",
+	"SuccessfulDelete",
+	"FailedDelete",
+	"SuccessfulUpdatePodInPlace",
+	"FailedUpdatePodInPlace",
+	"SuccessfulUpdatePodReadiness",
+	"FailedUpdatePodReadiness",
+	"RecreateFailed",
+	"ScalingReplicaSet",
+)
+
+// unknownRevision groups the events that cannot be related to a revision.
+const unknownRevision = "<unknown>"
+
+var (
+	eventsLong = templates.LongDesc(`
+		Show the events of a workload and of its pods, grouped by the revision of the pods.
+
+		With --rollout-only, only the events Kruise controllers record while creating, deleting
+		or updating pods are shown, such as SuccessfulUpdatePodInPlace and FailedCreate.`)
+
+	eventsExample = templates.Examples(`
+		# Show the events of cloneset demo and its pods
+		kubectl-kruise events cloneset/demo
+
+		# Show only the rollout events of cloneset demo, grouped by revision
+		kubectl-kruise events cloneset/demo --rollout-only`)
+)
+
+// EventsOptions holds the command-line options for 'events' command
+type EventsOptions struct {
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+	FieldSelector    string
+	RolloutOnly      bool
+
+	Builder func() *resource.Builder
+	Client  kubernetes.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// RevisionEvents is the events of the pods of one revision.
+type RevisionEvents struct {
+	Revision string
+	Events   []corev1.Event
+}
+
+// NewEventsOptions returns an initialized EventsOptions instance
+func NewEventsOptions(streams genericclioptions.IOStreams) *EventsOptions {
+	return &EventsOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdEvents returns a Command instance for 'events' command
+func NewCmdEvents(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewEventsOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "events (TYPE/NAME | TYPE NAME) [--rollout-only]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show the events of a workload and its pods grouped by revision"),
+		Long:                  eventsLong,
+		Example:               eventsExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	internalcmdutil.AddFieldSelectorFlagVar(cmd, &o.FieldSelector)
+	cmd.Flags().BoolVar(&o.RolloutOnly, "rollout-only", o.RolloutOnly, "If true, only show the events recorded while creating, deleting or updating pods.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *EventsOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+	o.Client, err = f.KubernetesClientSet()
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *EventsOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if err := internalcmdutil.ValidateFieldSelector(o.FieldSelector); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Run performs the execution of 'events' command
+func (o *EventsOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		FieldSelectorParam(o.FieldSelector).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return err
+	}
+
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Fprintln(o.Out)
+		}
+		pods, err := polymorphichelpers.PodsForObject(o.Client.CoreV1(), info.Object)
+		if err != nil {
+			return err
+		}
+		eventList, err := o.Client.CoreV1().Events(info.Namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		accessor, err := meta.Accessor(info.Object)
+		if err != nil {
+			return err
+		}
+
+		groups := GroupEventsByRevision(accessor, pods, eventList.Items, o.RolloutOnly)
+		if err := o.printGroups(fmt.Sprintf("%s/%s", info.Mapping.Resource.Resource, info.Name), groups); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GroupEventsByRevision returns the events of the workload and of its pods, grouped by the
+// revision of the pods and sorted by time. Events of the workload are related to a pod when
+// their message names it, which is how Kruise controllers report the pods they update.
+func GroupEventsByRevision(workload metav1.Object, pods []corev1.Pod, events []corev1.Event, rolloutOnly bool) []RevisionEvents {
+	revisions := map[string]string{}
+	for _, pod := range pods {
+		revisions[pod.Name] = pod.Labels[appsv1.ControllerRevisionHashLabelKey]
+	}
+
+	grouped := map[string][]corev1.Event{}
+	for _, event := range events {
+		if rolloutOnly && !RolloutEventReasons.Has(event.Reason) {
+			continue
+		}
+
+		involved := event.InvolvedObject
+		revision, isPod := "", false
+		if involved.Kind == "Pod" {
+			revision, isPod = revisions[involved.Name]
+		}
+		isWorkload := (len(involved.UID) > 0 && involved.UID == workload.GetUID()) || (len(involved.UID) == 0 && involved.Kind != "Pod" && involved.Name == workload.GetName())
+		if !isPod && !isWorkload {
+			continue
+		}
+		if isWorkload {
+			revision = revisionFromMessage(event.Message, revisions)
+		}
+		if len(revision) == 0 {
+			revision = unknownRevision
+		}
+		grouped[revision] = append(grouped[revision], event)
+	}
+
+	var groups []RevisionEvents
+	for revision, events := range grouped {
+		sort.SliceStable(events, func(i, j int) bool { return eventTime(events[i]).Before(eventTime(events[j])) })
+		groups = append(groups, RevisionEvents{Revision: revision, Events: events})
+	}
+	// the revision with the most recent event, usually the updated one, is shown last
+	sort.Slice(groups, func(i, j int) bool {
+		return eventTime(groups[i].Events[len(groups[i].Events)-1]).Before(eventTime(groups[j].Events[len(groups[j].Events)-1]))
+	})
+	return groups
+}
+
+func revisionFromMessage(message string, revisions map[string]string) string {
+	// prefer the longest name, so that pod demo-1 is not matched in a message about demo-12
+	var matched string
+	for name := range revisions {
+		if len(name) > len(matched) && strings.Contains(message, name) {
+			matched = name
+		}
+	}
+	return revisions[matched]
+}
+
+func eventTime(event corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.FirstTimestamp.Time
+}
+
+func (o *EventsOptions) printGroups(name string, groups []RevisionEvents) error {
+	if len(groups) == 0 {
+		fmt.Fprintf(o.Out, "No events found for %s\n", name)
+		return nil
+	}
+
+	w := internalcmdutil.NewTableWriter(o.Out)
+	for i, group := range groups {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "REVISION %s:\n", group.Revision)
+		fmt.Fprintln(w, "LAST SEEN\tTYPE\tREASON\tOBJECT\tCOUNT\tMESSAGE")
+		for _, event := range group.Events {
+			lastSeen := "<unknown>"
+			if t := eventTime(event); !t.IsZero() {
+				lastSeen = duration.HumanDuration(time.Since(t))
+			}
+			object := fmt.Sprintf("%s/%s", strings.ToLower(event.InvolvedObject.Kind), event.InvolvedObject.Name)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", lastSeen, event.Type, event.Reason, object, event.Count, strings.TrimSpace(event.Message))
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newEvent(kind, name, reason, message string, minutesAgo int) corev1.Event {
+	return corev1.Event{
+		InvolvedObject: corev1.ObjectReference{Kind: kind, Name: name},
+		Reason:         reason,
+		Message:        message,
+		LastTimestamp:  metav1.NewTime(time.Now().Add(-time.Duration(minutesAgo) * time.Minute)),
+	}
+}
+
+func TestGroupEventsByRevision(t *testing.T) {
+	cs := &kruiseappsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Name: "demo", UID: "uid-demo"}}
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "demo-1", Labels: map[string]string{"controller-revision-hash": "demo-v1"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "demo-12", Labels: map[string]string{"controller-revision-hash": "demo-v2"}}},
+	}
+	updated := newEvent("CloneSet", "demo", "SuccessfulUpdatePodInPlace", "successfully update pod demo-12 in-place", 1)
+	updated.InvolvedObject.UID = "uid-demo"
+	events := []corev1.Event{
+		updated,
+		newEvent("CloneSet", "demo", "SuccessfulCreate", "succeed to create pod demo-1", 10),
+		newEvent("Pod", "demo-1", "Pulled", "Container image pulled", 9),
+		newEvent("Pod", "demo-12", "Killing", "Stopping container app", 2),
+		newEvent("CloneSet", "demo", "SuccessfulDelete", "succeed to delete pod demo-0", 20),
+		newEvent("Pod", "other", "SuccessfulCreate", "not ours", 1),
+		newEvent("CloneSet", "other", "SuccessfulCreate", "not ours", 1),
+	}
+
+	groups := GroupEventsByRevision(cs, pods, events, false)
+	expected := map[string][]string{
+		unknownRevision: {"SuccessfulDelete"},
+		"demo-v1":       {"SuccessfulCreate", "Pulled"},
+		"demo-v2":       {"Killing", "SuccessfulUpdatePodInPlace"},
+	}
+	checkGroups(t, groups, []string{unknownRevision, "demo-v1", "demo-v2"}, expected)
+
+	groups = GroupEventsByRevision(cs, pods, events, true)
+	expected = map[string][]string{
+		unknownRevision: {"SuccessfulDelete"},
+		"demo-v1":       {"SuccessfulCreate"},
+		"demo-v2":       {"SuccessfulUpdatePodInPlace"},
+	}
+	checkGroups(t, groups, []string{unknownRevision, "demo-v1", "demo-v2"}, expected)
+}
+
+func checkGroups(t *testing.T, groups []RevisionEvents, order []string, expected map[string][]string) {
+	if len(groups) != len(order) {
+		t.Fatalf("expected %d groups, got %+v", len(order), groups)
+	}
+	for i, group := range groups {
+		if group.Revision != order[i] {
+			t.Errorf("expected group %d to be %s, got %s", i, order[i], group.Revision)
+		}
+		var reasons []string
+		for _, event := range group.Events {
+			reasons = append(reasons, event.Reason)
+		}
+		if len(reasons) != len(expected[group.Revision]) {
+			t.Errorf("expected reasons %v for %s, got %v", expected[group.Revision], group.Revision, reasons)
+			continue
+		}
+		for j := range reasons {
+			if reasons[j] != expected[group.Revision][j] {
+				t.Errorf("expected reasons %v for %s, got %v", expected[group.Revision], group.Revision, reasons)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+const (
+	artifactsIndexFile  = "index.json"
+	artifactsStdoutFile = "stdout"
+	artifactsStderrFile = "stderr"
+	artifactsExitFile   = "exit-code"
+)
+
+// ArtifactsIndex is the index.json of the --output-dir of a fan-out exec.
+type ArtifactsIndex struct {
+	Command []string         `json:"command"`
+	Results []ArtifactResult `json:"results"`
+}
+
+// ArtifactResult is the result of the command in a pod, whose output is stored in the Stdout and
+// Stderr files, relative to the output directory.
+type ArtifactResult struct {
+	Node      string `json:"node,omitempty"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	// ExitCode is the exit code of the command, or -1 if it could not be run.
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// podArtifacts are the files the output of the command in a pod is stored in.
+type podArtifacts struct {
+	dir    string
+	result ArtifactResult
+	stdout *os.File
+	stderr *os.File
+}
+
+// newPodArtifacts creates the directory of pod in dir, and the files its output is stored in.
+func newPodArtifacts(dir, node string, pod *corev1.Pod) (*podArtifacts, error) {
+	a := &podArtifacts{
+		dir: filepath.Join(dir, pod.Name),
+		result: ArtifactResult{
+			Node:      node,
+			Namespace: pod.Namespace,
+			Pod:       pod.Name,
+			Stdout:    filepath.Join(pod.Name, artifactsStdoutFile),
+			Stderr:    filepath.Join(pod.Name, artifactsStderrFile),
+		},
+	}
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return nil, err
+	}
+	var err error
+	if a.stdout, err = os.Create(filepath.Join(dir, a.result.Stdout)); err != nil {
+		return nil, err
+	}
+	if a.stderr, err = os.Create(filepath.Join(dir, a.result.Stderr)); err != nil {
+		a.stdout.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// finish records the exit code of err, the error of the command, and closes the files.
+func (a *podArtifacts) finish(err error) error {
+	a.result.ExitCode = exitCodeFor(err)
+	if err != nil {
+		a.result.Error = err.Error()
+	}
+	stdoutErr, stderrErr := a.stdout.Close(), a.stderr.Close()
+	if stdoutErr != nil {
+		return stdoutErr
+	}
+	if stderrErr != nil {
+		return stderrErr
+	}
+	return ioutil.WriteFile(filepath.Join(a.dir, artifactsExitFile), []byte(strconv.Itoa(a.result.ExitCode)+"\n"), 0644)
+}
+
+// exitCodeFor returns the exit code of the command that failed with err, 0 if err is nil, or -1
+// if the command could not be run.
+func exitCodeFor(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(utilexec.ExitError); ok && exitErr.Exited() {
+		return exitErr.ExitStatus()
+	}
+	return -1
+}
+
+// writeArtifactsIndex writes the index.json of the results of command to dir.
+func writeArtifactsIndex(dir string, command []string, results []ArtifactResult) error {
+	data, err := json.MarshalIndent(ArtifactsIndex{Command: command, Results: results}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, artifactsIndexFile), append(data, '\n'), 0644)
+}
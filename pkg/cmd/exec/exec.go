@@ -29,6 +29,7 @@ import (
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/resource"
 	coreclient "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -59,11 +60,36 @@ var (
 		# Switch to raw terminal mode, sends stdin to 'bash' in working sidecar container from cloneset myclone 
 		# and sends stdout/stderr from 'bash' back to the client
 		kubectl kruise exec clone/myclone -S sidecar-container -it -- bash
+
+		# Same, reconnecting to the new working sidecar container when a hot upgrade of the SidecarSet switches it
+		kubectl kruise exec clone/myclone -S sidecar-container -it --follow-hot-upgrade -- bash
+
+		# Wait up to 5 minutes for a ready pod of the updated revision of the cloneset myclone, then run 'date' in it
+		kubectl kruise exec clone/myclone --wait-ready --revision updated --timeout 5m -- date
+
+		# Run 'date' in a pod of revision 3 of the cloneset myclone, as listed by 'rollout history'
+		kubectl kruise exec clone/myclone --revision 3 -- date
+
+		# Run 'df -h' in the pod of the advanced daemonset myads on node node-1
+		kubectl kruise exec ads/myads --node node-1 -- df -h
+
+		# Run 'df -h' in the pod of the advanced daemonset myads on each node, prefixing the output by the node name
+		kubectl kruise exec ads/myads --all-nodes -- df -h
+
+		# Same, storing the output and exit code of the command in each pod in the directory results/
+		kubectl kruise exec ads/myads --all-nodes --output-dir results/ -- df -h
+
+		# Run 'df -h' in the pods of an advanced daemonset on the nodes of the web pool only
+		kubectl kruise exec ads/myads --all-nodes --node-selector-expr 'pool In (web)' -- df -h
+
+		# Record an interactive session in the cloneset myclone to session.cast, replayable with 'asciinema play'
+		kubectl kruise exec clone/myclone -it --record-session session.cast --record-redact 'password=\S+' -- bash
 		`))
 )
 
 const (
-	defaultPodExecTimeout = 60 * time.Second
+	defaultPodExecTimeout   = 60 * time.Second
+	defaultWaitReadyTimeout = 5 * time.Minute
 )
 
 func NewCmdExec(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
@@ -92,8 +118,22 @@ func NewCmdExec(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.C
 	// TODO support UID
 	cmd.Flags().StringVarP(&options.ContainerName, "container", "c", options.ContainerName, "Container name. If omitted, the first container in the pod will be chosen")
 	cmd.Flags().StringVarP(&options.SidecarSetContainer, "sidecar", "S", options.SidecarSetContainer, "SidecarSet container name.When sidecarset is hotUpgrade, the working container will be chosen")
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc("container", util.ContainerCompletionFunc(f, "pods")))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc("sidecar", util.SidecarCompletionFunc(f, "pods")))
 	cmd.Flags().BoolVarP(&options.Stdin, "stdin", "i", options.Stdin, "Pass stdin to the container")
 	cmd.Flags().BoolVarP(&options.TTY, "tty", "t", options.TTY, "Stdin is a TTY")
+	cmd.Flags().BoolVar(&options.WaitReady, "wait-ready", options.WaitReady, "If true, wait for a ready pod, of --revision if set, instead of failing when the pods of the workload are still starting")
+	cmd.Flags().StringVar(&options.Revision, "revision", options.Revision, "The revision of the pod of the workload: 'updated', 'stable', a revision number as listed by 'rollout history', or a revision hash")
+	cmd.Flags().DurationVar(&options.WaitReadyTimeout, "timeout", defaultWaitReadyTimeout, "With --wait-ready, the length of time to wait for a ready pod")
+	cmd.Flags().StringVar(&options.Node, "node", options.Node, "Execute the command in the pod of the workload, such as an Advanced DaemonSet, on this node")
+	cmd.Flags().BoolVar(&options.AllNodes, "all-nodes", options.AllNodes, "If true, execute the command in the pod of the workload on each node, with the output prefixed by the node name")
+	util.AddNodeSelectorExprFlagVar(cmd, &options.NodeExprs)
+	cmd.Flags().StringVar(&options.OutputDir, "output-dir", options.OutputDir, "With --all-nodes, the directory to store the stdout, stderr and exit code of the command in each pod in, with an index.json of the results")
+	cmd.Flags().StringVar(&options.RecordSession, "record-session", options.RecordSession, "Record the output of the interactive session to this file in the asciinema v2 format, for audit or sharing")
+	cmd.Flags().Int64Var(&options.RecordMaxSize, "record-max-size", defaultRecordMaxSize, "With --record-session, the maximum size of the recording in bytes, after which the output is no longer recorded. Zero means no limit")
+	cmd.Flags().StringArrayVar(&options.RecordRedact, "record-redact", options.RecordRedact, "With --record-session, a regular expression whose matches are replaced by "+recordRedacted+" in the recording, such as 'password=\\S+'. Can be repeated")
+	cmd.Flags().BoolVar(&options.FollowHotUpgrade, "follow-hot-upgrade", options.FollowHotUpgrade, "With --sidecar and --stdin, reconnect the session to the new working container when a hot upgrade of the SidecarSet switches it, instead of only warning")
+	cmd.Flags().BoolVar(&options.CRLF, "crlf", options.CRLF, "If true, convert the LF line endings of the output to CRLF when there is no TTY, for Windows consoles and tools")
 	return cmd
 }
 
@@ -128,11 +168,16 @@ type StreamOptions struct {
 	TTY                 bool
 	// minimize unnecessary output
 	Quiet bool
+	// convert the LF line endings of the output to CRLF when there is no TTY
+	CRLF bool
 	// InterruptParent, if set, is used to handle interrupts while attached
 	InterruptParent *interrupt.Handler
 
 	genericclioptions.IOStreams
 
+	// restoreConsole restores the console modes changed by the TTY setup
+	restoreConsole func()
+
 	// for testing
 	overrideStreams func() (io.ReadCloser, io.Writer, io.Writer)
 	isTerminalIn    func(t term.TTY) bool
@@ -152,8 +197,34 @@ type ExecOptions struct {
 
 	Builder          func() *resource.Builder
 	ExecutablePodFn  internalpolymorphichelpers.AttachablePodForObjectFunc
+	ReadyPodFn       func(genericclioptions.RESTClientGetter, runtime.Object, string, time.Duration) (*corev1.Pod, error)
+	RevisionPodFn    func(genericclioptions.RESTClientGetter, runtime.Object, string) (*corev1.Pod, error)
 	restClientGetter genericclioptions.RESTClientGetter
 
+	// Revision selects the pod of the workload, and WaitReady waits up to WaitReadyTimeout for it to be ready
+	WaitReady        bool
+	Revision         string
+	WaitReadyTimeout time.Duration
+
+	// Node selects the pod of the workload on a node, and AllNodes runs the command in the pod on each node
+	Node         string
+	AllNodes     bool
+	OutputDir    string
+	PodsByNodeFn func(genericclioptions.RESTClientGetter, runtime.Object, string) (map[string]*corev1.Pod, error)
+	// NodeExprs restricts --all-nodes to the nodes matching them
+	NodeExprs        []string
+	nodeRequirements []corev1.NodeSelectorRequirement
+	NodeClient       coreclient.NodesGetter
+
+	// RecordSession is the file the output of the session is recorded to, in the asciinema v2 format
+	RecordSession string
+	RecordMaxSize int64
+	RecordRedact  []string
+
+	// FollowHotUpgrade reconnects the session to the new working container of the SidecarSet
+	// container when a hot upgrade stops the one it is in
+	FollowHotUpgrade bool
+
 	Pod           *corev1.Pod
 	Executor      RemoteExecutor
 	PodClient     coreclient.PodsGetter
@@ -184,6 +255,9 @@ func (p *ExecOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, argsIn []s
 	}
 
 	p.ExecutablePodFn = internalpolymorphichelpers.AttachablePodForObjectFn
+	p.ReadyPodFn = internalpolymorphichelpers.ReadyPodForObject
+	p.RevisionPodFn = internalpolymorphichelpers.PodOfRevisionForObject
+	p.PodsByNodeFn = internalpolymorphichelpers.PodsByNodeForObject
 
 	p.GetPodTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
 	if err != nil {
@@ -211,8 +285,10 @@ func (p *ExecOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, argsIn []s
 		return err
 	}
 	p.PodClient = clientset.CoreV1()
+	p.NodeClient = clientset.CoreV1()
 
-	return nil
+	p.nodeRequirements, err = util.ParseNodeSelectorExprs(p.NodeExprs)
+	return err
 }
 
 // Validate checks that the provided exec options are specified.
@@ -226,7 +302,25 @@ func (p *ExecOptions) Validate() error {
 	if p.Out == nil || p.ErrOut == nil {
 		return fmt.Errorf("both output and error output must be provided")
 	}
-	return nil
+	if p.WaitReadyTimeout < 0 {
+		return fmt.Errorf("--timeout must not be negative")
+	}
+	if len(p.RecordSession) > 0 && (!p.Stdin || !p.TTY) {
+		return fmt.Errorf("--record-session requires an interactive session with --stdin and --tty")
+	}
+	if len(p.RecordSession) > 0 && p.AllNodes {
+		return fmt.Errorf("--record-session can not be used with --all-nodes")
+	}
+	if p.FollowHotUpgrade && (len(p.SidecarSetContainer) == 0 || !p.Stdin) {
+		return fmt.Errorf("--follow-hot-upgrade requires --sidecar and an interactive session with --stdin")
+	}
+	if p.FollowHotUpgrade && p.AllNodes {
+		return fmt.Errorf("--follow-hot-upgrade can not be used with --all-nodes")
+	}
+	if p.RecordMaxSize < 0 {
+		return fmt.Errorf("--record-max-size must not be negative")
+	}
+	return p.validateNodes()
 }
 
 func (o *StreamOptions) SetupTTY() term.TTY {
@@ -267,7 +361,9 @@ func (o *StreamOptions) SetupTTY() term.TTY {
 	t.Raw = true
 
 	if o.overrideStreams == nil {
-		// use dockerterm.StdStreams() to get the right I/O handles on Windows
+		// use dockerterm.StdStreams() to get the right I/O handles on Windows, and restore the
+		// console modes it changes when done
+		o.restoreConsole = saveConsoleModes()
 		o.overrideStreams = dockerterm.StdStreams
 	}
 	stdin, stdout, _ := o.overrideStreams()
@@ -283,10 +379,7 @@ func (o *StreamOptions) SetupTTY() term.TTY {
 
 // Run executes a validated remote execution against a pod.
 func (p *ExecOptions) Run() error {
-	var (
-		err           error
-		containerName string
-	)
+	var err error
 	// we still need legacy pod getter when PodName in ExecOptions struct is provided,
 	// since there are any other command run this function by providing Podname with PodsGetter
 	// and without resource builder, eg: `kubectl cp`.
@@ -295,6 +388,14 @@ func (p *ExecOptions) Run() error {
 		if err != nil {
 			return err
 		}
+		if p.WaitReady {
+			p.Pod, err = p.ReadyPodFn(p.restClientGetter, p.Pod, p.Revision, p.WaitReadyTimeout)
+		} else if len(p.Revision) > 0 {
+			p.Pod, err = p.RevisionPodFn(p.restClientGetter, p.Pod, p.Revision)
+		}
+		if err != nil {
+			return err
+		}
 	} else {
 		builder := p.Builder().
 			WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
@@ -309,19 +410,35 @@ func (p *ExecOptions) Run() error {
 			return err
 		}
 
-		p.Pod, err = p.ExecutablePodFn(p.restClientGetter, obj, p.GetPodTimeout)
+		if len(p.Node) > 0 || p.AllNodes {
+			return p.runOnNodes(obj)
+		}
+		if p.WaitReady {
+			p.Pod, err = p.ReadyPodFn(p.restClientGetter, obj, p.Revision, p.WaitReadyTimeout)
+		} else if len(p.Revision) > 0 {
+			p.Pod, err = p.RevisionPodFn(p.restClientGetter, obj, p.Revision)
+		} else {
+			p.Pod, err = p.ExecutablePodFn(p.restClientGetter, obj, p.GetPodTimeout)
+		}
 		if err != nil {
 			return err
 		}
 	}
 
+	return p.execPod()
+}
+
+// execPod executes the command in a container of p.Pod.
+func (p *ExecOptions) execPod() error {
+	var containerName string
 	pod := p.Pod
 
 	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
 		return fmt.Errorf("cannot exec into a container in a completed pod; current phase is %s", pod.Status.Phase)
 	}
 	hotUpgradeContainerInfos := util.GetPodHotUpgradeInfoInAnnotations(pod)
-	if workingContainer, ok := hotUpgradeContainerInfos[p.SidecarSetContainer]; ok {
+	workingContainer, hotUpgrade := hotUpgradeContainerInfos[p.SidecarSetContainer]
+	if hotUpgrade {
 		containerName = workingContainer
 		fmt.Fprintf(p.ErrOut, "Enter working container %s of SidecarSet.\n", containerName)
 	} else {
@@ -340,44 +457,96 @@ func (p *ExecOptions) Run() error {
 
 	// ensure we can recover the terminal while attached
 	t := p.SetupTTY()
+	if p.restoreConsole != nil {
+		defer p.restoreConsole()
+	}
 
-	var sizeQueue remotecommand.TerminalSizeQueue
+	errOut := p.ErrOut
 	if t.Raw {
-		// this call spawns a goroutine to monitor/update the terminal size
-		sizeQueue = t.MonitorSize(t.GetSize())
-
 		// unset p.Err if it was previously set because both stdout and stderr go over p.Out when tty is
 		// true
 		p.ErrOut = nil
+	} else if p.CRLF {
+		p.Out = newCRLFWriter(p.Out)
+		if p.ErrOut != nil {
+			p.ErrOut = newCRLFWriter(p.ErrOut)
+		}
 	}
-
-	fn := func() error {
-		restClient, err := restclient.RESTClientFor(p.Config)
+	if len(p.RecordSession) > 0 {
+		stopRecording, err := p.recordSession(t, errOut)
 		if err != nil {
 			return err
 		}
+		defer stopRecording()
+	}
+
+	// the hot upgrades of the working container are only followed in interactive sessions
+	watchHotUpgrade := hotUpgrade && p.Stdin && p.PodClient != nil
+	warnOut := errOut
+	if t.Raw && warnOut != nil {
+		warnOut = newCRLFWriter(warnOut)
+	}
+	in := p.In
+	var sessionIn *sharedInput
+	if watchHotUpgrade && p.FollowHotUpgrade && in != nil {
+		sessionIn = newSharedInput(in)
+	}
+	for {
+		var sizeQueue remotecommand.TerminalSizeQueue
+		if t.Raw {
+			// this call spawns a goroutine to monitor/update the terminal size
+			sizeQueue = t.MonitorSize(t.GetSize())
+		}
+		endInput := func() {}
+		if sessionIn != nil {
+			in, endInput = sessionIn.session()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		if watchHotUpgrade && warnOut != nil {
+			p.watchWorkingContainer(ctx, pod, containerName, warnOut)
+		}
+
+		err := t.Safe(func() error {
+			return p.stream(pod, containerName, in, t.Raw, sizeQueue)
+		})
+		cancel()
+		endInput()
+		if !watchHotUpgrade || !p.FollowHotUpgrade {
+			return err
+		}
+		// the session ends when the hot upgrade stops its working container: reconnect to the new one
+		next, getErr := p.currentWorkingContainer(pod)
+		if getErr != nil || len(next) == 0 || next == containerName {
+			return err
+		}
+		if warnOut != nil {
+			fmt.Fprintf(warnOut, "Reconnecting to working container %s of SidecarSet.\n", next)
+		}
+		containerName = next
+	}
+}
 
-		// TODO: consider abstracting into a client invocation or client helper
-		req := restClient.Post().
-			Resource("pods").
-			Name(pod.Name).
-			Namespace(pod.Namespace).
-			SubResource("exec")
-		req.VersionedParams(&corev1.PodExecOptions{
-			Container: containerName,
-			Command:   p.Command,
-			Stdin:     p.Stdin,
-			Stdout:    p.Out != nil,
-			Stderr:    p.ErrOut != nil,
-			TTY:       t.Raw,
-		}, scheme.ParameterCodec)
-
-		return p.Executor.Execute("POST", req.URL(), p.Config, p.In, p.Out, p.ErrOut, t.Raw, sizeQueue)
-	}
-
-	if err := t.Safe(fn); err != nil {
+// stream executes the command in container of pod, with in as its stdin.
+func (p *ExecOptions) stream(pod *corev1.Pod, containerName string, in io.Reader, tty bool, sizeQueue remotecommand.TerminalSizeQueue) error {
+	restClient, err := restclient.RESTClientFor(p.Config)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	// TODO: consider abstracting into a client invocation or client helper
+	req := restClient.Post().
+		Resource("pods").
+		Name(pod.Name).
+		Namespace(pod.Namespace).
+		SubResource("exec")
+	req.VersionedParams(&corev1.PodExecOptions{
+		Container: containerName,
+		Command:   p.Command,
+		Stdin:     p.Stdin,
+		Stdout:    p.Out != nil,
+		Stderr:    p.ErrOut != nil,
+		TTY:       tty,
+	}, scheme.ParameterCodec)
+
+	return p.Executor.Execute("POST", req.URL(), p.Config, in, p.Out, p.ErrOut, tty, sizeQueue)
 }
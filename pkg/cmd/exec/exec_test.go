@@ -27,6 +27,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/openkruise/kruise-tools/pkg/cmd/util"
 	corev1 "k8s.io/api/core/v1"
@@ -524,3 +525,39 @@ func TestSetupTTY(t *testing.T) {
 		t.Errorf("attach stdin, TTY, is a terminal: tty.Out should equal o.Out")
 	}
 }
+
+func TestValidateWaitReady(t *testing.T) {
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	options := &ExecOptions{StreamOptions: StreamOptions{IOStreams: streams}, ResourceName: "clone/demo", Command: []string{"date"}}
+
+	options.Revision = "updated"
+	if err := options.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	options.WaitReady = true
+	if err := options.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	options.WaitReadyTimeout = -time.Second
+	if err := options.Validate(); err == nil {
+		t.Errorf("expected an error for a negative --timeout")
+	}
+}
+
+func TestValidateRecordSession(t *testing.T) {
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	options := &ExecOptions{StreamOptions: StreamOptions{IOStreams: streams}, ResourceName: "clone/demo", Command: []string{"bash"}}
+
+	options.RecordSession = "session.cast"
+	if err := options.Validate(); err == nil {
+		t.Errorf("expected an error for --record-session without --stdin and --tty")
+	}
+	options.Stdin, options.TTY = true, true
+	if err := options.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	options.RecordMaxSize = -1
+	if err := options.Validate(); err == nil {
+		t.Errorf("expected an error for a negative --record-max-size")
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/openkruise/kruise-tools/pkg/cmd/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// watchWorkingContainer warns on out when a hot upgrade of the SidecarSet container switches the
+// working container of pod away from container, until ctx is done. The old working container is
+// stopped by the hot upgrade, ending the session in it.
+func (p *ExecOptions) watchWorkingContainer(ctx context.Context, pod *corev1.Pod, container string, out io.Writer) {
+	w, err := p.PodClient.Pods(pod.Namespace).Watch(ctx, metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", pod.Name).String(),
+		ResourceVersion: pod.ResourceVersion,
+	})
+	if err != nil {
+		fmt.Fprintf(out, "Warning: unable to watch the hot upgrade of SidecarSet container %s: %v\n", p.SidecarSetContainer, err)
+		return
+	}
+	go func() {
+		defer w.Stop()
+		working := container
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-w.ResultChan():
+				if !ok {
+					return
+				}
+				pod, ok := event.Object.(*corev1.Pod)
+				if !ok {
+					continue
+				}
+				next := util.GetPodHotUpgradeInfoInAnnotations(pod)[p.SidecarSetContainer]
+				if len(next) == 0 || next == working {
+					continue
+				}
+				working = next
+				if p.FollowHotUpgrade {
+					fmt.Fprintf(out, "Warning: SidecarSet container %s was hot upgraded, the session will reconnect to working container %s when it ends.\n", p.SidecarSetContainer, next)
+				} else {
+					fmt.Fprintf(out, "Warning: SidecarSet container %s was hot upgraded, working container %s is going to stop. Use --follow-hot-upgrade to reconnect to working container %s.\n", p.SidecarSetContainer, container, next)
+				}
+			}
+		}
+	}()
+}
+
+// currentWorkingContainer returns the working container of the SidecarSet container of pod, read
+// from the server.
+func (p *ExecOptions) currentWorkingContainer(pod *corev1.Pod) (string, error) {
+	current, err := p.PodClient.Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return util.GetPodHotUpgradeInfoInAnnotations(current)[p.SidecarSetContainer], nil
+}
+
+// sharedInput reads an input for consecutive sessions. The reads of a session return io.EOF once
+// it ends, so that the reads left pending by a session do not consume the input of the next one.
+type sharedInput struct {
+	in    io.Reader
+	start sync.Once
+	data  chan []byte
+	err   error
+
+	mu      sync.Mutex
+	pending []byte
+}
+
+func newSharedInput(in io.Reader) *sharedInput {
+	return &sharedInput{in: in, data: make(chan []byte)}
+}
+
+// session returns the input of a session, and the function ending it.
+func (s *sharedInput) session() (io.Reader, func()) {
+	s.start.Do(func() {
+		go func() {
+			for {
+				buf := make([]byte, 32*1024)
+				n, err := s.in.Read(buf)
+				if n > 0 {
+					s.data <- buf[:n]
+				}
+				if err != nil {
+					s.err = err
+					close(s.data)
+					return
+				}
+			}
+		}()
+	})
+	done := make(chan struct{})
+	var once sync.Once
+	return &sessionInput{s: s, done: done}, func() { once.Do(func() { close(done) }) }
+}
+
+type sessionInput struct {
+	s    *sharedInput
+	done chan struct{}
+}
+
+// Read implements io.Reader
+func (r *sessionInput) Read(p []byte) (int, error) {
+	select {
+	case <-r.done:
+		return 0, io.EOF
+	default:
+	}
+
+	s := r.s
+	s.mu.Lock()
+	if len(s.pending) > 0 {
+		n := copy(p, s.pending)
+		s.pending = s.pending[n:]
+		s.mu.Unlock()
+		return n, nil
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return 0, io.EOF
+	case data, ok := <-s.data:
+		if !ok {
+			return 0, s.err
+		}
+		n := copy(p, data)
+		if n < len(data) {
+			s.mu.Lock()
+			s.pending = append(s.pending, data[n:]...)
+			s.mu.Unlock()
+		}
+		return n, nil
+	}
+}
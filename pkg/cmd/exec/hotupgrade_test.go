@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// hotUpgradeExecutor switches the working container of the pod during the first session, which
+// then ends as the hot upgrade stops its container.
+type hotUpgradeExecutor struct {
+	client     *fake.Clientset
+	containers []string
+	stdin      string
+}
+
+func (e *hotUpgradeExecutor) Execute(method string, url *url.URL, config *restclient.Config, stdin io.Reader, stdout, stderr io.Writer, tty bool, terminalSizeQueue remotecommand.TerminalSizeQueue) error {
+	e.containers = append(e.containers, url.Query().Get("container"))
+	if len(e.containers) > 1 {
+		data, err := ioutil.ReadAll(stdin)
+		e.stdin = string(data)
+		return err
+	}
+	pod, err := e.client.CoreV1().Pods("test").Get(context.TODO(), "foo", metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	pod.Annotations["kruise.io/sidecarset-working-hotupgrade-container"] = `{"sidecar":"sidecar-2"}`
+	if _, err := e.client.CoreV1().Pods("test").Update(context.TODO(), pod, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	return fmt.Errorf("command terminated with exit code 137")
+}
+
+func TestExecFollowHotUpgrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		follow     bool
+		containers []string
+		stdin      string
+		errOut     string
+		expectErr  bool
+	}{
+		{
+			name:       "follow",
+			follow:     true,
+			containers: []string{"sidecar-1", "sidecar-2"},
+			stdin:      "ls\n",
+			errOut:     "Reconnecting to working container sidecar-2 of SidecarSet.",
+		},
+		{
+			name:       "no follow",
+			containers: []string{"sidecar-1"},
+			expectErr:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Namespace:   "test",
+					Annotations: map[string]string{"kruise.io/sidecarset-working-hotupgrade-container": `{"sidecar":"sidecar-1"}`},
+				},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "sidecar-1"}, {Name: "sidecar-2"}}},
+			}
+			client := fake.NewSimpleClientset(pod)
+			ex := &hotUpgradeExecutor{client: client}
+			errOut := &lockedBuffer{}
+			p := &ExecOptions{
+				StreamOptions: StreamOptions{
+					Stdin:               true,
+					SidecarSetContainer: "sidecar",
+					IOStreams:           genericclioptions.IOStreams{In: strings.NewReader("ls\n"), Out: ioutil.Discard, ErrOut: errOut},
+				},
+				Command:          []string{"bash"},
+				FollowHotUpgrade: test.follow,
+				Pod:              pod,
+				PodClient:        client.CoreV1(),
+				Executor:         ex,
+				Config:           &restclient.Config{APIPath: "/api", ContentConfig: restclient.ContentConfig{NegotiatedSerializer: scheme.Codecs, GroupVersion: &schema.GroupVersion{Version: "v1"}}},
+			}
+
+			err := p.execPod()
+			if test.expectErr != (err != nil) {
+				t.Errorf("expected error %v, got %v", test.expectErr, err)
+			}
+			if !reflect.DeepEqual(ex.containers, test.containers) {
+				t.Errorf("expected the sessions in %v, got %v", test.containers, ex.containers)
+			}
+			if ex.stdin != test.stdin {
+				t.Errorf("expected the stdin %q in the new session, got %q", test.stdin, ex.stdin)
+			}
+			if !strings.Contains(errOut.String(), test.errOut) {
+				t.Errorf("expected %q in the output, got %q", test.errOut, errOut.String())
+			}
+		})
+	}
+}
+
+func TestSharedInput(t *testing.T) {
+	in := newSharedInput(strings.NewReader("abcdef"))
+
+	first, end := in.session()
+	buf := make([]byte, 2)
+	if n, err := first.Read(buf); err != nil || string(buf[:n]) != "ab" {
+		t.Fatalf("expected ab, got %q, %v", buf[:n], err)
+	}
+	end()
+	if _, err := first.Read(buf); err != io.EOF {
+		t.Errorf("expected EOF from an ended session, got %v", err)
+	}
+
+	second, _ := in.session()
+	data, err := ioutil.ReadAll(second)
+	if err != nil || string(data) != "cdef" {
+		t.Errorf("expected the rest of the input cdef in the next session, got %q, %v", data, err)
+	}
+}
+
+func TestValidateFollowHotUpgrade(t *testing.T) {
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	options := &ExecOptions{StreamOptions: StreamOptions{IOStreams: streams}, ResourceName: "clone/demo", Command: []string{"bash"}, FollowHotUpgrade: true}
+
+	if err := options.Validate(); err == nil {
+		t.Errorf("expected an error for --follow-hot-upgrade without --sidecar")
+	}
+	options.SidecarSetContainer, options.Stdin = "sidecar", true
+	if err := options.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
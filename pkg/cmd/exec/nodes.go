@@ -0,0 +1,214 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openkruise/kruise-tools/pkg/cmd/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// allNodesParallelism is the number of nodes the command of --all-nodes runs on at the same time.
+const allNodesParallelism = 10
+
+func (p *ExecOptions) validateNodes() error {
+	if len(p.OutputDir) > 0 && !p.AllNodes {
+		return fmt.Errorf("--output-dir requires --all-nodes")
+	}
+	if len(p.NodeExprs) > 0 && !p.AllNodes {
+		return fmt.Errorf("--node-selector-expr requires --all-nodes")
+	}
+	if len(p.Node) == 0 && !p.AllNodes {
+		return nil
+	}
+	if len(p.Node) > 0 && p.AllNodes {
+		return fmt.Errorf("--node and --all-nodes can not be used together")
+	}
+	if len(p.PodName) > 0 {
+		return fmt.Errorf("--node and --all-nodes require a workload, not a pod name")
+	}
+	if p.WaitReady {
+		return fmt.Errorf("--wait-ready can not be used with --node or --all-nodes")
+	}
+	if p.AllNodes && (p.Stdin || p.TTY) {
+		return fmt.Errorf("--all-nodes can not be used with --stdin or --tty")
+	}
+	return nil
+}
+
+// runOnNodes executes the command in the pod of obj on --node, or in the pod on each node with
+// --all-nodes, with the output of each node prefixed by its name and, with --output-dir, stored
+// in a directory per pod along with its exit code and an index of the results.
+func (p *ExecOptions) runOnNodes(obj runtime.Object) error {
+	pods, err := p.PodsByNodeFn(p.restClientGetter, obj, p.Revision)
+	if err != nil {
+		return err
+	}
+	if !p.AllNodes {
+		pod, ok := pods[p.Node]
+		if !ok {
+			return fmt.Errorf("no pod found on node %s", p.Node)
+		}
+		p.Pod = pod
+		return p.execPod()
+	}
+	if len(p.nodeRequirements) > 0 {
+		if pods, err = p.podsOnSelectedNodes(pods); err != nil {
+			return err
+		}
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("no pod found on any node")
+	}
+
+	nodes := make([]string, 0, len(pods))
+	for node := range pods {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	var artifacts []*podArtifacts
+	if len(p.OutputDir) > 0 {
+		for _, node := range nodes {
+			a, err := newPodArtifacts(p.OutputDir, node, pods[node])
+			if err != nil {
+				return err
+			}
+			artifacts = append(artifacts, a)
+		}
+	}
+
+	mu := &sync.Mutex{}
+	errs := make([]error, len(nodes))
+	sem := make(chan struct{}, allNodesParallelism)
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		o := *p
+		o.Pod = pods[node]
+		out := newPrefixWriter(p.Out, "["+node+"] ", mu)
+		errOut := newPrefixWriter(p.ErrOut, "["+node+"] ", mu)
+		o.Out, o.ErrOut = out, errOut
+		if artifacts != nil {
+			o.Out, o.ErrOut = io.MultiWriter(out, artifacts[i].stdout), io.MultiWriter(errOut, artifacts[i].stderr)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = o.execPod()
+			out.Flush()
+			errOut.Flush()
+		}(i)
+	}
+	wg.Wait()
+
+	if artifacts != nil {
+		results := make([]ArtifactResult, len(artifacts))
+		for i, a := range artifacts {
+			if err := a.finish(errs[i]); err != nil {
+				return err
+			}
+			results[i] = a.result
+		}
+		if err := writeArtifactsIndex(p.OutputDir, p.Command, results); err != nil {
+			return err
+		}
+	}
+
+	var failures []string
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", nodes[i], err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("the command failed on %d of %d nodes:\n%s", len(failures), len(nodes), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// prefixWriter prefixes the lines written to it, writing each complete line at once with the
+// lock it shares with the writers of the other nodes, so that their lines are not interleaved.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	mu     *sync.Mutex
+	line   []byte
+}
+
+func newPrefixWriter(w io.Writer, prefix string, mu *sync.Mutex) *prefixWriter {
+	return &prefixWriter{w: w, prefix: prefix, mu: mu}
+}
+
+// Write implements io.Writer
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.line = append(w.line, p...)
+	for {
+		i := bytes.IndexByte(w.line, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.line[:i+1]); err != nil {
+			return 0, err
+		}
+		w.line = w.line[i+1:]
+	}
+}
+
+// Flush writes the last line, if it has no line ending.
+func (w *prefixWriter) Flush() {
+	if len(w.line) > 0 {
+		_ = w.writeLine(append(w.line, '\n'))
+		w.line = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := fmt.Fprintf(w.w, "%s%s", w.prefix, line)
+	return err
+}
+
+// podsOnSelectedNodes returns the pods by node on the nodes matching --node-selector-expr.
+func (p *ExecOptions) podsOnSelectedNodes(pods map[string]*corev1.Pod) (map[string]*corev1.Pod, error) {
+	selector, err := util.NodeLabelSelector(p.nodeRequirements)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := p.NodeClient.Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	selected := map[string]*corev1.Pod{}
+	for _, node := range nodes.Items {
+		if pod, ok := pods[node.Name]; ok {
+			selected[node.Name] = pod
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// nodeExecutor prints the name of the pod it executes in, in two writes, and fails in failPod.
+type nodeExecutor struct {
+	failPod string
+}
+
+func (e *nodeExecutor) Execute(method string, url *url.URL, config *restclient.Config, stdin io.Reader, stdout, stderr io.Writer, tty bool, terminalSizeQueue remotecommand.TerminalSizeQueue) error {
+	pod := path.Base(path.Dir(url.Path))
+	if pod == e.failPod {
+		fmt.Fprint(stderr, "boom")
+		return utilexec.CodeExitError{Err: fmt.Errorf("exit code 1"), Code: 1}
+	}
+	fmt.Fprintf(stdout, "hello from ")
+	fmt.Fprintf(stdout, "%s\nbye\n", pod)
+	return nil
+}
+
+func nodePod(name, node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+		Spec:       corev1.PodSpec{NodeName: node, Containers: []corev1.Container{{Name: "main"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func newNodesOptions(out, errOut *bytes.Buffer, failPod string) *ExecOptions {
+	return &ExecOptions{
+		StreamOptions: StreamOptions{IOStreams: genericclioptions.IOStreams{Out: out, ErrOut: errOut}},
+		Command:       []string{"date"},
+		Executor:      &nodeExecutor{failPod: failPod},
+		Config:        &restclient.Config{APIPath: "/api", ContentConfig: restclient.ContentConfig{NegotiatedSerializer: scheme.Codecs, GroupVersion: &schema.GroupVersion{Version: "v1"}}},
+		PodsByNodeFn: func(genericclioptions.RESTClientGetter, runtime.Object, string) (map[string]*corev1.Pod, error) {
+			return map[string]*corev1.Pod{"node-a": nodePod("ds-a", "node-a"), "node-b": nodePod("ds-b", "node-b")}, nil
+		},
+	}
+}
+
+func TestRunOnNode(t *testing.T) {
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	o := newNodesOptions(out, errOut, "")
+	o.Node = "node-b"
+	if err := o.runOnNodes(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "hello from ds-b\nbye\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	o.Node = "node-c"
+	if err := o.runOnNodes(nil); err == nil || !strings.Contains(err.Error(), "no pod found on node node-c") {
+		t.Errorf("expected no pod on node-c, got %v", err)
+	}
+}
+
+func TestRunOnAllNodes(t *testing.T) {
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	o := newNodesOptions(out, errOut, "ds-b")
+	o.AllNodes = true
+	err := o.runOnNodes(nil)
+	if err == nil || !strings.Contains(err.Error(), "failed on 1 of 2 nodes") || !strings.Contains(err.Error(), "node-b: exit code 1") {
+		t.Errorf("expected the command to fail on node-b, got %v", err)
+	}
+	if out.String() != "[node-a] hello from ds-a\n[node-a] bye\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if errOut.String() != "[node-b] boom\n" {
+		t.Errorf("unexpected error output %q", errOut.String())
+	}
+}
+
+func TestRunOnSelectedNodes(t *testing.T) {
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	o := newNodesOptions(out, errOut, "")
+	o.AllNodes = true
+	o.NodeClient = fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"pool": "batch"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b", Labels: map[string]string{"pool": "web"}}},
+	).CoreV1()
+	o.nodeRequirements = []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"web", "api"}}}
+	if err := o.runOnNodes(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "[node-b] hello from ds-b\n[node-b] bye\n" {
+		t.Errorf("expected the command to run on node-b only, got %q", out.String())
+	}
+
+	o.nodeRequirements = []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpDoesNotExist}}
+	if err := o.runOnNodes(nil); err == nil || !strings.Contains(err.Error(), "no pod found on any node") {
+		t.Errorf("expected no pod on the selected nodes, got %v", err)
+	}
+}
+
+func TestRunOnAllNodesOutputDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	o := newNodesOptions(&bytes.Buffer{}, &bytes.Buffer{}, "ds-b")
+	o.AllNodes = true
+	o.OutputDir = dir
+	if err := o.runOnNodes(nil); err == nil {
+		t.Errorf("expected the command to fail on node-b")
+	}
+
+	for file, expected := range map[string]string{
+		"ds-a/stdout":    "hello from ds-a\nbye\n",
+		"ds-a/stderr":    "",
+		"ds-a/exit-code": "0\n",
+		"ds-b/stdout":    "",
+		"ds-b/stderr":    "boom",
+		"ds-b/exit-code": "1\n",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil || string(data) != expected {
+			t.Errorf("expected %s to be %q, got %q, %v", file, expected, data, err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index ArtifactsIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	expected := ArtifactsIndex{
+		Command: []string{"date"},
+		Results: []ArtifactResult{
+			{Node: "node-a", Namespace: "test", Pod: "ds-a", Stdout: "ds-a/stdout", Stderr: "ds-a/stderr"},
+			{Node: "node-b", Namespace: "test", Pod: "ds-b", ExitCode: 1, Error: "exit code 1", Stdout: "ds-b/stdout", Stderr: "ds-b/stderr"},
+		},
+	}
+	if !reflect.DeepEqual(index, expected) {
+		t.Errorf("expected index %+v, got %+v", expected, index)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	mu := &sync.Mutex{}
+	a, b := newPrefixWriter(out, "[a] ", mu), newPrefixWriter(out, "[b] ", mu)
+	fmt.Fprint(a, "one ")
+	fmt.Fprint(b, "two\n")
+	fmt.Fprint(a, "line\nlast")
+	a.Flush()
+	b.Flush()
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(lines)
+	expected := []string{"[a] last", "[a] one line", "[b] two"}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %v, got %v", expected, lines)
+	}
+}
+
+func TestValidateNodes(t *testing.T) {
+	tests := []struct {
+		o        ExecOptions
+		expected string
+	}{
+		{o: ExecOptions{}},
+		{o: ExecOptions{Node: "node-a"}},
+		{o: ExecOptions{Node: "node-a", AllNodes: true}, expected: "can not be used together"},
+		{o: ExecOptions{AllNodes: true, StreamOptions: StreamOptions{PodName: "demo"}}, expected: "not a pod name"},
+		{o: ExecOptions{AllNodes: true, WaitReady: true}, expected: "--wait-ready"},
+		{o: ExecOptions{AllNodes: true, StreamOptions: StreamOptions{Stdin: true}}, expected: "--stdin"},
+		{o: ExecOptions{Node: "node-a", StreamOptions: StreamOptions{Stdin: true, TTY: true}}},
+		{o: ExecOptions{AllNodes: true, OutputDir: "results"}},
+		{o: ExecOptions{Node: "node-a", OutputDir: "results"}, expected: "--output-dir requires --all-nodes"},
+		{o: ExecOptions{AllNodes: true, NodeExprs: []string{"pool In (web)"}}},
+		{o: ExecOptions{Node: "node-a", NodeExprs: []string{"pool In (web)"}}, expected: "--node-selector-expr requires --all-nodes"},
+	}
+	for i, test := range tests {
+		err := test.o.validateNodes()
+		if len(test.expected) == 0 && err != nil || len(test.expected) > 0 && (err == nil || !strings.Contains(err.Error(), test.expected)) {
+			t.Errorf("%d: expected error %q, got %v", i, test.expected, err)
+		}
+	}
+}
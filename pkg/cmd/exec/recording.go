@@ -0,0 +1,207 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"k8s.io/kubectl/pkg/util/term"
+)
+
+const (
+	defaultRecordMaxSize = 10 * 1024 * 1024
+	recordRedacted       = "[REDACTED]"
+)
+
+// castHeader is the header of an asciinema v2 recording.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     uint16            `json:"width"`
+	Height    uint16            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// SessionRecorder records the output of a session in the asciinema v2 format: a JSON header line
+// followed by a [time, "o", data] line per write, with the time in seconds since the start.
+type SessionRecorder struct {
+	// Redact is called with the output before it is recorded, to remove secrets from it. The
+	// output is recorded as it is written, so a secret split across two writes is not matched.
+	Redact func(data string) string
+
+	mu        sync.Mutex
+	w         io.Writer
+	start     time.Time
+	now       func() time.Time
+	maxSize   int64
+	size      int64
+	truncated bool
+	// pending are the bytes of an incomplete UTF-8 character ending the last write
+	pending []byte
+}
+
+// NewSessionRecorder writes the header of a width x height session named title to w, and returns
+// the recorder of its output. The recording stops once it reaches maxSize bytes, if positive.
+func NewSessionRecorder(w io.Writer, width, height uint16, title string, maxSize int64, now func() time.Time) (*SessionRecorder, error) {
+	r := &SessionRecorder{w: w, now: now, start: now(), maxSize: maxSize}
+	header := castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": os.Getenv("TERM")},
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	return r, r.writeLine(data)
+}
+
+// Writer returns a writer writing to w and recording what it writes.
+func (r *SessionRecorder) Writer(w io.Writer) io.Writer {
+	return &recordingWriter{w: w, r: r}
+}
+
+// Truncated returns true if the recording stopped at its maximum size.
+func (r *SessionRecorder) Truncated() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.truncated
+}
+
+// record records p as output, keeping an incomplete UTF-8 character at its end for the next call.
+func (r *SessionRecorder) record(p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.truncated {
+		return
+	}
+	data := append(r.pending, p...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	r.pending = append([]byte(nil), data[cut:]...)
+	if cut == 0 {
+		return
+	}
+
+	output := string(data[:cut])
+	if r.Redact != nil {
+		output = r.Redact(output)
+	}
+	elapsed := float64(r.now().Sub(r.start)) / float64(time.Second)
+	event, err := json.Marshal([]interface{}{json.Number(fmt.Sprintf("%.6f", elapsed)), "o", output})
+	if err != nil {
+		return
+	}
+	if r.maxSize > 0 && r.size+int64(len(event))+1 > r.maxSize {
+		r.truncated = true
+		return
+	}
+	_ = r.writeLine(event)
+}
+
+func (r *SessionRecorder) writeLine(data []byte) error {
+	n, err := fmt.Fprintf(r.w, "%s\n", data)
+	r.size += int64(n)
+	return err
+}
+
+type recordingWriter struct {
+	w io.Writer
+	r *SessionRecorder
+}
+
+// Write implements io.Writer
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.r.record(p[:n])
+	return n, err
+}
+
+// RedactPatterns returns a Redact function of SessionRecorder replacing the matches of patterns.
+func RedactPatterns(patterns []string) (func(string) string, error) {
+	var regexps []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --record-redact pattern %q: %v", pattern, err)
+		}
+		regexps = append(regexps, re)
+	}
+	return func(data string) string {
+		for _, re := range regexps {
+			data = re.ReplaceAllString(data, recordRedacted)
+		}
+		return data
+	}, nil
+}
+
+// recordSession records the output of the session of t to the --record-session file, and returns
+// the function that ends the recording, printing to errOut if it was truncated.
+func (p *ExecOptions) recordSession(t term.TTY, errOut io.Writer) (func(), error) {
+	redact, err := RedactPatterns(p.RecordRedact)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Create(p.RecordSession)
+	if err != nil {
+		return nil, err
+	}
+	width, height := uint16(80), uint16(24)
+	if size := t.GetSize(); size != nil {
+		width, height = size.Width, size.Height
+	}
+	title := fmt.Sprintf("%s/%s", p.Pod.Namespace, p.Pod.Name)
+	recorder, err := NewSessionRecorder(file, width, height, title, p.RecordMaxSize, time.Now)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if len(p.RecordRedact) > 0 {
+		recorder.Redact = redact
+	}
+
+	p.Out = recorder.Writer(p.Out)
+	if p.ErrOut != nil {
+		p.ErrOut = recorder.Writer(p.ErrOut)
+	}
+	return func() {
+		if err := file.Close(); err != nil && errOut != nil {
+			fmt.Fprintf(errOut, "Warning: unable to save the session recording %s: %v\n", p.RecordSession, err)
+		}
+		if recorder.Truncated() && errOut != nil {
+			fmt.Fprintf(errOut, "Warning: the session recording %s was truncated at %d bytes\n", p.RecordSession, p.RecordMaxSize)
+		}
+	}, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionRecorder(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	now := start
+	clock := func() time.Time { return now }
+
+	tests := []struct {
+		name    string
+		writes  []string
+		maxSize int64
+		redact  []string
+		expect  []string
+		trunc   bool
+	}{
+		{
+			name:   "events",
+			writes: []string{"$ ls\r\n", "a b\r\n"},
+			expect: []string{`[0.500000,"o","$ ls\r\n"]`, `[1.000000,"o","a b\r\n"]`},
+		},
+		{
+			name:   "utf-8 split across writes",
+			writes: []string{"h\xc3", "\xa9!"},
+			expect: []string{`[0.500000,"o","h"]`, `[1.000000,"o","é!"]`},
+		},
+		{
+			name:   "redacted",
+			writes: []string{"password=s3cret ok\r\n"},
+			redact: []string{`password=\S+`},
+			expect: []string{`[0.500000,"o","[REDACTED] ok\r\n"]`},
+		},
+		{
+			name:    "truncated",
+			writes:  []string{"first\r\n", "second\r\n"},
+			maxSize: 150,
+			expect:  []string{`[0.500000,"o","first\r\n"]`},
+			trunc:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now = start
+			recording := &bytes.Buffer{}
+			r, err := NewSessionRecorder(recording, 120, 40, "default/foo", test.maxSize, clock)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(test.redact) > 0 {
+				if r.Redact, err = RedactPatterns(test.redact); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			out := &bytes.Buffer{}
+			w := r.Writer(out)
+			for _, data := range test.writes {
+				now = now.Add(500 * time.Millisecond)
+				if _, err := w.Write([]byte(data)); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if out.String() != strings.Join(test.writes, "") {
+				t.Errorf("expected the output %q to be written, got %q", strings.Join(test.writes, ""), out.String())
+			}
+			lines := strings.Split(strings.TrimSuffix(recording.String(), "\n"), "\n")
+			header := `{"version":2,"width":120,"height":40,"timestamp":1600000000,"title":"default/foo","env":{"TERM":`
+			if !strings.HasPrefix(lines[0], header) {
+				t.Errorf("expected the header %s, got %s", header, lines[0])
+			}
+			if strings.Join(lines[1:], "\n") != strings.Join(test.expect, "\n") {
+				t.Errorf("expected the events\n%s\ngot\n%s", strings.Join(test.expect, "\n"), strings.Join(lines[1:], "\n"))
+			}
+			if r.Truncated() != test.trunc {
+				t.Errorf("expected truncated %v, got %v", test.trunc, r.Truncated())
+			}
+		})
+	}
+}
+
+func TestRedactPatternsInvalid(t *testing.T) {
+	if _, err := RedactPatterns([]string{"("}); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+}
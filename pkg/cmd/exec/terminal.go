@@ -0,0 +1,51 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"io"
+)
+
+// crlfWriter converts the LF line endings written to it to CRLF, leaving CRLF as is, for the
+// Windows consoles and tools that do not move to the start of the line on LF.
+type crlfWriter struct {
+	w io.Writer
+	// cr is true if the last byte written was CR, which may end a write just before its LF
+	cr bool
+}
+
+func newCRLFWriter(w io.Writer) io.Writer {
+	return &crlfWriter{w: w}
+}
+
+// Write implements io.Writer
+func (c *crlfWriter) Write(p []byte) (int, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(p) + bytes.Count(p, []byte{'\n'}))
+	for _, b := range p {
+		if b == '\n' && !c.cr {
+			buf.WriteByte('\r')
+		}
+		buf.WriteByte(b)
+		c.cr = b == '\r'
+	}
+	if _, err := c.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCRLFWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		writes   []string
+		expected string
+	}{
+		{
+			name:     "lf",
+			writes:   []string{"a\nb\n"},
+			expected: "a\r\nb\r\n",
+		},
+		{
+			name:     "crlf kept",
+			writes:   []string{"a\r\nb\n"},
+			expected: "a\r\nb\r\n",
+		},
+		{
+			name:     "crlf split across writes",
+			writes:   []string{"a\r", "\nb"},
+			expected: "a\r\nb",
+		},
+		{
+			name:     "empty lines",
+			writes:   []string{"\n\n"},
+			expected: "\r\n\r\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w := newCRLFWriter(buf)
+			for _, s := range test.writes {
+				n, err := w.Write([]byte(s))
+				if err != nil || n != len(s) {
+					t.Fatalf("unexpected write result %d, %v", n, err)
+				}
+			}
+			if buf.String() != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+//go:build windows
+// +build windows
+
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// saveConsoleModes saves the modes of the standard console handles and returns a function that
+// restores them. dockerterm.StdStreams turns on virtual terminal processing and leaves it on,
+// which mangles the output of the programs run in the same console after kubectl-kruise exits.
+func saveConsoleModes() func() {
+	var restores []func()
+	for _, f := range []*os.File{os.Stdin, os.Stdout, os.Stderr} {
+		handle := windows.Handle(f.Fd())
+		var mode uint32
+		if err := windows.GetConsoleMode(handle, &mode); err != nil {
+			// not a console
+			continue
+		}
+		restores = append(restores, func() { _ = windows.SetConsoleMode(handle, mode) })
+	}
+	return func() {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+}
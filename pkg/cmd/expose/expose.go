@@ -21,6 +21,7 @@ import (
 	"regexp"
 	"strings"
 
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 
 	"github.com/spf13/cobra"
@@ -196,7 +197,7 @@ func (o *ExposeServiceOptions) Complete(f cmdutil.Factory, cmd *cobra.Command) e
 	o.PrintObj = printer.PrintObj
 
 	o.RecordFlags.Complete(cmd)
-	o.Recorder, err = o.RecordFlags.ToRecorder()
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io"
+	"strings"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/freeze"
+	"github.com/openkruise/kruise-tools/pkg/readonly"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kubectlcmd "k8s.io/kubectl/pkg/cmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// readOnlyKubectlCommands are the kubectl commands that run in read-only mode. The kubectl commands
+// do not use the read-only transport of kubectl-kruise, so the others, including exec and proxy,
+// are refused.
+var readOnlyKubectlCommands = sets.NewString(
+	"api-resources", "api-versions", "auth can-i", "cluster-info", "completion", "config current-context",
+	"config get-clusters", "config get-contexts", "config view", "describe", "diff", "explain", "get",
+	"kustomize", "logs", "options", "plugin", "plugin list", "top", "top node", "top pod", "version", "wait",
+)
+
+// newKubectlFallthroughCommand returns the upstream kubectl command, embedded in this binary, to
+// run args, the command line without the binary name, when kubectl-kruise has no such command,
+// so that kubectl-kruise get, describe, logs and the like behave as with kubectl. It returns
+// nil if kubectl has no such command either.
+//
+// The commands of kubectl-kruise that share their name with kubectl ones, such as rollout, set
+// or scale, handle all the kinds the kubectl ones do, and more, so they never fall through.
+//
+// With -o yaml and -o json, the objects they print are normalized as those of kubectl-kruise, so
+// that the output is stable.
+//
+// In read-only mode, only the kubectl commands that do not change resources are run. The others
+// are run after checking the freeze windows of the cluster.
+func newKubectlFallthroughCommand(args []string, in io.Reader, out, errout io.Writer) *cobra.Command {
+	// kubectl does not know --read-only and --break-glass
+	args = freeze.ParseArgs(readonly.ParseArgs(args))
+	stableOut := internalcmdutil.NewStableOutputWriter(out)
+	upstream := kubectlcmd.NewKubectlCommand(in, stableOut, errout)
+	if !hasSubCommand(upstream, args) {
+		return nil
+	}
+	found, _, _ := upstream.Find(args)
+	path := strings.TrimPrefix(found.CommandPath(), upstream.Name()+" ")
+	if podCommands.Has(path) {
+		withPodRevision(found)
+	}
+	internalcmdutil.WithStableOutput(found, stableOut)
+	if readonly.Enabled() {
+		if !readOnlyKubectlCommands.Has(path) {
+			found.PreRun, found.PreRunE, found.RunE = nil, nil, nil
+			found.Run = func(*cobra.Command, []string) {
+				cmdutil.CheckErr(readonly.Check(PluginPrefix + " " + path))
+			}
+		}
+	}
+	if !readOnlyKubectlCommands.Has(path) {
+		withFreezeCheck(found, PluginPrefix+" "+path)
+	}
+	upstream.Use = PluginPrefix
+	upstream.SetArgs(args)
+	return upstream
+}
+
+// hasSubCommand returns true if args, the command line without the binary name, runs one of the
+// sub commands of root.
+func hasSubCommand(root *cobra.Command, args []string) bool {
+	found, _, err := root.Find(args)
+	return err == nil && found != root
+}
+
+// withFreezeCheck checks the freeze windows of the cluster before cmd runs, as the kubectl commands
+// do not use the freeze transport of kubectl-kruise.
+func withFreezeCheck(cmd *cobra.Command, operation string) {
+	check := func(cmd *cobra.Command) {
+		// the kubeconfig flags of kubectl are persistent flags of its root command
+		flags := genericclioptions.NewConfigFlags(true)
+		for name, value := range map[string]*string{
+			"kubeconfig": flags.KubeConfig, "context": flags.Context, "cluster": flags.ClusterName, "user": flags.AuthInfoName,
+			"namespace": flags.Namespace, "server": flags.APIServer, "token": flags.BearerToken, "as": flags.Impersonate,
+		} {
+			if flag := cmd.Flags().Lookup(name); flag != nil {
+				*value = flag.Value.String()
+			}
+		}
+		namespace, _, err := flags.ToRawKubeConfigLoader().Namespace()
+		cmdutil.CheckErr(err)
+		cmdutil.CheckErr(freeze.Check(flags, namespace, operation))
+	}
+	if preRunE := cmd.PreRunE; preRunE != nil {
+		cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+			check(cmd)
+			return preRunE(cmd, args)
+		}
+		return
+	}
+	preRun := cmd.PreRun
+	cmd.PreRun = func(cmd *cobra.Command, args []string) {
+		check(cmd)
+		if preRun != nil {
+			preRun(cmd, args)
+		}
+	}
+}
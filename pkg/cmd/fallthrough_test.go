@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/readonly"
+)
+
+func TestKubectlFallthroughCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"get", "pods"}, expected: "get"},
+		{args: []string{"-n", "demo", "logs", "pod/demo", "-f"}, expected: "logs"},
+		{args: []string{"config", "view"}, expected: "view"},
+		{args: []string{"nosuch"}},
+		{args: []string{"-n", "demo"}},
+	}
+	for _, test := range tests {
+		cmd := newKubectlFallthroughCommand(test.args, &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+		if len(test.expected) == 0 {
+			if cmd != nil {
+				t.Errorf("%v: expected no kubectl command", test.args)
+			}
+			continue
+		}
+		if cmd == nil {
+			t.Errorf("%v: expected the kubectl %s command", test.args, test.expected)
+			continue
+		}
+		found, _, err := cmd.Find(test.args)
+		if err != nil || found.Name() != test.expected {
+			t.Errorf("%v: expected the kubectl %s command, got %v, %v", test.args, test.expected, found, err)
+		}
+		if cmd.Name() != PluginPrefix {
+			t.Errorf("%v: expected the kubectl command to be named %s, got %s", test.args, PluginPrefix, cmd.Name())
+		}
+	}
+}
+
+func TestKubectlFallthroughCommandReadOnly(t *testing.T) {
+	defer readonly.ParseArgs([]string{"--read-only=false"})
+
+	get := newKubectlFallthroughCommand([]string{"--read-only", "get", "pods"}, &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+	if get == nil {
+		t.Fatalf("expected the kubectl get command")
+	}
+	found, _, err := get.Find([]string{"get", "pods"})
+	if err != nil || found.Name() != "get" || found.Run == nil {
+		t.Errorf("expected the kubectl get command to run, got %v, %v", found, err)
+	}
+
+	for _, args := range [][]string{{"delete", "pod/demo"}, {"exec", "demo", "--", "sh"}} {
+		cmd := newKubectlFallthroughCommand(args, &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+		if cmd == nil {
+			t.Fatalf("%v: expected a kubectl command", args)
+		}
+		found, _, err := cmd.Find(args)
+		if err != nil || found.Name() != args[0] {
+			t.Fatalf("%v: expected the kubectl %s command, got %v, %v", args, args[0], found, err)
+		}
+		if found.RunE != nil || found.PreRunE != nil {
+			t.Errorf("%v: expected the kubectl command to be refused in read-only mode", args)
+		}
+	}
+}
+
+func TestKruiseCommandsDoNotFallThrough(t *testing.T) {
+	for _, args := range [][]string{{"rollout", "status", "cloneset/demo"}, {"set", "image", "cloneset/demo", "nginx=nginx"}, {"scale", "cloneset/demo"}} {
+		root := NewDefaultKubectlCommandWithArgs(append([]string{PluginPrefix}, args...), &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+		// promote is only a kubectl-kruise command
+		if !hasSubCommand(root, []string{"promote"}) {
+			t.Errorf("%v: expected the kubectl-kruise command", args)
+		}
+	}
+	root := NewDefaultKubectlCommandWithArgs([]string{PluginPrefix, "get", "pods"}, &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+	if hasSubCommand(root, []string{"promote"}) {
+		t.Errorf("expected the kubectl command for get")
+	}
+}
+
+func TestKubectlFallthroughCommandPodRevision(t *testing.T) {
+	for _, args := range [][]string{
+		{"logs", "cloneset/demo", "--wait-ready", "--revision", "updated"},
+		{"port-forward", "cloneset/demo", "8080:80", "--revision", "stable"},
+	} {
+		cmd := newKubectlFallthroughCommand(args, &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+		if cmd == nil {
+			t.Fatalf("%v: expected the kubectl %s command", args, args[0])
+		}
+		found, _, err := cmd.Find(args)
+		if err != nil || found.Name() != args[0] {
+			t.Fatalf("%v: expected the kubectl %s command, got %v, %v", args, args[0], found, err)
+		}
+		for _, flag := range []string{"wait-ready", "revision", "timeout"} {
+			if found.Flags().Lookup(flag) == nil {
+				t.Errorf("%v: expected the --%s flag", args, flag)
+			}
+		}
+		if err := found.ParseFlags(args[1:]); err != nil {
+			t.Errorf("unexpected error parsing %v: %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	hookPhasePre  = "pre"
+	hookPhasePost = "post"
+)
+
+// HookAction is the planned action of a command, passed as JSON on the standard input of its hooks.
+type HookAction struct {
+	// Phase is "pre" before the command runs and "post" after it ran.
+	Phase string `json:"phase"`
+	// Command is the command, e.g. "set image".
+	Command string `json:"command"`
+	// Args are the arguments of the command, e.g. ["cloneset/demo", "nginx=nginx:1.25"].
+	Args []string `json:"args"`
+	// Flags are the flags given to the command, including the root flags such as namespace.
+	Flags map[string]string `json:"flags,omitempty"`
+	// ExitCode is the exit code of the command, in the post phase.
+	ExitCode int `json:"exitCode,omitempty"`
+	// Error is the error the command failed with, in the post phase.
+	Error string `json:"error,omitempty"`
+}
+
+// addHooks runs the pre and post hooks of the commands of root around them. A failed pre hook
+// blocks its command, a failed post hook is only reported.
+func addHooks(root *cobra.Command, hooks []config.Hook, errOut io.Writer) {
+	if len(hooks) == 0 {
+		return
+	}
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		path := commandPath(root, cmd)
+		var matched []config.Hook
+		for _, hook := range hooks {
+			if path == hook.Command || strings.HasPrefix(path, hook.Command+" ") {
+				matched = append(matched, hook)
+			}
+		}
+		if len(matched) > 0 && (cmd.Run != nil || cmd.RunE != nil) {
+			withHooks(cmd, path, matched, errOut)
+		}
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+	walk(root)
+}
+
+// commandPath returns the path of cmd without the name of root, e.g. "set image".
+func commandPath(root, cmd *cobra.Command) string {
+	return strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), root.Name()), " ")
+}
+
+// withHooks wraps the run function of cmd, named path, with hooks.
+func withHooks(cmd *cobra.Command, path string, hooks []config.Hook, errOut io.Writer) {
+	before := func(c *cobra.Command, args []string) *HookAction {
+		action := newHookAction(path, c, args)
+		cmdutil.CheckErr(runHooks(hooks, action, errOut))
+		action.Phase = hookPhasePost
+		// the command may exit on failure, so the post hooks run before it does
+		cmdutil.BehaviorOnFatal(func(msg string, code int) {
+			action.ExitCode, action.Error = code, strings.TrimSpace(msg)
+			reportHookError(runHooks(hooks, action, errOut), errOut)
+			exitWithMessage(msg, code)
+		})
+		return action
+	}
+	after := func(action *HookAction, err error) {
+		cmdutil.DefaultBehaviorOnFatal()
+		if err != nil {
+			action.ExitCode, action.Error = cmdutil.DefaultErrorExitCode, err.Error()
+		}
+		reportHookError(runHooks(hooks, action, errOut), errOut)
+	}
+
+	if run := cmd.Run; run != nil {
+		cmd.Run = func(c *cobra.Command, args []string) {
+			action := before(c, args)
+			run(c, args)
+			after(action, nil)
+		}
+		return
+	}
+	runE := cmd.RunE
+	cmd.RunE = func(c *cobra.Command, args []string) error {
+		action := before(c, args)
+		err := runE(c, args)
+		after(action, err)
+		return err
+	}
+}
+
+// newHookAction returns the action of cmd, named path, run with args, in the pre phase.
+func newHookAction(path string, cmd *cobra.Command, args []string) *HookAction {
+	action := &HookAction{Phase: hookPhasePre, Command: path, Args: args}
+	if action.Args == nil {
+		action.Args = []string{}
+	}
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if action.Flags == nil {
+			action.Flags = map[string]string{}
+		}
+		action.Flags[flag.Name] = flag.Value.String()
+	})
+	return action
+}
+
+// runHooks runs the hooks of the phase of action, with action as JSON on their standard input and
+// their output written to errOut, so that the output of the command is not changed.
+func runHooks(hooks []config.Hook, action *HookAction, errOut io.Writer) error {
+	data, err := json.Marshal(action)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		argv := hook.Pre
+		if action.Phase == hookPhasePost {
+			argv = hook.Post
+		}
+		if len(argv) == 0 {
+			continue
+		}
+		hookCmd := exec.Command(argv[0], argv[1:]...)
+		hookCmd.Stdin = bytes.NewReader(data)
+		hookCmd.Stdout = errOut
+		hookCmd.Stderr = errOut
+		if err := hookCmd.Run(); err != nil {
+			if action.Phase == hookPhasePre {
+				return fmt.Errorf("%s is blocked by the pre hook %s: %v", action.Command, argv[0], err)
+			}
+			return fmt.Errorf("the post hook %s of %s failed: %v", argv[0], action.Command, err)
+		}
+	}
+	return nil
+}
+
+func reportHookError(err error, errOut io.Writer) {
+	if err != nil {
+		fmt.Fprintf(errOut, "Warning: %v\n", err)
+	}
+}
+
+// exitWithMessage prints msg and exits with code, as kubectl does on fatal errors.
+func exitWithMessage(msg string, code int) {
+	if len(msg) > 0 {
+		if !strings.HasSuffix(msg, "\n") {
+			msg += "\n"
+		}
+		fmt.Fprint(os.Stderr, msg)
+	}
+	os.Exit(code)
+}
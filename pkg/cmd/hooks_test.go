@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+	"github.com/spf13/cobra"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+func TestAddHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pre, post := filepath.Join(dir, "pre.json"), filepath.Join(dir, "post.json")
+
+	var ran []string
+	newRoot := func() *cobra.Command {
+		root := &cobra.Command{Use: PluginPrefix}
+		root.PersistentFlags().StringP("namespace", "n", "", "")
+		set := &cobra.Command{Use: "set"}
+		image := &cobra.Command{Use: "image", Run: func(_ *cobra.Command, args []string) { ran = append(ran, "set image") }}
+		scale := &cobra.Command{Use: "scale", Run: func(_ *cobra.Command, args []string) { ran = append(ran, "scale") }}
+		set.AddCommand(image)
+		root.AddCommand(set, scale)
+		return root
+	}
+	hooks := []config.Hook{{
+		Command: "set",
+		Pre:     []string{"sh", "-c", "cat > " + pre},
+		Post:    []string{"sh", "-c", "cat > " + post},
+	}}
+
+	errOut := &bytes.Buffer{}
+	root := newRoot()
+	addHooks(root, hooks, errOut)
+	root.SetArgs([]string{"-n", "prod", "set", "image", "cloneset/demo", "nginx=nginx:1.25"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"set image"}) {
+		t.Errorf("expected set image to run, ran %v", ran)
+	}
+	for _, expected := range []HookAction{
+		{Phase: "pre", Command: "set image", Args: []string{"cloneset/demo", "nginx=nginx:1.25"}, Flags: map[string]string{"namespace": "prod"}},
+		{Phase: "post", Command: "set image", Args: []string{"cloneset/demo", "nginx=nginx:1.25"}, Flags: map[string]string{"namespace": "prod"}},
+	} {
+		file := pre
+		if expected.Phase == "post" {
+			file = post
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		action := HookAction{}
+		if err := json.Unmarshal(data, &action); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(action, expected) {
+			t.Errorf("expected the action %+v, got %+v", expected, action)
+		}
+	}
+
+	// commands without hooks are not changed
+	ran = nil
+	root = newRoot()
+	addHooks(root, []config.Hook{{Command: "set", Pre: []string{"false"}}}, errOut)
+	root.SetArgs([]string{"scale"})
+	if err := root.Execute(); err != nil || !reflect.DeepEqual(ran, []string{"scale"}) {
+		t.Errorf("expected scale to run, ran %v, %v", ran, err)
+	}
+
+	// a failed pre hook blocks the command
+	defer cmdutil.DefaultBehaviorOnFatal()
+	var fatal string
+	cmdutil.BehaviorOnFatal(func(msg string, code int) {
+		fatal = msg
+		panic("fatal")
+	})
+	ran = nil
+	root.SetArgs([]string{"set", "image", "cloneset/demo", "nginx=nginx:1.25"})
+	func() {
+		defer func() { recover() }()
+		root.Execute()
+	}()
+	if len(ran) != 0 {
+		t.Errorf("expected set image to be blocked, ran %v", ran)
+	}
+	if expected := "error: set image is blocked by the pre hook false: exit status 1"; fatal != expected {
+		t.Errorf("expected %q, got %q", expected, fatal)
+	}
+}
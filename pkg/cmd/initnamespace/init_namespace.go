@@ -0,0 +1,422 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package initnamespace
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ProfileLabel is set on the namespaces to the name of their profile. The
+	// ResourceDistributions of a profile select the namespaces with it.
+	ProfileLabel = "kubectl.kruise.io/namespace-profile"
+
+	// DefaultProfile is the profile used unless --profile is given.
+	DefaultProfile = "default"
+
+	deleteProtectionLabel = "policy.kruise.io/delete-protection"
+)
+
+var resourceDistributionsResource = kruiseappsv1alpha1.SchemeGroupVersion.WithResource("resourcedistributions")
+
+var (
+	initNamespaceLong = templates.LongDesc(i18n.T(`
+		Provision a namespace with the recommended Kruise settings of a profile, giving the
+		tenants a consistent starting point.
+
+		The profiles are configured as namespaceProfiles in the kubectl-kruise config file,
+		~/.kube/kubectl-kruise.yaml. A profile gives the labels of the namespace, such as the
+		Kruise deletion protection, the SidecarSet templates to inject into its pods, and the
+		Secrets and ConfigMaps, such as shared pull secrets and CA bundles, to distribute to it.
+		The default profile, unless configured, only protects the namespace from deletion while
+		it has active pods.
+
+		The namespace is created unless it exists, and labeled with the profile. A copy of each
+		SidecarSet template, restricted to the namespace, is created. The Secrets and ConfigMaps
+		are distributed by a ResourceDistribution per profile, selecting the namespaces labeled
+		with it, which needs Kruise v1.0 or later. The SidecarSets and ResourceDistributions
+		that already exist are left unchanged, so that the command can be run again safely.`))
+
+	initNamespaceExample = templates.Examples(i18n.T(`
+		# Provision namespace team-a with the default profile
+		kubectl-kruise init-namespace team-a
+
+		# Provision namespace team-b with the restricted profile of the config file
+		kubectl-kruise init-namespace team-b --profile restricted
+
+		# Show what would be provisioned, without changing anything
+		kubectl-kruise init-namespace team-c --profile restricted --dry-run=client`))
+)
+
+// InitNamespaceOptions holds the options for 'init-namespace' command
+type InitNamespaceOptions struct {
+	Namespace      string
+	Profile        string
+	ConfigPath     string
+	DryRunStrategy cmdutil.DryRunStrategy
+
+	profile     config.NamespaceProfile
+	sidecarSets []*kruiseappsv1alpha1.SidecarSet
+
+	Client        kubernetes.Interface
+	KruiseClient  kruiseclientsets.Interface
+	DynamicClient dynamic.Interface
+
+	genericclioptions.IOStreams
+}
+
+// NewInitNamespaceOptions returns an initialized InitNamespaceOptions instance
+func NewInitNamespaceOptions(streams genericclioptions.IOStreams) *InitNamespaceOptions {
+	return &InitNamespaceOptions{
+		Profile:    DefaultProfile,
+		ConfigPath: config.Path(),
+		IOStreams:  streams,
+	}
+}
+
+// NewCmdInitNamespace returns a Command instance for 'init-namespace' command
+func NewCmdInitNamespace(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewInitNamespaceOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "init-namespace NAME [--profile=PROFILE]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Provision a namespace with the recommended Kruise settings of a profile"),
+		Long:                  initNamespaceLong,
+		Example:               initNamespaceExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Profile, "profile", o.Profile, "The name of the profile of the namespace in the config file.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *InitNamespaceOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmdutil.UsageErrorf(cmd, "exactly one namespace must be specified")
+	}
+	o.Namespace = args[0]
+
+	var err error
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	if err := o.loadProfile(); err != nil {
+		return err
+	}
+
+	o.Client, err = f.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	o.DynamicClient, err = f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	clientConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig)
+	return err
+}
+
+// loadProfile reads the profile of the namespace and its SidecarSet templates from the config file.
+func (o *InitNamespaceOptions) loadProfile() error {
+	cfg, err := config.Load(o.ConfigPath)
+	if err != nil {
+		return err
+	}
+	profile, ok := cfg.NamespaceProfiles[o.Profile]
+	if !ok {
+		if o.Profile != DefaultProfile {
+			return fmt.Errorf("unknown profile %q, the profiles are configured as namespaceProfiles in %s", o.Profile, o.ConfigPath)
+		}
+		profile = config.NamespaceProfile{Labels: map[string]string{deleteProtectionLabel: "Cascading"}}
+	}
+	o.profile = profile
+
+	o.sidecarSets = nil
+	for _, path := range profile.SidecarSets {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(o.ConfigPath), path)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sidecarSet := &kruiseappsv1alpha1.SidecarSet{}
+		if err := yaml.UnmarshalStrict(data, sidecarSet); err != nil {
+			return fmt.Errorf("invalid SidecarSet template %s: %v", path, err)
+		}
+		if len(sidecarSet.Name) == 0 {
+			return fmt.Errorf("invalid SidecarSet template %s: the name is missing", path)
+		}
+		o.sidecarSets = append(o.sidecarSets, sidecarSet)
+	}
+	return nil
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *InitNamespaceOptions) Validate() error {
+	if errs := validation.IsDNS1123Label(o.Namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid namespace %q: %s", o.Namespace, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsValidLabelValue(o.Profile); len(errs) > 0 {
+		return fmt.Errorf("invalid --profile %q: %s", o.Profile, strings.Join(errs, ", "))
+	}
+	for _, d := range o.profile.Distributions {
+		if d.Kind != "Secret" && d.Kind != "ConfigMap" {
+			return fmt.Errorf("invalid distribution of %s %s/%s in profile %s, must be a Secret or a ConfigMap", d.Kind, d.Namespace, d.Name, o.Profile)
+		}
+		if len(d.Namespace) == 0 || len(d.Name) == 0 {
+			return fmt.Errorf("invalid distribution of %s %s/%s in profile %s, its namespace and name must be given", d.Kind, d.Namespace, d.Name, o.Profile)
+		}
+	}
+	return nil
+}
+
+// Run performs the execution of 'init-namespace' command
+func (o *InitNamespaceOptions) Run() error {
+	if err := o.initNamespace(); err != nil {
+		return err
+	}
+	for _, template := range o.sidecarSets {
+		if err := o.initSidecarSet(template); err != nil {
+			return err
+		}
+	}
+	for _, d := range o.profile.Distributions {
+		if err := o.initDistribution(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// initNamespace creates the namespace with the labels of the profile, or adds them to it.
+func (o *InitNamespaceOptions) initNamespace() error {
+	labels := map[string]string{ProfileLabel: o.Profile}
+	for key, value := range o.profile.Labels {
+		labels[key] = value
+	}
+
+	namespace, err := o.Client.CoreV1().Namespaces().Get(context.TODO(), o.Namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: o.Namespace, Labels: labels}}
+		if o.DryRunStrategy != cmdutil.DryRunClient {
+			if _, err := o.Client.CoreV1().Namespaces().Create(context.TODO(), namespace, metav1.CreateOptions{DryRun: o.dryRun()}); err != nil {
+				return err
+			}
+		}
+		o.printResult("namespace", o.Namespace, "created")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	changed := false
+	if namespace.Labels == nil {
+		namespace.Labels = map[string]string{}
+	}
+	for key, value := range labels {
+		if namespace.Labels[key] != value {
+			namespace.Labels[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		o.printResult("namespace", o.Namespace, "unchanged")
+		return nil
+	}
+	if o.DryRunStrategy != cmdutil.DryRunClient {
+		if _, err := o.Client.CoreV1().Namespaces().Update(context.TODO(), namespace, metav1.UpdateOptions{DryRun: o.dryRun()}); err != nil {
+			return err
+		}
+	}
+	o.printResult("namespace", o.Namespace, "configured")
+	return nil
+}
+
+// initSidecarSet creates the copy of template restricted to the namespace, unless it exists.
+func (o *InitNamespaceOptions) initSidecarSet(template *kruiseappsv1alpha1.SidecarSet) error {
+	sidecarSet := NamespaceSidecarSet(template, o.Namespace, o.Profile)
+	_, err := o.KruiseClient.AppsV1alpha1().SidecarSets().Get(context.TODO(), sidecarSet.Name, metav1.GetOptions{})
+	if err == nil {
+		o.printResult("sidecarset.apps.kruise.io", sidecarSet.Name, "unchanged")
+		return nil
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+	if o.DryRunStrategy != cmdutil.DryRunClient {
+		if _, err := o.KruiseClient.AppsV1alpha1().SidecarSets().Create(context.TODO(), sidecarSet, metav1.CreateOptions{DryRun: o.dryRun()}); err != nil {
+			return err
+		}
+	}
+	o.printResult("sidecarset.apps.kruise.io", sidecarSet.Name, "created")
+	return nil
+}
+
+// initDistribution creates the ResourceDistribution of d for the profile, unless it exists. It
+// distributes to the namespace as soon as the namespace is labeled with the profile.
+func (o *InitNamespaceOptions) initDistribution(d config.Distribution) error {
+	name := DistributionName(o.Profile, d)
+	client := o.DynamicClient.Resource(resourceDistributionsResource)
+	_, err := client.Get(context.TODO(), name, metav1.GetOptions{})
+	if err == nil {
+		o.printResult("resourcedistribution.apps.kruise.io", name, "unchanged")
+		return nil
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	var source runtime.Object
+	switch d.Kind {
+	case "Secret":
+		source, err = o.Client.CoreV1().Secrets(d.Namespace).Get(context.TODO(), d.Name, metav1.GetOptions{})
+	default:
+		source, err = o.Client.CoreV1().ConfigMaps(d.Namespace).Get(context.TODO(), d.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the %s to distribute: %v", d.Kind, err)
+	}
+	distribution, err := ResourceDistribution(name, o.Profile, source)
+	if err != nil {
+		return err
+	}
+	if o.DryRunStrategy != cmdutil.DryRunClient {
+		if _, err := client.Create(context.TODO(), distribution, metav1.CreateOptions{DryRun: o.dryRun()}); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to create resourcedistribution %s, ResourceDistributions need Kruise v1.0 or later: %v", name, err)
+			}
+			return err
+		}
+	}
+	o.printResult("resourcedistribution.apps.kruise.io", name, "created")
+	return nil
+}
+
+// NamespaceSidecarSet returns the copy of template restricted to namespace, named after both.
+func NamespaceSidecarSet(template *kruiseappsv1alpha1.SidecarSet, namespace, profile string) *kruiseappsv1alpha1.SidecarSet {
+	sidecarSet := &kruiseappsv1alpha1.SidecarSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%s", template.Name, namespace),
+			Labels:      map[string]string{ProfileLabel: profile},
+			Annotations: template.Annotations,
+		},
+		Spec: *template.Spec.DeepCopy(),
+	}
+	for key, value := range template.Labels {
+		sidecarSet.Labels[key] = value
+	}
+	sidecarSet.Spec.Namespace = namespace
+	return sidecarSet
+}
+
+// DistributionName returns the name of the ResourceDistribution of d for profile.
+func DistributionName(profile string, d config.Distribution) string {
+	return strings.ToLower(fmt.Sprintf("%s-%s-%s", profile, d.Kind, d.Name))
+}
+
+// ResourceDistribution returns the ResourceDistribution named name distributing a copy of source
+// to the namespaces labeled with profile.
+func ResourceDistribution(name, profile string, source runtime.Object) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(source)
+	if err != nil {
+		return nil, err
+	}
+	resource := &unstructured.Unstructured{Object: content}
+	resource.SetAPIVersion("v1")
+	switch source.(type) {
+	case *corev1.Secret:
+		resource.SetKind("Secret")
+	default:
+		resource.SetKind("ConfigMap")
+	}
+	// only the name, labels and annotations of the source are distributed
+	metadata := map[string]interface{}{"name": resource.GetName()}
+	if labels := resource.GetLabels(); len(labels) > 0 {
+		metadata["labels"] = content["metadata"].(map[string]interface{})["labels"]
+	}
+	if annotations := resource.GetAnnotations(); len(annotations) > 0 {
+		metadata["annotations"] = content["metadata"].(map[string]interface{})["annotations"]
+	}
+	resource.Object["metadata"] = metadata
+
+	distribution := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"resource": resource.Object,
+			"targets": map[string]interface{}{
+				"namespaceLabelSelector": map[string]interface{}{
+					"matchLabels": map[string]interface{}{ProfileLabel: profile},
+				},
+			},
+		},
+	}}
+	distribution.SetAPIVersion(kruiseappsv1alpha1.SchemeGroupVersion.String())
+	distribution.SetKind("ResourceDistribution")
+	distribution.SetName(name)
+	distribution.SetLabels(map[string]string{ProfileLabel: profile})
+	return distribution, nil
+}
+
+func (o *InitNamespaceOptions) dryRun() []string {
+	if o.DryRunStrategy == cmdutil.DryRunServer {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+func (o *InitNamespaceOptions) printResult(resource, name, operation string) {
+	switch o.DryRunStrategy {
+	case cmdutil.DryRunClient:
+		operation += " (dry run)"
+	case cmdutil.DryRunServer:
+		operation += " (server dry run)"
+	}
+	fmt.Fprintf(o.Out, "%s/%s %s\n", resource, name, operation)
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package initnamespace
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const sidecarSetTemplate = `apiVersion: apps.kruise.io/v1alpha1
+kind: SidecarSet
+metadata:
+  name: log-agent
+spec:
+  selector:
+    matchLabels:
+      logging: enabled
+  containers:
+  - name: log-agent
+    image: fluent-bit:1.9
+`
+
+func newTestOptions(t *testing.T, profile string) (*InitNamespaceOptions, *bytes.Buffer) {
+	dir, err := ioutil.TempDir("", "init-namespace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := ioutil.WriteFile(filepath.Join(dir, "log-agent.yaml"), []byte(sidecarSetTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{NamespaceProfiles: map[string]config.NamespaceProfile{
+		"tenant": {
+			Labels:        map[string]string{deleteProtectionLabel: "Always"},
+			SidecarSets:   []string{"log-agent.yaml"},
+			Distributions: []config.Distribution{{Kind: "Secret", Namespace: "kube-system", Name: "pull-secret"}},
+		},
+	}}
+	path := filepath.Join(dir, "kubectl-kruise.yaml")
+	if err := config.Save(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	o := NewInitNamespaceOptions(genericclioptions.IOStreams{Out: out, ErrOut: out})
+	o.Namespace, o.Profile, o.ConfigPath = "team-a", profile, path
+	if err := o.loadProfile(); err != nil {
+		t.Fatal(err)
+	}
+	o.Client = fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "pull-secret", ResourceVersion: "7"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte("{}")},
+	})
+	o.KruiseClient = kruisefake.NewSimpleClientset()
+	o.DynamicClient = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	return o, out
+}
+
+func TestInitNamespace(t *testing.T) {
+	o, out := newTestOptions(t, "tenant")
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `namespace/team-a created
+sidecarset.apps.kruise.io/log-agent-team-a created
+resourcedistribution.apps.kruise.io/tenant-secret-pull-secret created
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	namespace, err := o.Client.CoreV1().Namespaces().Get(context.TODO(), "team-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if namespace.Labels[ProfileLabel] != "tenant" || namespace.Labels[deleteProtectionLabel] != "Always" {
+		t.Errorf("unexpected labels of the namespace %v", namespace.Labels)
+	}
+	sidecarSet, err := o.KruiseClient.AppsV1alpha1().SidecarSets().Get(context.TODO(), "log-agent-team-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sidecarSet.Spec.Namespace != "team-a" || sidecarSet.Spec.Containers[0].Image != "fluent-bit:1.9" {
+		t.Errorf("unexpected sidecarset %v", sidecarSet.Spec)
+	}
+
+	out.Reset()
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	expected = `namespace/team-a unchanged
+sidecarset.apps.kruise.io/log-agent-team-a unchanged
+resourcedistribution.apps.kruise.io/tenant-secret-pull-secret unchanged
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestInitNamespaceDryRun(t *testing.T) {
+	o, out := newTestOptions(t, DefaultProfile)
+	o.DryRunStrategy = cmdutil.DryRunClient
+	o.Client = fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}})
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "namespace/team-a configured (dry run)\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	namespace, err := o.Client.CoreV1().Namespaces().Get(context.TODO(), "team-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(namespace.Labels) > 0 {
+		t.Errorf("expected the namespace to be left unchanged, got labels %v", namespace.Labels)
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	o, _ := newTestOptions(t, DefaultProfile)
+	if o.profile.Labels[deleteProtectionLabel] != "Cascading" || len(o.sidecarSets) > 0 {
+		t.Errorf("unexpected default profile %v", o.profile)
+	}
+	o.Profile = "unknown"
+	if err := o.loadProfile(); err == nil || !strings.Contains(err.Error(), `unknown profile "unknown"`) {
+		t.Errorf("expected an unknown profile error, got %v", err)
+	}
+}
+
+func TestResourceDistribution(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "ca-bundle", UID: "uid-1", Labels: map[string]string{"app": "ca"}},
+		Data:       map[string]string{"ca.crt": "cert"},
+	}
+	distribution, err := ResourceDistribution("tenant-configmap-ca-bundle", "tenant", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource, _, _ := unstructured.NestedMap(distribution.Object, "spec", "resource")
+	expected := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "ca-bundle", "labels": map[string]interface{}{"app": "ca"}},
+		"data":       map[string]interface{}{"ca.crt": "cert"},
+	}
+	if !reflect.DeepEqual(resource, expected) {
+		t.Errorf("expected resource %v, got %v", expected, resource)
+	}
+	selector, _, _ := unstructured.NestedStringMap(distribution.Object, "spec", "targets", "namespaceLabelSelector", "matchLabels")
+	if selector[ProfileLabel] != "tenant" {
+		t.Errorf("unexpected namespace selector %v", selector)
+	}
+}
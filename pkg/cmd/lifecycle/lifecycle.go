@@ -0,0 +1,53 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	lifecycleLong = templates.LongDesc(`
+		Manage the lifecycle hooks of the pods of Kruise workloads.
+
+		CloneSets and Advanced StatefulSets hold their pods before deleting them, and before
+		and after updating them in place, while the pods still have the labels or finalizers of
+		their PreDelete and InPlaceUpdate hooks.`)
+
+	lifecycleExample = templates.Examples(`
+		# List the pods of cloneset demo held by its lifecycle hooks
+		kubectl-kruise lifecycle blocked cloneset/demo`)
+)
+
+// NewCmdLifecycle returns a Command instance for 'lifecycle' sub command
+func NewCmdLifecycle(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "lifecycle SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Manage the lifecycle hooks of the pods of a workload"),
+		Long:                  lifecycleLong,
+		Example:               lifecycleExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(NewCmdLifecycleBlocked(f, streams))
+	return cmd
+}
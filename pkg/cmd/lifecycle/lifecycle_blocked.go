@@ -0,0 +1,338 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appspub "github.com/openkruise/kruise-api/apps/pub"
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	blockedLong = templates.LongDesc(`
+		List the pods of a workload held by its lifecycle hooks.
+
+		A pod is held by the PreDelete hook before being deleted, and by the InPlaceUpdate hook
+		before being updated in place, for as long as it has one of the labels or finalizers of
+		the hook. They are usually removed by the operator of the application once the pod is
+		drained, so a pod held for long means that the operator is stuck. Use --unblock to
+		remove them from a pod and release it.`)
+
+	blockedExample = templates.Examples(`
+		# List the pods of cloneset demo held by its lifecycle hooks
+		kubectl-kruise lifecycle blocked cloneset/demo
+
+		# Release pod demo-xyz from the hook holding it, after confirmation
+		kubectl-kruise lifecycle blocked cloneset/demo --unblock demo-xyz
+
+		# Release pod demo-xyz of advanced statefulset demo without confirmation
+		kubectl-kruise lifecycle blocked asts/demo --unblock demo-xyz --yes`)
+)
+
+const (
+	// PreDeleteHook is the hook holding the pods in the PreparingDelete state
+	PreDeleteHook = "PreDelete"
+	// InPlaceUpdateHook is the hook holding the pods in the PreparingUpdate state
+	InPlaceUpdateHook = "InPlaceUpdate"
+)
+
+// BlockedPod is a pod held by a lifecycle hook.
+type BlockedPod struct {
+	Pod   *corev1.Pod
+	Hook  string
+	State appspub.LifecycleStateType
+	// HeldSince is when the pod entered its lifecycle state, zero if unknown
+	HeldSince time.Time
+	// Labels and Finalizers are the ones of the hook the pod has, which hold it
+	Labels     map[string]string
+	Finalizers []string
+}
+
+// LifecycleBlockedOptions holds the command-line options for 'lifecycle blocked' sub command
+type LifecycleBlockedOptions struct {
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+	Unblock          string
+	Yes              bool
+
+	Builder func() *resource.Builder
+	Client  kubernetes.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NewLifecycleBlockedOptions returns an initialized LifecycleBlockedOptions instance
+func NewLifecycleBlockedOptions(streams genericclioptions.IOStreams) *LifecycleBlockedOptions {
+	return &LifecycleBlockedOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdLifecycleBlocked returns a Command instance for 'lifecycle blocked' sub command
+func NewCmdLifecycleBlocked(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewLifecycleBlockedOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "blocked (TYPE/NAME | TYPE NAME) [--unblock POD]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("List the pods of a workload held by its lifecycle hooks"),
+		Long:                  blockedLong,
+		Example:               blockedExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "identifying the workload to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringVar(&o.Unblock, "unblock", o.Unblock, "Release this blocked pod by removing the labels and finalizers of the hook holding it, after confirmation.")
+	cmd.Flags().BoolVarP(&o.Yes, "yes", "y", o.Yes, "If true, release the pod of --unblock without confirmation.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *LifecycleBlockedOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+	o.Client, err = f.KubernetesClientSet()
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *LifecycleBlockedOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if o.Yes && len(o.Unblock) == 0 {
+		return fmt.Errorf("--yes requires --unblock")
+	}
+	return nil
+}
+
+// Run performs the execution of 'lifecycle blocked' sub command
+func (o *LifecycleBlockedOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return err
+	}
+
+	var blocked []BlockedPod
+	for _, info := range infos {
+		lifecycle, err := lifecycleForObject(info.Object)
+		if err != nil {
+			return err
+		}
+		pods, err := polymorphichelpers.PodsForObject(o.Client.CoreV1(), info.Object)
+		if err != nil {
+			return err
+		}
+		blocked = append(blocked, BlockedPods(pods, lifecycle)...)
+	}
+
+	if len(o.Unblock) == 0 {
+		return o.printBlocked(blocked)
+	}
+	for _, b := range blocked {
+		if b.Pod.Name == o.Unblock {
+			return o.unblock(b)
+		}
+	}
+	return fmt.Errorf("pod/%s is not held by a lifecycle hook of %s", o.Unblock, strings.Join(o.Resources, " "))
+}
+
+// lifecycleForObject returns the lifecycle hooks of a workload, nil if it has none.
+func lifecycleForObject(obj runtime.Object) (*appspub.Lifecycle, error) {
+	switch t := obj.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		return t.Spec.Lifecycle, nil
+	case *kruiseappsv1beta1.StatefulSet:
+		return t.Spec.Lifecycle, nil
+	}
+	return nil, fmt.Errorf("%T has no lifecycle hooks, only CloneSets and Advanced StatefulSets have", obj)
+}
+
+// BlockedPods returns the pods held by the hooks of lifecycle, held longest first.
+func BlockedPods(pods []corev1.Pod, lifecycle *appspub.Lifecycle) []BlockedPod {
+	if lifecycle == nil {
+		return nil
+	}
+	var blocked []BlockedPod
+	for i := range pods {
+		pod := &pods[i]
+		state := appspub.LifecycleStateType(pod.Labels[appspub.LifecycleStateKey])
+		var hookName string
+		var hook *appspub.LifecycleHook
+		switch state {
+		case appspub.LifecycleStatePreparingDelete:
+			hookName, hook = PreDeleteHook, lifecycle.PreDelete
+		case appspub.LifecycleStatePreparingUpdate:
+			hookName, hook = InPlaceUpdateHook, lifecycle.InPlaceUpdate
+		}
+		if hook == nil {
+			continue
+		}
+
+		b := BlockedPod{Pod: pod, Hook: hookName, State: state, Labels: map[string]string{}}
+		for k, v := range hook.LabelsHandler {
+			if value, ok := pod.Labels[k]; ok && value == v {
+				b.Labels[k] = v
+			}
+		}
+		for _, finalizer := range hook.FinalizersHandler {
+			for _, f := range pod.Finalizers {
+				if f == finalizer {
+					b.Finalizers = append(b.Finalizers, finalizer)
+				}
+			}
+		}
+		if len(b.Labels) == 0 && len(b.Finalizers) == 0 {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, pod.Annotations[appspub.LifecycleTimestampKey]); err == nil {
+			b.HeldSince = t
+		}
+		blocked = append(blocked, b)
+	}
+
+	sort.SliceStable(blocked, func(i, j int) bool {
+		if blocked[i].HeldSince.IsZero() != blocked[j].HeldSince.IsZero() {
+			return !blocked[i].HeldSince.IsZero()
+		}
+		return blocked[i].HeldSince.Before(blocked[j].HeldSince)
+	})
+	return blocked
+}
+
+// Holds returns the labels and finalizers holding the pod, e.g. "label example.com/hook=true".
+func (b BlockedPod) Holds() []string {
+	var holds []string
+	for k, v := range b.Labels {
+		holds = append(holds, fmt.Sprintf("label %s=%s", k, v))
+	}
+	sort.Strings(holds)
+	for _, f := range b.Finalizers {
+		holds = append(holds, "finalizer "+f)
+	}
+	return holds
+}
+
+// Unblock removes the labels and finalizers holding pod, a copy of the blocked pod.
+func (b BlockedPod) Unblock(pod *corev1.Pod) {
+	for k := range b.Labels {
+		delete(pod.Labels, k)
+	}
+	var finalizers []string
+	for _, f := range pod.Finalizers {
+		held := false
+		for _, finalizer := range b.Finalizers {
+			held = held || f == finalizer
+		}
+		if !held {
+			finalizers = append(finalizers, f)
+		}
+	}
+	pod.Finalizers = finalizers
+}
+
+func (o *LifecycleBlockedOptions) printBlocked(blocked []BlockedPod) error {
+	if len(blocked) == 0 {
+		fmt.Fprintln(o.Out, "No pods held by lifecycle hooks")
+		return nil
+	}
+	w := internalcmdutil.NewTableWriter(o.Out)
+	fmt.Fprintln(w, "POD\tHOOK\tSTATE\tHELD FOR\tTO CLEAR")
+	for _, b := range blocked {
+		held := "<unknown>"
+		if !b.HeldSince.IsZero() {
+			held = duration.HumanDuration(time.Since(b.HeldSince))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", b.Pod.Name, b.Hook, b.State, held, strings.Join(b.Holds(), ", "))
+	}
+	return w.Flush()
+}
+
+// unblock releases the blocked pod, after confirmation unless --yes.
+func (o *LifecycleBlockedOptions) unblock(b BlockedPod) error {
+	holds := strings.Join(b.Holds(), ", ")
+	if !o.Yes {
+		fmt.Fprintf(o.Out, "Release pod/%s from the %s hook by removing %s? [y/N]: ", b.Pod.Name, b.Hook, holds)
+		answer := ""
+		if o.In != nil {
+			answer, _ = bufio.NewReader(o.In).ReadString('\n')
+		}
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			return fmt.Errorf("pod/%s was not released", b.Pod.Name)
+		}
+	}
+
+	pod := b.Pod.DeepCopy()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		b.Unblock(pod)
+		_, err := o.Client.CoreV1().Pods(pod.Namespace).Update(context.TODO(), pod, metav1.UpdateOptions{})
+		if err != nil {
+			if latest, getErr := o.Client.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{}); getErr == nil {
+				pod = latest
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update pod/%s: %v", pod.Name, err)
+	}
+	fmt.Fprintf(o.Out, "pod/%s released from the %s hook, removed %s\n", pod.Name, b.Hook, holds)
+	return nil
+}
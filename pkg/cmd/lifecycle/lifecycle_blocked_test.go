@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appspub "github.com/openkruise/kruise-api/apps/pub"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newHookedPod(name string, state appspub.LifecycleStateType, heldSince time.Time, labels map[string]string, finalizers ...string) corev1.Pod {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   "default",
+		Labels:      map[string]string{appspub.LifecycleStateKey: string(state), "app": "demo"},
+		Annotations: map[string]string{},
+		Finalizers:  finalizers,
+	}}
+	for k, v := range labels {
+		pod.Labels[k] = v
+	}
+	if !heldSince.IsZero() {
+		pod.Annotations[appspub.LifecycleTimestampKey] = heldSince.Format(time.RFC3339)
+	}
+	return pod
+}
+
+var testLifecycle = &appspub.Lifecycle{
+	PreDelete:     &appspub.LifecycleHook{FinalizersHandler: []string{"example.com/drain"}},
+	InPlaceUpdate: &appspub.LifecycleHook{LabelsHandler: map[string]string{"example.com/serving": "true"}},
+}
+
+func TestBlockedPods(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	pods := []corev1.Pod{
+		newHookedPod("normal", appspub.LifecycleStateNormal, time.Time{}, map[string]string{"example.com/serving": "true"}, "example.com/drain"),
+		newHookedPod("updating", appspub.LifecycleStatePreparingUpdate, now.Add(-time.Minute), map[string]string{"example.com/serving": "true"}),
+		newHookedPod("deleting", appspub.LifecycleStatePreparingDelete, now.Add(-time.Hour), nil, "example.com/drain", "other"),
+		newHookedPod("released", appspub.LifecycleStatePreparingDelete, now.Add(-time.Hour), nil, "other"),
+		newHookedPod("serving-false", appspub.LifecycleStatePreparingUpdate, time.Time{}, map[string]string{"example.com/serving": "false"}),
+	}
+
+	blocked := BlockedPods(pods, testLifecycle)
+	var names []string
+	for _, b := range blocked {
+		names = append(names, b.Pod.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"deleting", "updating"}) {
+		t.Fatalf("expected the blocked pods deleting and updating, held longest first, got %v", names)
+	}
+	if blocked[0].Hook != PreDeleteHook || !blocked[0].HeldSince.Equal(now.Add(-time.Hour)) {
+		t.Errorf("unexpected blocked pod %+v", blocked[0])
+	}
+	if holds := blocked[0].Holds(); !reflect.DeepEqual(holds, []string{"finalizer example.com/drain"}) {
+		t.Errorf("unexpected holds %v", holds)
+	}
+	if holds := blocked[1].Holds(); blocked[1].Hook != InPlaceUpdateHook || !reflect.DeepEqual(holds, []string{"label example.com/serving=true"}) {
+		t.Errorf("unexpected hook %s holds %v", blocked[1].Hook, holds)
+	}
+
+	if blocked := BlockedPods(pods, nil); len(blocked) != 0 {
+		t.Errorf("expected no blocked pods without lifecycle, got %d", len(blocked))
+	}
+}
+
+func TestUnblock(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         string
+		yes        bool
+		expectErr  bool
+		finalizers []string
+	}{
+		{name: "confirmed", in: "y\n", finalizers: []string{"other"}},
+		{name: "yes", yes: true, finalizers: []string{"other"}},
+		{name: "declined", in: "n\n", expectErr: true, finalizers: []string{"example.com/drain", "other"}},
+		{name: "no answer", expectErr: true, finalizers: []string{"example.com/drain", "other"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := newHookedPod("deleting", appspub.LifecycleStatePreparingDelete, time.Time{}, nil, "example.com/drain", "other")
+			client := fake.NewSimpleClientset(&pod)
+			streams, in, out, _ := genericclioptions.NewTestIOStreams()
+			in.WriteString(test.in)
+			o := &LifecycleBlockedOptions{Yes: test.yes, Client: client, IOStreams: streams}
+
+			blocked := BlockedPods([]corev1.Pod{pod}, testLifecycle)
+			err := o.unblock(blocked[0])
+			if test.expectErr != (err != nil) {
+				t.Errorf("expected error %v, got %v", test.expectErr, err)
+			}
+			updated, err := client.CoreV1().Pods("default").Get(context.TODO(), "deleting", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(updated.Finalizers, test.finalizers) {
+				t.Errorf("expected the finalizers %v, got %v", test.finalizers, updated.Finalizers)
+			}
+			if !test.expectErr && !strings.Contains(out.String(), "pod/deleting released from the PreDelete hook") {
+				t.Errorf("unexpected output %q", out.String())
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package migrate
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/openkruise/kruise-tools/pkg/api"
 	"github.com/spf13/cobra"
@@ -38,6 +39,9 @@ type migrateOptions struct {
 
 	IsCreate       bool
 	IsCopy         bool
+	IsShadow       bool
+	ShadowInterval time.Duration
+	Labels         map[string]string
 	Replicas       int32
 	MaxSurge       int32
 	TimeoutSeconds int32
@@ -64,6 +68,12 @@ func NewCmdMigrate(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *co
 	# Create a same replicas CloneSet from an existing Deployment.
 	kubectl-kruise migrate CloneSet --from Deployment -n default --dst-name deployment-name --create --copy
 
+	# Create a CloneSet from an existing Deployment, labeled with its owner in addition to the conventions of the config file.
+	kubectl-kruise migrate CloneSet --from Deployment -n default --src-name deployment-name --dst-name cloneset-name --create --set-label owner=alice
+
+	# Create a CloneSet at zero replicas from an existing Deployment, and mirror the template changes of the Deployment into it until interrupted.
+	kubectl-kruise migrate CloneSet --from Deployment -n default --src-name deployment-name --dst-name cloneset-name --shadow
+
 	# Migrate replicas from an existing Deployment to an existing CloneSet.
 	kubectl-kruise migrate CloneSet --from Deployment -n default --src-name cloneset-name --dst-name deployment-name --replicas 10 --max-surge=2
 `,
@@ -79,6 +89,9 @@ func NewCmdMigrate(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *co
 
 	cmd.Flags().BoolVar(&o.IsCreate, "create", false, "Create dst workload with replicas=0 from src workload.")
 	cmd.Flags().BoolVar(&o.IsCopy, "copy", false, "Copy replicas from src workload when create.")
+	cmd.Flags().BoolVar(&o.IsShadow, "shadow", false, "Create dst workload with replicas=0 from src workload if it does not exist, then keep its template in sync with the one of src workload until interrupted.")
+	cmd.Flags().DurationVar(&o.ShadowInterval, "shadow-interval", 10*time.Second, "Interval between two syncs of the template of dst workload in --shadow mode.")
+	cmd.Flags().StringToStringVar(&o.Labels, "set-label", nil, "Labels to set on dst workload when create, overriding the labels of the conventions of the config file (e.g. --set-label owner=alice).")
 	cmd.Flags().Int32Var(&o.Replicas, "replicas", -1, "The replicas needs to migrate, -1 indicates all replicas in src workload.")
 	cmd.Flags().Int32Var(&o.MaxSurge, "max-surge", 1, "Max surge during migration.")
 	cmd.Flags().Int32Var(&o.TimeoutSeconds, "timeout-seconds", -1, "Timeout seconds for migration, -1 indicates no limited.")
@@ -110,6 +123,13 @@ func (o *migrateOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []
 	if len(o.DstName) == 0 && !o.IsCreate {
 		return fmt.Errorf("must specify --dst-name")
 	}
+	if o.IsShadow {
+		if o.IsCreate || o.IsCopy {
+			return fmt.Errorf("--shadow creates dst workload with replicas=0, it can not be used with --create or --copy")
+		} else if o.ShadowInterval <= 0 {
+			return fmt.Errorf("--shadow-interval must be positive")
+		}
+	}
 
 	switch args[0] {
 	case "CloneSet", "cloneset", "clone":
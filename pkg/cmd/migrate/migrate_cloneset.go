@@ -17,6 +17,7 @@ limitations under the License.
 package migrate
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -26,6 +27,9 @@ import (
 	"github.com/openkruise/kruise-tools/pkg/migration"
 	clonesetmigration "github.com/openkruise/kruise-tools/pkg/migration/cloneset"
 	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 )
 
@@ -35,6 +39,10 @@ func (o *migrateOptions) migrateCloneSet(f cmdutil.Factory, cmd *cobra.Command)
 		return err
 	}
 
+	if o.IsShadow {
+		return o.shadowCloneSet(cfg)
+	}
+
 	if o.IsCreate {
 
 		ctrl, err := clonesetcreation.NewControl(cfg)
@@ -42,7 +50,7 @@ func (o *migrateOptions) migrateCloneSet(f cmdutil.Factory, cmd *cobra.Command)
 			return err
 		}
 
-		opts := creation.Options{CopyReplicas: o.IsCopy}
+		opts := creation.Options{CopyReplicas: o.IsCopy, Labels: o.Labels}
 		if err := ctrl.Create(o.SrcRef, o.DstRef, opts); err != nil {
 			return err
 		}
@@ -100,3 +108,36 @@ func (o *migrateOptions) migrateCloneSet(f cmdutil.Factory, cmd *cobra.Command)
 
 	return nil
 }
+
+// shadowCloneSet creates the CloneSet at zero replicas unless it exists, then mirrors the template
+// changes of the Deployment into it until interrupted, so that the replicas can be migrated later.
+func (o *migrateOptions) shadowCloneSet(cfg *rest.Config) error {
+	ctrl, err := clonesetcreation.NewControl(cfg)
+	if err != nil {
+		return err
+	}
+
+	opts := creation.Options{Labels: o.Labels}
+	if err := ctrl.Create(o.SrcRef, o.DstRef, opts); errors.Is(err, creation.ErrDstExists) {
+		internalcmdutil.Print(fmt.Sprintf("%s/%s already exists, syncing its template from %s/%s", o.To, o.DstName, o.From, o.SrcName))
+	} else if err != nil {
+		return err
+	} else {
+		internalcmdutil.Print(fmt.Sprintf("Successfully created from %s/%s to %s/%s with replicas=0", o.From, o.SrcName, o.To, o.DstName))
+	}
+
+	return wait.PollImmediateInfinite(o.ShadowInterval, func() (bool, error) {
+		changed, err := ctrl.SyncTemplate(o.SrcRef, o.DstRef)
+		if apierrors.IsNotFound(err) {
+			// src or dst workload was deleted, there is nothing left to mirror
+			return false, err
+		} else if err != nil {
+			internalcmdutil.Print(fmt.Sprintf("Failed to sync template, retrying in %s: %v", o.ShadowInterval, err))
+			return false, nil
+		}
+		if changed {
+			internalcmdutil.Print(fmt.Sprintf("Synced template from %s/%s to %s/%s", o.From, o.SrcName, o.To, o.DstName))
+		}
+		return false, nil
+	})
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package new
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	newLong = templates.LongDesc(`
+		Write the manifests of Kruise resources from the answers to a few questions.`)
+
+	newExample = templates.Examples(`
+		# Write the Rollout of a CloneSet to rollout.yaml
+		kubectl-kruise new rollout > rollout.yaml`)
+)
+
+// NewCmdNew returns a Command instance for 'new' command
+func NewCmdNew(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "new SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Write the manifests of Kruise resources interactively"),
+		Long:                  newLong,
+		Example:               newExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(NewCmdNewRollout(f, streams))
+	return cmd
+}
@@ -0,0 +1,330 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package new
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/yaml"
+)
+
+// The traffic providers of the Rollouts
+const (
+	TrafficNone  = "none"
+	TrafficNginx = "nginx"
+)
+
+// manualPause is the answer pausing the steps until they are approved
+const manualPause = "manual"
+
+var (
+	newRolloutLong = templates.LongDesc(i18n.T(`
+		Write a Rollout from the answers to questions about its workload, its canary steps, its
+		traffic provider and its metric gates.
+
+		The questions are asked on the standard error and the answers read from the standard
+		input, an empty answer takes the default in brackets. The Rollout is written to the
+		standard output, ready to commit, with comments on what its Ingress needs for nginx
+		traffic routing and on the autopilot command gating its steps on a metric.`))
+
+	newRolloutExample = templates.Examples(i18n.T(`
+		# Write the Rollout of a workload to rollout.yaml
+		kubectl-kruise new rollout -n default > rollout.yaml
+
+		# Write the Rollout of cloneset demo with the default answers to the other questions
+		printf 'CloneSet\ndemo\n' | kubectl-kruise new rollout`))
+)
+
+// NewRolloutOptions holds the options for 'new rollout' sub command
+type NewRolloutOptions struct {
+	Namespace string
+
+	genericclioptions.IOStreams
+}
+
+// RolloutAnswers are the answers to the questions of 'new rollout'.
+type RolloutAnswers struct {
+	Kind     string
+	Workload string
+	Name     string
+	// Weights are the percentages of the canary steps, the last one is usually 100.
+	Weights []int32
+	// Pause is the duration of the pause after each step, zero until the step is approved.
+	Pause         time.Duration
+	Traffic       string
+	Service       string
+	Ingress       string
+	PrometheusURL string
+	MetricQuery   string
+}
+
+// workloadAPIVersions are the API versions of the workloads of the Rollouts, by kind
+var workloadAPIVersions = map[string]string{
+	"CloneSet":   "apps.kruise.io/v1alpha1",
+	"Deployment": "apps/v1",
+}
+
+// NewNewRolloutOptions returns an initialized NewRolloutOptions instance
+func NewNewRolloutOptions(streams genericclioptions.IOStreams) *NewRolloutOptions {
+	return &NewRolloutOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdNewRollout returns a Command instance for 'new rollout' sub command
+func NewCmdNewRollout(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewNewRolloutOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "rollout",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Write a Rollout interactively"),
+		Long:                  newRolloutLong,
+		Example:               newRolloutExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *NewRolloutOptions) Complete(f cmdutil.Factory, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %v", args)
+	}
+	var err error
+	o.Namespace, _, err = f.ToRawKubeConfigLoader().Namespace()
+	return err
+}
+
+// Run performs the execution of 'new rollout' sub command
+func (o *NewRolloutOptions) Run() error {
+	answers, err := AskRollout(o.In, o.ErrOut)
+	if err != nil {
+		return err
+	}
+	return WriteRollout(o.Out, o.Namespace, answers)
+}
+
+// prompter asks questions on out and reads the answers from in, one per line.
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask asks the question until parse accepts the answer, or the default if the answer is empty.
+// An empty default makes the answer required.
+func (p *prompter) ask(question, def string, parse func(string) error) (string, error) {
+	for {
+		if len(def) > 0 {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+		if !p.in.Scan() {
+			if err := p.in.Err(); err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("no answer to %q", question)
+		}
+		answer := strings.TrimSpace(p.in.Text())
+		if len(answer) == 0 {
+			answer = def
+		}
+		err := fmt.Errorf("an answer is required")
+		if len(answer) > 0 {
+			if err = parse(answer); err == nil {
+				return answer, nil
+			}
+		}
+		fmt.Fprintf(p.out, "  %v\n", err)
+	}
+}
+
+// AskRollout asks the questions of 'new rollout' on out and returns the answers read from in.
+func AskRollout(in io.Reader, out io.Writer) (*RolloutAnswers, error) {
+	p := &prompter{in: bufio.NewScanner(in), out: out}
+	a := &RolloutAnswers{}
+	anyAnswer := func(string) error { return nil }
+
+	var err error
+	if a.Kind, err = p.ask("Kind of the workload (CloneSet, Deployment)", "CloneSet", func(answer string) error {
+		if _, ok := workloadAPIVersions[answer]; !ok {
+			return fmt.Errorf("unsupported kind %q, must be CloneSet or Deployment", answer)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if a.Workload, err = p.ask("Name of the workload", "", anyAnswer); err != nil {
+		return nil, err
+	}
+	if a.Name, err = p.ask("Name of the rollout", a.Workload, anyAnswer); err != nil {
+		return nil, err
+	}
+	if _, err = p.ask("Traffic percentages of the canary steps", "20,50,100", func(answer string) error {
+		a.Weights, err = parseWeights(answer)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if _, err = p.ask("Pause after each step, a duration or manual until approved", manualPause, func(answer string) error {
+		if answer == manualPause {
+			a.Pause = 0
+			return nil
+		}
+		a.Pause, err = time.ParseDuration(answer)
+		if err == nil && a.Pause < time.Second {
+			err = fmt.Errorf("the pause must be at least 1s")
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if a.Traffic, err = p.ask("Traffic provider (none, nginx)", TrafficNone, func(answer string) error {
+		if answer != TrafficNone && answer != TrafficNginx {
+			return fmt.Errorf("unsupported traffic provider %q, must be none or nginx", answer)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if a.Traffic == TrafficNginx {
+		if a.Service, err = p.ask("Service of the workload", a.Workload, anyAnswer); err != nil {
+			return nil, err
+		}
+		if a.Ingress, err = p.ask("Ingress of the service", a.Service, anyAnswer); err != nil {
+			return nil, err
+		}
+	}
+	if a.PrometheusURL, err = p.ask("Prometheus URL of the metric gates, or none", "none", func(answer string) error {
+		if answer == "none" {
+			return nil
+		}
+		u, err := url.Parse(answer)
+		if err == nil && (len(u.Scheme) == 0 || len(u.Host) == 0) {
+			err = fmt.Errorf("invalid URL %q", answer)
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if a.PrometheusURL == "none" {
+		a.PrometheusURL = ""
+	} else if a.MetricQuery, err = p.ask("PromQL query passing when the canary is healthy", "", anyAnswer); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// parseWeights parses comma separated increasing percentages.
+func parseWeights(answer string) ([]int32, error) {
+	var weights []int32
+	for _, s := range strings.Split(answer, ",") {
+		weight, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(s), "%"), 10, 32)
+		if err != nil || weight < 1 || weight > 100 {
+			return nil, fmt.Errorf("invalid percentage %q, must be between 1 and 100", s)
+		}
+		if len(weights) > 0 && int32(weight) <= weights[len(weights)-1] {
+			return nil, fmt.Errorf("the percentages must be increasing")
+		}
+		weights = append(weights, int32(weight))
+	}
+	return weights, nil
+}
+
+// Rollout returns the Rollout of the answers in namespace.
+func Rollout(namespace string, a *RolloutAnswers) *kruiserolloutsv1apha1.Rollout {
+	canary := &kruiserolloutsv1apha1.CanaryStrategy{}
+	for _, weight := range a.Weights {
+		step := kruiserolloutsv1apha1.CanaryStep{Weight: weight}
+		if a.Pause > 0 {
+			seconds := int32(a.Pause / time.Second)
+			step.Pause.Duration = &seconds
+		}
+		canary.Steps = append(canary.Steps, step)
+	}
+	if a.Traffic == TrafficNginx {
+		canary.TrafficRouting = &kruiserolloutsv1apha1.TrafficRouting{
+			Service: a.Service,
+			Type:    kruiserolloutsv1apha1.TrafficRoutingNginx,
+			Nginx:   &kruiserolloutsv1apha1.NginxTrafficRouting{Ingress: a.Ingress},
+		}
+	}
+	return &kruiserolloutsv1apha1.Rollout{
+		TypeMeta:   metav1.TypeMeta{APIVersion: kruiserolloutsv1apha1.GroupVersion.String(), Kind: "Rollout"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: a.Name},
+		Spec: kruiserolloutsv1apha1.RolloutSpec{
+			ObjectRef: kruiserolloutsv1apha1.ObjectRef{
+				Type: kruiserolloutsv1apha1.WorkloadRefType,
+				WorkloadRef: &kruiserolloutsv1apha1.WorkloadRef{
+					APIVersion: workloadAPIVersions[a.Kind],
+					Kind:       a.Kind,
+					Name:       a.Workload,
+				},
+			},
+			Strategy: kruiserolloutsv1apha1.RolloutStrategy{
+				Type:   kruiserolloutsv1apha1.RolloutStrategyCanary,
+				Canary: canary,
+			},
+		},
+	}
+}
+
+// WriteRollout writes the Rollout of the answers in namespace to out as YAML, after comments on
+// its Ingress and metric gates.
+func WriteRollout(out io.Writer, namespace string, a *RolloutAnswers) error {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(Rollout(namespace, a))
+	if err != nil {
+		return err
+	}
+	// the status and the creation timestamp of the Rollout are not part of its manifest
+	delete(obj, "status")
+	unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	if a.Traffic == TrafficNginx {
+		fmt.Fprintf(out, "# The Ingress %s must be served by ingress-nginx. The Rollouts controller creates the\n", a.Ingress)
+		fmt.Fprintf(out, "# Ingress %s-canary with the nginx.ingress.kubernetes.io/canary annotations.\n", a.Ingress)
+	}
+	if len(a.MetricQuery) > 0 {
+		fmt.Fprintf(out, "# Approve the steps when the metric passes with:\n")
+		fmt.Fprintf(out, "#   kubectl-kruise rollout autopilot rollout/%s --prometheus-url %s --metric-query %s\n", a.Name, a.PrometheusURL, strconv.Quote(a.MetricQuery))
+	}
+	_, err = out.Write(data)
+	return err
+}
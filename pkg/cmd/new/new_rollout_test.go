@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package new
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAskRollout(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected *RolloutAnswers
+		err      string
+	}{
+		{
+			name:  "defaults",
+			input: "\ndemo\n\n\n\n\n\n",
+			expected: &RolloutAnswers{
+				Kind:     "CloneSet",
+				Workload: "demo",
+				Name:     "demo",
+				Weights:  []int32{20, 50, 100},
+				Traffic:  TrafficNone,
+			},
+		},
+		{
+			name: "nginx and metric gates after invalid answers",
+			input: "StatefulSet\nDeployment\n\nweb\nweb-rollout\n50,20\n10%,100%\n1h\nistio\nnginx\n\nweb-ingress\n" +
+				"prometheus\nhttp://prometheus:9090\n\nerror_rate < 0.01\n",
+			expected: &RolloutAnswers{
+				Kind:          "Deployment",
+				Workload:      "web",
+				Name:          "web-rollout",
+				Weights:       []int32{10, 100},
+				Pause:         time.Hour,
+				Traffic:       TrafficNginx,
+				Service:       "web",
+				Ingress:       "web-ingress",
+				PrometheusURL: "http://prometheus:9090",
+				MetricQuery:   "error_rate < 0.01",
+			},
+		},
+		{
+			name:  "no answer",
+			input: "CloneSet\n",
+			err:   `no answer to "Name of the workload"`,
+		},
+	}
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		answers, err := AskRollout(strings.NewReader(test.input), out)
+		if len(test.err) > 0 {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: expected error %q, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(answers, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, answers)
+		}
+	}
+}
+
+func TestWriteRollout(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := WriteRollout(out, "default", &RolloutAnswers{
+		Kind:          "CloneSet",
+		Workload:      "demo",
+		Name:          "demo",
+		Weights:       []int32{20, 100},
+		Pause:         5 * time.Minute,
+		Traffic:       TrafficNginx,
+		Service:       "demo",
+		Ingress:       "demo",
+		PrometheusURL: "http://prometheus:9090",
+		MetricQuery:   `sum(rate(http_errors{app="demo"}[5m])) < 1`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# The Ingress demo must be served by ingress-nginx. The Rollouts controller creates the
+# Ingress demo-canary with the nginx.ingress.kubernetes.io/canary annotations.
+# Approve the steps when the metric passes with:
+#   kubectl-kruise rollout autopilot rollout/demo --prometheus-url http://prometheus:9090 --metric-query "sum(rate(http_errors{app=\"demo\"}[5m])) < 1"
+apiVersion: rollouts.kruise.io/v1alpha1
+kind: Rollout
+metadata:
+  name: demo
+  namespace: default
+spec:
+  objectRef:
+    type: workloadRef
+    workloadRef:
+      apiVersion: apps.kruise.io/v1alpha1
+      kind: CloneSet
+      name: demo
+  strategy:
+    canary:
+      steps:
+      - pause:
+          duration: 300
+        weight: 20
+      - pause:
+          duration: 300
+        weight: 100
+      trafficRouting:
+        nginx:
+          ingress: demo
+        service: demo
+        type: nginx
+    type: canary
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
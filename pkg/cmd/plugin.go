@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/freeze"
+	"github.com/openkruise/kruise-tools/pkg/readonly"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	kubectlcmd "k8s.io/kubectl/pkg/cmd"
+)
+
+const (
+	// PluginPrefix is the prefix of the executables on PATH that extend kubectl-kruise:
+	// `kubectl-kruise foo bar` runs kubectl-kruise-foo-bar or kubectl-kruise-foo.
+	PluginPrefix = "kubectl-kruise"
+
+	// PluginCallerEnv is set to the path of the kubectl-kruise binary that runs a plugin,
+	// so that plugins can call back into it.
+	PluginCallerEnv = "KUBECTL_KRUISE_CALLER"
+
+	// PluginFlagEnvPrefix prefixes the environment variables that pass the root flags given
+	// before the plugin name, e.g. `kubectl-kruise -n demo foo` sets KUBECTL_KRUISE_FLAG_NAMESPACE=demo.
+	PluginFlagEnvPrefix = "KUBECTL_KRUISE_FLAG_"
+)
+
+// pluginHandler adds the environment shared with all plugins to the one they are executed with.
+type pluginHandler struct {
+	kubectlcmd.PluginHandler
+	env []string
+}
+
+// Execute implements PluginHandler
+func (h *pluginHandler) Execute(executablePath string, cmdArgs, environment []string) error {
+	return h.PluginHandler.Execute(executablePath, cmdArgs, append(environment, h.env...))
+}
+
+// handlePluginCommand runs the plugin that satisfies args, the command line without the binary
+// name, if there is one. Root flags may be placed before the plugin name and are passed to the
+// plugin through the environment, with KUBECONFIG set from --kubeconfig, so that plugins
+// connect to the same cluster with the same credentials.
+func handlePluginCommand(root *cobra.Command, handler kubectlcmd.PluginHandler, args []string) error {
+	flags, err := parseRootFlags(root, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return nil
+	}
+
+	caller, err := os.Executable()
+	if err != nil {
+		caller = os.Args[0]
+	}
+	return kubectlcmd.HandlePluginCommand(&pluginHandler{PluginHandler: handler, env: pluginEnvironment(flags, caller)}, flags.Args())
+}
+
+// parseRootFlags parses the root flags placed before the first argument of args, which are
+// left in the returned flags' Args().
+func parseRootFlags(root *cobra.Command, args []string) (*pflag.FlagSet, error) {
+	flags := pflag.NewFlagSet(root.Name(), pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.SetOutput(ioutil.Discard)
+	flags.AddFlagSet(root.PersistentFlags())
+	return flags, flags.Parse(args)
+}
+
+// pluginEnvironment returns the environment variables of the root flags that were set. Read-only
+// mode is passed as KUBECTL_KRUISE_READ_ONLY and the reason of --break-glass as
+// KUBECTL_KRUISE_BREAK_GLASS, so that the calls of plugins back into kubectl-kruise are read-only
+// too, and are refused during the freeze windows of the cluster unless the glass was broken.
+func pluginEnvironment(flags *pflag.FlagSet, caller string) []string {
+	env := []string{fmt.Sprintf("%s=%s", PluginCallerEnv, caller)}
+	if readonly.Enabled() {
+		env = append(env, fmt.Sprintf("%s=true", readonly.EnvName))
+	}
+	if reason := freeze.BreakGlass(); len(reason) > 0 {
+		env = append(env, fmt.Sprintf("%s=%s", freeze.EnvName, reason))
+	}
+	flags.Visit(func(flag *pflag.Flag) {
+		name := strings.ToUpper(strings.Replace(flag.Name, "-", "_", -1))
+		env = append(env, fmt.Sprintf("%s%s=%s", PluginFlagEnvPrefix, name, flag.Value.String()))
+		if flag.Name == "kubeconfig" {
+			env = append(env, fmt.Sprintf("KUBECONFIG=%s", flag.Value.String()))
+		}
+	})
+	return env
+}
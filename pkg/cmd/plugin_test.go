@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/freeze"
+	"github.com/openkruise/kruise-tools/pkg/readonly"
+
+	"github.com/spf13/cobra"
+)
+
+type testPluginHandler struct {
+	plugins  map[string]bool
+	executed string
+	args     []string
+	env      []string
+}
+
+func (h *testPluginHandler) Lookup(filename string) (string, bool) {
+	if h.plugins[filename] {
+		return "/usr/local/bin/kubectl-kruise-" + filename, true
+	}
+	return "", false
+}
+
+func (h *testPluginHandler) Execute(executablePath string, cmdArgs, environment []string) error {
+	h.executed, h.args, h.env = executablePath, cmdArgs, environment
+	return nil
+}
+
+func TestHandlePluginCommand(t *testing.T) {
+	root := &cobra.Command{Use: "kubectl-kruise"}
+	root.PersistentFlags().StringP("namespace", "n", "", "")
+	root.PersistentFlags().String("kubeconfig", "", "")
+	root.PersistentFlags().String("context", "", "")
+
+	handler := &testPluginHandler{plugins: map[string]bool{"canary": true, "canary-report": true}}
+	args := []string{"-n", "demo", "--kubeconfig=/tmp/config", "canary", "report", "--since", "1h"}
+	if err := handlePluginCommand(root, handler, args); err != nil {
+		t.Fatal(err)
+	}
+
+	if handler.executed != "/usr/local/bin/kubectl-kruise-canary-report" {
+		t.Errorf("expected the longest matching plugin to be executed, got %q", handler.executed)
+	}
+	if !reflect.DeepEqual(handler.args, []string{"--since", "1h"}) {
+		t.Errorf("unexpected plugin args %v", handler.args)
+	}
+	env := strings.Join(handler.env, "\n")
+	for _, expected := range []string{PluginCallerEnv + "=", "KUBECTL_KRUISE_FLAG_NAMESPACE=demo", "KUBECTL_KRUISE_FLAG_KUBECONFIG=/tmp/config", "KUBECONFIG=/tmp/config"} {
+		if !strings.Contains(env, expected) {
+			t.Errorf("expected plugin environment to contain %q", expected)
+		}
+	}
+	if strings.Contains(env, "KUBECTL_KRUISE_FLAG_CONTEXT") {
+		t.Errorf("expected unset flags not to be passed")
+	}
+
+	handler = &testPluginHandler{}
+	if err := handlePluginCommand(root, handler, []string{"unknown"}); err != nil || handler.executed != "" {
+		t.Errorf("expected no plugin to be executed, got %q, %v", handler.executed, err)
+	}
+}
+
+func TestHandlePluginCommandReadOnly(t *testing.T) {
+	root := &cobra.Command{Use: "kubectl-kruise"}
+	readonly.AddFlags(root.PersistentFlags())
+	defer readonly.ParseArgs([]string{"--read-only=false"})
+
+	handler := &testPluginHandler{plugins: map[string]bool{"canary": true}}
+	if err := handlePluginCommand(root, handler, []string{"--read-only", "canary"}); err != nil {
+		t.Fatal(err)
+	}
+	env := strings.Join(handler.env, "\n")
+	if !strings.Contains(env, readonly.EnvName+"=true") {
+		t.Errorf("expected the plugin to run in read-only mode, got environment %v", handler.env)
+	}
+}
+
+func TestHandlePluginCommandBreakGlass(t *testing.T) {
+	root := &cobra.Command{Use: "kubectl-kruise"}
+	freeze.AddFlags(root.PersistentFlags())
+	defer freeze.ParseArgs([]string{"--break-glass="})
+
+	handler := &testPluginHandler{plugins: map[string]bool{"canary": true}}
+	if err := handlePluginCommand(root, handler, []string{"--break-glass", "INC-4242 rollback", "canary"}); err != nil {
+		t.Fatal(err)
+	}
+	env := strings.Join(handler.env, "\n")
+	if !strings.Contains(env, freeze.EnvName+"=INC-4242 rollback") {
+		t.Errorf("expected the plugin to break the glass, got environment %v", handler.env)
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	podLong = templates.LongDesc(`
+		Manage the Kruise state of pods.
+
+		These commands help you operate single pods of Kruise workloads.`)
+
+	podExample = templates.Examples(`
+		# Take pod demo-xyz out of service endpoints without deleting it
+		kubectl-kruise pod ready pod/demo-xyz --set=false`)
+)
+
+// NewCmdPod returns a Command instance for 'pod' sub command
+func NewCmdPod(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "pod SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Manage the Kruise state of pods"),
+		Long:                  podLong,
+		Example:               podExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(NewCmdPodReady(f, streams))
+	return cmd
+}
@@ -0,0 +1,350 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	appspub "github.com/openkruise/kruise-api/apps/pub"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// ReadyUserAgent identifies the holds of kubectl-kruise in the KruisePodReady condition.
+const ReadyUserAgent = "kubectl-kruise"
+
+var (
+	podReadyLong = templates.LongDesc(`
+		Show or toggle the KruisePodReady condition of pods.
+
+		Pods that have the KruisePodReady readiness gate are only ready, and receive traffic
+		from services, while this condition is True. Setting it to false adds a hold with the
+		given --reason, which keeps the pod not ready until the hold is removed by setting it
+		to true again. Holds added by Kruise itself, for example during in-place update, are
+		kept untouched.`)
+
+	podReadyExample = templates.Examples(`
+		# Show the KruisePodReady condition of pod demo-xyz
+		kubectl-kruise pod ready pod/demo-xyz
+
+		# Drain traffic from pod demo-xyz without deleting it
+		kubectl-kruise pod ready pod/demo-xyz --set=false --reason=disk-repair
+
+		# Let pod demo-xyz receive traffic again
+		kubectl-kruise pod ready pod/demo-xyz --set=true --reason=disk-repair
+
+		# Drain traffic from all pods labeled zone=a
+		kubectl-kruise pod ready -l zone=a --set=false
+
+		# Show the state of all running pods
+		kubectl-kruise pod ready --field-selector status.phase=Running
+
+		# Drain traffic from the pods labeled app=demo whose app container restarted
+		kubectl-kruise pod ready -l app=demo --filter-expr 'status.containerStatuses.exists(c, c.name == "app" && c.restartCount > 0)' --set=false`)
+)
+
+// PodReadyOptions holds the command-line options for 'pod ready' sub command
+type PodReadyOptions struct {
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+	Selector         string
+	FieldSelector    string
+	FilterExpr       string
+	Reason           string
+	DryRun           bool
+
+	// ready is nil when only the current state is shown
+	ready  *bool
+	filter *internalcmdutil.FilterExpr
+
+	Builder func() *resource.Builder
+	Client  kubernetes.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// podReadyMessage is a hold on the KruisePodReady condition, in the format the Kruise controllers use.
+type podReadyMessage struct {
+	UserAgent string `json:"userAgent"`
+	Key       string `json:"key"`
+}
+
+// NewPodReadyOptions returns an initialized PodReadyOptions instance
+func NewPodReadyOptions(streams genericclioptions.IOStreams) *PodReadyOptions {
+	return &PodReadyOptions{
+		Reason:    "maintenance",
+		IOStreams: streams,
+	}
+}
+
+// NewCmdPodReady returns a Command instance for 'pod ready' sub command
+func NewCmdPodReady(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewPodReadyOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "ready (pod/NAME | -l SELECTOR) [--set=true|false] [--reason=REASON]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show or toggle the KruisePodReady condition of pods"),
+		Long:                  podReadyLong,
+		Example:               podReadyExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "identifying the pods to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	internalcmdutil.AddFieldSelectorFlagVar(cmd, &o.FieldSelector)
+	internalcmdutil.AddFilterExprFlagVar(cmd, &o.FilterExpr)
+	cmd.Flags().Bool("set", true, "Set to false to hold the pods not ready, or to true to remove the hold.")
+	cmd.Flags().StringVar(&o.Reason, "reason", o.Reason, "The key of the hold, so that holds for different reasons are removed independently.")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, only print the pods that would be changed.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *PodReadyOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+	if cmd.Flags().Changed("set") {
+		ready := cmdutil.GetFlagBool(cmd, "set")
+		o.ready = &ready
+	}
+	if o.filter, err = internalcmdutil.CompileFilterExpr(o.FilterExpr); err != nil {
+		return err
+	}
+	o.Client, err = f.KubernetesClientSet()
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *PodReadyOptions) Validate() error {
+	if len(o.Resources) == 0 && len(o.Selector) == 0 && len(o.FieldSelector) == 0 && len(o.FilterExpr) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("one or more pods must be specified as <name>, pod/<name>, with -l, --field-selector or --filter-expr")
+	}
+	if err := internalcmdutil.ValidateFieldSelector(o.FieldSelector); err != nil {
+		return err
+	}
+	if o.ready != nil && len(o.Reason) == 0 {
+		return fmt.Errorf("--reason must not be empty")
+	}
+	return nil
+}
+
+// Run performs the execution of 'pod ready' sub command
+func (o *PodReadyOptions) Run() error {
+	resources := o.Resources
+	if len(resources) == 1 && !strings.Contains(resources[0], "/") {
+		resources = []string{"pods", resources[0]}
+	} else if len(resources) == 0 && (len(o.Selector) > 0 || len(o.FieldSelector) > 0 || len(o.FilterExpr) > 0) {
+		resources = []string{"pods"}
+	}
+
+	r := o.Builder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		LabelSelectorParam(o.Selector).
+		FieldSelectorParam(o.FieldSelector).
+		ResourceTypeOrNameArgs(true, resources...).
+		ContinueOnError().
+		Latest().
+		Flatten().
+		Do()
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	var allErrs []error
+	err := r.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		pod, ok := info.Object.(*corev1.Pod)
+		if !ok {
+			allErrs = append(allErrs, fmt.Errorf("%s/%s is not a pod", info.Mapping.Resource.Resource, info.Name))
+			return nil
+		}
+		if matches, err := o.filter.Matches(pod); err != nil || !matches {
+			return err
+		}
+		if o.ready == nil {
+			o.printReadyState(pod)
+			return nil
+		}
+		if err := o.toggle(pod); err != nil {
+			allErrs = append(allErrs, err)
+		}
+		return nil
+	})
+	if err != nil {
+		allErrs = append(allErrs, err)
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+func (o *PodReadyOptions) toggle(pod *corev1.Pod) error {
+	if !hasKruiseReadinessGate(pod) {
+		return fmt.Errorf("pod/%s has no %s readiness gate, its readiness cannot be changed", pod.Name, appspub.KruisePodReadyConditionType)
+	}
+
+	var changed bool
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		changed, err = updateReadyCondition(pod, o.Reason, *o.ready)
+		if err != nil || !changed || o.DryRun {
+			return err
+		}
+		_, err = o.Client.CoreV1().Pods(pod.Namespace).UpdateStatus(context.TODO(), pod, metav1.UpdateOptions{})
+		if err != nil {
+			if latest, getErr := o.Client.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{}); getErr == nil {
+				pod = latest
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update pod/%s: %v", pod.Name, err)
+	}
+
+	state := "not changed"
+	if changed && *o.ready {
+		state = fmt.Sprintf("released %s", o.Reason)
+	} else if changed {
+		state = fmt.Sprintf("held not ready for %s", o.Reason)
+	}
+	if o.DryRun {
+		state += " (dry run)"
+	}
+	fmt.Fprintf(o.Out, "pod/%s %s\n", pod.Name, state)
+	return nil
+}
+
+func (o *PodReadyOptions) printReadyState(pod *corev1.Pod) {
+	if !hasKruiseReadinessGate(pod) {
+		fmt.Fprintf(o.Out, "pod/%s has no %s readiness gate\n", pod.Name, appspub.KruisePodReadyConditionType)
+		return
+	}
+	condition := getReadyCondition(pod)
+	if condition == nil {
+		fmt.Fprintf(o.Out, "pod/%s %s=Unknown\n", pod.Name, appspub.KruisePodReadyConditionType)
+		return
+	}
+	var holds []string
+	messages, _ := parseReadyMessages(condition.Message)
+	for _, m := range messages {
+		holds = append(holds, fmt.Sprintf("%s/%s", m.UserAgent, m.Key))
+	}
+	if len(holds) == 0 {
+		fmt.Fprintf(o.Out, "pod/%s %s=%s\n", pod.Name, appspub.KruisePodReadyConditionType, condition.Status)
+		return
+	}
+	fmt.Fprintf(o.Out, "pod/%s %s=%s held by %s\n", pod.Name, appspub.KruisePodReadyConditionType, condition.Status, strings.Join(holds, ","))
+}
+
+// updateReadyCondition adds or removes the hold for key in the KruisePodReady condition of pod.
+// The condition is False while any hold is present, and True otherwise.
+func updateReadyCondition(pod *corev1.Pod, key string, ready bool) (bool, error) {
+	condition := getReadyCondition(pod)
+	if condition == nil {
+		pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{Type: appspub.KruisePodReadyConditionType})
+		condition = &pod.Status.Conditions[len(pod.Status.Conditions)-1]
+	}
+
+	messages, err := parseReadyMessages(condition.Message)
+	if err != nil {
+		return false, fmt.Errorf("unrecognized %s condition message %q: %v", appspub.KruisePodReadyConditionType, condition.Message, err)
+	}
+	hold := podReadyMessage{UserAgent: ReadyUserAgent, Key: key}
+	var updated []podReadyMessage
+	for _, m := range messages {
+		if m != hold {
+			updated = append(updated, m)
+		}
+	}
+	if !ready {
+		updated = append(updated, hold)
+	}
+
+	status := corev1.ConditionTrue
+	message := ""
+	if len(updated) > 0 {
+		status = corev1.ConditionFalse
+		b, _ := json.Marshal(updated)
+		message = string(b)
+	}
+	if condition.Status == status && condition.Message == message {
+		return false, nil
+	}
+	if condition.Status != status {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	condition.Status = status
+	condition.Message = message
+	return true, nil
+}
+
+func parseReadyMessages(message string) ([]podReadyMessage, error) {
+	var messages []podReadyMessage
+	if len(message) == 0 {
+		return messages, nil
+	}
+	err := json.Unmarshal([]byte(message), &messages)
+	return messages, err
+}
+
+func getReadyCondition(pod *corev1.Pod) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == appspub.KruisePodReadyConditionType {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func hasKruiseReadinessGate(pod *corev1.Pod) bool {
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == appspub.KruisePodReadyConditionType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"testing"
+
+	appspub "github.com/openkruise/kruise-api/apps/pub"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestUpdateReadyCondition(t *testing.T) {
+	kruiseHold := `[{"userAgent":"Lifecycle","key":"PreDelete"}]`
+	tests := []struct {
+		name            string
+		conditions      []corev1.PodCondition
+		ready           bool
+		expectedChanged bool
+		expectedStatus  corev1.ConditionStatus
+		expectedMessage string
+	}{
+		{
+			name:            "hold without condition",
+			ready:           false,
+			expectedChanged: true,
+			expectedStatus:  corev1.ConditionFalse,
+			expectedMessage: `[{"userAgent":"kubectl-kruise","key":"maintenance"}]`,
+		},
+		{
+			name:            "hold twice",
+			conditions:      []corev1.PodCondition{{Type: appspub.KruisePodReadyConditionType, Status: corev1.ConditionFalse, Message: `[{"userAgent":"kubectl-kruise","key":"maintenance"}]`}},
+			ready:           false,
+			expectedStatus:  corev1.ConditionFalse,
+			expectedMessage: `[{"userAgent":"kubectl-kruise","key":"maintenance"}]`,
+		},
+		{
+			name:            "release",
+			conditions:      []corev1.PodCondition{{Type: appspub.KruisePodReadyConditionType, Status: corev1.ConditionFalse, Message: `[{"userAgent":"kubectl-kruise","key":"maintenance"}]`}},
+			ready:           true,
+			expectedChanged: true,
+			expectedStatus:  corev1.ConditionTrue,
+		},
+		{
+			name:            "release keeps holds of kruise",
+			conditions:      []corev1.PodCondition{{Type: appspub.KruisePodReadyConditionType, Status: corev1.ConditionFalse, Message: `[{"userAgent":"Lifecycle","key":"PreDelete"},{"userAgent":"kubectl-kruise","key":"maintenance"}]`}},
+			ready:           true,
+			expectedChanged: true,
+			expectedStatus:  corev1.ConditionFalse,
+			expectedMessage: kruiseHold,
+		},
+		{
+			name:           "release without hold",
+			conditions:     []corev1.PodCondition{{Type: appspub.KruisePodReadyConditionType, Status: corev1.ConditionTrue}},
+			ready:          true,
+			expectedStatus: corev1.ConditionTrue,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &corev1.Pod{Status: corev1.PodStatus{Conditions: test.conditions}}
+			changed, err := updateReadyCondition(pod, "maintenance", test.ready)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != test.expectedChanged {
+				t.Errorf("expected changed %v, got %v", test.expectedChanged, changed)
+			}
+			condition := getReadyCondition(pod)
+			if condition.Status != test.expectedStatus || condition.Message != test.expectedMessage {
+				t.Errorf("expected %s %q, got %s %q", test.expectedStatus, test.expectedMessage, condition.Status, condition.Message)
+			}
+		})
+	}
+}
+
+func TestToggle(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo-xyz"},
+		Spec:       corev1.PodSpec{ReadinessGates: []corev1.PodReadinessGate{{ConditionType: appspub.KruisePodReadyConditionType}}},
+	}
+	client := fake.NewSimpleClientset(pod)
+
+	ready := false
+	o := NewPodReadyOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.Client = client
+	o.ready = &ready
+	if err := o.toggle(pod.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+	latest, _ := client.CoreV1().Pods("default").Get(context.TODO(), "demo-xyz", metav1.GetOptions{})
+	if condition := getReadyCondition(latest); condition == nil || condition.Status != corev1.ConditionFalse {
+		t.Errorf("expected pod to be held not ready, got %+v", latest.Status.Conditions)
+	}
+
+	if err := o.toggle(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "no-gate"}}); err == nil {
+		t.Errorf("expected an error for a pod without readiness gate")
+	}
+}
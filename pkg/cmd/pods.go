@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"time"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+const defaultPodWaitReadyTimeout = 5 * time.Minute
+
+// podCommands are the kubectl commands, run on a pod or TYPE/NAME, that choose the pod of workloads
+// with --revision and --wait-ready, as exec does.
+var podCommands = sets.NewString("logs", "port-forward")
+
+// withPodRevision adds the --revision, --wait-ready and --timeout flags of 'exec' to cmd, a kubectl
+// command run on a pod or TYPE/NAME: the pod of the workload is chosen from --revision, such as the
+// updated one to debug the new code path, and with --wait-ready, it is waited for to be ready
+// instead of failing while the pods are still starting.
+func withPodRevision(cmd *cobra.Command) {
+	var (
+		waitReady bool
+		revision  string
+		timeout   time.Duration
+	)
+	cmd.Flags().StringVar(&revision, "revision", revision, "The revision of the pod of the workload: 'updated', 'stable', a revision number as listed by 'rollout history', or a revision hash")
+	cmd.Flags().BoolVar(&waitReady, "wait-ready", waitReady, "If true, wait for a ready pod, of --revision if set, instead of failing when the pods of the workload are still starting")
+	cmd.Flags().DurationVar(&timeout, "timeout", defaultPodWaitReadyTimeout, "With --wait-ready, the length of time to wait for a ready pod")
+
+	run := cmd.Run
+	cmd.Run = func(c *cobra.Command, args []string) {
+		if !waitReady && len(revision) == 0 {
+			run(c, args)
+			return
+		}
+		if len(args) == 0 {
+			cmdutil.CheckErr(cmdutil.UsageErrorf(c, "--revision and --wait-ready require a pod or TYPE/NAME"))
+		}
+		name, err := podName(c, args[0], revision, waitReady, timeout)
+		cmdutil.CheckErr(err)
+		run(c, append([]string{name}, args[1:]...))
+	}
+}
+
+// podName returns the pod/NAME of the pod of revision of the pod or TYPE/NAME resource, waiting
+// for it to be ready if waitReady is true, with the cluster and namespace of the kubeconfig flags of cmd.
+func podName(cmd *cobra.Command, resource, revision string, waitReady bool, timeout time.Duration) (string, error) {
+	getter := configFlagsFrom(cmd)
+	namespace, _, err := getter.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return "", err
+	}
+	f := cmdutil.NewFactory(internalcmdutil.WithKruiseShortNames(getter))
+	obj, err := f.NewBuilder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(namespace).DefaultNamespace().
+		ResourceNames("pods", resource).
+		Do().Object()
+	if err != nil {
+		return "", err
+	}
+	var pod *corev1.Pod
+	if waitReady {
+		pod, err = internalpolymorphichelpers.ReadyPodForObject(f, obj, revision, timeout)
+	} else {
+		pod, err = internalpolymorphichelpers.PodOfRevisionForObject(f, obj, revision)
+	}
+	if err != nil {
+		return "", err
+	}
+	return "pod/" + pod.Name, nil
+}
+
+// configFlagsFrom returns the kubeconfig flags set on cmd, such as --context or --namespace.
+func configFlagsFrom(cmd *cobra.Command) *genericclioptions.ConfigFlags {
+	configFlags := genericclioptions.NewConfigFlags(true)
+	flags := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	configFlags.AddFlags(flags)
+	flags.VisitAll(func(flag *pflag.Flag) {
+		set := cmd.Flags().Lookup(flag.Name)
+		if set == nil || !set.Changed {
+			return
+		}
+		if values, ok := set.Value.(pflag.SliceValue); ok {
+			if slice, ok := flag.Value.(pflag.SliceValue); ok {
+				_ = slice.Replace(values.GetSlice())
+				return
+			}
+		}
+		_ = flag.Value.Set(set.Value.String())
+	})
+	return configFlags
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package promote
+
+import (
+	"fmt"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	kset "github.com/openkruise/kruise-tools/pkg/cmd/set"
+	"github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	promoteLong = templates.LongDesc(`
+		Promote the progressive delivery of a resource.
+
+		What promoting means depends on the kind of the resource:
+
+		* Rollout: the current step, which must be paused, is approved.
+		* BatchRelease: the next batch is released, once the current one is ready. With --full,
+		  all the remaining batches are released.
+		* CloneSet and Advanced StatefulSet: the partition is set to 0, so that all pods are updated.
+
+		With --snapshot, the state of the workload and of its pods, and the values of the
+		--snapshot-metric queries, are recorded in the kubectl.kruise.io/promotion-snapshots
+		annotation of the promoted resource, as the evidence each promotion was decided on.`)
+
+	promoteExample = templates.Examples(`
+		# Approve the current step of rollout demo
+		kubectl-kruise promote rollout/demo
+
+		# Release all the remaining batches of batchrelease demo
+		kubectl-kruise promote batchrelease/demo --full
+
+		# Update all the pods of cloneset demo
+		kubectl-kruise promote cloneset/demo
+
+		# Approve the current step of rollout demo, recording its error rate at the time
+		kubectl-kruise promote rollout/demo --snapshot --prometheus-url http://prometheus:9090 \
+		  --snapshot-metric 'errors=sum(rate(http_errors{app="demo"}[5m])) / sum(rate(http_requests{app="demo"}[5m]))'`)
+)
+
+// PromoteOptions holds the command-line options for 'promote' command
+type PromoteOptions struct {
+	PrintFlags *genericclioptions.PrintFlags
+	ToPrinter  func(string) (printers.ResourcePrinter, error)
+
+	Resources []string
+	Full      bool
+	Snapshot  util.PromotionSnapshotOptions
+
+	Builder          func() *resource.Builder
+	Promoter         polymorphichelpers.ObjectPromoterFunc
+	Namespace        string
+	EnforceNamespace bool
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NewPromoteOptions returns an initialized PromoteOptions instance
+func NewPromoteOptions(streams genericclioptions.IOStreams) *PromoteOptions {
+	return &PromoteOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("promoted").WithTypeSetter(internalapi.GetScheme()),
+		IOStreams:  streams,
+	}
+}
+
+// NewCmdPromote returns a Command instance for 'promote' command
+func NewCmdPromote(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewPromoteOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "promote (TYPE NAME | TYPE/NAME) [--full] [--snapshot]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Promote a Rollout, a BatchRelease or a partitioned workload"),
+		Long:                  promoteLong,
+		Example:               promoteExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+		ValidArgs: []string{"rollout", "batchrelease", "cloneset", "statefulset"},
+	}
+
+	usage := "identifying the resource to promote."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.Full, "full", o.Full, "If true, promote to the end instead of the next step. Only supported by BatchReleases, CloneSets and Advanced StatefulSets.")
+	util.AddPromotionSnapshotFlags(cmd, &o.Snapshot)
+	o.PrintFlags.AddFlags(cmd)
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *PromoteOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	o.Resources = args
+	o.Promoter = polymorphichelpers.ObjectPromoterFn
+
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	o.ToPrinter = func(operation string) (printers.ResourcePrinter, error) {
+		o.PrintFlags.NamePrintFlags.Operation = operation
+		return o.PrintFlags.ToPrinter()
+	}
+	o.Builder = f.NewBuilder
+	return o.Snapshot.Complete(f)
+}
+
+// Validate makes sure that a resource is given
+func (o *PromoteOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	return o.Snapshot.Validate()
+}
+
+// Run performs the execution of 'promote' command
+func (o *PromoteOptions) Run() error {
+	r := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		ContinueOnError().
+		Latest().
+		Flatten().
+		Do()
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	allErrs := []error{}
+	infos, err := r.Infos()
+	if err != nil {
+		allErrs = append(allErrs, err)
+	}
+
+	promote := func(obj runtime.Object) ([]byte, error) {
+		return o.Promoter(obj, o.Full)
+	}
+	for _, patch := range kset.CalculatePatches(infos, scheme.DefaultJSONEncoder(), promote) {
+		info := patch.Info
+
+		if patch.Err != nil {
+			resourceString := info.Mapping.Resource.Resource
+			if len(info.Mapping.Resource.Group) > 0 {
+				resourceString = resourceString + "." + info.Mapping.Resource.Group
+			}
+			allErrs = append(allErrs, fmt.Errorf("error: %s %q %v", resourceString, info.Name, patch.Err))
+			continue
+		}
+
+		operation := "promoted"
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			operation = "already promoted"
+		} else {
+			// the snapshot is taken before the promotion changes the resource
+			var snapshot *util.PromotionSnapshot
+			if o.Snapshot.Snapshot {
+				if snapshot, err = o.Snapshot.Take("promote", info.Object); err != nil {
+					allErrs = append(allErrs, fmt.Errorf("failed to take a snapshot of %s, not promoted: %v", info.ObjectName(), err))
+					continue
+				}
+			}
+			// the steps of Rollouts are approved in their status
+			var obj runtime.Object
+			if _, ok := info.Object.(*kruiserolloutsv1apha1.Rollout); ok {
+				obj, err = util.PatchSubResource(info.Client, info.Mapping.Resource.Resource, "status", info.Namespace, info.Name, info.Namespaced(), types.MergePatchType, patch.Patch, nil)
+			} else {
+				obj, err = resource.NewHelper(info.Client, info.Mapping).Patch(info.Namespace, info.Name, types.MergePatchType, patch.Patch, nil)
+			}
+			if err != nil {
+				allErrs = append(allErrs, fmt.Errorf("failed to patch: %v", err))
+				continue
+			}
+			info.Refresh(obj, true)
+			if snapshot != nil {
+				if err := o.Snapshot.Record(info, snapshot); err != nil {
+					allErrs = append(allErrs, fmt.Errorf("%s promoted but recording its snapshot failed: %v", info.ObjectName(), err))
+				}
+			}
+		}
+
+		printer, err := o.ToPrinter(operation)
+		if err != nil {
+			allErrs = append(allErrs, err)
+			continue
+		}
+		if err = printer.PrintObj(info.Object, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(allErrs)
+}
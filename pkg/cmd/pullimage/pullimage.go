@@ -0,0 +1,805 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullimage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// WorkloadLabel is set on the ImagePullJobs created for a workload to its name.
+const WorkloadLabel = "kubectl.kruise.io/pull-image-workload"
+
+// The reasons of the nodes failing to pull an image
+const (
+	ReasonDiskPressure = "disk pressure"
+	ReasonAuth         = "auth error"
+	ReasonNotFound     = "image not found"
+	ReasonTimeout      = "timeout"
+	ReasonUnknown      = "unknown"
+)
+
+// failureKeywords are the keywords of the pull errors of the reasons, in lower case
+var failureKeywords = []struct {
+	reason   string
+	keywords []string
+}{
+	{ReasonDiskPressure, []string{"no space left on device", "disk pressure", "disk quota"}},
+	{ReasonAuth, []string{"unauthorized", "authentication required", "access denied", "denied", "forbidden", "401", "403"}},
+	{ReasonNotFound, []string{"manifest unknown", "not found", "404"}},
+	{ReasonTimeout, []string{"timeout", "timed out", "deadline exceeded"}},
+}
+
+var (
+	pullImageLong = templates.LongDesc(`
+		Pre-pull the images of a workload on the nodes with ImagePullJobs.
+
+		An ImagePullJob is created for every image of the containers of the pod template of the
+		workload, named after the workload and the container. With --all-containers, the images
+		of the init containers and of the sidecar containers injected by SidecarSets are pulled
+		too. All the jobs share the same selector: the nodes running the pods of the workload,
+		or the nodes of --node-selector and --node-selector-expr. Jobs that already exist for the same image are kept.
+
+		The images of private registries are pulled with the secrets of --pull-secret, or else
+		with the imagePullSecrets of the workload and of its service account, like its pods.
+
+		Each node retries a failed pull --backoff-limit times, each pull taking at most
+		--pull-timeout, and the jobs stop after --active-deadline. The defaults of Kruise apply
+		to the settings not given.
+
+		The combined status of the jobs is printed at the end, with the nodes that failed to pull
+		an image and the reason, such as disk pressure or an auth error, read from their
+		NodeImages. With --wait, the jobs are waited for to complete, the failures are printed
+		as they happen, and the command fails if any node failed to pull an image.`)
+
+	pullImageExample = templates.Examples(`
+		# Pre-pull the images of cloneset demo on the nodes running its pods
+		kubectl-kruise pull-image --from-workload cloneset/demo
+
+		# Pre-pull all the images of cloneset demo, sidecars included, on the nodes labeled pool=web, and wait
+		kubectl-kruise pull-image --from-workload cloneset/demo --all-containers --node-selector pool=web --wait
+
+		# Pre-pull the images of cloneset demo from a private registry with the secret registry-creds
+		kubectl-kruise pull-image --from-workload cloneset/demo --pull-secret registry-creds
+
+		# Pre-pull the images of cloneset demo on the nodes of the web and api pools, except the spot nodes
+		kubectl-kruise pull-image --from-workload cloneset/demo --node-selector-expr 'pool In (web,api)' --node-selector-expr 'spot DoesNotExist'
+
+		# Pre-pull the images of cloneset demo on 20% of the nodes at a time, retrying once and giving up after 30 minutes
+		kubectl-kruise pull-image --from-workload cloneset/demo --parallelism 20% --backoff-limit 1 --pull-timeout 5m --active-deadline 30m --wait
+
+		# Print the ImagePullJobs that would be created, without creating them
+		kubectl-kruise pull-image --from-workload asts/demo --all-containers --dry-run=client -o yaml`)
+)
+
+// Image is an image of a workload, with the first container running it.
+type Image struct {
+	Image     string
+	Container string
+}
+
+// PullImageOptions holds the command-line options for 'pull-image' command
+type PullImageOptions struct {
+	PrintFlags *genericclioptions.PrintFlags
+	PrintObj   printers.ResourcePrinterFunc
+
+	Namespace        string
+	EnforceNamespace bool
+
+	FromWorkload   string
+	AllContainers  bool
+	PullSecrets    []string
+	NodeSelector   string
+	NodeExprs      []string
+	Parallelism    string
+	BackoffLimit   int32
+	PullTimeout    time.Duration
+	ActiveDeadline time.Duration
+	Wait           bool
+	Timeout        time.Duration
+	BundleOut      string
+	DryRunStrategy cmdutil.DryRunStrategy
+
+	parallelism      intstr.IntOrString
+	nodeRequirements []corev1.NodeSelectorRequirement
+	bundle           *internalcmdutil.Bundle
+
+	Builder      func() *resource.Builder
+	Client       kubernetes.Interface
+	KruiseClient kruiseclientsets.Interface
+
+	genericclioptions.IOStreams
+}
+
+// NewPullImageOptions returns an initialized PullImageOptions instance
+func NewPullImageOptions(streams genericclioptions.IOStreams) *PullImageOptions {
+	return &PullImageOptions{
+		PrintFlags:   genericclioptions.NewPrintFlags("created").WithTypeSetter(internalapi.GetScheme()),
+		BackoffLimit: -1,
+		Timeout:      10 * time.Minute,
+		IOStreams:    streams,
+	}
+}
+
+// NewCmdPullImage returns a Command instance for 'pull-image' command
+func NewCmdPullImage(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewPullImageOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "pull-image --from-workload TYPE/NAME [--all-containers] [--node-selector=SELECTOR] [--wait]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Pre-pull the images of a workload on the nodes with ImagePullJobs"),
+		Long:                  pullImageLong,
+		Example:               pullImageExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+
+	cmd.Flags().StringVar(&o.FromWorkload, "from-workload", o.FromWorkload, "The workload whose images are pulled, e.g. cloneset/demo.")
+	cmd.Flags().BoolVar(&o.AllContainers, "all-containers", o.AllContainers, "If true, also pull the images of the init containers and of the sidecar containers injected by SidecarSets.")
+	cmd.Flags().StringSliceVar(&o.PullSecrets, "pull-secret", o.PullSecrets, "The secrets in the namespace of the workload to pull the images with. Defaults to the imagePullSecrets of the workload and of its service account.")
+	cmd.Flags().StringVar(&o.NodeSelector, "node-selector", o.NodeSelector, "Pull the images on the nodes matching this label selector, instead of the nodes running the pods of the workload.")
+	internalcmdutil.AddNodeSelectorExprFlagVar(cmd, &o.NodeExprs)
+	cmd.Flags().StringVar(&o.Parallelism, "parallelism", o.Parallelism, "The number or percentage of nodes pulling an image at the same time. Defaults to 1.")
+	cmd.Flags().Int32Var(&o.BackoffLimit, "backoff-limit", o.BackoffLimit, "The number of retries of a node before it fails to pull an image, -1 for the default of Kruise (3).")
+	cmd.Flags().DurationVar(&o.PullTimeout, "pull-timeout", o.PullTimeout, "The time a node may take to pull an image, e.g. 5m, 0 for the default of Kruise (10m).")
+	cmd.Flags().DurationVar(&o.ActiveDeadline, "active-deadline", o.ActiveDeadline, "The time after which the jobs stop pulling the images, e.g. 1h, 0 for no deadline.")
+	cmd.Flags().BoolVar(&o.Wait, "wait", o.Wait, "If true, wait for the jobs to complete.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "With --wait, the time to wait for the jobs to complete.")
+	internalcmdutil.AddBundleOutFlagVar(cmd, &o.BundleOut)
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *PullImageOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmdutil.UsageErrorf(cmd, "unexpected arguments %v, the workload is given with --from-workload", args)
+	}
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Builder = f.NewBuilder
+	if len(o.Parallelism) > 0 {
+		o.parallelism = intstr.Parse(o.Parallelism)
+	}
+	if o.nodeRequirements, err = internalcmdutil.ParseNodeSelectorExprs(o.NodeExprs); err != nil {
+		return err
+	}
+	if len(o.BundleOut) > 0 {
+		if o.bundle, err = internalcmdutil.NewBundle(o.BundleOut, o.FromWorkload); err != nil {
+			return err
+		}
+	}
+
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	o.Client, err = f.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	clientConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig)
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *PullImageOptions) Validate() error {
+	if len(o.FromWorkload) == 0 {
+		return fmt.Errorf("the workload must be given with --from-workload")
+	}
+	if len(o.NodeSelector) > 0 {
+		if _, err := labels.Parse(o.NodeSelector); err != nil {
+			return fmt.Errorf("invalid --node-selector %q: %v", o.NodeSelector, err)
+		}
+	}
+	if err := internalcmdutil.NodeSelectorRequirementsAsLabelSelector(&metav1.LabelSelector{}, o.nodeRequirements); err != nil {
+		return err
+	}
+	if len(o.Parallelism) > 0 {
+		if value, err := intstr.GetScaledValueFromIntOrPercent(&o.parallelism, 100, true); err != nil || value <= 0 {
+			return fmt.Errorf("invalid --parallelism %q, must be a positive number or percentage", o.Parallelism)
+		}
+	}
+	if o.BackoffLimit < -1 {
+		return fmt.Errorf("--backoff-limit must not be negative, or -1 for the default")
+	}
+	if o.PullTimeout < 0 || (o.PullTimeout > 0 && o.PullTimeout < time.Second) {
+		return fmt.Errorf("--pull-timeout must be at least 1s")
+	}
+	if o.ActiveDeadline < 0 || (o.ActiveDeadline > 0 && o.ActiveDeadline < time.Second) {
+		return fmt.Errorf("--active-deadline must be at least 1s")
+	}
+	if o.Timeout <= 0 {
+		return fmt.Errorf("--timeout must be positive")
+	}
+	if o.DryRunStrategy == cmdutil.DryRunServer {
+		return fmt.Errorf("--dry-run=server is not supported")
+	}
+	if o.Wait && o.DryRunStrategy != cmdutil.DryRunNone {
+		return fmt.Errorf("--wait can not be used with --dry-run")
+	}
+	return nil
+}
+
+// Run performs the execution of 'pull-image' command
+func (o *PullImageOptions) Run() error {
+	obj, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		ResourceNames("", o.FromWorkload).
+		SingleResourceType().
+		Latest().
+		Do().
+		Object()
+	if err != nil {
+		return err
+	}
+
+	template, err := podTemplateForObject(obj)
+	if err != nil {
+		return err
+	}
+	images, err := o.imagesForObject(obj, template)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return fmt.Errorf("no images found in %s", o.FromWorkload)
+	}
+	pullSecrets, err := o.pullSecretsForTemplate(obj, template)
+	if err != nil {
+		return err
+	}
+	jobs, err := o.newImagePullJobs(obj, template, images, pullSecrets)
+	if err != nil {
+		return err
+	}
+
+	if o.bundle != nil {
+		for _, job := range jobs {
+			o.bundle.Label(job)
+		}
+	}
+
+	if o.DryRunStrategy != cmdutil.DryRunNone {
+		for _, job := range jobs {
+			if err := o.PrintObj(job, o.Out); err != nil {
+				return err
+			}
+			if o.bundle != nil {
+				o.bundle.Add(job)
+			}
+		}
+		return o.writeBundle()
+	}
+
+	var created []*kruiseappsv1alpha1.ImagePullJob
+	for _, job := range jobs {
+		result, err := o.createOrKeep(job)
+		if err != nil {
+			return err
+		}
+		// jobs kept from outside of the bundle are not deleted with it
+		if o.bundle != nil && result.Labels[internalcmdutil.BundleLabel] == o.bundle.Name() {
+			o.bundle.Add(result)
+		}
+		created = append(created, result)
+	}
+	if err := o.writeBundle(); err != nil {
+		return err
+	}
+	if !o.Wait {
+		return o.printReport(created)
+	}
+
+	reported := map[string]bool{}
+	err = wait.PollImmediate(2*time.Second, o.Timeout, func() (bool, error) {
+		done := true
+		for i, job := range created {
+			if job.Status.CompletionTime != nil {
+				continue
+			}
+			latest, err := o.KruiseClient.AppsV1alpha1().ImagePullJobs(job.Namespace).Get(context.TODO(), job.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			created[i] = latest
+			done = done && latest.Status.CompletionTime != nil
+			if err := o.printNewFailures(latest, reported); err != nil {
+				return false, err
+			}
+		}
+		return done, nil
+	})
+	if reportErr := o.printReport(created); reportErr != nil {
+		return reportErr
+	}
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out after %v waiting for the images to be pulled", o.Timeout)
+	} else if err != nil {
+		return err
+	}
+	if failed := failedNodes(created); failed > 0 {
+		return fmt.Errorf("%d node(s) failed to pull the images of %s", failed, o.FromWorkload)
+	}
+	return nil
+}
+
+// imagesForObject returns the images of the workload, with the sidecar containers injected in
+// its pods with --all-containers.
+func (o *PullImageOptions) imagesForObject(obj runtime.Object, template *corev1.PodTemplateSpec) ([]Image, error) {
+	if !o.AllContainers {
+		return WorkloadImages(template, nil, nil, false), nil
+	}
+
+	pods, err := polymorphichelpers.PodsForObject(o.Client.CoreV1(), obj)
+	if err != nil {
+		return nil, err
+	}
+	sidecarSets, err := o.KruiseClient.AppsV1alpha1().SidecarSets().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the SidecarSets: %v", err)
+	}
+	namespace, _, err := polymorphichelpers.SelectorsForObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	var matched []kruiseappsv1alpha1.SidecarSet
+	for _, sidecarSet := range sidecarSets.Items {
+		if len(sidecarSet.Spec.Namespace) > 0 && sidecarSet.Spec.Namespace != namespace {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(sidecarSet.Spec.Selector)
+		if err != nil || !selector.Matches(labels.Set(template.Labels)) {
+			continue
+		}
+		matched = append(matched, sidecarSet)
+	}
+	return WorkloadImages(template, matched, pods, true), nil
+}
+
+// WorkloadImages returns the images of the containers of template, in order. With allContainers,
+// the images of its init containers, of the containers of the SidecarSets matching it, and of the
+// other containers of its pods, injected by SidecarSets, follow. An image is only returned once.
+func WorkloadImages(template *corev1.PodTemplateSpec, sidecarSets []kruiseappsv1alpha1.SidecarSet, pods []corev1.Pod, allContainers bool) []Image {
+	var images []Image
+	seenImages := map[string]bool{}
+	seenContainers := map[string]bool{}
+	add := func(containers ...corev1.Container) {
+		for _, c := range containers {
+			if seenContainers[c.Name] {
+				continue
+			}
+			seenContainers[c.Name] = true
+			if len(c.Image) > 0 && !seenImages[c.Image] {
+				seenImages[c.Image] = true
+				images = append(images, Image{Image: c.Image, Container: c.Name})
+			}
+		}
+	}
+
+	add(template.Spec.Containers...)
+	if !allContainers {
+		return images
+	}
+	add(template.Spec.InitContainers...)
+	for _, sidecarSet := range sidecarSets {
+		for _, c := range sidecarSet.Spec.InitContainers {
+			add(c.Container)
+		}
+		for _, c := range sidecarSet.Spec.Containers {
+			add(c.Container)
+		}
+	}
+	for _, pod := range pods {
+		add(pod.Spec.InitContainers...)
+		add(pod.Spec.Containers...)
+	}
+	return images
+}
+
+// newImagePullJobs returns an ImagePullJob per image of the workload, all with the same selector.
+func (o *PullImageOptions) newImagePullJobs(obj runtime.Object, template *corev1.PodTemplateSpec, images []Image, pullSecrets []string) ([]*kruiseappsv1alpha1.ImagePullJob, error) {
+	namespace, selector, err := polymorphichelpers.SelectorsForObject(obj)
+	if err != nil {
+		return nil, fmt.Errorf("cannot select the pods of %T: %v", obj, err)
+	}
+	name := o.FromWorkload[strings.LastIndex(o.FromWorkload, "/")+1:]
+
+	spec := kruiseappsv1alpha1.ImagePullJobSpec{
+		PullSecrets:      pullSecrets,
+		CompletionPolicy: kruiseappsv1alpha1.CompletionPolicy{Type: kruiseappsv1alpha1.Always},
+	}
+	if len(o.NodeSelector) > 0 || len(o.nodeRequirements) > 0 {
+		nodeSelector, err := metav1.ParseToLabelSelector(o.NodeSelector)
+		if err != nil {
+			return nil, err
+		}
+		if err := internalcmdutil.NodeSelectorRequirementsAsLabelSelector(nodeSelector, o.nodeRequirements); err != nil {
+			return nil, err
+		}
+		spec.Selector = &kruiseappsv1alpha1.ImagePullJobNodeSelector{LabelSelector: *nodeSelector}
+	} else {
+		podSelector, err := metav1.ParseToLabelSelector(selector.String())
+		if err != nil {
+			return nil, err
+		}
+		spec.PodSelector = &kruiseappsv1alpha1.ImagePullJobPodSelector{LabelSelector: *podSelector}
+	}
+	if len(o.Parallelism) > 0 {
+		parallelism := o.parallelism
+		spec.Parallelism = &parallelism
+	}
+	if o.BackoffLimit >= 0 || o.PullTimeout > 0 {
+		spec.PullPolicy = &kruiseappsv1alpha1.PullPolicy{}
+		if o.BackoffLimit >= 0 {
+			backoffLimit := o.BackoffLimit
+			spec.PullPolicy.BackoffLimit = &backoffLimit
+		}
+		if o.PullTimeout > 0 {
+			timeoutSeconds := int32(o.PullTimeout.Seconds())
+			spec.PullPolicy.TimeoutSeconds = &timeoutSeconds
+		}
+	}
+	if o.ActiveDeadline > 0 {
+		activeDeadlineSeconds := int64(o.ActiveDeadline.Seconds())
+		spec.CompletionPolicy.ActiveDeadlineSeconds = &activeDeadlineSeconds
+	}
+
+	var jobs []*kruiseappsv1alpha1.ImagePullJob
+	for _, image := range images {
+		job := &kruiseappsv1alpha1.ImagePullJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      jobName(name, image.Container),
+			},
+			Spec: *spec.DeepCopy(),
+		}
+		job.Spec.Image = image.Image
+		if len(validation.IsValidLabelValue(name)) == 0 {
+			job.Labels = map[string]string{WorkloadLabel: name}
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// pullSecretsForTemplate returns the secrets of --pull-secret, or else the imagePullSecrets of
+// template and of its service account. The secrets must exist in the namespace of the workload,
+// where the ImagePullJobs read them.
+func (o *PullImageOptions) pullSecretsForTemplate(obj runtime.Object, template *corev1.PodTemplateSpec) ([]string, error) {
+	namespace, _, err := polymorphichelpers.SelectorsForObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.PullSecrets) > 0 {
+		for _, name := range o.PullSecrets {
+			if _, err := o.Client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{}); err != nil {
+				return nil, fmt.Errorf("invalid --pull-secret %s: %v", name, err)
+			}
+		}
+		return o.PullSecrets, nil
+	}
+
+	references := template.Spec.ImagePullSecrets
+	serviceAccountName := template.Spec.ServiceAccountName
+	if len(serviceAccountName) == 0 {
+		serviceAccountName = "default"
+	}
+	serviceAccount, err := o.Client.CoreV1().ServiceAccounts(namespace).Get(context.TODO(), serviceAccountName, metav1.GetOptions{})
+	if err == nil {
+		references = append(references, serviceAccount.ImagePullSecrets...)
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	var secrets []string
+	seen := map[string]bool{}
+	for _, reference := range references {
+		if seen[reference.Name] {
+			continue
+		}
+		seen[reference.Name] = true
+		if _, err := o.Client.CoreV1().Secrets(namespace).Get(context.TODO(), reference.Name, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+			fmt.Fprintf(o.ErrOut, "Warning: image pull secret %s of %s not found, skipping it\n", reference.Name, o.FromWorkload)
+			continue
+		}
+		secrets = append(secrets, reference.Name)
+	}
+	return secrets, nil
+}
+
+// podTemplateForObject returns the pod template of a workload.
+func podTemplateForObject(obj runtime.Object) (*corev1.PodTemplateSpec, error) {
+	var template *corev1.PodTemplateSpec
+	ok, err := polymorphichelpers.UpdatePodTemplateForObjectFn(obj, func(t *corev1.PodTemplateSpec) error {
+		template = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok || template == nil {
+		return nil, fmt.Errorf("%T has no pod template to pull the images of", obj)
+	}
+	return template, nil
+}
+
+// jobName returns the name of the ImagePullJob of container of the workload name.
+func jobName(name, container string) string {
+	jobName := fmt.Sprintf("%s-%s", name, container)
+	if len(jobName) > validation.DNS1123SubdomainMaxLength {
+		jobName = jobName[:validation.DNS1123SubdomainMaxLength]
+	}
+	return strings.TrimRight(jobName, "-.")
+}
+
+// writeBundle writes the jobs of --bundle-out, if any.
+func (o *PullImageOptions) writeBundle() error {
+	if o.bundle == nil {
+		return nil
+	}
+	if err := o.bundle.Write(); err != nil {
+		return fmt.Errorf("failed to write --bundle-out: %v", err)
+	}
+	// not to Out, which holds the manifests of a dry run
+	fmt.Fprintf(o.ErrOut, "bundle %s written to %s\n", o.bundle.Name(), o.BundleOut)
+	return nil
+}
+
+// createOrKeep creates job, or returns the existing job of the same name if it pulls the same image.
+func (o *PullImageOptions) createOrKeep(job *kruiseappsv1alpha1.ImagePullJob) (*kruiseappsv1alpha1.ImagePullJob, error) {
+	jobs := o.KruiseClient.AppsV1alpha1().ImagePullJobs(job.Namespace)
+	created, err := jobs.Create(context.TODO(), job, metav1.CreateOptions{})
+	if err == nil {
+		fmt.Fprintf(o.Out, "imagepulljob/%s created for %s\n", created.Name, created.Spec.Image)
+		return created, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create ImagePullJob %s: %v", job.Name, err)
+	}
+	existing, err := jobs.Get(context.TODO(), job.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if existing.Spec.Image != job.Spec.Image {
+		return nil, fmt.Errorf("imagepulljob/%s already exists for image %s, delete it to pull %s", job.Name, existing.Spec.Image, job.Spec.Image)
+	}
+	fmt.Fprintf(o.Out, "imagepulljob/%s unchanged for %s\n", existing.Name, existing.Spec.Image)
+	return existing, nil
+}
+
+// jobPhase returns the phase of job in the report.
+func jobPhase(job *kruiseappsv1alpha1.ImagePullJob) string {
+	switch {
+	case job.Status.CompletionTime == nil:
+		return "Running"
+	case job.Status.Failed > 0:
+		return "Failed"
+	}
+	return "Completed"
+}
+
+func failedNodes(jobs []*kruiseappsv1alpha1.ImagePullJob) int32 {
+	var failed int32
+	for _, job := range jobs {
+		failed += job.Status.Failed
+	}
+	return failed
+}
+
+// NodeFailure is a node that failed to pull the image of an ImagePullJob.
+type NodeFailure struct {
+	Node    string
+	Image   string
+	Reason  string
+	Message string
+}
+
+// nodeFailures returns the failures of the failed nodes of job, with the messages of their
+// NodeImages and their reasons. The nodes of skip are skipped.
+func (o *PullImageOptions) nodeFailures(job *kruiseappsv1alpha1.ImagePullJob, skip map[string]bool) ([]NodeFailure, error) {
+	var failures []NodeFailure
+	for _, name := range job.Status.FailedNodes {
+		if skip[job.Name+"/"+name] {
+			continue
+		}
+		failure := NodeFailure{Node: name, Image: job.Spec.Image}
+		nodeImage, err := o.KruiseClient.AppsV1alpha1().NodeImages().Get(context.TODO(), name, metav1.GetOptions{})
+		if err == nil {
+			failure.Message = PullMessage(nodeImage, job.Spec.Image)
+		} else if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		var node *corev1.Node
+		if o.Client != nil {
+			if node, err = o.Client.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+				node = nil
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		failure.Reason = FailureReason(node, failure.Message)
+		failures = append(failures, failure)
+	}
+	return failures, nil
+}
+
+// printNewFailures prints the failed nodes of job not reported yet, and adds them to reported.
+func (o *PullImageOptions) printNewFailures(job *kruiseappsv1alpha1.ImagePullJob, reported map[string]bool) error {
+	failures, err := o.nodeFailures(job, reported)
+	if err != nil {
+		return err
+	}
+	for _, failure := range failures {
+		reported[job.Name+"/"+failure.Node] = true
+		fmt.Fprintf(o.Out, "imagepulljob/%s: node %s failed to pull %s: %s\n", job.Name, failure.Node, failure.Image, failureText(failure))
+	}
+	return nil
+}
+
+func failureText(failure NodeFailure) string {
+	if len(failure.Message) == 0 {
+		return failure.Reason
+	}
+	return fmt.Sprintf("%s (%s)", failure.Reason, failure.Message)
+}
+
+// PullMessage returns the message of the pull of image in nodeImage, empty if it has none.
+func PullMessage(nodeImage *kruiseappsv1alpha1.NodeImage, image string) string {
+	name, tag := internalcmdutil.SplitImageTag(image)
+	for _, t := range nodeImage.Status.ImageStatuses[name].Tags {
+		if t.Tag == tag {
+			return t.Message
+		}
+	}
+	return ""
+}
+
+// FailureReason returns the reason of a node failing to pull an image with message: disk pressure
+// if the node is under disk pressure, or else the reason of the first keywords of message.
+func FailureReason(node *corev1.Node, message string) string {
+	if node != nil {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeDiskPressure && condition.Status == corev1.ConditionTrue {
+				return ReasonDiskPressure
+			}
+		}
+	}
+	lower := strings.ToLower(message)
+	for _, k := range failureKeywords {
+		for _, keyword := range k.keywords {
+			if strings.Contains(lower, keyword) {
+				return k.reason
+			}
+		}
+	}
+	return ReasonUnknown
+}
+
+func (o *PullImageOptions) printReport(jobs []*kruiseappsv1alpha1.ImagePullJob) error {
+	var failures []NodeFailure
+	if o.KruiseClient != nil {
+		for _, job := range jobs {
+			jobFailures, err := o.nodeFailures(job, nil)
+			if err != nil {
+				return err
+			}
+			failures = append(failures, jobFailures...)
+		}
+	}
+
+	w := internalcmdutil.NewTableWriter(o.Out)
+	fmt.Fprintln(w, "\nJOB\tIMAGE\tPHASE\tDESIRED\tSUCCEEDED\tFAILED\tMESSAGE")
+	completed := 0
+	var desired, succeeded int32
+	for _, job := range jobs {
+		phase := jobPhase(job)
+		if phase == "Completed" {
+			completed++
+		}
+		desired += job.Status.Desired
+		succeeded += job.Status.Succeeded
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%s\n", job.Name, job.Spec.Image, phase, job.Status.Desired, job.Status.Succeeded, job.Status.Failed, job.Status.Message)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "\n%d of %d images pulled, %d of %d node pulls succeeded, %d failed%s\n", completed, len(jobs), succeeded, desired, failedNodes(jobs), failureCounts(failures))
+	if len(failures) == 0 {
+		return nil
+	}
+
+	w = internalcmdutil.NewTableWriter(o.Out)
+	fmt.Fprintln(w, "\nFAILED NODE\tIMAGE\tREASON\tMESSAGE")
+	for _, failure := range failures {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", failure.Node, failure.Image, failure.Reason, failure.Message)
+	}
+	return w.Flush()
+}
+
+// failureCounts returns the numbers of failures by reason, e.g. " (2 auth error, 1 disk pressure)".
+func failureCounts(failures []NodeFailure) string {
+	if len(failures) == 0 {
+		return ""
+	}
+	counts := map[string]int{}
+	var reasons []string
+	for _, failure := range failures {
+		if counts[failure.Reason] == 0 {
+			reasons = append(reasons, failure.Reason)
+		}
+		counts[failure.Reason]++
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] != counts[reasons[j]] {
+			return counts[reasons[i]] > counts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		parts[i] = fmt.Sprintf("%d %s", counts[reason], reason)
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullimage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newCloneSet() *kruiseappsv1alpha1.CloneSet {
+	return &kruiseappsv1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+		Spec: kruiseappsv1alpha1.CloneSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "demo"}},
+				Spec: corev1.PodSpec{
+					InitContainers:   []corev1.Container{{Name: "init", Image: "busybox:1.35"}},
+					Containers:       []corev1.Container{{Name: "app", Image: "demo:v2"}, {Name: "proxy", Image: "envoy:1.22"}},
+					ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+				},
+			},
+		},
+	}
+}
+
+func TestWorkloadImages(t *testing.T) {
+	cs := newCloneSet()
+	sidecarSets := []kruiseappsv1alpha1.SidecarSet{{
+		Spec: kruiseappsv1alpha1.SidecarSetSpec{
+			Containers: []kruiseappsv1alpha1.SidecarContainer{{Container: corev1.Container{Name: "log", Image: "fluentbit:2"}}},
+		},
+	}}
+	pods := []corev1.Pod{{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "demo:v1"},
+			{Name: "log", Image: "fluentbit:1"},
+			{Name: "agent", Image: "agent:3"},
+			{Name: "agent-copy", Image: "envoy:1.22"},
+		}},
+	}}
+
+	images := WorkloadImages(&cs.Spec.Template, sidecarSets, pods, false)
+	expected := []Image{{Image: "demo:v2", Container: "app"}, {Image: "envoy:1.22", Container: "proxy"}}
+	if !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected the images %v, got %v", expected, images)
+	}
+
+	images = WorkloadImages(&cs.Spec.Template, sidecarSets, pods, true)
+	expected = append(expected, Image{Image: "busybox:1.35", Container: "init"}, Image{Image: "fluentbit:2", Container: "log"}, Image{Image: "agent:3", Container: "agent"})
+	if !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected all the images %v, got %v", expected, images)
+	}
+}
+
+func TestNewImagePullJobs(t *testing.T) {
+	cs := newCloneSet()
+	o := NewPullImageOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.FromWorkload = "cloneset/demo"
+	o.Parallelism = "10%"
+	o.parallelism = intstr.Parse(o.Parallelism)
+
+	jobs, err := o.newImagePullJobs(cs, &cs.Spec.Template, WorkloadImages(&cs.Spec.Template, nil, nil, false), []string{"registry"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].Name != "demo-app" || jobs[1].Name != "demo-proxy" || jobs[1].Spec.Image != "envoy:1.22" {
+		t.Fatalf("unexpected jobs %+v", jobs)
+	}
+	for _, job := range jobs {
+		if job.Namespace != "default" || job.Labels[WorkloadLabel] != "demo" {
+			t.Errorf("unexpected metadata %+v", job.ObjectMeta)
+		}
+		if job.Spec.PodSelector == nil || !reflect.DeepEqual(job.Spec.PodSelector.MatchLabels, map[string]string{"app": "demo"}) || job.Spec.Selector != nil {
+			t.Errorf("expected the pod selector of the cloneset, got %+v", job.Spec)
+		}
+		if !reflect.DeepEqual(job.Spec.PullSecrets, []string{"registry"}) || job.Spec.Parallelism.String() != "10%" {
+			t.Errorf("unexpected spec %+v", job.Spec)
+		}
+	}
+
+	o.NodeSelector = "pool=web"
+	jobs, err = o.newImagePullJobs(cs, &cs.Spec.Template, WorkloadImages(&cs.Spec.Template, nil, nil, false), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if jobs[0].Spec.PodSelector != nil || jobs[0].Spec.Selector == nil || jobs[0].Spec.Selector.MatchLabels["pool"] != "web" {
+		t.Errorf("expected the node selector pool=web, got %+v", jobs[0].Spec)
+	}
+
+	o.NodeSelector = ""
+	o.nodeRequirements = []corev1.NodeSelectorRequirement{{Key: "spot", Operator: corev1.NodeSelectorOpDoesNotExist}}
+	jobs, err = o.newImagePullJobs(cs, &cs.Spec.Template, WorkloadImages(&cs.Spec.Template, nil, nil, false), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expressions := []metav1.LabelSelectorRequirement{{Key: "spot", Operator: metav1.LabelSelectorOpDoesNotExist}}
+	if jobs[0].Spec.PodSelector != nil || jobs[0].Spec.Selector == nil || !reflect.DeepEqual(jobs[0].Spec.Selector.MatchExpressions, expressions) {
+		t.Errorf("expected the node selector of --node-selector-expr, got %+v", jobs[0].Spec)
+	}
+	if jobs[0].Spec.PullPolicy != nil || jobs[0].Spec.CompletionPolicy.ActiveDeadlineSeconds != nil {
+		t.Errorf("expected the defaults of Kruise, got %+v", jobs[0].Spec)
+	}
+
+	o.BackoffLimit = 0
+	o.PullTimeout = 5 * time.Minute
+	o.ActiveDeadline = time.Hour
+	jobs, err = o.newImagePullJobs(cs, &cs.Spec.Template, WorkloadImages(&cs.Spec.Template, nil, nil, false), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policy := jobs[0].Spec.PullPolicy
+	if policy == nil || policy.BackoffLimit == nil || *policy.BackoffLimit != 0 || policy.TimeoutSeconds == nil || *policy.TimeoutSeconds != 300 {
+		t.Errorf("expected a backoff limit of 0 and a timeout of 300s, got %+v", policy)
+	}
+	if deadline := jobs[0].Spec.CompletionPolicy.ActiveDeadlineSeconds; deadline == nil || *deadline != 3600 {
+		t.Errorf("expected an active deadline of 3600s, got %v", deadline)
+	}
+}
+
+func TestFailureReason(t *testing.T) {
+	pressure := &corev1.Node{Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue}}}}
+	cases := []struct {
+		node     *corev1.Node
+		message  string
+		expected string
+	}{
+		{pressure, "context canceled", ReasonDiskPressure},
+		{nil, "write /var/lib/containerd/tmp: no space left on device", ReasonDiskPressure},
+		{nil, "failed to authorize: 401 Unauthorized", ReasonAuth},
+		{nil, "pull access denied, repository does not exist", ReasonAuth},
+		{nil, "docker.io/library/demo:v9: not found", ReasonNotFound},
+		{nil, "context deadline exceeded", ReasonTimeout},
+		{&corev1.Node{}, "", ReasonUnknown},
+	}
+	for _, c := range cases {
+		if reason := FailureReason(c.node, c.message); reason != c.expected {
+			t.Errorf("expected %q for %q, got %q", c.expected, c.message, reason)
+		}
+	}
+}
+
+func TestValidatePullPolicy(t *testing.T) {
+	cases := []struct {
+		modify   func(o *PullImageOptions)
+		expected string
+	}{
+		{func(o *PullImageOptions) { o.BackoffLimit = -2 }, "--backoff-limit must not be negative"},
+		{func(o *PullImageOptions) { o.PullTimeout = time.Millisecond }, "--pull-timeout must be at least 1s"},
+		{func(o *PullImageOptions) { o.ActiveDeadline = -time.Minute }, "--active-deadline must be at least 1s"},
+	}
+	for _, c := range cases {
+		o := NewPullImageOptions(genericclioptions.NewTestIOStreamsDiscard())
+		o.FromWorkload = "cloneset/demo"
+		c.modify(o)
+		if err := o.Validate(); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("expected error %q, got %v", c.expected, err)
+		}
+	}
+}
+
+func TestPullSecretsForTemplate(t *testing.T) {
+	cs := newCloneSet()
+	cs.Spec.Template.Spec.ImagePullSecrets = append(cs.Spec.Template.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: "missing"})
+	newSecret := func(name string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+	}
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta:       metav1.ObjectMeta{Namespace: "default", Name: "default"},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}, {Name: "mirror"}},
+	}
+
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	o := NewPullImageOptions(streams)
+	o.FromWorkload = "cloneset/demo"
+	o.Client = fake.NewSimpleClientset(newSecret("registry"), newSecret("mirror"), newSecret("private"), serviceAccount)
+
+	secrets, err := o.pullSecretsForTemplate(cs, &cs.Spec.Template)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(secrets, []string{"registry", "mirror"}) {
+		t.Errorf("expected the secrets of the workload and of its service account, got %v", secrets)
+	}
+	if !strings.Contains(errOut.String(), "image pull secret missing of cloneset/demo not found") {
+		t.Errorf("expected a warning for the missing secret, got %q", errOut.String())
+	}
+
+	o.PullSecrets = []string{"private"}
+	if secrets, err = o.pullSecretsForTemplate(cs, &cs.Spec.Template); err != nil || !reflect.DeepEqual(secrets, []string{"private"}) {
+		t.Errorf("expected the secrets of --pull-secret, got %v, %v", secrets, err)
+	}
+	o.PullSecrets = []string{"missing"}
+	if _, err = o.pullSecretsForTemplate(cs, &cs.Spec.Template); err == nil {
+		t.Errorf("expected an error for a missing --pull-secret")
+	}
+}
+
+func TestCreateOrKeep(t *testing.T) {
+	existing := &kruiseappsv1alpha1.ImagePullJob{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo-app"},
+		Spec:       kruiseappsv1alpha1.ImagePullJobSpec{Image: "demo:v2"},
+	}
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewPullImageOptions(streams)
+	o.Client = fake.NewSimpleClientset()
+	o.KruiseClient = kruisefake.NewSimpleClientset(existing)
+
+	if _, err := o.createOrKeep(existing.DeepCopy()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	other := existing.DeepCopy()
+	other.Spec.Image = "demo:v3"
+	if _, err := o.createOrKeep(other); err == nil || !strings.Contains(err.Error(), "already exists for image demo:v2") {
+		t.Errorf("expected an error for another image, got %v", err)
+	}
+	created := existing.DeepCopy()
+	created.Name = "demo-proxy"
+	if _, err := o.createOrKeep(created); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "imagepulljob/demo-app unchanged for demo:v2\nimagepulljob/demo-proxy created for demo:v2\n"
+	if out.String() != expected {
+		t.Errorf("expected the output %q, got %q", expected, out.String())
+	}
+}
+
+func TestPrintReport(t *testing.T) {
+	now := metav1.Now()
+	jobs := []*kruiseappsv1alpha1.ImagePullJob{
+		{ObjectMeta: metav1.ObjectMeta{Name: "demo-app"}, Spec: kruiseappsv1alpha1.ImagePullJobSpec{Image: "demo:v2"}, Status: kruiseappsv1alpha1.ImagePullJobStatus{CompletionTime: &now, Desired: 3, Succeeded: 3}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "demo-log"}, Spec: kruiseappsv1alpha1.ImagePullJobSpec{Image: "fluentbit:2"}, Status: kruiseappsv1alpha1.ImagePullJobStatus{CompletionTime: &now, Desired: 3, Succeeded: 2, Failed: 1}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "demo-proxy"}, Spec: kruiseappsv1alpha1.ImagePullJobSpec{Image: "envoy:1.22"}, Status: kruiseappsv1alpha1.ImagePullJobStatus{Desired: 3, Succeeded: 1, Active: 2}},
+	}
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewPullImageOptions(streams)
+	if err := o.printReport(jobs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"demo-log     fluentbit:2   Failed", "demo-proxy   envoy:1.22    Running", "1 of 3 images pulled, 6 of 9 node pulls succeeded, 1 failed"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in the report, got\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestPrintReportFailures(t *testing.T) {
+	now := metav1.Now()
+	jobs := []*kruiseappsv1alpha1.ImagePullJob{
+		{ObjectMeta: metav1.ObjectMeta{Name: "demo-app"}, Spec: kruiseappsv1alpha1.ImagePullJobSpec{Image: "registry.example.com/demo:v2"},
+			Status: kruiseappsv1alpha1.ImagePullJobStatus{CompletionTime: &now, Desired: 3, Succeeded: 1, Failed: 2, FailedNodes: []string{"node-a", "node-b"}}},
+	}
+	nodeImage := &kruiseappsv1alpha1.NodeImage{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status: kruiseappsv1alpha1.NodeImageStatus{ImageStatuses: map[string]kruiseappsv1alpha1.ImageStatus{
+			"registry.example.com/demo": {Tags: []kruiseappsv1alpha1.ImageTagStatus{{Tag: "v2", Phase: kruiseappsv1alpha1.ImagePhaseFailed, Message: "401 Unauthorized"}}},
+		}},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-b"},
+		Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue}}},
+	}
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewPullImageOptions(streams)
+	o.Client = fake.NewSimpleClientset(node)
+	o.KruiseClient = kruisefake.NewSimpleClientset(nodeImage)
+	if err := o.printReport(jobs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"0 of 1 images pulled, 1 of 3 node pulls succeeded, 2 failed (1 auth error, 1 disk pressure)",
+		"node-a        registry.example.com/demo:v2   auth error      401 Unauthorized",
+		"node-b        registry.example.com/demo:v2   disk pressure",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in the report, got\n%s", expected, out.String())
+		}
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o.IOStreams = streams
+	reported := map[string]bool{"demo-app/node-b": true}
+	if err := o.printNewFailures(jobs[0], reported); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "imagepulljob/demo-app: node node-a failed to pull registry.example.com/demo:v2: auth error (401 Unauthorized)\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	if !reported["demo-app/node-a"] {
+		t.Errorf("expected node-a to be reported")
+	}
+}
@@ -0,0 +1,409 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recreate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	recreateLong = templates.LongDesc(`
+		Recreate containers of a pod, or of all running pods of a workload, in place.
+
+		A ContainerRecreateRequest is created for every pod. Pods of a workload are handled in waves
+		of --parallel pods, waiting for each wave to complete and for --interval before the next
+		one starts. If containers fail to be recreated and the failure policy is Fail, no further
+		wave is started. A report of all requests is printed at the end.`)
+
+	recreateExample = templates.Examples(`
+		# Recreate the app container of pod demo-xyz
+		kubectl-kruise recreate pod/demo-xyz -c app
+
+		# Recreate the app container of all pods of cloneset demo, 10% of the pods at a time, 30s apart
+		kubectl-kruise recreate cloneset/demo -c app --parallel 10% --interval 30s
+
+		# Print the ContainerRecreateRequests that would be created, without creating them
+		kubectl-kruise recreate cloneset/demo -c app,sidecar --dry-run=client -o yaml`)
+)
+
+// RecreateOptions holds the command-line options for 'recreate' command
+type RecreateOptions struct {
+	PrintFlags *genericclioptions.PrintFlags
+	PrintObj   printers.ResourcePrinterFunc
+
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+
+	Containers         []string
+	Parallel           string
+	Interval           time.Duration
+	Timeout            time.Duration
+	FailurePolicy      string
+	UnreadyGracePeriod time.Duration
+	BundleOut          string
+	DryRunStrategy     cmdutil.DryRunStrategy
+
+	parallel intstr.IntOrString
+	bundle   *internalcmdutil.Bundle
+
+	Builder      func() *resource.Builder
+	Client       kubernetes.Interface
+	KruiseClient kruiseclientsets.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NewRecreateOptions returns an initialized RecreateOptions instance
+func NewRecreateOptions(streams genericclioptions.IOStreams) *RecreateOptions {
+	return &RecreateOptions{
+		PrintFlags:    genericclioptions.NewPrintFlags("created").WithTypeSetter(internalapi.GetScheme()),
+		Parallel:      "1",
+		Timeout:       10 * time.Minute,
+		FailurePolicy: string(kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyFail),
+		IOStreams:     streams,
+	}
+}
+
+// NewCmdRecreate returns a Command instance for 'recreate' command
+func NewCmdRecreate(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRecreateOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "recreate (TYPE/NAME | pod/NAME) -c CONTAINER[,CONTAINER] [--parallel=N|N%] [--interval=DURATION]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Recreate containers of a pod or of all pods of a workload in place"),
+		Long:                  recreateLong,
+		Example:               recreateExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringSliceVarP(&o.Containers, "containers", "c", o.Containers, "Names of the containers to recreate.")
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc("containers", internalcmdutil.ContainerCompletionFunc(f, "")))
+	cmd.Flags().StringVar(&o.Parallel, "parallel", o.Parallel, "The number or percentage of pods whose containers are recreated at the same time.")
+	cmd.Flags().DurationVar(&o.Interval, "interval", o.Interval, "The time to wait between two waves.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The time to wait for a wave to complete.")
+	cmd.Flags().StringVar(&o.FailurePolicy, "failure-policy", o.FailurePolicy, "Fail to stop recreating the containers of a pod, and any further wave, once one fails, or Ignore.")
+	cmd.Flags().DurationVar(&o.UnreadyGracePeriod, "unready-grace-period", o.UnreadyGracePeriod, "The time a pod is kept not-ready before its containers are recreated.")
+	internalcmdutil.AddBundleOutFlagVar(cmd, &o.BundleOut)
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *RecreateOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+	o.parallel = intstr.Parse(o.Parallel)
+	if len(o.BundleOut) > 0 {
+		owners := append([]string{}, o.Resources...)
+		owners = append(owners, o.Filenames...)
+		if o.bundle, err = internalcmdutil.NewBundle(o.BundleOut, strings.Join(owners, ",")); err != nil {
+			return err
+		}
+	}
+
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	o.Client, err = f.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	clientConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig)
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *RecreateOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if len(o.Containers) == 0 {
+		return fmt.Errorf("at least one container must be given with -c")
+	}
+	if value, err := intstr.GetScaledValueFromIntOrPercent(&o.parallel, 100, true); err != nil || value <= 0 {
+		return fmt.Errorf("invalid --parallel %q, must be a positive number or percentage", o.Parallel)
+	}
+	switch kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyType(o.FailurePolicy) {
+	case kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyFail, kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyIgnore:
+	default:
+		return fmt.Errorf("invalid --failure-policy %q, must be Fail or Ignore", o.FailurePolicy)
+	}
+	if o.DryRunStrategy == cmdutil.DryRunServer {
+		return fmt.Errorf("--dry-run=server is not supported")
+	}
+	return nil
+}
+
+// Run performs the execution of 'recreate' command
+func (o *RecreateOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return err
+	}
+
+	var pods []corev1.Pod
+	for _, info := range infos {
+		selected, err := o.runningPodsForObject(info.Object)
+		if err != nil {
+			return err
+		}
+		pods = append(pods, selected...)
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("no running pods found")
+	}
+
+	waveSize, _ := intstr.GetScaledValueFromIntOrPercent(&o.parallel, len(pods), true)
+	if waveSize < 1 {
+		waveSize = 1
+	}
+
+	var results []*kruiseappsv1alpha1.ContainerRecreateRequest
+	for start := 0; start < len(pods); start += waveSize {
+		end := start + waveSize
+		if end > len(pods) {
+			end = len(pods)
+		}
+		if start > 0 && o.Interval > 0 && o.DryRunStrategy == cmdutil.DryRunNone {
+			time.Sleep(o.Interval)
+		}
+
+		wave, err := o.runWave(pods[start:end])
+		results = append(results, wave...)
+		if err != nil {
+			o.printReport(results)
+			// the requests created before the failure are still written to the bundle
+			if bundleErr := o.writeBundle(); bundleErr != nil {
+				fmt.Fprintf(o.ErrOut, "error: %v\n", bundleErr)
+			}
+			return err
+		}
+	}
+
+	if err := o.writeBundle(); err != nil {
+		return err
+	}
+	if o.DryRunStrategy != cmdutil.DryRunNone {
+		return nil
+	}
+	return o.printReport(results)
+}
+
+// writeBundle writes the requests of --bundle-out, if any.
+func (o *RecreateOptions) writeBundle() error {
+	if o.bundle == nil {
+		return nil
+	}
+	if err := o.bundle.Write(); err != nil {
+		return fmt.Errorf("failed to write --bundle-out: %v", err)
+	}
+	// not to Out, which holds the manifests of a dry run
+	fmt.Fprintf(o.ErrOut, "bundle %s written to %s\n", o.bundle.Name(), o.BundleOut)
+	return nil
+}
+
+// runWave creates a ContainerRecreateRequest for each pod and waits for all of them to complete.
+func (o *RecreateOptions) runWave(pods []corev1.Pod) ([]*kruiseappsv1alpha1.ContainerRecreateRequest, error) {
+	var crrs []*kruiseappsv1alpha1.ContainerRecreateRequest
+	for i := range pods {
+		crr := o.newContainerRecreateRequest(&pods[i])
+		if o.bundle != nil {
+			o.bundle.Label(crr)
+		}
+		if o.DryRunStrategy != cmdutil.DryRunNone {
+			if err := o.PrintObj(crr, o.Out); err != nil {
+				return crrs, err
+			}
+			if o.bundle != nil {
+				o.bundle.Add(crr)
+			}
+			continue
+		}
+		created, err := o.KruiseClient.AppsV1alpha1().ContainerRecreateRequests(crr.Namespace).Create(context.TODO(), crr, metav1.CreateOptions{})
+		if err != nil {
+			return crrs, fmt.Errorf("failed to create ContainerRecreateRequest for pod %s: %v", pods[i].Name, err)
+		}
+		fmt.Fprintf(o.Out, "containerrecreaterequest/%s created for pod %s\n", created.Name, pods[i].Name)
+		if o.bundle != nil {
+			o.bundle.Add(created)
+		}
+		crrs = append(crrs, created)
+	}
+	if len(crrs) == 0 {
+		return crrs, nil
+	}
+
+	err := wait.PollImmediate(2*time.Second, o.Timeout, func() (bool, error) {
+		done := true
+		for i, crr := range crrs {
+			if crr.Status.Phase == kruiseappsv1alpha1.ContainerRecreateRequestCompleted {
+				continue
+			}
+			latest, err := o.KruiseClient.AppsV1alpha1().ContainerRecreateRequests(crr.Namespace).Get(context.TODO(), crr.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			crrs[i] = latest
+			if latest.Status.Phase != kruiseappsv1alpha1.ContainerRecreateRequestCompleted {
+				done = false
+			}
+		}
+		return done, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return crrs, fmt.Errorf("timed out after %v waiting for containers to be recreated", o.Timeout)
+	} else if err != nil {
+		return crrs, err
+	}
+
+	if o.FailurePolicy == string(kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyFail) {
+		for _, crr := range crrs {
+			if failed(crr) {
+				return crrs, fmt.Errorf("failed to recreate containers of pod %s, no further pods are recreated", crr.Spec.PodName)
+			}
+		}
+	}
+	return crrs, nil
+}
+
+func (o *RecreateOptions) newContainerRecreateRequest(pod *corev1.Pod) *kruiseappsv1alpha1.ContainerRecreateRequest {
+	crr := &kruiseappsv1alpha1.ContainerRecreateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: pod.Namespace,
+			Name:      fmt.Sprintf("%s-%s", pod.Name, utilrand.String(5)),
+		},
+		Spec: kruiseappsv1alpha1.ContainerRecreateRequestSpec{
+			PodName: pod.Name,
+			Strategy: &kruiseappsv1alpha1.ContainerRecreateRequestStrategy{
+				FailurePolicy: kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyType(o.FailurePolicy),
+			},
+		},
+	}
+	for _, name := range o.Containers {
+		crr.Spec.Containers = append(crr.Spec.Containers, kruiseappsv1alpha1.ContainerRecreateRequestContainer{Name: name})
+	}
+	if o.UnreadyGracePeriod > 0 {
+		seconds := int64(o.UnreadyGracePeriod.Seconds())
+		crr.Spec.Strategy.UnreadyGracePeriodSeconds = &seconds
+	}
+	return crr
+}
+
+// runningPodsForObject returns the running pods of a pod or a workload, sorted by name.
+func (o *RecreateOptions) runningPodsForObject(obj runtime.Object) ([]corev1.Pod, error) {
+	all, err := polymorphichelpers.PodsForObject(o.Client.CoreV1(), obj)
+	if err != nil {
+		return nil, err
+	}
+	var pods []corev1.Pod
+	for _, pod := range all {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+func (o *RecreateOptions) printReport(crrs []*kruiseappsv1alpha1.ContainerRecreateRequest) error {
+	w := internalcmdutil.NewTableWriter(o.Out)
+	succeeded := 0
+	fmt.Fprintln(w, "\nPOD\tREQUEST\tPHASE\tCONTAINERS\tMESSAGE")
+	for _, crr := range crrs {
+		var states []string
+		for _, state := range crr.Status.ContainerRecreateStates {
+			states = append(states, fmt.Sprintf("%s=%s", state.Name, state.Phase))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", crr.Spec.PodName, crr.Name, crr.Status.Phase, strings.Join(states, ","), crr.Status.Message)
+		if crr.Status.Phase == kruiseappsv1alpha1.ContainerRecreateRequestCompleted && !failed(crr) {
+			succeeded++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "\n%d of %d pods recreated their containers successfully\n", succeeded, len(crrs))
+	return nil
+}
+
+func failed(crr *kruiseappsv1alpha1.ContainerRecreateRequest) bool {
+	for _, state := range crr.Status.ContainerRecreateStates {
+		if state.Phase == kruiseappsv1alpha1.ContainerRecreateRequestFailed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recreate
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+func newPod(name string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": "demo"}},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestRunningPodsForObject(t *testing.T) {
+	o := NewRecreateOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.Client = fake.NewSimpleClientset(
+		newPod("demo-b", corev1.PodRunning),
+		newPod("demo-a", corev1.PodRunning),
+		newPod("demo-c", corev1.PodPending),
+	)
+	cs := &kruiseappsv1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+		Spec: kruiseappsv1alpha1.CloneSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+		},
+	}
+
+	pods, err := o.runningPodsForObject(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 2 || pods[0].Name != "demo-a" || pods[1].Name != "demo-b" {
+		t.Errorf("expected running pods demo-a and demo-b, got %v", pods)
+	}
+
+	if _, err := o.runningPodsForObject(&kruiseappsv1alpha1.CloneSet{}); err == nil {
+		t.Errorf("expected an error for a workload without selector")
+	}
+}
+
+func TestRunWave(t *testing.T) {
+	tests := []struct {
+		name          string
+		failPod       string
+		failurePolicy kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyType
+		expectErr     bool
+	}{
+		{name: "all succeeded", failurePolicy: kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyFail},
+		{name: "failed with Fail policy", failPod: "demo-b", failurePolicy: kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyFail, expectErr: true},
+		{name: "failed with Ignore policy", failPod: "demo-b", failurePolicy: kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyIgnore},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := kruisefake.NewSimpleClientset()
+			client.PrependReactor("create", "containerrecreaterequests", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				crr := action.(clienttesting.CreateAction).GetObject().(*kruiseappsv1alpha1.ContainerRecreateRequest)
+				phase := kruiseappsv1alpha1.ContainerRecreateRequestSucceeded
+				if crr.Spec.PodName == test.failPod {
+					phase = kruiseappsv1alpha1.ContainerRecreateRequestFailed
+				}
+				crr.Status.Phase = kruiseappsv1alpha1.ContainerRecreateRequestCompleted
+				for _, c := range crr.Spec.Containers {
+					crr.Status.ContainerRecreateStates = append(crr.Status.ContainerRecreateStates, kruiseappsv1alpha1.ContainerRecreateRequestContainerRecreateState{Name: c.Name, Phase: phase})
+				}
+				return false, nil, nil
+			})
+
+			streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+			o := NewRecreateOptions(streams)
+			o.KruiseClient = client
+			o.Containers = []string{"app"}
+			o.FailurePolicy = string(test.failurePolicy)
+			o.Timeout = 10 * time.Second
+
+			crrs, err := o.runWave([]corev1.Pod{*newPod("demo-a", corev1.PodRunning), *newPod("demo-b", corev1.PodRunning)})
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error %v, got %v", test.expectErr, err)
+			}
+			if len(crrs) != 2 {
+				t.Fatalf("expected 2 requests, got %d", len(crrs))
+			}
+			for _, crr := range crrs {
+				if crr.Spec.Strategy.FailurePolicy != test.failurePolicy || len(crr.Spec.Containers) != 1 || crr.Spec.Containers[0].Name != "app" {
+					t.Errorf("unexpected request spec %+v", crr.Spec)
+				}
+			}
+
+			if err := o.printReport(crrs); err != nil {
+				t.Fatal(err)
+			}
+			expected := "2 of 2 pods"
+			if test.failPod != "" {
+				expected = "1 of 2 pods"
+			}
+			if !strings.Contains(buf.String(), expected) {
+				t.Errorf("expected report to contain %q, got:\n%s", expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestRunWaveBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recreate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	o := NewRecreateOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.Containers = []string{"app"}
+	o.BundleOut = filepath.Join(dir, "restart-demo.yaml")
+	if o.bundle, err = internalcmdutil.NewBundle(o.BundleOut, "cloneset/demo"); err != nil {
+		t.Fatal(err)
+	}
+	o.DryRunStrategy = cmdutil.DryRunClient
+	o.PrintObj = func(runtime.Object, io.Writer) error { return nil }
+
+	if _, err := o.runWave([]corev1.Pod{*newPod("demo-a", corev1.PodRunning), *newPod("demo-b", corev1.PodRunning)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.writeBundle(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(o.BundleOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.Count(string(data), "kubectl.kruise.io/bundle: restart-demo"); count != 2 {
+		t.Errorf("expected 2 labeled requests in the bundle, got %d:\n%s", count, data)
+	}
+}
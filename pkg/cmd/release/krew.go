@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KrewPlugin is the krew manifest of a plugin, which krew also keeps as the receipt of an
+// installed plugin in KREW_ROOT/receipts/NAME.yaml.
+type KrewPlugin struct {
+	APIVersion string         `json:"apiVersion"`
+	Kind       string         `json:"kind"`
+	Metadata   KrewMetadata   `json:"metadata"`
+	Spec       KrewPluginSpec `json:"spec"`
+}
+
+// KrewMetadata is the metadata of a krew manifest.
+type KrewMetadata struct {
+	Name string `json:"name"`
+}
+
+// KrewPluginSpec is the spec of a krew manifest.
+type KrewPluginSpec struct {
+	Version          string         `json:"version"`
+	ShortDescription string         `json:"shortDescription,omitempty"`
+	Homepage         string         `json:"homepage,omitempty"`
+	Description      string         `json:"description,omitempty"`
+	Platforms        []KrewPlatform `json:"platforms,omitempty"`
+}
+
+// KrewPlatform is the archive of a plugin for the platforms matched by its selector.
+type KrewPlatform struct {
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	URI      string                `json:"uri"`
+	Sha256   string                `json:"sha256"`
+	Files    []KrewFileOperation   `json:"files"`
+	Bin      string                `json:"bin"`
+}
+
+// KrewFileOperation copies the files matched by From in the archive to To.
+type KrewFileOperation struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// KrewPluginName is the name of kubectl-kruise in the krew index
+	KrewPluginName = "kruise"
+
+	defaultBaseURL = "https://github.com/openkruise/kruise-tools/releases/download"
+)
+
+// Platforms are the OS/ARCH pairs kubectl-kruise is released for.
+var Platforms = []string{"linux/amd64", "linux/arm64", "darwin/amd64", "darwin/arm64", "windows/amd64"}
+
+var (
+	releaseLong = templates.LongDesc(`
+		Commands for the maintainers of kubectl-kruise to publish its releases.`)
+
+	krewManifestLong = templates.LongDesc(`
+		Print the krew manifest of a release of kubectl-kruise.
+
+		The sha256 checksums of the release archives are read from the sha256sums.txt asset
+		of the release, as given by --checksums. The manifest is ready to be submitted to the
+		krew index.`)
+
+	krewManifestExample = templates.Examples(`
+		# Print the krew manifest of v1.0.0, with the checksums downloaded from its release
+		kubectl-kruise release krew-manifest --version v1.0.0 --checksums sha256sums.txt > kruise.yaml`)
+)
+
+// KrewManifestOptions holds the command-line options for 'release krew-manifest' command
+type KrewManifestOptions struct {
+	Version   string
+	Checksums string
+	BaseURL   string
+
+	genericclioptions.IOStreams
+}
+
+// NewKrewManifestOptions returns an initialized KrewManifestOptions instance
+func NewKrewManifestOptions(streams genericclioptions.IOStreams) *KrewManifestOptions {
+	return &KrewManifestOptions{
+		BaseURL:   defaultBaseURL,
+		IOStreams: streams,
+	}
+}
+
+// NewCmdRelease returns a Command instance for 'release' sub command
+func NewCmdRelease(streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "release SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Commands to publish the releases of kubectl-kruise"),
+		Long:                  releaseLong,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+	cmd.AddCommand(NewCmdKrewManifest(streams))
+	return cmd
+}
+
+// NewCmdKrewManifest returns a Command instance for 'release krew-manifest' sub command
+func NewCmdKrewManifest(streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewKrewManifestOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "krew-manifest --version=TAG --checksums=FILE",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print the krew manifest of a release"),
+		Long:                  krewManifestLong,
+		Example:               krewManifestExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Version, "version", o.Version, "The tag of the release, e.g. v1.0.0.")
+	cmd.Flags().StringVar(&o.Checksums, "checksums", o.Checksums, "The sha256sums.txt asset of the release.")
+	cmd.Flags().StringVar(&o.BaseURL, "base-url", o.BaseURL, "The URL the archives of the releases are downloaded from, followed by /TAG/ARCHIVE.")
+	return cmd
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *KrewManifestOptions) Validate() error {
+	if len(o.Version) == 0 {
+		return fmt.Errorf("--version is required")
+	}
+	if _, err := utilversion.ParseSemantic(o.Version); err != nil || !strings.HasPrefix(o.Version, "v") {
+		return fmt.Errorf("invalid --version %q, must be a semantic version starting with v", o.Version)
+	}
+	if len(o.Checksums) == 0 {
+		return fmt.Errorf("--checksums is required")
+	}
+	return nil
+}
+
+// Run prints the krew manifest
+func (o *KrewManifestOptions) Run() error {
+	data, err := ioutil.ReadFile(o.Checksums)
+	if err != nil {
+		return err
+	}
+	checksums, err := ParseChecksums(string(data))
+	if err != nil {
+		return err
+	}
+	manifest, err := KrewManifest(o.Version, strings.TrimSuffix(o.BaseURL, "/"), checksums)
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	_, err = o.Out.Write(out)
+	return err
+}
+
+// ArchiveName returns the name of the release archive of kubectl-kruise for os and arch.
+func ArchiveName(os, arch string) string {
+	return fmt.Sprintf("kubectl-kruise-%s-%s.tar.gz", os, arch)
+}
+
+// ParseChecksums parses the checksums in the format of sha256sum into a map by file name.
+func ParseChecksums(data string) (map[string]string, error) {
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 || len(fields[0]) != 64 {
+			return nil, fmt.Errorf("invalid checksum line %q", scanner.Text())
+		}
+		checksums[path.Base(strings.TrimPrefix(fields[1], "*"))] = strings.ToLower(fields[0])
+	}
+	return checksums, scanner.Err()
+}
+
+// KrewManifest returns the krew manifest of the release version, whose archives are
+// downloaded from baseURL/version and have checksums.
+func KrewManifest(version, baseURL string, checksums map[string]string) (*KrewPlugin, error) {
+	manifest := &KrewPlugin{
+		APIVersion: "krew.googlecontainertools.github.com/v1alpha2",
+		Kind:       "Plugin",
+		Metadata:   KrewMetadata{Name: KrewPluginName},
+		Spec: KrewPluginSpec{
+			Version:          version,
+			ShortDescription: "Easily handle OpenKruise workloads",
+			Homepage:         "https://openkruise.io/",
+			Description: "kubectl kruise is a kubectl plugin from the OpenKruise project. OpenKruise is an extended component suite for Kubernetes,\n" +
+				"which mainly focuses on application automations, such as deployment, upgrade, ops and avalibility protection.\n" +
+				"This plugin allows you to better handle, manage and maintain OpenKruise workloads.\n",
+		},
+	}
+	for _, platform := range Platforms {
+		parts := strings.SplitN(platform, "/", 2)
+		archive := ArchiveName(parts[0], parts[1])
+		sha256, ok := checksums[archive]
+		if !ok {
+			return nil, fmt.Errorf("no checksum of %s", archive)
+		}
+		manifest.Spec.Platforms = append(manifest.Spec.Platforms, KrewPlatform{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"os": parts[0], "arch": parts[1]}},
+			URI:      fmt.Sprintf("%s/%s/%s", baseURL, version, archive),
+			Sha256:   sha256,
+			Files: []KrewFileOperation{
+				{From: "*/kubectl-kruise", To: "."},
+				{From: "*/LICENSE", To: "."},
+			},
+			Bin: "kubectl-kruise",
+		})
+	}
+	return manifest, nil
+}
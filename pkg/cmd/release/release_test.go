@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestKrewManifest(t *testing.T) {
+	var lines []string
+	for i, platform := range Platforms {
+		parts := strings.SplitN(platform, "/", 2)
+		lines = append(lines, fmt.Sprintf("%064d  kubectl-kruise/%s", i, ArchiveName(parts[0], parts[1])))
+	}
+	checksums, err := ParseChecksums(strings.Join(lines, "\n") + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := KrewManifest("v1.0.0", defaultBaseURL, checksums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Spec.Version != "v1.0.0" || len(manifest.Spec.Platforms) != len(Platforms) {
+		t.Fatalf("unexpected manifest %+v", manifest.Spec)
+	}
+	darwin := manifest.Spec.Platforms[3]
+	if darwin.URI != "https://github.com/openkruise/kruise-tools/releases/download/v1.0.0/kubectl-kruise-darwin-arm64.tar.gz" {
+		t.Errorf("unexpected uri %s", darwin.URI)
+	}
+	if darwin.Sha256 != fmt.Sprintf("%064d", 3) {
+		t.Errorf("unexpected sha256 %s", darwin.Sha256)
+	}
+	if darwin.Selector.MatchLabels["os"] != "darwin" || darwin.Selector.MatchLabels["arch"] != "arm64" {
+		t.Errorf("unexpected selector %v", darwin.Selector)
+	}
+
+	delete(checksums, ArchiveName("windows", "amd64"))
+	if _, err := KrewManifest("v1.0.0", defaultBaseURL, checksums); err == nil {
+		t.Errorf("expected an error for a missing checksum")
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	if _, err := ParseChecksums("abc kubectl-kruise-linux-amd64.tar.gz\n"); err == nil {
+		t.Errorf("expected an error for an invalid checksum")
+	}
+}
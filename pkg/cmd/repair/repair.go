@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repair
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	repairLong = templates.LongDesc(`
+		Find and repair the resources left inconsistent by interrupted operations.`)
+
+	repairExample = templates.Examples(`
+		# List the orphaned pods of namespace default
+		kubectl-kruise repair orphans -n default`)
+)
+
+// NewCmdRepair returns a Command instance for 'repair' command
+func NewCmdRepair(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "repair SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Repair the resources left by interrupted operations"),
+		Long:                  repairLong,
+		Example:               repairExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(NewCmdRepairOrphans(f, streams))
+	return cmd
+}
@@ -0,0 +1,422 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repair
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseclientset "github.com/openkruise/kruise-api/client/clientset/versioned"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// The problems of the orphaned pods
+const (
+	// ProblemOwnerDeleted is the problem of a pod whose controller no longer exists
+	ProblemOwnerDeleted = "owner deleted"
+	// ProblemOwnerReplaced is the problem of a pod whose controller was deleted and created again
+	ProblemOwnerReplaced = "owner replaced"
+	// ProblemNoOwner is the problem of a pod selected by a CloneSet without controller
+	ProblemNoOwner = "no owner"
+)
+
+// The repairs of the orphaned pods
+const (
+	// ActionAdopt makes the only CloneSet selecting the pod its controller
+	ActionAdopt = "adopt"
+	// ActionDelete evicts the pod no CloneSet selects
+	ActionDelete = "delete"
+	// ActionNone leaves the pod selected by several CloneSets to be repaired by hand
+	ActionNone = "none"
+)
+
+var (
+	orphansLong = templates.LongDesc(i18n.T(`
+		Find the orphaned pods of a namespace, and adopt them into their CloneSet or delete them.
+
+		Interrupted migrations leave pods whose controller was deleted, or deleted and created
+		again with the same name, and pods without controller selected by a CloneSet. A pod
+		selected by a single CloneSet is adopted by it: the CloneSet becomes its controller,
+		and then updates or scales it in as any of its pods. A pod selected by no CloneSet is
+		deleted with an eviction, which respects the PodDisruptionBudgets. A pod selected by
+		several CloneSets is only reported.
+
+		Without --adopt or --delete the orphaned pods are only listed. Each repair is confirmed,
+		unless --yes is given.`))
+
+	orphansExample = templates.Examples(i18n.T(`
+		# List the orphaned pods of namespace default and how they would be repaired
+		kubectl-kruise repair orphans -n default
+
+		# Adopt the orphaned pods into their CloneSets, after confirmation of each pod
+		kubectl-kruise repair orphans -n default --adopt
+
+		# Adopt or delete all the orphaned pods without confirmation
+		kubectl-kruise repair orphans -n default --adopt --delete --yes`))
+)
+
+// OrphanPod is a pod without a live controller.
+type OrphanPod struct {
+	Pod *corev1.Pod
+	// Owner is the controller reference of the pod to a deleted controller, nil if it has none
+	Owner   *metav1.OwnerReference
+	Problem string
+	// CloneSets are the CloneSets of the namespace of the pod selecting it
+	CloneSets []*kruiseappsv1alpha1.CloneSet
+}
+
+// Action returns how the pod is repaired.
+func (p OrphanPod) Action() string {
+	switch len(p.CloneSets) {
+	case 0:
+		return ActionDelete
+	case 1:
+		return ActionAdopt
+	}
+	return ActionNone
+}
+
+// OwnerUIDFunc returns the UID of the owner of ref in namespace, and false if it does not exist.
+type OwnerUIDFunc func(namespace string, ref metav1.OwnerReference) (types.UID, bool, error)
+
+// RepairOrphansOptions holds the command-line options for 'repair orphans' sub command
+type RepairOrphansOptions struct {
+	Namespace string
+	Adopt     bool
+	Delete    bool
+	Yes       bool
+
+	Client        kubernetes.Interface
+	KruiseClient  kruiseclientset.Interface
+	DynamicClient dynamic.Interface
+	Mapper        meta.RESTMapper
+
+	in *bufio.Reader
+
+	genericclioptions.IOStreams
+}
+
+// NewRepairOrphansOptions returns an initialized RepairOrphansOptions instance
+func NewRepairOrphansOptions(streams genericclioptions.IOStreams) *RepairOrphansOptions {
+	return &RepairOrphansOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdRepairOrphans returns a Command instance for 'repair orphans' sub command
+func NewCmdRepairOrphans(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRepairOrphansOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "orphans [--adopt] [--delete] [--yes]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Adopt or delete the orphaned pods of a namespace"),
+		Long:                  orphansLong,
+		Example:               orphansExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.Adopt, "adopt", o.Adopt, "If true, make the only CloneSet selecting each orphaned pod its controller, after confirmation.")
+	cmd.Flags().BoolVar(&o.Delete, "delete", o.Delete, "If true, evict the orphaned pods no CloneSet selects, after confirmation.")
+	cmd.Flags().BoolVarP(&o.Yes, "yes", "y", o.Yes, "If true, repair the orphaned pods without confirmation.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *RepairOrphansOptions) Complete(f cmdutil.Factory, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %v", args)
+	}
+	var err error
+	if o.Namespace, _, err = f.ToRawKubeConfigLoader().Namespace(); err != nil {
+		return err
+	}
+	if o.Client, err = f.KubernetesClientSet(); err != nil {
+		return err
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	if o.KruiseClient, err = kruiseclientset.NewForConfig(config); err != nil {
+		return err
+	}
+	if o.DynamicClient, err = f.DynamicClient(); err != nil {
+		return err
+	}
+	o.Mapper, err = f.ToRESTMapper()
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *RepairOrphansOptions) Validate() error {
+	if o.Yes && !o.Adopt && !o.Delete {
+		return fmt.Errorf("--yes requires --adopt or --delete")
+	}
+	return nil
+}
+
+// Run performs the execution of 'repair orphans' sub command
+func (o *RepairOrphansOptions) Run() error {
+	pods, err := o.Client.CoreV1().Pods(o.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	cloneSets, err := o.KruiseClient.AppsV1alpha1().CloneSets(o.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+	orphans, err := FindOrphans(pods.Items, cloneSets.Items, o.ownerUID())
+	if err != nil {
+		return err
+	}
+	if err := o.printOrphans(orphans); err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, orphan := range orphans {
+		var err error
+		switch action := orphan.Action(); {
+		case action == ActionAdopt && o.Adopt:
+			err = o.adopt(orphan)
+		case action == ActionDelete && o.Delete:
+			err = o.evict(orphan)
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// FindOrphans returns the pods whose controller does not exist, or was created again since, and
+// the pods without controller selected by cloneSets. The pods being deleted are skipped.
+func FindOrphans(pods []corev1.Pod, cloneSets []kruiseappsv1alpha1.CloneSet, ownerUID OwnerUIDFunc) ([]OrphanPod, error) {
+	var orphans []OrphanPod
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		orphan := OrphanPod{Pod: pod, CloneSets: selectingCloneSets(pod, cloneSets)}
+		if orphan.Owner = metav1.GetControllerOf(pod); orphan.Owner == nil {
+			if len(orphan.CloneSets) == 0 {
+				// a pod without controller is orphaned only if it belongs to a CloneSet
+				continue
+			}
+			orphan.Problem = ProblemNoOwner
+		} else {
+			uid, found, err := ownerUID(pod.Namespace, *orphan.Owner)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case !found:
+				orphan.Problem = ProblemOwnerDeleted
+			case uid != orphan.Owner.UID:
+				orphan.Problem = ProblemOwnerReplaced
+			default:
+				continue
+			}
+		}
+		orphans = append(orphans, orphan)
+	}
+	return orphans, nil
+}
+
+// selectingCloneSets returns the cloneSets in the namespace of pod selecting it.
+func selectingCloneSets(pod *corev1.Pod, cloneSets []kruiseappsv1alpha1.CloneSet) []*kruiseappsv1alpha1.CloneSet {
+	var selecting []*kruiseappsv1alpha1.CloneSet
+	for i := range cloneSets {
+		cloneSet := &cloneSets[i]
+		if cloneSet.Namespace != pod.Namespace || cloneSet.DeletionTimestamp != nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(cloneSet.Spec.Selector)
+		if err != nil || selector.Empty() {
+			continue
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			selecting = append(selecting, cloneSet)
+		}
+	}
+	return selecting
+}
+
+// ownerUID returns an OwnerUIDFunc getting the owners with the dynamic client, once each. An
+// owner of a kind the server no longer serves does not exist.
+func (o *RepairOrphansOptions) ownerUID() OwnerUIDFunc {
+	type owner struct {
+		uid   types.UID
+		found bool
+	}
+	owners := map[string]owner{}
+	return func(namespace string, ref metav1.OwnerReference) (types.UID, bool, error) {
+		key := strings.Join([]string{namespace, ref.APIVersion, ref.Kind, ref.Name}, "/")
+		if cached, ok := owners[key]; ok {
+			return cached.uid, cached.found, nil
+		}
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return "", false, err
+		}
+		mapping, err := o.Mapper.RESTMapping(gv.WithKind(ref.Kind).GroupKind(), gv.Version)
+		if meta.IsNoMatchError(err) {
+			owners[key] = owner{}
+			return "", false, nil
+		} else if err != nil {
+			return "", false, err
+		}
+		client := o.DynamicClient.Resource(mapping.Resource)
+		var obj metav1.Object
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			obj, err = client.Namespace(namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+		} else {
+			obj, err = client.Get(context.TODO(), ref.Name, metav1.GetOptions{})
+		}
+		if errors.IsNotFound(err) {
+			owners[key] = owner{}
+			return "", false, nil
+		} else if err != nil {
+			return "", false, err
+		}
+		owners[key] = owner{uid: obj.GetUID(), found: true}
+		return obj.GetUID(), true, nil
+	}
+}
+
+func (o *RepairOrphansOptions) printOrphans(orphans []OrphanPod) error {
+	if len(orphans) == 0 {
+		fmt.Fprintf(o.Out, "No orphaned pods in namespace %s\n", o.Namespace)
+		return nil
+	}
+	w := internalcmdutil.NewTableWriter(o.Out)
+	fmt.Fprintln(w, "POD\tOWNER\tPROBLEM\tACTION")
+	for _, orphan := range orphans {
+		owner := "<none>"
+		if orphan.Owner != nil {
+			owner = strings.ToLower(orphan.Owner.Kind) + "/" + orphan.Owner.Name
+		}
+		action := orphan.Action()
+		switch action {
+		case ActionAdopt:
+			action = "adopt by cloneset/" + orphan.CloneSets[0].Name
+		case ActionNone:
+			var names []string
+			for _, cloneSet := range orphan.CloneSets {
+				names = append(names, cloneSet.Name)
+			}
+			action = "none, selected by clonesets " + strings.Join(names, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", orphan.Pod.Name, owner, orphan.Problem, action)
+	}
+	return w.Flush()
+}
+
+// confirm asks to confirm the question, unless --yes.
+func (o *RepairOrphansOptions) confirm(question string) bool {
+	if o.Yes {
+		return true
+	}
+	fmt.Fprintf(o.Out, "%s? [y/N]: ", question)
+	answer := ""
+	if o.In != nil {
+		if o.in == nil {
+			o.in = bufio.NewReader(o.In)
+		}
+		answer, _ = o.in.ReadString('\n')
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// adopt makes the CloneSet selecting the orphaned pod its controller.
+func (o *RepairOrphansOptions) adopt(orphan OrphanPod) error {
+	cloneSet := orphan.CloneSets[0]
+	if !o.confirm(fmt.Sprintf("Adopt pod/%s by cloneset/%s", orphan.Pod.Name, cloneSet.Name)) {
+		fmt.Fprintf(o.Out, "pod/%s skipped\n", orphan.Pod.Name)
+		return nil
+	}
+
+	pod := orphan.Pod.DeepCopy()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var refs []metav1.OwnerReference
+		for _, ref := range pod.OwnerReferences {
+			if ref.Controller == nil || !*ref.Controller {
+				refs = append(refs, ref)
+			}
+		}
+		pod.OwnerReferences = append(refs, *metav1.NewControllerRef(cloneSet, kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet")))
+		_, err := o.Client.CoreV1().Pods(pod.Namespace).Update(context.TODO(), pod, metav1.UpdateOptions{})
+		if errors.IsConflict(err) {
+			if latest, getErr := o.Client.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{}); getErr == nil {
+				pod = latest
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to adopt pod/%s: %v", pod.Name, err)
+	}
+	fmt.Fprintf(o.Out, "pod/%s adopted by cloneset/%s\n", pod.Name, cloneSet.Name)
+	return nil
+}
+
+// evict deletes the orphaned pod with an eviction, which respects the PodDisruptionBudgets.
+func (o *RepairOrphansOptions) evict(orphan OrphanPod) error {
+	pod := orphan.Pod
+	if !o.confirm(fmt.Sprintf("Delete pod/%s", pod.Name)) {
+		fmt.Fprintf(o.Out, "pod/%s skipped\n", pod.Name)
+		return nil
+	}
+	eviction := &policyv1beta1.Eviction{ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace, Name: pod.Name}}
+	if err := o.Client.CoreV1().Pods(pod.Namespace).Evict(context.TODO(), eviction); err != nil {
+		return fmt.Errorf("failed to evict pod/%s: %v", pod.Name, err)
+	}
+	fmt.Fprintf(o.Out, "pod/%s evicted\n", pod.Name)
+	return nil
+}
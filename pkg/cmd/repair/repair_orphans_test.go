@@ -0,0 +1,168 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repair
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var (
+	deploymentKind = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	cloneSetKind   = kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet")
+)
+
+func orphanPod(name string, labels map[string]string, owner *metav1.OwnerReference) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: labels}}
+	if owner != nil {
+		pod.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return pod
+}
+
+func ownerRef(gvk schema.GroupVersionKind, name string, uid types.UID) *metav1.OwnerReference {
+	controller := true
+	return &metav1.OwnerReference{APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind, Name: name, UID: uid, Controller: &controller}
+}
+
+func cloneSet(name, uid, app string) *kruiseappsv1alpha1.CloneSet {
+	return &kruiseappsv1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID(uid)},
+		Spec: kruiseappsv1alpha1.CloneSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+		},
+	}
+}
+
+func owner(gvk schema.GroupVersionKind, name, uid string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetUID(types.UID(uid))
+	return obj
+}
+
+func newTestOrphansOptions(in string) (*RepairOrphansOptions, *fake.Clientset, *bytes.Buffer) {
+	client := fake.NewSimpleClientset(
+		orphanPod("web-1", map[string]string{"app": "web"}, ownerRef(cloneSetKind, "web", "old-web")),
+		orphanPod("web-2", map[string]string{"app": "web"}, ownerRef(deploymentKind, "web", "old-deploy")),
+		orphanPod("web-3", map[string]string{"app": "web"}, ownerRef(cloneSetKind, "web", "web")),
+		orphanPod("stray", map[string]string{"app": "old"}, ownerRef(deploymentKind, "old", "old-deploy")),
+		orphanPod("api-1", map[string]string{"app": "api"}, nil),
+		orphanPod("lonely", nil, nil),
+		orphanPod("healthy", map[string]string{"app": "live"}, ownerRef(deploymentKind, "live", "live")),
+	)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(deploymentKind, meta.RESTScopeNamespace)
+	mapper.Add(cloneSetKind, meta.RESTScopeNamespace)
+
+	out := &bytes.Buffer{}
+	o := NewRepairOrphansOptions(genericclioptions.IOStreams{In: strings.NewReader(in), Out: out, ErrOut: out})
+	o.Namespace = "default"
+	o.Client = client
+	o.KruiseClient = kruisefake.NewSimpleClientset(cloneSet("web", "web", "web"), cloneSet("api", "api", "api"), cloneSet("api-canary", "api-canary", "api"))
+	o.DynamicClient = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: "apps", Version: "v1", Resource: "deployments"}:         "DeploymentList",
+		kruiseappsv1alpha1.SchemeGroupVersion.WithResource("clonesets"): "CloneSetList",
+	}, owner(cloneSetKind, "web", "web"), owner(deploymentKind, "live", "live"))
+	o.Mapper = mapper
+	return o, client, out
+}
+
+func TestRepairOrphansList(t *testing.T) {
+	o, client, out := newTestOrphansOptions("")
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `POD     OWNER            PROBLEM          ACTION
+api-1   <none>           no owner         none, selected by clonesets api, api-canary
+stray   deployment/old   owner deleted    delete
+web-1   cloneset/web     owner replaced   adopt by cloneset/web
+web-2   deployment/web   owner deleted    adopt by cloneset/web
+`
+	if out.String() != expected {
+		t.Errorf("expected output:\n%s\ngot:\n%s", expected, out.String())
+	}
+	for _, action := range client.Actions() {
+		if !action.Matches("list", "pods") {
+			t.Errorf("unexpected action %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+}
+
+func TestRepairOrphansAdoptAndDelete(t *testing.T) {
+	o, client, out := newTestOrphansOptions("y\ny\nn\n")
+	o.Adopt, o.Delete = true, true
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), `Delete pod/stray? [y/N]: pod/stray evicted
+Adopt pod/web-1 by cloneset/web? [y/N]: pod/web-1 adopted by cloneset/web
+Adopt pod/web-2 by cloneset/web? [y/N]: pod/web-2 skipped
+`) {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	var evicted []string
+	for _, action := range client.Actions() {
+		if action.Matches("create", "pods") && action.GetSubresource() == "eviction" {
+			evicted = append(evicted, action.(clienttesting.CreateAction).GetObject().(metav1.Object).GetName())
+		}
+	}
+	if len(evicted) != 1 || evicted[0] != "stray" {
+		t.Errorf("expected the eviction of stray, got %v", evicted)
+	}
+
+	for name, uid := range map[string]types.UID{"web-1": "web", "web-2": "old-deploy"} {
+		pod, err := client.Tracker().Get(corev1.SchemeGroupVersion.WithResource("pods"), "default", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ref := metav1.GetControllerOf(pod.(*corev1.Pod)); ref == nil || ref.UID != uid {
+			t.Errorf("expected pod %s to be controlled by %s, got %v", name, uid, ref)
+		}
+	}
+}
+
+func TestRepairOrphansValidate(t *testing.T) {
+	o := NewRepairOrphansOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.Yes = true
+	if err := o.Validate(); err == nil || err.Error() != "--yes requires --adopt or --delete" {
+		t.Errorf("unexpected error %v", err)
+	}
+	o.Delete = true
+	if err := o.Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restarts
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	restartsLong = templates.LongDesc(`
+		Show the container restarts of all pods of a workload.
+
+		Containers are sorted by restart count, with OOMKilled containers first on ties, so that
+		the worst offenders are listed at the top. The revision of each pod is shown to tell
+		whether the restarts come from the new or the old version during a rollout.`)
+
+	restartsExample = templates.Examples(`
+		# Show the containers of cloneset demo that have been restarted
+		kubectl-kruise restarts cloneset/demo
+
+		# Show the containers of cloneset demo whose last restart was in the last 24 hours
+		kubectl-kruise restarts cloneset/demo --since 24h
+
+		# Show the 5 containers of advanced statefulset demo restarted most
+		kubectl-kruise restarts statefulsets.apps.kruise.io/demo --top 5
+
+		# Show the restarted containers of the pods of cloneset demo running on spot nodes
+		kubectl-kruise restarts cloneset/demo --filter-expr 'spec.nodeName.startsWith("spot-")'`)
+)
+
+// RestartsOptions holds the command-line options for 'restarts' command
+type RestartsOptions struct {
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+	FieldSelector    string
+	FilterExpr       string
+	Since            time.Duration
+	Top              int
+
+	filter *internalcmdutil.FilterExpr
+
+	Builder func() *resource.Builder
+	Client  kubernetes.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// RestartRow is the restart history of one container.
+type RestartRow struct {
+	Pod         string
+	Revision    string
+	Container   string
+	Restarts    int32
+	ExitCode    *int32
+	Reason      string
+	OOMKilled   bool
+	LastRestart time.Time
+}
+
+// NewRestartsOptions returns an initialized RestartsOptions instance
+func NewRestartsOptions(streams genericclioptions.IOStreams) *RestartsOptions {
+	return &RestartsOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdRestarts returns a Command instance for 'restarts' command
+func NewCmdRestarts(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRestartsOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "restarts (TYPE/NAME | TYPE NAME) [--since=DURATION] [--top=N]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show the container restarts of all pods of a workload"),
+		Long:                  restartsLong,
+		Example:               restartsExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	internalcmdutil.AddFieldSelectorFlagVar(cmd, &o.FieldSelector)
+	internalcmdutil.AddFilterExprFlagVar(cmd, &o.FilterExpr)
+	cmd.Flags().DurationVar(&o.Since, "since", o.Since, "Only show containers whose last restart is newer than this duration, e.g. 24h. Defaults to all restarted containers.")
+	cmd.Flags().IntVar(&o.Top, "top", o.Top, "Only show this many containers. Defaults to all.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *RestartsOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+	if o.filter, err = internalcmdutil.CompileFilterExpr(o.FilterExpr); err != nil {
+		return err
+	}
+	o.Client, err = f.KubernetesClientSet()
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *RestartsOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if err := internalcmdutil.ValidateFieldSelector(o.FieldSelector); err != nil {
+		return err
+	}
+	if o.Since < 0 {
+		return fmt.Errorf("--since must not be negative")
+	}
+	if o.Top < 0 {
+		return fmt.Errorf("--top must not be negative")
+	}
+	return nil
+}
+
+// Run performs the execution of 'restarts' command
+func (o *RestartsOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		FieldSelectorParam(o.FieldSelector).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return err
+	}
+
+	var pods []corev1.Pod
+	for _, info := range infos {
+		selected, err := polymorphichelpers.PodsForObject(o.Client.CoreV1(), info.Object)
+		if err != nil {
+			return err
+		}
+		for i := range selected {
+			matches, err := o.filter.Matches(&selected[i])
+			if err != nil {
+				return err
+			}
+			if matches {
+				pods = append(pods, selected[i])
+			}
+		}
+	}
+
+	rows := RestartRows(pods, o.Since, time.Now())
+	return o.printRows(rows, len(pods))
+}
+
+// RestartRows returns the restarted containers of pods, worst offenders first. If since is
+// positive, containers whose last restart is older than since are left out.
+func RestartRows(pods []corev1.Pod, since time.Duration, now time.Time) []RestartRow {
+	var rows []RestartRow
+	for _, pod := range pods {
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if status.RestartCount == 0 {
+				continue
+			}
+			row := RestartRow{
+				Pod:       pod.Name,
+				Revision:  pod.Labels[appsv1.ControllerRevisionHashLabelKey],
+				Container: status.Name,
+				Restarts:  status.RestartCount,
+			}
+			if terminated := status.LastTerminationState.Terminated; terminated != nil {
+				exitCode := terminated.ExitCode
+				row.ExitCode = &exitCode
+				row.Reason = terminated.Reason
+				row.OOMKilled = terminated.Reason == "OOMKilled"
+				row.LastRestart = terminated.FinishedAt.Time
+			}
+			if since > 0 && (row.LastRestart.IsZero() || now.Sub(row.LastRestart) > since) {
+				continue
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Restarts != rows[j].Restarts {
+			return rows[i].Restarts > rows[j].Restarts
+		}
+		if rows[i].OOMKilled != rows[j].OOMKilled {
+			return rows[i].OOMKilled
+		}
+		return rows[i].LastRestart.After(rows[j].LastRestart)
+	})
+	return rows
+}
+
+func (o *RestartsOptions) printRows(rows []RestartRow, podCount int) error {
+	if len(rows) == 0 {
+		fmt.Fprintf(o.Out, "No restarted containers found in %d pods\n", podCount)
+		return nil
+	}
+
+	var restarts int32
+	var oomKilled int
+	containerCount := len(rows)
+	for _, row := range rows {
+		restarts += row.Restarts
+		if row.OOMKilled {
+			oomKilled++
+		}
+	}
+	if o.Top > 0 && len(rows) > o.Top {
+		rows = rows[:o.Top]
+	}
+
+	w := internalcmdutil.NewTableWriter(o.Out)
+	fmt.Fprintln(w, "POD\tREVISION\tCONTAINER\tRESTARTS\tLAST EXIT\tREASON\tOOMKILLED\tLAST RESTART")
+	for _, row := range rows {
+		exitCode, lastRestart := "<none>", "<unknown>"
+		if row.ExitCode != nil {
+			exitCode = strconv.Itoa(int(*row.ExitCode))
+		}
+		if !row.LastRestart.IsZero() {
+			lastRestart = duration.HumanDuration(time.Since(row.LastRestart)) + " ago"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%t\t%s\n", row.Pod, row.Revision, row.Container, row.Restarts, exitCode, row.Reason, row.OOMKilled, lastRestart)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "\n%d restarts in %d containers of %d pods, %d last OOMKilled\n", restarts, containerCount, podCount, oomKilled)
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restarts
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newStatus(name string, restarts int32, reason string, exitCode int32, finishedAt time.Time) corev1.ContainerStatus {
+	status := corev1.ContainerStatus{Name: name, RestartCount: restarts}
+	if restarts > 0 {
+		status.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{
+			Reason:     reason,
+			ExitCode:   exitCode,
+			FinishedAt: metav1.NewTime(finishedAt),
+		}
+	}
+	return status
+}
+
+func TestRestartRows(t *testing.T) {
+	now := time.Now()
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "demo-a", Labels: map[string]string{"controller-revision-hash": "demo-v1"}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+				newStatus("app", 3, "Error", 1, now.Add(-time.Hour)),
+				newStatus("sidecar", 0, "", 0, time.Time{}),
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "demo-b", Labels: map[string]string{"controller-revision-hash": "demo-v2"}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+				newStatus("app", 3, "OOMKilled", 137, now.Add(-2*time.Hour)),
+				newStatus("sidecar", 5, "Error", 2, now.Add(-48*time.Hour)),
+			}},
+		},
+	}
+
+	rows := RestartRows(pods, 0, now)
+	expected := []string{"demo-b/sidecar", "demo-b/app", "demo-a/app"}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %+v", len(expected), rows)
+	}
+	for i, row := range rows {
+		if row.Pod+"/"+row.Container != expected[i] {
+			t.Errorf("expected row %d to be %s, got %s/%s", i, expected[i], row.Pod, row.Container)
+		}
+	}
+	if !rows[1].OOMKilled || *rows[1].ExitCode != 137 || rows[1].Revision != "demo-v2" {
+		t.Errorf("unexpected row %+v", rows[1])
+	}
+
+	rows = RestartRows(pods, 24*time.Hour, now)
+	if len(rows) != 2 || rows[0].Container != "app" || rows[1].Container != "app" {
+		t.Errorf("expected only the app containers restarted in the last 24h, got %+v", rows)
+	}
+}
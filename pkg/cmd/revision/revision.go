@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revision
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	revisionLong = templates.LongDesc(`
+		Search the ControllerRevisions of a workload.`)
+
+	revisionExample = templates.Examples(`
+		# Find the revision of cloneset demo that introduced the image nginx:1.25
+		kubectl-kruise revision grep cloneset/demo --field image --value nginx:1.25`)
+)
+
+// NewCmdRevision returns a Command instance for 'revision' command
+func NewCmdRevision(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "revision SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Search the revisions of a workload"),
+		Long:                  revisionLong,
+		Example:               revisionExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(NewCmdRevisionGrep(f, streams))
+	return cmd
+}
@@ -0,0 +1,351 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revision
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// The fields of the pod template searched by 'revision grep'
+const (
+	FieldImage      = "image"
+	FieldEnv        = "env"
+	FieldLabel      = "label"
+	FieldAnnotation = "annotation"
+)
+
+var (
+	grepLong = templates.LongDesc(`
+		Search the ControllerRevisions of a workload for a field of their pod templates.
+
+		The field is one of:
+
+		* image: the images of the containers
+		* env:NAME: the value of the environment variable NAME of the containers
+		* label:KEY and annotation:KEY: a label or an annotation of the pod template
+
+		With --value, the revisions in which the field took the value are printed, and the first
+		of them is reported as the revision that introduced it, which is where to look first when
+		hunting a regression. The command fails if no revision has the value. Without --value,
+		every revision that changed the field is printed.
+
+		The images and environment variables of all containers are searched unless -c is given.
+		Deployments keep their history in ReplicaSets and are not supported.`)
+
+	grepExample = templates.Examples(`
+		# Find the revision of cloneset demo that introduced the image nginx:1.25
+		kubectl-kruise revision grep cloneset/demo --field image --value nginx:1.25
+
+		# Find the revision of advanced statefulset db that set LOG_LEVEL=debug in the mysql container
+		kubectl-kruise revision grep asts/db --field env:LOG_LEVEL --value debug -c mysql
+
+		# List the revisions of cloneset demo that changed its images
+		kubectl-kruise revision grep cloneset/demo --field image`)
+)
+
+// GrepOptions holds the command-line options for 'revision grep' sub command
+type GrepOptions struct {
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+	Field            string
+	Value            string
+	Container        string
+
+	field string
+	key   string
+
+	Builder      func() *resource.Builder
+	Client       kubernetes.Interface
+	KruiseClient kruiseclientsets.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NewGrepOptions returns an initialized GrepOptions instance
+func NewGrepOptions(streams genericclioptions.IOStreams) *GrepOptions {
+	return &GrepOptions{IOStreams: streams}
+}
+
+// NewCmdRevisionGrep returns a Command instance for 'revision grep' sub command
+func NewCmdRevisionGrep(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewGrepOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "grep (TYPE/NAME | TYPE NAME) --field=FIELD [--value=VALUE]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Find the revisions of a workload that introduced a value"),
+		Long:                  grepLong,
+		Example:               grepExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringVar(&o.Field, "field", o.Field, "The field to search: image, env:NAME, label:KEY or annotation:KEY.")
+	cmd.Flags().StringVar(&o.Value, "value", o.Value, "The value to find the revisions of, all the changes of the field are listed if empty.")
+	cmd.Flags().StringVarP(&o.Container, "container", "c", o.Container, "The container to search the image or environment of, all containers if empty.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *GrepOptions) Complete(f cmdutil.Factory, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+
+	o.Client, err = f.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	clientConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig)
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *GrepOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if len(o.Field) == 0 {
+		return fmt.Errorf("a field must be given with --field")
+	}
+	o.field, o.key = o.Field, ""
+	if i := strings.Index(o.Field, ":"); i >= 0 {
+		o.field, o.key = o.Field[:i], o.Field[i+1:]
+	}
+	switch o.field {
+	case FieldImage:
+		if len(o.key) > 0 {
+			return fmt.Errorf("invalid --field %q, use -c to select the container of the image", o.Field)
+		}
+	case FieldEnv, FieldLabel, FieldAnnotation:
+		if len(o.key) == 0 {
+			return fmt.Errorf("invalid --field %q, must be %s:NAME", o.Field, o.field)
+		}
+	default:
+		return fmt.Errorf("invalid --field %q, must be image, env:NAME, label:KEY or annotation:KEY", o.Field)
+	}
+	if len(o.Container) > 0 && (o.field == FieldLabel || o.field == FieldAnnotation) {
+		return fmt.Errorf("-c only applies to the image and env fields")
+	}
+	return nil
+}
+
+// Run performs the execution of 'revision grep' sub command
+func (o *GrepOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		revisions, err := polymorphichelpers.RevisionTemplatesFor(info.Mapping.GroupVersionKind.GroupKind(), o.Client, o.KruiseClient, info.Namespace, info.Name)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%s/%s", info.Mapping.Resource.GroupResource().String(), info.Name)
+		if err := o.grep(name, revisions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match is a revision in which the searched field changed or took the searched value.
+type Match struct {
+	Revision *polymorphichelpers.RevisionTemplate
+	Value    string
+}
+
+// Grep returns the revisions, sorted by revision, in which the field took value, or every revision
+// that changed the field if value is empty. A revision is returned only if the revision before it
+// did not already match, so the first match is the revision that introduced the value.
+func (o *GrepOptions) Grep(revisions []polymorphichelpers.RevisionTemplate, value string) []Match {
+	sorted := make([]polymorphichelpers.RevisionTemplate, len(revisions))
+	copy(sorted, revisions)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Revision.Revision < sorted[j].Revision.Revision })
+
+	var matches []Match
+	var previous map[string]string
+	for i := range sorted {
+		values := o.values(sorted[i].Template)
+		var matched bool
+		if len(value) > 0 {
+			matched = hasValue(values, value) && (i == 0 || !hasValue(previous, value))
+		} else {
+			matched = i == 0 || formatValues(values) != formatValues(previous)
+		}
+		if matched {
+			matches = append(matches, Match{Revision: &sorted[i], Value: formatValues(values)})
+		}
+		previous = values
+	}
+	return matches
+}
+
+func (o *GrepOptions) grep(name string, revisions []polymorphichelpers.RevisionTemplate) error {
+	if len(revisions) == 0 {
+		return fmt.Errorf("%s: no rollout history found", name)
+	}
+	matches := o.Grep(revisions, o.Value)
+	if len(o.Value) > 0 && len(matches) == 0 {
+		return fmt.Errorf("%s: %s %s not found in any of the %d revisions", name, o.Field, o.Value, len(revisions))
+	}
+
+	w := internalcmdutil.NewTableWriter(o.Out)
+	fmt.Fprintf(w, "REVISION\tNAME\tCREATED\t%s\n", strings.ToUpper(o.Field))
+	for _, m := range matches {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", m.Revision.Revision.Revision, m.Revision.Revision.Name,
+			m.Revision.Revision.CreationTimestamp.UTC().Format(time.RFC3339), m.Value)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(o.Value) > 0 {
+		first := matches[0].Revision.Revision
+		fmt.Fprintf(o.Out, "\n%s: %s %s was first introduced in revision %d (%s), created at %s\n", name, o.Field, o.Value,
+			first.Revision, first.Name, first.CreationTimestamp.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// values returns the values of the searched field in template, by container name for the image
+// and env fields. A missing field has no value.
+func (o *GrepOptions) values(template *corev1.PodTemplateSpec) map[string]string {
+	values := map[string]string{}
+	if template == nil {
+		return values
+	}
+	switch o.field {
+	case FieldLabel:
+		if v, ok := template.Labels[o.key]; ok {
+			values[""] = v
+		}
+		return values
+	case FieldAnnotation:
+		if v, ok := template.Annotations[o.key]; ok {
+			values[""] = v
+		}
+		return values
+	}
+	containers := make([]corev1.Container, 0, len(template.Spec.InitContainers)+len(template.Spec.Containers))
+	containers = append(append(containers, template.Spec.InitContainers...), template.Spec.Containers...)
+	for _, c := range containers {
+		if len(o.Container) > 0 && c.Name != o.Container {
+			continue
+		}
+		if o.field == FieldImage {
+			values[c.Name] = c.Image
+			continue
+		}
+		for _, env := range c.Env {
+			if env.Name != o.key {
+				continue
+			}
+			if env.ValueFrom != nil {
+				values[c.Name] = valueFrom(env.ValueFrom)
+			} else {
+				values[c.Name] = env.Value
+			}
+		}
+	}
+	return values
+}
+
+// valueFrom describes the source of an environment variable, as 'kubectl describe' does.
+func valueFrom(source *corev1.EnvVarSource) string {
+	switch {
+	case source.ConfigMapKeyRef != nil:
+		return fmt.Sprintf("<configmap %s key %s>", source.ConfigMapKeyRef.Name, source.ConfigMapKeyRef.Key)
+	case source.SecretKeyRef != nil:
+		return fmt.Sprintf("<secret %s key %s>", source.SecretKeyRef.Name, source.SecretKeyRef.Key)
+	case source.FieldRef != nil:
+		return fmt.Sprintf("<field %s>", source.FieldRef.FieldPath)
+	case source.ResourceFieldRef != nil:
+		return fmt.Sprintf("<resource %s>", source.ResourceFieldRef.Resource)
+	}
+	return "<unknown>"
+}
+
+func hasValue(values map[string]string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// formatValues formats values as "CONTAINER=VALUE, ..." sorted by container, or as the bare value
+// of a label or annotation. It returns <none> if there is no value.
+func formatValues(values map[string]string) string {
+	if len(values) == 0 {
+		return "<none>"
+	}
+	if v, ok := values[""]; ok && len(values) == 1 {
+		return v
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + values[k]
+	}
+	return strings.Join(parts, ", ")
+}
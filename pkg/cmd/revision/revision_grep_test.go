@@ -0,0 +1,148 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revision
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func newRevision(name string, revision int64, image, logLevel string) polymorphichelpers.RevisionTemplate {
+	created := time.Date(2024, 5, 1, int(revision), 0, 0, 0, time.UTC)
+	template := &corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "demo"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: image},
+			{Name: "sidecar", Image: "envoy:1.22"},
+		}},
+	}
+	if len(logLevel) > 0 {
+		template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "LOG_LEVEL", Value: logLevel}}
+	}
+	return polymorphichelpers.RevisionTemplate{
+		Revision: &appsv1.ControllerRevision{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)}, Revision: revision},
+		Template: template,
+	}
+}
+
+// the revisions of the tests, out of order as they are listed
+var revisions = []polymorphichelpers.RevisionTemplate{
+	newRevision("demo-c", 3, "nginx:1.25", "debug"),
+	newRevision("demo-a", 1, "nginx:1.24", ""),
+	newRevision("demo-b", 2, "nginx:1.24", "info"),
+	newRevision("demo-d", 4, "nginx:1.24", "debug"),
+	newRevision("demo-e", 5, "nginx:1.25", "debug"),
+}
+
+func matchNames(matches []Match) string {
+	var names []string
+	for _, m := range matches {
+		names = append(names, m.Revision.Revision.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestGrep(t *testing.T) {
+	tests := []struct {
+		field     string
+		container string
+		value     string
+		expected  string
+		values    string
+	}{
+		{field: "image", value: "nginx:1.25", expected: "demo-c,demo-e", values: "app=nginx:1.25, sidecar=envoy:1.22"},
+		{field: "image", container: "app", expected: "demo-a,demo-c,demo-d,demo-e", values: "app=nginx:1.24"},
+		{field: "image", container: "sidecar", value: "envoy:1.22", expected: "demo-a", values: "sidecar=envoy:1.22"},
+		{field: "env:LOG_LEVEL", value: "debug", expected: "demo-c", values: "app=debug"},
+		{field: "env:LOG_LEVEL", expected: "demo-a,demo-b,demo-c", values: "<none>"},
+		{field: "label:app", expected: "demo-a", values: "demo"},
+		{field: "annotation:owner", value: "sre", expected: ""},
+	}
+	for _, test := range tests {
+		o := NewGrepOptions(genericclioptions.NewTestIOStreamsDiscard())
+		o.Resources = []string{"cloneset/demo"}
+		o.Field, o.Container, o.Value = test.field, test.container, test.value
+		if err := o.Validate(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.field, err)
+		}
+		matches := o.Grep(revisions, test.value)
+		if names := matchNames(matches); names != test.expected {
+			t.Errorf("%s=%s: expected revisions %s, got %s", test.field, test.value, test.expected, names)
+			continue
+		}
+		if len(matches) > 0 && matches[0].Value != test.values {
+			t.Errorf("%s=%s: expected value %q, got %q", test.field, test.value, test.values, matches[0].Value)
+		}
+	}
+}
+
+func TestGrepOutput(t *testing.T) {
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewGrepOptions(streams)
+	o.Resources = []string{"cloneset/demo"}
+	o.Field, o.Value, o.Container = "image", "nginx:1.25", "app"
+	if err := o.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.grep("clonesets.apps.kruise.io/demo", revisions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `REVISION   NAME     CREATED                IMAGE
+3          demo-c   2024-05-01T03:00:00Z   app=nginx:1.25
+5          demo-e   2024-05-01T05:00:00Z   app=nginx:1.25
+
+clonesets.apps.kruise.io/demo: image nginx:1.25 was first introduced in revision 3 (demo-c), created at 2024-05-01T03:00:00Z
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	o.Value = "nginx:1.26"
+	err := o.grep("clonesets.apps.kruise.io/demo", revisions)
+	if err == nil || err.Error() != "clonesets.apps.kruise.io/demo: image nginx:1.26 not found in any of the 5 revisions" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGrepValidate(t *testing.T) {
+	tests := []struct {
+		field     string
+		container string
+		expected  string
+	}{
+		{field: "", expected: "a field must be given with --field"},
+		{field: "command", expected: `invalid --field "command", must be image, env:NAME, label:KEY or annotation:KEY`},
+		{field: "env", expected: `invalid --field "env", must be env:NAME`},
+		{field: "image:app", expected: `invalid --field "image:app", use -c to select the container of the image`},
+		{field: "label:app", container: "app", expected: "-c only applies to the image and env fields"},
+	}
+	for _, test := range tests {
+		o := NewGrepOptions(genericclioptions.NewTestIOStreamsDiscard())
+		o.Resources = []string{"cloneset/demo"}
+		o.Field, o.Container = test.field, test.container
+		if err := o.Validate(); err == nil || err.Error() != test.expected {
+			t.Errorf("%s: expected error %q, got %v", test.field, test.expected, err)
+		}
+	}
+}
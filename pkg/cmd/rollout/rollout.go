@@ -19,6 +19,7 @@ limitations under the License.
 
 import (
 	"github.com/lithammer/dedent"
+	"github.com/openkruise/kruise-tools/pkg/writeback"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
@@ -66,7 +67,11 @@ func NewCmdRollout(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobr
 	cmd.AddCommand(NewCmdRolloutUndo(f, streams))
 	cmd.AddCommand(NewCmdRolloutStatus(f, streams))
 	cmd.AddCommand(NewCmdRolloutRestart(f, streams))
-	cmd.AddCommand(NewCmdRolloutApprove(f, streams))
+	cmd.AddCommand(writeback.WithWriteBack(NewCmdRolloutApprove(f, streams), streams.Out))
+	cmd.AddCommand(NewCmdRolloutSchedule(f, streams))
+	cmd.AddCommand(NewCmdRolloutAutopilot(f, streams))
+	cmd.AddCommand(NewCmdRolloutRouteNginx(f, streams))
+	cmd.AddCommand(NewCmdRolloutCompare(f, streams))
 
 	return cmd
 }
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	kset "github.com/openkruise/kruise-tools/pkg/cmd/set"
 	"github.com/openkruise/kruise-tools/pkg/cmd/util"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	"github.com/spf13/cobra"
@@ -29,7 +30,6 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
-	"k8s.io/kubectl/pkg/cmd/set"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util/i18n"
@@ -43,6 +43,7 @@ type ApproveOptions struct {
 	ToPrinter  func(string) (printers.ResourcePrinter, error)
 
 	Resources []string
+	Snapshot  util.PromotionSnapshotOptions
 
 	Builder          func() *resource.Builder
 	Approver         internalpolymorphichelpers.ObjectApproverFunc
@@ -61,12 +62,20 @@ var (
 
 		Paused resources will not be reconciled by a controller. By approving a
 		resource, we allow it to be continue to rollout.
-		Currently only kruise-rollouts support being approved.`)
+		Currently only kruise-rollouts support being approved.
+
+		With --snapshot, the state of the workload of the rollout and of its pods, and the values
+		of the --snapshot-metric queries, are recorded in the kubectl.kruise.io/promotion-snapshots
+		annotation of the rollout, as the evidence each step was approved on.`)
 
 	ApproveExample = templates.Examples(`
 		# approve an rollout resource that has been checked for correct 
 		
-		kubectl-kruise rollout approve rollout/nginx`)
+		kubectl-kruise rollout approve rollout/nginx
+
+		# approve rollout nginx and record the state of its pods and its p99 latency at the time
+		kubectl-kruise rollout approve rollout/nginx --snapshot --prometheus-url http://prometheus:9090 \
+		  --snapshot-metric 'p99=histogram_quantile(0.99, sum(rate(http_duration_seconds_bucket{app="nginx"}[5m])) by (le))'`)
 )
 
 // NewRolloutApproveOptions returns an initialized ApproveOptions instance
@@ -99,6 +108,7 @@ func NewCmdRolloutApprove(f cmdutil.Factory, streams genericclioptions.IOStreams
 
 	usage := "identifying the resource to get from a server."
 	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	util.AddPromotionSnapshotFlags(cmd, &o.Snapshot)
 	o.PrintFlags.AddFlags(cmd)
 	return cmd
 }
@@ -122,14 +132,14 @@ func (o *ApproveOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []
 
 	o.Builder = f.NewBuilder
 
-	return nil
+	return o.Snapshot.Complete(f)
 }
 
 func (o *ApproveOptions) Validate() error {
 	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
 		return fmt.Errorf("required resource not specified")
 	}
-	return nil
+	return o.Snapshot.Validate()
 }
 
 // RunApprove performs the execution of 'rollout approve' sub command
@@ -158,7 +168,9 @@ func (o ApproveOptions) RunApprove() error {
 		allErrs = append(allErrs, err)
 	}
 
-	for _, patch := range set.CalculatePatches(infos, scheme.DefaultJSONEncoder(), set.PatchFn(o.Approver)) {
+	// the patches are calculated by kruise so that they are gated by the policies and recorded
+	// for --write-back, with --write-back-only leaving the cluster unchanged
+	for _, patch := range kset.CalculatePatches(infos, scheme.DefaultJSONEncoder(), kset.PatchFn(o.Approver)) {
 		info := patch.Info
 
 		if patch.Err != nil {
@@ -182,6 +194,15 @@ func (o ApproveOptions) RunApprove() error {
 			continue
 		}
 
+		// the snapshot is taken before the step is approved
+		var snapshot *util.PromotionSnapshot
+		if o.Snapshot.Snapshot {
+			if snapshot, err = o.Snapshot.Take("approve", info.Object); err != nil {
+				allErrs = append(allErrs, fmt.Errorf("failed to take a snapshot of %s, not approved: %v", info.ObjectName(), err))
+				continue
+			}
+		}
+
 		obj, err := util.PatchSubResource(info.Client, info.Mapping.Resource.Resource, "status", info.Namespace, info.Name, info.Namespaced(), types.MergePatchType, patch.Patch, nil)
 		if err != nil {
 			allErrs = append(allErrs, fmt.Errorf("failed to patch: %v", err))
@@ -189,6 +210,11 @@ func (o ApproveOptions) RunApprove() error {
 		}
 
 		info.Refresh(obj, true)
+		if snapshot != nil {
+			if err := o.Snapshot.Record(info, snapshot); err != nil {
+				allErrs = append(allErrs, fmt.Errorf("%s approved but recording its snapshot failed: %v", info.ObjectName(), err))
+			}
+		}
 		printer, err := o.ToPrinter("approved")
 		if err != nil {
 			allErrs = append(allErrs, err)
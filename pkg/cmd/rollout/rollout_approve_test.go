@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/openkruise/kruise-tools/pkg/writeback"
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest/fake"
+	"k8s.io/client-go/restmapper"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const rolloutManifest = `apiVersion: rollouts.kruise.io/v1alpha1
+kind: Rollout
+metadata:
+  name: demo
+  namespace: default
+spec:
+  objectRef:
+    workloadRef:
+      apiVersion: apps.kruise.io/v1alpha1
+      kind: CloneSet
+      name: demo
+`
+
+func TestRolloutApproveWriteBackOnly(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo, err := ioutil.TempDir("", "approve-writeback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repo)
+	if err := ioutil.WriteFile(filepath.Join(repo, "rollout.yaml"), []byte(rolloutManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"config", "user.name", "test"}, {"config", "user.email", "test@example.com"}, {"add", "."}, {"commit", "-q", "-m", "init"}} {
+		if output, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+
+	rollout := &kruiserolloutsv1apha1.Rollout{
+		TypeMeta:   metav1.TypeMeta{APIVersion: kruiserolloutsv1apha1.GroupVersion.String(), Kind: "Rollout"},
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"},
+		Status: kruiserolloutsv1apha1.RolloutStatus{CanaryStatus: &kruiserolloutsv1apha1.CanaryStatus{
+			CurrentStepIndex: 1,
+			CurrentStepState: kruiserolloutsv1apha1.CanaryStepStatePaused,
+		}},
+	}
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewRolloutApproveOptions(streams)
+	o.Resources = []string{"rollout/demo"}
+	o.Namespace = "default"
+	o.Approver = internalpolymorphichelpers.ObjectApproverFn
+	o.ToPrinter = func(operation string) (printers.ResourcePrinter, error) {
+		o.PrintFlags.NamePrintFlags.Operation = operation
+		return o.PrintFlags.ToPrinter()
+	}
+	o.Builder = readOnlyRolloutBuilder(t, rollout, "with --write-back-only")
+
+	cmd := writeback.WithWriteBack(&cobra.Command{
+		Use: "approve",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.RunApprove())
+		},
+	}, out)
+	cmd.Flags().Set("write-back", repo)
+	cmd.Flags().Set("write-back-only", "true")
+	cmd.Run(cmd, []string{"rollout/demo"})
+
+	if !strings.Contains(out.String(), "changes committed to "+repo) {
+		t.Errorf("expected the approval to be committed, got %q", out.String())
+	}
+	manifest, err := ioutil.ReadFile(filepath.Join(repo, "rollout.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(manifest), "currentStepState: "+string(kruiserolloutsv1apha1.CanaryStepStateCompleted)) {
+		t.Errorf("expected the approved step in the manifest, got\n%s", manifest)
+	}
+}
+
+// readOnlyRolloutBuilder returns a builder serving rollout, which fails the test on any request
+// other than a GET, explained by reason.
+func readOnlyRolloutBuilder(t *testing.T, rollout *kruiserolloutsv1apha1.Rollout, reason string) func() *resource.Builder {
+	body, err := json.Marshal(rollout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &fake.RESTClient{
+		GroupVersion:         kruiserolloutsv1apha1.GroupVersion,
+		NegotiatedSerializer: serializer.NewCodecFactory(internalapi.GetScheme()).WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodGet {
+				t.Errorf("unexpected request %s: %s %s", reason, req.Method, req.URL)
+			}
+			header := http.Header{}
+			header.Set("Content-Type", "application/json")
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+		}),
+	}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{kruiserolloutsv1apha1.GroupVersion})
+	mapper.Add(kruiserolloutsv1apha1.GroupVersion.WithKind("Rollout"), meta.RESTScopeNamespace)
+	return func() *resource.Builder {
+		return resource.NewFakeBuilder(
+			func(schema.GroupVersion) (resource.RESTClient, error) { return client, nil },
+			func() (meta.RESTMapper, error) { return mapper, nil },
+			func() (restmapper.CategoryExpander, error) { return restmapper.SimpleCategoryExpander{}, nil },
+		)
+	}
+}
@@ -0,0 +1,355 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	kset "github.com/openkruise/kruise-tools/pkg/cmd/set"
+	"github.com/openkruise/kruise-tools/pkg/cmd/util"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const autopilotSyncPeriod = 5 * time.Second
+
+var (
+	autopilotLong = templates.LongDesc(`
+		Follow a Kruise Rollout through all its steps, approving each paused step once its
+		verification window passed, as a client-side alternative to an external CD system.
+
+		When a step is paused, autopilot waits for --soak, from the time the pods of the step
+		were ready, then runs the gates of the step: the --verify-cmd shell command, which must
+		exit with a zero code, and the --metric-query PromQL query against --prometheus-url,
+		which must return at least one sample and only non-zero samples, as the comparisons of
+		PromQL return. The step is approved once they passed. The verify command gets the
+		rollout in its environment: KRUISE_WORKLOAD_KIND, KRUISE_WORKLOAD_NAMESPACE,
+		KRUISE_WORKLOAD_NAME, KRUISE_WORKLOAD_REVISION, KRUISE_UPDATED_REPLICAS and
+		KRUISE_ROLLOUT_STEP.
+
+		If a gate fails, the Rollout is paused, or with --on-failure=abort, also the workload
+		is rolled back to its previous revision, and autopilot exits with a non-zero code. It
+		exits once the Rollout is healthy, or fails if it is cancelled or rolled back.`)
+
+	autopilotExample = templates.Examples(`
+		# Approve each step of rollout demo after 10 minutes
+		kubectl-kruise rollout autopilot rollout/demo --soak 10m
+
+		# Approve each step once the error ratio is under 1% after 5 minutes, rolling back otherwise
+		kubectl-kruise rollout autopilot rollout/demo --soak 5m --on-failure abort \
+		  --prometheus-url http://prometheus:9090 \
+		  --metric-query 'sum(rate(http_errors{app="demo"}[5m])) / sum(rate(http_requests{app="demo"}[5m])) < 0.01'`)
+)
+
+// RolloutAutopilotOptions holds the options for 'rollout autopilot' sub command
+type RolloutAutopilotOptions struct {
+	Resources     []string
+	Soak          time.Duration
+	VerifyCmd     string
+	PrometheusURL string
+	MetricQuery   string
+	GateTimeout   time.Duration
+	OnFailure     string
+	Timeout       time.Duration
+
+	Builder          func() *resource.Builder
+	Approver         internalpolymorphichelpers.ObjectApproverFunc
+	RESTClientGetter genericclioptions.RESTClientGetter
+	HTTPClient       *http.Client
+	Namespace        string
+	EnforceNamespace bool
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NewRolloutAutopilotOptions returns an initialized RolloutAutopilotOptions instance
+func NewRolloutAutopilotOptions(streams genericclioptions.IOStreams) *RolloutAutopilotOptions {
+	return &RolloutAutopilotOptions{
+		Soak:        5 * time.Minute,
+		GateTimeout: time.Minute,
+		OnFailure:   VerifyFailurePause,
+		HTTPClient:  http.DefaultClient,
+		IOStreams:   streams,
+	}
+}
+
+// NewCmdRolloutAutopilot returns a Command instance for 'rollout autopilot' sub command
+func NewCmdRolloutAutopilot(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRolloutAutopilotOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "autopilot RESOURCE [--soak=DURATION] [--verify-cmd=COMMAND] [--metric-query=QUERY --prometheus-url=URL]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Follow a Rollout, approving its steps once their verification passed"),
+		Long:                  autopilotLong,
+		Example:               autopilotExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+		ValidArgs: []string{"rollout"},
+	}
+
+	usage := "identifying the rollout to follow."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().DurationVar(&o.Soak, "soak", o.Soak, "The time to wait after the pods of a paused step are ready before verifying it.")
+	cmd.Flags().StringVar(&o.VerifyCmd, "verify-cmd", o.VerifyCmd, "A shell command verifying a paused step, which must exit with a zero code for the step to be approved.")
+	cmd.Flags().StringVar(&o.PrometheusURL, "prometheus-url", o.PrometheusURL, "The URL of the Prometheus server --metric-query is run against.")
+	cmd.Flags().StringVar(&o.MetricQuery, "metric-query", o.MetricQuery, "A PromQL query verifying a paused step, which must return only non-zero samples for the step to be approved.")
+	cmd.Flags().DurationVar(&o.GateTimeout, "gate-timeout", o.GateTimeout, "The time after which the verify command or the metric query fails, zero means no timeout.")
+	cmd.Flags().StringVar(&o.OnFailure, "on-failure", o.OnFailure, "What to do when the verification of a step fails, one of: pause, abort.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The time after which autopilot gives up, zero means no timeout.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *RolloutAutopilotOptions) Complete(f cmdutil.Factory, args []string) error {
+	o.Resources = args
+	o.Builder = f.NewBuilder
+	o.Approver = internalpolymorphichelpers.ObjectApproverFn
+	o.RESTClientGetter = f
+
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *RolloutAutopilotOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if o.Soak < 0 {
+		return fmt.Errorf("--soak must not be negative")
+	}
+	if (len(o.MetricQuery) > 0) != (len(o.PrometheusURL) > 0) {
+		return fmt.Errorf("--metric-query and --prometheus-url must be given together")
+	}
+	if o.OnFailure != VerifyFailurePause && o.OnFailure != VerifyFailureAbort {
+		return fmt.Errorf("invalid --on-failure %q, must be one of: %s, %s", o.OnFailure, VerifyFailurePause, VerifyFailureAbort)
+	}
+	return nil
+}
+
+// Run performs the execution of 'rollout autopilot' sub command
+func (o *RolloutAutopilotOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return err
+	}
+	if len(infos) != 1 {
+		return fmt.Errorf("autopilot follows a single rollout, got %d resources", len(infos))
+	}
+	info := infos[0]
+	if _, ok := info.Object.(*kruiserolloutsv1apha1.Rollout); !ok {
+		return internalpolymorphichelpers.NewUnsupportedKindError("autopilot", info.Object, schema.GroupKind{Group: "rollouts.kruise.io", Kind: "Rollout"})
+	}
+	return o.follow(info)
+}
+
+// follow approves the paused steps of the rollout of info once verified, until it is healthy.
+func (o *RolloutAutopilotOptions) follow(info *resource.Info) error {
+	helper := resource.NewHelper(info.Client, info.Mapping)
+	var lastState string
+	verified := int32(0)
+
+	condition := func() (bool, error) {
+		obj, err := helper.Get(info.Namespace, info.Name)
+		if err != nil {
+			return false, err
+		}
+		info.Refresh(obj, true)
+		rollout := info.Object.(*kruiserolloutsv1apha1.Rollout)
+
+		done, err := rolloutDone(rollout)
+		if done || err != nil {
+			if done {
+				fmt.Fprintf(o.Out, "%s is healthy, roll out complete\n", info.ObjectName())
+			}
+			return done, err
+		}
+		status := rollout.Status.CanaryStatus
+		if status == nil {
+			return false, nil
+		}
+		steps := 0
+		if rollout.Spec.Strategy.Canary != nil {
+			steps = len(rollout.Spec.Strategy.Canary.Steps)
+		}
+		if state := fmt.Sprintf("step %d/%d %s", status.CurrentStepIndex, steps, status.CurrentStepState); state != lastState {
+			fmt.Fprintf(o.Out, "%s: %s, %d canary pods ready out of %d\n", info.ObjectName(), state, status.CanaryReadyReplicas, status.CanaryReplicas)
+			lastState = state
+		}
+		if status.CurrentStepState != kruiserolloutsv1apha1.CanaryStepStatePaused {
+			return false, nil
+		}
+
+		if readyAt := pausedSince(status); time.Since(readyAt) < o.Soak {
+			return false, nil
+		}
+		if verified != status.CurrentStepIndex {
+			if err := o.verify(info, rollout); err != nil {
+				return false, o.fail(info, rollout, err)
+			}
+			verified = status.CurrentStepIndex
+		}
+		if err := o.approve(info); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(o.Out, "%s: step %d/%d approved\n", info.ObjectName(), status.CurrentStepIndex, steps)
+		return false, nil
+	}
+
+	if o.Timeout > 0 {
+		err := wait.PollImmediate(autopilotSyncPeriod, o.Timeout, condition)
+		if err == wait.ErrWaitTimeout {
+			return fmt.Errorf("timed out after %s following %s", o.Timeout, info.ObjectName())
+		}
+		return err
+	}
+	return wait.PollImmediateInfinite(autopilotSyncPeriod, condition)
+}
+
+// rolloutDone returns true once rollout is healthy, and an error if it was cancelled or rolled back.
+func rolloutDone(rollout *kruiserolloutsv1apha1.Rollout) (bool, error) {
+	switch rollout.Status.Phase {
+	case kruiserolloutsv1apha1.RolloutPhaseHealthy, kruiserolloutsv1apha1.RolloutPhaseCompleted:
+		return rollout.Status.CanaryStatus == nil || rollout.Status.CanaryStatus.CurrentStepState == kruiserolloutsv1apha1.CanaryStepStateCompleted, nil
+	case kruiserolloutsv1apha1.RolloutPhaseCancelled, kruiserolloutsv1apha1.RolloutPhaseRollback:
+		return false, fmt.Errorf("rollout %s/%s is %s: %s", rollout.Namespace, rollout.Name, strings.ToLower(string(rollout.Status.Phase)), rollout.Status.Message)
+	}
+	return false, nil
+}
+
+// pausedSince returns the time the pods of the current step were ready, or now if unknown.
+func pausedSince(status *kruiserolloutsv1apha1.CanaryStatus) time.Time {
+	if status.LastUpdateTime == nil {
+		return time.Now()
+	}
+	return status.LastUpdateTime.Time
+}
+
+// verify runs the gates of the paused step of rollout.
+func (o *RolloutAutopilotOptions) verify(info *resource.Info, rollout *kruiserolloutsv1apha1.Rollout) error {
+	status := rollout.Status.CanaryStatus
+	if len(o.VerifyCmd) > 0 {
+		step := VerifyStep{
+			Kind:      "Rollout",
+			Namespace: rollout.Namespace,
+			Name:      rollout.Name,
+			Revision:  status.CanaryRevision,
+			Updated:   status.CanaryReplicas,
+			Step:      status.CurrentStepIndex,
+		}
+		fmt.Fprintf(o.Out, "%s: verifying step %d...\n", info.ObjectName(), status.CurrentStepIndex)
+		if err := runVerifyCommand(o.VerifyCmd, step, o.GateTimeout, o.Out, o.ErrOut); err != nil {
+			return fmt.Errorf("verify command failed: %v", err)
+		}
+	}
+	if len(o.MetricQuery) > 0 {
+		if err := runMetricQuery(o.HTTPClient, o.PrometheusURL, o.MetricQuery, o.GateTimeout); err != nil {
+			return fmt.Errorf("metric query failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// approve completes the paused step of the rollout of info.
+func (o *RolloutAutopilotOptions) approve(info *resource.Info) error {
+	patch := &kset.Patch{Info: info}
+	kset.CalculatePatch(patch, scheme.DefaultJSONEncoder(), kset.PatchFn(o.Approver))
+	if patch.Err != nil {
+		return patch.Err
+	}
+	obj, err := util.PatchSubResource(info.Client, info.Mapping.Resource.Resource, "status", info.Namespace, info.Name, info.Namespaced(), types.MergePatchType, patch.Patch, nil)
+	if err != nil {
+		return fmt.Errorf("failed to approve: %v", err)
+	}
+	return info.Refresh(obj, true)
+}
+
+// fail pauses the rollout of info after its verification failed with cause, and with
+// --on-failure=abort rolls its workload back, and returns the error autopilot exits with.
+func (o *RolloutAutopilotOptions) fail(info *resource.Info, rollout *kruiserolloutsv1apha1.Rollout, cause error) error {
+	step := rollout.Status.CanaryStatus.CurrentStepIndex
+	if _, err := resource.NewHelper(info.Client, info.Mapping).Patch(info.Namespace, info.Name, types.MergePatchType, []byte(`{"spec":{"strategy":{"paused":true}}}`), nil); err != nil {
+		return fmt.Errorf("verification of step %d of %s failed: %v, and pausing it failed: %v", step, info.ObjectName(), cause, err)
+	}
+	if o.OnFailure != VerifyFailureAbort {
+		return fmt.Errorf("verification of step %d of %s failed: %v, rollout paused", step, info.ObjectName(), cause)
+	}
+
+	workload, err := o.workload(rollout)
+	if err == nil {
+		var rollbacker internalpolymorphichelpers.Rollbacker
+		if rollbacker, err = internalpolymorphichelpers.RollbackerFn(o.RESTClientGetter, workload.Mapping); err == nil {
+			_, err = rollbacker.Rollback(workload.Object, nil, 0, cmdutil.DryRunNone)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("verification of step %d of %s failed: %v, rollout paused but rolling back its workload failed: %v", step, info.ObjectName(), cause, err)
+	}
+	return fmt.Errorf("verification of step %d of %s failed: %v, rollout paused and %s rolled back", step, info.ObjectName(), cause, workload.ObjectName())
+}
+
+// workload returns the workload referenced by rollout.
+func (o *RolloutAutopilotOptions) workload(rollout *kruiserolloutsv1apha1.Rollout) (*resource.Info, error) {
+	ref := rollout.Spec.ObjectRef.WorkloadRef
+	if ref == nil {
+		return nil, fmt.Errorf("rollout %s has no workload", rollout.Name)
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(rollout.Namespace).
+		ResourceTypeOrNameArgs(true, fmt.Sprintf("%s.%s.%s/%s", strings.ToLower(ref.Kind), gv.Version, gv.Group, ref.Name)).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return nil, err
+	}
+	return infos[0], nil
+}
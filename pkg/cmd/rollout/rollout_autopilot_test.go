@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"bytes"
+	goruntime "runtime"
+	"testing"
+
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+func TestRolloutDone(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    kruiserolloutsv1apha1.RolloutStatus
+		expected  bool
+		expectErr bool
+	}{
+		{
+			name:     "healthy",
+			status:   kruiserolloutsv1apha1.RolloutStatus{Phase: kruiserolloutsv1apha1.RolloutPhaseHealthy},
+			expected: true,
+		},
+		{
+			name: "last step completed",
+			status: kruiserolloutsv1apha1.RolloutStatus{
+				Phase:        kruiserolloutsv1apha1.RolloutPhaseHealthy,
+				CanaryStatus: &kruiserolloutsv1apha1.CanaryStatus{CurrentStepState: kruiserolloutsv1apha1.CanaryStepStateCompleted},
+			},
+			expected: true,
+		},
+		{
+			name: "progressing",
+			status: kruiserolloutsv1apha1.RolloutStatus{
+				Phase:        kruiserolloutsv1apha1.RolloutPhaseProgressing,
+				CanaryStatus: &kruiserolloutsv1apha1.CanaryStatus{CurrentStepState: kruiserolloutsv1apha1.CanaryStepStatePaused},
+			},
+		},
+		{
+			name:      "cancelled",
+			status:    kruiserolloutsv1apha1.RolloutStatus{Phase: kruiserolloutsv1apha1.RolloutPhaseCancelled},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		done, err := rolloutDone(&kruiserolloutsv1apha1.Rollout{Status: test.status})
+		if done != test.expected || (err != nil) != test.expectErr {
+			t.Errorf("%s: expected %t and error %t, got %t and %v", test.name, test.expected, test.expectErr, done, err)
+		}
+	}
+}
+
+func TestAutopilotVerify(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("verify commands are run by cmd on windows")
+	}
+	rollout := &kruiserolloutsv1apha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+		Status: kruiserolloutsv1apha1.RolloutStatus{CanaryStatus: &kruiserolloutsv1apha1.CanaryStatus{
+			CanaryRevision:   "demo-abc",
+			CanaryReplicas:   2,
+			CurrentStepIndex: 3,
+			CurrentStepState: kruiserolloutsv1apha1.CanaryStepStatePaused,
+		}},
+	}
+	out := &bytes.Buffer{}
+	o := NewRolloutAutopilotOptions(genericclioptions.IOStreams{Out: out, ErrOut: out})
+	o.VerifyCmd = `echo "$KRUISE_WORKLOAD_KIND/$KRUISE_WORKLOAD_NAME $KRUISE_WORKLOAD_REVISION $KRUISE_UPDATED_REPLICAS $KRUISE_ROLLOUT_STEP"`
+	info := &resource.Info{Name: "demo", Mapping: &meta.RESTMapping{Resource: kruiserolloutsv1apha1.GroupVersion.WithResource("rollouts")}}
+	if err := o.verify(info, rollout); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "rollouts/demo: verifying step 3...\nRollout/demo demo-abc 2 3\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	o.VerifyCmd = "exit 1"
+	if err := o.verify(info, rollout); err == nil {
+		t.Errorf("expected the verification to fail")
+	}
+}
+
+func TestValidateAutopilotFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		modify    func(o *RolloutAutopilotOptions)
+		expectErr bool
+	}{
+		{name: "soak only", modify: func(o *RolloutAutopilotOptions) {}},
+		{
+			name: "metric gate",
+			modify: func(o *RolloutAutopilotOptions) {
+				o.PrometheusURL, o.MetricQuery = "http://prometheus:9090", "up == 1"
+			},
+		},
+		{
+			name:      "metric query without prometheus",
+			modify:    func(o *RolloutAutopilotOptions) { o.MetricQuery = "up == 1" },
+			expectErr: true,
+		},
+		{
+			name:      "unknown failure action",
+			modify:    func(o *RolloutAutopilotOptions) { o.OnFailure = "rollback" },
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		o := NewRolloutAutopilotOptions(genericclioptions.NewTestIOStreamsDiscard())
+		o.Resources = []string{"rollout/demo"}
+		test.modify(o)
+		if err := o.Validate(); (err != nil) != test.expectErr {
+			t.Errorf("%s: expected error %t, got %v", test.name, test.expectErr, err)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	compareLong = templates.LongDesc(i18n.T(`
+		Compare the rollout of the same workloads across clusters.
+
+		The image, the update revision, the partition and the health of the replicas of the
+		workloads are read from each kubeconfig context and printed as a table, with the fields
+		that differ from the first context marked as drift. Use it before promoting a release to
+		check that the canary cluster matches production.`))
+
+	compareExample = templates.Examples(`
+		# Compare a cloneset between the canary and the production clusters
+		kubectl-kruise rollout compare cloneset/abc --contexts canary,production
+
+		# Fail if the advanced statefulsets with the label app=abc drifted
+		kubectl-kruise rollout compare asts -l app=abc --contexts canary,production --fail-on-drift`)
+)
+
+const compareNotFound = "<not found>"
+
+// compareFields are the fields compared by 'rollout compare', in the order they are printed.
+var compareFields = []string{"images", "revision", "partition", "replicas", "ready", "updated"}
+
+// RolloutCompareOptions holds the options for 'rollout compare' sub command
+type RolloutCompareOptions struct {
+	Contexts    []string
+	Selector    string
+	FailOnDrift bool
+
+	Resources  []string
+	Namespace  string
+	NewBuilder func(context string) *resource.Builder
+
+	genericclioptions.IOStreams
+}
+
+// NewRolloutCompareOptions returns an initialized RolloutCompareOptions instance
+func NewRolloutCompareOptions(streams genericclioptions.IOStreams) *RolloutCompareOptions {
+	return &RolloutCompareOptions{IOStreams: streams}
+}
+
+// NewCmdRolloutCompare returns a Command instance for 'rollout compare' sub command
+func NewCmdRolloutCompare(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRolloutCompareOptions(streams)
+
+	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "advanced statefulset"}
+
+	cmd := &cobra.Command{
+		Use:                   "compare (TYPE NAME | TYPE/NAME) --contexts CONTEXT,CONTEXT [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Compare the rollout of a resource across clusters"),
+		Long:                  compareLong,
+		Example:               compareExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+		ValidArgs: validArgs,
+	}
+
+	cmd.Flags().StringSliceVar(&o.Contexts, "contexts", o.Contexts, "The kubeconfig contexts of the clusters to compare, the first one being the reference.")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().BoolVar(&o.FailOnDrift, "fail-on-drift", o.FailOnDrift, "If true, exit with a non-zero code when the workloads drifted across the clusters.")
+
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *RolloutCompareOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	o.Resources = args
+
+	var err error
+	if o.Namespace, _, err = f.ToRawKubeConfigLoader().Namespace(); err != nil {
+		return err
+	}
+
+	kubeConfig := ""
+	if flag := cmd.Flags().Lookup("kubeconfig"); flag != nil {
+		kubeConfig = flag.Value.String()
+	}
+	o.NewBuilder = func(context string) *resource.Builder {
+		flags := genericclioptions.NewConfigFlags(true)
+		flags.KubeConfig = &kubeConfig
+		flags.Context = &context
+		return cmdutil.NewFactory(internalcmdutil.WithKruiseShortNames(flags)).NewBuilder()
+	}
+	return nil
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *RolloutCompareOptions) Validate() error {
+	if len(o.Resources) == 0 {
+		return fmt.Errorf("required resource not specified")
+	}
+	if len(o.Contexts) < 2 {
+		return fmt.Errorf("at least two contexts must be specified with --contexts")
+	}
+	seen := map[string]bool{}
+	for _, context := range o.Contexts {
+		if len(context) == 0 {
+			return fmt.Errorf("--contexts must not contain empty contexts")
+		}
+		if seen[context] {
+			return fmt.Errorf("context %q is specified more than once in --contexts", context)
+		}
+		seen[context] = true
+	}
+	return nil
+}
+
+// Run performs the execution of 'rollout compare' sub command
+func (o *RolloutCompareOptions) Run() error {
+	workloads := map[string]map[string]map[string]string{}
+	for _, context := range o.Contexts {
+		r := o.NewBuilder(context).
+			WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+			NamespaceParam(o.Namespace).DefaultNamespace().
+			LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(true, o.Resources...).
+			ContinueOnError().
+			Latest().
+			Flatten().
+			Do()
+		err := r.Visit(func(info *resource.Info, err error) error {
+			if err != nil {
+				return err
+			}
+			info.Object.GetObjectKind().SetGroupVersionKind(info.Mapping.GroupVersionKind)
+			name := fmt.Sprintf("%s/%s", strings.ToLower(info.Mapping.GroupVersionKind.Kind), info.Name)
+			if workloads[name] == nil {
+				workloads[name] = map[string]map[string]string{}
+			}
+			workloads[name][context] = CompareFieldsForObject(info.Object)
+			return nil
+		})
+		// the workloads missing from a context are printed as drift
+		if err := utilerrors.FilterOut(err, apierrors.IsNotFound); err != nil {
+			return fmt.Errorf("context %s: %v", context, err)
+		}
+	}
+	if len(workloads) == 0 {
+		return fmt.Errorf("no resources found in the contexts %s", strings.Join(o.Contexts, ", "))
+	}
+
+	drifted, err := PrintComparison(o.Out, o.Contexts, workloads)
+	if err != nil {
+		return err
+	}
+	if drifted > 0 && o.FailOnDrift {
+		return fmt.Errorf("%d workload(s) drifted across the contexts %s", drifted, strings.Join(o.Contexts, ", "))
+	}
+	return nil
+}
+
+// CompareFieldsForObject returns the compared fields of a workload by name.
+func CompareFieldsForObject(obj runtime.Object) map[string]string {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return map[string]string{}
+		}
+		u = &unstructured.Unstructured{Object: data}
+	}
+
+	state := internalcmdutil.ResourceStateFor(u)
+	fields := map[string]string{
+		"images":    imagesForObject(u),
+		"revision":  state.Revision,
+		"partition": "-",
+		"replicas":  formatCount(state.Replicas),
+		"ready":     formatCount(state.ReadyReplicas),
+		"updated":   formatCount(state.UpdatedReplicas),
+	}
+	if len(fields["revision"]) == 0 {
+		fields["revision"] = "-"
+	}
+	if typed, err := internalapi.GetScheme().New(u.GroupVersionKind()); err == nil {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err == nil {
+			if _, partition, _, ok := internalpolymorphichelpers.PartitionForObject(typed); ok {
+				fields["partition"] = fmt.Sprint(partition)
+			}
+		}
+	}
+	return fields
+}
+
+// imagesForObject returns the images of the containers of the pod template of a workload, as
+// name=image pairs sorted by container name.
+func imagesForObject(u *unstructured.Unstructured) string {
+	containers, _, _ := unstructured.NestedSlice(u.Object, "spec", "template", "spec", "containers")
+	var images []string
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		images = append(images, fmt.Sprintf("%v=%v", container["name"], container["image"]))
+	}
+	if len(images) == 0 {
+		return "-"
+	}
+	sort.Strings(images)
+	return strings.Join(images, ",")
+}
+
+func formatCount(count *int64) string {
+	if count == nil {
+		return "-"
+	}
+	return fmt.Sprint(*count)
+}
+
+// PrintComparison prints the compared fields of workloads by name and context as a table, one
+// row per field, with the fields that differ from the first context marked as drift, and returns
+// the number of drifted workloads. Workloads missing from a context drifted.
+func PrintComparison(out io.Writer, contexts []string, workloads map[string]map[string]map[string]string) (int, error) {
+	var names []string
+	for name := range workloads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := internalcmdutil.NewTableWriter(out)
+	fmt.Fprintf(w, "NAME\tFIELD\t%s\tDRIFT\n", strings.ToUpper(strings.Join(contexts, "\t")))
+	drifted := 0
+	for _, name := range names {
+		workloadDrifted := false
+		for _, field := range compareFields {
+			values := make([]string, len(contexts))
+			drift := ""
+			for i, context := range contexts {
+				values[i] = compareNotFound
+				if fields, ok := workloads[name][context]; ok {
+					values[i] = fields[field]
+				}
+				if values[i] != values[0] {
+					drift = "*"
+				}
+			}
+			workloadDrifted = workloadDrifted || len(drift) > 0
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, field, strings.Join(values, "\t"), drift)
+		}
+		if workloadDrifted {
+			drifted++
+		}
+	}
+	return drifted, w.Flush()
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+func newCompareCloneSet(image string, partition int) *kruiseappsv1alpha1.CloneSet {
+	cs := &kruiseappsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo", Generation: 1}}
+	cs.SetGroupVersionKind(kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet"))
+	cs.Spec.Replicas = pointer.Int32Ptr(4)
+	value := intstr.FromInt(partition)
+	cs.Spec.UpdateStrategy.Partition = &value
+	cs.Spec.Template.Spec.Containers = []corev1.Container{{Name: "sidecar", Image: "busybox"}, {Name: "main", Image: image}}
+	cs.Status = kruiseappsv1alpha1.CloneSetStatus{ObservedGeneration: 1, Replicas: 4, ReadyReplicas: 4, UpdatedReplicas: 4 - int32(partition), UpdateRevision: "demo-" + image[len(image)-1:]}
+	return cs
+}
+
+func TestCompareFieldsForObject(t *testing.T) {
+	expected := map[string]string{
+		"images":    "main=nginx:2,sidecar=busybox",
+		"revision":  "demo-2",
+		"partition": "1",
+		"replicas":  "4",
+		"ready":     "4",
+		"updated":   "3",
+	}
+	if fields := CompareFieldsForObject(newCompareCloneSet("nginx:2", 1)); !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected %v, got %v", expected, fields)
+	}
+}
+
+func TestPrintComparison(t *testing.T) {
+	workloads := map[string]map[string]map[string]string{
+		"cloneset/demo": {
+			"canary":     CompareFieldsForObject(newCompareCloneSet("nginx:2", 0)),
+			"production": CompareFieldsForObject(newCompareCloneSet("nginx:1", 0)),
+		},
+		"cloneset/same": {
+			"canary":     CompareFieldsForObject(newCompareCloneSet("nginx:1", 0)),
+			"production": CompareFieldsForObject(newCompareCloneSet("nginx:1", 0)),
+		},
+		"cloneset/new": {
+			"canary": CompareFieldsForObject(newCompareCloneSet("nginx:2", 0)),
+		},
+	}
+	out := &bytes.Buffer{}
+	drifted, err := PrintComparison(out, []string{"canary", "production"}, workloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drifted != 2 {
+		t.Errorf("expected 2 drifted workloads, got %d:\n%s", drifted, out.String())
+	}
+
+	rows := map[string][]string{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		fields := strings.Fields(line)
+		rows[fields[0]+" "+fields[1]] = fields[2:]
+	}
+	for row, expected := range map[string][]string{
+		"NAME FIELD":              {"CANARY", "PRODUCTION", "DRIFT"},
+		"cloneset/demo images":    {"main=nginx:2,sidecar=busybox", "main=nginx:1,sidecar=busybox", "*"},
+		"cloneset/demo revision":  {"demo-2", "demo-1", "*"},
+		"cloneset/demo partition": {"0", "0"},
+		"cloneset/same images":    {"main=nginx:1,sidecar=busybox", "main=nginx:1,sidecar=busybox"},
+		"cloneset/new replicas":   {"4", "<not", "found>", "*"},
+	} {
+		if !reflect.DeepEqual(rows[row], expected) {
+			t.Errorf("expected row %q to be %v, got %v", row, expected, rows[row])
+		}
+	}
+}
+
+func TestRolloutCompareValidate(t *testing.T) {
+	testCases := []struct {
+		contexts []string
+		expected string
+	}{
+		{contexts: []string{"canary"}, expected: "at least two contexts"},
+		{contexts: []string{"canary", ""}, expected: "empty contexts"},
+		{contexts: []string{"canary", "canary"}, expected: "more than once"},
+		{contexts: []string{"canary", "production"}},
+	}
+	for _, tc := range testCases {
+		o := &RolloutCompareOptions{Contexts: tc.contexts, Resources: []string{"cloneset/demo"}}
+		err := o.Validate()
+		if len(tc.expected) == 0 && err != nil || len(tc.expected) > 0 && (err == nil || !strings.Contains(err.Error(), tc.expected)) {
+			t.Errorf("contexts %v: expected error %q, got %v", tc.contexts, tc.expected, err)
+		}
+	}
+}
@@ -45,7 +45,10 @@ var (
 		kubectl-kruise rollout history asts/abc
 
 		# View the details of daemonset revision 3
-		kubectl-kruise rollout history daemonset/abc --revision=3`)
+		kubectl-kruise rollout history daemonset/abc --revision=3
+
+		# Export the rollout history of a cloneset to the directory archive
+		kubectl-kruise rollout history export cloneset/abc -o archive/`)
 )
 
 // RolloutHistoryOptions holds the options for 'rollout history' sub command
@@ -102,6 +105,9 @@ func NewCmdRolloutHistory(f cmdutil.Factory, streams genericclioptions.IOStreams
 
 	o.PrintFlags.AddFlags(cmd)
 
+	cmd.AddCommand(NewCmdRolloutHistoryExport(f, streams))
+	cmd.AddCommand(NewCmdRolloutHistoryImport(f, streams))
+
 	return cmd
 }
 
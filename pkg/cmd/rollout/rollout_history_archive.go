@@ -0,0 +1,410 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const podTemplateFileSuffix = "-pod-template.yaml"
+
+var (
+	historyExportLong = templates.LongDesc(i18n.T(`
+		Export the rollout history of workloads to a directory, for archiving.
+
+		Each revision is written to DIR/NAMESPACE/RESOURCE/NAME as two files: revision-N.yaml holds
+		its ControllerRevision, with its labels, annotations and owner, and revision-N-pod-template.yaml
+		holds the full pod template it rolls out. Only the kinds that keep their history in
+		ControllerRevisions are supported, which excludes deployments.`))
+
+	historyExportExample = templates.Examples(`
+		# Export the rollout history of cloneset demo to the directory archive
+		kubectl-kruise rollout history export cloneset/demo -o archive/
+
+		# Export the rollout history of all advanced statefulsets
+		kubectl-kruise rollout history export asts -o archive/`)
+
+	historyImportLong = templates.LongDesc(i18n.T(`
+		Recreate the ControllerRevisions exported by 'rollout history export', e.g. in a recovery cluster.
+
+		The revisions are owned by their workloads again if these already exist, otherwise they are
+		created without owner and adopted by the controllers once the workloads are created. Revisions
+		that already exist are left unchanged.`))
+
+	historyImportExample = templates.Examples(`
+		# Recreate the revisions exported to the directory archive
+		kubectl-kruise rollout history import archive/
+
+		# Recreate the exported revisions in namespace recovery
+		kubectl-kruise rollout history import archive/ -n recovery`)
+)
+
+// RolloutHistoryExportOptions holds the options for 'rollout history export' sub command
+type RolloutHistoryExportOptions struct {
+	OutputDir string
+
+	Builder          func() *resource.Builder
+	Resources        []string
+	Namespace        string
+	EnforceNamespace bool
+
+	Client       kubernetes.Interface
+	KruiseClient kruiseclientsets.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NewRolloutHistoryExportOptions returns an initialized RolloutHistoryExportOptions instance
+func NewRolloutHistoryExportOptions(streams genericclioptions.IOStreams) *RolloutHistoryExportOptions {
+	return &RolloutHistoryExportOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdRolloutHistoryExport returns a Command instance for 'rollout history export' sub command
+func NewCmdRolloutHistoryExport(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRolloutHistoryExportOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "export (TYPE NAME | TYPE/NAME) -o DIR",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Export the rollout history of workloads to a directory"),
+		Long:                  historyExportLong,
+		Example:               historyExportExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringVarP(&o.OutputDir, "output-dir", "o", o.OutputDir, "The directory to write the revisions to. It is created if it does not exist.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *RolloutHistoryExportOptions) Complete(f cmdutil.Factory, args []string) error {
+	o.Resources = args
+
+	var err error
+	if o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace(); err != nil {
+		return err
+	}
+	o.Builder = f.NewBuilder
+
+	o.Client, err = f.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	clientConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig)
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *RolloutHistoryExportOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if len(o.OutputDir) == 0 {
+		return fmt.Errorf("an output directory must be given with -o")
+	}
+	return nil
+}
+
+// Run performs the execution of 'rollout history export' sub command
+func (o *RolloutHistoryExportOptions) Run() error {
+	r := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		ContinueOnError().
+		Latest().
+		Flatten().
+		Do()
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	return r.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		return o.export(info)
+	})
+}
+
+// export writes the revisions of the workload of info to the output directory.
+func (o *RolloutHistoryExportOptions) export(info *resource.Info) error {
+	revisions, err := internalpolymorphichelpers.RevisionTemplatesFor(info.Mapping.GroupVersionKind.GroupKind(), o.Client, o.KruiseClient, info.Namespace, info.Name)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(o.OutputDir, info.Namespace, info.Mapping.Resource.GroupResource().String(), info.Name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, revision := range revisions {
+		history := revision.Revision.DeepCopy()
+		history.TypeMeta = metav1.TypeMeta{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "ControllerRevision"}
+		history.ResourceVersion = ""
+		history.SelfLink = ""
+		history.ManagedFields = nil
+
+		template := &corev1.PodTemplate{
+			TypeMeta: metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "PodTemplate"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              history.Name,
+				Namespace:         history.Namespace,
+				Labels:            history.Labels,
+				Annotations:       history.Annotations,
+				CreationTimestamp: history.CreationTimestamp,
+			},
+			Template: *revision.Template,
+		}
+
+		name := filepath.Join(dir, fmt.Sprintf("revision-%d", history.Revision))
+		if err := writeYAML(name+".yaml", history); err != nil {
+			return err
+		}
+		if err := writeYAML(name+podTemplateFileSuffix, template); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(o.Out, "%s/%s: %d revisions exported to %s\n", info.Mapping.Resource.GroupResource().String(), info.Name, len(revisions), dir)
+	return nil
+}
+
+// writeYAML writes obj to the file name in YAML.
+func writeYAML(name string, obj runtime.Object) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return (&printers.YAMLPrinter{}).PrintObj(obj, file)
+}
+
+// RolloutHistoryImportOptions holds the options for 'rollout history import' sub command
+type RolloutHistoryImportOptions struct {
+	Dir              string
+	Namespace        string
+	EnforceNamespace bool
+
+	Client        kubernetes.Interface
+	DynamicClient dynamic.Interface
+	Mapper        meta.RESTMapper
+
+	genericclioptions.IOStreams
+}
+
+// NewRolloutHistoryImportOptions returns an initialized RolloutHistoryImportOptions instance
+func NewRolloutHistoryImportOptions(streams genericclioptions.IOStreams) *RolloutHistoryImportOptions {
+	return &RolloutHistoryImportOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdRolloutHistoryImport returns a Command instance for 'rollout history import' sub command
+func NewCmdRolloutHistoryImport(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRolloutHistoryImportOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "import DIR",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Recreate the exported ControllerRevisions of workloads"),
+		Long:                  historyImportLong,
+		Example:               historyImportExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *RolloutHistoryImportOptions) Complete(f cmdutil.Factory, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("exactly one directory must be specified")
+	}
+	o.Dir = args[0]
+
+	var err error
+	if o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace(); err != nil {
+		return err
+	}
+	o.Client, err = f.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	o.DynamicClient, err = f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	o.Mapper, err = f.ToRESTMapper()
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *RolloutHistoryImportOptions) Validate() error {
+	if info, err := os.Stat(o.Dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", o.Dir)
+	}
+	return nil
+}
+
+// Run performs the execution of 'rollout history import' sub command
+func (o *RolloutHistoryImportOptions) Run() error {
+	revisions, err := readRevisions(o.Dir)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		return fmt.Errorf("no ControllerRevisions found in %s", o.Dir)
+	}
+	for _, revision := range revisions {
+		if err := o.importRevision(revision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importRevision creates revision in the namespace given with -n, or else in its own namespace, owned
+// by its workload if it exists.
+func (o *RolloutHistoryImportOptions) importRevision(revision *appsv1.ControllerRevision) error {
+	revision = revision.DeepCopy()
+	if o.EnforceNamespace || len(revision.Namespace) == 0 {
+		revision.Namespace = o.Namespace
+	}
+	revision.UID = ""
+	revision.ResourceVersion = ""
+	revision.CreationTimestamp = metav1.Time{}
+	revision.ManagedFields = nil
+
+	var orphaned []string
+	owners := revision.OwnerReferences[:0]
+	for _, ref := range revision.OwnerReferences {
+		uid, err := o.ownerUID(revision.Namespace, ref)
+		if apierrors.IsNotFound(err) {
+			orphaned = append(orphaned, fmt.Sprintf("%s/%s", strings.ToLower(ref.Kind), ref.Name))
+			continue
+		} else if err != nil {
+			return err
+		}
+		ref.UID = uid
+		owners = append(owners, ref)
+	}
+	revision.OwnerReferences = owners
+
+	_, err := o.Client.AppsV1().ControllerRevisions(revision.Namespace).Create(context.TODO(), revision, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		fmt.Fprintf(o.Out, "controllerrevision/%s unchanged\n", revision.Name)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if len(orphaned) > 0 {
+		fmt.Fprintf(o.Out, "controllerrevision/%s created without owner, %s not found\n", revision.Name, strings.Join(orphaned, ", "))
+		return nil
+	}
+	fmt.Fprintf(o.Out, "controllerrevision/%s created\n", revision.Name)
+	return nil
+}
+
+// ownerUID returns the UID of the owner referenced by ref in namespace.
+func (o *RolloutHistoryImportOptions) ownerUID(namespace string, ref metav1.OwnerReference) (types.UID, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return "", err
+	}
+	mapping, err := o.Mapper.RESTMapping(schema.GroupKind{Group: gv.Group, Kind: ref.Kind}, gv.Version)
+	if err != nil {
+		return "", err
+	}
+	owner, err := o.DynamicClient.Resource(mapping.Resource).Namespace(namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return owner.GetUID(), nil
+}
+
+// readRevisions returns the ControllerRevisions in the YAML files under dir, ignoring the other objects.
+func readRevisions(dir string) ([]*appsv1.ControllerRevision, error) {
+	var revisions []*appsv1.ControllerRevision
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".yaml" || strings.HasSuffix(path, podTemplateFileSuffix) {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(data, nil, nil)
+		if err != nil {
+			return fmt.Errorf("unable to decode %s: %v", path, err)
+		}
+		if revision, ok := obj.(*appsv1.ControllerRevision); ok {
+			revisions = append(revisions, revision)
+		}
+		return nil
+	})
+	return revisions, err
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var cloneSetGVK = kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet")
+
+func newArchivedRevision(cs *kruiseappsv1alpha1.CloneSet, revision int64, image string) *appsv1.ControllerRevision {
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       cs.Namespace,
+			Name:            fmt.Sprintf("%s-%d", cs.Name, revision),
+			Labels:          map[string]string{"app": "demo"},
+			Annotations:     map[string]string{"kubernetes.io/change-cause": "image " + image},
+			ResourceVersion: "42",
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(cs, cloneSetGVK)},
+		},
+		Revision: revision,
+		Data: runtime.RawExtension{Raw: []byte(fmt.Sprintf(
+			`{"spec":{"template":{"$patch":"replace","metadata":{"labels":{"app":"demo"}},"spec":{"containers":[{"name":"main","image":%q}]}}}}`, image))},
+	}
+}
+
+func TestRolloutHistoryExportImport(t *testing.T) {
+	cs := &kruiseappsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo", UID: "uid-1"}}
+	cs.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}}
+
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := &bytes.Buffer{}
+	export := &RolloutHistoryExportOptions{
+		OutputDir:    dir,
+		Client:       fake.NewSimpleClientset(newArchivedRevision(cs, 2, "nginx:2"), newArchivedRevision(cs, 1, "nginx:1")),
+		KruiseClient: kruisefake.NewSimpleClientset(cs),
+		IOStreams:    genericclioptions.IOStreams{Out: out},
+	}
+	info := &resource.Info{Namespace: "default", Name: "demo", Mapping: &meta.RESTMapping{
+		GroupVersionKind: cloneSetGVK,
+		Resource:         kruiseappsv1alpha1.SchemeGroupVersion.WithResource("clonesets"),
+	}}
+	if err := export.export(info); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "clonesets.apps.kruise.io/demo: 2 revisions exported") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	template, err := ioutil.ReadFile(filepath.Join(dir, "default", "clonesets.apps.kruise.io", "demo", "revision-1-pod-template.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"kind: PodTemplate", "image: nginx:1", "kubernetes.io/change-cause: image nginx:1"} {
+		if !strings.Contains(string(template), expected) {
+			t.Errorf("expected the pod template to contain %q, got:\n%s", expected, template)
+		}
+	}
+
+	revisions, err := readRevisions(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Revision != 1 || revisions[1].Revision != 2 {
+		t.Fatalf("expected revisions 1 and 2, got %v", revisions)
+	}
+	if len(revisions[0].ResourceVersion) != 0 {
+		t.Errorf("expected the resource version not to be exported, got %q", revisions[0].ResourceVersion)
+	}
+
+	// the workload has another UID in the recovery cluster
+	recovered := &unstructured.Unstructured{}
+	recovered.SetGroupVersionKind(cloneSetGVK)
+	recovered.SetNamespace("recovery")
+	recovered.SetName("demo")
+	recovered.SetUID("uid-2")
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{kruiseappsv1alpha1.SchemeGroupVersion})
+	mapper.Add(cloneSetGVK, meta.RESTScopeNamespace)
+
+	client := fake.NewSimpleClientset()
+	out.Reset()
+	imports := &RolloutHistoryImportOptions{
+		Namespace:        "recovery",
+		EnforceNamespace: true,
+		Client:           client,
+		DynamicClient:    dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), recovered),
+		Mapper:           mapper,
+		IOStreams:        genericclioptions.IOStreams{Out: out},
+	}
+	for _, revision := range append(revisions, revisions[0]) {
+		if err := imports.importRevision(revision); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected := "controllerrevision/demo-1 created\ncontrollerrevision/demo-2 created\ncontrollerrevision/demo-1 unchanged\n"
+	if out.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, out.String())
+	}
+	created, err := client.AppsV1().ControllerRevisions("recovery").Get(context.TODO(), "demo-2", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if owner := metav1.GetControllerOf(created); owner == nil || owner.UID != "uid-2" {
+		t.Errorf("expected the revision to be owned by uid-2, got %v", owner)
+	}
+
+	// without the workload, the revisions are created without owner
+	out.Reset()
+	imports.Namespace = "other"
+	imports.Client = fake.NewSimpleClientset()
+	if err := imports.importRevision(revisions[0]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "controllerrevision/demo-1 created without owner, cloneset/demo not found\n"; out.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, out.String())
+	}
+	if len(revisions[0].OwnerReferences) != 1 {
+		t.Errorf("expected the read revision not to be changed, got owners %v", revisions[0].OwnerReferences)
+	}
+}
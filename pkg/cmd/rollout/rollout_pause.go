@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	kset "github.com/openkruise/kruise-tools/pkg/cmd/set"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 
 	"github.com/spf13/cobra"
@@ -28,7 +29,6 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
-	"k8s.io/kubectl/pkg/cmd/set"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util/i18n"
@@ -151,7 +151,7 @@ func (o *PauseOptions) RunPause() error {
 		allErrs = append(allErrs, err)
 	}
 
-	for _, patch := range set.CalculatePatches(infos, scheme.DefaultJSONEncoder(), set.PatchFn(o.Pauser)) {
+	for _, patch := range kset.CalculatePatches(infos, scheme.DefaultJSONEncoder(), kset.PatchFn(o.Pauser)) {
 		info := patch.Info
 
 		if patch.Err != nil {
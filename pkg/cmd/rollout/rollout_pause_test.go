@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/openkruise/kruise-tools/pkg/policy"
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+func TestRolloutPausePolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pause-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opa := filepath.Join(dir, "opa")
+	script := "#!/bin/sh\ncat > /dev/null\necho '{\"result\":[{\"expressions\":[{\"value\":[\"rollouts are frozen\"]}]}]}'\n"
+	if err := ioutil.WriteFile(opa, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	policy.Configure(&config.Policy{Rego: []string{"policies/"}, OPA: opa})
+	defer policy.Configure(nil)
+
+	rollout := &kruiserolloutsv1apha1.Rollout{
+		TypeMeta:   metav1.TypeMeta{APIVersion: kruiserolloutsv1apha1.GroupVersion.String(), Kind: "Rollout"},
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"},
+	}
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	o := &PauseOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("paused"),
+		Pauser:     internalpolymorphichelpers.ObjectPauserFn,
+		Builder:    readOnlyRolloutBuilder(t, rollout, "for a change denied by policy"),
+		Namespace:  "default",
+		Resources:  []string{"rollout/demo"},
+		IOStreams:  streams,
+	}
+	o.ToPrinter = func(operation string) (printers.ResourcePrinter, error) {
+		o.PrintFlags.NamePrintFlags.Operation = operation
+		return o.PrintFlags.ToPrinter()
+	}
+
+	err = o.RunPause()
+	if err == nil || !strings.Contains(err.Error(), "denied by policy: rollouts are frozen") {
+		t.Errorf("expected the pause to be denied by policy, got %v", err)
+	}
+}
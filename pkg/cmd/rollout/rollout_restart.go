@@ -22,6 +22,7 @@ import (
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	kset "github.com/openkruise/kruise-tools/pkg/cmd/set"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/types"
@@ -29,7 +30,6 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
-	"k8s.io/kubectl/pkg/cmd/set"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util/i18n"
@@ -210,7 +210,7 @@ func (o RestartOptions) RunRestart() error {
 		return utilerrors.NewAggregate(allErrs)
 
 	default:
-		for _, patch := range set.CalculatePatches(infos, scheme.DefaultJSONEncoder(), set.PatchFn(o.Restarter)) {
+		for _, patch := range kset.CalculatePatches(infos, scheme.DefaultJSONEncoder(), kset.PatchFn(o.Restarter)) {
 			info := patch.Info
 			if patch.Err != nil {
 				resourceString := info.Mapping.Resource.Resource
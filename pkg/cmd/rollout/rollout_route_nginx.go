@@ -0,0 +1,304 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	"github.com/spf13/cobra"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	// the annotations of the canary Ingress the Rollouts controller manages for nginx
+	nginxCanaryAnnotation       = "nginx.ingress.kubernetes.io/canary"
+	nginxCanaryWeightAnnotation = "nginx.ingress.kubernetes.io/canary-weight"
+	nginxCanaryCookieAnnotation = "nginx.ingress.kubernetes.io/canary-by-cookie"
+
+	// the canary Ingress is named after the stable one with this suffix
+	canaryIngressSuffix = "-canary"
+)
+
+var (
+	routeNginxLong = templates.LongDesc(`
+		View or edit the canary Ingress the Rollouts controller manages for a rollout with nginx
+		traffic routing.
+
+		Without flags, the weight and sticky cookie of the canary Ingress are shown next to the
+		weight of the current step of the rollout, to debug mismatches of the controller and the
+		Ingress. --canary-weight and --sticky-cookie edit the annotations of the canary Ingress
+		directly. The controller may change them back when it next routes traffic.`)
+
+	routeNginxExample = templates.Examples(`
+		# Show the canary weight of rollout demo
+		kubectl-kruise rollout route-nginx rollout/demo
+
+		# Route 20% of the traffic, and the requests with cookie canary=always, to the canary pods
+		kubectl-kruise rollout route-nginx rollout/demo --canary-weight 20 --sticky-cookie canary
+
+		# Remove the sticky cookie
+		kubectl-kruise rollout route-nginx rollout/demo --sticky-cookie ""`)
+)
+
+// RouteNginxOptions holds the command-line options for 'rollout route-nginx' sub command
+type RouteNginxOptions struct {
+	Resources        []string
+	Namespace        string
+	EnforceNamespace bool
+
+	CanaryWeight int32
+	StickyCookie string
+	setWeight    bool
+	setCookie    bool
+
+	Builder func() *resource.Builder
+	Client  kubernetes.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NginxRoute is the traffic routing of a rollout by its canary Ingress.
+type NginxRoute struct {
+	Ingress       string
+	CanaryIngress string
+	// Canary is false if the canary Ingress does not exist or is not an nginx canary
+	Canary       bool
+	Weight       string
+	StickyCookie string
+	// ExpectedWeight is the weight of the current step of the rollout, if it is in a step
+	ExpectedWeight *int32
+}
+
+// NewRouteNginxOptions returns an initialized RouteNginxOptions instance
+func NewRouteNginxOptions(streams genericclioptions.IOStreams) *RouteNginxOptions {
+	return &RouteNginxOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdRolloutRouteNginx returns a Command instance for 'rollout route-nginx' sub command
+func NewCmdRolloutRouteNginx(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRouteNginxOptions(streams)
+
+	validArgs := []string{"rollout"}
+
+	cmd := &cobra.Command{
+		Use:                   "route-nginx (TYPE/NAME | TYPE NAME) [--canary-weight=WEIGHT] [--sticky-cookie=COOKIE]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("View or edit the nginx canary Ingress of a rollout"),
+		Long:                  routeNginxLong,
+		Example:               routeNginxExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+		ValidArgs: validArgs,
+	}
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().Int32Var(&o.CanaryWeight, "canary-weight", o.CanaryWeight, "The percentage of the traffic to route to the canary pods, from 0 to 100.")
+	cmd.Flags().StringVar(&o.StickyCookie, "sticky-cookie", o.StickyCookie, "The cookie that routes the requests to the canary pods when set to always. An empty value removes it.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *RouteNginxOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.setWeight = cmd.Flags().Changed("canary-weight")
+	o.setCookie = cmd.Flags().Changed("sticky-cookie")
+	o.Builder = f.NewBuilder
+	o.Client, err = f.KubernetesClientSet()
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *RouteNginxOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if o.setWeight && (o.CanaryWeight < 0 || o.CanaryWeight > 100) {
+		return fmt.Errorf("--canary-weight must be between 0 and 100")
+	}
+	return nil
+}
+
+// Run edits the canary Ingress if requested, and shows its traffic routing
+func (o *RouteNginxOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		SingleResourceType().
+		Latest().
+		Flatten().
+		Do().Infos()
+	if err != nil {
+		return err
+	}
+
+	w := internalcmdutil.NewTableWriter(o.Out)
+	fmt.Fprintln(w, "ROLLOUT\tINGRESS\tCANARY INGRESS\tWEIGHT\tEXPECTED WEIGHT\tSTICKY COOKIE")
+	var warnings []string
+	for _, info := range infos {
+		rollout, ok := info.Object.(*kruiserolloutsv1apha1.Rollout)
+		if !ok {
+			return internalpolymorphichelpers.NewUnsupportedKindError("nginx traffic routing", info.Object, schema.GroupKind{Group: "rollouts.kruise.io", Kind: "Rollout"})
+		}
+		ingress, err := NginxIngressForRollout(rollout)
+		if err != nil {
+			return err
+		}
+		canaryIngress := ingress + canaryIngressSuffix
+
+		if o.setWeight || o.setCookie {
+			if err := o.patchCanaryIngress(rollout.Namespace, canaryIngress); err != nil {
+				return err
+			}
+		}
+
+		canary, err := o.Client.NetworkingV1().Ingresses(rollout.Namespace).Get(context.TODO(), canaryIngress, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			canary = nil
+		} else if err != nil {
+			return err
+		}
+
+		route := NginxRouteForRollout(rollout, ingress, canary)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", rollout.Name, route.Ingress, route.CanaryIngress,
+			valueOrNone(route.Weight), expectedWeightString(route.ExpectedWeight), valueOrNone(route.StickyCookie))
+		if warning := route.Mismatch(); len(warning) > 0 {
+			warnings = append(warnings, fmt.Sprintf("rollout %s: %s", rollout.Name, warning))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(o.ErrOut, "Warning: %s\n", warning)
+	}
+	return nil
+}
+
+// patchCanaryIngress sets the weight and sticky cookie annotations of the canary Ingress.
+func (o *RouteNginxOptions) patchCanaryIngress(namespace, name string) error {
+	annotations := map[string]interface{}{}
+	if o.setWeight {
+		annotations[nginxCanaryWeightAnnotation] = strconv.Itoa(int(o.CanaryWeight))
+	}
+	if o.setCookie {
+		if len(o.StickyCookie) > 0 {
+			annotations[nginxCanaryCookieAnnotation] = o.StickyCookie
+		} else {
+			annotations[nginxCanaryCookieAnnotation] = nil
+		}
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	if _, err := o.Client.NetworkingV1().Ingresses(namespace).Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("cannot patch ingress %s: %v", name, err)
+	}
+	fmt.Fprintf(o.ErrOut, "ingress/%s patched\n", name)
+	return nil
+}
+
+// NginxIngressForRollout returns the stable Ingress of a rollout with nginx traffic routing.
+func NginxIngressForRollout(rollout *kruiserolloutsv1apha1.Rollout) (string, error) {
+	canary := rollout.Spec.Strategy.Canary
+	if canary == nil || canary.TrafficRouting == nil || canary.TrafficRouting.Type != kruiserolloutsv1apha1.TrafficRoutingNginx ||
+		canary.TrafficRouting.Nginx == nil || len(canary.TrafficRouting.Nginx.Ingress) == 0 {
+		return "", fmt.Errorf("rollout %s has no nginx traffic routing", rollout.Name)
+	}
+	return canary.TrafficRouting.Nginx.Ingress, nil
+}
+
+// NginxRouteForRollout returns the traffic routing of rollout by the canary Ingress, which is
+// nil if it does not exist.
+func NginxRouteForRollout(rollout *kruiserolloutsv1apha1.Rollout, ingress string, canary *networkingv1.Ingress) NginxRoute {
+	route := NginxRoute{Ingress: ingress, CanaryIngress: ingress + canaryIngressSuffix}
+	if canary != nil {
+		route.Canary = canary.Annotations[nginxCanaryAnnotation] == "true"
+		route.Weight = canary.Annotations[nginxCanaryWeightAnnotation]
+		route.StickyCookie = canary.Annotations[nginxCanaryCookieAnnotation]
+	}
+
+	status := rollout.Status.CanaryStatus
+	if status != nil && rollout.Spec.Strategy.Canary != nil {
+		steps := rollout.Spec.Strategy.Canary.Steps
+		if index := int(status.CurrentStepIndex); index > 0 && index <= len(steps) {
+			weight := steps[index-1].Weight
+			route.ExpectedWeight = &weight
+		}
+	}
+	return route
+}
+
+// Mismatch describes how the canary Ingress disagrees with the current step of the rollout.
+func (r NginxRoute) Mismatch() string {
+	if r.ExpectedWeight == nil {
+		return ""
+	}
+	if !r.Canary {
+		return fmt.Sprintf("the current step routes %d%% of the traffic to the canary pods, but ingress %s is not an nginx canary", *r.ExpectedWeight, r.CanaryIngress)
+	}
+	if r.Weight != strconv.Itoa(int(*r.ExpectedWeight)) {
+		return fmt.Sprintf("the current step routes %d%% of the traffic to the canary pods, but ingress %s routes %s%%", *r.ExpectedWeight, r.CanaryIngress, valueOrNone(r.Weight))
+	}
+	return ""
+}
+
+func expectedWeightString(weight *int32) string {
+	if weight == nil {
+		return "<none>"
+	}
+	return strconv.Itoa(int(*weight))
+}
+
+func valueOrNone(value string) string {
+	if len(value) == 0 {
+		return "<none>"
+	}
+	return value
+}
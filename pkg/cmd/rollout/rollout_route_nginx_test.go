@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"strings"
+	"testing"
+
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newNginxRollout(stepIndex int32) *kruiserolloutsv1apha1.Rollout {
+	rollout := &kruiserolloutsv1apha1.Rollout{ObjectMeta: metav1.ObjectMeta{Name: "demo"}}
+	rollout.Spec.Strategy.Canary = &kruiserolloutsv1apha1.CanaryStrategy{
+		Steps: []kruiserolloutsv1apha1.CanaryStep{{Weight: 20}, {Weight: 50}},
+		TrafficRouting: &kruiserolloutsv1apha1.TrafficRouting{
+			Type:  kruiserolloutsv1apha1.TrafficRoutingNginx,
+			Nginx: &kruiserolloutsv1apha1.NginxTrafficRouting{Ingress: "demo"},
+		},
+	}
+	if stepIndex > 0 {
+		rollout.Status.CanaryStatus = &kruiserolloutsv1apha1.CanaryStatus{CurrentStepIndex: stepIndex}
+	}
+	return rollout
+}
+
+func newCanaryIngress(weight string) *networkingv1.Ingress {
+	return &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{
+		Name: "demo-canary",
+		Annotations: map[string]string{
+			nginxCanaryAnnotation:       "true",
+			nginxCanaryWeightAnnotation: weight,
+			nginxCanaryCookieAnnotation: "canary",
+		},
+	}}
+}
+
+func TestNginxRouteForRollout(t *testing.T) {
+	tests := []struct {
+		name     string
+		rollout  *kruiserolloutsv1apha1.Rollout
+		canary   *networkingv1.Ingress
+		mismatch string
+	}{
+		{
+			name:    "no rollout in progress",
+			rollout: newNginxRollout(0),
+		},
+		{
+			name:    "weight of the current step",
+			rollout: newNginxRollout(2),
+			canary:  newCanaryIngress("50"),
+		},
+		{
+			name:     "weight mismatch",
+			rollout:  newNginxRollout(1),
+			canary:   newCanaryIngress("50"),
+			mismatch: "routes 20% of the traffic to the canary pods, but ingress demo-canary routes 50%",
+		},
+		{
+			name:     "no canary ingress",
+			rollout:  newNginxRollout(1),
+			mismatch: "ingress demo-canary is not an nginx canary",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingress, err := NginxIngressForRollout(test.rollout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			route := NginxRouteForRollout(test.rollout, ingress, test.canary)
+			if route.CanaryIngress != "demo-canary" {
+				t.Errorf("unexpected canary ingress %s", route.CanaryIngress)
+			}
+			mismatch := route.Mismatch()
+			if len(test.mismatch) == 0 && len(mismatch) > 0 || !strings.Contains(mismatch, test.mismatch) {
+				t.Errorf("expected mismatch %q, got %q", test.mismatch, mismatch)
+			}
+		})
+	}
+}
+
+func TestNginxIngressForRollout(t *testing.T) {
+	rollout := newNginxRollout(0)
+	rollout.Spec.Strategy.Canary.TrafficRouting = nil
+	if _, err := NginxIngressForRollout(rollout); err == nil {
+		t.Errorf("expected an error for a rollout without traffic routing")
+	}
+}
@@ -0,0 +1,570 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	kset "github.com/openkruise/kruise-tools/pkg/cmd/set"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/openkruise/kruise-tools/pkg/utils"
+	"github.com/spf13/cobra"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	// RolloutScheduleAnnotation stores the partition-stepping plan of 'rollout schedule' on the workload.
+	RolloutScheduleAnnotation = "kruise.io/rollout-schedule"
+)
+
+// scheduleSyncPeriod is how often --follow gets the workload, a variable so that tests do not wait.
+var scheduleSyncPeriod = 10 * time.Second
+
+// SchedulePlan is a partition-stepping plan that only progresses during maintenance windows.
+type SchedulePlan struct {
+	Window         string   `json:"window"`
+	WindowDuration string   `json:"windowDuration"`
+	Steps          []string `json:"steps"`
+	StepInterval   string   `json:"stepInterval,omitempty"`
+}
+
+// RolloutScheduleOptions is the start of the data required to perform the operation.  As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type RolloutScheduleOptions struct {
+	PrintFlags *genericclioptions.PrintFlags
+	ToPrinter  func(string) (printers.ResourcePrinter, error)
+
+	Resources []string
+
+	Builder          func() *resource.Builder
+	Pauser           internalpolymorphichelpers.ObjectPauserFunc
+	Resumer          internalpolymorphichelpers.ObjectResumerFunc
+	Namespace        string
+	EnforceNamespace bool
+
+	Window         string
+	WindowDuration time.Duration
+	Steps          []string
+	StepInterval   time.Duration
+
+	Follow         bool
+	Windows        int
+	EmitCronJob    bool
+	Image          string
+	ServiceAccount string
+
+	VerifyCmd       string
+	VerifyTimeout   time.Duration
+	OnVerifyFailure string
+
+	schedule *utils.CronSchedule
+	steps    []intstr.IntOrString
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+var (
+	scheduleLong = templates.LongDesc(`
+		Schedule a partitioned rollout so that it only progresses during maintenance windows.
+
+		A window opens whenever the cron spec given by --window fires and stays open for
+		--window-duration. Each step of --steps is the number or percentage of pods that are
+		updated once the step is reached; the partition of the workload is lowered to the next
+		step when the previous one is complete and --step-interval has passed. Set the partition
+		to the number of replicas before applying the new revision, so that the plan starts from
+		the first step.
+
+		By default the plan is stored in the ` + RolloutScheduleAnnotation + ` annotation of the workload.
+		With --follow the plan is executed by this command: outside windows the workload is paused,
+		if it supports being paused, and the partition is not changed. With --emit-cronjob an
+		AdvancedCronJob is printed that runs --follow in the cluster for every window.
+
+		With --verify-cmd, the command is run in a shell whenever a step is complete, before the
+		next one starts. The workload is exposed to it by the KRUISE_WORKLOAD_KIND,
+		KRUISE_WORKLOAD_NAMESPACE, KRUISE_WORKLOAD_NAME, KRUISE_WORKLOAD_REVISION, KRUISE_REPLICAS,
+		KRUISE_PARTITION and KRUISE_UPDATED_REPLICAS environment variables. If it exits with a
+		non-zero code, the roll out is paused, or with --on-verify-failure=abort the partition is
+		raised back to the number of replicas so that the updated pods are rolled back.
+
+		Currently clonesets and advanced statefulsets support being scheduled.`)
+
+	scheduleExample = templates.Examples(`
+		# Store a plan that updates 20%, 50% and then all pods of cloneset sample on weekday nights
+		kubectl-kruise rollout schedule cloneset/sample --window "0 22 * * 1-5" --window-duration 4h --steps 20%,50%,100%
+
+		# Execute the plan from the command line, waiting 30 minutes between steps
+		kubectl-kruise rollout schedule cloneset/sample --window "0 22 * * 1-5" --window-duration 4h --steps 20%,50%,100% --step-interval 30m --follow
+
+		# Run a smoke test after each step, rolling back the updated pods if it fails
+		kubectl-kruise rollout schedule cloneset/sample --window "0 22 * * 1-5" --window-duration 4h --steps 20%,50%,100% --follow \
+		  --verify-cmd ./smoke-test.sh --verify-timeout 5m --on-verify-failure abort
+
+		# Print an AdvancedCronJob that executes the plan in the cluster
+		kubectl-kruise rollout schedule cloneset/sample --window "0 22 * * 1-5" --window-duration 4h --steps 20%,50%,100% \
+		  --emit-cronjob --image openkruise/kruise-tools:latest --service-account rollout-scheduler`)
+)
+
+// NewRolloutScheduleOptions returns an initialized RolloutScheduleOptions instance
+func NewRolloutScheduleOptions(streams genericclioptions.IOStreams) *RolloutScheduleOptions {
+	return &RolloutScheduleOptions{
+		PrintFlags:      genericclioptions.NewPrintFlags("scheduled").WithTypeSetter(internalapi.GetScheme()),
+		Steps:           []string{"100%"},
+		OnVerifyFailure: VerifyFailurePause,
+		IOStreams:       streams,
+	}
+}
+
+// NewCmdRolloutSchedule returns a Command instance for 'rollout schedule' sub command
+func NewCmdRolloutSchedule(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRolloutScheduleOptions(streams)
+
+	validArgs := []string{"cloneset", "advanced statefulset"}
+
+	cmd := &cobra.Command{
+		Use:                   "schedule RESOURCE --window=CRON --window-duration=DURATION [--steps=N%,...] [--follow | --emit-cronjob]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Progress a partitioned rollout only during maintenance windows"),
+		Long:                  scheduleLong,
+		Example:               scheduleExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunSchedule())
+		},
+		ValidArgs: validArgs,
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringVar(&o.Window, "window", o.Window, "Cron spec (minute hour day-of-month month day-of-week) of the start of the maintenance windows.")
+	cmd.Flags().DurationVar(&o.WindowDuration, "window-duration", o.WindowDuration, "How long each maintenance window stays open.")
+	cmd.Flags().StringSliceVar(&o.Steps, "steps", o.Steps, "Comma separated numbers or percentages of pods updated at each step.")
+	cmd.Flags().DurationVar(&o.StepInterval, "step-interval", o.StepInterval, "The minimum time between the completion of a step and the start of the next one.")
+	cmd.Flags().BoolVar(&o.Follow, "follow", o.Follow, "If true, execute the plan until it is complete.")
+	cmd.Flags().IntVar(&o.Windows, "windows", o.Windows, "With --follow, the number of windows to execute the plan in before exiting, zero means until the plan is complete. A window the command is started after counts as passed.")
+	cmd.Flags().BoolVar(&o.EmitCronJob, "emit-cronjob", o.EmitCronJob, "If true, print an AdvancedCronJob that executes the plan in the cluster instead.")
+	cmd.Flags().StringVar(&o.Image, "image", o.Image, "With --emit-cronjob, the image containing kubectl-kruise.")
+	cmd.Flags().StringVar(&o.ServiceAccount, "service-account", o.ServiceAccount, "With --emit-cronjob, the service account allowed to patch the workload.")
+	cmd.Flags().StringVar(&o.VerifyCmd, "verify-cmd", o.VerifyCmd, "With --follow, a shell command run when a step is complete, before the next step starts. A non-zero exit code stops the roll out.")
+	cmd.Flags().DurationVar(&o.VerifyTimeout, "verify-timeout", o.VerifyTimeout, "The time after which the verify command fails, zero means no timeout.")
+	cmd.Flags().StringVar(&o.OnVerifyFailure, "on-verify-failure", o.OnVerifyFailure, "What to do when the verify command fails, one of: pause, abort.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *RolloutScheduleOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	o.Resources = args
+	o.Builder = f.NewBuilder
+	o.Pauser = internalpolymorphichelpers.ObjectPauserFn
+	o.Resumer = internalpolymorphichelpers.ObjectResumerFn
+
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	if o.EmitCronJob && !cmd.Flags().Changed("output") {
+		o.PrintFlags.OutputFormat = stringPtr("yaml")
+	}
+	o.ToPrinter = func(operation string) (printers.ResourcePrinter, error) {
+		o.PrintFlags.NamePrintFlags.Operation = operation
+		return o.PrintFlags.ToPrinter()
+	}
+
+	if len(o.Window) > 0 {
+		if o.schedule, err = utils.ParseCron(o.Window); err != nil {
+			return err
+		}
+	}
+	o.steps = nil
+	for _, step := range o.Steps {
+		o.steps = append(o.steps, intstr.Parse(strings.TrimSpace(step)))
+	}
+	return nil
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *RolloutScheduleOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if o.schedule == nil {
+		return fmt.Errorf("--window is required")
+	}
+	if o.WindowDuration <= 0 {
+		return fmt.Errorf("--window-duration must be positive")
+	}
+	if len(o.steps) == 0 {
+		return fmt.Errorf("at least one step is required")
+	}
+	for _, step := range o.steps {
+		if _, err := intstr.GetScaledValueFromIntOrPercent(&step, 100, true); err != nil || (step.Type == intstr.Int && step.IntVal <= 0) {
+			return fmt.Errorf("invalid step %q, must be a positive number or a percentage", step.String())
+		}
+	}
+	if o.Follow && o.EmitCronJob {
+		return fmt.Errorf("--follow and --emit-cronjob can not be used together")
+	}
+	if o.EmitCronJob && len(o.Image) == 0 {
+		return fmt.Errorf("--image is required with --emit-cronjob")
+	}
+	if o.Windows < 0 {
+		return fmt.Errorf("--windows must not be negative")
+	}
+	if len(o.VerifyCmd) > 0 && !o.Follow && !o.EmitCronJob {
+		return fmt.Errorf("--verify-cmd requires --follow or --emit-cronjob")
+	}
+	if o.OnVerifyFailure != VerifyFailurePause && o.OnVerifyFailure != VerifyFailureAbort {
+		return fmt.Errorf("invalid --on-verify-failure %q, must be one of: %s, %s", o.OnVerifyFailure, VerifyFailurePause, VerifyFailureAbort)
+	}
+	return nil
+}
+
+// RunSchedule performs the execution of 'rollout schedule' sub command
+func (o *RolloutScheduleOptions) RunSchedule() error {
+	r := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		SingleResourceType().
+		Latest().
+		Do()
+	if err := r.Err(); err != nil {
+		return err
+	}
+	infos, err := r.Infos()
+	if err != nil {
+		return err
+	}
+	if len(infos) != 1 {
+		return fmt.Errorf("rollout schedule is only supported on individual resources - %d resources were found", len(infos))
+	}
+	info := infos[0]
+	if _, _, _, ok := internalpolymorphichelpers.PartitionForObject(info.Object); !ok {
+		return internalpolymorphichelpers.NewUnsupportedKindError("rollout schedule", info.Object, internalpolymorphichelpers.PartitionedKinds...)
+	}
+
+	switch {
+	case o.EmitCronJob:
+		printer, err := o.ToPrinter("created")
+		if err != nil {
+			return err
+		}
+		return printer.PrintObj(o.cronJobForObject(info), o.Out)
+	case o.Follow:
+		return o.follow(info)
+	default:
+		return o.annotate(info)
+	}
+}
+
+func (o *RolloutScheduleOptions) plan() SchedulePlan {
+	plan := SchedulePlan{
+		Window:         o.Window,
+		WindowDuration: o.WindowDuration.String(),
+		Steps:          o.Steps,
+	}
+	if o.StepInterval > 0 {
+		plan.StepInterval = o.StepInterval.String()
+	}
+	return plan
+}
+
+// annotate stores the plan in the rollout-schedule annotation of the workload.
+func (o *RolloutScheduleOptions) annotate(info *resource.Info) error {
+	value, err := json.Marshal(o.plan())
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{RolloutScheduleAnnotation: string(value)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	obj, err := resource.NewHelper(info.Client, info.Mapping).Patch(info.Namespace, info.Name, types.MergePatchType, patch, nil)
+	if err != nil {
+		return fmt.Errorf("failed to patch: %v", err)
+	}
+	info.Refresh(obj, true)
+	printer, err := o.ToPrinter("scheduled")
+	if err != nil {
+		return err
+	}
+	return printer.PrintObj(info.Object, o.Out)
+}
+
+// follow executes the plan until it is complete or the given number of windows has passed.
+func (o *RolloutScheduleOptions) follow(info *resource.Info) error {
+	helper := resource.NewHelper(info.Client, info.Mapping)
+	var stepDoneAt time.Time
+	started, inWindow, paused := false, false, false
+	windows := 0
+	verified := int32(-1)
+
+	return wait.PollImmediateInfinite(scheduleSyncPeriod, func() (bool, error) {
+		obj, err := helper.Get(info.Namespace, info.Name)
+		if apierrors.IsNotFound(err) {
+			return false, err
+		}
+		if err != nil {
+			// a plan runs for hours, do not give it up for a transient error
+			fmt.Fprintf(o.ErrOut, "Warning: unable to get %s, retrying: %v\n", info.ObjectName(), err)
+			return false, nil
+		}
+		info.Refresh(obj, true)
+		now := time.Now()
+
+		// the last step may complete after the window closed, the plan is complete all the same
+		replicas, partition, updated, _ := internalpolymorphichelpers.PartitionForObject(info.Object)
+		next, done, err := o.nextPartition(replicas, partition, updated)
+		if err != nil {
+			return false, err
+		}
+		if done {
+			if len(o.VerifyCmd) > 0 && verified != partition {
+				if err := o.verify(info, replicas, partition, updated); err != nil {
+					return false, err
+				}
+			}
+			if paused {
+				if err := o.toggle(info, kset.PatchFn(o.Resumer)); err != nil {
+					return false, err
+				}
+			}
+			fmt.Fprintf(o.Out, "scheduled roll out of %s complete: %d new pods have been updated\n", info.ObjectName(), updated)
+			return true, nil
+		}
+
+		if !o.schedule.InWindow(now, o.WindowDuration) {
+			if !started || inWindow {
+				if err := o.toggle(info, kset.PatchFn(o.Pauser)); err != nil {
+					return false, err
+				}
+				fmt.Fprintf(o.Out, "%s is outside of its maintenance window, next window starts at %s\n", info.ObjectName(), o.schedule.Next(now).Format(time.RFC3339))
+				// a command started outside a window missed it, e.g. a job of --emit-cronjob
+				// started late, and counts it as passed
+				windows++
+			}
+			started, inWindow, paused = true, false, true
+			return o.Windows > 0 && windows >= o.Windows, nil
+		}
+		if !started || !inWindow {
+			if err := o.toggle(info, kset.PatchFn(o.Resumer)); err != nil {
+				return false, err
+			}
+			fmt.Fprintf(o.Out, "maintenance window of %s opened\n", info.ObjectName())
+		}
+		started, inWindow, paused = true, true, false
+
+		if next == partition {
+			fmt.Fprintf(o.Out, "Waiting for partitioned roll out of %s to finish: %d out of %d new pods have been updated...\n", info.ObjectName(), updated, replicas-partition)
+			return false, nil
+		}
+		if len(o.VerifyCmd) > 0 && verified != partition {
+			if err := o.verify(info, replicas, partition, updated); err != nil {
+				return false, err
+			}
+			verified = partition
+		}
+		if stepDoneAt.IsZero() {
+			stepDoneAt = now
+		}
+		if now.Sub(stepDoneAt) < o.StepInterval {
+			return false, nil
+		}
+		stepDoneAt = time.Time{}
+		if err := o.patch(info, partitionPatchFn(next)); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(o.Out, "partition of %s lowered to %d, updating %d out of %d pods\n", info.ObjectName(), next, replicas-next, replicas)
+		return false, nil
+	})
+}
+
+// verify runs the verify command for the completed step at partition, and stops the roll out
+// as configured by --on-verify-failure if it fails.
+func (o *RolloutScheduleOptions) verify(info *resource.Info, replicas, partition, updated int32) error {
+	step := VerifyStep{
+		Kind:      info.Mapping.GroupVersionKind.Kind,
+		Namespace: info.Namespace,
+		Name:      info.Name,
+		Revision:  updateRevisionForObject(info.Object),
+		Replicas:  replicas,
+		Partition: partition,
+		Updated:   updated,
+	}
+	fmt.Fprintf(o.Out, "verifying %s with %d out of %d pods updated...\n", info.ObjectName(), updated, replicas)
+	err := runVerifyCommand(o.VerifyCmd, step, o.VerifyTimeout, o.Out, o.ErrOut)
+	if err == nil {
+		fmt.Fprintf(o.Out, "verification of %s succeeded\n", info.ObjectName())
+		return nil
+	}
+
+	if o.OnVerifyFailure == VerifyFailureAbort {
+		if perr := o.patch(info, partitionPatchFn(replicas)); perr != nil {
+			return perr
+		}
+		return fmt.Errorf("verification of %s failed: %v, roll out aborted and partition raised to %d", info.ObjectName(), err, replicas)
+	}
+	if perr := o.toggle(info, kset.PatchFn(o.Pauser)); perr != nil {
+		return perr
+	}
+	return fmt.Errorf("verification of %s failed: %v, roll out paused", info.ObjectName(), err)
+}
+
+// partitionPatchFn returns a PatchFn that sets the partition of the workload.
+func partitionPatchFn(partition int32) kset.PatchFn {
+	return func(obj runtime.Object) ([]byte, error) {
+		if err := internalpolymorphichelpers.UpdatePartitionForObject(obj, partition); err != nil {
+			return nil, err
+		}
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	}
+}
+
+// toggle pauses or resumes the workload with fn, errors of fn such as "is already paused" or of
+// workloads that can not be paused are ignored.
+func (o *RolloutScheduleOptions) toggle(info *resource.Info, fn kset.PatchFn) error {
+	return o.patch(info, func(obj runtime.Object) ([]byte, error) {
+		if data, err := fn(obj); err == nil {
+			return data, nil
+		}
+		return nil, nil
+	})
+}
+
+// patch applies the changes of fn to the workload.
+func (o *RolloutScheduleOptions) patch(info *resource.Info, fn kset.PatchFn) error {
+	patch := &kset.Patch{Info: info}
+	kset.CalculatePatch(patch, scheme.DefaultJSONEncoder(), fn)
+	if patch.Err != nil {
+		return fmt.Errorf("failed to update %s: %v", info.ObjectName(), patch.Err)
+	}
+	if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+		return nil
+	}
+	obj, err := resource.NewHelper(info.Client, info.Mapping).Patch(info.Namespace, info.Name, types.MergePatchType, patch.Patch, nil)
+	if err != nil {
+		return fmt.Errorf("failed to patch: %v", err)
+	}
+	return info.Refresh(obj, true)
+}
+
+// nextPartition returns the partition to move the workload to, which is the current partition while the
+// current step is in progress, and whether the plan is complete.
+func (o *RolloutScheduleOptions) nextPartition(replicas, partition, updated int32) (int32, bool, error) {
+	for _, step := range o.steps {
+		p, err := internalpolymorphichelpers.PartitionForUpdated(step, replicas)
+		if err != nil {
+			return 0, false, err
+		}
+		if partition > p {
+			if updated < replicas-partition {
+				return partition, false, nil
+			}
+			return p, false, nil
+		}
+	}
+	return partition, updated >= replicas-partition, nil
+}
+
+// cronJobForObject returns an AdvancedCronJob that runs 'rollout schedule --follow' for a single window whenever one opens.
+func (o *RolloutScheduleOptions) cronJobForObject(info *resource.Info) *kruiseappsv1alpha1.AdvancedCronJob {
+	args := []string{
+		"rollout", "schedule", fmt.Sprintf("%s/%s", info.Mapping.Resource.GroupResource().String(), info.Name),
+		"--namespace", info.Namespace,
+		"--window", o.Window,
+		"--window-duration", o.WindowDuration.String(),
+		"--steps", strings.Join(o.Steps, ","),
+		"--follow", "--windows", "1",
+	}
+	if o.StepInterval > 0 {
+		args = append(args, "--step-interval", o.StepInterval.String())
+	}
+	if len(o.VerifyCmd) > 0 {
+		args = append(args, "--verify-cmd", o.VerifyCmd, "--on-verify-failure", o.OnVerifyFailure)
+		if o.VerifyTimeout > 0 {
+			args = append(args, "--verify-timeout", o.VerifyTimeout.String())
+		}
+	}
+	// leave the job some time to pause the workload after the window closed
+	deadline := int64((o.WindowDuration + 5*time.Minute).Seconds())
+	backoffLimit := int32(0)
+
+	return &kruiseappsv1alpha1.AdvancedCronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      info.Name + "-rollout-schedule",
+			Namespace: info.Namespace,
+		},
+		Spec: kruiseappsv1alpha1.AdvancedCronJobSpec{
+			Schedule:          o.Window,
+			ConcurrencyPolicy: kruiseappsv1alpha1.ForbidConcurrent,
+			Template: kruiseappsv1alpha1.CronJobTemplate{
+				JobTemplate: &batchv1beta1.JobTemplateSpec{
+					Spec: batchv1.JobSpec{
+						ActiveDeadlineSeconds: &deadline,
+						BackoffLimit:          &backoffLimit,
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								ServiceAccountName: o.ServiceAccount,
+								RestartPolicy:      corev1.RestartPolicyNever,
+								Containers: []corev1.Container{{
+									Name:    "rollout-schedule",
+									Image:   o.Image,
+									Command: []string{"kubectl-kruise"},
+									Args:    args,
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
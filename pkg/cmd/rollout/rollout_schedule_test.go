@@ -0,0 +1,342 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/config"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/openkruise/kruise-tools/pkg/policy"
+	"github.com/openkruise/kruise-tools/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest/fake"
+)
+
+func newScheduleOptions(t *testing.T, steps ...string) *RolloutScheduleOptions {
+	schedule, err := utils.ParseCron("* * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &RolloutScheduleOptions{
+		Window:         "* * * * *",
+		WindowDuration: time.Hour,
+		Steps:          steps,
+		schedule:       schedule,
+	}
+	for _, step := range steps {
+		o.steps = append(o.steps, intstr.Parse(step))
+	}
+	return o
+}
+
+func TestNextPartition(t *testing.T) {
+	tests := []struct {
+		name                        string
+		steps                       []string
+		replicas, partition, update int32
+		expected                    int32
+		done                        bool
+	}{
+		{name: "first step", steps: []string{"20%", "50%", "100%"}, replicas: 10, partition: 10, expected: 8},
+		{name: "step in progress", steps: []string{"20%", "50%", "100%"}, replicas: 10, partition: 8, update: 1, expected: 8},
+		{name: "step complete", steps: []string{"20%", "50%", "100%"}, replicas: 10, partition: 8, update: 2, expected: 5},
+		{name: "last step in progress", steps: []string{"20%", "50%", "100%"}, replicas: 10, partition: 0, update: 7, expected: 0},
+		{name: "plan complete", steps: []string{"20%", "50%", "100%"}, replicas: 10, partition: 0, update: 10, expected: 0, done: true},
+		{name: "plan ends with a partition", steps: []string{"3"}, replicas: 10, partition: 7, update: 3, expected: 7, done: true},
+		{name: "partition below the steps", steps: []string{"2", "5"}, replicas: 10, partition: 4, update: 6, expected: 4, done: true},
+		{name: "steps above the replicas", steps: []string{"5", "20"}, replicas: 4, partition: 4, expected: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := newScheduleOptions(t, test.steps...)
+			next, done, err := o.nextPartition(test.replicas, test.partition, test.update)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if next != test.expected || done != test.done {
+				t.Errorf("expected partition %d and done %v, got %d and %v", test.expected, test.done, next, done)
+			}
+		})
+	}
+}
+
+func TestScheduleCronJob(t *testing.T) {
+	info := &resource.Info{
+		Name:      "demo",
+		Namespace: "prod",
+		Mapping:   &meta.RESTMapping{Resource: kruiseappsv1alpha1.SchemeGroupVersion.WithResource("clonesets")},
+	}
+	tests := []struct {
+		name     string
+		options  func(*RolloutScheduleOptions)
+		expected []string
+	}{
+		{
+			name:     "plan",
+			expected: []string{"rollout", "schedule", "clonesets.apps.kruise.io/demo", "--namespace", "prod", "--window", "* * * * *", "--window-duration", "1h0m0s", "--steps", "20%,100%", "--follow", "--windows", "1"},
+		},
+		{
+			name: "step interval and verification",
+			options: func(o *RolloutScheduleOptions) {
+				o.StepInterval = 30 * time.Minute
+				o.VerifyCmd = "./smoke.sh"
+				o.OnVerifyFailure = VerifyFailureAbort
+				o.VerifyTimeout = time.Minute
+			},
+			expected: []string{"rollout", "schedule", "clonesets.apps.kruise.io/demo", "--namespace", "prod", "--window", "* * * * *", "--window-duration", "1h0m0s", "--steps", "20%,100%", "--follow", "--windows", "1",
+				"--step-interval", "30m0s", "--verify-cmd", "./smoke.sh", "--on-verify-failure", "abort", "--verify-timeout", "1m0s"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := newScheduleOptions(t, "20%", "100%")
+			o.Image = "kruise/kubectl-kruise:v1"
+			o.ServiceAccount = "rollout"
+			if test.options != nil {
+				test.options(o)
+			}
+			cronJob := o.cronJobForObject(info)
+			if cronJob.Name != "demo-rollout-schedule" || cronJob.Namespace != "prod" || cronJob.Spec.Schedule != o.Window || cronJob.Spec.ConcurrencyPolicy != kruiseappsv1alpha1.ForbidConcurrent {
+				t.Errorf("unexpected cronjob %+v", cronJob.ObjectMeta)
+			}
+			job := cronJob.Spec.Template.JobTemplate.Spec
+			if *job.ActiveDeadlineSeconds != int64((time.Hour+5*time.Minute).Seconds()) || *job.BackoffLimit != 0 {
+				t.Errorf("unexpected job limits %d, %d", *job.ActiveDeadlineSeconds, *job.BackoffLimit)
+			}
+			pod := job.Template.Spec
+			if pod.ServiceAccountName != "rollout" || len(pod.Containers) != 1 || pod.Containers[0].Image != o.Image {
+				t.Errorf("unexpected pod %+v", pod)
+			}
+			if args := pod.Containers[0].Args; !reflect.DeepEqual(args, test.expected) {
+				t.Errorf("expected args %q, got %q", test.expected, args)
+			}
+		})
+	}
+}
+
+// scheduleServer serves a CloneSet whose pods are updated as soon as its partition is lowered.
+type scheduleServer struct {
+	t          *testing.T
+	cloneSet   []byte
+	getErrors  int
+	getStatus  int
+	patchCount int
+}
+
+func (s *scheduleServer) handle(req *http.Request) (*http.Response, error) {
+	respond := func(code int, body []byte) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Content-Type", "application/json")
+		return &http.Response{StatusCode: code, Header: header, Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+	}
+	switch req.Method {
+	case http.MethodGet:
+		if s.getStatus != 0 {
+			return respond(s.getStatus, []byte("error"))
+		}
+		if s.getErrors > 0 {
+			s.getErrors--
+			return respond(http.StatusServiceUnavailable, []byte("unavailable"))
+		}
+	case http.MethodPatch:
+		s.patchCount++
+		patch, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		if s.cloneSet, err = jsonpatch.MergePatch(s.cloneSet, patch); err != nil {
+			s.t.Fatal(err)
+		}
+		cs := &kruiseappsv1alpha1.CloneSet{}
+		if err := json.Unmarshal(s.cloneSet, cs); err != nil {
+			s.t.Fatal(err)
+		}
+		replicas, partition, _, _ := internalpolymorphichelpers.PartitionForObject(cs)
+		cs.Status.UpdatedReplicas = replicas - partition
+		if s.cloneSet, err = json.Marshal(cs); err != nil {
+			s.t.Fatal(err)
+		}
+	default:
+		s.t.Errorf("unexpected request %s %s", req.Method, req.URL)
+	}
+	return respond(http.StatusOK, s.cloneSet)
+}
+
+func TestScheduleFollow(t *testing.T) {
+	defer func(period time.Duration) { scheduleSyncPeriod = period }(scheduleSyncPeriod)
+	scheduleSyncPeriod = time.Millisecond
+
+	dir, err := ioutil.TempDir("", "schedule-follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opa := filepath.Join(dir, "opa")
+	script := "#!/bin/sh\ncat > /dev/null\necho '{\"result\":[{\"expressions\":[{\"value\":[\"partitions are frozen\"]}]}]}'\n"
+	if err := ioutil.WriteFile(opa, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		getErrors   int
+		getStatus   int
+		denied      bool
+		outside     bool
+		windows     int
+		partition   string
+		updated     int32
+		expectErr   string
+		expectOut   []string
+		unexpected  string
+		expectWarn  bool
+		expectPatch int
+	}{
+		{
+			name:        "plan complete",
+			expectOut:   []string{"partition of clonesets/demo lowered to 2", "partition of clonesets/demo lowered to 0", "scheduled roll out of clonesets/demo complete"},
+			expectPatch: 2,
+		},
+		{
+			name:        "transient get errors are retried",
+			getErrors:   2,
+			expectOut:   []string{"scheduled roll out of clonesets/demo complete"},
+			expectWarn:  true,
+			expectPatch: 2,
+		},
+		{
+			name:      "deleted workload",
+			getStatus: http.StatusNotFound,
+			expectErr: "the server could not find the requested resource",
+		},
+		{
+			name:       "denied partition",
+			denied:     true,
+			expectErr:  "failed to update clonesets/demo: denied by policy: partitions are frozen",
+			unexpected: "lowered",
+		},
+		{
+			name:        "plan complete after the window closed",
+			outside:     true,
+			partition:   "0",
+			updated:     3,
+			expectOut:   []string{"clonesets/demo is outside of its maintenance window", "scheduled roll out of clonesets/demo complete: 4 new pods have been updated"},
+			expectPatch: 2,
+		},
+		{
+			name:        "started outside of its single window",
+			outside:     true,
+			windows:     1,
+			expectOut:   []string{"clonesets/demo is outside of its maintenance window"},
+			unexpected:  "complete",
+			expectPatch: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.denied {
+				policy.Configure(&config.Policy{Rego: []string{"policies/"}, OPA: opa})
+				defer policy.Configure(nil)
+			}
+			replicas, partition := int32(4), intstr.FromInt(4)
+			if len(test.partition) > 0 {
+				partition = intstr.Parse(test.partition)
+			}
+			cs := &kruiseappsv1alpha1.CloneSet{
+				TypeMeta:   metav1.TypeMeta{APIVersion: kruiseappsv1alpha1.SchemeGroupVersion.String(), Kind: "CloneSet"},
+				ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"},
+				Spec: kruiseappsv1alpha1.CloneSetSpec{
+					Replicas:       &replicas,
+					UpdateStrategy: kruiseappsv1alpha1.CloneSetUpdateStrategy{Partition: &partition},
+				},
+				Status: kruiseappsv1alpha1.CloneSetStatus{UpdatedReplicas: test.updated},
+			}
+			body, err := json.Marshal(cs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := &scheduleServer{t: t, cloneSet: body, getErrors: test.getErrors, getStatus: test.getStatus}
+			client := &fake.RESTClient{
+				GroupVersion:         kruiseappsv1alpha1.SchemeGroupVersion,
+				NegotiatedSerializer: serializer.NewCodecFactory(internalapi.GetScheme()).WithoutConversion(),
+				Client:               fake.CreateHTTPClient(server.handle),
+			}
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{kruiseappsv1alpha1.SchemeGroupVersion})
+			mapper.Add(kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet"), meta.RESTScopeNamespace)
+
+			streams, _, out, errOut := genericclioptions.NewTestIOStreams()
+			o := newScheduleOptions(t, "50%", "100%")
+			o.IOStreams = streams
+			o.Pauser = internalpolymorphichelpers.ObjectPauserFn
+			o.Resumer = internalpolymorphichelpers.ObjectResumerFn
+			o.Windows = test.windows
+			if test.outside {
+				// no window is ever open
+				o.WindowDuration = 0
+			}
+			info := &resource.Info{
+				Client:    client,
+				Mapping:   &meta.RESTMapping{Resource: kruiseappsv1alpha1.SchemeGroupVersion.WithResource("clonesets"), GroupVersionKind: kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet"), Scope: meta.RESTScopeNamespace},
+				Namespace: "default",
+				Name:      "demo",
+				Object:    cs,
+			}
+
+			err = o.follow(info)
+			if len(test.expectErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+					t.Errorf("expected error %q, got %v", test.expectErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			for _, expected := range test.expectOut {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("expected %q in the output, got %q", expected, out.String())
+				}
+			}
+			if len(test.unexpected) > 0 && strings.Contains(out.String(), test.unexpected) {
+				t.Errorf("unexpected %q in the output, got %q", test.unexpected, out.String())
+			}
+			if warned := strings.Contains(errOut.String(), "retrying"); warned != test.expectWarn {
+				t.Errorf("expected a retry warning %v, got %q", test.expectWarn, errOut.String())
+			}
+			if server.patchCount != test.expectPatch {
+				t.Errorf("expected %d patches, got %d", test.expectPatch, server.patchCount)
+			}
+		})
+	}
+}
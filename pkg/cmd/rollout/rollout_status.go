@@ -22,19 +22,20 @@ import (
 	"time"
 
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 
 	"github.com/spf13/cobra"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	watchtools "k8s.io/client-go/tools/watch"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
@@ -53,7 +54,13 @@ var (
 		you can use --watch=false. Note that if a new rollout starts in-between, then
 		'rollout status' will continue watching the latest revision. If you want to
 		pin to a specific revision and abort if it is rolled over by another revision,
-		use --revision=N where N is the revision you need to watch for.`)
+		use --revision=N where N is the revision you need to watch for.
+
+		With --tail-new-pods, the logs of the pods created or updated in place while
+		watching are streamed inline, prefixed by the name of their pod.
+
+		With -o, the workload is printed once in the given format instead of its
+		status being watched, e.g. with -o template=rollout-summary.`)
 
 	statusExample = templates.Examples(`
 		# Watch the rollout status of a deployment
@@ -63,7 +70,16 @@ var (
 		kubectl-kruise rollout status cloneset/nginx
 
 		# Watch the rollout status of a advanced statefulset
-		kubectl-kruise rollout status asts/nginx`)
+		kubectl-kruise rollout status asts/nginx
+
+		# Watch the rollout status of the cloneset selected by a field selector
+		kubectl-kruise rollout status clonesets --field-selector metadata.name=nginx
+
+		# Watch the rollout status of a cloneset, with the last 20 lines and the new logs of its updated pods
+		kubectl-kruise rollout status cloneset/nginx --tail-new-pods=20
+
+		# Print a summary of the rollout of a cloneset
+		kubectl-kruise rollout status cloneset/nginx -o template=rollout-summary`)
 )
 
 // RolloutStatusOptions holds the command-line options for 'rollout status' sub command
@@ -73,14 +89,17 @@ type RolloutStatusOptions struct {
 	Namespace        string
 	EnforceNamespace bool
 	BuilderArgs      []string
+	FieldSelector    string
 
-	Watch    bool
-	Revision int64
-	Timeout  time.Duration
+	Watch       bool
+	Revision    int64
+	Timeout     time.Duration
+	TailNewPods int64
 
 	StatusViewerFn func(*meta.RESTMapping) (internalpolymorphichelpers.StatusViewer, error)
 	Builder        func() *resource.Builder
 	DynamicClient  dynamic.Interface
+	Client         kubernetes.Interface
 
 	FilenameOptions *resource.FilenameOptions
 	genericclioptions.IOStreams
@@ -94,6 +113,7 @@ func NewRolloutStatusOptions(streams genericclioptions.IOStreams) *RolloutStatus
 		IOStreams:       streams,
 		Watch:           true,
 		Timeout:         0,
+		TailNewPods:     -1,
 	}
 }
 
@@ -119,9 +139,13 @@ func NewCmdRolloutStatus(f cmdutil.Factory, streams genericclioptions.IOStreams)
 
 	usage := "identifying the resource to get from a server."
 	cmdutil.AddFilenameOptionFlags(cmd, o.FilenameOptions, usage)
+	o.PrintFlags.AddFlags(cmd)
+	internalcmdutil.AddFieldSelectorFlagVar(cmd, &o.FieldSelector)
 	cmd.Flags().BoolVarP(&o.Watch, "watch", "w", o.Watch, "Watch the status of the rollout until it's done.")
 	cmd.Flags().Int64Var(&o.Revision, "revision", o.Revision, "Pin to a specific revision for showing its status. Defaults to 0 (last revision).")
 	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The length of time to wait before ending watch, zero means never. Any other values should contain a corresponding time unit (e.g. 1s, 2m, 3h).")
+	cmd.Flags().Int64Var(&o.TailNewPods, "tail-new-pods", o.TailNewPods, "If set, stream the logs of the pods created or updated while watching, starting with their given number of last lines (10 if no number is given).")
+	cmd.Flags().Lookup("tail-new-pods").NoOptDefVal = "10"
 
 	return cmd
 }
@@ -148,6 +172,12 @@ func (o *RolloutStatusOptions) Complete(f cmdutil.Factory, args []string) error
 	if err != nil {
 		return err
 	}
+	if o.TailNewPods >= 0 {
+		o.Client, err = f.KubernetesClientSet()
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -158,29 +188,34 @@ func (o *RolloutStatusOptions) Validate() error {
 		return fmt.Errorf("required resource not specified")
 	}
 
+	if err := internalcmdutil.ValidateFieldSelector(o.FieldSelector); err != nil {
+		return err
+	}
+
 	if o.Revision < 0 {
 		return fmt.Errorf("revision must be a positive integer: %v", o.Revision)
 	}
 
+	if o.TailNewPods >= 0 {
+		if !o.Watch {
+			return fmt.Errorf("--tail-new-pods requires --watch")
+		}
+		if o.PrintFlags.OutputFormat != nil && len(*o.PrintFlags.OutputFormat) > 0 {
+			return fmt.Errorf("--tail-new-pods can not be used with --output")
+		}
+	}
+
 	return nil
 }
 
 // Run performs the execution of 'rollout status' sub command
 func (o *RolloutStatusOptions) Run() error {
-	r := o.Builder().
-		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
-		NamespaceParam(o.Namespace).DefaultNamespace().
-		FilenameParam(o.EnforceNamespace, o.FilenameOptions).
-		ResourceTypeOrNameArgs(true, o.BuilderArgs...).
-		SingleResourceType().
-		Latest().
-		Do()
-	err := r.Err()
-	if err != nil {
-		return err
+	infos, err := o.infos(true)
+	if runtime.IsNotRegisteredError(err) {
+		// the resource is only served at a version that is not compiled in, e.g. during an
+		// upgrade of Kruise: its status is read from the unstructured object
+		infos, err = o.infos(false)
 	}
-
-	infos, err := r.Infos()
 	if err != nil {
 		return err
 	}
@@ -190,6 +225,14 @@ func (o *RolloutStatusOptions) Run() error {
 	info := infos[0]
 	mapping := info.ResourceMapping()
 
+	if o.PrintFlags.OutputFormat != nil && len(*o.PrintFlags.OutputFormat) > 0 {
+		printer, err := o.PrintFlags.ToPrinter()
+		if err != nil {
+			return err
+		}
+		return printer.PrintObj(info.Object, o.Out)
+	}
+
 	statusViewer, err := o.StatusViewerFn(mapping)
 	if err != nil {
 		return err
@@ -207,13 +250,9 @@ func (o *RolloutStatusOptions) Run() error {
 		},
 	}
 
-	preconditionFunc := func(store cache.Store) (bool, error) {
-		_, exists, err := store.Get(&metav1.ObjectMeta{Namespace: info.Namespace, Name: info.Name})
-		if err != nil {
-			return true, err
-		}
-		if !exists {
-			// We need to make sure we see the object in the cache before we start waiting for events
+	preconditionFunc := func(objects []runtime.Object) (bool, error) {
+		if len(objects) == 0 {
+			// We need to make sure we see the object before we start waiting for events
 			// or we would be waiting for the timeout if such object didn't exist.
 			return true, apierrors.NewNotFound(mapping.Resource.GroupResource(), info.Name)
 		}
@@ -223,16 +262,35 @@ func (o *RolloutStatusOptions) Run() error {
 
 	// if the rollout isn't done yet, keep watching deployment status
 	ctx, cancel := watchtools.ContextWithOptionalTimeout(context.Background(), o.Timeout)
+	out := o.Out
+	var tailer *podTailer
+	if o.TailNewPods >= 0 {
+		out = &syncWriter{w: o.Out}
+		tailer = newPodTailer(ctx, o.Client, o.TailNewPods, out)
+		if err := tailer.update(info.Object); err != nil {
+			cancel()
+			return err
+		}
+		defer tailer.wait()
+	}
 	intr := interrupt.New(nil, cancel)
 	return intr.Run(func() error {
-		_, err = watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, preconditionFunc, func(e watch.Event) (bool, error) {
+		// stop streaming the logs once the rollout is done
+		defer cancel()
+		// resume the watch if the API server restarts during long rollouts
+		_, err = internalpolymorphichelpers.UntilWithResume(ctx, lw, preconditionFunc, func(e watch.Event) (bool, error) {
 			switch t := e.Type; t {
 			case watch.Added, watch.Modified:
 				status, done, err := statusViewer.Status(e.Object.(runtime.Unstructured), o.Revision)
 				if err != nil {
 					return false, err
 				}
-				fmt.Fprintf(o.Out, "%s", status)
+				fmt.Fprintf(out, "%s", status)
+				if tailer != nil {
+					if err := tailer.update(info.Object); err != nil {
+						return false, err
+					}
+				}
 				// Quit waiting if the rollout is done
 				if done {
 					return true, nil
@@ -256,3 +314,24 @@ func (o *RolloutStatusOptions) Run() error {
 		return err
 	})
 }
+
+// infos returns the resources to watch, decoded to typed objects if typed is true.
+func (o *RolloutStatusOptions) infos(typed bool) ([]*resource.Info, error) {
+	b := o.Builder()
+	if typed {
+		b = b.WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...)
+	} else {
+		b = b.Unstructured()
+	}
+	r := b.NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, o.FilenameOptions).
+		FieldSelectorParam(o.FieldSelector).
+		ResourceTypeOrNameArgs(true, o.BuilderArgs...).
+		SingleResourceType().
+		Latest().
+		Do()
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return r.Infos()
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// tailRetryInterval is how often the logs of a pod are requested until its containers started.
+const tailRetryInterval = 2 * time.Second
+
+// syncWriter serializes the writes of the status and of the logs of several pods.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// podTailer streams the logs of the pods of a workload that are created, or updated in place,
+// after it first listed them, each line prefixed by the name of its pod.
+type podTailer struct {
+	ctx       context.Context
+	client    kubernetes.Interface
+	tailLines int64
+	out       io.Writer
+
+	listed bool
+	// revisions are the revisions of the pods that were listed, by uid
+	revisions map[types.UID]string
+	wg        sync.WaitGroup
+}
+
+func newPodTailer(ctx context.Context, client kubernetes.Interface, tailLines int64, out io.Writer) *podTailer {
+	return &podTailer{
+		ctx:       ctx,
+		client:    client,
+		tailLines: tailLines,
+		out:       out,
+		revisions: map[types.UID]string{},
+	}
+}
+
+// update lists the pods of workload and starts streaming the logs of the new or updated ones.
+func (t *podTailer) update(workload runtime.Object) error {
+	pods, err := internalpolymorphichelpers.PodsForObject(t.client.CoreV1(), workload)
+	if err != nil {
+		return err
+	}
+	for i := range pods {
+		pod := &pods[i]
+		revision := podRevision(pod)
+		if previous, ok := t.revisions[pod.UID]; ok && previous == revision {
+			continue
+		}
+		t.revisions[pod.UID] = revision
+		if t.listed {
+			t.tail(pod)
+		}
+	}
+	t.listed = true
+	return nil
+}
+
+// wait waits for the streams to end, once the context of the tailer is done.
+func (t *podTailer) wait() {
+	t.wg.Wait()
+}
+
+func (t *podTailer) tail(pod *corev1.Pod) {
+	for _, c := range pod.Spec.Containers {
+		prefix := fmt.Sprintf("[%s] ", pod.Name)
+		if len(pod.Spec.Containers) > 1 {
+			prefix = fmt.Sprintf("[%s/%s] ", pod.Name, c.Name)
+		}
+		t.wg.Add(1)
+		go func(container, prefix string) {
+			defer t.wg.Done()
+			t.stream(pod.Namespace, pod.Name, container, prefix)
+		}(c.Name, prefix)
+	}
+}
+
+// stream copies the logs of a container to the output, retrying until the container started.
+func (t *podTailer) stream(namespace, name, container, prefix string) {
+	options := &corev1.PodLogOptions{Container: container, Follow: true, TailLines: &t.tailLines}
+	for {
+		logs, err := t.client.CoreV1().Pods(namespace).GetLogs(name, options).Stream(t.ctx)
+		if err == nil {
+			scanner := bufio.NewScanner(logs)
+			for scanner.Scan() {
+				fmt.Fprintf(t.out, "%s%s\n", prefix, scanner.Text())
+			}
+			logs.Close()
+			return
+		}
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-time.After(tailRetryInterval):
+		}
+	}
+}
+
+// podRevision returns the revision of pod, which changes when it is updated in place.
+func podRevision(pod *corev1.Pod) string {
+	if revision, ok := pod.Labels["controller-revision-hash"]; ok {
+		return revision
+	}
+	return pod.Labels["pod-template-hash"]
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTailPod(name, revision string, containers ...string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      name,
+		UID:       types.UID(name),
+		Labels:    map[string]string{"app": "demo", "controller-revision-hash": revision},
+	}}
+	for _, c := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: c})
+	}
+	return pod
+}
+
+func TestPodTailer(t *testing.T) {
+	cs := &kruiseappsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"}}
+	cs.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}}
+
+	client := fake.NewSimpleClientset(newTailPod("demo-a", "v1", "main"), newTailPod("demo-b", "v1", "main"))
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncWriter{w: &bytes.Buffer{}}
+	tailer := newPodTailer(ctx, client, 10, out)
+
+	// the pods listed first are not tailed
+	if err := tailer.update(cs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// demo-a is updated in place, demo-c is created with two containers
+	updated := newTailPod("demo-a", "v2", "main")
+	if _, err := client.CoreV1().Pods("default").Update(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CoreV1().Pods("default").Create(context.TODO(), newTailPod("demo-c", "v2", "main", "sidecar"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tailer.update(cs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// pods are only tailed once per revision
+	if err := tailer.update(cs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tailer.wait()
+	cancel()
+
+	lines := strings.Split(strings.TrimSpace(out.w.(*bytes.Buffer).String()), "\n")
+	sort.Strings(lines)
+	expected := []string{"[demo-a] fake logs", "[demo-c/main] fake logs", "[demo-c/sidecar] fake logs"}
+	if strings.Join(lines, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, lines)
+	}
+}
+
+func TestRolloutStatusTailValidation(t *testing.T) {
+	o := NewRolloutStatusOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.BuilderArgs = []string{"cloneset/demo"}
+	o.TailNewPods = 10
+	o.Watch = false
+	if err := o.Validate(); err == nil {
+		t.Errorf("expected an error without --watch")
+	}
+	o.Watch = true
+	if err := o.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -19,14 +19,19 @@ package rollout
 
 import (
 	"fmt"
+	"net/http"
 
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	kset "github.com/openkruise/kruise-tools/pkg/cmd/set"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util/i18n"
@@ -48,13 +53,24 @@ type UndoOptions struct {
 	EnforceNamespace bool
 	RESTClientGetter genericclioptions.RESTClientGetter
 
+	CheckImages  bool
+	ImageExists  kset.ImageChecker
+	Client       kubernetes.Interface
+	KruiseClient kruiseclientsets.Interface
+	nodeImages   []kruiseappsv1alpha1.NodeImage
+
 	resource.FilenameOptions
 	genericclioptions.IOStreams
 }
 
 var (
 	undoLong = templates.LongDesc(`
-		Rollback to a previous rollout.`)
+		Rollback to a previous rollout.
+
+		Before rolling back, the images of the revision to roll back to are looked up in their
+		registries, and a warning is printed for those that were deleted since, with the number
+		of nodes that still cache them according to their NodeImages, since the rolled back pods
+		would hit ImagePullBackOff. --check-images=false skips the check.`)
 
 	undoExample = templates.Examples(`
 		# Rollback to the previous cloneset
@@ -73,9 +89,10 @@ var (
 // NewRolloutUndoOptions returns an initialized UndoOptions instance
 func NewRolloutUndoOptions(streams genericclioptions.IOStreams) *UndoOptions {
 	return &UndoOptions{
-		PrintFlags: genericclioptions.NewPrintFlags("rolled back").WithTypeSetter(internalapi.GetScheme()),
-		IOStreams:  streams,
-		ToRevision: int64(0),
+		PrintFlags:  genericclioptions.NewPrintFlags("rolled back").WithTypeSetter(internalapi.GetScheme()),
+		IOStreams:   streams,
+		ToRevision:  int64(0),
+		CheckImages: true,
 	}
 }
 
@@ -100,6 +117,7 @@ func NewCmdRolloutUndo(f cmdutil.Factory, streams genericclioptions.IOStreams) *
 	}
 
 	cmd.Flags().Int64Var(&o.ToRevision, "to-revision", o.ToRevision, "The revision to rollback to. Default to 0 (last revision).")
+	cmd.Flags().BoolVar(&o.CheckImages, "check-images", o.CheckImages, "If true, warn when the images of the revision to rollback to are no longer in their registries.")
 	usage := "identifying the resource to get from a server."
 	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
 	cmdutil.AddDryRunFlag(cmd)
@@ -138,6 +156,19 @@ func (o *UndoOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []str
 	o.RESTClientGetter = f
 	o.Builder = f.NewBuilder
 
+	if o.CheckImages {
+		if o.Client, err = f.KubernetesClientSet(); err != nil {
+			return err
+		}
+		clientConfig, err := f.ToRESTConfig()
+		if err != nil {
+			return err
+		}
+		if o.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig); err != nil {
+			return err
+		}
+		o.ImageExists = kset.NewImageChecker(http.DefaultClient, o.Client)
+	}
 	return err
 }
 
@@ -177,6 +208,9 @@ func (o *UndoOptions) RunUndo() error {
 				return err
 			}
 		}
+		if o.CheckImages && o.ImageExists != nil {
+			o.checkUndoImages(info)
+		}
 		result, err := rollbacker.Rollback(info.Object, nil, o.ToRevision, o.DryRunStrategy)
 		if err != nil {
 			return err
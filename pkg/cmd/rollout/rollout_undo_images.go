@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// checkUndoImages warns when the images of the revision info is rolled back to are no longer in
+// their registries, since the rolled back pods would then hit ImagePullBackOff, unless they are
+// scheduled on the nodes that still cache the images in their NodeImages.
+func (o *UndoOptions) checkUndoImages(info *resource.Info) {
+	template, revision, err := o.undoTemplate(info)
+	if err != nil {
+		fmt.Fprintf(o.ErrOut, "Warning: unable to check the images of %s: %v\n", info.ObjectName(), err)
+		return
+	}
+	if template == nil {
+		// unsupported kinds and unknown revisions are reported by the rollback itself
+		return
+	}
+
+	var containers []corev1.Container
+	containers = append(containers, template.Spec.InitContainers...)
+	containers = append(containers, template.Spec.Containers...)
+	for _, c := range containers {
+		found, err := o.ImageExists(c.Image, info.Namespace, template.Spec.ImagePullSecrets)
+		if err != nil {
+			fmt.Fprintf(o.ErrOut, "Warning: unable to check image %s of revision %d of %s: %v\n", c.Image, revision, info.ObjectName(), err)
+			continue
+		}
+		if found {
+			continue
+		}
+		if nodes := o.cachedNodes(c.Image); nodes > 0 {
+			fmt.Fprintf(o.ErrOut, "Warning: image %s of revision %d of %s is not in its registry and only cached on %d nodes, the pods scheduled on other nodes would hit ImagePullBackOff\n", c.Image, revision, info.ObjectName(), nodes)
+		} else {
+			fmt.Fprintf(o.ErrOut, "Warning: image %s of revision %d of %s is not in its registry nor cached on any node, the rollback would hit ImagePullBackOff\n", c.Image, revision, info.ObjectName())
+		}
+	}
+}
+
+// undoTemplate returns the pod template of the revision info is rolled back to, and the revision,
+// or nil if the kind of info has no history or the revision is unknown.
+func (o *UndoOptions) undoTemplate(info *resource.Info) (*corev1.PodTemplateSpec, int64, error) {
+	gk := info.Mapping.GroupVersionKind.GroupKind()
+	if gk.Kind == "Deployment" {
+		return o.deploymentUndoTemplate(info)
+	}
+	supported := false
+	for _, kind := range internalpolymorphichelpers.ControllerRevisionKinds {
+		supported = supported || kind == gk
+	}
+	if !supported {
+		return nil, 0, nil
+	}
+
+	revisions, err := internalpolymorphichelpers.RevisionTemplatesFor(gk, o.Client, o.KruiseClient, info.Namespace, info.Name)
+	if err != nil {
+		return nil, 0, err
+	}
+	if o.ToRevision > 0 {
+		for _, r := range revisions {
+			if r.Revision.Revision == o.ToRevision {
+				return r.Template, r.Revision.Revision, nil
+			}
+		}
+		return nil, 0, nil
+	}
+	// the last revision is the current one
+	if len(revisions) < 2 {
+		return nil, 0, nil
+	}
+	previous := revisions[len(revisions)-2]
+	return previous.Template, previous.Revision.Revision, nil
+}
+
+// deploymentUndoTemplate returns the pod template of the ReplicaSet of the revision a Deployment
+// is rolled back to, as 'rollout undo' picks it.
+func (o *UndoOptions) deploymentUndoTemplate(info *resource.Info) (*corev1.PodTemplateSpec, int64, error) {
+	deployment, err := o.Client.AppsV1().Deployments(info.Namespace).Get(context.TODO(), info.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, 0, err
+	}
+	replicaSets, err := o.Client.AppsV1().ReplicaSets(info.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, 0, err
+	}
+	current, _ := strconv.ParseInt(deployment.Annotations[deploymentRevisionAnnotation], 10, 64)
+
+	var target *appsv1.ReplicaSet
+	var targetRevision int64
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if owner := metav1.GetControllerOf(rs); owner == nil || owner.UID != deployment.UID {
+			continue
+		}
+		revision, err := strconv.ParseInt(rs.Annotations[deploymentRevisionAnnotation], 10, 64)
+		if err != nil {
+			continue
+		}
+		if o.ToRevision > 0 {
+			if revision == o.ToRevision {
+				return &rs.Spec.Template, revision, nil
+			}
+		} else if revision < current && revision > targetRevision {
+			target, targetRevision = rs, revision
+		}
+	}
+	if target == nil {
+		return nil, 0, nil
+	}
+	return &target.Spec.Template, targetRevision, nil
+}
+
+// cachedNodes returns the number of nodes whose NodeImage has pulled image. The NodeImages are
+// listed once, and the nodes are not counted if they can not be listed.
+func (o *UndoOptions) cachedNodes(image string) int {
+	if o.nodeImages == nil {
+		o.nodeImages = []kruiseappsv1alpha1.NodeImage{}
+		list, err := o.KruiseClient.AppsV1alpha1().NodeImages().List(context.TODO(), metav1.ListOptions{})
+		if err == nil {
+			o.nodeImages = list.Items
+		} else if !apierrors.IsNotFound(err) {
+			fmt.Fprintf(o.ErrOut, "Warning: unable to list the images cached on the nodes: %v\n", err)
+		}
+	}
+	name, tag := internalcmdutil.SplitImageTag(image)
+	nodes := 0
+	for _, nodeImage := range o.nodeImages {
+		for _, t := range nodeImage.Status.ImageStatuses[name].Tags {
+			if t.Tag == tag && t.Phase == kruiseappsv1alpha1.ImagePhaseSucceeded {
+				nodes++
+				break
+			}
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+)
+
+func newNodeImage(node, name, tag string) *kruiseappsv1alpha1.NodeImage {
+	return &kruiseappsv1alpha1.NodeImage{
+		ObjectMeta: metav1.ObjectMeta{Name: node},
+		Status: kruiseappsv1alpha1.NodeImageStatus{ImageStatuses: map[string]kruiseappsv1alpha1.ImageStatus{
+			name: {Tags: []kruiseappsv1alpha1.ImageTagStatus{{Tag: tag, Phase: kruiseappsv1alpha1.ImagePhaseSucceeded}}},
+		}},
+	}
+}
+
+func TestCheckUndoImages(t *testing.T) {
+	cs := &kruiseappsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo", UID: "uid-1"}}
+	cs.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}}
+	info := &resource.Info{Namespace: "default", Name: "demo", Mapping: &meta.RESTMapping{
+		GroupVersionKind: cloneSetGVK,
+		Resource:         kruiseappsv1alpha1.SchemeGroupVersion.WithResource("clonesets"),
+	}}
+	registry := map[string]bool{"nginx:2": true, "nginx:3": true}
+
+	tests := []struct {
+		name       string
+		toRevision int64
+		nodeImages []*kruiseappsv1alpha1.NodeImage
+		expected   string
+	}{
+		{name: "previous revision in the registry", expected: ""},
+		{
+			name:       "deleted image",
+			toRevision: 1,
+			expected:   "Warning: image nginx:1 of revision 1 of clonesets/demo is not in its registry nor cached on any node",
+		},
+		{
+			name:       "deleted image cached on a node",
+			toRevision: 1,
+			nodeImages: []*kruiseappsv1alpha1.NodeImage{newNodeImage("node-a", "nginx", "1"), newNodeImage("node-b", "nginx", "2")},
+			expected:   "Warning: image nginx:1 of revision 1 of clonesets/demo is not in its registry and only cached on 1 nodes",
+		},
+		{name: "unknown revision", toRevision: 5, expected: ""},
+	}
+	for _, test := range tests {
+		var objects []runtime.Object
+		objects = append(objects, cs)
+		for _, nodeImage := range test.nodeImages {
+			objects = append(objects, nodeImage)
+		}
+		errOut := &bytes.Buffer{}
+		o := NewRolloutUndoOptions(genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: errOut})
+		o.ToRevision = test.toRevision
+		o.Client = fake.NewSimpleClientset(newArchivedRevision(cs, 1, "nginx:1"), newArchivedRevision(cs, 2, "nginx:2"), newArchivedRevision(cs, 3, "nginx:3"))
+		o.KruiseClient = kruisefake.NewSimpleClientset(objects...)
+		o.ImageExists = func(image, namespace string, pullSecrets []corev1.LocalObjectReference) (bool, error) {
+			return registry[image], nil
+		}
+		o.checkUndoImages(info)
+		if len(test.expected) == 0 && errOut.Len() > 0 {
+			t.Errorf("%s: expected no warning, got %q", test.name, errOut.String())
+		} else if !strings.Contains(errOut.String(), test.expected) {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, errOut.String())
+		}
+	}
+}
+
+func TestDeploymentUndoTemplate(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid-1", Annotations: map[string]string{deploymentRevisionAnnotation: "3"}},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+	}
+	newReplicaSet := func(revision int, image string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "default",
+				Name:            fmt.Sprintf("web-%d", revision),
+				Labels:          map[string]string{"app": "web"},
+				Annotations:     map[string]string{deploymentRevisionAnnotation: fmt.Sprint(revision)},
+				OwnerReferences: []metav1.OwnerReference{{UID: "uid-1", Controller: pointer.BoolPtr(true)}},
+			},
+			Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: image}}}}},
+		}
+	}
+	o := NewRolloutUndoOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.Client = fake.NewSimpleClientset(deployment, newReplicaSet(1, "web:1"), newReplicaSet(2, "web:2"), newReplicaSet(3, "web:3"))
+	info := &resource.Info{Namespace: "default", Name: "web", Mapping: &meta.RESTMapping{GroupVersionKind: appsv1.SchemeGroupVersion.WithKind("Deployment")}}
+
+	for toRevision, expected := range map[int64]string{0: "web:2", 1: "web:1"} {
+		o.ToRevision = toRevision
+		template, revision, err := o.undoTemplate(info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if template == nil || template.Spec.Containers[0].Image != expected {
+			t.Errorf("--to-revision=%d: expected image %s, got revision %d %v", toRevision, expected, revision, template)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	goruntime "runtime"
+	"strconv"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// VerifyFailurePause pauses the workload when the verify command of a step fails.
+	VerifyFailurePause = "pause"
+	// VerifyFailureAbort raises the partition back to the number of replicas when the verify
+	// command of a step fails, so that the updated pods are rolled back.
+	VerifyFailureAbort = "abort"
+)
+
+// VerifyStep describes the completed step of a staged rollout that a verify command checks.
+type VerifyStep struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Revision  string
+	Replicas  int32
+	Partition int32
+	Updated   int32
+	// Step is the index of the paused step of a Rollout, zero for partitioned workloads.
+	Step int32
+}
+
+// Env returns the environment variables that expose the step to the verify command.
+func (s VerifyStep) Env() []string {
+	env := []string{
+		"KRUISE_WORKLOAD_KIND=" + s.Kind,
+		"KRUISE_WORKLOAD_NAMESPACE=" + s.Namespace,
+		"KRUISE_WORKLOAD_NAME=" + s.Name,
+		"KRUISE_WORKLOAD_REVISION=" + s.Revision,
+		fmt.Sprintf("KRUISE_REPLICAS=%d", s.Replicas),
+		fmt.Sprintf("KRUISE_PARTITION=%d", s.Partition),
+		fmt.Sprintf("KRUISE_UPDATED_REPLICAS=%d", s.Updated),
+	}
+	if s.Step > 0 {
+		env = append(env, fmt.Sprintf("KRUISE_ROLLOUT_STEP=%d", s.Step))
+	}
+	return env
+}
+
+// updateRevisionForObject returns the update revision of a partitioned workload.
+func updateRevisionForObject(obj runtime.Object) string {
+	switch t := obj.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		return t.Status.UpdateRevision
+	case *kruiseappsv1beta1.StatefulSet:
+		return t.Status.UpdateRevision
+	case *kruiseappsv1alpha1.StatefulSet:
+		return t.Status.UpdateRevision
+	}
+	return ""
+}
+
+// runVerifyCommand runs command in the shell of the platform, with the step exposed in its
+// environment. It fails if the command exits with a non-zero code or does not finish in time.
+func runVerifyCommand(command string, step VerifyStep, timeout time.Duration, out, errOut io.Writer) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var cmd *exec.Cmd
+	if goruntime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), step.Env()...)
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+// runMetricQuery runs the PromQL query against the Prometheus server at address, and fails
+// unless it returns at least one sample and all the samples are non-zero, as the comparisons
+// of PromQL return, e.g. "sum(rate(http_errors[5m])) / sum(rate(http_requests[5m])) < 0.01".
+func runMetricQuery(client *http.Client, address, query string, timeout time.Duration) error {
+	values, err := util.QueryPrometheus(client, address, query, timeout)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("query returned no samples")
+	}
+	for _, value := range values {
+		if v, err := strconv.ParseFloat(value, 64); err != nil || v == 0 {
+			return fmt.Errorf("query returned %s", value)
+		}
+	}
+	return nil
+}
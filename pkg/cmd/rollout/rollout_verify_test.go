@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	goruntime "runtime"
+	"testing"
+	"time"
+
+	"github.com/openkruise/kruise-tools/pkg/utils"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestRunVerifyCommand(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("verify commands are run by cmd on windows")
+	}
+	step := VerifyStep{Kind: "CloneSet", Namespace: "default", Name: "demo", Revision: "demo-abc", Replicas: 10, Partition: 8, Updated: 2}
+
+	tests := []struct {
+		name      string
+		command   string
+		timeout   time.Duration
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "environment",
+			command:  `echo "$KRUISE_WORKLOAD_KIND/$KRUISE_WORKLOAD_NAMESPACE/$KRUISE_WORKLOAD_NAME $KRUISE_WORKLOAD_REVISION $KRUISE_UPDATED_REPLICAS/$KRUISE_REPLICAS $KRUISE_PARTITION"`,
+			expected: "CloneSet/default/demo demo-abc 2/10 8\n",
+		},
+		{
+			name:      "non-zero exit",
+			command:   "exit 3",
+			expectErr: true,
+		},
+		{
+			name:      "timeout",
+			command:   "exec sleep 5",
+			timeout:   100 * time.Millisecond,
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := runVerifyCommand(test.command, step, test.timeout, out, &bytes.Buffer{})
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error %t, got %v", test.expectErr, err)
+			}
+			if out.String() != test.expected {
+				t.Errorf("expected output %q, got %q", test.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestValidateVerifyFlags(t *testing.T) {
+	schedule, err := utils.ParseCron("0 22 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		modify    func(o *RolloutScheduleOptions)
+		expectErr bool
+	}{
+		{
+			name:   "verify with follow",
+			modify: func(o *RolloutScheduleOptions) { o.Follow = true },
+		},
+		{
+			name:      "verify without follow",
+			modify:    func(o *RolloutScheduleOptions) {},
+			expectErr: true,
+		},
+		{
+			name: "abort on failure",
+			modify: func(o *RolloutScheduleOptions) {
+				o.Follow = true
+				o.OnVerifyFailure = VerifyFailureAbort
+			},
+		},
+		{
+			name: "unknown failure action",
+			modify: func(o *RolloutScheduleOptions) {
+				o.Follow = true
+				o.OnVerifyFailure = "rollback"
+			},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := NewRolloutScheduleOptions(genericclioptions.NewTestIOStreamsDiscard())
+			o.Resources = []string{"cloneset/demo"}
+			o.schedule = schedule
+			o.WindowDuration = time.Hour
+			o.steps = []intstr.IntOrString{intstr.FromString("100%")}
+			o.VerifyCmd = "./smoke-test.sh"
+			test.modify(o)
+			if err := o.Validate(); (err != nil) != test.expectErr {
+				t.Errorf("expected error %t, got %v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestRunMetricQuery(t *testing.T) {
+	responses := map[string]string{
+		"passing":   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1650000000,"0.002"]}]}}`,
+		"empty":     `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		"zero":      `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"a":"1"},"value":[1650000000,"1"]},{"metric":{"a":"2"},"value":[1650000000,"0"]}]}}`,
+		"scalar":    `{"status":"success","data":{"resultType":"scalar","result":[1650000000,"1"]}}`,
+		"matrix":    `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+		"malformed": `{"status":"error","error":"parse error"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, responses[r.URL.Query().Get("query")])
+	}))
+	defer server.Close()
+
+	tests := map[string]bool{
+		"passing":   false,
+		"empty":     true,
+		"zero":      true,
+		"scalar":    false,
+		"matrix":    true,
+		"malformed": true,
+	}
+	for query, expectErr := range tests {
+		err := runMetricQuery(server.Client(), server.URL+"/", query, time.Second)
+		if (err != nil) != expectErr {
+			t.Errorf("%s: expected error %t, got %v", query, expectErr, err)
+		}
+	}
+}
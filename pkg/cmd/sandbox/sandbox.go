@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sandbox
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	// SandboxLabel is set on the ephemeral namespaces of the sandboxes, and on the objects
+	// created to delete them, to the name of the namespace.
+	SandboxLabel = "kubectl.kruise.io/sandbox"
+	// ExpireAtAnnotation is set on the ephemeral namespaces of the sandboxes to the time they
+	// are deleted at, in RFC 3339.
+	ExpireAtAnnotation = "kubectl.kruise.io/sandbox-expire-at"
+)
+
+var (
+	sandboxLong = templates.LongDesc(`
+		Deploy manifests to ephemeral namespaces, deleted after a TTL, for quick validation
+		environments, e.g. before merging a change of the manifests.`)
+
+	sandboxExample = templates.Examples(`
+		# Deploy the manifests of app/ to a new namespace deleted in 2 hours
+		kubectl-kruise sandbox deploy -f app/ --ttl 2h`)
+)
+
+// NewCmdSandbox returns a Command instance for 'sandbox' command
+func NewCmdSandbox(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "sandbox SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Deploy manifests to ephemeral namespaces"),
+		Long:                  sandboxLong,
+		Example:               sandboxExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(NewCmdSandboxDeploy(f, streams))
+	return cmd
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// cleanupName is the name of the objects created to delete a sandbox namespace.
+const cleanupName = "kubectl-kruise-sandbox-cleanup"
+
+var (
+	deployLong = templates.LongDesc(`
+		Deploy manifests to a new ephemeral namespace, wait for them to be ready, and
+		schedule the deletion of the namespace after --ttl.
+
+		The namespaces of the manifests are rewritten to the ephemeral one, and the manifests
+		of namespaces are left out. Manifests of other cluster-scoped resources are refused,
+		since they would outlive the sandbox.
+
+		The namespace is deleted by a BroadcastJob of a single pod created in it, which waits
+		for the TTL and deletes the namespace with the permission granted by a ClusterRole
+		and a ClusterRoleBinding owned by the namespace, so that nothing is left behind. The
+		namespace is labeled ` + SandboxLabel + ` and annotated with the time it expires at.`)
+
+	deployExample = templates.Examples(`
+		# Deploy the manifests of app/ to a new namespace deleted in 2 hours
+		kubectl-kruise sandbox deploy -f app/ --ttl 2h
+
+		# Deploy the manifests of a kustomization to a namespace named pr-1234-xxxxx, without waiting
+		kubectl-kruise sandbox deploy -k overlays/pr --name-prefix pr-1234 --wait=false`)
+)
+
+// DeployOptions holds the command-line options for 'sandbox deploy' sub command
+type DeployOptions struct {
+	TTL          time.Duration
+	NamePrefix   string
+	CleanupImage string
+	Wait         bool
+	Timeout      time.Duration
+
+	Namespace string
+
+	Builder      func() *resource.Builder
+	Client       kubernetes.Interface
+	KruiseClient kruiseclientsets.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NewDeployOptions returns an initialized DeployOptions instance
+func NewDeployOptions(streams genericclioptions.IOStreams) *DeployOptions {
+	return &DeployOptions{
+		TTL:          2 * time.Hour,
+		NamePrefix:   "sandbox",
+		CleanupImage: "bitnami/kubectl:latest",
+		Wait:         true,
+		Timeout:      5 * time.Minute,
+		IOStreams:    streams,
+	}
+}
+
+// NewCmdSandboxDeploy returns a Command instance for 'sandbox deploy' sub command
+func NewCmdSandboxDeploy(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewDeployOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "deploy (-f FILENAME | -k DIRECTORY) [--ttl DURATION]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Deploy manifests to a new namespace deleted after a TTL"),
+		Long:                  deployLong,
+		Example:               deployExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "containing the manifests to deploy."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().DurationVar(&o.TTL, "ttl", o.TTL, "The time after which the namespace is deleted, with everything deployed in it.")
+	cmd.Flags().StringVar(&o.NamePrefix, "name-prefix", o.NamePrefix, "The prefix of the name of the namespace, followed by a random suffix.")
+	cmd.Flags().StringVar(&o.CleanupImage, "cleanup-image", o.CleanupImage, "The image of the pod deleting the namespace, which must have kubectl and sh.")
+	cmd.Flags().BoolVar(&o.Wait, "wait", o.Wait, "If true, wait for the deployed resources to be ready.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The time to wait for with --wait.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *DeployOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmdutil.UsageErrorf(cmd, "unexpected arguments %v, the manifests are given with -f or -k", args)
+	}
+	o.Namespace = fmt.Sprintf("%s-%s", o.NamePrefix, utilrand.String(5))
+	o.Builder = f.NewBuilder
+
+	clientConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.Client, err = kubernetes.NewForConfig(clientConfig)
+	if err != nil {
+		return err
+	}
+	o.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig)
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *DeployOptions) Validate() error {
+	if cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("the manifests must be given with -f or -k")
+	}
+	if o.TTL < time.Minute {
+		return fmt.Errorf("invalid --ttl %s, must be at least 1m", o.TTL)
+	}
+	if errs := validation.IsDNS1123Label(o.Namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid --name-prefix %q: %s", o.NamePrefix, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Run performs the execution of 'sandbox deploy' sub command
+func (o *DeployOptions) Run() error {
+	infos, err := o.Builder().
+		Unstructured().
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(false, &o.FilenameOptions).
+		Flatten().
+		Do().Infos()
+	if err != nil {
+		return err
+	}
+	infos, err = SandboxInfos(infos, o.Namespace)
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		return fmt.Errorf("no objects found in the manifests")
+	}
+
+	expireAt := time.Now().Add(o.TTL).UTC()
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        o.Namespace,
+			Labels:      map[string]string{SandboxLabel: o.Namespace},
+			Annotations: map[string]string{ExpireAtAnnotation: expireAt.Format(time.RFC3339)},
+		},
+	}
+	namespace, err = o.Client.CoreV1().Namespaces().Create(context.TODO(), namespace, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	if err := o.scheduleCleanup(namespace); err != nil {
+		// without cleanup, the namespace would never be deleted
+		_ = o.Client.CoreV1().Namespaces().Delete(context.TODO(), namespace.Name, metav1.DeleteOptions{})
+		return fmt.Errorf("failed to schedule the deletion of namespace %s: %v", namespace.Name, err)
+	}
+	fmt.Fprintf(o.Out, "namespace/%s created, deleted at %s\n", namespace.Name, expireAt.Format(time.RFC3339))
+
+	for _, info := range infos {
+		obj, err := resource.NewHelper(info.Client, info.Mapping).Create(info.Namespace, true, info.Object)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %v", info.ObjectName(), err)
+		}
+		if err := info.Refresh(obj, true); err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Out, "%s created\n", info.ObjectName())
+	}
+	if !o.Wait {
+		return nil
+	}
+
+	states, err := internalcmdutil.ReadyStates(infos, true, o.Timeout)
+	if err != nil {
+		for _, state := range states {
+			if !state.Ready {
+				fmt.Fprintf(o.ErrOut, "%s/%s is not ready\n", strings.ToLower(state.Kind), state.Name)
+			}
+		}
+		return err
+	}
+	fmt.Fprintf(o.Out, "sandbox %s is ready\n", namespace.Name)
+	return nil
+}
+
+// SandboxInfos moves the namespaced objects of infos to namespace, leaving out the namespaces.
+// It returns an error for the other cluster-scoped objects.
+func SandboxInfos(infos []*resource.Info, namespace string) ([]*resource.Info, error) {
+	var sandboxed []*resource.Info
+	for _, info := range infos {
+		gk := info.Mapping.GroupVersionKind.GroupKind()
+		if gk.Group == "" && gk.Kind == "Namespace" {
+			continue
+		}
+		if info.Mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			return nil, fmt.Errorf("%s is cluster-scoped and cannot be deployed to a sandbox", info.ObjectName())
+		}
+		if u, ok := info.Object.(*unstructured.Unstructured); ok {
+			u.SetNamespace(namespace)
+		}
+		info.Namespace = namespace
+		sandboxed = append(sandboxed, info)
+	}
+	return sandboxed, nil
+}
+
+// scheduleCleanup creates the objects deleting namespace after the TTL.
+func (o *DeployOptions) scheduleCleanup(namespace *corev1.Namespace) error {
+	account, role, binding, job := CleanupObjects(namespace, o.TTL, o.CleanupImage)
+	if _, err := o.Client.CoreV1().ServiceAccounts(namespace.Name).Create(context.TODO(), account, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	if _, err := o.Client.RbacV1().ClusterRoles().Create(context.TODO(), role, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	if _, err := o.Client.RbacV1().ClusterRoleBindings().Create(context.TODO(), binding, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	_, err := o.KruiseClient.AppsV1alpha1().BroadcastJobs(namespace.Name).Create(context.TODO(), job, metav1.CreateOptions{})
+	return err
+}
+
+// CleanupObjects returns the objects deleting namespace after ttl: the service account of the
+// pod, the cluster role and binding allowing it to delete the namespace, owned by the namespace
+// so that they are garbage collected with it, and the BroadcastJob running the pod.
+func CleanupObjects(namespace *corev1.Namespace, ttl time.Duration, image string) (*corev1.ServiceAccount, *rbacv1.ClusterRole, *rbacv1.ClusterRoleBinding, *kruiseappsv1alpha1.BroadcastJob) {
+	labels := map[string]string{SandboxLabel: namespace.Name}
+	owner := metav1.OwnerReference{APIVersion: "v1", Kind: "Namespace", Name: namespace.Name, UID: namespace.UID}
+	clusterName := cleanupName + "-" + namespace.Name
+
+	account := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace.Name, Name: cleanupName, Labels: labels},
+	}
+	role := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterName, Labels: labels, OwnerReferences: []metav1.OwnerReference{owner}},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups:     []string{""},
+			Resources:     []string{"namespaces"},
+			ResourceNames: []string{namespace.Name},
+			Verbs:         []string{"get", "delete"},
+		}},
+	}
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterName, Labels: labels, OwnerReferences: []metav1.OwnerReference{owner}},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterName},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: namespace.Name, Name: cleanupName}},
+	}
+
+	parallelism := intstr.FromInt(1)
+	job := &kruiseappsv1alpha1.BroadcastJob{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace.Name, Name: cleanupName, Labels: labels},
+		Spec: kruiseappsv1alpha1.BroadcastJobSpec{
+			// a single pod, on any node, is enough to delete the namespace
+			Parallelism: &parallelism,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: cleanupName,
+					RestartPolicy:      corev1.RestartPolicyOnFailure,
+					Containers: []corev1.Container{{
+						Name:    "cleanup",
+						Image:   image,
+						Command: []string{"/bin/sh", "-c", fmt.Sprintf("sleep %d && kubectl delete namespace %s --wait=false", int64(ttl.Seconds()), namespace.Name)},
+					}},
+				},
+			},
+			CompletionPolicy: kruiseappsv1alpha1.CompletionPolicy{Type: kruiseappsv1alpha1.Never},
+		},
+	}
+	return account, role, binding, job
+}
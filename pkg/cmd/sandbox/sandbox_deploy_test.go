@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sandbox
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newInfo(group, version, kind, namespace, name string, scope meta.RESTScope) *resource.Info {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: group, Version: version, Kind: kind})
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return &resource.Info{
+		Namespace: namespace,
+		Name:      name,
+		Object:    u,
+		Mapping:   &meta.RESTMapping{GroupVersionKind: u.GroupVersionKind(), Scope: scope},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   func(o *DeployOptions)
+		expectErr string
+	}{
+		{name: "valid", options: func(o *DeployOptions) {}},
+		{name: "no manifests", options: func(o *DeployOptions) { o.Filenames = nil }, expectErr: "must be given with -f or -k"},
+		{name: "short ttl", options: func(o *DeployOptions) { o.TTL = 30 * time.Second }, expectErr: "invalid --ttl"},
+		{name: "invalid prefix", options: func(o *DeployOptions) { o.NamePrefix = "PR_1"; o.Namespace = "PR_1-abcde" }, expectErr: "invalid --name-prefix"},
+	}
+	for _, test := range tests {
+		o := NewDeployOptions(genericclioptions.NewTestIOStreamsDiscard())
+		o.Filenames = []string{"app/"}
+		o.Namespace = "sandbox-abcde"
+		test.options(o)
+		err := o.Validate()
+		if len(test.expectErr) == 0 && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if len(test.expectErr) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectErr)) {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestSandboxInfos(t *testing.T) {
+	infos, err := SandboxInfos([]*resource.Info{
+		newInfo("", "v1", "Namespace", "", "demo", meta.RESTScopeRoot),
+		newInfo("apps.kruise.io", "v1alpha1", "CloneSet", "demo", "web", meta.RESTScopeNamespace),
+		newInfo("", "v1", "ConfigMap", "default", "config", meta.RESTScopeNamespace),
+	}, "sandbox-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("expected the namespace to be left out, got %d objects", len(infos))
+	}
+	for _, info := range infos {
+		if info.Namespace != "sandbox-abcde" || info.Object.(*unstructured.Unstructured).GetNamespace() != "sandbox-abcde" {
+			t.Errorf("expected %s in namespace sandbox-abcde, got %s", info.Name, info.Namespace)
+		}
+	}
+
+	_, err = SandboxInfos([]*resource.Info{
+		newInfo("apps.kruise.io", "v1alpha1", "SidecarSet", "", "log", meta.RESTScopeRoot),
+	}, "sandbox-abcde")
+	if err == nil || !strings.Contains(err.Error(), "cluster-scoped") {
+		t.Errorf("expected a cluster-scoped error, got %v", err)
+	}
+}
+
+func TestScheduleCleanup(t *testing.T) {
+	o := NewDeployOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.TTL = 90 * time.Minute
+	o.Client = fake.NewSimpleClientset()
+	o.KruiseClient = kruisefake.NewSimpleClientset()
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sandbox-abcde", UID: "uid"}}
+	if err := o.scheduleCleanup(namespace); err != nil {
+		t.Fatal(err)
+	}
+
+	role, err := o.Client.RbacV1().ClusterRoles().Get(context.TODO(), "kubectl-kruise-sandbox-cleanup-sandbox-abcde", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs := role.OwnerReferences; len(refs) != 1 || refs[0].Kind != "Namespace" || refs[0].UID != "uid" {
+		t.Errorf("expected the cluster role to be owned by the namespace, got %v", refs)
+	}
+	if names := role.Rules[0].ResourceNames; len(names) != 1 || names[0] != "sandbox-abcde" {
+		t.Errorf("expected the cluster role to only allow deleting the namespace, got %v", role.Rules)
+	}
+	binding, err := o.Client.RbacV1().ClusterRoleBindings().Get(context.TODO(), "kubectl-kruise-sandbox-cleanup-sandbox-abcde", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject := binding.Subjects[0]; subject.Namespace != "sandbox-abcde" || subject.Name != cleanupName {
+		t.Errorf("unexpected subject %v", subject)
+	}
+	if _, err := o.Client.CoreV1().ServiceAccounts("sandbox-abcde").Get(context.TODO(), cleanupName, metav1.GetOptions{}); err != nil {
+		t.Error(err)
+	}
+
+	job, err := o.KruiseClient.AppsV1alpha1().BroadcastJobs("sandbox-abcde").Get(context.TODO(), cleanupName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Spec.Parallelism.IntValue() != 1 {
+		t.Errorf("expected a single cleanup pod, got parallelism %v", job.Spec.Parallelism)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if command := strings.Join(container.Command, " "); command != "/bin/sh -c sleep 5400 && kubectl delete namespace sandbox-abcde --wait=false" {
+		t.Errorf("unexpected cleanup command %q", command)
+	}
+	if container.Image != o.CleanupImage || job.Spec.Template.Spec.ServiceAccountName != cleanupName {
+		t.Errorf("unexpected cleanup pod %+v", job.Spec.Template.Spec)
+	}
+}
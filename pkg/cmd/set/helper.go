@@ -19,6 +19,9 @@ package set
 import (
 	"strings"
 
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/openkruise/kruise-tools/pkg/policy"
+	"github.com/openkruise/kruise-tools/pkg/writeback"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -93,6 +96,8 @@ type PatchFn func(runtime.Object) ([]byte, error)
 
 // CalculatePatch calls the mutation function on the provided info object, and generates a strategic merge patch for
 // the changes in the object. Encoder must be able to encode the info into the appropriate destination type.
+// Changes denied by the policies of the config file are returned as errors, and changes are
+// recorded for --write-back.
 // This function returns whether the mutation function made any change in the original object.
 func CalculatePatch(patch *Patch, encoder runtime.Encoder, mutateFn PatchFn) bool {
 	patch.Before, patch.Err = runtime.Encode(encoder, patch.Info.Object)
@@ -105,9 +110,39 @@ func CalculatePatch(patch *Patch, encoder runtime.Encoder, mutateFn PatchFn) boo
 	}
 
 	patch.Patch, patch.Err = strategicpatch.CreateTwoWayMergePatch(patch.Before, patch.After, patch.Info.Object)
+	// the policies gate changes only
+	if patch.Err == nil && len(patch.Patch) > 0 && string(patch.Patch) != "{}" {
+		patch.Err = policy.Check(patch.Before, patch.After)
+	}
+	if recording, only := writeback.Recording(); recording && patch.Err == nil && len(patch.Patch) > 0 && string(patch.Patch) != "{}" {
+		writeback.Record(changeFor(patch))
+		// with --write-back-only, the change is not applied to the cluster
+		if only {
+			patch.Patch = []byte("{}")
+			return false
+		}
+	}
 	return true
 }
 
+// changeFor returns the change of patch to write back.
+func changeFor(patch *Patch) writeback.Change {
+	change := writeback.Change{
+		Namespace:  patch.Info.Namespace,
+		Name:       patch.Info.Name,
+		Before:     patch.Before,
+		After:      patch.After,
+		Patch:      patch.Patch,
+		DataStruct: patch.Info.Object,
+	}
+	if patch.Info.Mapping != nil {
+		change.GroupKind = patch.Info.Mapping.GroupVersionKind.GroupKind()
+	} else {
+		change.GroupKind = patch.Info.Object.GetObjectKind().GroupVersionKind().GroupKind()
+	}
+	return change
+}
+
 // CalculatePatches calculates patches on each provided info object. If the provided mutateFn
 // makes no change in an object, the object is not included in the final list of patches.
 func CalculatePatches(infos []*resource.Info, encoder runtime.Encoder, mutateFn PatchFn) []*Patch {
@@ -121,6 +156,34 @@ func CalculatePatches(infos []*resource.Info, encoder runtime.Encoder, mutateFn
 	return patches
 }
 
+// mergePatch returns a JSON merge patch between the Before and After states of the patch.
+// Kruise workloads are custom resources and do not accept strategic merge patches, so commands
+// that rewrite list fields of a pod template send this patch instead.
+func mergePatch(patch *Patch) ([]byte, error) {
+	return jsonpatch.CreateMergePatch(patch.Before, patch.After)
+}
+
+// updateStringMap returns a copy of existing with the keys in remove deleted and the entries of add set.
+func updateStringMap(existing, add map[string]string, remove []string) map[string]string {
+	if len(add) == 0 && len(remove) == 0 {
+		return existing
+	}
+	out := map[string]string{}
+	for k, v := range existing {
+		out[k] = v
+	}
+	for _, k := range remove {
+		delete(out, k)
+	}
+	for k, v := range add {
+		out[k] = v
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
 func findEnv(env []v1.EnvVar, name string) (v1.EnvVar, bool) {
 	for _, e := range env {
 		if e.Name == name {
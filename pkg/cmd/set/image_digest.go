@@ -0,0 +1,426 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	dockerHubRegistry = "docker.io"
+	dockerHubHost     = "registry-1.docker.io"
+)
+
+// manifestMediaTypes are the manifests a tag is resolved to, multi-arch indexes first so that the
+// digest is the one of the tag, whatever the platform of the nodes.
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// DigestResolver is a func that returns the digest-pinned reference of an image, authenticating to
+// its registry with the image pull secrets of a pod in the given namespace.
+type DigestResolver func(image, namespace string, pullSecrets []corev1.LocalObjectReference) (string, error)
+
+// imageReference is an image reference split into the parts the registry API needs.
+type imageReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// parseImageReference splits image as the container runtimes do: the first component is the
+// registry if it looks like a host, Docker Hub otherwise, and the tag defaults to latest.
+func parseImageReference(image string) (imageReference, error) {
+	ref := imageReference{}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	if len(name) == 0 {
+		return ref, fmt.Errorf("invalid image reference %q", image)
+	}
+	if i := strings.Index(name, "/"); i >= 0 && (strings.ContainsAny(name[:i], ".:") || name[:i] == "localhost") {
+		ref.Registry, ref.Repository = name[:i], name[i+1:]
+	} else {
+		ref.Registry, ref.Repository = dockerHubRegistry, name
+		if !strings.Contains(name, "/") {
+			ref.Repository = "library/" + name
+		}
+	}
+	if len(ref.Tag) == 0 {
+		ref.Tag = "latest"
+	}
+	return ref, nil
+}
+
+// host returns the host of the registry API.
+func (r imageReference) host() string {
+	if r.Registry == dockerHubRegistry || r.Registry == "index.docker.io" {
+		return dockerHubHost
+	}
+	return r.Registry
+}
+
+// registryCredentials are the username and password of a registry.
+type registryCredentials struct {
+	Username string
+	Password string
+}
+
+// registryClient calls the registry API with the image pull secrets of the resources, caching the
+// digests of each invocation.
+type registryClient struct {
+	client    *http.Client
+	clientset kubernetes.Interface
+	cache     map[string]string
+	platforms map[string][]string
+}
+
+func newRegistryClient(client *http.Client, clientset kubernetes.Interface) *registryClient {
+	return &registryClient{client: client, clientset: clientset, cache: map[string]string{}, platforms: map[string][]string{}}
+}
+
+// newDigestResolver returns a DigestResolver that reads the image pull secrets with clientset,
+// if it is not nil.
+func newDigestResolver(client *http.Client, clientset kubernetes.Interface) DigestResolver {
+	return newRegistryClient(client, clientset).resolve
+}
+
+func (r *registryClient) resolve(image, namespace string, pullSecrets []corev1.LocalObjectReference) (string, error) {
+	ref, err := parseImageReference(image)
+	if err != nil {
+		return "", err
+	}
+	if len(ref.Digest) > 0 {
+		return image, nil
+	}
+	key := cacheKey(image, namespace, pullSecrets)
+	if digest, ok := r.cache[key]; ok {
+		return image + "@" + digest, nil
+	}
+
+	credentials, err := r.credentials(ref.Registry, namespace, pullSecrets)
+	if err != nil {
+		return "", err
+	}
+	digest, err := r.digest(ref, credentials)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve the digest of %s: %v", image, err)
+	}
+	r.cache[key] = digest
+	return image + "@" + digest, nil
+}
+
+// ImageChecker is a func that returns whether an image exists in its registry, authenticating with
+// the image pull secrets of a pod in the given namespace.
+type ImageChecker func(image, namespace string, pullSecrets []corev1.LocalObjectReference) (bool, error)
+
+// NewImageChecker returns an ImageChecker that reads the image pull secrets with clientset, if it
+// is not nil.
+func NewImageChecker(client *http.Client, clientset kubernetes.Interface) ImageChecker {
+	return newRegistryClient(client, clientset).exists
+}
+
+// exists returns whether the manifest of the tag, or of the digest, of image is in its registry.
+func (r *registryClient) exists(image, namespace string, pullSecrets []corev1.LocalObjectReference) (bool, error) {
+	ref, err := parseImageReference(image)
+	if err != nil {
+		return false, err
+	}
+	credentials, err := r.credentials(ref.Registry, namespace, pullSecrets)
+	if err != nil {
+		return false, err
+	}
+	reference := ref.Tag
+	if len(ref.Digest) > 0 {
+		reference = ref.Digest
+	}
+	resp, err := r.get(http.MethodHead, ref, "manifests/"+reference, credentials)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("%s %s returned %s", resp.Request.Method, resp.Request.URL, resp.Status)
+}
+
+// cacheKey returns the key of what the registry returned for image with the pull secrets of namespace.
+func cacheKey(image, namespace string, pullSecrets []corev1.LocalObjectReference) string {
+	var secretNames []string
+	for _, secret := range pullSecrets {
+		secretNames = append(secretNames, secret.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", namespace, strings.Join(secretNames, ","), image)
+}
+
+// credentials returns the credentials of registry in the first image pull secret that has some.
+// Missing secrets are ignored, as the kubelet does.
+func (r *registryClient) credentials(registry, namespace string, pullSecrets []corev1.LocalObjectReference) (*registryCredentials, error) {
+	if r.clientset == nil {
+		return nil, nil
+	}
+	for _, ref := range pullSecrets {
+		secret, err := r.clientset.CoreV1().Secrets(namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if credentials := credentialsFromSecret(secret, registry); credentials != nil {
+			return credentials, nil
+		}
+	}
+	return nil, nil
+}
+
+// dockerConfigEntry is an entry of the auths of a docker config
+type dockerConfigEntry struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
+// credentialsFromSecret returns the credentials of registry in a dockerconfigjson or dockercfg secret.
+func credentialsFromSecret(secret *corev1.Secret, registry string) *registryCredentials {
+	auths := map[string]dockerConfigEntry{}
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		config := struct {
+			Auths map[string]dockerConfigEntry `json:"auths"`
+		}{}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil
+		}
+		auths = config.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	for server, entry := range auths {
+		if normalizeRegistry(server) != normalizeRegistry(registry) {
+			continue
+		}
+		if len(entry.Auth) > 0 {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				continue
+			}
+			if parts := strings.SplitN(string(decoded), ":", 2); len(parts) == 2 {
+				return &registryCredentials{Username: parts[0], Password: parts[1]}
+			}
+		}
+		return &registryCredentials{Username: entry.Username, Password: entry.Password}
+	}
+	return nil
+}
+
+// normalizeRegistry returns the host of a registry given as in docker configs, e.g.
+// https://index.docker.io/v1/ is docker.io.
+func normalizeRegistry(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	if i := strings.Index(server, "/"); i >= 0 {
+		server = server[:i]
+	}
+	switch server {
+	case "index.docker.io", dockerHubHost:
+		return dockerHubRegistry
+	}
+	return server
+}
+
+// digest returns the digest of the manifest of the tag of ref.
+func (r *registryClient) digest(ref imageReference, credentials *registryCredentials) (string, error) {
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		resp, err := r.get(method, ref, "manifests/"+ref.Tag, credentials)
+		if err != nil {
+			return "", err
+		}
+		digest, err := digestFromResponse(resp, method == http.MethodGet)
+		resp.Body.Close()
+		if err != nil || len(digest) > 0 {
+			return digest, err
+		}
+		// some registries do not return the digest of HEAD requests, compute it from the manifest
+	}
+	return "", fmt.Errorf("the registry did not return the digest of the manifest")
+}
+
+// get requests the path of the repository of ref, e.g. manifests/<tag>, answering the
+// authentication challenge of the registry if any.
+func (r *registryClient) get(method string, ref imageReference, path string, credentials *registryCredentials) (*http.Response, error) {
+	requestURL := fmt.Sprintf("https://%s/v2/%s/%s", ref.host(), ref.Repository, path)
+	resp, err := r.request(method, requestURL, "")
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+	authorization, err := r.authorize(resp.Header.Get("WWW-Authenticate"), credentials)
+	if err != nil {
+		return nil, err
+	}
+	return r.request(method, requestURL, authorization)
+}
+
+func (r *registryClient) request(method, requestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(method, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if len(authorization) > 0 {
+		req.Header.Set("Authorization", authorization)
+	}
+	return r.client.Do(req)
+}
+
+func digestFromResponse(resp *http.Response, computeFromBody bool) (string, error) {
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s returned %s", resp.Request.Method, resp.Request.URL, resp.Status)
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); len(digest) > 0 {
+		return digest, nil
+	}
+	if !computeFromBody {
+		return "", nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
+
+// authorize returns the Authorization header that answers the challenge of the registry.
+func (r *registryClient) authorize(challenge string, credentials *registryCredentials) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if credentials == nil {
+			return "", fmt.Errorf("the registry requires credentials, add an image pull secret to the workload")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials.Username+":"+credentials.Password)), nil
+	case "bearer":
+		tokenURL, err := url.Parse(params["realm"])
+		if err != nil || len(params["realm"]) == 0 {
+			return "", fmt.Errorf("invalid bearer challenge %q", challenge)
+		}
+		query := tokenURL.Query()
+		for _, key := range []string{"service", "scope"} {
+			if len(params[key]) > 0 {
+				query.Set(key, params[key])
+			}
+		}
+		tokenURL.RawQuery = query.Encode()
+		req, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		if credentials != nil {
+			req.SetBasicAuth(credentials.Username, credentials.Password)
+		}
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("unable to get a registry token: %s", resp.Status)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		token := struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}{}
+		if err := json.Unmarshal(body, &token); err != nil {
+			return "", err
+		}
+		if len(token.Token) == 0 {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+	default:
+		return "", fmt.Errorf("unsupported registry authentication %q", challenge)
+	}
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull".
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+	rest := parts[1]
+	for len(rest) > 0 {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return parts[0], params
+}
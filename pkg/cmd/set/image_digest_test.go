@@ -0,0 +1,137 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected imageReference
+	}{
+		{"nginx", imageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{"nginx:1.21", imageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.21"}},
+		{"openkruise/kruise-manager:v1.0", imageReference{Registry: "docker.io", Repository: "openkruise/kruise-manager", Tag: "v1.0"}},
+		{"localhost:5000/app", imageReference{Registry: "localhost:5000", Repository: "app", Tag: "latest"}},
+		{"registry.example.com/team/app:v2@sha256:abc", imageReference{Registry: "registry.example.com", Repository: "team/app", Tag: "v2", Digest: "sha256:abc"}},
+	}
+	for _, test := range tests {
+		got, err := parseImageReference(test.image)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.image, err)
+		} else if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.image, test.expected, got)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+	expected := map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "repository:library/nginx:pull"}
+	if scheme != "Bearer" || !reflect.DeepEqual(params, expected) {
+		t.Errorf("unexpected challenge %s %v", scheme, params)
+	}
+}
+
+func TestDigestResolver(t *testing.T) {
+	const digest = "sha256:0123456789abcdef"
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if user, password, ok := r.BasicAuth(); !ok || user != "robot" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("scope") != "repository:team/app:pull" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"token":"t0k3n"}`)
+		case r.URL.Path == "/v2/team/app/manifests/v1":
+			if r.Header.Get("Authorization") != "Bearer t0k3n" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:team/app:pull"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if !strings.Contains(r.Header.Get("Accept"), "manifest.list.v2+json") {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	auth := base64.StdEncoding.EncodeToString([]byte("robot:secret"))
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pull"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(`{"auths":{"https://%s":{"auth":"%s"}}}`, registry, auth)),
+		},
+	})
+	resolve := newDigestResolver(server.Client(), clientset)
+	pullSecrets := []corev1.LocalObjectReference{{Name: "missing"}, {Name: "pull"}}
+
+	image := registry + "/team/app:v1"
+	got, err := resolve(image, "default", pullSecrets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := image + "@" + digest; got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	pinned := image + "@sha256:fedcba"
+	if got, err := resolve(pinned, "default", pullSecrets); err != nil || got != pinned {
+		t.Errorf("expected %s to be left as is, got %s, %v", pinned, got, err)
+	}
+
+	if _, err := resolve(image, "default", nil); err == nil {
+		t.Errorf("expected an error without credentials")
+	}
+	if _, err := resolve(registry+"/team/app:v2", "default", pullSecrets); err == nil {
+		t.Errorf("expected an error for an unknown tag")
+	}
+
+	exists := NewImageChecker(server.Client(), clientset)
+	if found, err := exists(image, "default", pullSecrets); err != nil || !found {
+		t.Errorf("expected %s to exist, got %v, %v", image, found, err)
+	}
+	if found, err := exists(registry+"/team/app:v2", "default", pullSecrets); err != nil || found {
+		t.Errorf("expected an unknown tag not to exist, got %v, %v", found, err)
+	}
+	if _, err := exists(image, "default", nil); err == nil {
+		t.Errorf("expected an error checking an image without credentials")
+	}
+}
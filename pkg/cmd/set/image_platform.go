@@ -0,0 +1,148 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PlatformChecker is a func that returns an error if the registry has no manifest of image for one
+// of the platforms, given as os/arch.
+type PlatformChecker func(image, namespace string, pullSecrets []corev1.LocalObjectReference, platforms []string) error
+
+// newPlatformChecker returns a PlatformChecker that reads the image pull secrets with clientset,
+// if it is not nil.
+func newPlatformChecker(client *http.Client, clientset kubernetes.Interface) PlatformChecker {
+	return newRegistryClient(client, clientset).checkPlatforms
+}
+
+// nodePlatforms returns the os/arch platforms of the nodes matching nodeSelector, where the pods
+// of a pod spec may run.
+func nodePlatforms(nodes []corev1.Node, nodeSelector map[string]string) []string {
+	selector := labels.SelectorFromSet(nodeSelector)
+	platforms := sets.NewString()
+	for _, node := range nodes {
+		if !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		info := node.Status.NodeInfo
+		if len(info.OperatingSystem) > 0 && len(info.Architecture) > 0 {
+			platforms.Insert(info.OperatingSystem + "/" + info.Architecture)
+		}
+	}
+	return platforms.List()
+}
+
+func (r *registryClient) checkPlatforms(image, namespace string, pullSecrets []corev1.LocalObjectReference, platforms []string) error {
+	ref, err := parseImageReference(image)
+	if err != nil {
+		return err
+	}
+	key := cacheKey(image, namespace, pullSecrets)
+	available, ok := r.platforms[key]
+	if !ok {
+		credentials, err := r.credentials(ref.Registry, namespace, pullSecrets)
+		if err != nil {
+			return err
+		}
+		if available, err = r.imagePlatforms(ref, credentials); err != nil {
+			return fmt.Errorf("unable to check the platforms of %s: %v", image, err)
+		}
+		r.platforms[key] = available
+	}
+
+	var missing []string
+	for _, platform := range platforms {
+		if !sets.NewString(available...).Has(platform) {
+			missing = append(missing, platform)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("image %s has no manifest for %s of the nodes, it is only available for %s",
+			image, strings.Join(missing, ", "), strings.Join(available, ", "))
+	}
+	return nil
+}
+
+// imageManifest has the fields of image manifests and indexes that tell their platforms.
+type imageManifest struct {
+	Manifests []struct {
+		Platform *struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"platform,omitempty"`
+	} `json:"manifests,omitempty"`
+	Config *struct {
+		Digest string `json:"digest"`
+	} `json:"config,omitempty"`
+}
+
+// imagePlatforms returns the os/arch platforms the manifest of ref is available for, reading the
+// config of the image when the manifest is not an index.
+func (r *registryClient) imagePlatforms(ref imageReference, credentials *registryCredentials) ([]string, error) {
+	reference := ref.Tag
+	if len(ref.Digest) > 0 {
+		reference = ref.Digest
+	}
+	manifest := imageManifest{}
+	if err := r.getJSON(ref, "manifests/"+reference, credentials, &manifest); err != nil {
+		return nil, err
+	}
+
+	platforms := sets.NewString()
+	for _, m := range manifest.Manifests {
+		// attestations are stored in indexes as unknown/unknown manifests
+		if m.Platform != nil && m.Platform.OS != "unknown" {
+			platforms.Insert(m.Platform.OS + "/" + m.Platform.Architecture)
+		}
+	}
+	if len(manifest.Manifests) == 0 && manifest.Config != nil {
+		config := struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		}{}
+		if err := r.getJSON(ref, "blobs/"+manifest.Config.Digest, credentials, &config); err != nil {
+			return nil, err
+		}
+		platforms.Insert(config.OS + "/" + config.Architecture)
+	}
+	return platforms.List(), nil
+}
+
+func (r *registryClient) getJSON(ref imageReference, path string, credentials *registryCredentials, into interface{}) error {
+	resp, err := r.get(http.MethodGet, ref, path, credentials)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(into)
+	case http.StatusNotFound:
+		return fmt.Errorf("%s not found in the registry %s", path, ref.Registry)
+	default:
+		return fmt.Errorf("GET %s returned %s", resp.Request.URL, resp.Status)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodePlatforms(t *testing.T) {
+	node := func(name, arch string) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kubernetes.io/arch": arch}},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OperatingSystem: "linux", Architecture: arch}},
+		}
+	}
+	nodes := []corev1.Node{node("a", "amd64"), node("b", "arm64"), node("c", "amd64")}
+
+	if got, expected := nodePlatforms(nodes, nil), []string{"linux/amd64", "linux/arm64"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got, expected := nodePlatforms(nodes, map[string]string{"kubernetes.io/arch": "arm64"}), []string{"linux/arm64"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestCheckPlatforms(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/multi/manifests/v1":
+			fmt.Fprint(w, `{"manifests":[{"platform":{"os":"linux","architecture":"amd64"}},{"platform":{"os":"linux","architecture":"arm64"}},{"platform":{"os":"unknown","architecture":"unknown"}}]}`)
+		case "/v2/single/manifests/v1":
+			fmt.Fprint(w, `{"config":{"digest":"sha256:c0nf"}}`)
+		case "/v2/single/blobs/sha256:c0nf":
+			fmt.Fprint(w, `{"os":"linux","architecture":"amd64"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+	check := newPlatformChecker(server.Client(), nil)
+
+	tests := []struct {
+		image     string
+		platforms []string
+		err       string
+	}{
+		{image: "multi:v1", platforms: []string{"linux/amd64", "linux/arm64"}},
+		{image: "single:v1", platforms: []string{"linux/amd64"}},
+		{image: "single:v1", platforms: []string{"linux/amd64", "linux/arm64"}, err: "no manifest for linux/arm64"},
+		{image: "missing:v1", platforms: []string{"linux/amd64"}, err: "not found"},
+	}
+	for _, test := range tests {
+		err := check(registry+"/"+test.image, "default", nil, test.platforms)
+		if len(test.err) == 0 && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.image, err)
+		} else if len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: expected error %q, got %v", test.image, test.err, err)
+		}
+	}
+}
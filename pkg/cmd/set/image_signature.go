@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// CosignPathEnv overrides the path of the cosign binary that verifies image signatures.
+const CosignPathEnv = "KUBECTL_KRUISE_COSIGN"
+
+// ImageVerifier is a func that fails unless the image is signed, or attested, as required.
+type ImageVerifier func(image string) error
+
+// cosignVerifier returns an ImageVerifier that runs cosign to verify the signature of images with
+// the public key at key, or their attestation of the given predicate type if it is not empty.
+func cosignVerifier(key, attestationType string) ImageVerifier {
+	return func(image string) error {
+		cosign := os.Getenv(CosignPathEnv)
+		if len(cosign) == 0 {
+			var err error
+			if cosign, err = exec.LookPath("cosign"); err != nil {
+				return fmt.Errorf("cosign is required to verify image signatures: %v", err)
+			}
+		}
+		stderr := &bytes.Buffer{}
+		cmd := exec.Command(cosign, cosignArgs(key, attestationType, image)...)
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			if message := strings.TrimSpace(stderr.String()); len(message) > 0 {
+				return fmt.Errorf("%v: %s", err, message)
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// cosignArgs returns the arguments of cosign to verify the signature or the attestation of image.
+func cosignArgs(key, attestationType, image string) []string {
+	if len(attestationType) > 0 {
+		return []string{"verify-attestation", "--key", key, "--type", attestationType, image}
+	}
+	return []string{"verify", "--key", key, image}
+}
+
+// verifyImages verifies each image once, in order, and returns the errors of the images that
+// failed the verification.
+func verifyImages(images map[string]string, resolve ImageResolver, verify ImageVerifier) []error {
+	unique := map[string]bool{}
+	for _, image := range images {
+		resolved, err := resolve(image)
+		if err != nil {
+			// reported when the image is set
+			continue
+		}
+		unique[resolved] = true
+	}
+	var sorted []string
+	for image := range unique {
+		sorted = append(sorted, image)
+	}
+	sort.Strings(sorted)
+
+	var errs []error
+	for _, image := range sorted {
+		if err := verify(image); err != nil {
+			errs = append(errs, fmt.Errorf("error: image %q failed signature verification: %v", image, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	goruntime "runtime"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestCosignArgs(t *testing.T) {
+	if got, expected := cosignArgs("k.pub", "", "nginx:1.21"), []string{"verify", "--key", "k.pub", "nginx:1.21"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if got, expected := cosignArgs("k.pub", "spdx", "nginx:1.21"), []string{"verify-attestation", "--key", "k.pub", "--type", "spdx", "nginx:1.21"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestVerifyImages(t *testing.T) {
+	var verified []string
+	verify := func(image string) error {
+		verified = append(verified, image)
+		if strings.HasPrefix(image, "unsigned") {
+			return fmt.Errorf("no signatures found")
+		}
+		return nil
+	}
+	errs := verifyImages(map[string]string{"app": "nginx:1.21", "sidecar": "unsigned:1.0", "init": "nginx:1.21"}, resolveImageFunc, verify)
+	if expected := []string{"nginx:1.21", "unsigned:1.0"}; !reflect.DeepEqual(verified, expected) {
+		t.Errorf("expected images %q to be verified once, got %q", expected, verified)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "unsigned:1.0") {
+		t.Errorf("expected an error for unsigned:1.0, got %v", errs)
+	}
+}
+
+func TestCosignVerifier(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("the fake cosign is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "cosign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cosign := filepath.Join(dir, "cosign")
+	script := "#!/bin/sh\ncase \"$4\" in signed*) exit 0;; esac\necho \"no matching signatures\" >&2\nexit 1\n"
+	if err := ioutil.WriteFile(cosign, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv(CosignPathEnv, os.Getenv(CosignPathEnv))
+	os.Setenv(CosignPathEnv, cosign)
+
+	verify := cosignVerifier("k.pub", "")
+	if err := verify("signed:1.0"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := verify("unsigned:1.0"); err == nil || !strings.Contains(err.Error(), "no matching signatures") {
+		t.Errorf("expected the error of cosign, got %v", err)
+	}
+}
+
+func TestSetImageUnsignedLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdImage(tf, streams)
+	cmd.Flags().Set("output", "name")
+	cmd.Flags().Set("local", "true")
+
+	opts := SetImageOptions{
+		PrintFlags:      genericclioptions.NewPrintFlags("").WithDefaultOutput("name").WithTypeSetter(scheme.Scheme),
+		FilenameOptions: resource.FilenameOptions{Filenames: []string{"../../../testdata/controller.yaml"}},
+		Local:           true,
+		VerifySignature: true,
+		CosignKey:       "k.pub",
+		IOStreams:       streams,
+	}
+	if err := opts.Complete(tf, cmd, []string{"cassandra=unsigned:1.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	opts.VerifyImage = func(image string) error { return fmt.Errorf("no signatures found") }
+	if err := opts.Run(); err == nil || !strings.Contains(err.Error(), "failed signature verification") {
+		t.Errorf("expected a verification error, got %v", err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("expected no resource to be updated, got %q", buf.String())
+	}
+}
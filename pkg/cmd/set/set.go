@@ -48,6 +48,18 @@ func NewCmdSet(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Co
 	cmd.AddCommand(NewCmdSubject(f, streams))
 	cmd.AddCommand(NewCmdServiceAccount(f, streams))
 	cmd.AddCommand(NewCmdEnv(f, streams))
+	cmd.AddCommand(NewCmdScheduling(f, streams))
+	cmd.AddCommand(NewCmdPull(f, streams))
+	cmd.AddCommand(NewCmdSecurity(f, streams))
+	cmd.AddCommand(NewCmdTemplateMetadata(f, streams))
+	cmd.AddCommand(NewCmdTermination(f, streams))
+	cmd.AddCommand(NewCmdReadinessGate(f, streams))
+	cmd.AddCommand(NewCmdUpdateStrategy(f, streams))
+	cmd.AddCommand(NewCmdPartition(f, streams))
+	cmd.AddCommand(NewCmdSurge(f, streams))
+	cmd.AddCommand(NewCmdVolume(f, streams))
+	cmd.AddCommand(NewCmdProbe(f, streams))
+	cmd.AddCommand(NewCmdLifecycle(f, streams))
 
 	return cmd
 }
@@ -23,9 +23,8 @@ import (
 	"sort"
 	"strings"
 
-	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
-	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	"github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
 	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	"github.com/spf13/cobra"
 
@@ -47,7 +46,8 @@ import (
 var (
 	validEnvNameRegexp = regexp.MustCompile("[^a-zA-Z0-9_]")
 	envResources       = `
-  	pod (po), replicationcontroller (rc), deployment (deploy), daemonset (ds), job, replicaset (rs), cloneset (cs)`
+  	pod (po), replicationcontroller (rc), deployment (deploy), daemonset (ds), job, replicaset (rs), cloneset (cs),
+  	advanced statefulset (asts), advanced daemonset (ads), broadcastjob (bcj), advancedcronjob (acj), uniteddeployment (ud)`
 
 	envLong = templates.LongDesc(`
 		Update environment variables on a pod template.
@@ -165,6 +165,7 @@ func NewCmdEnv(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Co
 	usage := "the resource to update the env"
 	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
 	cmd.Flags().StringVarP(&o.ContainerSelector, "containers", "c", o.ContainerSelector, "The names of containers in the selected pod templates to change - may use wildcards")
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc("containers", internalcmdutil.ContainerCompletionFunc(f, "")))
 	cmd.Flags().StringVarP(&o.From, "from", "", "", "The name of a resource from which to inject environment variables")
 	cmd.Flags().StringVarP(&o.Prefix, "prefix", "", "", "Prefix to append to variable names")
 	cmd.Flags().StringArrayVarP(&o.EnvParams, "env", "e", o.EnvParams, "Specify a key-value pair for an environment variable to set into each container.")
@@ -372,445 +373,179 @@ func (o *EnvOptions) RunEnv() error {
 		return nil
 	}
 
-	switch infos[0].Object.(type) {
-	case *kruiseappsv1alpha1.CloneSet:
-
-		obj, err := resource.
-			NewHelper(infos[0].Client, infos[0].Mapping).
-			Get(o.namespace, infos[0].Name)
-		if err != nil {
-			return err
-		}
-		res := obj.(*kruiseappsv1alpha1.CloneSet)
-
-		resolutionErrorsEncountered := false
-		containers, _ := selectContainers(res.Spec.Template.Spec.Containers, o.ContainerSelector)
-
-		objName, err := meta.NewAccessor().Name(res)
-		if err != nil {
-			return err
-		}
-
-		gvks, _, err := scheme.Scheme.ObjectKinds(res)
-		if err != nil {
-			return err
-		}
-
-		objKind := res.GetObjectKind().GroupVersionKind().Kind
-		if len(objKind) == 0 {
-			for _, gvk := range gvks {
-				if len(gvk.Kind) == 0 {
-					continue
-				}
-				if len(gvk.Version) == 0 || gvk.Version == runtime.APIVersionInternal {
-					continue
-				}
-
-				objKind = gvk.Kind
-				break
-			}
-		}
-
-		if len(containers) == 0 {
-			if gvks, _, err := scheme.Scheme.ObjectKinds(res); err == nil {
-				objKind := res.GetObjectKind().GroupVersionKind().Kind
-				if len(objKind) == 0 {
-					for _, gvk := range gvks {
-						if len(gvk.Kind) == 0 {
-							continue
-						}
-						if len(gvk.Version) == 0 || gvk.Version == runtime.APIVersionInternal {
-							continue
-						}
-
-						objKind = gvk.Kind
-						break
-					}
-				}
-
-				fmt.Fprintf(o.ErrOut, "warning: %s/%s does not have any containers matching %q\n", objKind, objName, o.ContainerSelector)
+	patches := CalculatePatches(infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		_, err := o.updatePodSpecForObject(obj, func(spec *v1.PodSpec) error {
+			resolutionErrorsEncountered := false
+			containers, _ := selectContainers(spec.Containers, o.ContainerSelector)
+			objName, err := meta.NewAccessor().Name(obj)
+			if err != nil {
+				return err
 			}
-			return nil
-		}
 
-		for _, c := range containers {
-			if !o.Overwrite {
-				if err := validateNoOverwrites(c.Env, env); err != nil {
-					return err
-				}
+			gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+			if err != nil {
+				return err
 			}
-
-			c.Env = updateEnv(c.Env, env, remove)
-			if o.List {
-				resolveErrors := map[string][]string{}
-				store := envutil.NewResourceStore()
-
-				fmt.Fprintf(o.Out, "# %s %s, container %s\n", objKind, objName, c.Name)
-				for _, env := range c.Env {
-					// Print the simple value
-					if env.ValueFrom == nil {
-						fmt.Fprintf(o.Out, "%s=%s\n", env.Name, env.Value)
+			objKind := obj.GetObjectKind().GroupVersionKind().Kind
+			if len(objKind) == 0 {
+				for _, gvk := range gvks {
+					if len(gvk.Kind) == 0 {
 						continue
 					}
-
-					// Print the reference version
-					if !o.Resolve {
-						fmt.Fprintf(o.Out, "# %s from %s\n", env.Name, envutil.GetEnvVarRefString(env.ValueFrom))
-						continue
-					}
-
-					value, err := envutil.GetEnvVarRefValue(o.clientset, o.namespace, store, env.ValueFrom, res, c)
-					// Print the resolved value
-					if err == nil {
-						fmt.Fprintf(o.Out, "%s=%s\n", env.Name, value)
+					if len(gvk.Version) == 0 || gvk.Version == runtime.APIVersionInternal {
 						continue
 					}
 
-					// Print the reference version and save the resolve error
-					fmt.Fprintf(o.Out, "# %s from %s\n", env.Name, envutil.GetEnvVarRefString(env.ValueFrom))
-					errString := err.Error()
-					resolveErrors[errString] = append(resolveErrors[errString], env.Name)
-					resolutionErrorsEncountered = true
+					objKind = gvk.Kind
+					break
 				}
-
-				// Print any resolution errors
-				var errs []string
-				for err, vars := range resolveErrors {
-					sort.Strings(vars)
-					errs = append(errs, fmt.Sprintf("error retrieving reference for %s: %v", strings.Join(vars, ", "), err))
-				}
-				sort.Strings(errs)
-				for _, err := range errs {
-					_, _ = fmt.Fprintln(o.ErrOut, err)
-				}
-			}
-		}
-
-		if !o.Local {
-			_, err := resource.
-				NewHelper(infos[0].Client, infos[0].Mapping).
-				DryRun(o.dryRunStrategy == cmdutil.DryRunServer).
-				Replace(infos[0].Namespace, infos[0].Name, true, res)
-			if err != nil {
-				return fmt.Errorf("failed to patch env update to pod template: %v", err)
 			}
-		}
-
-		if resolutionErrorsEncountered {
-			return errors.New("failed to retrieve valueFrom references")
-		}
-
-		if o.List {
-			return nil
-		}
-
-		if err := o.PrintObj(res, o.Out); err != nil {
-			return errors.New(err.Error())
-		}
-
-		return nil
-	case *kruiseappsv1beta1.StatefulSet:
-		obj, err := resource.
-			NewHelper(infos[0].Client, infos[0].Mapping).
-			DryRun(o.dryRunStrategy == cmdutil.DryRunServer).
-			Get(o.namespace, infos[0].Name)
-		if err != nil {
-			return err
-		}
-		res := obj.(*kruiseappsv1beta1.StatefulSet)
-
-		resolutionErrorsEncountered := false
-		containers, _ := selectContainers(res.Spec.Template.Spec.Containers, o.ContainerSelector)
-
-		objName, err := meta.NewAccessor().Name(res)
-		if err != nil {
-			return err
-		}
-
-		gvks, _, err := scheme.Scheme.ObjectKinds(res)
-		if err != nil {
-			return err
-		}
-
-		objKind := res.GetObjectKind().GroupVersionKind().Kind
-		if len(objKind) == 0 {
-			for _, gvk := range gvks {
-				if len(gvk.Kind) == 0 {
-					continue
-				}
-				if len(gvk.Version) == 0 || gvk.Version == runtime.APIVersionInternal {
-					continue
-				}
 
-				objKind = gvk.Kind
-				break
-			}
-		}
+			if len(containers) == 0 {
+				if gvks, _, err := scheme.Scheme.ObjectKinds(obj); err == nil {
+					objKind := obj.GetObjectKind().GroupVersionKind().Kind
+					if len(objKind) == 0 {
+						for _, gvk := range gvks {
+							if len(gvk.Kind) == 0 {
+								continue
+							}
+							if len(gvk.Version) == 0 || gvk.Version == runtime.APIVersionInternal {
+								continue
+							}
 
-		if len(containers) == 0 {
-			if gvks, _, err := scheme.Scheme.ObjectKinds(res); err == nil {
-				objKind := res.GetObjectKind().GroupVersionKind().Kind
-				if len(objKind) == 0 {
-					for _, gvk := range gvks {
-						if len(gvk.Kind) == 0 {
-							continue
+							objKind = gvk.Kind
+							break
 						}
-						if len(gvk.Version) == 0 || gvk.Version == runtime.APIVersionInternal {
-							continue
-						}
-
-						objKind = gvk.Kind
-						break
 					}
-				}
 
-				fmt.Fprintf(o.ErrOut, "warning: %s/%s does not have any containers matching %q\n", objKind, objName, o.ContainerSelector)
-			}
-			return nil
-		}
-
-		for _, c := range containers {
-			if !o.Overwrite {
-				if err := validateNoOverwrites(c.Env, env); err != nil {
-					return err
+					fmt.Fprintf(o.ErrOut, "warning: %s/%s does not have any containers matching %q\n", objKind, objName, o.ContainerSelector)
 				}
+				return nil
 			}
-
-			c.Env = updateEnv(c.Env, env, remove)
-			if o.List {
-				resolveErrors := map[string][]string{}
-				store := envutil.NewResourceStore()
-
-				fmt.Fprintf(o.Out, "# %s %s, container %s\n", objKind, objName, c.Name)
-				for _, env := range c.Env {
-					// Print the simple value
-					if env.ValueFrom == nil {
-						fmt.Fprintf(o.Out, "%s=%s\n", env.Name, env.Value)
-						continue
-					}
-
-					// Print the reference version
-					if !o.Resolve {
-						fmt.Fprintf(o.Out, "# %s from %s\n", env.Name, envutil.GetEnvVarRefString(env.ValueFrom))
-						continue
-					}
-
-					value, err := envutil.GetEnvVarRefValue(o.clientset, o.namespace, store, env.ValueFrom, res, c)
-					// Print the resolved value
-					if err == nil {
-						fmt.Fprintf(o.Out, "%s=%s\n", env.Name, value)
-						continue
+			for _, c := range containers {
+				if !o.Overwrite {
+					if err := validateNoOverwrites(c.Env, env); err != nil {
+						return err
 					}
-
-					// Print the reference version and save the resolve error
-					fmt.Fprintf(o.Out, "# %s from %s\n", env.Name, envutil.GetEnvVarRefString(env.ValueFrom))
-					errString := err.Error()
-					resolveErrors[errString] = append(resolveErrors[errString], env.Name)
-					resolutionErrorsEncountered = true
-				}
-
-				// Print any resolution errors
-				var errs []string
-				for err, vars := range resolveErrors {
-					sort.Strings(vars)
-					errs = append(errs, fmt.Sprintf("error retrieving reference for %s: %v", strings.Join(vars, ", "), err))
-				}
-				sort.Strings(errs)
-				for _, err := range errs {
-					_, _ = fmt.Fprintln(o.ErrOut, err)
 				}
-			}
-		}
-
-		if !o.Local {
-			_, err := resource.
-				NewHelper(infos[0].Client, infos[0].Mapping).
-				DryRun(o.dryRunStrategy == cmdutil.DryRunServer).
-				Replace(infos[0].Namespace, infos[0].Name, true, res)
-			if err != nil {
-				return fmt.Errorf("failed to patch env update to pod template: %v", err)
-			}
-		}
-
-		if resolutionErrorsEncountered {
-			return errors.New("failed to retrieve valueFrom references")
-		}
-
-		if o.List {
-			return nil
-		}
-
-		if err := o.PrintObj(res, o.Out); err != nil {
-			return errors.New(err.Error())
-		}
 
-		return nil
-	default:
-		patches := CalculatePatches(infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
-			_, err := o.updatePodSpecForObject(obj, func(spec *v1.PodSpec) error {
-				resolutionErrorsEncountered := false
-				containers, _ := selectContainers(spec.Containers, o.ContainerSelector)
-				objName, err := meta.NewAccessor().Name(obj)
-				if err != nil {
-					return err
-				}
+				c.Env = updateEnv(c.Env, env, remove)
+				if o.List {
+					resolveErrors := map[string][]string{}
+					store := envutil.NewResourceStore()
 
-				gvks, _, err := scheme.Scheme.ObjectKinds(obj)
-				if err != nil {
-					return err
-				}
-				objKind := obj.GetObjectKind().GroupVersionKind().Kind
-				if len(objKind) == 0 {
-					for _, gvk := range gvks {
-						if len(gvk.Kind) == 0 {
+					fmt.Fprintf(o.Out, "# %s %s, container %s\n", objKind, objName, c.Name)
+					for _, env := range c.Env {
+						// Print the simple value
+						if env.ValueFrom == nil {
+							fmt.Fprintf(o.Out, "%s=%s\n", env.Name, env.Value)
 							continue
 						}
-						if len(gvk.Version) == 0 || gvk.Version == runtime.APIVersionInternal {
+
+						// Print the reference version
+						if !o.Resolve {
+							fmt.Fprintf(o.Out, "# %s from %s\n", env.Name, envutil.GetEnvVarRefString(env.ValueFrom))
 							continue
 						}
 
-						objKind = gvk.Kind
-						break
-					}
-				}
-
-				if len(containers) == 0 {
-					if gvks, _, err := scheme.Scheme.ObjectKinds(obj); err == nil {
-						objKind := obj.GetObjectKind().GroupVersionKind().Kind
-						if len(objKind) == 0 {
-							for _, gvk := range gvks {
-								if len(gvk.Kind) == 0 {
-									continue
-								}
-								if len(gvk.Version) == 0 || gvk.Version == runtime.APIVersionInternal {
-									continue
-								}
-
-								objKind = gvk.Kind
-								break
-							}
+						value, err := envutil.GetEnvVarRefValue(o.clientset, o.namespace, store, env.ValueFrom, obj, c)
+						// Print the resolved value
+						if err == nil {
+							fmt.Fprintf(o.Out, "%s=%s\n", env.Name, value)
+							continue
 						}
 
-						fmt.Fprintf(o.ErrOut, "warning: %s/%s does not have any containers matching %q\n", objKind, objName, o.ContainerSelector)
-					}
-					return nil
-				}
-				for _, c := range containers {
-					if !o.Overwrite {
-						if err := validateNoOverwrites(c.Env, env); err != nil {
-							return err
-						}
+						// Print the reference version and save the resolve error
+						fmt.Fprintf(o.Out, "# %s from %s\n", env.Name, envutil.GetEnvVarRefString(env.ValueFrom))
+						errString := err.Error()
+						resolveErrors[errString] = append(resolveErrors[errString], env.Name)
+						resolutionErrorsEncountered = true
 					}
 
-					c.Env = updateEnv(c.Env, env, remove)
-					if o.List {
-						resolveErrors := map[string][]string{}
-						store := envutil.NewResourceStore()
-
-						fmt.Fprintf(o.Out, "# %s %s, container %s\n", objKind, objName, c.Name)
-						for _, env := range c.Env {
-							// Print the simple value
-							if env.ValueFrom == nil {
-								fmt.Fprintf(o.Out, "%s=%s\n", env.Name, env.Value)
-								continue
-							}
-
-							// Print the reference version
-							if !o.Resolve {
-								fmt.Fprintf(o.Out, "# %s from %s\n", env.Name, envutil.GetEnvVarRefString(env.ValueFrom))
-								continue
-							}
-
-							value, err := envutil.GetEnvVarRefValue(o.clientset, o.namespace, store, env.ValueFrom, obj, c)
-							// Print the resolved value
-							if err == nil {
-								fmt.Fprintf(o.Out, "%s=%s\n", env.Name, value)
-								continue
-							}
-
-							// Print the reference version and save the resolve error
-							fmt.Fprintf(o.Out, "# %s from %s\n", env.Name, envutil.GetEnvVarRefString(env.ValueFrom))
-							errString := err.Error()
-							resolveErrors[errString] = append(resolveErrors[errString], env.Name)
-							resolutionErrorsEncountered = true
-						}
-
-						// Print any resolution errors
-						var errs []string
-						for err, vars := range resolveErrors {
-							sort.Strings(vars)
-							errs = append(errs, fmt.Sprintf("error retrieving reference for %s: %v", strings.Join(vars, ", "), err))
-						}
-						sort.Strings(errs)
-						for _, err := range errs {
-							_, _ = fmt.Fprintln(o.ErrOut, err)
-						}
+					// Print any resolution errors
+					var errs []string
+					for err, vars := range resolveErrors {
+						sort.Strings(vars)
+						errs = append(errs, fmt.Sprintf("error retrieving reference for %s: %v", strings.Join(vars, ", "), err))
+					}
+					sort.Strings(errs)
+					for _, err := range errs {
+						_, _ = fmt.Fprintln(o.ErrOut, err)
 					}
 				}
-				if resolutionErrorsEncountered {
-					return errors.New("failed to retrieve valueFrom references")
-				}
-				return nil
-			})
-
-			if err == nil {
-				return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
 			}
-			return nil, err
+			if resolutionErrorsEncountered {
+				return errors.New("failed to retrieve valueFrom references")
+			}
+			return nil
 		})
 
-		if o.List {
-			return nil
+		if err == nil {
+			return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
 		}
+		return nil, err
+	})
 
-		var allErrs []error
-
+	var allErrs []error
+	if o.List {
 		for _, patch := range patches {
-			info := patch.Info
 			if patch.Err != nil {
-				name := info.ObjectName()
-				allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
-				continue
+				allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", patch.Info.ObjectName(), patch.Err))
 			}
+		}
+		return utilerrors.NewAggregate(allErrs)
+	}
 
-			// no changes
-			if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
-				continue
-			}
+	for _, patch := range patches {
+		info := patch.Info
+		if patch.Err != nil {
+			name := info.ObjectName()
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
 
-			if o.Local || o.dryRunStrategy == cmdutil.DryRunClient {
-				if err := o.PrintObj(info.Object, o.Out); err != nil {
-					allErrs = append(allErrs, err)
-				}
-				continue
-			}
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
 
-			if o.dryRunStrategy == cmdutil.DryRunServer {
-				if err := o.dryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
-					allErrs = append(allErrs, err)
-					continue
-				}
+		if o.Local || o.dryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
 			}
+			continue
+		}
 
-			actual, err := resource.
-				NewHelper(info.Client, info.Mapping).
-				DryRun(o.dryRunStrategy == cmdutil.DryRunServer).
-				Patch(info.Namespace, info.Name, types.StrategicMergePatchType, patch.Patch, nil)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("failed to patch env update to pod template: %v", err))
+		if o.dryRunStrategy == cmdutil.DryRunServer {
+			if err := o.dryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
 				continue
 			}
+		}
 
-			// make sure arguments to set or replace environment variables are set
-			// before returning a successful message
-			if len(env) == 0 && len(o.envArgs) == 0 {
-				return fmt.Errorf("at least one environment variable must be provided")
-			}
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", info.ObjectName(), err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.dryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch env update to pod template: %v", err))
+			continue
+		}
 
-			if err := o.PrintObj(actual, o.Out); err != nil {
-				allErrs = append(allErrs, err)
-			}
+		// make sure arguments to set or replace environment variables are set
+		// before returning a successful message
+		if len(env) == 0 && len(o.envArgs) == 0 {
+			return fmt.Errorf("at least one environment variable must be provided")
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
 		}
-		return utilerrors.NewAggregate(allErrs)
 	}
+	return utilerrors.NewAggregate(allErrs)
 }
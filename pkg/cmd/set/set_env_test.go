@@ -666,3 +666,55 @@ func TestSetEnvFromResource(t *testing.T) {
 		})
 	}
 }
+
+func TestSetEnvKruiseWorkloadsLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	tests := []struct {
+		name     string
+		args     []string
+		list     bool
+		expected string
+	}{
+		{
+			name:     "set env",
+			args:     []string{"LEVEL=debug"},
+			expected: "cloneset.apps.kruise.io/web\nstatefulset.apps.kruise.io/db\ndaemonset.apps.kruise.io/agent\nuniteddeployment.apps.kruise.io/shop\n",
+		},
+		{
+			name: "list env",
+			args: []string{"LEVEL=debug"},
+			list: true,
+			expected: "# CloneSet web, container app\nLEVEL=debug\n# StatefulSet db, container app\nLEVEL=debug\n" +
+				"# DaemonSet agent, container app\nLEVEL=debug\n# UnitedDeployment shop, container app\nLEVEL=debug\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+			opts := NewEnvOptions(streams)
+			opts.PrintFlags = genericclioptions.NewPrintFlags("").WithDefaultOutput("name").WithTypeSetter(scheme.Scheme)
+			opts.FilenameOptions = resource.FilenameOptions{Filenames: []string{"../../../testdata/set/kruise-workloads.yaml"}}
+			opts.Local = true
+			opts.List = test.list
+			err := opts.Complete(tf, NewCmdEnv(tf, streams), test.args)
+			assert.NoError(t, err)
+			err = opts.Validate()
+			assert.NoError(t, err)
+			err = opts.RunEnv()
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
@@ -17,17 +17,24 @@ limitations under the License.
 package set
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
 	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
@@ -52,6 +59,20 @@ type SetImageOptions struct {
 	Local          bool
 	ResolveImage   ImageResolver
 
+	VerifySignature bool
+	CosignKey       string
+	AttestationType string
+	VerifyImage     ImageVerifier
+
+	ResolveDigest      bool
+	ResolveImageDigest DigestResolver
+
+	CheckPlatforms      bool
+	Nodes               []corev1.Node
+	CheckImagePlatforms PlatformChecker
+
+	RequireAll bool
+
 	PrintObj printers.ResourcePrinterFunc
 	Recorder genericclioptions.Recorder
 
@@ -64,7 +85,8 @@ type SetImageOptions struct {
 
 var (
 	imageResources = `
-  	pod (po), replicationcontroller (rc), deployment (deploy), daemonset (ds), replicaset (rs), cloneset(cs)`
+  	pod (po), replicationcontroller (rc), deployment (deploy), daemonset (ds), replicaset (rs), cloneset(cs),
+  	advanced statefulset (asts), advanced daemonset (ads), uniteddeployment (ud), sidecarset`
 
 	imageLong = templates.LongDesc(`
 		Update existing container image(s) of resources.
@@ -82,8 +104,26 @@ var (
 		# Update image of all containers of cloneset sample to 'nginx:1.9.1'
 		kubectl-kruise set image cloneset sample *=nginx:1.9.1
 
+		# Update the image of the sidecar container log-agent injected by sidecarset log-agent
+		kubectl-kruise set image sidecarset/log-agent log-agent=fluent-bit:1.9.3
+
 		# Print result (in yaml format) of updating nginx container image from local file, without hitting the server
-		kubectl-kruise set image -f path/to/file.yaml nginx=nginx:1.9.1 --local -o yaml`)
+		kubectl-kruise set image -f path/to/file.yaml nginx=nginx:1.9.1 --local -o yaml
+
+		# Update the nginx container image only if it is signed with the key k.pub
+		kubectl-kruise set image cloneset/sample nginx=nginx:1.9.1 --verify-signature --cosign-key k.pub
+
+		# Update the nginx container image only if it has an SPDX SBOM attestation signed with the key k.pub
+		kubectl-kruise set image cloneset/sample nginx=nginx:1.9.1 --verify-signature --cosign-key k.pub --attestation-type spdx
+
+		# Pin the nginx container image to the digest the tag 1.9.1 currently points to
+		kubectl-kruise set image cloneset/sample nginx=nginx:1.9.1 --resolve-digest
+
+		# Update the nginx container image only if it is available for the architectures of the nodes
+		kubectl-kruise set image cloneset/sample nginx=nginx:1.9.1 --check-platforms
+
+		# Update the app and sidecar container images together, or none of them if either container is missing
+		kubectl-kruise set image cloneset/sample app=app:1.0 sidecar=sidecar:2.0 --require-all`)
 )
 
 // NewImageOptions returns an initialized SetImageOptions instance
@@ -123,6 +163,12 @@ func NewCmdImage(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.
 	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
 	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
 	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set image will NOT contact api-server but run locally.")
+	cmd.Flags().BoolVar(&o.VerifySignature, "verify-signature", o.VerifySignature, "If true, verify the signatures of the images with cosign before updating any resource, and fail if any is not signed.")
+	cmd.Flags().StringVar(&o.CosignKey, "cosign-key", o.CosignKey, "With --verify-signature, the path or KMS URI of the public key the images must be signed with.")
+	cmd.Flags().StringVar(&o.AttestationType, "attestation-type", o.AttestationType, "With --verify-signature, verify an attestation of this predicate type instead of the signature, e.g. spdx, cyclonedx or slsaprovenance.")
+	cmd.Flags().BoolVar(&o.ResolveDigest, "resolve-digest", o.ResolveDigest, "If true, resolve the tags of the images to their digests with the registry, authenticating with the imagePullSecrets of the resources, and set the digest-pinned images.")
+	cmd.Flags().BoolVar(&o.CheckPlatforms, "check-platforms", o.CheckPlatforms, "If true, check that the images are available in the registry for the os/arch of the nodes the pods may run on, and fail before updating any resource if not.")
+	cmd.Flags().BoolVar(&o.RequireAll, "require-all", o.RequireAll, "If true, fail without updating any resource if any of the named containers is missing from any of the resources.")
 	cmdutil.AddDryRunFlag(cmd)
 	return cmd
 }
@@ -136,12 +182,12 @@ func (o *SetImageOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args [
 		return err
 	}
 
-	o.Recorder, err = o.RecordFlags.ToRecorder()
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
 	if err != nil {
 		return err
 	}
 
-	o.UpdatePodSpecForObject = polymorphichelpers.UpdatePodSpecForObjectFn
+	o.UpdatePodSpecForObject = updateContainersForObject
 	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
 	if err != nil {
 		return err
@@ -157,6 +203,29 @@ func (o *SetImageOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args [
 	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
 	o.Output = cmdutil.GetFlagString(cmd, "output")
 	o.ResolveImage = resolveImageFunc
+	if o.VerifySignature {
+		o.VerifyImage = cosignVerifier(o.CosignKey, o.AttestationType)
+	}
+	if o.ResolveDigest || o.CheckPlatforms {
+		// the image pull secrets cannot be read without contacting the api-server, --local resolves anonymously
+		var clientset kubernetes.Interface
+		if !o.Local {
+			if clientset, err = f.KubernetesClientSet(); err != nil {
+				return err
+			}
+		}
+		if o.ResolveDigest {
+			o.ResolveImageDigest = newDigestResolver(http.DefaultClient, clientset)
+		}
+		if o.CheckPlatforms && clientset != nil {
+			nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			o.Nodes = nodes.Items
+			o.CheckImagePlatforms = newPlatformChecker(http.DefaultClient, clientset)
+		}
+	}
 
 	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
 	printer, err := o.PrintFlags.ToPrinter()
@@ -222,6 +291,15 @@ func (o *SetImageOptions) Validate() error {
 	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
 		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
 	}
+	if o.Local && o.CheckPlatforms {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --check-platforms, the platforms of the nodes are read from the api-server"))
+	}
+	if o.VerifySignature && len(o.CosignKey) == 0 {
+		errors = append(errors, fmt.Errorf("--cosign-key is required with --verify-signature"))
+	}
+	if !o.VerifySignature && (len(o.CosignKey) > 0 || len(o.AttestationType) > 0) {
+		errors = append(errors, fmt.Errorf("--cosign-key and --attestation-type require --verify-signature"))
+	}
 	return utilerrors.NewAggregate(errors)
 }
 
@@ -229,10 +307,31 @@ func (o *SetImageOptions) Validate() error {
 func (o *SetImageOptions) Run() error {
 	var allErrs []error
 
+	// verify all images before any resource is updated
+	if o.VerifyImage != nil {
+		if errs := verifyImages(o.ContainerImages, o.ResolveImage, o.VerifyImage); len(errs) > 0 {
+			return utilerrors.NewAggregate(errs)
+		}
+	}
+
+	var preflightErrs []error
 	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		namespace, objName := "", ""
+		if accessor, err := meta.Accessor(obj); err == nil {
+			namespace, objName = accessor.GetNamespace(), accessor.GetName()
+		}
 		_, err := o.UpdatePodSpecForObject(obj, func(spec *corev1.PodSpec) error {
 			for name, image := range o.ContainerImages {
 				resolvedImageName, err := o.ResolveImage(image)
+				if err == nil && o.ResolveImageDigest != nil {
+					resolvedImageName, err = o.ResolveImageDigest(resolvedImageName, namespace, spec.ImagePullSecrets)
+				}
+				if err == nil && o.CheckImagePlatforms != nil {
+					if err := o.CheckImagePlatforms(resolvedImageName, namespace, spec.ImagePullSecrets, nodePlatforms(o.Nodes, spec.NodeSelector)); err != nil {
+						preflightErrs = append(preflightErrs, err)
+						continue
+					}
+				}
 				if err != nil {
 					allErrs = append(allErrs, fmt.Errorf("error: unable to resolve image %q for container %q: %v", image, name, err))
 					if name == "*" {
@@ -243,7 +342,9 @@ func (o *SetImageOptions) Run() error {
 
 				initContainerFound := setImage(spec.InitContainers, name, resolvedImageName)
 				containerFound := setImage(spec.Containers, name, resolvedImageName)
-				if !containerFound && !initContainerFound {
+				if !containerFound && !initContainerFound && o.RequireAll {
+					preflightErrs = append(preflightErrs, fmt.Errorf("error: unable to find container named %q in %s", name, objName))
+				} else if !containerFound && !initContainerFound {
 					allErrs = append(allErrs, fmt.Errorf("error: unable to find container named %q", name))
 				}
 			}
@@ -260,6 +361,11 @@ func (o *SetImageOptions) Run() error {
 		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
 	})
 
+	// fail before any resource is updated rather than midway through the rollout
+	if len(preflightErrs) > 0 {
+		return utilerrors.NewAggregate(preflightErrs)
+	}
+
 	for _, patch := range patches {
 		info := patch.Info
 		if patch.Err != nil {
@@ -287,10 +393,15 @@ func (o *SetImageOptions) Run() error {
 		}
 
 		// patch the change
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", info.ObjectName(), err))
+			continue
+		}
 		actual, err := resource.
 			NewHelper(info.Client, info.Mapping).
 			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
-			Patch(info.Namespace, info.Name, types.MergePatchType, patch.Patch, nil)
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
 		if err != nil {
 			allErrs = append(allErrs, fmt.Errorf("failed to patch image update to pod template: %v", err))
 			continue
@@ -304,6 +415,32 @@ func (o *SetImageOptions) Run() error {
 	return utilerrors.NewAggregate(allErrs)
 }
 
+// updateContainersForObject updates the containers of the pod template of obj with fn, or the
+// sidecar containers of a SidecarSet, which has no pod template, as the containers of a pod spec.
+func updateContainersForObject(obj runtime.Object, fn func(*corev1.PodSpec) error) (bool, error) {
+	sidecarSet, ok := obj.(*kruiseappsv1alpha1.SidecarSet)
+	if !ok {
+		return polymorphichelpers.UpdatePodSpecForObjectFn(obj, fn)
+	}
+	spec := &corev1.PodSpec{ImagePullSecrets: sidecarSet.Spec.ImagePullSecrets}
+	for _, c := range sidecarSet.Spec.InitContainers {
+		spec.InitContainers = append(spec.InitContainers, c.Container)
+	}
+	for _, c := range sidecarSet.Spec.Containers {
+		spec.Containers = append(spec.Containers, c.Container)
+	}
+	if err := fn(spec); err != nil {
+		return true, err
+	}
+	for i := range sidecarSet.Spec.InitContainers {
+		sidecarSet.Spec.InitContainers[i].Container = spec.InitContainers[i]
+	}
+	for i := range sidecarSet.Spec.Containers {
+		sidecarSet.Spec.Containers[i].Container = spec.Containers[i]
+	}
+	return true, nil
+}
+
 func setImage(containers []corev1.Container, containerName string, image string) bool {
 	containerFound := false
 	// Find the container to update, and update its image
@@ -17,12 +17,16 @@ limitations under the License.
 package set
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
 	"testing"
 
+	jsonpatch "github.com/evanphx/json-patch"
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
 	"github.com/stretchr/testify/assert"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -31,9 +35,12 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/resource"
 	restclient "k8s.io/client-go/rest"
@@ -774,3 +781,282 @@ func TestSetImageRemoteWithSpecificContainers(t *testing.T) {
 		})
 	}
 }
+
+func TestSetImageRemoteRequireAll(t *testing.T) {
+	object := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}},
+				},
+			},
+		},
+	}
+	path := "/namespaces/test/deployments/nginx"
+
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         appsv1.SchemeGroupVersion,
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch p, m := req.URL.Path, req.Method; {
+			case p == path && m == http.MethodGet:
+				return &http.Response{StatusCode: http.StatusOK, Header: cmdtesting.DefaultHeader(), Body: objBody(object)}, nil
+			default:
+				t.Errorf("%s: unexpected request: %s %#v\n%#v", "image", req.Method, req.URL, req)
+				return nil, fmt.Errorf("unexpected request")
+			}
+		}),
+	}
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdImage(tf, streams)
+	cmd.Flags().Set("output", "name")
+	opts := NewImageOptions(streams)
+	opts.RequireAll = true
+	err := opts.Complete(tf, cmd, []string{"deployment", "nginx", "nginx=thingy", "sidecar=thingy"})
+	assert.NoError(t, err)
+	err = opts.Run()
+	assert.EqualError(t, err, `error: unable to find container named "sidecar" in nginx`)
+	assert.Empty(t, buf.String())
+}
+
+func TestSetImageSidecarSetLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion: schema.GroupVersion{Version: "v1"},
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+
+	outputFormat := "yaml"
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdImage(tf, streams)
+	cmd.Flags().Set("output", outputFormat)
+	cmd.Flags().Set("local", "true")
+	opts := SetImageOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithDefaultOutput(outputFormat).WithTypeSetter(scheme.Scheme),
+		FilenameOptions: resource.FilenameOptions{
+			Filenames: []string{"../../../testdata/set/sidecarset.yaml"}},
+		Local:     true,
+		IOStreams: streams,
+	}
+	err := opts.Complete(tf, cmd, []string{"log-agent=fluent-bit:1.9.3", "init-config=busybox:1.35"})
+	assert.NoError(t, err)
+	err = opts.Run()
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "kind: SidecarSet")
+	assert.Contains(t, buf.String(), "image: fluent-bit:1.9.3")
+	assert.Contains(t, buf.String(), "image: busybox:1.35")
+}
+
+// kruiseServer serves a Kruise workload like the API server serves custom resources: it refuses
+// strategic merge patches, applies JSON merge patches to the workload and replaces it on update.
+type kruiseServer struct {
+	t       *testing.T
+	path    string
+	body    []byte
+	patched bool
+}
+
+func newKruiseServer(t *testing.T, obj runtime.Object, path string) *kruiseServer {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &kruiseServer{t: t, path: path, body: body}
+}
+
+func (s *kruiseServer) client() *fake.RESTClient {
+	return &fake.RESTClient{
+		GroupVersion:         kruiseappsv1alpha1.SchemeGroupVersion,
+		NegotiatedSerializer: serializer.NewCodecFactory(internalapi.GetScheme()).WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch p, m := req.URL.Path, req.Method; {
+			case p == s.path && m == http.MethodGet:
+			case p == s.path && m == http.MethodPatch:
+				if contentType := req.Header.Get("Content-Type"); contentType != string(types.MergePatchType) {
+					return &http.Response{StatusCode: http.StatusUnsupportedMediaType, Header: cmdtesting.DefaultHeader(), Body: cmdtesting.StringBody("unsupported " + contentType)}, nil
+				}
+				patch, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				if s.body, err = jsonpatch.MergePatch(s.body, patch); err != nil {
+					return nil, err
+				}
+				s.patched = true
+			case p == s.path && m == http.MethodPut:
+				body, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				s.body, s.patched = body, true
+			default:
+				s.t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+				return nil, fmt.Errorf("unexpected request")
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: cmdtesting.DefaultHeader(), Body: cmdtesting.BytesBody(s.body)}, nil
+		}),
+	}
+}
+
+// newKruiseTestCloneSet returns a CloneSet with an app and a sidecar container, which commands
+// must leave unchanged but for the fields they set.
+func newKruiseTestCloneSet() *kruiseappsv1alpha1.CloneSet {
+	return &kruiseappsv1alpha1.CloneSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: kruiseappsv1alpha1.SchemeGroupVersion.String(), Kind: "CloneSet"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "test"},
+		Spec: kruiseappsv1alpha1.CloneSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "app",
+							Image: "nginx:1.21",
+							Ports: []corev1.ContainerPort{{ContainerPort: 80}},
+							Env:   []corev1.EnvVar{{Name: "LEVEL", Value: "debug"}},
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{corev1.ResourceCPU: apiresource.MustParse("100m")},
+							},
+						},
+						{
+							Name:  "sidecar",
+							Image: "fluent-bit:1.9",
+							Env:   []corev1.EnvVar{{Name: "OUTPUT", Value: "stdout"}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// templateContainers returns the containers of the pod template of the workload served by s.
+func (s *kruiseServer) templateContainers() []corev1.Container {
+	var workload struct {
+		Spec struct {
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(s.body, &workload); err != nil {
+		s.t.Fatal(err)
+	}
+	return workload.Spec.Template.Spec.Containers
+}
+
+func TestSetImageKruiseRemote(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected map[string]string
+	}{
+		{name: "single container", args: []string{"cloneset", "web", "app=nginx:1.25"}, expected: map[string]string{"app": "nginx:1.25", "sidecar": "fluent-bit:1.9"}},
+		{name: "all containers", args: []string{"cloneset", "web", "app=nginx:1.25", "sidecar=fluent-bit:2.0"}, expected: map[string]string{"app": "nginx:1.25", "sidecar": "fluent-bit:2.0"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tf := cmdtesting.NewTestFactory().WithNamespace("test")
+			defer tf.Cleanup()
+			server := newKruiseServer(t, newKruiseTestCloneSet(), "/namespaces/test/clonesets/web")
+			tf.Client = server.client()
+
+			streams := genericclioptions.NewTestIOStreamsDiscard()
+			cmd := NewCmdImage(tf, streams)
+			cmd.Flags().Set("output", "name")
+			opts := NewImageOptions(streams)
+			opts.RequireAll = true
+			assert.NoError(t, opts.Complete(tf, cmd, test.args))
+			assert.NoError(t, opts.Run())
+			assert.True(t, server.patched)
+
+			containers := server.templateContainers()
+			if assert.Len(t, containers, 2) {
+				for _, c := range containers {
+					assert.Equal(t, test.expected[c.Name], c.Image)
+				}
+				app, sidecar := containers[0], containers[1]
+				assert.Equal(t, []corev1.ContainerPort{{ContainerPort: 80}}, app.Ports)
+				assert.Equal(t, []corev1.EnvVar{{Name: "LEVEL", Value: "debug"}}, app.Env)
+				assert.Equal(t, "100m", app.Resources.Requests.Cpu().String())
+				assert.Equal(t, []corev1.EnvVar{{Name: "OUTPUT", Value: "stdout"}}, sidecar.Env)
+			}
+		})
+	}
+}
+
+func TestSetImageSidecarSetRemoteRequireAll(t *testing.T) {
+	sidecarSet := &kruiseappsv1alpha1.SidecarSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: kruiseappsv1alpha1.SchemeGroupVersion.String(), Kind: "SidecarSet"},
+		ObjectMeta: metav1.ObjectMeta{Name: "logging"},
+		Spec: kruiseappsv1alpha1.SidecarSetSpec{
+			Containers: []kruiseappsv1alpha1.SidecarContainer{
+				{Container: corev1.Container{Name: "fluent-bit", Image: "fluent-bit:1.9", Env: []corev1.EnvVar{{Name: "OUTPUT", Value: "stdout"}}}},
+				{Container: corev1.Container{Name: "exporter", Image: "exporter:0.1", Ports: []corev1.ContainerPort{{ContainerPort: 9100}}}},
+			},
+		},
+	}
+	tests := []struct {
+		name        string
+		args        []string
+		expectedErr string
+		expected    map[string]string
+	}{
+		{
+			name:     "all containers",
+			args:     []string{"sidecarset", "logging", "fluent-bit=fluent-bit:2.0", "exporter=exporter:0.2"},
+			expected: map[string]string{"fluent-bit": "fluent-bit:2.0", "exporter": "exporter:0.2"},
+		},
+		{
+			name:        "missing container",
+			args:        []string{"sidecarset", "logging", "fluent-bit=fluent-bit:2.0", "proxy=envoy:1.22"},
+			expectedErr: `unable to find container named "proxy" in logging`,
+			expected:    map[string]string{"fluent-bit": "fluent-bit:1.9", "exporter": "exporter:0.1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tf := cmdtesting.NewTestFactory().WithNamespace("test")
+			defer tf.Cleanup()
+			// the RESTMapper of the test factory maps every kind it does not know as namespaced
+			server := newKruiseServer(t, sidecarSet, "/namespaces/test/sidecarsets/logging")
+			tf.Client = server.client()
+
+			streams := genericclioptions.NewTestIOStreamsDiscard()
+			cmd := NewCmdImage(tf, streams)
+			cmd.Flags().Set("output", "name")
+			opts := NewImageOptions(streams)
+			opts.RequireAll = true
+			assert.NoError(t, opts.Complete(tf, cmd, test.args))
+			err := opts.Run()
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedErr == "", server.patched)
+
+			patched := &kruiseappsv1alpha1.SidecarSet{}
+			if err := json.Unmarshal(server.body, patched); err != nil {
+				t.Fatal(err)
+			}
+			containers := patched.Spec.Containers
+			if assert.Len(t, containers, 2) {
+				for _, c := range containers {
+					assert.Equal(t, test.expected[c.Name], c.Image)
+				}
+				assert.Equal(t, []corev1.EnvVar{{Name: "OUTPUT", Value: "stdout"}}, containers[0].Env)
+				assert.Equal(t, []corev1.ContainerPort{{ContainerPort: 9100}}, containers[1].Ports)
+			}
+		})
+	}
+}
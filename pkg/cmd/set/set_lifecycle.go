@@ -0,0 +1,385 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+
+	appspub "github.com/openkruise/kruise-api/apps/pub"
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	lifecycleLong = templates.LongDesc(`
+		Configure or clear the PreDelete and InPlaceUpdate lifecycle hooks of a CloneSet or an
+		Advanced StatefulSet.
+
+		While a pod has a label of the labelsHandler or a finalizer of the finalizersHandler of a
+		hook, the workload holds it before deleting it (PreDelete), or before and after updating
+		it in place (InPlaceUpdate), until the controller behind the hook removes them. The labels
+		and finalizers given are added to the selected hooks, --remove-label and --remove-finalizer
+		remove them, and --clear removes the hooks. A hook without labels or finalizers is removed.
+
+		'kubectl-kruise lifecycle blocked' lists the pods held by the hooks.
+
+		Possible resources include (case insensitive):
+
+		cloneset (cs), statefulset.apps.kruise.io (asts)`)
+
+	lifecycleExample = templates.Examples(`
+		# Hold the pods of cloneset foo before deleting them while they have the label example.io/drained=false
+		kubectl-kruise set lifecycle cloneset/foo --pre-delete --label example.io/drained=false
+
+		# Hold the pods of advanced statefulset db around in-place updates with the finalizer example.io/unregister
+		kubectl-kruise set lifecycle asts/db --in-place-update --finalizer example.io/unregister
+
+		# Remove the finalizer example.io/unregister from both hooks of cloneset foo
+		kubectl-kruise set lifecycle cloneset/foo --pre-delete --in-place-update --remove-finalizer example.io/unregister
+
+		# Clear the PreDelete hook of cloneset foo
+		kubectl-kruise set lifecycle cloneset/foo --pre-delete --clear
+
+		# Print the result (in yaml format) of updating a local file, without hitting the server
+		kubectl-kruise set lifecycle -f path/to/file.yaml --pre-delete --label example.io/drained=false --local -o yaml`)
+)
+
+// SetLifecycleOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetLifecycleOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos          []*resource.Info
+	Selector       string
+	DryRunStrategy cmdutil.DryRunStrategy
+	DryRunVerifier *resource.DryRunVerifier
+	All            bool
+	Local          bool
+
+	PreDelete        bool
+	InPlaceUpdate    bool
+	Labels           []string
+	RemoveLabels     []string
+	Finalizers       []string
+	RemoveFinalizers []string
+	Clear            bool
+
+	labelsToAdd map[string]string
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	genericclioptions.IOStreams
+}
+
+// NewLifecycleOptions returns an initialized SetLifecycleOptions instance
+func NewLifecycleOptions(streams genericclioptions.IOStreams) *SetLifecycleOptions {
+	return &SetLifecycleOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("lifecycle hooks updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdLifecycle returns an initialized Command instance for the 'set lifecycle' sub command
+func NewCmdLifecycle(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewLifecycleOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "lifecycle (-f FILENAME | TYPE NAME) [--pre-delete] [--in-place-update] [--label=KEY=VALUE] [--finalizer=NAME] [--remove-label=KEY] [--remove-finalizer=NAME] [--clear]",
+		DisableFlagsInUseLine: true,
+		Aliases:               []string{"lifecycle-hooks"},
+		Short:                 i18n.T("Configure or clear the PreDelete and InPlaceUpdate lifecycle hooks of a workload"),
+		Long:                  lifecycleLong,
+		Example:               lifecycleExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().BoolVar(&o.PreDelete, "pre-delete", o.PreDelete, "If true, update the PreDelete hook.")
+	cmd.Flags().BoolVar(&o.InPlaceUpdate, "in-place-update", o.InPlaceUpdate, "If true, update the InPlaceUpdate hook.")
+	cmd.Flags().StringArrayVar(&o.Labels, "label", o.Labels, "Label of the labelsHandler of the hooks in the form KEY=VALUE. May be repeated.")
+	cmd.Flags().StringArrayVar(&o.RemoveLabels, "remove-label", o.RemoveLabels, "Key of a label to remove from the labelsHandler of the hooks. May be repeated.")
+	cmd.Flags().StringArrayVar(&o.Finalizers, "finalizer", o.Finalizers, "Finalizer of the finalizersHandler of the hooks. May be repeated.")
+	cmd.Flags().StringArrayVar(&o.RemoveFinalizers, "remove-finalizer", o.RemoveFinalizers, "Finalizer to remove from the finalizersHandler of the hooks. May be repeated.")
+	cmd.Flags().BoolVar(&o.Clear, "clear", o.Clear, "If true, remove the hooks.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set lifecycle will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetLifecycleOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	o.labelsToAdd, _, err = cmdutil.ParsePairs(o.Labels, "label", false)
+	if err != nil {
+		return err
+	}
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetLifecycleOptions are valid
+func (o *SetLifecycleOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	if !o.PreDelete && !o.InPlaceUpdate {
+		errors = append(errors, fmt.Errorf("at least one of --pre-delete or --in-place-update is required"))
+	}
+	changes := len(o.Labels) + len(o.RemoveLabels) + len(o.Finalizers) + len(o.RemoveFinalizers)
+	if o.Clear && changes > 0 {
+		errors = append(errors, fmt.Errorf("--clear cannot be combined with labels or finalizers"))
+	}
+	if !o.Clear && changes == 0 {
+		errors = append(errors, fmt.Errorf("at least one of --label, --finalizer, --remove-label, --remove-finalizer or --clear is required"))
+	}
+	for key, value := range o.labelsToAdd {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errors = append(errors, fmt.Errorf("invalid label key %q: %s", key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errors = append(errors, fmt.Errorf("invalid label value %q: %s", value, msg))
+		}
+		if sets.NewString(o.RemoveLabels...).Has(key) {
+			errors = append(errors, fmt.Errorf("cannot both set and remove label %q", key))
+		}
+	}
+	for _, finalizer := range o.Finalizers {
+		for _, msg := range validation.IsQualifiedName(finalizer) {
+			errors = append(errors, fmt.Errorf("invalid finalizer %q: %s", finalizer, msg))
+		}
+		if sets.NewString(o.RemoveFinalizers...).Has(finalizer) {
+			errors = append(errors, fmt.Errorf("cannot both set and remove finalizer %q", finalizer))
+		}
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+// Run performs the execution of 'set lifecycle' sub command
+func (o *SetLifecycleOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		var lifecycle **appspub.Lifecycle
+		switch t := obj.(type) {
+		case *kruiseappsv1alpha1.CloneSet:
+			lifecycle = &t.Spec.Lifecycle
+		case *kruiseappsv1beta1.StatefulSet:
+			lifecycle = &t.Spec.Lifecycle
+		default:
+			return nil, fmt.Errorf("lifecycle hooks are only supported by CloneSets and Advanced StatefulSets of apps.kruise.io/v1beta1")
+		}
+		*lifecycle = o.updateLifecycle(*lifecycle)
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch lifecycle hooks: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// updateLifecycle returns lifecycle with the selected hooks updated, or nil if it has no hook left.
+func (o *SetLifecycleOptions) updateLifecycle(lifecycle *appspub.Lifecycle) *appspub.Lifecycle {
+	if lifecycle == nil {
+		lifecycle = &appspub.Lifecycle{}
+	}
+	if o.PreDelete {
+		lifecycle.PreDelete = o.updateHook(lifecycle.PreDelete)
+	}
+	if o.InPlaceUpdate {
+		lifecycle.InPlaceUpdate = o.updateHook(lifecycle.InPlaceUpdate)
+	}
+	if lifecycle.PreDelete == nil && lifecycle.InPlaceUpdate == nil {
+		return nil
+	}
+	return lifecycle
+}
+
+// updateHook returns hook with the labels and finalizers of the flags, or nil if it is cleared or
+// has no label or finalizer left.
+func (o *SetLifecycleOptions) updateHook(hook *appspub.LifecycleHook) *appspub.LifecycleHook {
+	if o.Clear {
+		return nil
+	}
+	if hook == nil {
+		hook = &appspub.LifecycleHook{}
+	}
+	hook.LabelsHandler = updateStringMap(hook.LabelsHandler, o.labelsToAdd, o.RemoveLabels)
+
+	remove := sets.NewString(o.RemoveFinalizers...)
+	var finalizers []string
+	for _, finalizer := range hook.FinalizersHandler {
+		if !remove.Has(finalizer) {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+	for _, finalizer := range o.Finalizers {
+		if !sets.NewString(finalizers...).Has(finalizer) {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+	hook.FinalizersHandler = finalizers
+
+	if len(hook.LabelsHandler) == 0 && len(hook.FinalizersHandler) == 0 {
+		return nil
+	}
+	return hook
+}
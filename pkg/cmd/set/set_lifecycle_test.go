@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	appspub "github.com/openkruise/kruise-api/apps/pub"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestSetLifecycleLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdLifecycle(tf, streams)
+	cmd.Flags().Set("output", "yaml")
+	opts := NewLifecycleOptions(streams)
+	opts.PrintFlags = genericclioptions.NewPrintFlags("").WithDefaultOutput("yaml").WithTypeSetter(scheme.Scheme)
+	opts.FilenameOptions = resource.FilenameOptions{Filenames: []string{"../../../testdata/set/kruise-workloads.yaml"}}
+	opts.Local = true
+	opts.PreDelete = true
+	opts.Labels = []string{"example.io/drained=false"}
+
+	err := opts.Complete(tf, cmd, []string{})
+	assert.NoError(t, err)
+	err = opts.Validate()
+	assert.NoError(t, err)
+	err = opts.Run()
+	// the Advanced DaemonSet and the UnitedDeployment have no lifecycle hooks
+	if assert.Error(t, err) {
+		assert.Equal(t, 2, strings.Count(err.Error(), "lifecycle hooks are only supported by CloneSets and Advanced StatefulSets"))
+	}
+	assert.Contains(t, buf.String(), "kind: CloneSet")
+	assert.Contains(t, buf.String(), "kind: StatefulSet")
+	assert.Equal(t, 2, strings.Count(buf.String(), "preDelete:\n      labelsHandler:\n        example.io/drained: \"false\"\n"))
+}
+
+func TestUpdateLifecycle(t *testing.T) {
+	existing := func() *appspub.Lifecycle {
+		return &appspub.Lifecycle{
+			PreDelete:     &appspub.LifecycleHook{LabelsHandler: map[string]string{"example.io/drained": "false"}},
+			InPlaceUpdate: &appspub.LifecycleHook{FinalizersHandler: []string{"example.io/unregister"}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		opts     SetLifecycleOptions
+		existing *appspub.Lifecycle
+		expected *appspub.Lifecycle
+	}{
+		{
+			name:     "add a hook",
+			opts:     SetLifecycleOptions{InPlaceUpdate: true, Finalizers: []string{"example.io/unregister"}},
+			expected: &appspub.Lifecycle{InPlaceUpdate: &appspub.LifecycleHook{FinalizersHandler: []string{"example.io/unregister"}}},
+		},
+		{
+			name:     "add to both hooks",
+			opts:     SetLifecycleOptions{PreDelete: true, InPlaceUpdate: true, Labels: []string{"example.io/ready=false"}, Finalizers: []string{"example.io/unregister"}},
+			existing: existing(),
+			expected: &appspub.Lifecycle{
+				PreDelete:     &appspub.LifecycleHook{LabelsHandler: map[string]string{"example.io/drained": "false", "example.io/ready": "false"}, FinalizersHandler: []string{"example.io/unregister"}},
+				InPlaceUpdate: &appspub.LifecycleHook{LabelsHandler: map[string]string{"example.io/ready": "false"}, FinalizersHandler: []string{"example.io/unregister"}},
+			},
+		},
+		{
+			name:     "remove the last finalizer",
+			opts:     SetLifecycleOptions{InPlaceUpdate: true, RemoveFinalizers: []string{"example.io/unregister"}},
+			existing: existing(),
+			expected: &appspub.Lifecycle{PreDelete: existing().PreDelete},
+		},
+		{
+			name:     "clear",
+			opts:     SetLifecycleOptions{PreDelete: true, InPlaceUpdate: true, Clear: true},
+			existing: existing(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var err error
+			test.opts.labelsToAdd, _, err = cmdutil.ParsePairs(test.opts.Labels, "label", false)
+			assert.NoError(t, err)
+			assert.NoError(t, test.opts.Validate())
+			assert.Equal(t, test.expected, test.opts.updateLifecycle(test.existing))
+		})
+	}
+}
+
+func TestSetLifecycleValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        SetLifecycleOptions
+		expectedErr string
+	}{
+		{
+			name:        "no hook",
+			opts:        SetLifecycleOptions{Finalizers: []string{"example.io/unregister"}},
+			expectedErr: "at least one of --pre-delete or --in-place-update is required",
+		},
+		{
+			name:        "nothing to change",
+			opts:        SetLifecycleOptions{PreDelete: true},
+			expectedErr: "at least one of --label, --finalizer, --remove-label, --remove-finalizer or --clear is required",
+		},
+		{
+			name:        "clear with a finalizer",
+			opts:        SetLifecycleOptions{PreDelete: true, Clear: true, Finalizers: []string{"example.io/unregister"}},
+			expectedErr: "--clear cannot be combined with labels or finalizers",
+		},
+		{
+			name:        "invalid finalizer",
+			opts:        SetLifecycleOptions{PreDelete: true, Finalizers: []string{"example.io/un register"}},
+			expectedErr: `invalid finalizer "example.io/un register": `,
+		},
+		{
+			name:        "set and remove a finalizer",
+			opts:        SetLifecycleOptions{PreDelete: true, Finalizers: []string{"example.io/unregister"}, RemoveFinalizers: []string{"example.io/unregister"}},
+			expectedErr: `cannot both set and remove finalizer "example.io/unregister"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.opts.Validate()
+			if assert.Error(t, err) {
+				assert.True(t, strings.HasPrefix(err.Error(), test.expectedErr), err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,462 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/openkruise/kruise-tools/pkg/writeback"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	partitionLong = templates.LongDesc(`
+		Update the partition of a workload, the number of pods that are kept at the old revision
+		during an update.
+
+		The partition can be given as an absolute number or as a percentage of the current replicas,
+		rounded up, or computed from the current replicas of the workload with --keep-old, the number
+		of pods to keep at the old revision, or with --updated, the number or percentage of pods to
+		update. An absolute partition must not exceed the current replicas of the workload; the
+		replicas of an advanced daemonset are the number of nodes it is scheduled to.
+
+		With --wait the command waits until the updated replicas of the workload reach the number of
+		pods above the partition. With --track it also keeps the partition up to date, re-computing it
+		whenever the workload is scaled in the meantime.
+
+		Possible resources include (case insensitive):
+		cloneset (cs), advanced statefulset (asts), advanced daemonset (ads)`)
+
+	partitionExample = templates.Examples(`
+		# Keep 5 pods of cloneset sample at the old revision
+		kubectl-kruise set partition cloneset/sample 5
+
+		# Keep 20% of the pods of advanced daemonset agent at the old revision and wait for the others to be updated
+		kubectl-kruise set partition daemonsets.apps.kruise.io/agent 20% --wait --timeout 10m
+
+		# Keep 3 pods of cloneset sample at the old revision, whatever its replicas are
+		kubectl-kruise set partition cloneset/sample --keep-old 3
+
+		# Update 80% of the pods of advanced statefulset sample and follow the rollout, even if it is scaled
+		kubectl-kruise set partition asts/sample --updated 80% --track`)
+)
+
+// SetPartitionOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetPartitionOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos          []*resource.Info
+	Selector       string
+	DryRunStrategy cmdutil.DryRunStrategy
+	DryRunVerifier *resource.DryRunVerifier
+	All            bool
+	Local          bool
+	Wait           bool
+	Track          bool
+	Timeout        time.Duration
+
+	Updated string
+
+	// exactly one of these is set after Complete
+	partition *intstr.IntOrString
+	keepOld   *int32
+	updated   *intstr.IntOrString
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	genericclioptions.IOStreams
+}
+
+// NewPartitionOptions returns an initialized SetPartitionOptions instance
+func NewPartitionOptions(streams genericclioptions.IOStreams) *SetPartitionOptions {
+	return &SetPartitionOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("partition updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdPartition returns an initialized Command instance for the 'set partition' sub command
+func NewCmdPartition(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewPartitionOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "partition (-f FILENAME | TYPE NAME) (PARTITION | PARTITION% | --keep-old=N | --updated=N|N%) [--wait | --track]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Update the partition of a workload"),
+		Long:                  partitionLong,
+		Example:               partitionExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().Int32("keep-old", 0, "The number of pods to keep at the old revision.")
+	cmd.Flags().StringVar(&o.Updated, "updated", o.Updated, "The number or percentage of pods to update to the new revision.")
+	cmd.Flags().BoolVar(&o.Wait, "wait", o.Wait, "If true, wait until the updated replicas of the workload reach the number of pods above the partition.")
+	cmd.Flags().BoolVar(&o.Track, "track", o.Track, "If true, watch the rollout until it reaches the partition, re-computing the partition when the workload is scaled.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The length of time to wait for or track the rollout, zero means forever.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set partition will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetPartitionOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	if cmd.Flags().Changed("keep-old") {
+		keepOld := cmdutil.GetFlagInt32(cmd, "keep-old")
+		o.keepOld = &keepOld
+	}
+	if len(o.Updated) > 0 {
+		updated := intstr.Parse(o.Updated)
+		o.updated = &updated
+	}
+	if len(args) > 0 && o.keepOld == nil && o.updated == nil {
+		partition, err := parsePartition(args[len(args)-1])
+		if err != nil {
+			return err
+		}
+		o.partition = &partition
+		args = args[:len(args)-1]
+	}
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetPartitionOptions are valid
+func (o *SetPartitionOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	set := 0
+	for _, given := range []bool{o.partition != nil, o.keepOld != nil, o.updated != nil} {
+		if given {
+			set++
+		}
+	}
+	if set != 1 {
+		errors = append(errors, fmt.Errorf("exactly one of PARTITION, --keep-old or --updated is required"))
+	}
+	if o.partition != nil {
+		if err := validateIntOrPercent(*o.partition); err != nil {
+			errors = append(errors, fmt.Errorf("invalid partition: %v", err))
+		}
+	}
+	if o.keepOld != nil && *o.keepOld < 0 {
+		errors = append(errors, fmt.Errorf("--keep-old must not be negative"))
+	}
+	if o.updated != nil {
+		if err := validateIntOrPercent(*o.updated); err != nil {
+			errors = append(errors, fmt.Errorf("invalid --updated: %v", err))
+		}
+	}
+	if o.Wait && o.Track {
+		errors = append(errors, fmt.Errorf("--wait and --track can not be used together, --track also waits for the rollout"))
+	}
+	if (o.Wait || o.Track) && (o.Local || o.DryRunStrategy != cmdutil.DryRunNone) {
+		errors = append(errors, fmt.Errorf("--wait and --track can not be used with --local or --dry-run"))
+	}
+	if _, only := writeback.Recording(); only && (o.Wait || o.Track) {
+		errors = append(errors, fmt.Errorf("--wait and --track can not be used with --write-back-only, the workload is not changed"))
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+// Run performs the execution of 'set partition' sub command
+func (o *SetPartitionOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		if err := o.updatePartition(obj); err != nil {
+			return nil, err
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			if o.Wait || o.Track {
+				if err := o.track(info); err != nil {
+					allErrs = append(allErrs, err)
+				}
+			}
+			continue
+		}
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch partition: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+			continue
+		}
+		if o.Wait || o.Track {
+			info.Refresh(actual, true)
+			if err := o.track(info); err != nil {
+				allErrs = append(allErrs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// trackPeriod is the interval at which --wait and --track poll the workload.
+var trackPeriod = 2 * time.Second
+
+// track polls the workload until its rollout reaches the partition. With --track, the partition is also
+// re-computed when the replicas change.
+func (o *SetPartitionOptions) track(info *resource.Info) error {
+	helper := resource.NewHelper(info.Client, info.Mapping)
+	condition := func() (bool, error) {
+		obj, err := helper.Get(info.Namespace, info.Name)
+		if err != nil {
+			return false, err
+		}
+		info.Refresh(obj, true)
+		if !o.Track {
+			return o.rolledOut(info), nil
+		}
+
+		// the partition re-computed for the new replicas is not a new change: it is not checked
+		// against the policies or recorded again, unlike the patches of CalculatePatch
+		patch := &Patch{Info: info}
+		if patch.Before, err = runtime.Encode(scheme.DefaultJSONEncoder(), info.Object); err != nil {
+			return false, err
+		}
+		if err := o.updatePartition(info.Object); err != nil {
+			return false, err
+		}
+		if patch.After, err = runtime.Encode(scheme.DefaultJSONEncoder(), info.Object); err != nil {
+			return false, err
+		}
+		body, err := mergePatch(patch)
+		if err != nil {
+			return false, err
+		}
+		if string(body) != "{}" {
+			if _, err := helper.Patch(info.Namespace, info.Name, types.MergePatchType, body, nil); err != nil {
+				return false, err
+			}
+			replicas, partition, _, _ := polymorphichelpers.PartitionForObject(info.Object)
+			fmt.Fprintf(o.Out, "%s scaled to %d replicas, partition re-computed to %d\n", info.ObjectName(), replicas, partition)
+			return false, nil
+		}
+
+		return o.rolledOut(info), nil
+	}
+	if o.Timeout == 0 {
+		return wait.PollImmediateInfinite(trackPeriod, condition)
+	}
+	return wait.PollImmediate(trackPeriod, o.Timeout, condition)
+}
+
+// rolledOut prints the progress of the partitioned rollout of the workload and returns whether it is complete.
+func (o *SetPartitionOptions) rolledOut(info *resource.Info) bool {
+	replicas, partition, updated, _ := polymorphichelpers.PartitionForObject(info.Object)
+	if updated < replicas-partition {
+		fmt.Fprintf(o.Out, "Waiting for partitioned roll out of %s to finish: %d out of %d new pods have been updated...\n", info.ObjectName(), updated, replicas-partition)
+		return false
+	}
+	fmt.Fprintf(o.Out, "partitioned roll out of %s complete: %d new pods have been updated\n", info.ObjectName(), updated)
+	return true
+}
+
+// desiredPartition computes the partition from the given expression and the current replicas of the workload.
+func (o *SetPartitionOptions) desiredPartition(replicas int32) (int32, error) {
+	switch {
+	case o.partition != nil:
+		if o.partition.Type == intstr.String {
+			value, err := intstr.GetScaledValueFromIntOrPercent(o.partition, int(replicas), true)
+			return int32(value), err
+		}
+		if o.partition.IntVal > replicas {
+			return 0, fmt.Errorf("partition %d exceeds the %d replicas", o.partition.IntVal, replicas)
+		}
+		return o.partition.IntVal, nil
+	case o.keepOld != nil:
+		if *o.keepOld > replicas {
+			return replicas, nil
+		}
+		return *o.keepOld, nil
+	case o.updated != nil:
+		return polymorphichelpers.PartitionForUpdated(*o.updated, replicas)
+	}
+	return 0, fmt.Errorf("no partition given")
+}
+
+func (o *SetPartitionOptions) updatePartition(obj runtime.Object) error {
+	replicas, _, _, ok := polymorphichelpers.PartitionForObject(obj)
+	if !ok {
+		return polymorphichelpers.NewUnsupportedKindError("setting the partition", obj, polymorphichelpers.PartitionedKinds...)
+	}
+	partition, err := o.desiredPartition(replicas)
+	if err != nil {
+		return err
+	}
+	return polymorphichelpers.UpdatePartitionForObject(obj, partition)
+}
+
+// parsePartition parses the PARTITION argument, an absolute number or a percentage of the replicas.
+func parsePartition(value string) (intstr.IntOrString, error) {
+	if strings.HasSuffix(value, "%") {
+		// the percentage is checked by Validate
+		return intstr.FromString(value), nil
+	}
+	partition, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return intstr.IntOrString{}, fmt.Errorf("invalid partition %q: %v", value, err)
+	}
+	return intstr.FromInt(int(partition)), nil
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/config"
+	"github.com/openkruise/kruise-tools/pkg/policy"
+	"github.com/openkruise/kruise-tools/pkg/writeback"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestSetPartitionLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	outputFormat := "yaml"
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdPartition(tf, streams)
+	cmd.Flags().Set("output", outputFormat)
+	cmd.Flags().Set("local", "true")
+
+	opts := SetPartitionOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithDefaultOutput(outputFormat).WithTypeSetter(scheme.Scheme),
+		FilenameOptions: resource.FilenameOptions{
+			Filenames: []string{"../../../testdata/set/cloneset.yaml"}},
+		Local:     true,
+		Updated:   "50%",
+		IOStreams: streams,
+	}
+	err := opts.Complete(tf, cmd, []string{})
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err == nil {
+		err = opts.Run()
+	}
+	assert.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "kind: CloneSet")
+	assert.Contains(t, out, "partition: 1")
+}
+
+func TestSetPartitionValidation(t *testing.T) {
+	opts := SetPartitionOptions{}
+	assert.Error(t, opts.Validate())
+
+	partition, keepOld := intstr.FromInt(1), int32(2)
+	opts = SetPartitionOptions{partition: &partition, keepOld: &keepOld}
+	assert.Error(t, opts.Validate())
+
+	opts = SetPartitionOptions{partition: &partition, Track: true, Local: true}
+	assert.Error(t, opts.Validate())
+
+	opts = SetPartitionOptions{partition: &partition, Wait: true, Track: true}
+	assert.EqualError(t, opts.Validate(), "--wait and --track can not be used together, --track also waits for the rollout")
+
+	opts = SetPartitionOptions{partition: &partition, Wait: true}
+	assert.NoError(t, opts.Validate())
+
+	percent := intstr.FromString("120%")
+	opts = SetPartitionOptions{partition: &percent}
+	assert.EqualError(t, opts.Validate(), `invalid partition: "120%" must be a percentage between 0% and 100%`)
+}
+
+func TestParsePartition(t *testing.T) {
+	partition, err := parsePartition("5")
+	assert.NoError(t, err)
+	assert.Equal(t, intstr.FromInt(5), partition)
+
+	partition, err = parsePartition("20%")
+	assert.NoError(t, err)
+	assert.Equal(t, intstr.FromString("20%"), partition)
+
+	_, err = parsePartition("five")
+	assert.Error(t, err)
+}
+
+func TestDesiredPartition(t *testing.T) {
+	keepOld := int32(3)
+	opts := SetPartitionOptions{keepOld: &keepOld}
+	for replicas, expected := range map[int32]int32{10: 3, 2: 2} {
+		partition, err := opts.desiredPartition(replicas)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, partition)
+	}
+
+	partition := intstr.FromString("25%")
+	opts = SetPartitionOptions{partition: &partition}
+	for replicas, expected := range map[int32]int32{10: 3, 4: 1, 0: 0} {
+		partition, err := opts.desiredPartition(replicas)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, partition, replicas)
+	}
+
+	partition = intstr.FromInt(5)
+	opts = SetPartitionOptions{partition: &partition}
+	_, err := opts.desiredPartition(3)
+	assert.EqualError(t, err, "partition 5 exceeds the 3 replicas")
+
+	updated := intstr.FromString("80%")
+	opts = SetPartitionOptions{updated: &updated}
+	for replicas, expected := range map[int32]int32{10: 2, 3: 0, 7: 1} {
+		partition, err := opts.desiredPartition(replicas)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, partition, replicas)
+	}
+}
+
+func TestUpdatePartition(t *testing.T) {
+	replicas := int32(10)
+	cs := &appsv1alpha1.CloneSet{}
+	cs.Spec.Replicas = &replicas
+	updated := intstr.FromInt(4)
+	opts := SetPartitionOptions{updated: &updated}
+	assert.NoError(t, opts.updatePartition(cs))
+	assert.Equal(t, intstr.FromInt(6), *cs.Spec.UpdateStrategy.Partition)
+
+	ds := &appsv1alpha1.DaemonSet{}
+	ds.Status.DesiredNumberScheduled = 5
+	assert.NoError(t, opts.updatePartition(ds))
+	assert.Equal(t, int32(1), *ds.Spec.UpdateStrategy.RollingUpdate.Partition)
+
+	assert.Error(t, opts.updatePartition(&appsv1alpha1.BroadcastJob{}))
+}
+
+func TestSetPartitionWriteBackOnly(t *testing.T) {
+	partition := intstr.FromInt(1)
+	for _, opts := range []*SetPartitionOptions{
+		{partition: &partition, Wait: true},
+		{partition: &partition, Track: true},
+	} {
+		var err error
+		cmd := writeback.WithWriteBack(&cobra.Command{
+			Use: "partition",
+			Run: func(cmd *cobra.Command, args []string) {
+				err = opts.Validate()
+			},
+		}, ioutil.Discard)
+		cmd.Flags().Set("write-back", "manifests")
+		cmd.Flags().Set("write-back-only", "true")
+		cmd.Run(cmd, nil)
+		assert.EqualError(t, err, "--wait and --track can not be used with --write-back-only, the workload is not changed")
+	}
+}
+
+func TestSetPartitionTrack(t *testing.T) {
+	defer func(period time.Duration) { trackPeriod = period }(trackPeriod)
+	trackPeriod = time.Millisecond
+
+	// the policies gate the change of the command, not the partition re-computed while tracking
+	dir, err := ioutil.TempDir("", "partition-track")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opa := filepath.Join(dir, "opa")
+	script := "#!/bin/sh\ncat > /dev/null\necho '{\"result\":[{\"expressions\":[{\"value\":[\"partitions are frozen\"]}]}]}'\n"
+	if err := ioutil.WriteFile(opa, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	policy.Configure(&config.Policy{Rego: []string{"policies/"}, OPA: opa})
+	defer policy.Configure(nil)
+
+	cloneSet := newKruiseTestCloneSet()
+	replicas, partition := int32(4), intstr.FromInt(2)
+	cloneSet.Spec.Replicas = &replicas
+	cloneSet.Spec.UpdateStrategy.Partition = &partition
+	cloneSet.Status.UpdatedReplicas = 2
+	server := newKruiseServer(t, cloneSet, "/namespaces/test/clonesets/web")
+	gets := 0
+	client := server.client()
+	get := client.Client.Transport
+	client.Client = fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodGet {
+			if gets++; gets == 2 {
+				// the workload is scaled once the command started tracking it
+				server.body, err = jsonpatch.MergePatch(server.body, []byte(`{"spec":{"replicas":6},"status":{"updatedReplicas":3}}`))
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		return get.RoundTrip(req)
+	})
+
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+	tf.Client = client
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	cmd := writeback.WithWriteBack(NewCmdPartition(tf, streams), out)
+	cmd.Flags().Set("write-back", dir)
+	cmd.Flags().Set("updated", "50%")
+	cmd.Flags().Set("track", "true")
+	cmd.Run(cmd, []string{"cloneset", "web"})
+
+	assert.True(t, server.patched)
+	assert.Contains(t, out.String(), "clonesets/web scaled to 6 replicas, partition re-computed to 3")
+	assert.Contains(t, out.String(), "partitioned roll out of clonesets/web complete: 3 new pods have been updated")
+	assert.Contains(t, out.String(), "nothing to write back")
+}
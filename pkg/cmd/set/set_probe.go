@@ -0,0 +1,516 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	probeLong = templates.LongDesc(`
+		Set or remove the liveness, readiness and startup probes of the containers of a pod template.
+
+		The probe checks an HTTP endpoint with --get-url, a TCP port with --open-tcp, or runs the
+		command given after --. Without any of them, only the timing of the existing probe is
+		updated, e.g. its --period or --failure-threshold. --remove removes the probe.
+
+		The port of --get-url and --open-tcp is a number or the name of a container port. The
+		host of --get-url may be omitted to probe the pod IP, as in http://:8080/healthz.
+
+		Possible resources include (case insensitive):
+		` + envResources)
+
+	probeExample = templates.Examples(`
+		# Set an HTTP readiness probe on the app container of cloneset foo, every 5 seconds
+		kubectl-kruise set probe cloneset/foo -c app --readiness --get-url=http://:8080/healthz --period=5s
+
+		# Set a TCP liveness probe on the port named mysql of advanced statefulset db
+		kubectl-kruise set probe asts/db --liveness --open-tcp=mysql --initial-delay=30s
+
+		# Set a startup probe running a command, allowing 5 minutes to start
+		kubectl-kruise set probe cloneset/foo -c app --startup --period=10s --failure-threshold=30 -- cat /tmp/started
+
+		# Only raise the failure threshold of the existing liveness probe
+		kubectl-kruise set probe cloneset/foo -c app --liveness --failure-threshold=5
+
+		# Remove the readiness probe of all containers of advanced daemonset agent
+		kubectl-kruise set probe daemonset.apps.kruise.io/agent --readiness --remove
+
+		# Print the result (in yaml format) of setting a probe in a local file, without hitting the server
+		kubectl-kruise set probe -f path/to/file.yaml --readiness --open-tcp=8080 --local -o yaml`)
+)
+
+// SetProbeOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetProbeOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos             []*resource.Info
+	Selector          string
+	ContainerSelector string
+	DryRunStrategy    cmdutil.DryRunStrategy
+	DryRunVerifier    *resource.DryRunVerifier
+	All               bool
+	Local             bool
+
+	Liveness  bool
+	Readiness bool
+	Startup   bool
+	Remove    bool
+
+	GetURL           string
+	OpenTCP          string
+	Command          []string
+	InitialDelay     time.Duration
+	Period           time.Duration
+	Timeout          time.Duration
+	SuccessThreshold int32
+	FailureThreshold int32
+
+	handler *corev1.Handler
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	UpdatePodSpecForObject polymorphichelpers.UpdatePodSpecForObjectFunc
+
+	genericclioptions.IOStreams
+}
+
+// NewProbeOptions returns an initialized SetProbeOptions instance, selecting all containers by default
+func NewProbeOptions(streams genericclioptions.IOStreams) *SetProbeOptions {
+	return &SetProbeOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("probes updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		ContainerSelector: "*",
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdProbe returns an initialized Command instance for the 'set probe' sub command
+func NewCmdProbe(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewProbeOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "probe (-f FILENAME | TYPE NAME) (--liveness | --readiness | --startup) (--get-url=URL | --open-tcp=PORT | --remove | -- COMMAND [args...])",
+		DisableFlagsInUseLine: true,
+		Aliases:               []string{"probes"},
+		Short:                 i18n.T("Set or remove the liveness, readiness and startup probes of a pod template"),
+		Long:                  probeLong,
+		Example:               probeExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringVarP(&o.ContainerSelector, "containers", "c", o.ContainerSelector, "The names of containers in the selected pod templates to set the probes of, all containers are selected by default - may use wildcards")
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc("containers", internalcmdutil.ContainerCompletionFunc(f, "")))
+	cmd.Flags().BoolVar(&o.Liveness, "liveness", o.Liveness, "If true, set the liveness probe.")
+	cmd.Flags().BoolVar(&o.Readiness, "readiness", o.Readiness, "If true, set the readiness probe.")
+	cmd.Flags().BoolVar(&o.Startup, "startup", o.Startup, "If true, set the startup probe.")
+	cmd.Flags().BoolVar(&o.Remove, "remove", o.Remove, "If true, remove the probes instead of setting them.")
+	cmd.Flags().StringVar(&o.GetURL, "get-url", o.GetURL, "The URL to probe with an HTTP GET, e.g. http://:8080/healthz, the port may be the name of a container port.")
+	cmd.Flags().StringVar(&o.OpenTCP, "open-tcp", o.OpenTCP, "The port to probe by opening a TCP connection, a number or the name of a container port.")
+	cmd.Flags().DurationVar(&o.InitialDelay, "initial-delay", o.InitialDelay, "The time after the container starts before the probe is first run, e.g. 30s.")
+	cmd.Flags().DurationVar(&o.Period, "period", o.Period, "The time between two runs of the probe, e.g. 5s.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The time after which a run of the probe fails, e.g. 1s.")
+	cmd.Flags().Int32Var(&o.SuccessThreshold, "success-threshold", o.SuccessThreshold, "The number of successes in a row for the probe to be successful after failing.")
+	cmd.Flags().Int32Var(&o.FailureThreshold, "failure-threshold", o.FailureThreshold, "The number of failures in a row for the probe to fail.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set probe will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetProbeOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	if i := cmd.ArgsLenAtDash(); i >= 0 {
+		o.Command = args[i:]
+		args = args[:i]
+	}
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.UpdatePodSpecForObject = polymorphichelpers.UpdatePodSpecForObjectFn
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetProbeOptions are valid
+func (o *SetProbeOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	if !o.Liveness && !o.Readiness && !o.Startup {
+		errors = append(errors, fmt.Errorf("at least one of --liveness, --readiness or --startup is required"))
+	}
+
+	handlers := 0
+	for _, set := range []bool{len(o.GetURL) > 0, len(o.OpenTCP) > 0, len(o.Command) > 0} {
+		if set {
+			handlers++
+		}
+	}
+	if handlers > 1 {
+		errors = append(errors, fmt.Errorf("only one of --get-url, --open-tcp or a command may be given"))
+	}
+	if o.Remove && (handlers > 0 || o.timingSet()) {
+		errors = append(errors, fmt.Errorf("--remove does not take a probe or its timing"))
+	}
+	if !o.Remove && handlers == 0 && !o.timingSet() {
+		errors = append(errors, fmt.Errorf("one of --get-url, --open-tcp, a command, a timing flag or --remove is required"))
+	}
+
+	o.handler = nil
+	switch {
+	case len(o.GetURL) > 0:
+		action, err := parseGetURL(o.GetURL)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			o.handler = &corev1.Handler{HTTPGet: action}
+		}
+	case len(o.OpenTCP) > 0:
+		port, err := parseProbePort(o.OpenTCP)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("invalid --open-tcp: %v", err))
+		} else {
+			o.handler = &corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: port}}
+		}
+	case len(o.Command) > 0:
+		o.handler = &corev1.Handler{Exec: &corev1.ExecAction{Command: o.Command}}
+	}
+
+	durations := []struct {
+		flag  string
+		value time.Duration
+	}{{"--initial-delay", o.InitialDelay}, {"--period", o.Period}, {"--timeout", o.Timeout}}
+	for _, d := range durations {
+		if d.value < 0 || d.value%time.Second != 0 {
+			errors = append(errors, fmt.Errorf("%s must be a whole number of seconds, got %s", d.flag, d.value))
+		}
+	}
+	if o.SuccessThreshold < 0 || o.FailureThreshold < 0 {
+		errors = append(errors, fmt.Errorf("--success-threshold and --failure-threshold must not be negative"))
+	}
+	if o.SuccessThreshold > 1 && (o.Liveness || o.Startup) {
+		errors = append(errors, fmt.Errorf("--success-threshold must be 1 for liveness and startup probes"))
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+func (o *SetProbeOptions) timingSet() bool {
+	return o.InitialDelay != 0 || o.Period != 0 || o.Timeout != 0 || o.SuccessThreshold != 0 || o.FailureThreshold != 0
+}
+
+// parseGetURL parses url of the form SCHEME://[HOST]:PORT[/PATH] into an HTTP GET action.
+// Unlike net/url, the port may be the name of a container port.
+func parseGetURL(url string) (*corev1.HTTPGetAction, error) {
+	action := &corev1.HTTPGetAction{}
+	rest := url
+	switch {
+	case strings.HasPrefix(strings.ToLower(rest), "http://"):
+		action.Scheme = corev1.URISchemeHTTP
+		rest = rest[len("http://"):]
+	case strings.HasPrefix(strings.ToLower(rest), "https://"):
+		action.Scheme = corev1.URISchemeHTTPS
+		rest = rest[len("https://"):]
+	default:
+		return nil, fmt.Errorf("invalid --get-url %q, the scheme must be http or https", url)
+	}
+
+	hostPort := rest
+	action.Path = "/"
+	if i := strings.Index(rest, "/"); i >= 0 {
+		hostPort, action.Path = rest[:i], rest[i:]
+	}
+	i := strings.LastIndex(hostPort, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid --get-url %q, the port is required", url)
+	}
+	action.Host = hostPort[:i]
+	port, err := parseProbePort(hostPort[i+1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid --get-url %q: %v", url, err)
+	}
+	action.Port = port
+	return action, nil
+}
+
+// parseProbePort parses a port number or the name of a container port.
+func parseProbePort(s string) (intstr.IntOrString, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		for _, msg := range validation.IsValidPortNum(n) {
+			return intstr.IntOrString{}, fmt.Errorf("port %d %s", n, msg)
+		}
+		return intstr.FromInt(n), nil
+	}
+	for _, msg := range validation.IsValidPortName(s) {
+		return intstr.IntOrString{}, fmt.Errorf("port %q %s", s, msg)
+	}
+	return intstr.FromString(s), nil
+}
+
+// Run performs the execution of 'set probe' sub command
+func (o *SetProbeOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		_, err := o.UpdatePodSpecForObject(obj, func(spec *corev1.PodSpec) error {
+			containers, _ := selectContainers(spec.Containers, o.ContainerSelector)
+			if len(containers) == 0 {
+				return fmt.Errorf("unable to find container named %s", o.ContainerSelector)
+			}
+			for _, c := range containers {
+				if err := o.updateProbes(c); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch probe update to pod template: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// updateProbes sets or removes the selected probes of container c.
+func (o *SetProbeOptions) updateProbes(c *corev1.Container) error {
+	probes := []struct {
+		selected bool
+		kind     string
+		probe    **corev1.Probe
+	}{
+		{o.Liveness, "liveness", &c.LivenessProbe},
+		{o.Readiness, "readiness", &c.ReadinessProbe},
+		{o.Startup, "startup", &c.StartupProbe},
+	}
+	for _, p := range probes {
+		if !p.selected {
+			continue
+		}
+		if o.Remove {
+			*p.probe = nil
+			continue
+		}
+		probe := *p.probe
+		if o.handler != nil {
+			if probe == nil {
+				probe = &corev1.Probe{}
+			}
+			probe.Handler = *o.handler.DeepCopy()
+		} else if probe == nil {
+			return fmt.Errorf("container %q has no %s probe, use --get-url, --open-tcp or a command to add one", c.Name, p.kind)
+		}
+		if err := o.resolvePort(c, &probe.Handler); err != nil {
+			return err
+		}
+		if o.InitialDelay > 0 {
+			probe.InitialDelaySeconds = int32(o.InitialDelay.Seconds())
+		}
+		if o.Period > 0 {
+			probe.PeriodSeconds = int32(o.Period.Seconds())
+		}
+		if o.Timeout > 0 {
+			probe.TimeoutSeconds = int32(o.Timeout.Seconds())
+		}
+		if o.SuccessThreshold > 0 {
+			probe.SuccessThreshold = o.SuccessThreshold
+		}
+		if o.FailureThreshold > 0 {
+			probe.FailureThreshold = o.FailureThreshold
+		}
+		*p.probe = probe
+	}
+	return nil
+}
+
+// resolvePort makes sure a named port of the probe set by the flags is a port of container c.
+func (o *SetProbeOptions) resolvePort(c *corev1.Container, handler *corev1.Handler) error {
+	if o.handler == nil {
+		return nil
+	}
+	var port intstr.IntOrString
+	switch {
+	case handler.HTTPGet != nil:
+		port = handler.HTTPGet.Port
+	case handler.TCPSocket != nil:
+		port = handler.TCPSocket.Port
+	default:
+		return nil
+	}
+	if port.Type == intstr.Int {
+		return nil
+	}
+	for _, p := range c.Ports {
+		if p.Name == port.StrVal {
+			return nil
+		}
+	}
+	return fmt.Errorf("container %q has no port named %s", c.Name, port.StrVal)
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestSetProbeLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdProbe(tf, streams)
+	cmd.Flags().Set("output", "yaml")
+	opts := NewProbeOptions(streams)
+	opts.PrintFlags = genericclioptions.NewPrintFlags("").WithDefaultOutput("yaml").WithTypeSetter(scheme.Scheme)
+	opts.FilenameOptions = resource.FilenameOptions{Filenames: []string{"../../../testdata/set/kruise-workloads.yaml"}}
+	opts.Local = true
+	opts.Readiness = true
+	opts.GetURL = "http://:8080/healthz"
+	opts.Period = 5 * time.Second
+
+	err := opts.Complete(tf, cmd, []string{})
+	assert.NoError(t, err)
+	err = opts.Validate()
+	assert.NoError(t, err)
+	err = opts.Run()
+	assert.NoError(t, err)
+	for _, kind := range []string{"CloneSet", "StatefulSet", "DaemonSet", "UnitedDeployment"} {
+		assert.Contains(t, buf.String(), "kind: "+kind)
+	}
+	assert.Equal(t, 4, strings.Count(buf.String(), "readinessProbe:\n"))
+	assert.Equal(t, 4, strings.Count(buf.String(), "path: /healthz\n"))
+	assert.Equal(t, 4, strings.Count(buf.String(), "periodSeconds: 5\n"))
+}
+
+func TestUpdateProbes(t *testing.T) {
+	existing := func() *corev1.Container {
+		return &corev1.Container{
+			Name:  "app",
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+			LivenessProbe: &corev1.Probe{
+				Handler:          corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(8080)}},
+				PeriodSeconds:    10,
+				FailureThreshold: 3,
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		opts        SetProbeOptions
+		expected    func(c *corev1.Container)
+		expectedErr string
+	}{
+		{
+			name: "timing of the existing probe",
+			opts: SetProbeOptions{Liveness: true, FailureThreshold: 5},
+			expected: func(c *corev1.Container) {
+				c.LivenessProbe.FailureThreshold = 5
+			},
+		},
+		{
+			name: "replace the handler and keep the timing",
+			opts: SetProbeOptions{Liveness: true, GetURL: "http://:http/healthz"},
+			expected: func(c *corev1.Container) {
+				c.LivenessProbe.Handler = corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("http"), Scheme: corev1.URISchemeHTTP}}
+			},
+		},
+		{
+			name: "add an exec startup probe",
+			opts: SetProbeOptions{Startup: true, Command: []string{"cat", "/tmp/started"}, Period: 10 * time.Second, FailureThreshold: 30},
+			expected: func(c *corev1.Container) {
+				c.StartupProbe = &corev1.Probe{Handler: corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"cat", "/tmp/started"}}}, PeriodSeconds: 10, FailureThreshold: 30}
+			},
+		},
+		{
+			name: "remove",
+			opts: SetProbeOptions{Liveness: true, Readiness: true, Remove: true},
+			expected: func(c *corev1.Container) {
+				c.LivenessProbe = nil
+			},
+		},
+		{
+			name:        "timing without a probe",
+			opts:        SetProbeOptions{Readiness: true, Period: 5 * time.Second},
+			expectedErr: `container "app" has no readiness probe`,
+		},
+		{
+			name:        "unknown named port",
+			opts:        SetProbeOptions{Readiness: true, OpenTCP: "grpc"},
+			expectedErr: `container "app" has no port named grpc`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.NoError(t, test.opts.Validate())
+			c := existing()
+			err := test.opts.updateProbes(c)
+			if len(test.expectedErr) > 0 {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.expectedErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			expected := existing()
+			test.expected(expected)
+			assert.Equal(t, expected, c)
+		})
+	}
+}
+
+func TestParseGetURL(t *testing.T) {
+	action, err := parseGetURL("https://localhost:8443")
+	assert.NoError(t, err)
+	assert.Equal(t, &corev1.HTTPGetAction{Host: "localhost", Port: intstr.FromInt(8443), Path: "/", Scheme: corev1.URISchemeHTTPS}, action)
+
+	action, err = parseGetURL("http://:web/ready?full=1")
+	assert.NoError(t, err)
+	assert.Equal(t, &corev1.HTTPGetAction{Port: intstr.FromString("web"), Path: "/ready?full=1", Scheme: corev1.URISchemeHTTP}, action)
+
+	for _, url := range []string{"tcp://:8080", "http://localhost/healthz", "http://:99999/", "http://:not_a_port/"} {
+		_, err := parseGetURL(url)
+		assert.Error(t, err, url)
+	}
+}
+
+func TestSetProbeValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        SetProbeOptions
+		expectedErr string
+	}{
+		{
+			name: "readiness over http",
+			opts: SetProbeOptions{Readiness: true, GetURL: "http://:8080/healthz", Period: 5 * time.Second},
+		},
+		{
+			name:        "no probe kind",
+			opts:        SetProbeOptions{OpenTCP: "8080"},
+			expectedErr: "at least one of --liveness, --readiness or --startup is required",
+		},
+		{
+			name:        "two handlers",
+			opts:        SetProbeOptions{Liveness: true, OpenTCP: "8080", Command: []string{"true"}},
+			expectedErr: "only one of --get-url, --open-tcp or a command may be given",
+		},
+		{
+			name:        "nothing to set",
+			opts:        SetProbeOptions{Liveness: true},
+			expectedErr: "one of --get-url, --open-tcp, a command, a timing flag or --remove is required",
+		},
+		{
+			name:        "remove with a handler",
+			opts:        SetProbeOptions{Liveness: true, Remove: true, OpenTCP: "8080"},
+			expectedErr: "--remove does not take a probe or its timing",
+		},
+		{
+			name:        "fractional period",
+			opts:        SetProbeOptions{Readiness: true, Period: 1500 * time.Millisecond},
+			expectedErr: "--period must be a whole number of seconds, got 1.5s",
+		},
+		{
+			name:        "success threshold of a liveness probe",
+			opts:        SetProbeOptions{Liveness: true, SuccessThreshold: 2},
+			expectedErr: "--success-threshold must be 1 for liveness and startup probes",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.opts.Validate()
+			if len(test.expectedErr) == 0 {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.True(t, strings.HasPrefix(err.Error(), test.expectedErr), err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	pullLong = templates.LongDesc(`
+		Update the image pull policy of containers and the image pull secrets of a pod template.
+
+		The pull policy is applied to the containers and init containers matched by --containers.
+		Pull secrets are added to or removed from the pod template and must already exist in the
+		namespace of the workload.
+
+		Possible resources include (case insensitive):
+		` + imageResources)
+
+	pullExample = templates.Examples(`
+		# Pull images of cloneset sample only if not present, using the regcred secret
+		kubectl-kruise set pull cloneset/sample --policy IfNotPresent --add-secret regcred
+
+		# Always pull the image of the nginx container in all clonesets labeled app=web
+		kubectl-kruise set pull cloneset -l app=web -c nginx --policy Always
+
+		# Replace the old-cred pull secret with new-cred on every cloneset in the namespace
+		kubectl-kruise set pull cloneset --all --remove-secret old-cred --add-secret new-cred
+
+		# Print the result (in yaml format) of updating a local file, without hitting the server
+		kubectl-kruise set pull -f path/to/file.yaml --policy Never --local -o yaml`)
+)
+
+// SetPullOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetPullOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos             []*resource.Info
+	Selector          string
+	ContainerSelector string
+	DryRunStrategy    cmdutil.DryRunStrategy
+	DryRunVerifier    *resource.DryRunVerifier
+	All               bool
+	Local             bool
+
+	PullPolicy    string
+	AddSecrets    []string
+	RemoveSecrets []string
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	UpdatePodSpecForObject polymorphichelpers.UpdatePodSpecForObjectFunc
+
+	genericclioptions.IOStreams
+}
+
+// NewPullOptions returns an initialized SetPullOptions instance, selecting all containers by default
+func NewPullOptions(streams genericclioptions.IOStreams) *SetPullOptions {
+	return &SetPullOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("image pull settings updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		ContainerSelector: "*",
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdPull returns an initialized Command instance for the 'set pull' sub command
+func NewCmdPull(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewPullOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "pull (-f FILENAME | TYPE NAME) [--policy=POLICY] [--add-secret=SECRET] [--remove-secret=SECRET]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Update image pull policy and pull secrets of a pod template"),
+		Long:                  pullLong,
+		Example:               pullExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringVarP(&o.ContainerSelector, "containers", "c", o.ContainerSelector, "The names of containers in the selected pod templates to change, all containers are selected by default - may use wildcards")
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc("containers", internalcmdutil.ContainerCompletionFunc(f, "")))
+	cmd.Flags().StringVar(&o.PullPolicy, "policy", o.PullPolicy, "The image pull policy to set on the selected containers. One of: Always, IfNotPresent, Never.")
+	cmd.Flags().StringArrayVar(&o.AddSecrets, "add-secret", o.AddSecrets, "Name of an image pull secret to add to the pod template. May be repeated.")
+	cmd.Flags().StringArrayVar(&o.RemoveSecrets, "remove-secret", o.RemoveSecrets, "Name of an image pull secret to remove from the pod template. May be repeated.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set pull will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetPullOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.UpdatePodSpecForObject = polymorphichelpers.UpdatePodSpecForObjectFn
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetPullOptions are valid
+func (o *SetPullOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	if len(o.PullPolicy) == 0 && len(o.AddSecrets) == 0 && len(o.RemoveSecrets) == 0 {
+		errors = append(errors, fmt.Errorf("at least one of --policy, --add-secret or --remove-secret is required"))
+	}
+	switch corev1.PullPolicy(o.PullPolicy) {
+	case "", corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+	default:
+		errors = append(errors, fmt.Errorf("invalid --policy %q: must be one of Always, IfNotPresent or Never", o.PullPolicy))
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+// Run performs the execution of 'set pull' sub command
+func (o *SetPullOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		_, err := o.UpdatePodSpecForObject(obj, func(spec *corev1.PodSpec) error {
+			if len(o.PullPolicy) > 0 {
+				initContainers, _ := selectContainers(spec.InitContainers, o.ContainerSelector)
+				containers, _ := selectContainers(spec.Containers, o.ContainerSelector)
+				if len(initContainers) == 0 && len(containers) == 0 {
+					allErrs = append(allErrs, fmt.Errorf("error: unable to find container named %s", o.ContainerSelector))
+				}
+				for _, c := range append(initContainers, containers...) {
+					c.ImagePullPolicy = corev1.PullPolicy(o.PullPolicy)
+				}
+			}
+			spec.ImagePullSecrets = updatePullSecrets(spec.ImagePullSecrets, o.AddSecrets, o.RemoveSecrets)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch image pull update to pod template: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+func updatePullSecrets(existing []corev1.LocalObjectReference, add, remove []string) []corev1.LocalObjectReference {
+	removed := sets.NewString(remove...)
+	present := sets.NewString()
+	var out []corev1.LocalObjectReference
+	for _, ref := range existing {
+		if removed.Has(ref.Name) {
+			continue
+		}
+		present.Insert(ref.Name)
+		out = append(out, ref)
+	}
+	for _, name := range add {
+		if present.Has(name) {
+			continue
+		}
+		present.Insert(name)
+		out = append(out, corev1.LocalObjectReference{Name: name})
+	}
+	return out
+}
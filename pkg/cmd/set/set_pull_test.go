@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestSetPullLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	outputFormat := "yaml"
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdPull(tf, streams)
+	cmd.Flags().Set("output", outputFormat)
+	cmd.Flags().Set("local", "true")
+
+	opts := SetPullOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithDefaultOutput(outputFormat).WithTypeSetter(scheme.Scheme),
+		FilenameOptions: resource.FilenameOptions{
+			Filenames: []string{"../../../testdata/set/cloneset.yaml"}},
+		Local:             true,
+		ContainerSelector: "nginx",
+		PullPolicy:        "IfNotPresent",
+		AddSecrets:        []string{"regcred"},
+		IOStreams:         streams,
+	}
+	err := opts.Complete(tf, cmd, []string{})
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err == nil {
+		err = opts.Run()
+	}
+	assert.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "imagePullPolicy: IfNotPresent")
+	assert.Contains(t, out, "- name: regcred")
+	assert.Equal(t, 1, strings.Count(out, "imagePullPolicy"))
+}
+
+func TestSetPullValidation(t *testing.T) {
+	opts := SetPullOptions{}
+	assert.Error(t, opts.Validate())
+
+	opts = SetPullOptions{PullPolicy: "Sometimes"}
+	assert.Error(t, opts.Validate())
+
+	opts = SetPullOptions{PullPolicy: "Never"}
+	assert.NoError(t, opts.Validate())
+}
+
+func TestUpdatePullSecrets(t *testing.T) {
+	existing := []corev1.LocalObjectReference{{Name: "old-cred"}, {Name: "regcred"}}
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "regcred"}, {Name: "new-cred"}},
+		updatePullSecrets(existing, []string{"regcred", "new-cred"}, []string{"old-cred"}))
+	assert.Nil(t, updatePullSecrets(existing, nil, []string{"old-cred", "regcred"}))
+}
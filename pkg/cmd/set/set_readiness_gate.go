@@ -0,0 +1,333 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+
+	appspub "github.com/openkruise/kruise-api/apps/pub"
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	readinessGateLong = templates.LongDesc(`
+		Update the readiness gates of a pod template (spec.template.spec.readinessGates).
+
+		A pod with readiness gates is only ready once all of the listed pod conditions are true.
+		Workloads using the InPlaceIfPossible or InPlaceOnly update policy need the
+		` + string(appspub.InPlaceUpdateReady) + ` gate, so that their pods are taken out of service
+		while they are updated in place. Removing that gate from such a workload is refused
+		unless --force is given.
+
+		Possible resources include (case insensitive):
+		` + imageResources)
+
+	readinessGateExample = templates.Examples(`
+		# Add the InPlaceUpdateReady readiness gate to the pods of cloneset sample
+		kubectl-kruise set readiness-gate cloneset/sample --add InPlaceUpdateReady
+
+		# Remove a custom readiness gate from the pods of all clonesets labeled app=web
+		kubectl-kruise set readiness-gate cloneset -l app=web --remove example.com/load-balancer-ready
+
+		# Print the result (in yaml format) of updating a local file, without hitting the server
+		kubectl-kruise set readiness-gate -f path/to/file.yaml --add InPlaceUpdateReady --local -o yaml`)
+)
+
+// SetReadinessGateOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetReadinessGateOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos          []*resource.Info
+	Selector       string
+	DryRunStrategy cmdutil.DryRunStrategy
+	DryRunVerifier *resource.DryRunVerifier
+	All            bool
+	Local          bool
+	Force          bool
+
+	Add    []string
+	Remove []string
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	UpdatePodSpecForObject polymorphichelpers.UpdatePodSpecForObjectFunc
+
+	genericclioptions.IOStreams
+}
+
+// NewReadinessGateOptions returns an initialized SetReadinessGateOptions instance
+func NewReadinessGateOptions(streams genericclioptions.IOStreams) *SetReadinessGateOptions {
+	return &SetReadinessGateOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("readiness gates updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdReadinessGate returns an initialized Command instance for the 'set readiness-gate' sub command
+func NewCmdReadinessGate(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewReadinessGateOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "readiness-gate (-f FILENAME | TYPE NAME) [--add=CONDITION_TYPE] [--remove=CONDITION_TYPE]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Update readiness gates of a pod template"),
+		Long:                  readinessGateLong,
+		Example:               readinessGateExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringArrayVar(&o.Add, "add", o.Add, "Pod condition type to add as a readiness gate. May be repeated.")
+	cmd.Flags().StringArrayVar(&o.Remove, "remove", o.Remove, "Pod condition type of a readiness gate to remove. May be repeated.")
+	cmd.Flags().BoolVar(&o.Force, "force", o.Force, "If true, remove the "+string(appspub.InPlaceUpdateReady)+" gate even if the update policy of the workload requires it.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set readiness-gate will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetReadinessGateOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.UpdatePodSpecForObject = polymorphichelpers.UpdatePodSpecForObjectFn
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetReadinessGateOptions are valid
+func (o *SetReadinessGateOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	if len(o.Add) == 0 && len(o.Remove) == 0 {
+		errors = append(errors, fmt.Errorf("at least one of --add or --remove is required"))
+	}
+	for _, gate := range o.Add {
+		if len(gate) == 0 {
+			errors = append(errors, fmt.Errorf("readiness gate condition type must not be empty"))
+		}
+		if sets.NewString(o.Remove...).Has(gate) {
+			errors = append(errors, fmt.Errorf("cannot both add and remove readiness gate %q", gate))
+		}
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+// Run performs the execution of 'set readiness-gate' sub command
+func (o *SetReadinessGateOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		if !o.Force && sets.NewString(o.Remove...).Has(string(appspub.InPlaceUpdateReady)) {
+			if policy, ok := polymorphichelpers.PodUpdatePolicyForObject(obj); ok && policy != string(appsv1alpha1.RecreateCloneSetUpdateStrategyType) {
+				return nil, fmt.Errorf("readiness gate %s is required by the %s update policy, use --force to remove it anyway", appspub.InPlaceUpdateReady, policy)
+			}
+		}
+		_, err := o.UpdatePodSpecForObject(obj, func(spec *corev1.PodSpec) error {
+			spec.ReadinessGates = updateReadinessGates(spec.ReadinessGates, o.Add, o.Remove)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		o.warnUnusedGate(info)
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch readiness gates update to pod template: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// warnUnusedGate tells the user when the InPlaceUpdateReady gate is added to a workload that never updates pods in place.
+func (o *SetReadinessGateOptions) warnUnusedGate(info *resource.Info) {
+	if !sets.NewString(o.Add...).Has(string(appspub.InPlaceUpdateReady)) {
+		return
+	}
+	policy, ok := polymorphichelpers.PodUpdatePolicyForObject(info.Object)
+	if !ok {
+		fmt.Fprintf(o.ErrOut, "Warning: %s does not support in-place update, readiness gate %s will never become true\n", info.ObjectName(), appspub.InPlaceUpdateReady)
+	} else if policy == string(appsv1alpha1.RecreateCloneSetUpdateStrategyType) {
+		fmt.Fprintf(o.ErrOut, "Warning: %s uses the %s update policy, readiness gate %s is not needed\n", info.ObjectName(), policy, appspub.InPlaceUpdateReady)
+	}
+}
+
+func updateReadinessGates(existing []corev1.PodReadinessGate, add, remove []string) []corev1.PodReadinessGate {
+	skip := sets.NewString(remove...)
+	var gates []corev1.PodReadinessGate
+	for _, gate := range existing {
+		if skip.Has(string(gate.ConditionType)) {
+			continue
+		}
+		gates = append(gates, gate)
+		skip.Insert(string(gate.ConditionType))
+	}
+	for _, conditionType := range add {
+		if skip.Has(conditionType) {
+			continue
+		}
+		gates = append(gates, corev1.PodReadinessGate{ConditionType: corev1.PodConditionType(conditionType)})
+		skip.Insert(conditionType)
+	}
+	return gates
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestSetReadinessGateLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	outputFormat := "yaml"
+
+	streams, _, buf, errBuf := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdReadinessGate(tf, streams)
+	cmd.Flags().Set("output", outputFormat)
+	cmd.Flags().Set("local", "true")
+
+	opts := SetReadinessGateOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithDefaultOutput(outputFormat).WithTypeSetter(scheme.Scheme),
+		FilenameOptions: resource.FilenameOptions{
+			Filenames: []string{"../../../testdata/set/cloneset.yaml"}},
+		Local:     true,
+		Add:       []string{"InPlaceUpdateReady"},
+		IOStreams: streams,
+	}
+	err := opts.Complete(tf, cmd, []string{})
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err == nil {
+		err = opts.Run()
+	}
+	assert.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "kind: CloneSet")
+	assert.Contains(t, errBuf.String(), "is not needed")
+	assert.Contains(t, out, "conditionType: InPlaceUpdateReady")
+}
+
+func TestSetReadinessGateValidation(t *testing.T) {
+	opts := SetReadinessGateOptions{}
+	assert.Error(t, opts.Validate())
+
+	opts = SetReadinessGateOptions{Add: []string{"a"}, Remove: []string{"a"}}
+	assert.Error(t, opts.Validate())
+}
+
+func TestSetReadinessGateRemoveRequired(t *testing.T) {
+	cs := &appsv1alpha1.CloneSet{}
+	cs.Spec.UpdateStrategy.Type = appsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType
+	cs.Spec.Template.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: "InPlaceUpdateReady"}}
+
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	opts := NewReadinessGateOptions(streams)
+	opts.Remove = []string{"InPlaceUpdateReady"}
+	opts.UpdatePodSpecForObject = polymorphichelpers.UpdatePodSpecForObjectFn
+	opts.Infos = []*resource.Info{{Name: "sample", Object: cs}}
+	assert.Error(t, opts.Run())
+
+	opts.Force = true
+	opts.Local = true
+	opts.PrintObj = func(runtime.Object, io.Writer) error { return nil }
+	assert.NoError(t, opts.Run())
+	assert.Empty(t, cs.Spec.Template.Spec.ReadinessGates)
+}
+
+func TestUpdateReadinessGates(t *testing.T) {
+	existing := []corev1.PodReadinessGate{{ConditionType: "a"}, {ConditionType: "b"}}
+	assert.Equal(t, []corev1.PodReadinessGate{{ConditionType: "b"}, {ConditionType: "c"}},
+		updateReadinessGates(existing, []string{"b", "c", "c"}, []string{"a"}))
+}
@@ -19,9 +19,12 @@ package set
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/cost"
 	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	"github.com/spf13/cobra"
 
@@ -33,6 +36,7 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	generateversioned "k8s.io/kubectl/pkg/generate/versioned"
@@ -47,6 +51,13 @@ var (
 
 		for each compute resource, if a limit is specified and a request is omitted, the request will default to the limit.
 
+		With --inplace, the resources of CloneSets and Advanced StatefulSets updating their pods in place
+		are patched so that their controllers resize the pods in place, where the cluster supports
+		in-place pod vertical scaling. The command waits for the pods, and reports which were resized
+		without restart, which restarted their containers and which were recreated.
+
+		With a price table in the config file, the estimated monthly cost delta of the new requests is printed after each resource.
+
 		Possible resources include (case insensitive): %s.`)
 
 	resourcesExample = templates.Examples(`
@@ -56,6 +67,9 @@ var (
 		# Set the resource request and limits for all containers in nginx
 		kubectl-kruise set resources cloneset sample --limits=cpu=200m,memory=512Mi --requests=cpu=100m,memory=256Mi
 
+		# Resize the pods of cloneset sample in place, and report which restarted
+		kubectl-kruise set resources cloneset sample -c=nginx --requests=cpu=500m --inplace
+
 		# Remove the resource requests for resources on containers in nginx
 		kubectl-kruise set resources cloneset sample --limits=cpu=0,memory=0 --requests=cpu=0,memory=0
 
@@ -87,6 +101,10 @@ type SetResourcesOptions struct {
 	Requests             string
 	ResourceRequirements corev1.ResourceRequirements
 
+	InPlace        bool
+	InPlaceTimeout time.Duration
+	Client         kubernetes.Interface
+
 	UpdatePodSpecForObject polymorphichelpers.UpdatePodSpecForObjectFunc
 	Resources              []string
 	DryRunVerifier         *resource.DryRunVerifier
@@ -104,6 +122,7 @@ func NewResourcesOptions(streams genericclioptions.IOStreams) *SetResourcesOptio
 		Recorder: genericclioptions.NoopRecorder{},
 
 		ContainerSelector: "*",
+		InPlaceTimeout:    5 * time.Minute,
 
 		IOStreams: streams,
 	}
@@ -136,10 +155,13 @@ func NewCmdResources(f cmdutil.Factory, streams genericclioptions.IOStreams) *co
 	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
 	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones,supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
 	cmd.Flags().StringVarP(&o.ContainerSelector, "containers", "c", o.ContainerSelector, "The names of containers in the selected pod templates to change, all containers are selected by default - may use wildcards")
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc("containers", internalcmdutil.ContainerCompletionFunc(f, "")))
 	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set resources will NOT contact api-server but run locally.")
 	cmdutil.AddDryRunFlag(cmd)
 	cmd.Flags().StringVar(&o.Limits, "limits", o.Limits, "The resource requirement requests for this container.  For example, 'cpu=100m,memory=256Mi'.  Note that server side components may assign requests depending on the server configuration, such as limit ranges.")
 	cmd.Flags().StringVar(&o.Requests, "requests", o.Requests, "The resource requirement requests for this container.  For example, 'cpu=100m,memory=256Mi'.  Note that server side components may assign requests depending on the server configuration, such as limit ranges.")
+	cmd.Flags().BoolVar(&o.InPlace, "inplace", o.InPlace, "If true, resize the pods of CloneSets and Advanced StatefulSets in place, wait for them and report which were resized without restart.")
+	cmd.Flags().DurationVar(&o.InPlaceTimeout, "inplace-timeout", o.InPlaceTimeout, "The time to wait for the pods to be resized with --inplace.")
 	return cmd
 }
 
@@ -152,7 +174,7 @@ func (o *SetResourcesOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, ar
 		return err
 	}
 
-	o.Recorder, err = o.RecordFlags.ToRecorder()
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
 	if err != nil {
 		return err
 	}
@@ -172,6 +194,11 @@ func (o *SetResourcesOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, ar
 		return err
 	}
 	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+	if o.InPlace {
+		if o.Client, err = f.KubernetesClientSet(); err != nil {
+			return err
+		}
+	}
 
 	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
 	printer, err := o.PrintFlags.ToPrinter()
@@ -224,6 +251,9 @@ func (o *SetResourcesOptions) Validate() error {
 	if o.All && len(o.Selector) > 0 {
 		return fmt.Errorf("cannot set --all and --selector at the same time")
 	}
+	if o.InPlace && (o.Local || o.DryRunStrategy != cmdutil.DryRunNone) {
+		return fmt.Errorf("--inplace resizes the pods, it can not be used with --local or --dry-run")
+	}
 	if len(o.Limits) == 0 && len(o.Requests) == 0 {
 		return fmt.Errorf("you must specify an update to requests or limits (in the form of --requests/--limits)")
 	}
@@ -242,6 +272,9 @@ func (o *SetResourcesOptions) Run() error {
 	if len(o.Infos) == 0 {
 		return nil
 	}
+	if o.InPlace {
+		return o.runInPlace()
+	}
 
 	switch o.Infos[0].Object.(type) {
 	case *appsv1alpha1.CloneSet:
@@ -255,6 +288,7 @@ func (o *SetResourcesOptions) Run() error {
 			return err
 		}
 		res := obj.(*appsv1alpha1.CloneSet)
+		before := res.DeepCopy()
 
 		containers, _ := selectContainers(res.Spec.Template.Spec.Containers, o.ContainerSelector)
 
@@ -304,6 +338,10 @@ func (o *SetResourcesOptions) Run() error {
 		if err := o.PrintObj(res, o.Out); err != nil {
 			return errors.New(err.Error())
 		}
+		estimate, err := cost.EstimateObjects(before, res)
+		if err := o.printCost(o.Infos[0].ObjectName(), estimate, err); err != nil {
+			return err
+		}
 
 		return utilerrors.NewAggregate(allErrs)
 	case *appsv1beta1.StatefulSet:
@@ -317,6 +355,7 @@ func (o *SetResourcesOptions) Run() error {
 			return err
 		}
 		res := obj.(*appsv1beta1.StatefulSet)
+		before := res.DeepCopy()
 
 		containers, _ := selectContainers(res.Spec.Template.Spec.Containers, o.ContainerSelector)
 
@@ -366,6 +405,10 @@ func (o *SetResourcesOptions) Run() error {
 		if err := o.PrintObj(res, o.Out); err != nil {
 			return errors.New(err.Error())
 		}
+		estimate, err := cost.EstimateObjects(before, res)
+		if err := o.printCost(o.Infos[0].ObjectName(), estimate, err); err != nil {
+			return err
+		}
 
 		return utilerrors.NewAggregate(allErrs)
 	default:
@@ -424,10 +467,19 @@ func (o *SetResourcesOptions) Run() error {
 				continue
 			}
 
+			estimate, err := cost.EstimateJSON(patch.Before, patch.After)
+			if err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+
 			if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
 				if err := o.PrintObj(info.Object, o.Out); err != nil {
 					allErrs = append(allErrs, err)
 				}
+				if err := o.printCost(name, estimate, nil); err != nil {
+					allErrs = append(allErrs, err)
+				}
 				continue
 			}
 
@@ -438,10 +490,15 @@ func (o *SetResourcesOptions) Run() error {
 				}
 			}
 
+			body, err := mergePatch(patch)
+			if err != nil {
+				allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+				continue
+			}
 			actual, err := resource.
 				NewHelper(info.Client, info.Mapping).
 				DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
-				Patch(info.Namespace, info.Name, types.StrategicMergePatchType, patch.Patch, nil)
+				Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
 			if err != nil {
 				allErrs = append(allErrs, fmt.Errorf("failed to patch resources update to pod template %v", err))
 				continue
@@ -450,8 +507,21 @@ func (o *SetResourcesOptions) Run() error {
 			if err := o.PrintObj(actual, o.Out); err != nil {
 				allErrs = append(allErrs, err)
 			}
+			if err := o.printCost(name, estimate, nil); err != nil {
+				allErrs = append(allErrs, err)
+			}
 		}
 		return utilerrors.NewAggregate(allErrs)
 
 	}
 }
+
+// printCost prints the estimated cost delta of the change of the resource name after its output,
+// unless the output is printed with -o.
+func (o *SetResourcesOptions) printCost(name string, estimate *cost.Estimate, err error) error {
+	if err != nil || estimate == nil || len(o.Output) > 0 {
+		return err
+	}
+	_, err = fmt.Fprintf(o.Out, "%s %s\n", name, estimate)
+	return err
+}
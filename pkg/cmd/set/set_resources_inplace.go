@@ -0,0 +1,268 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/cost"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// The results of the pods of an in-place resize
+const (
+	resizedInPlace   = "resized in place"
+	restartedInPlace = "restarted in place"
+	recreated        = "recreated"
+	created          = "created"
+)
+
+const inPlacePollInterval = 2 * time.Second
+
+// podResize is the result of an in-place resize for a pod of a workload.
+type podResize struct {
+	Name   string
+	Result string
+}
+
+// podSnapshot is what tells whether a pod was resized without restart: its UID and the restarts
+// of its containers.
+type podSnapshot struct {
+	name     string
+	restarts int32
+}
+
+// runInPlace updates the resources of the CloneSets and Advanced StatefulSets of o.Infos with a
+// merge patch of their containers, which their controllers can apply to the pods in place, waits
+// for the pods to be updated and reports which were resized without restart.
+func (o *SetResourcesOptions) runInPlace() error {
+	var allErrs []error
+	var infos []*resource.Info
+	for _, info := range o.Infos {
+		if err := checkInPlacePolicy(info.Object); err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v", info.ObjectName(), err))
+			continue
+		}
+		infos = append(infos, info)
+	}
+
+	snapshots := map[*resource.Info]map[types.UID]podSnapshot{}
+	for _, info := range infos {
+		pods, err := polymorphichelpers.PodsForObject(o.Client.CoreV1(), info.Object)
+		if err != nil {
+			return err
+		}
+		snapshots[info] = snapshotPods(pods)
+	}
+
+	patches := CalculatePatches(infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		transformed := false
+		_, err := o.UpdatePodSpecForObject(obj, func(spec *corev1.PodSpec) error {
+			transformed = o.updateResources(spec)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !transformed {
+			return nil, fmt.Errorf("unable to find container named %s", o.ContainerSelector)
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v", name, patch.Err))
+			continue
+		}
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch resources update to pod template %v", err))
+			continue
+		}
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+			continue
+		}
+		estimate, err := cost.EstimateJSON(patch.Before, patch.After)
+		if err := o.printCost(name, estimate, err); err != nil {
+			allErrs = append(allErrs, err)
+		}
+
+		if err := o.waitInPlace(info); err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v", name, err))
+		}
+		pods, err := polymorphichelpers.PodsForObject(o.Client.CoreV1(), actual)
+		if err != nil {
+			allErrs = append(allErrs, err)
+			continue
+		}
+		out := o.Out
+		if len(o.Output) > 0 {
+			out = o.ErrOut
+		}
+		if err := printPodResizes(out, name, comparePods(snapshots[info], pods)); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// checkInPlacePolicy returns an error unless obj updates its pods in place.
+func checkInPlacePolicy(obj runtime.Object) error {
+	policy, ok := polymorphichelpers.PodUpdatePolicyForObject(obj)
+	if !ok {
+		return polymorphichelpers.NewUnsupportedKindError("resizing in place", obj,
+			appsv1alpha1.SchemeGroupVersion.WithKind("CloneSet").GroupKind(),
+			appsv1beta1.SchemeGroupVersion.WithKind("StatefulSet").GroupKind())
+	}
+	if policy != string(appsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType) && policy != string(appsv1alpha1.InPlaceOnlyCloneSetUpdateStrategyType) {
+		return fmt.Errorf("recreates its pods with the %s update policy, switch it to InPlaceIfPossible with 'set update-strategy' first", policy)
+	}
+	return nil
+}
+
+// updateResources sets the requirements of o to the selected containers of spec, and returns
+// false if no container is selected.
+func (o *SetResourcesOptions) updateResources(spec *corev1.PodSpec) bool {
+	containers, _ := selectContainers(spec.Containers, o.ContainerSelector)
+	for _, c := range containers {
+		for key, value := range o.ResourceRequirements.Limits {
+			if c.Resources.Limits == nil {
+				c.Resources.Limits = corev1.ResourceList{}
+			}
+			c.Resources.Limits[key] = value
+		}
+		for key, value := range o.ResourceRequirements.Requests {
+			if c.Resources.Requests == nil {
+				c.Resources.Requests = corev1.ResourceList{}
+			}
+			c.Resources.Requests[key] = value
+		}
+	}
+	return len(containers) > 0
+}
+
+// waitInPlace waits until the controller of info rolled out its new resources to all its pods.
+func (o *SetResourcesOptions) waitInPlace(info *resource.Info) error {
+	return wait.PollImmediate(inPlacePollInterval, o.InPlaceTimeout, func() (bool, error) {
+		obj, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+		if err != nil {
+			return false, err
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return false, err
+		}
+		u := &unstructured.Unstructured{Object: content}
+		u.SetGroupVersionKind(info.Mapping.GroupVersionKind)
+		return internalcmdutil.ResourceStateFor(u).Ready, nil
+	})
+}
+
+func snapshotPods(pods []corev1.Pod) map[types.UID]podSnapshot {
+	snapshots := map[types.UID]podSnapshot{}
+	for _, pod := range pods {
+		snapshots[pod.UID] = podSnapshot{name: pod.Name, restarts: podRestarts(&pod)}
+	}
+	return snapshots
+}
+
+func podRestarts(pod *corev1.Pod) int32 {
+	var restarts int32
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+	return restarts
+}
+
+// comparePods returns the result of the resize for each pod, from the pods before it and after:
+// the pods kept with the same restarts were resized in place, those which restarted a container
+// were restarted in place, those gone were recreated, and the new ones were created.
+func comparePods(before map[types.UID]podSnapshot, after []corev1.Pod) []podResize {
+	var resizes []podResize
+	seen := map[types.UID]bool{}
+	for _, pod := range after {
+		snapshot, ok := before[pod.UID]
+		switch {
+		case !ok:
+			resizes = append(resizes, podResize{Name: pod.Name, Result: created})
+		case podRestarts(&pod) > snapshot.restarts:
+			resizes = append(resizes, podResize{Name: pod.Name, Result: restartedInPlace})
+		default:
+			resizes = append(resizes, podResize{Name: pod.Name, Result: resizedInPlace})
+		}
+		seen[pod.UID] = true
+	}
+	for uid, snapshot := range before {
+		if !seen[uid] {
+			resizes = append(resizes, podResize{Name: snapshot.name, Result: recreated})
+		}
+	}
+	sort.Slice(resizes, func(i, j int) bool { return resizes[i].Name < resizes[j].Name })
+	return resizes
+}
+
+// printPodResizes prints the results of the resize of the pods of the workload name.
+func printPodResizes(out io.Writer, name string, resizes []podResize) error {
+	counts := map[string]int{}
+	for _, resize := range resizes {
+		counts[resize.Result]++
+	}
+	fmt.Fprintf(out, "%s: %d %s, %d %s, %d %s\n", name,
+		counts[resizedInPlace], resizedInPlace, counts[restartedInPlace], restartedInPlace, counts[recreated], recreated)
+	w := internalcmdutil.NewTableWriter(out)
+	fmt.Fprintln(w, "  POD\tRESULT")
+	for _, resize := range resizes {
+		fmt.Fprintf(w, "  %s\t%s\n", resize.Name, resize.Result)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newResizePod(name string, uid types.UID, restarts ...int32) corev1.Pod {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, UID: uid}}
+	for _, count := range restarts {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{RestartCount: count})
+	}
+	return pod
+}
+
+func TestComparePods(t *testing.T) {
+	before := snapshotPods([]corev1.Pod{
+		newResizePod("demo-a", "a", 0, 1),
+		newResizePod("demo-b", "b", 2),
+		newResizePod("demo-c", "c", 0),
+	})
+	after := []corev1.Pod{
+		newResizePod("demo-a", "a", 0, 1),
+		newResizePod("demo-b", "b", 3),
+		newResizePod("demo-d", "d", 0),
+	}
+	expected := []podResize{
+		{Name: "demo-a", Result: resizedInPlace},
+		{Name: "demo-b", Result: restartedInPlace},
+		{Name: "demo-c", Result: recreated},
+		{Name: "demo-d", Result: created},
+	}
+	if got := comparePods(before, after); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestCheckInPlacePolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		obj       runtime.Object
+		expectErr string
+	}{
+		{
+			name: "cloneset in place if possible",
+			obj: &appsv1alpha1.CloneSet{Spec: appsv1alpha1.CloneSetSpec{
+				UpdateStrategy: appsv1alpha1.CloneSetUpdateStrategy{Type: appsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType},
+			}},
+		},
+		{
+			name:      "cloneset recreate",
+			obj:       &appsv1alpha1.CloneSet{},
+			expectErr: "ReCreate update policy",
+		},
+		{
+			name: "advanced statefulset in place only",
+			obj: &appsv1beta1.StatefulSet{Spec: appsv1beta1.StatefulSetSpec{
+				UpdateStrategy: appsv1beta1.StatefulSetUpdateStrategy{
+					RollingUpdate: &appsv1beta1.RollingUpdateStatefulSetStrategy{PodUpdatePolicy: appsv1beta1.InPlaceOnlyPodUpdateStrategyType},
+				},
+			}},
+		},
+		{
+			name:      "deployment",
+			obj:       &appsv1.Deployment{},
+			expectErr: "resizing in place",
+		},
+	}
+	for _, test := range tests {
+		err := checkInPlacePolicy(test.obj)
+		if len(test.expectErr) == 0 && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if len(test.expectErr) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectErr)) {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestUpdateResources(t *testing.T) {
+	o := &SetResourcesOptions{ContainerSelector: "nginx"}
+	o.ResourceRequirements.Requests = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}
+	spec := &corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx"}, {Name: "sidecar"}}}
+	if !o.updateResources(spec) {
+		t.Fatalf("expected container nginx to be selected")
+	}
+	if cpu := spec.Containers[0].Resources.Requests[corev1.ResourceCPU]; cpu.String() != "500m" {
+		t.Errorf("expected the cpu request of nginx to be 500m, got %s", cpu.String())
+	}
+	if spec.Containers[1].Resources.Requests != nil {
+		t.Errorf("expected sidecar to be left unchanged, got %v", spec.Containers[1].Resources)
+	}
+	o.ContainerSelector = "app"
+	if o.updateResources(spec) {
+		t.Errorf("expected no container to be selected")
+	}
+}
+
+func TestPrintPodResizes(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := printPodResizes(buf, "cloneset.apps.kruise.io/demo", []podResize{
+		{Name: "demo-a", Result: resizedInPlace},
+		{Name: "demo-b", Result: recreated},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `cloneset.apps.kruise.io/demo: 1 resized in place, 0 restarted in place, 1 recreated
+  POD      RESULT
+  demo-a   resized in place
+  demo-b   recreated
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
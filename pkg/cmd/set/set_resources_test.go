@@ -23,6 +23,7 @@ import (
 	"strings"
 	"testing"
 
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	appsv1beta1 "k8s.io/api/apps/v1beta1"
@@ -516,3 +517,57 @@ func TestSetResourcesRemote(t *testing.T) {
 		})
 	}
 }
+
+func TestSetResourcesKruiseRemote(t *testing.T) {
+	daemonSet := &kruiseappsv1alpha1.DaemonSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: kruiseappsv1alpha1.SchemeGroupVersion.String(), Kind: "DaemonSet"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "test"},
+		Spec:       kruiseappsv1alpha1.DaemonSetSpec{Template: newKruiseTestCloneSet().Spec.Template},
+	}
+	tests := []struct {
+		name   string
+		object runtime.Object
+		path   string
+		args   []string
+	}{
+		{name: "CloneSet", object: newKruiseTestCloneSet(), path: "/namespaces/test/clonesets/web", args: []string{"cloneset", "web"}},
+		{name: "Advanced DaemonSet", object: daemonSet, path: "/namespaces/test/daemonsets/web", args: []string{"daemonsets.v1alpha1.apps.kruise.io", "web"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tf := cmdtesting.NewTestFactory().WithNamespace("test")
+			defer tf.Cleanup()
+			server := newKruiseServer(t, test.object, test.path)
+			tf.Client = server.client()
+
+			streams := genericclioptions.NewTestIOStreamsDiscard()
+			cmd := NewCmdResources(tf, streams)
+			cmd.Flags().Set("output", "name")
+			opts := NewResourcesOptions(streams)
+			opts.Limits = "cpu=200m,memory=512Mi"
+			opts.ContainerSelector = "app"
+			err := opts.Complete(tf, cmd, test.args)
+			if err == nil {
+				err = opts.Validate()
+			}
+			if err == nil {
+				err = opts.Run()
+			}
+			assert.NoError(t, err)
+			assert.True(t, server.patched)
+
+			containers := server.templateContainers()
+			if assert.Len(t, containers, 2) {
+				app, sidecar := containers[0], containers[1]
+				assert.Equal(t, "200m", app.Resources.Limits.Cpu().String())
+				assert.Equal(t, "512Mi", app.Resources.Limits.Memory().String())
+				assert.Equal(t, "100m", app.Resources.Requests.Cpu().String())
+				assert.Equal(t, []corev1.ContainerPort{{ContainerPort: 80}}, app.Ports)
+				assert.Equal(t, []corev1.EnvVar{{Name: "LEVEL", Value: "debug"}}, app.Env)
+				assert.Equal(t, "fluent-bit:1.9", sidecar.Image)
+				assert.Equal(t, []corev1.EnvVar{{Name: "OUTPUT", Value: "stdout"}}, sidecar.Env)
+				assert.Empty(t, sidecar.Resources.Limits)
+			}
+		})
+	}
+}
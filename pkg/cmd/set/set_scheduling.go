@@ -0,0 +1,541 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	schedulingLong = templates.LongDesc(`
+		Update scheduling constraints of a pod template.
+
+		Tolerations are given as KEY[=VALUE][:EFFECT], node selectors as KEY=VALUE, required node
+		affinities as KEY=VALUE[,VALUE...] or KEY!=VALUE[,VALUE...] and topology spread constraints
+		as TOPOLOGY_KEY:MAX_SKEW[:WHEN_UNSATISFIABLE]. Append a trailing dash to a key (e.g.
+		'dedicated-') to remove every entry with that key.
+
+		Node affinities are added to every term of the required node affinity, so that they
+		restrict the nodes of all the terms.
+
+		Topology spread constraints select the pods of the workload itself, using the
+		selector of the workload.
+
+		Possible resources include (case insensitive):
+		` + imageResources)
+
+	schedulingExample = templates.Examples(`
+		# Tolerate the dedicated=infra:NoSchedule taint and pin cloneset sample to pool x
+		kubectl-kruise set scheduling cloneset/sample --toleration dedicated=infra:NoSchedule --node-selector pool=x
+
+		# Schedule the pods of cloneset sample on the nodes of zone a or b, but not on spot nodes
+		kubectl-kruise set scheduling cloneset/sample --node-affinity topology.kubernetes.io/zone=a,b --node-affinity node.kubernetes.io/lifecycle!=spot
+
+		# Spread the pods of cloneset sample across zones with a max skew of 1
+		kubectl-kruise set scheduling cloneset/sample --topology-spread topology.kubernetes.io/zone:1:DoNotSchedule
+
+		# Remove the pool node selector and the dedicated toleration from all clonesets labeled app=web
+		kubectl-kruise set scheduling cloneset -l app=web --node-selector pool- --toleration dedicated-
+
+		# Print the result (in yaml format) of updating a local file, without hitting the server
+		kubectl-kruise set scheduling -f path/to/file.yaml --node-selector pool=x --local -o yaml`)
+)
+
+// SetSchedulingOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetSchedulingOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos          []*resource.Info
+	Selector       string
+	DryRunStrategy cmdutil.DryRunStrategy
+	DryRunVerifier *resource.DryRunVerifier
+	All            bool
+	Local          bool
+
+	Tolerations     []string
+	NodeSelectors   []string
+	NodeAffinities  []string
+	TopologySpreads []string
+
+	tolerationsToAdd     []corev1.Toleration
+	tolerationsToRemove  []string
+	nodeSelectorToAdd    map[string]string
+	nodeSelectorToRemove []string
+	affinitiesToAdd      []corev1.NodeSelectorRequirement
+	affinitiesToRemove   []string
+	spreadsToAdd         []corev1.TopologySpreadConstraint
+	spreadsToRemove      []string
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	UpdatePodSpecForObject polymorphichelpers.UpdatePodSpecForObjectFunc
+
+	genericclioptions.IOStreams
+}
+
+// NewSchedulingOptions returns an initialized SetSchedulingOptions instance
+func NewSchedulingOptions(streams genericclioptions.IOStreams) *SetSchedulingOptions {
+	return &SetSchedulingOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("scheduling updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdScheduling returns an initialized Command instance for the 'set scheduling' sub command
+func NewCmdScheduling(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewSchedulingOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "scheduling (-f FILENAME | TYPE NAME) [--toleration=KEY=VALUE:EFFECT] [--node-selector=KEY=VALUE] [--node-affinity=KEY=VALUES] [--topology-spread=KEY:SKEW:WHEN]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Update tolerations, node selectors, node affinity and topology spread of a pod template"),
+		Long:                  schedulingLong,
+		Example:               schedulingExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringArrayVar(&o.Tolerations, "toleration", o.Tolerations, "Toleration to add in the form KEY[=VALUE][:EFFECT], or KEY- to remove all tolerations of KEY. May be repeated.")
+	cmd.Flags().StringArrayVar(&o.NodeSelectors, "node-selector", o.NodeSelectors, "Node selector to add in the form KEY=VALUE, or KEY- to remove it. May be repeated.")
+	cmd.Flags().StringArrayVar(&o.NodeAffinities, "node-affinity", o.NodeAffinities, "Required node affinity to add in the form KEY=VALUE[,VALUE...] or KEY!=VALUE[,VALUE...], or KEY- to remove it. May be repeated.")
+	cmd.Flags().StringArrayVar(&o.TopologySpreads, "topology-spread", o.TopologySpreads, "Topology spread constraint to add in the form TOPOLOGY_KEY:MAX_SKEW[:DoNotSchedule|ScheduleAnyway], or TOPOLOGY_KEY- to remove it. May be repeated.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set scheduling will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetSchedulingOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.UpdatePodSpecForObject = polymorphichelpers.UpdatePodSpecForObjectFn
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	o.tolerationsToAdd, o.tolerationsToRemove, err = parseTolerations(o.Tolerations)
+	if err != nil {
+		return err
+	}
+	o.nodeSelectorToAdd, o.nodeSelectorToRemove, err = cmdutil.ParsePairs(o.NodeSelectors, "node selector", true)
+	if err != nil {
+		return err
+	}
+	o.affinitiesToAdd, o.affinitiesToRemove, err = parseNodeAffinities(o.NodeAffinities)
+	if err != nil {
+		return err
+	}
+	o.spreadsToAdd, o.spreadsToRemove, err = parseTopologySpreads(o.TopologySpreads)
+	if err != nil {
+		return err
+	}
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetSchedulingOptions are valid
+func (o *SetSchedulingOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	if len(o.Tolerations) == 0 && len(o.NodeSelectors) == 0 && len(o.NodeAffinities) == 0 && len(o.TopologySpreads) == 0 {
+		errors = append(errors, fmt.Errorf("at least one of --toleration, --node-selector, --node-affinity or --topology-spread is required"))
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+// Run performs the execution of 'set scheduling' sub command
+func (o *SetSchedulingOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		selector := podSelectorForObject(obj)
+		_, err := o.UpdatePodSpecForObject(obj, func(spec *corev1.PodSpec) error {
+			spec.Tolerations = updateTolerations(spec.Tolerations, o.tolerationsToAdd, o.tolerationsToRemove)
+			spec.NodeSelector = updateStringMap(spec.NodeSelector, o.nodeSelectorToAdd, o.nodeSelectorToRemove)
+			spec.Affinity = updateNodeAffinity(spec.Affinity, o.affinitiesToAdd, o.affinitiesToRemove)
+			spec.TopologySpreadConstraints = updateTopologySpreads(spec.TopologySpreadConstraints, o.spreadsToAdd, o.spreadsToRemove, selector)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch scheduling update to pod template: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// parseTolerations parses KEY[=VALUE][:EFFECT] specs into tolerations to add, and KEY- specs into keys to remove.
+// The suffix after the last colon is only the effect if it is one, so that values may contain colons.
+func parseTolerations(specs []string) ([]corev1.Toleration, []string, error) {
+	var add []corev1.Toleration
+	var remove []string
+	for _, spec := range specs {
+		if strings.HasSuffix(spec, "-") {
+			remove = append(remove, strings.TrimSuffix(spec, "-"))
+			continue
+		}
+		toleration := corev1.Toleration{Operator: corev1.TolerationOpExists}
+		keyValue := spec
+		if i := strings.LastIndex(spec, ":"); i >= 0 {
+			switch effect := corev1.TaintEffect(spec[i+1:]); effect {
+			case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+				keyValue, toleration.Effect = spec[:i], effect
+			default:
+				if !strings.Contains(spec[:i], "=") {
+					return nil, nil, fmt.Errorf("invalid toleration %q: unsupported effect %q", spec, effect)
+				}
+			}
+		}
+		parts := strings.SplitN(keyValue, "=", 2)
+		toleration.Key = parts[0]
+		if len(parts) == 2 {
+			toleration.Operator = corev1.TolerationOpEqual
+			toleration.Value = parts[1]
+		}
+		if len(toleration.Key) == 0 && toleration.Operator == corev1.TolerationOpEqual {
+			return nil, nil, fmt.Errorf("invalid toleration %q: a value requires a key", spec)
+		}
+		add = append(add, toleration)
+	}
+	return add, remove, nil
+}
+
+// parseNodeAffinities parses KEY=VALUE[,VALUE...] and KEY!=VALUE[,VALUE...] specs into node selector
+// requirements to add, and KEY- specs into keys to remove.
+func parseNodeAffinities(specs []string) ([]corev1.NodeSelectorRequirement, []string, error) {
+	var add []corev1.NodeSelectorRequirement
+	var remove []string
+	for _, spec := range specs {
+		if strings.HasSuffix(spec, "-") {
+			remove = append(remove, strings.TrimSuffix(spec, "-"))
+			continue
+		}
+		requirement := corev1.NodeSelectorRequirement{Operator: corev1.NodeSelectorOpIn}
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || len(parts[1]) == 0 {
+			return nil, nil, fmt.Errorf("invalid node affinity %q: expected KEY=VALUE[,VALUE...] or KEY!=VALUE[,VALUE...]", spec)
+		}
+		requirement.Key = parts[0]
+		if strings.HasSuffix(requirement.Key, "!") {
+			requirement.Key, requirement.Operator = strings.TrimSuffix(requirement.Key, "!"), corev1.NodeSelectorOpNotIn
+		}
+		if len(requirement.Key) == 0 {
+			return nil, nil, fmt.Errorf("invalid node affinity %q: a value requires a key", spec)
+		}
+		requirement.Values = strings.Split(parts[1], ",")
+		add = append(add, requirement)
+	}
+	return add, remove, nil
+}
+
+// parseTopologySpreads parses TOPOLOGY_KEY:MAX_SKEW[:WHEN_UNSATISFIABLE] specs into constraints to add,
+// and TOPOLOGY_KEY- specs into topology keys to remove.
+func parseTopologySpreads(specs []string) ([]corev1.TopologySpreadConstraint, []string, error) {
+	var add []corev1.TopologySpreadConstraint
+	var remove []string
+	for _, spec := range specs {
+		if strings.HasSuffix(spec, "-") {
+			remove = append(remove, strings.TrimSuffix(spec, "-"))
+			continue
+		}
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 || len(parts) > 3 || len(parts[0]) == 0 {
+			return nil, nil, fmt.Errorf("invalid topology spread %q: expected TOPOLOGY_KEY:MAX_SKEW[:WHEN_UNSATISFIABLE]", spec)
+		}
+		skew, err := strconv.ParseInt(parts[1], 10, 32)
+		if err != nil || skew < 1 {
+			return nil, nil, fmt.Errorf("invalid topology spread %q: max skew must be a positive integer", spec)
+		}
+		constraint := corev1.TopologySpreadConstraint{
+			TopologyKey:       parts[0],
+			MaxSkew:           int32(skew),
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+		}
+		if len(parts) == 3 {
+			constraint.WhenUnsatisfiable = corev1.UnsatisfiableConstraintAction(parts[2])
+			switch constraint.WhenUnsatisfiable {
+			case corev1.DoNotSchedule, corev1.ScheduleAnyway:
+			default:
+				return nil, nil, fmt.Errorf("invalid topology spread %q: unsupported action %q", spec, parts[2])
+			}
+		}
+		add = append(add, constraint)
+	}
+	return add, remove, nil
+}
+
+func updateTolerations(existing, add []corev1.Toleration, remove []string) []corev1.Toleration {
+	removed := sets.NewString(remove...)
+	var out []corev1.Toleration
+	for _, t := range existing {
+		if removed.Has(t.Key) {
+			continue
+		}
+		replaced := false
+		for _, n := range add {
+			if n.Key == t.Key && n.Effect == t.Effect {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out = append(out, t)
+		}
+	}
+	return append(out, add...)
+}
+
+// updateNodeAffinity adds the requirements of add to every term of the required node affinity, replacing
+// the ones of the same keys, and removes the requirements of the keys of remove. Terms left without
+// requirement are dropped, and so is the required node affinity once it has no term.
+func updateNodeAffinity(affinity *corev1.Affinity, add []corev1.NodeSelectorRequirement, remove []string) *corev1.Affinity {
+	if len(add) == 0 && len(remove) == 0 {
+		return affinity
+	}
+	removed := sets.NewString(remove...)
+	for _, r := range add {
+		removed.Insert(r.Key)
+	}
+	var terms []corev1.NodeSelectorTerm
+	if affinity != nil && affinity.NodeAffinity != nil && affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		terms = affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	}
+	if len(terms) == 0 && len(add) > 0 {
+		terms = []corev1.NodeSelectorTerm{{}}
+	}
+	var out []corev1.NodeSelectorTerm
+	for _, term := range terms {
+		var requirements []corev1.NodeSelectorRequirement
+		for _, r := range term.MatchExpressions {
+			if !removed.Has(r.Key) {
+				requirements = append(requirements, r)
+			}
+		}
+		term.MatchExpressions = append(requirements, add...)
+		if len(term.MatchExpressions) > 0 || len(term.MatchFields) > 0 {
+			out = append(out, term)
+		}
+	}
+
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	}
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	if len(out) > 0 {
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{NodeSelectorTerms: out}
+	} else {
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = nil
+	}
+	if affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil && len(affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution) == 0 {
+		affinity.NodeAffinity = nil
+	}
+	if affinity.NodeAffinity == nil && affinity.PodAffinity == nil && affinity.PodAntiAffinity == nil {
+		return nil
+	}
+	return affinity
+}
+
+func updateTopologySpreads(existing, add []corev1.TopologySpreadConstraint, remove []string, selector *metav1.LabelSelector) []corev1.TopologySpreadConstraint {
+	removed := sets.NewString(remove...)
+	for _, c := range add {
+		removed.Insert(c.TopologyKey)
+	}
+	var out []corev1.TopologySpreadConstraint
+	for _, c := range existing {
+		if !removed.Has(c.TopologyKey) {
+			out = append(out, c)
+		}
+	}
+	for _, c := range add {
+		c.LabelSelector = selector.DeepCopy()
+		out = append(out, c)
+	}
+	return out
+}
+
+// podSelectorForObject returns the spec.selector of a workload, or nil if the object has none.
+func podSelectorForObject(obj runtime.Object) *metav1.LabelSelector {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil
+	}
+	raw, found, err := unstructured.NestedMap(content, "spec", "selector")
+	if err != nil || !found {
+		return nil
+	}
+	selector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, selector); err != nil {
+		return nil
+	}
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		return nil
+	}
+	return selector
+}
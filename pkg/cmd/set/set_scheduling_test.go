@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestSetSchedulingLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	outputFormat := "yaml"
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdScheduling(tf, streams)
+	cmd.Flags().Set("output", outputFormat)
+	cmd.Flags().Set("local", "true")
+
+	opts := SetSchedulingOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithDefaultOutput(outputFormat).WithTypeSetter(scheme.Scheme),
+		FilenameOptions: resource.FilenameOptions{
+			Filenames: []string{"../../../testdata/set/cloneset.yaml"}},
+		Local:           true,
+		Tolerations:     []string{"dedicated=infra:NoSchedule"},
+		NodeSelectors:   []string{"pool=x"},
+		NodeAffinities:  []string{"topology.kubernetes.io/zone=a,b"},
+		TopologySpreads: []string{"topology.kubernetes.io/zone:1"},
+		IOStreams:       streams,
+	}
+	err := opts.Complete(tf, cmd, []string{})
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err == nil {
+		err = opts.Run()
+	}
+	assert.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "kind: CloneSet")
+	assert.Contains(t, out, "pool: x")
+	assert.Contains(t, out, "key: dedicated")
+	assert.Contains(t, out, "effect: NoSchedule")
+	assert.Contains(t, out, "requiredDuringSchedulingIgnoredDuringExecution")
+	assert.Contains(t, out, "operator: In")
+	assert.Contains(t, out, "topologyKey: topology.kubernetes.io/zone")
+	assert.Contains(t, out, "whenUnsatisfiable: DoNotSchedule")
+}
+
+func TestSetSchedulingValidation(t *testing.T) {
+	opts := SetSchedulingOptions{}
+	assert.Error(t, opts.Validate())
+
+	opts = SetSchedulingOptions{All: true, Selector: "app=web", NodeSelectors: []string{"pool=x"}}
+	assert.Error(t, opts.Validate())
+}
+
+func TestParseTolerations(t *testing.T) {
+	add, remove, err := parseTolerations([]string{"dedicated=infra:NoSchedule", "gpu:NoExecute", "spot", "legacy-", "key=a:b", "zone=a:b:PreferNoSchedule"})
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "infra", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		{Key: "spot", Operator: corev1.TolerationOpExists},
+		{Key: "key", Operator: corev1.TolerationOpEqual, Value: "a:b"},
+		{Key: "zone", Operator: corev1.TolerationOpEqual, Value: "a:b", Effect: corev1.TaintEffectPreferNoSchedule},
+	}, add)
+	assert.Equal(t, []string{"legacy"}, remove)
+
+	_, _, err = parseTolerations([]string{"gpu:Sometimes"})
+	assert.Error(t, err)
+}
+
+func TestParseNodeAffinities(t *testing.T) {
+	add, remove, err := parseNodeAffinities([]string{"topology.kubernetes.io/zone=a,b", "node.kubernetes.io/lifecycle!=spot", "pool-"})
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.NodeSelectorRequirement{
+		{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a", "b"}},
+		{Key: "node.kubernetes.io/lifecycle", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"spot"}},
+	}, add)
+	assert.Equal(t, []string{"pool"}, remove)
+
+	for _, spec := range []string{"zone", "zone=", "=a", "!=a"} {
+		_, _, err = parseNodeAffinities([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestUpdateNodeAffinity(t *testing.T) {
+	zone := corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}}
+	pool := corev1.NodeSelectorRequirement{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"x"}}
+	required := func(terms ...corev1.NodeSelectorTerm) *corev1.Affinity {
+		return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+		}}
+	}
+
+	assert.Equal(t, required(corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{zone}}),
+		updateNodeAffinity(nil, []corev1.NodeSelectorRequirement{zone}, nil))
+
+	existing := required(
+		corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{pool, {Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"b"}}}},
+		corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "gpu", Operator: corev1.NodeSelectorOpExists}}},
+	)
+	assert.Equal(t, required(
+		corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{pool, zone}},
+		corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "gpu", Operator: corev1.NodeSelectorOpExists}, zone}},
+	), updateNodeAffinity(existing, []corev1.NodeSelectorRequirement{zone}, nil))
+
+	podAntiAffinity := &corev1.PodAntiAffinity{}
+	existing = required(corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{pool}})
+	existing.PodAntiAffinity = podAntiAffinity
+	assert.Equal(t, &corev1.Affinity{PodAntiAffinity: podAntiAffinity}, updateNodeAffinity(existing, nil, []string{"pool"}))
+	assert.Nil(t, updateNodeAffinity(required(corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{pool}}), nil, []string{"pool"}))
+}
+
+func TestParseTopologySpreads(t *testing.T) {
+	add, remove, err := parseTopologySpreads([]string{"zone:2:ScheduleAnyway", "kubernetes.io/hostname:1", "rack-"})
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.TopologySpreadConstraint{
+		{TopologyKey: "zone", MaxSkew: 2, WhenUnsatisfiable: corev1.ScheduleAnyway},
+		{TopologyKey: "kubernetes.io/hostname", MaxSkew: 1, WhenUnsatisfiable: corev1.DoNotSchedule},
+	}, add)
+	assert.Equal(t, []string{"rack"}, remove)
+
+	for _, spec := range []string{"zone", "zone:0", "zone:1:Never", ":1"} {
+		_, _, err = parseTopologySpreads([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestUpdateTolerations(t *testing.T) {
+	existing := []corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "old", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "legacy", Operator: corev1.TolerationOpExists},
+		{Key: "keep", Operator: corev1.TolerationOpExists},
+	}
+	add := []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "new", Effect: corev1.TaintEffectNoSchedule}}
+	assert.Equal(t, []corev1.Toleration{
+		{Key: "keep", Operator: corev1.TolerationOpExists},
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "new", Effect: corev1.TaintEffectNoSchedule},
+	}, updateTolerations(existing, add, []string{"legacy"}))
+}
@@ -0,0 +1,403 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	securityLong = templates.LongDesc(`
+		Update pod and container security context settings of a pod template.
+
+		Pod level settings (--run-as-non-root, --run-as-user, --seccomp-profile) are applied to
+		the pod security context, container level settings are applied to the containers and init
+		containers matched by --containers. Use --restricted to apply every setting required by
+		the restricted Pod Security Standard at once.
+
+		Possible resources include (case insensitive):
+		` + imageResources)
+
+	securityExample = templates.Examples(`
+		# Harden all containers of cloneset sample
+		kubectl-kruise set security cloneset/sample --run-as-non-root --read-only-rootfs --drop-caps ALL -c '*'
+
+		# Apply the restricted Pod Security Standard to every cloneset labeled app=web
+		kubectl-kruise set security cloneset -l app=web --restricted
+
+		# Allow the nginx container to bind low ports while dropping every other capability
+		kubectl-kruise set security cloneset/sample -c nginx --drop-caps ALL --add-caps NET_BIND_SERVICE
+
+		# Print the result (in yaml format) of updating a local file, without hitting the server
+		kubectl-kruise set security -f path/to/file.yaml --run-as-user 1000 --local -o yaml`)
+)
+
+// SetSecurityOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetSecurityOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos             []*resource.Info
+	Selector          string
+	ContainerSelector string
+	DryRunStrategy    cmdutil.DryRunStrategy
+	DryRunVerifier    *resource.DryRunVerifier
+	All               bool
+	Local             bool
+
+	Restricted     bool
+	SeccompProfile string
+	DropCaps       []string
+	AddCaps        []string
+
+	// settings are nil unless the corresponding flag has been given
+	runAsNonRoot             *bool
+	runAsUser                *int64
+	readOnlyRootFilesystem   *bool
+	allowPrivilegeEscalation *bool
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	UpdatePodSpecForObject polymorphichelpers.UpdatePodSpecForObjectFunc
+
+	genericclioptions.IOStreams
+}
+
+// NewSecurityOptions returns an initialized SetSecurityOptions instance, selecting all containers by default
+func NewSecurityOptions(streams genericclioptions.IOStreams) *SetSecurityOptions {
+	return &SetSecurityOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("security context updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		ContainerSelector: "*",
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdSecurity returns an initialized Command instance for the 'set security' sub command
+func NewCmdSecurity(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewSecurityOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "security (-f FILENAME | TYPE NAME) [--restricted] [--run-as-non-root] [--read-only-rootfs] [--drop-caps=CAPS]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Update security context of a pod template"),
+		Long:                  securityLong,
+		Example:               securityExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringVarP(&o.ContainerSelector, "containers", "c", o.ContainerSelector, "The names of containers in the selected pod templates to change, all containers are selected by default - may use wildcards")
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc("containers", internalcmdutil.ContainerCompletionFunc(f, "")))
+	cmd.Flags().BoolVar(&o.Restricted, "restricted", o.Restricted, "If true, apply runAsNonRoot, RuntimeDefault seccomp, no privilege escalation and drop ALL capabilities, as required by the restricted Pod Security Standard.")
+	cmd.Flags().Bool("run-as-non-root", false, "Require the pod to run as a non-root user.")
+	cmd.Flags().Int64("run-as-user", 0, "The UID to run the entrypoint of the pod containers.")
+	cmd.Flags().StringVar(&o.SeccompProfile, "seccomp-profile", o.SeccompProfile, "The seccomp profile type of the pod. One of: RuntimeDefault, Unconfined.")
+	cmd.Flags().Bool("read-only-rootfs", false, "Mount the root filesystem of the selected containers as read-only.")
+	cmd.Flags().Bool("allow-privilege-escalation", false, "Whether the selected containers may gain more privileges than their parent process.")
+	cmd.Flags().StringSliceVar(&o.DropCaps, "drop-caps", o.DropCaps, "Capabilities to drop from the selected containers, e.g. ALL.")
+	cmd.Flags().StringSliceVar(&o.AddCaps, "add-caps", o.AddCaps, "Capabilities to add to the selected containers, e.g. NET_BIND_SERVICE.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set security will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetSecurityOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.UpdatePodSpecForObject = polymorphichelpers.UpdatePodSpecForObjectFn
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	if cmd.Flags().Changed("run-as-non-root") {
+		v := cmdutil.GetFlagBool(cmd, "run-as-non-root")
+		o.runAsNonRoot = &v
+	}
+	if cmd.Flags().Changed("run-as-user") {
+		v := cmdutil.GetFlagInt64(cmd, "run-as-user")
+		o.runAsUser = &v
+	}
+	if cmd.Flags().Changed("read-only-rootfs") {
+		v := cmdutil.GetFlagBool(cmd, "read-only-rootfs")
+		o.readOnlyRootFilesystem = &v
+	}
+	if cmd.Flags().Changed("allow-privilege-escalation") {
+		v := cmdutil.GetFlagBool(cmd, "allow-privilege-escalation")
+		o.allowPrivilegeEscalation = &v
+	}
+	if o.Restricted {
+		runAsNonRoot, allowPrivilegeEscalation := true, false
+		o.runAsNonRoot = &runAsNonRoot
+		o.allowPrivilegeEscalation = &allowPrivilegeEscalation
+		if len(o.SeccompProfile) == 0 {
+			o.SeccompProfile = string(corev1.SeccompProfileTypeRuntimeDefault)
+		}
+		if !sets.NewString(o.DropCaps...).Has("ALL") {
+			o.DropCaps = append(o.DropCaps, "ALL")
+		}
+	}
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetSecurityOptions are valid
+func (o *SetSecurityOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	if o.runAsNonRoot == nil && o.runAsUser == nil && o.readOnlyRootFilesystem == nil && o.allowPrivilegeEscalation == nil &&
+		len(o.SeccompProfile) == 0 && len(o.DropCaps) == 0 && len(o.AddCaps) == 0 {
+		errors = append(errors, fmt.Errorf("at least one security setting is required"))
+	}
+	switch corev1.SeccompProfileType(o.SeccompProfile) {
+	case "", corev1.SeccompProfileTypeRuntimeDefault, corev1.SeccompProfileTypeUnconfined:
+	default:
+		errors = append(errors, fmt.Errorf("invalid --seccomp-profile %q: must be one of RuntimeDefault or Unconfined", o.SeccompProfile))
+	}
+	if o.runAsNonRoot != nil && *o.runAsNonRoot && o.runAsUser != nil && *o.runAsUser == 0 {
+		errors = append(errors, fmt.Errorf("cannot require --run-as-non-root while running as user 0"))
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+// Run performs the execution of 'set security' sub command
+func (o *SetSecurityOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		_, err := o.UpdatePodSpecForObject(obj, func(spec *corev1.PodSpec) error {
+			o.updatePodSecurityContext(spec)
+			if o.readOnlyRootFilesystem == nil && o.allowPrivilegeEscalation == nil && len(o.DropCaps) == 0 && len(o.AddCaps) == 0 {
+				return nil
+			}
+			initContainers, _ := selectContainers(spec.InitContainers, o.ContainerSelector)
+			containers, _ := selectContainers(spec.Containers, o.ContainerSelector)
+			if len(initContainers) == 0 && len(containers) == 0 {
+				allErrs = append(allErrs, fmt.Errorf("error: unable to find container named %s", o.ContainerSelector))
+			}
+			for _, c := range append(initContainers, containers...) {
+				o.updateContainerSecurityContext(c)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch security context update to pod template: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+func (o *SetSecurityOptions) updatePodSecurityContext(spec *corev1.PodSpec) {
+	if o.runAsNonRoot == nil && o.runAsUser == nil && len(o.SeccompProfile) == 0 {
+		return
+	}
+	if spec.SecurityContext == nil {
+		spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if o.runAsNonRoot != nil {
+		spec.SecurityContext.RunAsNonRoot = o.runAsNonRoot
+	}
+	if o.runAsUser != nil {
+		spec.SecurityContext.RunAsUser = o.runAsUser
+	}
+	if len(o.SeccompProfile) > 0 {
+		spec.SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileType(o.SeccompProfile)}
+	}
+}
+
+func (o *SetSecurityOptions) updateContainerSecurityContext(c *corev1.Container) {
+	if c.SecurityContext == nil {
+		c.SecurityContext = &corev1.SecurityContext{}
+	}
+	if o.readOnlyRootFilesystem != nil {
+		c.SecurityContext.ReadOnlyRootFilesystem = o.readOnlyRootFilesystem
+	}
+	if o.allowPrivilegeEscalation != nil {
+		c.SecurityContext.AllowPrivilegeEscalation = o.allowPrivilegeEscalation
+	}
+	if len(o.DropCaps) == 0 && len(o.AddCaps) == 0 {
+		return
+	}
+	if c.SecurityContext.Capabilities == nil {
+		c.SecurityContext.Capabilities = &corev1.Capabilities{}
+	}
+	c.SecurityContext.Capabilities.Drop = mergeCapabilities(c.SecurityContext.Capabilities.Drop, o.DropCaps)
+	c.SecurityContext.Capabilities.Add = mergeCapabilities(c.SecurityContext.Capabilities.Add, o.AddCaps)
+}
+
+func mergeCapabilities(existing []corev1.Capability, add []string) []corev1.Capability {
+	present := sets.NewString()
+	for _, c := range existing {
+		present.Insert(string(c))
+	}
+	for _, c := range add {
+		if present.Has(c) {
+			continue
+		}
+		present.Insert(c)
+		existing = append(existing, corev1.Capability(c))
+	}
+	return existing
+}
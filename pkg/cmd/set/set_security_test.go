@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestSetSecurityLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	outputFormat := "yaml"
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdSecurity(tf, streams)
+	cmd.Flags().Set("output", outputFormat)
+	cmd.Flags().Set("local", "true")
+	cmd.Flags().Set("read-only-rootfs", "true")
+
+	opts := SetSecurityOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithDefaultOutput(outputFormat).WithTypeSetter(scheme.Scheme),
+		FilenameOptions: resource.FilenameOptions{
+			Filenames: []string{"../../../testdata/set/cloneset.yaml"}},
+		Local:             true,
+		ContainerSelector: "nginx",
+		Restricted:        true,
+		IOStreams:         streams,
+	}
+	err := opts.Complete(tf, cmd, []string{})
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err == nil {
+		err = opts.Run()
+	}
+	assert.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "runAsNonRoot: true")
+	assert.Contains(t, out, "type: RuntimeDefault")
+	assert.Contains(t, out, "allowPrivilegeEscalation: false")
+	assert.Contains(t, out, "readOnlyRootFilesystem: true")
+	assert.Contains(t, out, "- ALL")
+	assert.Equal(t, 1, strings.Count(out, "readOnlyRootFilesystem"))
+}
+
+func TestSetSecurityValidation(t *testing.T) {
+	opts := SetSecurityOptions{}
+	assert.Error(t, opts.Validate())
+
+	opts = SetSecurityOptions{SeccompProfile: "Localhost"}
+	assert.Error(t, opts.Validate())
+
+	runAsNonRoot, runAsUser := true, int64(0)
+	opts = SetSecurityOptions{runAsNonRoot: &runAsNonRoot, runAsUser: &runAsUser}
+	assert.Error(t, opts.Validate())
+
+	opts = SetSecurityOptions{DropCaps: []string{"ALL"}}
+	assert.NoError(t, opts.Validate())
+}
+
+func TestMergeCapabilities(t *testing.T) {
+	assert.Equal(t, []corev1.Capability{"NET_RAW", "ALL"}, mergeCapabilities([]corev1.Capability{"NET_RAW"}, []string{"ALL", "NET_RAW"}))
+}
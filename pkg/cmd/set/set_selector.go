@@ -17,20 +17,27 @@ limitations under the License.
 package set
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/writeback"
 	"github.com/spf13/cobra"
 	"k8s.io/klog/v2"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util/i18n"
@@ -51,8 +58,13 @@ type SetSelectorOptions struct {
 	resources       []string
 	selector        *metav1.LabelSelector
 	resourceVersion string
+	target          string
 
 	// computed
+	Client kubernetes.Interface
+	// Target is the workload whose pods the selector must match, nil if not given
+	Target *resource.Info
+
 	WriteToServer  bool
 	PrintObj       printers.ResourcePrinterFunc
 	Recorder       genericclioptions.Recorder
@@ -69,11 +81,18 @@ var (
 
 		A selector must begin with a letter or number, and may contain letters, numbers, hyphens, dots, and underscores, up to %[1]d characters.
 		If --resource-version is specified, then updates will use this resource version, otherwise the existing resource-version will be used.
-        Note: currently selectors can only be set on Service objects.`)
+        Note: currently selectors can only be set on Service objects.
+
+		With --target, the selector is only set if it matches at least one pod of the given workload, in the namespace
+		of the Service: a pod controlled by the workload, or by a ReplicaSet of a Deployment. The pods of other
+		workloads the selector also matches are reported on the standard error.`)
 	selectorExample = templates.Examples(`
         # set the labels and selector before creating a deployment/service pair.
         kubectl create service clusterip my-svc --clusterip="None" -o yaml --dry-run=client | kubectl-kruise set selector --local -f - 'environment=qa' -o yaml | kubectl create -f -
-        kubectl create cloneset sample -o yaml --dry-run=client | kubectl label --local -f - environment=qa -o yaml | kubectl create -f -`)
+        kubectl create cloneset sample -o yaml --dry-run=client | kubectl label --local -f - environment=qa -o yaml | kubectl create -f -
+
+        # shift the traffic of service web to the canary cloneset, after checking the selector matches its pods
+        kubectl-kruise set selector service web 'app=web,track=canary' --target cloneset/web-canary`)
 )
 
 // NewSelectorOptions returns an initialized SelectorOptions instance
@@ -115,6 +134,7 @@ func NewCmdSelector(f cmdutil.Factory, streams genericclioptions.IOStreams) *cob
 	o.RecordFlags.AddFlags(cmd)
 
 	cmd.Flags().StringVarP(&o.resourceVersion, "resource-version", "", o.resourceVersion, "If non-empty, the selectors update will only succeed if this is the current resource-version for the object. Only valid when specifying a single resource.")
+	cmd.Flags().StringVar(&o.target, "target", o.target, "If non-empty, the workload (e.g. cloneset/web-canary) whose pods the selector must match, the selector is not set otherwise.")
 	cmdutil.AddDryRunFlag(cmd)
 
 	return cmd
@@ -125,7 +145,7 @@ func (o *SetSelectorOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, arg
 	var err error
 
 	o.RecordFlags.Complete(cmd)
-	o.Recorder, err = o.RecordFlags.ToRecorder()
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
 	if err != nil {
 		return err
 	}
@@ -150,6 +170,28 @@ func (o *SetSelectorOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, arg
 	}
 
 	o.ResourceFinder = o.ResourceBuilderFlags.ToBuilder(f, o.resources)
+	if len(o.target) > 0 && !*o.ResourceBuilderFlags.Local {
+		if o.Client, err = f.KubernetesClientSet(); err != nil {
+			return err
+		}
+		namespace, _, err := f.ToRawKubeConfigLoader().Namespace()
+		if err != nil {
+			return err
+		}
+		infos, err := f.NewBuilder().
+			Unstructured().
+			NamespaceParam(namespace).DefaultNamespace().
+			ResourceTypeOrNameArgs(false, o.target).
+			Latest().
+			Do().Infos()
+		if err != nil {
+			return err
+		}
+		if len(infos) != 1 {
+			return fmt.Errorf("--target must be a single workload, got %q", o.target)
+		}
+		o.Target = infos[0]
+	}
 	o.WriteToServer = !(*o.ResourceBuilderFlags.Local || o.dryRunStrategy == cmdutil.DryRunClient)
 
 	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.dryRunStrategy)
@@ -167,6 +209,9 @@ func (o *SetSelectorOptions) Validate() error {
 	if o.selector == nil {
 		return fmt.Errorf("one selector is required")
 	}
+	if len(o.target) > 0 && o.ResourceBuilderFlags.Local != nil && *o.ResourceBuilderFlags.Local {
+		return fmt.Errorf("--target gets the pods of the workload, it can not be used with --local")
+	}
 	return nil
 }
 
@@ -175,6 +220,14 @@ func (o *SetSelectorOptions) RunSelector() error {
 	r := o.ResourceFinder.Do()
 
 	return r.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		if o.Target != nil {
+			if err := o.validateTarget(info); err != nil {
+				return err
+			}
+		}
 		patch := &Patch{Info: info}
 
 		if len(o.resourceVersion) != 0 {
@@ -212,6 +265,10 @@ func (o *SetSelectorOptions) RunSelector() error {
 		if patch.Err != nil {
 			return patch.Err
 		}
+		// the change is only written back
+		if _, only := writeback.Recording(); only {
+			return nil
+		}
 		if !o.WriteToServer {
 			return o.PrintObj(info.Object, o.Out)
 		}
@@ -233,6 +290,57 @@ func (o *SetSelectorOptions) RunSelector() error {
 	})
 }
 
+// validateTarget makes sure the selector matches pods of the target workload in the namespace of info.
+func (o *SetSelectorOptions) validateTarget(info *resource.Info) error {
+	if o.Target.Namespace != info.Namespace {
+		return fmt.Errorf("%s is not in the namespace %s of %s", o.target, info.Namespace, info.ObjectName())
+	}
+	selector, err := metav1.LabelSelectorAsSelector(o.selector)
+	if err != nil {
+		return err
+	}
+	pods, err := o.Client.CoreV1().Pods(info.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+
+	target, err := meta.Accessor(o.Target.Object)
+	if err != nil {
+		return err
+	}
+	owners := map[types.UID]bool{target.GetUID(): true}
+	if o.Target.Mapping.GroupVersionKind.GroupKind() == appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind() {
+		replicaSets, err := o.Client.AppsV1().ReplicaSets(info.Namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		for i := range replicaSets.Items {
+			if ref := metav1.GetControllerOf(&replicaSets.Items[i]); ref != nil && ref.UID == target.GetUID() {
+				owners[replicaSets.Items[i].UID] = true
+			}
+		}
+	}
+
+	var owned int
+	others := sets.NewString()
+	for i := range pods.Items {
+		if ref := metav1.GetControllerOf(&pods.Items[i]); ref != nil && owners[ref.UID] {
+			owned++
+		} else if ref != nil {
+			others.Insert(strings.ToLower(ref.Kind) + "/" + ref.Name)
+		} else {
+			others.Insert("pod/" + pods.Items[i].Name)
+		}
+	}
+	if owned == 0 {
+		return fmt.Errorf("selector %s matches no pods of %s, %s is left unchanged", selector, o.target, info.ObjectName())
+	}
+	if others.Len() > 0 {
+		fmt.Fprintf(o.ErrOut, "Warning: selector %s also matches the pods of %s\n", selector, strings.Join(others.List(), ", "))
+	}
+	return nil
+}
+
 func updateSelectorForObject(obj runtime.Object, selector metav1.LabelSelector) error {
 	copyOldSelector := func() (map[string]string, error) {
 		if len(selector.MatchExpressions) > 0 {
@@ -25,11 +25,16 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestUpdateSelectorForObjectTypes(t *testing.T) {
@@ -340,3 +345,94 @@ func TestSelectorTest(t *testing.T) {
 		t.Errorf("did not set selector: %s", buf.String())
 	}
 }
+
+func TestSelectorTarget(t *testing.T) {
+	controller := true
+	pod := func(name, track string, owner types.UID) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "some-ns",
+			Name:      name,
+			Labels:    map[string]string{"app": "web", "track": track},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps.kruise.io/v1alpha1", Kind: "CloneSet", Name: "web-" + track, UID: owner, Controller: &controller},
+			},
+		}}
+	}
+	canary := &unstructured.Unstructured{}
+	canary.SetAPIVersion("apps.kruise.io/v1alpha1")
+	canary.SetKind("CloneSet")
+	canary.SetNamespace("some-ns")
+	canary.SetName("web-canary")
+	canary.SetUID("canary-uid")
+	target := &resource.Info{
+		Namespace: "some-ns",
+		Name:      "web-canary",
+		Object:    canary,
+		Mapping: &meta.RESTMapping{
+			Resource:         schema.GroupVersionResource{Group: "apps.kruise.io", Version: "v1alpha1", Resource: "clonesets"},
+			GroupVersionKind: canary.GroupVersionKind(),
+		},
+	}
+
+	tests := []struct {
+		name           string
+		selector       string
+		expectedErr    string
+		expectedOut    string
+		expectedErrOut string
+	}{
+		{
+			name:        "matches the canary pods only",
+			selector:    "app=web,track=canary",
+			expectedOut: "service/web\n",
+		},
+		{
+			name:           "matches the stable pods too",
+			selector:       "app=web",
+			expectedOut:    "service/web\n",
+			expectedErrOut: "Warning: selector app=web also matches the pods of cloneset/web-stable\n",
+		},
+		{
+			name:        "matches no canary pods",
+			selector:    "app=web,track=stable",
+			expectedErr: "selector app=web,track=stable matches no pods of cloneset/web-canary, services/web is left unchanged",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info := &resource.Info{
+				Namespace: "some-ns",
+				Name:      "web",
+				Object: &v1.Service{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+					ObjectMeta: metav1.ObjectMeta{Namespace: "some-ns", Name: "web"},
+				},
+				Mapping: &meta.RESTMapping{Resource: v1.SchemeGroupVersion.WithResource("services")},
+			}
+			selector, err := metav1.ParseToLabelSelector(test.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			iostreams, _, buf, errBuf := genericclioptions.NewTestIOStreams()
+			o := &SetSelectorOptions{
+				selector:       selector,
+				target:         "cloneset/web-canary",
+				Client:         fake.NewSimpleClientset(pod("web-1", "stable", "stable-uid"), pod("web-2", "canary", "canary-uid")),
+				Target:         target,
+				ResourceFinder: genericclioptions.NewSimpleFakeResourceFinder(info),
+				Recorder:       genericclioptions.NoopRecorder{},
+				PrintObj:       (&printers.NamePrinter{}).PrintObj,
+				IOStreams:      iostreams,
+			}
+
+			err = o.RunSelector()
+			if len(test.expectedErr) > 0 {
+				assert.EqualError(t, err, test.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedOut, buf.String())
+			assert.Equal(t, test.expectedErrOut, errBuf.String())
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
 	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	"github.com/spf13/cobra"
 
@@ -39,7 +40,8 @@ import (
 
 var (
 	serviceaccountResources = `
-	replicationcontroller (rc), deployment (deploy), daemonset (ds), job, replicaset (rs), statefulset, cloneset (cs)`
+	replicationcontroller (rc), deployment (deploy), daemonset (ds), job, replicaset (rs), statefulset, cloneset (cs),
+	advanced statefulset (asts), advanced daemonset, broadcastjob (bcj), advancedcronjob (acj), uniteddeployment (ud)`
 
 	serviceaccountLong = templates.LongDesc(i18n.T(`
 	Update ServiceAccount of pod template resources.
@@ -51,6 +53,12 @@ var (
 	# Set Deployment nginx-deployment's ServiceAccount to serviceaccount1
 	kubectl-kruise set serviceaccount cloneset sample serviceaccount1
 
+	# Set the ServiceAccount of all the advanced daemonsets in the namespace
+	kubectl-kruise set serviceaccount daemonset.apps.kruise.io --all serviceaccount1
+
+	# Set the ServiceAccount of every cloneset labeled app=web and stop mounting its token automatically
+	kubectl-kruise set serviceaccount cloneset -l app=web serviceaccount1 --automount=false
+
 	# Print the result (in yaml format) of updated cloneset with serviceaccount from local file, without hitting apiserver
 	kubectl-kruise set sa -f CloneSet.yaml serviceaccount1 --local --dry-run=client -o yaml
 	`))
@@ -66,6 +74,8 @@ type SetServiceAccountOptions struct {
 	dryRunVerifier         *resource.DryRunVerifier
 	shortOutput            bool
 	all                    bool
+	selector               string
+	automount              *bool
 	output                 string
 	local                  bool
 	updatePodSpecForObject polymorphichelpers.UpdatePodSpecForObjectFunc
@@ -103,6 +113,7 @@ func NewCmdServiceAccount(f cmdutil.Factory, streams genericclioptions.IOStreams
 		Example:               serviceaccountExample,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
 			cmdutil.CheckErr(o.Run())
 		},
 	}
@@ -113,6 +124,8 @@ func NewCmdServiceAccount(f cmdutil.Factory, streams genericclioptions.IOStreams
 	usage := "identifying the resource to get from a server."
 	cmdutil.AddFilenameOptionFlags(cmd, &o.fileNameOptions, usage)
 	cmd.Flags().BoolVar(&o.all, "all", o.all, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.selector, "selector", "l", o.selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().Bool("automount", true, "Whether the token of the ServiceAccount is automatically mounted into the pods. Left untouched unless specified.")
 	cmd.Flags().BoolVar(&o.local, "local", o.local, "If true, set serviceaccount will NOT contact api-server but run locally.")
 	cmdutil.AddDryRunFlag(cmd)
 	return cmd
@@ -123,7 +136,7 @@ func (o *SetServiceAccountOptions) Complete(f cmdutil.Factory, cmd *cobra.Comman
 	var err error
 
 	o.RecordFlags.Complete(cmd)
-	o.Recorder, err = o.RecordFlags.ToRecorder()
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
 	if err != nil {
 		return err
 	}
@@ -133,8 +146,9 @@ func (o *SetServiceAccountOptions) Complete(f cmdutil.Factory, cmd *cobra.Comman
 	if err != nil {
 		return err
 	}
-	if o.local && o.dryRunStrategy == cmdutil.DryRunServer {
-		return fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?")
+	if cmd.Flags().Changed("automount") {
+		automount := cmdutil.GetFlagBool(cmd, "automount")
+		o.automount = &automount
 	}
 	dynamicClient, err := f.DynamicClient()
 	if err != nil {
@@ -172,8 +186,12 @@ func (o *SetServiceAccountOptions) Complete(f cmdutil.Factory, cmd *cobra.Comman
 		FilenameParam(enforceNamespace, &o.fileNameOptions).
 		Flatten()
 	if !o.local {
-		builder.ResourceTypeOrNameArgs(o.all, resources...).
+		builder.LabelSelectorParam(o.selector).
+			ResourceTypeOrNameArgs(o.all, resources...).
 			Latest()
+	} else if len(resources) > 0 {
+		// --local cannot get the resources given as TYPE NAME from the api server
+		return resource.LocalResourceError
 	}
 	o.infos, err = builder.Do().Infos()
 	if err != nil {
@@ -182,12 +200,26 @@ func (o *SetServiceAccountOptions) Complete(f cmdutil.Factory, cmd *cobra.Comman
 	return nil
 }
 
+// Validate makes sure that provided values in SetServiceAccountOptions are valid
+func (o *SetServiceAccountOptions) Validate() error {
+	if o.local && o.dryRunStrategy == cmdutil.DryRunServer {
+		return fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?")
+	}
+	if o.all && len(o.selector) > 0 {
+		return fmt.Errorf("cannot set --all and --selector at the same time")
+	}
+	return nil
+}
+
 // Run creates and applies the patch either locally or calling apiserver.
 func (o *SetServiceAccountOptions) Run() error {
 	var patchErrs []error
 	patchFn := func(obj runtime.Object) ([]byte, error) {
 		_, err := o.updatePodSpecForObject(obj, func(podSpec *corev1.PodSpec) error {
 			podSpec.ServiceAccountName = o.serviceAccountName
+			if o.automount != nil {
+				podSpec.AutomountServiceAccountToken = o.automount
+			}
 			return nil
 		})
 		if err != nil {
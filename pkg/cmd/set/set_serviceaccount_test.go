@@ -21,6 +21,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -38,6 +39,7 @@ import (
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/rest/fake"
 	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
 )
 
@@ -99,6 +101,41 @@ func TestSetServiceAccountLocal(t *testing.T) {
 	}
 }
 
+func TestSetServiceAccountAutomountLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion: schema.GroupVersion{Version: "v1"},
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+
+	outputFormat := "yaml"
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdServiceAccount(tf, streams)
+	cmd.Flags().Set("output", outputFormat)
+	cmd.Flags().Set("local", "true")
+	cmd.Flags().Set("automount", "false")
+	saConfig := SetServiceAccountOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithDefaultOutput(outputFormat).WithTypeSetter(scheme.Scheme),
+		fileNameOptions: resource.FilenameOptions{
+			Filenames: []string{"../../../testdata/set/cloneset.yaml"}},
+		local:     true,
+		IOStreams: streams,
+	}
+	err := saConfig.Complete(tf, cmd, []string{serviceAccount})
+	assert.NoError(t, err)
+	err = saConfig.Run()
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "kind: CloneSet")
+	assert.Contains(t, buf.String(), "serviceAccountName: "+serviceAccount)
+	assert.Contains(t, buf.String(), "automountServiceAccountToken: false")
+}
+
 func TestSetServiceAccountMultiLocal(t *testing.T) {
 	tf := cmdtesting.NewTestFactory().WithNamespace("test")
 	defer tf.Cleanup()
@@ -140,6 +177,58 @@ func TestSetServiceAccountMultiLocal(t *testing.T) {
 	}
 }
 
+func TestSetServiceAccountKruiseWorkloadsLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdServiceAccount(tf, streams)
+	cmd.Flags().Set("output", "yaml")
+	cmd.Flags().Set("local", "true")
+	opts := SetServiceAccountOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithDefaultOutput("yaml").WithTypeSetter(scheme.Scheme),
+		fileNameOptions: resource.FilenameOptions{
+			Filenames: []string{"../../../testdata/set/kruise-workloads.yaml"}},
+		local:     true,
+		IOStreams: streams,
+	}
+	err := opts.Complete(tf, cmd, []string{serviceAccount})
+	assert.NoError(t, err)
+	err = opts.Validate()
+	assert.NoError(t, err)
+	err = opts.Run()
+	assert.NoError(t, err)
+	for _, kind := range []string{"CloneSet", "StatefulSet", "DaemonSet", "UnitedDeployment"} {
+		assert.Contains(t, buf.String(), "kind: "+kind)
+	}
+	assert.Equal(t, 4, strings.Count(buf.String(), "serviceAccountName: "+serviceAccount))
+}
+
+func TestSetServiceAccountLocalWithResourceArgs(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	streams := genericclioptions.NewTestIOStreamsDiscard()
+	cmd := NewCmdServiceAccount(tf, streams)
+	opts := SetServiceAccountOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithTypeSetter(scheme.Scheme),
+		local:      true,
+		IOStreams:  streams,
+	}
+	err := opts.Complete(tf, cmd, []string{"cloneset", "web", serviceAccount})
+	assert.Equal(t, resource.LocalResourceError, err)
+}
+
 func TestSetServiceAccountRemote(t *testing.T) {
 	inputs := []struct {
 		object       runtime.Object
@@ -400,6 +489,11 @@ func TestServiceAccountValidation(t *testing.T) {
 			assert.EqualError(t, err, input.errorString)
 		})
 	}
+
+	saConfig := &SetServiceAccountOptions{all: true, selector: "app=web"}
+	assert.EqualError(t, saConfig.Validate(), "cannot set --all and --selector at the same time")
+	saConfig = &SetServiceAccountOptions{local: true, dryRunStrategy: cmdutil.DryRunServer}
+	assert.EqualError(t, saConfig.Validate(), "cannot specify --local and --dry-run=server - did you mean --dry-run=client?")
 }
 
 func objBody(obj runtime.Object) io.ReadCloser {
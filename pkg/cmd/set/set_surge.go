@@ -0,0 +1,409 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	surgeLong = templates.LongDesc(`
+		Update how many pods a CloneSet may create above, and take down below, its replicas
+		during an update.
+
+		The combination is validated against the current replicas and update policy of the
+		CloneSet: both values can not round to 0, and surge is refused with the InPlaceOnly
+		policy, which never creates new pods. The quotas of the namespace and the nodes must
+		have capacity for the surge pods, unless --ignore-capacity is given.`)
+
+	surgeExample = templates.Examples(`
+		# Roll out cloneset sample by creating up to 20% more pods, never taking one down before its replacement is ready
+		kubectl-kruise set surge cloneset/sample --max-surge 20% --max-unavailable 0
+
+		# Disable surge of cloneset sample, updating at most 2 pods at a time
+		kubectl-kruise set surge cloneset/sample --max-surge 0 --max-unavailable 2
+
+		# Print the result (in yaml format) of updating a local file, without hitting the server
+		kubectl-kruise set surge -f path/to/file.yaml --max-surge 1 --local -o yaml`)
+)
+
+// SetSurgeOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetSurgeOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos          []*resource.Info
+	Selector       string
+	DryRunStrategy cmdutil.DryRunStrategy
+	DryRunVerifier *resource.DryRunVerifier
+	All            bool
+	Local          bool
+	IgnoreCapacity bool
+	Client         kubernetes.Interface
+
+	MaxSurge       string
+	MaxUnavailable string
+
+	// settings are nil unless the corresponding flag has been given
+	maxSurge       *intstr.IntOrString
+	maxUnavailable *intstr.IntOrString
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	genericclioptions.IOStreams
+}
+
+// NewSurgeOptions returns an initialized SetSurgeOptions instance
+func NewSurgeOptions(streams genericclioptions.IOStreams) *SetSurgeOptions {
+	return &SetSurgeOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("surge updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdSurge returns an initialized Command instance for the 'set surge' sub command
+func NewCmdSurge(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewSurgeOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "surge (-f FILENAME | TYPE NAME) [--max-surge=N|N%] [--max-unavailable=N|N%]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Update the max surge and max unavailable pods of a cloneset"),
+		Long:                  surgeLong,
+		Example:               surgeExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringVar(&o.MaxSurge, "max-surge", o.MaxSurge, "The maximum number or percentage of pods that can be created above the replicas during the update.")
+	cmd.Flags().StringVar(&o.MaxUnavailable, "max-unavailable", o.MaxUnavailable, "The maximum number or percentage of pods that can be unavailable during the update.")
+	cmd.Flags().BoolVar(&o.IgnoreCapacity, internalcmdutil.IgnoreCapacityFlag, o.IgnoreCapacity, "If true, set the surge even if the quotas or the nodes have not enough capacity for the surge pods.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set surge will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetSurgeOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+	if !o.Local {
+		if o.Client, err = f.KubernetesClientSet(); err != nil {
+			return err
+		}
+	}
+
+	if len(o.MaxSurge) > 0 {
+		maxSurge := intstr.Parse(o.MaxSurge)
+		o.maxSurge = &maxSurge
+	}
+	if len(o.MaxUnavailable) > 0 {
+		maxUnavailable := intstr.Parse(o.MaxUnavailable)
+		o.maxUnavailable = &maxUnavailable
+	}
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetSurgeOptions are valid
+func (o *SetSurgeOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	if o.maxSurge == nil && o.maxUnavailable == nil {
+		errors = append(errors, fmt.Errorf("at least one of --max-surge or --max-unavailable is required"))
+	}
+	if o.maxSurge != nil {
+		if err := validateIntOrPercent(*o.maxSurge); err != nil {
+			errors = append(errors, fmt.Errorf("invalid --max-surge: %v", err))
+		}
+	}
+	if o.maxUnavailable != nil {
+		if err := validateIntOrPercent(*o.maxUnavailable); err != nil {
+			errors = append(errors, fmt.Errorf("invalid --max-unavailable: %v", err))
+		}
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+// Run performs the execution of 'set surge' sub command
+func (o *SetSurgeOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		cs, ok := obj.(*appsv1alpha1.CloneSet)
+		if !ok {
+			return nil, polymorphichelpers.NewUnsupportedKindError("setting the surge", obj,
+				appsv1alpha1.SchemeGroupVersion.WithKind("CloneSet").GroupKind())
+		}
+		surge, err := o.updateSurge(cs)
+		if err != nil {
+			return nil, err
+		}
+		if surge > 0 {
+			if err := o.checkCapacity(cs, surge); err != nil {
+				return nil, err
+			}
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch surge: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// updateSurge sets the max surge and unavailable of cs, validated against its replicas and update
+// policy, and returns how many pods it may surge.
+func (o *SetSurgeOptions) updateSurge(cs *appsv1alpha1.CloneSet) (int, error) {
+	strategy := &cs.Spec.UpdateStrategy
+	if o.maxSurge != nil {
+		strategy.MaxSurge = o.maxSurge
+	}
+	if o.maxUnavailable != nil {
+		strategy.MaxUnavailable = o.maxUnavailable
+	}
+
+	replicas := 1
+	if cs.Spec.Replicas != nil {
+		replicas = int(*cs.Spec.Replicas)
+	}
+	// as the controller does, surge is rounded up and unavailable down
+	surge := 0
+	if strategy.MaxSurge != nil {
+		surge, _ = intstr.GetScaledValueFromIntOrPercent(strategy.MaxSurge, replicas, true)
+	}
+	// maxUnavailable defaults to 20% when it is not set
+	maxUnavailable := intstr.FromString("20%")
+	if strategy.MaxUnavailable != nil {
+		maxUnavailable = *strategy.MaxUnavailable
+	}
+	unavailable, _ := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, replicas, false)
+
+	if surge > 0 && strategy.Type == appsv1alpha1.InPlaceOnlyCloneSetUpdateStrategyType {
+		return 0, fmt.Errorf("maxSurge can not be used with the InPlaceOnly update policy, which never creates new pods")
+	}
+	if surge == 0 && unavailable == 0 && replicas > 0 {
+		return 0, fmt.Errorf("maxSurge %s and maxUnavailable %s are both 0 with %d replicas, the update would never progress",
+			intOrPercentString(strategy.MaxSurge), maxUnavailable.String(), replicas)
+	}
+	return surge, nil
+}
+
+// checkCapacity prints the capacity for the surge pods of cs, and fails if there is not enough
+// unless --ignore-capacity is given. Without an api-server, it only warns about the headroom needed.
+func (o *SetSurgeOptions) checkCapacity(cs *appsv1alpha1.CloneSet, surge int) error {
+	if o.Client == nil {
+		fmt.Fprintf(o.ErrOut, "Warning: cloneset/%s surges up to %d pods during updates, %s\n",
+			cs.Name, surge, surgeHeadroom(&cs.Spec.Template.Spec, surge))
+		return nil
+	}
+	report, err := internalcmdutil.CheckCapacity(o.Client, cs.Namespace, &cs.Spec.Template.Spec, surge)
+	if err != nil {
+		return err
+	}
+	if err := report.Print(o.ErrOut, "cloneset/"+cs.Name); err != nil {
+		return err
+	}
+	if !report.Go() && !o.IgnoreCapacity {
+		return fmt.Errorf("not enough capacity for %d surge pods, use --%s to set it anyway", surge, internalcmdutil.IgnoreCapacityFlag)
+	}
+	return nil
+}
+
+func intOrPercentString(value *intstr.IntOrString) string {
+	if value == nil {
+		return "<unset>"
+	}
+	return value.String()
+}
+
+// surgeHeadroom tells the resources the given number of surge pods of spec request.
+func surgeHeadroom(spec *corev1.PodSpec, pods int) string {
+	requests := map[corev1.ResourceName]*apiresource.Quantity{}
+	for _, c := range spec.Containers {
+		for name, quantity := range c.Resources.Requests {
+			if total, ok := requests[name]; ok {
+				total.Add(quantity)
+			} else {
+				q := quantity.DeepCopy()
+				requests[name] = &q
+			}
+		}
+	}
+	if len(requests) == 0 {
+		return fmt.Sprintf("make sure the quota of the namespace allows %d more pods", pods)
+	}
+
+	var needed []string
+	for name, quantity := range requests {
+		total := apiresource.NewMilliQuantity(quantity.MilliValue()*int64(pods), quantity.Format)
+		needed = append(needed, fmt.Sprintf("%s %s", name, total.String()))
+	}
+	sort.Strings(needed)
+	return fmt.Sprintf("make sure the quota of the namespace has headroom for %d pods and %s", pods, strings.Join(needed, ", "))
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"net/http"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	clientfake "k8s.io/client-go/kubernetes/fake"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestSetSurgeLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	outputFormat := "yaml"
+
+	streams, _, buf, errBuf := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdSurge(tf, streams)
+	cmd.Flags().Set("output", outputFormat)
+	cmd.Flags().Set("local", "true")
+
+	opts := SetSurgeOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithDefaultOutput(outputFormat).WithTypeSetter(scheme.Scheme),
+		FilenameOptions: resource.FilenameOptions{
+			Filenames: []string{"../../../testdata/set/cloneset.yaml"}},
+		Local:          true,
+		MaxSurge:       "50%",
+		MaxUnavailable: "0",
+		IOStreams:      streams,
+	}
+	err := opts.Complete(tf, cmd, []string{})
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err == nil {
+		err = opts.Run()
+	}
+	assert.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "kind: CloneSet")
+	assert.Contains(t, out, "maxSurge: 50%")
+	assert.Contains(t, out, "maxUnavailable: 0")
+	assert.Contains(t, errBuf.String(), "surges up to 2 pods")
+}
+
+func TestSetSurgeValidation(t *testing.T) {
+	opts := SetSurgeOptions{}
+	assert.Error(t, opts.Validate())
+
+	maxSurge := intstr.FromString("surge")
+	opts = SetSurgeOptions{maxSurge: &maxSurge}
+	assert.Error(t, opts.Validate())
+}
+
+func TestUpdateSurge(t *testing.T) {
+	replicas := int32(3)
+	newCloneSet := func(policy appsv1alpha1.CloneSetUpdateStrategyType) *appsv1alpha1.CloneSet {
+		cs := &appsv1alpha1.CloneSet{}
+		cs.Spec.Replicas = &replicas
+		cs.Spec.UpdateStrategy.Type = policy
+		return cs
+	}
+	zero, one, small := intstr.FromInt(0), intstr.FromInt(1), intstr.FromString("10%")
+
+	opts := SetSurgeOptions{maxSurge: &one, maxUnavailable: &zero}
+	surge, err := opts.updateSurge(newCloneSet(appsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, surge)
+
+	_, err = opts.updateSurge(newCloneSet(appsv1alpha1.InPlaceOnlyCloneSetUpdateStrategyType))
+	assert.Error(t, err)
+
+	// 10% of 3 replicas rounds down to 0 unavailable pods
+	opts = SetSurgeOptions{maxSurge: &zero, maxUnavailable: &small}
+	_, err = opts.updateSurge(newCloneSet(appsv1alpha1.RecreateCloneSetUpdateStrategyType))
+	assert.Error(t, err)
+
+	// the default maxUnavailable of 20% rounds down to 0 as well
+	opts = SetSurgeOptions{maxSurge: &zero}
+	_, err = opts.updateSurge(newCloneSet(appsv1alpha1.RecreateCloneSetUpdateStrategyType))
+	assert.Error(t, err)
+}
+
+func TestSurgeCapacity(t *testing.T) {
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pods"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourcePods: apiresource.MustParse("4")},
+			Used: corev1.ResourceList{corev1.ResourcePods: apiresource.MustParse("3")},
+		},
+	}
+	streams, _, _, errBuf := genericclioptions.NewTestIOStreams()
+	cs := &appsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "sample"}}
+
+	opts := SetSurgeOptions{Client: clientfake.NewSimpleClientset(quota), IOStreams: streams}
+	assert.Error(t, opts.checkCapacity(cs, 2))
+	assert.Contains(t, errBuf.String(), "NO-GO")
+
+	opts.IgnoreCapacity = true
+	assert.NoError(t, opts.checkCapacity(cs, 2))
+}
+
+func TestSurgeHeadroom(t *testing.T) {
+	spec := &corev1.PodSpec{Containers: []corev1.Container{
+		{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    apiresource.MustParse("100m"),
+			corev1.ResourceMemory: apiresource.MustParse("128Mi"),
+		}}},
+		{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU: apiresource.MustParse("50m"),
+		}}},
+	}}
+	assert.Equal(t, "make sure the quota of the namespace has headroom for 2 pods and cpu 300m, memory 256Mi", surgeHeadroom(spec, 2))
+	assert.Equal(t, "make sure the quota of the namespace allows 2 more pods", surgeHeadroom(&corev1.PodSpec{}, 2))
+}
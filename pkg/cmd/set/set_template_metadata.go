@@ -0,0 +1,332 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	templateMetadataLong = templates.LongDesc(`
+		Update labels and annotations of a pod template (spec.template.metadata).
+
+		Unlike 'kubectl label' and 'kubectl annotate', which change the metadata of the workload
+		object itself, this command changes the metadata of the pods the workload creates. Any
+		change to the pod template creates a new revision and rolls out to every pod: workloads
+		using the InPlaceIfPossible or InPlaceOnly update policy update their pods in place,
+		all others recreate them. Labels used by the selector of the workload can not be changed.
+
+		Possible resources include (case insensitive):
+		` + imageResources)
+
+	templateMetadataExample = templates.Examples(`
+		# Add the team=web label and the prometheus.io/scrape=true annotation to the pods of cloneset sample
+		kubectl-kruise set template-metadata cloneset/sample --label team=web --annotation prometheus.io/scrape=true
+
+		# Remove the legacy label from the pods of all clonesets labeled app=web
+		kubectl-kruise set template-metadata cloneset -l app=web --remove-label legacy
+
+		# Print the result (in yaml format) of updating a local file, without hitting the server
+		kubectl-kruise set template-metadata -f path/to/file.yaml --label team=web --local -o yaml`)
+)
+
+// SetTemplateMetadataOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetTemplateMetadataOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos          []*resource.Info
+	Selector       string
+	DryRunStrategy cmdutil.DryRunStrategy
+	DryRunVerifier *resource.DryRunVerifier
+	All            bool
+	Local          bool
+
+	Labels            []string
+	Annotations       []string
+	RemoveLabels      []string
+	RemoveAnnotations []string
+
+	labelsToAdd      map[string]string
+	annotationsToAdd map[string]string
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	UpdatePodTemplateForObject polymorphichelpers.UpdatePodTemplateForObjectFunc
+
+	genericclioptions.IOStreams
+}
+
+// NewTemplateMetadataOptions returns an initialized SetTemplateMetadataOptions instance
+func NewTemplateMetadataOptions(streams genericclioptions.IOStreams) *SetTemplateMetadataOptions {
+	return &SetTemplateMetadataOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("template metadata updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdTemplateMetadata returns an initialized Command instance for the 'set template-metadata' sub command
+func NewCmdTemplateMetadata(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewTemplateMetadataOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "template-metadata (-f FILENAME | TYPE NAME) [--label=KEY=VALUE] [--annotation=KEY=VALUE] [--remove-label=KEY] [--remove-annotation=KEY]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Update labels and annotations of a pod template"),
+		Long:                  templateMetadataLong,
+		Example:               templateMetadataExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringArrayVar(&o.Labels, "label", o.Labels, "Label to set on the pod template in the form KEY=VALUE. May be repeated.")
+	cmd.Flags().StringArrayVar(&o.Annotations, "annotation", o.Annotations, "Annotation to set on the pod template in the form KEY=VALUE. May be repeated.")
+	cmd.Flags().StringArrayVar(&o.RemoveLabels, "remove-label", o.RemoveLabels, "Key of a label to remove from the pod template. May be repeated.")
+	cmd.Flags().StringArrayVar(&o.RemoveAnnotations, "remove-annotation", o.RemoveAnnotations, "Key of an annotation to remove from the pod template. May be repeated.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set template-metadata will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetTemplateMetadataOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.UpdatePodTemplateForObject = polymorphichelpers.UpdatePodTemplateForObjectFn
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	o.labelsToAdd, _, err = cmdutil.ParsePairs(o.Labels, "label", false)
+	if err != nil {
+		return err
+	}
+	o.annotationsToAdd, _, err = cmdutil.ParsePairs(o.Annotations, "annotation", false)
+	if err != nil {
+		return err
+	}
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetTemplateMetadataOptions are valid
+func (o *SetTemplateMetadataOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	if len(o.Labels) == 0 && len(o.Annotations) == 0 && len(o.RemoveLabels) == 0 && len(o.RemoveAnnotations) == 0 {
+		errors = append(errors, fmt.Errorf("at least one of --label, --annotation, --remove-label or --remove-annotation is required"))
+	}
+	for key := range o.labelsToAdd {
+		if sets.NewString(o.RemoveLabels...).Has(key) {
+			errors = append(errors, fmt.Errorf("cannot both set and remove label %q", key))
+		}
+	}
+	for key := range o.annotationsToAdd {
+		if sets.NewString(o.RemoveAnnotations...).Has(key) {
+			errors = append(errors, fmt.Errorf("cannot both set and remove annotation %q", key))
+		}
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+// Run performs the execution of 'set template-metadata' sub command
+func (o *SetTemplateMetadataOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		selector := podSelectorForObject(obj)
+		_, err := o.UpdatePodTemplateForObject(obj, func(template *corev1.PodTemplateSpec) error {
+			if selector != nil {
+				for key, value := range selector.MatchLabels {
+					if newValue, ok := o.labelsToAdd[key]; ok && newValue != value {
+						return fmt.Errorf("label %q is used by the selector and can not be changed", key)
+					}
+					if sets.NewString(o.RemoveLabels...).Has(key) {
+						return fmt.Errorf("label %q is used by the selector and can not be removed", key)
+					}
+				}
+			}
+			template.Labels = updateStringMap(template.Labels, o.labelsToAdd, o.RemoveLabels)
+			template.Annotations = updateStringMap(template.Annotations, o.annotationsToAdd, o.RemoveAnnotations)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		o.warnUpdatePolicy(info)
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch metadata update to pod template: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// warnUpdatePolicy tells the user how the pods of the workload will pick up the new template metadata.
+func (o *SetTemplateMetadataOptions) warnUpdatePolicy(info *resource.Info) {
+	policy, ok := polymorphichelpers.PodUpdatePolicyForObject(info.Object)
+	if ok && policy != string(appsv1alpha1.RecreateCloneSetUpdateStrategyType) {
+		fmt.Fprintf(o.ErrOut, "Warning: %s uses the %s update policy, its pods will be updated in place\n", info.ObjectName(), policy)
+		return
+	}
+	fmt.Fprintf(o.ErrOut, "Warning: changing the pod template of %s creates a new revision, its pods will be recreated\n", info.ObjectName())
+}
@@ -0,0 +1,420 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+	"strconv"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const defaultTerminationGracePeriodSeconds = int64(corev1.DefaultTerminationGracePeriodSeconds)
+
+var (
+	terminationLong = templates.LongDesc(`
+		Update how the pods of a workload are started and stopped.
+
+		--grace-period sets terminationGracePeriodSeconds of the pod template, --prestop-sleep
+		injects a preStop hook that sleeps for the given seconds into the selected containers so
+		that endpoints are removed before the container receives SIGTERM, and --min-ready-seconds
+		sets how long a new pod has to be ready before it counts as available.
+
+		A preStop sleep is only injected into containers without a preStop hook of their own,
+		unless --overwrite is given, and it must be shorter than the termination grace period.
+		Use --prestop-sleep=0 to remove a previously injected sleep.
+
+		Possible resources include (case insensitive):
+		` + imageResources)
+
+	terminationExample = templates.Examples(`
+		# Give the pods of cloneset sample 60s to stop, sleeping 10s in preStop and requiring 5s of readiness
+		kubectl-kruise set termination cloneset/sample --grace-period 60 --prestop-sleep 10 --min-ready-seconds 5
+
+		# Only inject the preStop sleep into the nginx container
+		kubectl-kruise set termination cloneset/sample -c nginx --prestop-sleep 5
+
+		# Remove the injected preStop sleep from all clonesets labeled app=web
+		kubectl-kruise set termination cloneset -l app=web --prestop-sleep 0
+
+		# Print the result (in yaml format) of updating a local file, without hitting the server
+		kubectl-kruise set termination -f path/to/file.yaml --grace-period 45 --local -o yaml`)
+)
+
+// SetTerminationOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetTerminationOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos             []*resource.Info
+	Selector          string
+	ContainerSelector string
+	DryRunStrategy    cmdutil.DryRunStrategy
+	DryRunVerifier    *resource.DryRunVerifier
+	All               bool
+	Local             bool
+	Overwrite         bool
+
+	// settings are nil unless the corresponding flag has been given
+	gracePeriod     *int64
+	preStopSleep    *int64
+	minReadySeconds *int32
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	UpdatePodSpecForObject polymorphichelpers.UpdatePodSpecForObjectFunc
+
+	genericclioptions.IOStreams
+}
+
+// NewTerminationOptions returns an initialized SetTerminationOptions instance, selecting all containers by default
+func NewTerminationOptions(streams genericclioptions.IOStreams) *SetTerminationOptions {
+	return &SetTerminationOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("termination settings updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		ContainerSelector: "*",
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdTermination returns an initialized Command instance for the 'set termination' sub command
+func NewCmdTermination(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewTerminationOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "termination (-f FILENAME | TYPE NAME) [--grace-period=SECONDS] [--prestop-sleep=SECONDS] [--min-ready-seconds=SECONDS]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Update termination grace period, preStop sleep and minReadySeconds of a workload"),
+		Long:                  terminationLong,
+		Example:               terminationExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringVarP(&o.ContainerSelector, "containers", "c", o.ContainerSelector, "The names of containers in the selected pod templates to inject the preStop sleep into, all containers are selected by default - may use wildcards")
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc("containers", internalcmdutil.ContainerCompletionFunc(f, "")))
+	cmd.Flags().Int64("grace-period", defaultTerminationGracePeriodSeconds, "The terminationGracePeriodSeconds of the pod template.")
+	cmd.Flags().Int64("prestop-sleep", 0, "Seconds to sleep in an injected preStop hook of the selected containers, 0 removes an injected sleep.")
+	cmd.Flags().Int32("min-ready-seconds", 0, "The minReadySeconds of the workload.")
+	cmd.Flags().BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "If true, replace preStop hooks that were not injected by this command.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set termination will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetTerminationOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.UpdatePodSpecForObject = polymorphichelpers.UpdatePodSpecForObjectFn
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	if cmd.Flags().Changed("grace-period") {
+		v := cmdutil.GetFlagInt64(cmd, "grace-period")
+		o.gracePeriod = &v
+	}
+	if cmd.Flags().Changed("prestop-sleep") {
+		v := cmdutil.GetFlagInt64(cmd, "prestop-sleep")
+		o.preStopSleep = &v
+	}
+	if cmd.Flags().Changed("min-ready-seconds") {
+		v := cmdutil.GetFlagInt32(cmd, "min-ready-seconds")
+		o.minReadySeconds = &v
+	}
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetTerminationOptions are valid
+func (o *SetTerminationOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	if o.gracePeriod == nil && o.preStopSleep == nil && o.minReadySeconds == nil {
+		errors = append(errors, fmt.Errorf("at least one of --grace-period, --prestop-sleep or --min-ready-seconds is required"))
+	}
+	if o.gracePeriod != nil && *o.gracePeriod < 0 {
+		errors = append(errors, fmt.Errorf("--grace-period must not be negative"))
+	}
+	if o.preStopSleep != nil && *o.preStopSleep < 0 {
+		errors = append(errors, fmt.Errorf("--prestop-sleep must not be negative"))
+	}
+	if o.minReadySeconds != nil && *o.minReadySeconds < 0 {
+		errors = append(errors, fmt.Errorf("--min-ready-seconds must not be negative"))
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+// Run performs the execution of 'set termination' sub command
+func (o *SetTerminationOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		if o.minReadySeconds != nil {
+			if err := setMinReadySeconds(obj, *o.minReadySeconds); err != nil {
+				return nil, err
+			}
+		}
+		_, err := o.UpdatePodSpecForObject(obj, func(spec *corev1.PodSpec) error {
+			if o.gracePeriod != nil {
+				spec.TerminationGracePeriodSeconds = o.gracePeriod
+			}
+			if o.preStopSleep == nil {
+				return nil
+			}
+			gracePeriod := defaultTerminationGracePeriodSeconds
+			if spec.TerminationGracePeriodSeconds != nil {
+				gracePeriod = *spec.TerminationGracePeriodSeconds
+			}
+			if *o.preStopSleep >= gracePeriod {
+				return fmt.Errorf("preStop sleep of %ds must be shorter than the termination grace period of %ds", *o.preStopSleep, gracePeriod)
+			}
+			containers, _ := selectContainers(spec.Containers, o.ContainerSelector)
+			if len(containers) == 0 {
+				return fmt.Errorf("unable to find container named %s", o.ContainerSelector)
+			}
+			for _, c := range containers {
+				if err := o.updatePreStopSleep(c); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch termination update to pod template: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+func (o *SetTerminationOptions) updatePreStopSleep(c *corev1.Container) error {
+	var existing *corev1.Handler
+	if c.Lifecycle != nil {
+		existing = c.Lifecycle.PreStop
+	}
+	if existing != nil && !isPreStopSleep(existing) && !o.Overwrite {
+		return fmt.Errorf("container %q already has a preStop hook, use --overwrite to replace it", c.Name)
+	}
+	if *o.preStopSleep == 0 {
+		if existing != nil && isPreStopSleep(existing) {
+			c.Lifecycle.PreStop = nil
+			if c.Lifecycle.PostStart == nil {
+				c.Lifecycle = nil
+			}
+		}
+		return nil
+	}
+	if c.Lifecycle == nil {
+		c.Lifecycle = &corev1.Lifecycle{}
+	}
+	c.Lifecycle.PreStop = &corev1.Handler{
+		Exec: &corev1.ExecAction{Command: []string{"sh", "-c", "sleep " + strconv.FormatInt(*o.preStopSleep, 10)}},
+	}
+	return nil
+}
+
+// isPreStopSleep returns true if the hook is a plain sleep as injected by 'set termination'.
+func isPreStopSleep(hook *corev1.Handler) bool {
+	if hook.Exec == nil || len(hook.Exec.Command) != 3 || hook.Exec.Command[0] != "sh" || hook.Exec.Command[1] != "-c" {
+		return false
+	}
+	var seconds int64
+	_, err := fmt.Sscanf(hook.Exec.Command[2], "sleep %d", &seconds)
+	return err == nil
+}
+
+func setMinReadySeconds(obj runtime.Object, seconds int32) error {
+	switch t := obj.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		t.Spec.MinReadySeconds = seconds
+	case *kruiseappsv1alpha1.DaemonSet:
+		t.Spec.MinReadySeconds = seconds
+	case *kruiseappsv1beta1.StatefulSet:
+		if t.Spec.UpdateStrategy.RollingUpdate == nil {
+			t.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{}
+		}
+		t.Spec.UpdateStrategy.RollingUpdate.MinReadySeconds = &seconds
+	case *kruiseappsv1alpha1.StatefulSet:
+		if t.Spec.UpdateStrategy.RollingUpdate == nil {
+			t.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1alpha1.RollingUpdateStatefulSetStrategy{}
+		}
+		t.Spec.UpdateStrategy.RollingUpdate.MinReadySeconds = &seconds
+	case *appsv1.Deployment:
+		t.Spec.MinReadySeconds = seconds
+	case *appsv1.DaemonSet:
+		t.Spec.MinReadySeconds = seconds
+	case *appsv1.ReplicaSet:
+		t.Spec.MinReadySeconds = seconds
+	default:
+		return polymorphichelpers.NewUnsupportedKindError("setting minReadySeconds", obj,
+			kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet").GroupKind(),
+			kruiseappsv1alpha1.SchemeGroupVersion.WithKind("DaemonSet").GroupKind(),
+			kruiseappsv1beta1.SchemeGroupVersion.WithKind("StatefulSet").GroupKind(),
+			appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind(),
+			appsv1.SchemeGroupVersion.WithKind("DaemonSet").GroupKind(),
+			appsv1.SchemeGroupVersion.WithKind("ReplicaSet").GroupKind())
+	}
+	return nil
+}
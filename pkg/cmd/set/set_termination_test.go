@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"net/http"
+	"testing"
+
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestSetTerminationLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	outputFormat := "yaml"
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdTermination(tf, streams)
+	cmd.Flags().Set("output", outputFormat)
+	cmd.Flags().Set("local", "true")
+
+	opts := SetTerminationOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithDefaultOutput(outputFormat).WithTypeSetter(scheme.Scheme),
+		FilenameOptions: resource.FilenameOptions{
+			Filenames: []string{"../../../testdata/set/cloneset.yaml"}},
+		Local:             true,
+		ContainerSelector: "*",
+		IOStreams:         streams,
+	}
+	cmd.Flags().Set("grace-period", "60")
+	cmd.Flags().Set("prestop-sleep", "10")
+	cmd.Flags().Set("min-ready-seconds", "5")
+	err := opts.Complete(tf, cmd, []string{})
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err == nil {
+		err = opts.Run()
+	}
+	assert.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "kind: CloneSet")
+	assert.Contains(t, out, "terminationGracePeriodSeconds: 60")
+	assert.Contains(t, out, "sleep 10")
+	assert.Contains(t, out, "minReadySeconds: 5")
+}
+
+func TestSetTerminationValidation(t *testing.T) {
+	opts := SetTerminationOptions{}
+	assert.Error(t, opts.Validate())
+
+	sleep := int64(-1)
+	opts = SetTerminationOptions{preStopSleep: &sleep}
+	assert.Error(t, opts.Validate())
+}
+
+func TestUpdatePreStopSleep(t *testing.T) {
+	sleep := int64(5)
+	opts := SetTerminationOptions{preStopSleep: &sleep}
+
+	c := &corev1.Container{Name: "nginx"}
+	assert.NoError(t, opts.updatePreStopSleep(c))
+	assert.Equal(t, []string{"sh", "-c", "sleep 5"}, c.Lifecycle.PreStop.Exec.Command)
+
+	sleep = 0
+	assert.NoError(t, opts.updatePreStopSleep(c))
+	assert.Nil(t, c.Lifecycle)
+
+	custom := &corev1.Container{Name: "nginx", Lifecycle: &corev1.Lifecycle{PreStop: &corev1.Handler{
+		Exec: &corev1.ExecAction{Command: []string{"/drain.sh"}},
+	}}}
+	sleep = 5
+	assert.Error(t, opts.updatePreStopSleep(custom))
+	opts.Overwrite = true
+	assert.NoError(t, opts.updatePreStopSleep(custom))
+	assert.True(t, isPreStopSleep(custom.Lifecycle.PreStop))
+}
+
+func TestSetMinReadySeconds(t *testing.T) {
+	sts := &kruiseappsv1beta1.StatefulSet{}
+	assert.NoError(t, setMinReadySeconds(sts, 10))
+	assert.Equal(t, int32(10), *sts.Spec.UpdateStrategy.RollingUpdate.MinReadySeconds)
+
+	assert.Error(t, setMinReadySeconds(&corev1.Pod{}, 10))
+}
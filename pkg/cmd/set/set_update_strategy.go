@@ -0,0 +1,570 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	appspub "github.com/openkruise/kruise-api/apps/pub"
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	updateStrategyLong = templates.LongDesc(`
+		Update how a workload rolls out new revisions of its pods.
+
+		--type switches the pod update policy between ReCreate, InPlaceIfPossible and InPlaceOnly,
+		--max-unavailable limits how many pods may be unavailable during an update, --max-surge how
+		many pods a CloneSet may create above its replicas, and --grace-period sets how long pods are
+		kept not-ready before they are updated in place.
+
+		--priority-weight and --priority-order replace the priority strategy, the order the pods
+		are updated in: by the sum of the weights of the selectors they match, or by the integer
+		value of a label, the highest first. The priority strategy of an advanced statefulset only
+		applies to the Parallel pod management policy. --scatter replaces the scatter strategy of a
+		CloneSet, which spreads the updates of the pods with the given labels over the whole update.
+		--clear-priority and --clear-scatter remove them.
+
+		The update strategy of a workload can not be changed while a rollout is in progress,
+		unless --force is given. --grace-period is refused for the ReCreate policy, --max-surge for
+		the InPlaceOnly policy, and maxSurge and maxUnavailable can not both be 0.
+
+		Possible resources include (case insensitive):
+		cloneset (cs), advanced statefulset (asts)`)
+
+	updateStrategyExample = templates.Examples(`
+		# Update the pods of cloneset sample in place, at most 20% at a time, with a grace period of 5s
+		kubectl-kruise set update-strategy cloneset/sample --type InPlaceIfPossible --max-unavailable 20% --grace-period 5
+
+		# Surge one pod at a time and update the pods of zone-a first, then spread the updates of the canary pods
+		kubectl-kruise set update-strategy cloneset/sample --max-surge 1 --max-unavailable 0 \
+		  --priority-weight 100:topology.kubernetes.io/zone=zone-a --scatter track=canary
+
+		# Update the pods of advanced statefulset sample in the order of their example.io/rank label
+		kubectl-kruise set update-strategy asts/sample --priority-order example.io/rank
+
+		# Switch all advanced statefulsets labeled app=web back to recreating their pods
+		kubectl-kruise set update-strategy asts -l app=web --type ReCreate
+
+		# Print the result (in yaml format) of updating a local file, without hitting the server
+		kubectl-kruise set update-strategy -f path/to/file.yaml --type InPlaceOnly --local -o yaml`)
+)
+
+// SetUpdateStrategyOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetUpdateStrategyOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos          []*resource.Info
+	Selector       string
+	DryRunStrategy cmdutil.DryRunStrategy
+	DryRunVerifier *resource.DryRunVerifier
+	All            bool
+	Local          bool
+	Force          bool
+
+	Type            string
+	MaxUnavailable  string
+	MaxSurge        string
+	PriorityWeights []string
+	PriorityOrder   []string
+	Scatter         []string
+	ClearPriority   bool
+	ClearScatter    bool
+
+	// settings are nil unless the corresponding flag has been given
+	maxUnavailable *intstr.IntOrString
+	maxSurge       *intstr.IntOrString
+	gracePeriod    *int32
+	priority       *appspub.UpdatePriorityStrategy
+	scatter        appsv1alpha1.UpdateScatterStrategy
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	genericclioptions.IOStreams
+}
+
+// NewUpdateStrategyOptions returns an initialized SetUpdateStrategyOptions instance
+func NewUpdateStrategyOptions(streams genericclioptions.IOStreams) *SetUpdateStrategyOptions {
+	return &SetUpdateStrategyOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("update strategy updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdUpdateStrategy returns an initialized Command instance for the 'set update-strategy' sub command
+func NewCmdUpdateStrategy(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewUpdateStrategyOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "update-strategy (-f FILENAME | TYPE NAME) [--type=POLICY] [--max-unavailable=N|N%] [--max-surge=N|N%] [--grace-period=SECONDS] [--priority-weight=WEIGHT:SELECTOR | --priority-order=KEY] [--scatter=KEY=VALUE]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Update the update strategy of a workload"),
+		Long:                  updateStrategyLong,
+		Example:               updateStrategyExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringVar(&o.Type, "type", o.Type, "The pod update policy, one of: ReCreate, InPlaceIfPossible, InPlaceOnly.")
+	cmd.Flags().StringVar(&o.MaxUnavailable, "max-unavailable", o.MaxUnavailable, "The maximum number or percentage of pods that can be unavailable during the update.")
+	cmd.Flags().StringVar(&o.MaxSurge, "max-surge", o.MaxSurge, "The maximum number or percentage of pods a CloneSet can create above its replicas during the update.")
+	cmd.Flags().Int32("grace-period", 0, "Seconds a pod is kept not-ready before it is updated in place.")
+	cmd.Flags().StringArrayVar(&o.PriorityWeights, "priority-weight", o.PriorityWeights, "A WEIGHT:SELECTOR term of the priority strategy, e.g. 50:zone=a, the pods matching the selectors with the highest weights are updated first. Can be repeated.")
+	cmd.Flags().StringArrayVar(&o.PriorityOrder, "priority-order", o.PriorityOrder, "A label key of the priority strategy, the pods with the highest integer value are updated first. Can be repeated.")
+	cmd.Flags().BoolVar(&o.ClearPriority, "clear-priority", o.ClearPriority, "If true, remove the priority strategy.")
+	cmd.Flags().StringArrayVar(&o.Scatter, "scatter", o.Scatter, "A KEY=VALUE term of the scatter strategy of a CloneSet, the pods with this label are updated evenly over the update. Can be repeated.")
+	cmd.Flags().BoolVar(&o.ClearScatter, "clear-scatter", o.ClearScatter, "If true, remove the scatter strategy of a CloneSet.")
+	cmd.Flags().BoolVar(&o.Force, "force", o.Force, "If true, change the update strategy even if a rollout is in progress.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set update-strategy will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetUpdateStrategyOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	if cmd.Flags().Changed("grace-period") {
+		gracePeriod := cmdutil.GetFlagInt32(cmd, "grace-period")
+		o.gracePeriod = &gracePeriod
+	}
+	if len(o.MaxUnavailable) > 0 {
+		maxUnavailable := intstr.Parse(o.MaxUnavailable)
+		o.maxUnavailable = &maxUnavailable
+	}
+	if len(o.MaxSurge) > 0 {
+		maxSurge := intstr.Parse(o.MaxSurge)
+		o.maxSurge = &maxSurge
+	}
+	if o.priority, err = parsePriorityStrategy(o.PriorityWeights, o.PriorityOrder); err != nil {
+		return err
+	}
+	if o.scatter, err = parseScatterStrategy(o.Scatter); err != nil {
+		return err
+	}
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetUpdateStrategyOptions are valid
+func (o *SetUpdateStrategyOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	if len(o.Type) == 0 && o.maxUnavailable == nil && o.maxSurge == nil && o.gracePeriod == nil &&
+		o.priority == nil && o.scatter == nil && !o.ClearPriority && !o.ClearScatter {
+		errors = append(errors, fmt.Errorf("at least one of --type, --max-unavailable, --max-surge, --grace-period, --priority-weight, --priority-order, --scatter, --clear-priority or --clear-scatter is required"))
+	}
+	switch o.Type {
+	case "", string(appsv1alpha1.RecreateCloneSetUpdateStrategyType), string(appsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType), string(appsv1alpha1.InPlaceOnlyCloneSetUpdateStrategyType):
+	default:
+		errors = append(errors, fmt.Errorf("invalid --type %q, must be one of ReCreate, InPlaceIfPossible or InPlaceOnly", o.Type))
+	}
+	if o.maxUnavailable != nil {
+		if err := validateIntOrPercent(*o.maxUnavailable); err != nil {
+			errors = append(errors, fmt.Errorf("invalid --max-unavailable: %v", err))
+		}
+	}
+	if o.maxSurge != nil {
+		if err := validateIntOrPercent(*o.maxSurge); err != nil {
+			errors = append(errors, fmt.Errorf("invalid --max-surge: %v", err))
+		}
+	}
+	if o.priority != nil {
+		if o.ClearPriority {
+			errors = append(errors, fmt.Errorf("--clear-priority can not be used with --priority-weight or --priority-order"))
+		}
+		if err := o.priority.FieldsValidation(); err != nil {
+			errors = append(errors, fmt.Errorf("invalid priority strategy: %v", err))
+		}
+	}
+	if o.scatter != nil {
+		if o.ClearScatter {
+			errors = append(errors, fmt.Errorf("--clear-scatter can not be used with --scatter"))
+		}
+		if err := o.scatter.FieldsValidation(); err != nil {
+			errors = append(errors, fmt.Errorf("invalid scatter strategy: %v", err))
+		}
+	}
+	if o.gracePeriod != nil && *o.gracePeriod < 0 {
+		errors = append(errors, fmt.Errorf("--grace-period must not be negative"))
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+// Run performs the execution of 'set update-strategy' sub command
+func (o *SetUpdateStrategyOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		if !o.Force && rolloutInProgress(obj) {
+			return nil, fmt.Errorf("a rollout is in progress, wait for it to finish or use --force")
+		}
+		if err := o.updateStrategy(obj); err != nil {
+			return nil, err
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch update strategy: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+func (o *SetUpdateStrategyOptions) updateStrategy(obj runtime.Object) error {
+	switch t := obj.(type) {
+	case *appsv1alpha1.CloneSet:
+		strategy := &t.Spec.UpdateStrategy
+		if len(o.Type) > 0 {
+			strategy.Type = appsv1alpha1.CloneSetUpdateStrategyType(o.Type)
+		}
+		if o.maxSurge != nil {
+			strategy.MaxSurge = o.maxSurge
+		}
+		if o.maxUnavailable != nil {
+			strategy.MaxUnavailable = o.maxUnavailable
+		}
+		if len(o.Type) > 0 || o.maxSurge != nil || o.maxUnavailable != nil {
+			surge := strategy.MaxSurge != nil && !isZeroIntOrPercent(*strategy.MaxSurge)
+			if surge && strategy.Type == appsv1alpha1.InPlaceOnlyCloneSetUpdateStrategyType {
+				return fmt.Errorf("maxSurge can not be used with the InPlaceOnly update policy, which never creates new pods")
+			}
+			if !surge && strategy.MaxUnavailable != nil && isZeroIntOrPercent(*strategy.MaxUnavailable) {
+				return fmt.Errorf("maxUnavailable can not be 0 when maxSurge is 0")
+			}
+		}
+		if o.gracePeriod != nil {
+			strategy.InPlaceUpdateStrategy = &appspub.InPlaceUpdateStrategy{GracePeriodSeconds: *o.gracePeriod}
+		}
+		if o.priority != nil || o.ClearPriority {
+			strategy.PriorityStrategy = o.priority
+		}
+		if o.scatter != nil || o.ClearScatter {
+			strategy.ScatterStrategy = o.scatter
+		}
+	case *appsv1beta1.StatefulSet:
+		if err := o.checkStatefulSetStrategy(); err != nil {
+			return err
+		}
+		if t.Spec.UpdateStrategy.RollingUpdate == nil {
+			t.Spec.UpdateStrategy.RollingUpdate = &appsv1beta1.RollingUpdateStatefulSetStrategy{}
+		}
+		strategy := t.Spec.UpdateStrategy.RollingUpdate
+		if len(o.Type) > 0 {
+			strategy.PodUpdatePolicy = appsv1beta1.PodUpdateStrategyType(o.Type)
+		}
+		if o.maxUnavailable != nil {
+			if isZeroIntOrPercent(*o.maxUnavailable) {
+				return fmt.Errorf("maxUnavailable can not be 0")
+			}
+			strategy.MaxUnavailable = o.maxUnavailable
+		}
+		if o.gracePeriod != nil {
+			strategy.InPlaceUpdateStrategy = &appspub.InPlaceUpdateStrategy{GracePeriodSeconds: *o.gracePeriod}
+		}
+		if o.priority != nil || o.ClearPriority {
+			if o.priority != nil && t.Spec.PodManagementPolicy != appsv1.ParallelPodManagement {
+				return fmt.Errorf("the priority strategy of an advanced statefulset requires the Parallel podManagementPolicy")
+			}
+			// the priority strategy is all an unordered update holds
+			strategy.UnorderedUpdate = nil
+			if o.priority != nil {
+				strategy.UnorderedUpdate = &appsv1beta1.UnorderedUpdateStrategy{PriorityStrategy: o.priority}
+			}
+		}
+	case *appsv1alpha1.StatefulSet:
+		if err := o.checkStatefulSetStrategy(); err != nil {
+			return err
+		}
+		if t.Spec.UpdateStrategy.RollingUpdate == nil {
+			t.Spec.UpdateStrategy.RollingUpdate = &appsv1alpha1.RollingUpdateStatefulSetStrategy{}
+		}
+		strategy := t.Spec.UpdateStrategy.RollingUpdate
+		if len(o.Type) > 0 {
+			strategy.PodUpdatePolicy = appsv1alpha1.PodUpdateStrategyType(o.Type)
+		}
+		if o.maxUnavailable != nil {
+			if isZeroIntOrPercent(*o.maxUnavailable) {
+				return fmt.Errorf("maxUnavailable can not be 0")
+			}
+			strategy.MaxUnavailable = o.maxUnavailable
+		}
+		if o.gracePeriod != nil {
+			strategy.InPlaceUpdateStrategy = &appspub.InPlaceUpdateStrategy{GracePeriodSeconds: *o.gracePeriod}
+		}
+		if o.priority != nil || o.ClearPriority {
+			if o.priority != nil && t.Spec.PodManagementPolicy != appsv1.ParallelPodManagement {
+				return fmt.Errorf("the priority strategy of an advanced statefulset requires the Parallel podManagementPolicy")
+			}
+			// the priority strategy is all an unordered update holds
+			strategy.UnorderedUpdate = nil
+			if o.priority != nil {
+				strategy.UnorderedUpdate = &appsv1alpha1.UnorderedUpdateStrategy{PriorityStrategy: o.priority}
+			}
+		}
+	default:
+		return polymorphichelpers.NewUnsupportedKindError("setting the update strategy", obj,
+			appsv1alpha1.SchemeGroupVersion.WithKind("CloneSet").GroupKind(),
+			appsv1beta1.SchemeGroupVersion.WithKind("StatefulSet").GroupKind())
+	}
+
+	if o.gracePeriod != nil {
+		if policy, _ := polymorphichelpers.PodUpdatePolicyForObject(obj); policy == string(appsv1alpha1.RecreateCloneSetUpdateStrategyType) {
+			return fmt.Errorf("--grace-period only applies to in-place update, but the update policy is %s", policy)
+		}
+	}
+	return nil
+}
+
+// checkStatefulSetStrategy refuses the settings of the CloneSet update strategy that advanced statefulsets do not have.
+func (o *SetUpdateStrategyOptions) checkStatefulSetStrategy() error {
+	if o.maxSurge != nil {
+		return fmt.Errorf("maxSurge is only supported by CloneSets")
+	}
+	if o.scatter != nil || o.ClearScatter {
+		return fmt.Errorf("the scatter strategy is only supported by CloneSets")
+	}
+	return nil
+}
+
+// parsePriorityStrategy returns the priority strategy of the WEIGHT:SELECTOR terms and the ordered
+// label keys, nil if none are given.
+func parsePriorityStrategy(weights, order []string) (*appspub.UpdatePriorityStrategy, error) {
+	if len(weights) == 0 && len(order) == 0 {
+		return nil, nil
+	}
+	strategy := &appspub.UpdatePriorityStrategy{}
+	for _, term := range weights {
+		parts := strings.SplitN(term, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid --priority-weight %q, must be WEIGHT:SELECTOR", term)
+		}
+		weight, err := strconv.ParseInt(parts[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight of --priority-weight %q: %v", term, err)
+		}
+		selector, err := metav1.ParseToLabelSelector(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector of --priority-weight %q: %v", term, err)
+		}
+		strategy.WeightPriority = append(strategy.WeightPriority, appspub.UpdatePriorityWeightTerm{Weight: int32(weight), MatchSelector: *selector})
+	}
+	for _, key := range order {
+		strategy.OrderPriority = append(strategy.OrderPriority, appspub.UpdatePriorityOrderTerm{OrderedKey: key})
+	}
+	return strategy, nil
+}
+
+// parseScatterStrategy returns the scatter strategy of the KEY=VALUE terms, nil if none are given.
+func parseScatterStrategy(terms []string) (appsv1alpha1.UpdateScatterStrategy, error) {
+	var strategy appsv1alpha1.UpdateScatterStrategy
+	for _, term := range terms {
+		parts := strings.SplitN(term, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid --scatter %q, must be KEY=VALUE", term)
+		}
+		strategy = append(strategy, appsv1alpha1.UpdateScatterTerm{Key: parts[0], Value: parts[1]})
+	}
+	return strategy, nil
+}
+
+// rolloutInProgress returns true if the workload has not yet observed its latest spec or not all of its pods are updated.
+func rolloutInProgress(obj runtime.Object) bool {
+	switch t := obj.(type) {
+	case *appsv1alpha1.CloneSet:
+		return t.Status.ObservedGeneration < t.Generation || t.Status.UpdatedReplicas < t.Status.Replicas
+	case *appsv1beta1.StatefulSet:
+		return t.Status.ObservedGeneration < t.Generation || t.Status.UpdatedReplicas < t.Status.Replicas
+	case *appsv1alpha1.StatefulSet:
+		return t.Status.ObservedGeneration < t.Generation || t.Status.UpdatedReplicas < t.Status.Replicas
+	}
+	return false
+}
+
+func validateIntOrPercent(value intstr.IntOrString) error {
+	if value.Type == intstr.Int {
+		if value.IntVal < 0 {
+			return fmt.Errorf("%d must not be negative", value.IntVal)
+		}
+		return nil
+	}
+	if !strings.HasSuffix(value.StrVal, "%") {
+		return fmt.Errorf("%q must be an integer or a percentage", value.StrVal)
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(value.StrVal, "%"))
+	if err != nil || percent < 0 || percent > 100 {
+		return fmt.Errorf("%q must be a percentage between 0%% and 100%%", value.StrVal)
+	}
+	return nil
+}
+
+func isZeroIntOrPercent(value intstr.IntOrString) bool {
+	if value.Type == intstr.Int {
+		return value.IntVal == 0
+	}
+	return strings.TrimSuffix(value.StrVal, "%") == "0"
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestSetUpdateStrategyLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	outputFormat := "yaml"
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdUpdateStrategy(tf, streams)
+	cmd.Flags().Set("output", outputFormat)
+	cmd.Flags().Set("local", "true")
+
+	opts := SetUpdateStrategyOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithDefaultOutput(outputFormat).WithTypeSetter(scheme.Scheme),
+		FilenameOptions: resource.FilenameOptions{
+			Filenames: []string{"../../../testdata/set/cloneset.yaml"}},
+		Local:          true,
+		Type:           "InPlaceIfPossible",
+		MaxUnavailable: "20%",
+		IOStreams:      streams,
+	}
+	cmd.Flags().Set("grace-period", "5")
+	err := opts.Complete(tf, cmd, []string{})
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err == nil {
+		err = opts.Run()
+	}
+	assert.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "kind: CloneSet")
+	assert.Contains(t, out, "type: InPlaceIfPossible")
+	assert.Contains(t, out, "maxUnavailable: 20%")
+	assert.Contains(t, out, "gracePeriodSeconds: 5")
+}
+
+func TestSetUpdateStrategyValidation(t *testing.T) {
+	opts := SetUpdateStrategyOptions{}
+	assert.Error(t, opts.Validate())
+
+	opts = SetUpdateStrategyOptions{Type: "Rolling"}
+	assert.Error(t, opts.Validate())
+
+	maxUnavailable := intstr.FromString("120%")
+	opts = SetUpdateStrategyOptions{maxUnavailable: &maxUnavailable}
+	assert.Error(t, opts.Validate())
+
+	tests := []struct {
+		weights  []string
+		order    []string
+		scatter  []string
+		clear    bool
+		expected string
+	}{
+		{weights: []string{"50:zone=a"}},
+		{order: []string{"example.io/rank"}, scatter: []string{"track=canary"}},
+		{weights: []string{"zone=a"}, expected: `invalid --priority-weight "zone=a", must be WEIGHT:SELECTOR`},
+		{weights: []string{"high:zone=a"}, expected: `invalid weight of --priority-weight "high:zone=a"`},
+		{weights: []string{"50:zone=a"}, order: []string{"example.io/rank"}, expected: "invalid priority strategy: only one of weightPriority and orderPriority can be used"},
+		{weights: []string{"150:zone=a"}, expected: "invalid priority strategy: weight must be valid number in the range 1-100"},
+		{weights: []string{"50:zone=a"}, clear: true, expected: "--clear-priority can not be used with --priority-weight or --priority-order"},
+		{scatter: []string{"track"}, expected: `invalid --scatter "track", must be KEY=VALUE`},
+		{scatter: []string{"track=canary", "track=canary"}, expected: "invalid scatter strategy: duplicated key=track value=canary"},
+	}
+	for _, test := range tests {
+		opts := SetUpdateStrategyOptions{ClearPriority: test.clear}
+		var err error
+		opts.priority, err = parsePriorityStrategy(test.weights, test.order)
+		if err == nil {
+			opts.scatter, err = parseScatterStrategy(test.scatter)
+		}
+		if err == nil {
+			err = opts.Validate()
+		}
+		if len(test.expected) == 0 {
+			assert.NoError(t, err)
+			continue
+		}
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), test.expected), err.Error())
+		}
+	}
+}
+
+func TestUpdateStrategyPriorityAndScatter(t *testing.T) {
+	priority, err := parsePriorityStrategy([]string{"100:zone=a"}, nil)
+	assert.NoError(t, err)
+	scatter, err := parseScatterStrategy([]string{"track=canary"})
+	assert.NoError(t, err)
+	opts := SetUpdateStrategyOptions{priority: priority, scatter: scatter}
+
+	cs := &appsv1alpha1.CloneSet{}
+	assert.NoError(t, opts.updateStrategy(cs))
+	assert.Equal(t, int32(100), cs.Spec.UpdateStrategy.PriorityStrategy.WeightPriority[0].Weight)
+	assert.Equal(t, map[string]string{"zone": "a"}, cs.Spec.UpdateStrategy.PriorityStrategy.WeightPriority[0].MatchSelector.MatchLabels)
+	assert.Equal(t, appsv1alpha1.UpdateScatterStrategy{{Key: "track", Value: "canary"}}, cs.Spec.UpdateStrategy.ScatterStrategy)
+
+	opts = SetUpdateStrategyOptions{ClearPriority: true, ClearScatter: true}
+	assert.NoError(t, opts.updateStrategy(cs))
+	assert.Nil(t, cs.Spec.UpdateStrategy.PriorityStrategy)
+	assert.Nil(t, cs.Spec.UpdateStrategy.ScatterStrategy)
+
+	// advanced statefulsets have no scatter strategy, and update by priority only in parallel
+	asts := &appsv1beta1.StatefulSet{}
+	opts = SetUpdateStrategyOptions{priority: priority}
+	assert.EqualError(t, opts.updateStrategy(asts), "the priority strategy of an advanced statefulset requires the Parallel podManagementPolicy")
+	asts.Spec.PodManagementPolicy = appsv1.ParallelPodManagement
+	assert.NoError(t, opts.updateStrategy(asts))
+	assert.Equal(t, priority, asts.Spec.UpdateStrategy.RollingUpdate.UnorderedUpdate.PriorityStrategy)
+	opts = SetUpdateStrategyOptions{scatter: scatter}
+	assert.EqualError(t, opts.updateStrategy(asts), "the scatter strategy is only supported by CloneSets")
+}
+
+func TestUpdateStrategySurge(t *testing.T) {
+	one, zero := intstr.FromInt(1), intstr.FromInt(0)
+	opts := SetUpdateStrategyOptions{maxSurge: &one, maxUnavailable: &zero}
+	cs := &appsv1alpha1.CloneSet{}
+	assert.NoError(t, opts.updateStrategy(cs))
+	assert.Equal(t, one, *cs.Spec.UpdateStrategy.MaxSurge)
+	assert.Equal(t, zero, *cs.Spec.UpdateStrategy.MaxUnavailable)
+
+	// with maxUnavailable 0, the surge can not be removed
+	opts = SetUpdateStrategyOptions{maxSurge: &zero}
+	assert.EqualError(t, opts.updateStrategy(cs), "maxUnavailable can not be 0 when maxSurge is 0")
+
+	cs.Spec.UpdateStrategy.MaxSurge = &one
+	opts = SetUpdateStrategyOptions{Type: "InPlaceOnly"}
+	assert.EqualError(t, opts.updateStrategy(cs), "maxSurge can not be used with the InPlaceOnly update policy, which never creates new pods")
+	cs = &appsv1alpha1.CloneSet{}
+	assert.NoError(t, opts.updateStrategy(cs))
+	opts = SetUpdateStrategyOptions{maxSurge: &one}
+	assert.EqualError(t, opts.updateStrategy(cs), "maxSurge can not be used with the InPlaceOnly update policy, which never creates new pods")
+
+	assert.EqualError(t, opts.updateStrategy(&appsv1beta1.StatefulSet{}), "maxSurge is only supported by CloneSets")
+}
+
+func TestUpdateStrategyTransitions(t *testing.T) {
+	gracePeriod := int32(5)
+	opts := SetUpdateStrategyOptions{Type: "ReCreate", gracePeriod: &gracePeriod}
+	assert.Error(t, opts.updateStrategy(&appsv1alpha1.CloneSet{}))
+
+	maxUnavailable := intstr.FromInt(0)
+	opts = SetUpdateStrategyOptions{maxUnavailable: &maxUnavailable}
+	assert.Error(t, opts.updateStrategy(&appsv1alpha1.CloneSet{}))
+
+	cs := &appsv1alpha1.CloneSet{}
+	cs.Generation = 2
+	cs.Status.ObservedGeneration = 1
+	assert.True(t, rolloutInProgress(cs))
+	cs.Status.ObservedGeneration = 2
+	assert.False(t, rolloutInProgress(cs))
+}
@@ -0,0 +1,487 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+	"strings"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// The types of the volumes added by 'set volume'
+const (
+	VolumeTypeEmptyDir  = "emptyDir"
+	VolumeTypeHostPath  = "hostPath"
+	VolumeTypeConfigMap = "configMap"
+	VolumeTypeSecret    = "secret"
+	VolumeTypePVC       = "persistentVolumeClaim"
+)
+
+var volumeTypes = map[string]string{
+	"emptydir":              VolumeTypeEmptyDir,
+	"hostpath":              VolumeTypeHostPath,
+	"configmap":             VolumeTypeConfigMap,
+	"secret":                VolumeTypeSecret,
+	"persistentvolumeclaim": VolumeTypePVC,
+	"pvc":                   VolumeTypePVC,
+}
+
+var (
+	volumeLong = templates.LongDesc(`
+		Add or remove the volumes of a pod template and their mounts in its containers.
+
+		--add adds the volume --name of --type, emptyDir by default, and mounts it at --mount-path
+		in the selected containers. Without --type, the existing volume --name is only mounted,
+		which is how a volume is mounted in more containers. A volume or mount that already
+		exists is only replaced with --overwrite.
+
+		--remove unmounts the volume --name from the selected containers, and removes it from the
+		pod template when no container mounts it anymore.
+
+		Possible resources include (case insensitive):
+		` + envResources)
+
+	volumeExample = templates.Examples(`
+		# Add an emptyDir volume cache to cloneset foo, mounted at /cache in all its containers
+		kubectl-kruise set volume cloneset/foo --add --name=cache --type=emptyDir --mount-path=/cache
+
+		# Mount the configmap nginx-conf read-only at /etc/nginx/conf.d in the nginx container
+		kubectl-kruise set volume cloneset/foo --add --name=conf --type=configMap --configmap-name=nginx-conf --mount-path=/etc/nginx/conf.d --read-only -c nginx
+
+		# Also mount the existing volume cache in the sidecar container
+		kubectl-kruise set volume cloneset/foo --add --name=cache --mount-path=/var/cache -c sidecar
+
+		# Replace the volume data of advanced statefulset db with the persistent volume claim db-data
+		kubectl-kruise set volume asts/db --add --name=data --type=pvc --claim-name=db-data --mount-path=/var/lib/mysql --overwrite
+
+		# Remove the volume cache and its mounts
+		kubectl-kruise set volume cloneset/foo --remove --name=cache
+
+		# Print the result (in yaml format) of adding a volume to a local file, without hitting the server
+		kubectl-kruise set volume -f path/to/file.yaml --add --name=cache --mount-path=/cache --local -o yaml`)
+)
+
+// SetVolumeOptions is the start of the data required to perform the operation. As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SetVolumeOptions struct {
+	resource.FilenameOptions
+
+	PrintFlags  *genericclioptions.PrintFlags
+	RecordFlags *genericclioptions.RecordFlags
+
+	Infos             []*resource.Info
+	Selector          string
+	ContainerSelector string
+	DryRunStrategy    cmdutil.DryRunStrategy
+	DryRunVerifier    *resource.DryRunVerifier
+	All               bool
+	Local             bool
+	Overwrite         bool
+
+	Add           bool
+	Remove        bool
+	Name          string
+	Type          string
+	Path          string
+	ConfigMapName string
+	SecretName    string
+	ClaimName     string
+	MountPath     string
+	SubPath       string
+	ReadOnly      bool
+
+	PrintObj printers.ResourcePrinterFunc
+	Recorder genericclioptions.Recorder
+
+	UpdatePodSpecForObject polymorphichelpers.UpdatePodSpecForObjectFunc
+
+	genericclioptions.IOStreams
+}
+
+// NewVolumeOptions returns an initialized SetVolumeOptions instance, selecting all containers by default
+func NewVolumeOptions(streams genericclioptions.IOStreams) *SetVolumeOptions {
+	return &SetVolumeOptions{
+		PrintFlags:  genericclioptions.NewPrintFlags("volume updated").WithTypeSetter(scheme.Scheme),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+
+		Recorder: genericclioptions.NoopRecorder{},
+
+		ContainerSelector: "*",
+
+		IOStreams: streams,
+	}
+}
+
+// NewCmdVolume returns an initialized Command instance for the 'set volume' sub command
+func NewCmdVolume(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewVolumeOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "volume (-f FILENAME | TYPE NAME) (--add | --remove) --name=NAME [--type=TYPE] [--mount-path=PATH]",
+		DisableFlagsInUseLine: true,
+		Aliases:               []string{"volumes", "volume-mount"},
+		Short:                 i18n.T("Add or remove the volumes and volume mounts of a pod template"),
+		Long:                  volumeLong,
+		Example:               volumeExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringVarP(&o.ContainerSelector, "containers", "c", o.ContainerSelector, "The names of containers in the selected pod templates to mount or unmount the volume in, all containers are selected by default - may use wildcards")
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc("containers", internalcmdutil.ContainerCompletionFunc(f, "")))
+	cmd.Flags().BoolVar(&o.Add, "add", o.Add, "If true, add the volume and mount it in the selected containers.")
+	cmd.Flags().BoolVar(&o.Remove, "remove", o.Remove, "If true, unmount the volume from the selected containers, and remove it when no container mounts it anymore.")
+	cmd.Flags().StringVar(&o.Name, "name", o.Name, "The name of the volume.")
+	cmd.Flags().StringVar(&o.Type, "type", o.Type, "The type of the added volume: emptyDir, hostPath, configMap, secret or persistentVolumeClaim (pvc), emptyDir for a volume that does not exist.")
+	cmd.Flags().StringVar(&o.Path, "path", o.Path, "The path on the host of a hostPath volume.")
+	cmd.Flags().StringVar(&o.ConfigMapName, "configmap-name", o.ConfigMapName, "The name of the ConfigMap of a configMap volume.")
+	cmd.Flags().StringVar(&o.SecretName, "secret-name", o.SecretName, "The name of the Secret of a secret volume.")
+	cmd.Flags().StringVar(&o.ClaimName, "claim-name", o.ClaimName, "The name of the PersistentVolumeClaim of a persistentVolumeClaim volume.")
+	cmd.Flags().StringVar(&o.MountPath, "mount-path", o.MountPath, "The path in the selected containers to mount the volume at, the volume is not mounted if empty.")
+	cmd.Flags().StringVar(&o.SubPath, "sub-path", o.SubPath, "The path within the volume to mount instead of its root.")
+	cmd.Flags().BoolVar(&o.ReadOnly, "read-only", o.ReadOnly, "If true, mount the volume read-only.")
+	cmd.Flags().BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "If true, replace the volume and the mounts of the same names.")
+	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set volume will NOT contact api-server but run locally.")
+	cmdutil.AddDryRunFlag(cmd)
+	return cmd
+}
+
+// Complete completes all required options
+func (o *SetVolumeOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+
+	err = o.RecordFlags.Complete(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.Recorder, err = internalcmdutil.ToRecorder(f, cmd, o.RecordFlags)
+	if err != nil {
+		return err
+	}
+
+	o.UpdatePodSpecForObject = polymorphichelpers.UpdatePodSpecForObjectFn
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = printer.PrintObj
+
+	if len(o.Type) > 0 {
+		if t, ok := volumeTypes[strings.ToLower(o.Type)]; ok {
+			o.Type = t
+		}
+	}
+
+	cmdNamespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	builder := f.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		LocalParam(o.Local).
+		ContinueOnError().
+		NamespaceParam(cmdNamespace).DefaultNamespace().
+		FilenameParam(enforceNamespace, &o.FilenameOptions).
+		Flatten()
+
+	if !o.Local {
+		builder.LabelSelectorParam(o.Selector).
+			ResourceTypeOrNameArgs(o.All, args...).
+			Latest()
+	} else if len(args) > 0 {
+		// if a --local flag was provided, and a resource was specified in the form
+		// <resource>/<name>, fail immediately as --local cannot query the api server
+		// for the specified resource.
+		return resource.LocalResourceError
+	}
+
+	o.Infos, err = builder.Do().Infos()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate makes sure provided values in SetVolumeOptions are valid
+func (o *SetVolumeOptions) Validate() error {
+	var errors []error
+	if o.All && len(o.Selector) > 0 {
+		errors = append(errors, fmt.Errorf("cannot set --all and --selector at the same time"))
+	}
+	if o.Add == o.Remove {
+		errors = append(errors, fmt.Errorf("exactly one of --add or --remove is required"))
+	}
+	if len(o.Name) == 0 {
+		errors = append(errors, fmt.Errorf("--name is required"))
+	} else {
+		for _, msg := range validation.IsDNS1123Label(o.Name) {
+			errors = append(errors, fmt.Errorf("invalid --name %q: %s", o.Name, msg))
+		}
+	}
+	if len(o.Type) > 0 && !volumeTypeKnown(o.Type) {
+		errors = append(errors, fmt.Errorf("invalid --type %q, must be emptyDir, hostPath, configMap, secret or persistentVolumeClaim", o.Type))
+	}
+	sources := map[string]string{VolumeTypeHostPath: o.Path, VolumeTypeConfigMap: o.ConfigMapName, VolumeTypeSecret: o.SecretName, VolumeTypePVC: o.ClaimName}
+	flags := map[string]string{VolumeTypeHostPath: "--path", VolumeTypeConfigMap: "--configmap-name", VolumeTypeSecret: "--secret-name", VolumeTypePVC: "--claim-name"}
+	for _, t := range []string{VolumeTypeHostPath, VolumeTypeConfigMap, VolumeTypeSecret, VolumeTypePVC} {
+		if o.Type == t && len(sources[t]) == 0 {
+			errors = append(errors, fmt.Errorf("%s is required for a %s volume", flags[t], t))
+		} else if o.Type != t && len(sources[t]) > 0 {
+			errors = append(errors, fmt.Errorf("%s is only valid with --type=%s", flags[t], t))
+		}
+	}
+	if o.Add && len(o.Type) == 0 && len(o.MountPath) == 0 {
+		errors = append(errors, fmt.Errorf("--add requires --type or --mount-path"))
+	}
+	if o.Remove && (len(o.Type) > 0 || len(o.MountPath) > 0 || len(o.SubPath) > 0 || o.ReadOnly) {
+		errors = append(errors, fmt.Errorf("--remove only takes --name and --containers"))
+	}
+	if len(o.MountPath) == 0 && (len(o.SubPath) > 0 || o.ReadOnly) {
+		errors = append(errors, fmt.Errorf("--sub-path and --read-only require --mount-path"))
+	}
+	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
+	}
+	return utilerrors.NewAggregate(errors)
+}
+
+func volumeTypeKnown(t string) bool {
+	for _, known := range volumeTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Run performs the execution of 'set volume' sub command
+func (o *SetVolumeOptions) Run() error {
+	var allErrs []error
+
+	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
+		_, err := o.UpdatePodSpecForObject(obj, func(spec *corev1.PodSpec) error {
+			if o.Add {
+				return o.addVolume(spec)
+			}
+			return o.removeVolume(spec)
+		})
+		if err != nil {
+			return nil, err
+		}
+		// record this change (for rollout history)
+		if err := o.Recorder.Record(obj); err != nil {
+			klog.V(4).Infof("error recording current command: %v", err)
+		}
+
+		return runtime.Encode(scheme.DefaultJSONEncoder(), obj)
+	})
+
+	for _, patch := range patches {
+		info := patch.Info
+		name := info.ObjectName()
+		if patch.Err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, patch.Err))
+			continue
+		}
+
+		// no changes
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			continue
+		}
+
+		if o.Local || o.DryRunStrategy == cmdutil.DryRunClient {
+			if err := o.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+			continue
+		}
+
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+
+		body, err := mergePatch(patch)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("error: %s %v\n", name, err))
+			continue
+		}
+		actual, err := resource.
+			NewHelper(info.Client, info.Mapping).
+			DryRun(o.DryRunStrategy == cmdutil.DryRunServer).
+			Patch(info.Namespace, info.Name, types.MergePatchType, body, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch volume update to pod template: %v", err))
+			continue
+		}
+
+		if err := o.PrintObj(actual, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// volume returns the volume of the type and source given by the flags.
+func (o *SetVolumeOptions) volume() corev1.Volume {
+	volume := corev1.Volume{Name: o.Name}
+	switch o.Type {
+	case VolumeTypeHostPath:
+		volume.HostPath = &corev1.HostPathVolumeSource{Path: o.Path}
+	case VolumeTypeConfigMap:
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: o.ConfigMapName}}
+	case VolumeTypeSecret:
+		volume.Secret = &corev1.SecretVolumeSource{SecretName: o.SecretName}
+	case VolumeTypePVC:
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{ClaimName: o.ClaimName, ReadOnly: o.ReadOnly}
+	default:
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+	}
+	return volume
+}
+
+func (o *SetVolumeOptions) addVolume(spec *corev1.PodSpec) error {
+	index := volumeIndex(spec.Volumes, o.Name)
+	switch {
+	case index < 0:
+		// without --type, mounting a volume that does not exist adds an emptyDir
+		spec.Volumes = append(spec.Volumes, o.volume())
+	case len(o.Type) > 0 && !o.Overwrite:
+		return fmt.Errorf("volume %q already exists, use --overwrite to replace it", o.Name)
+	case len(o.Type) > 0:
+		spec.Volumes[index] = o.volume()
+	}
+
+	if len(o.MountPath) == 0 {
+		return nil
+	}
+	containers, _ := selectContainers(spec.Containers, o.ContainerSelector)
+	if len(containers) == 0 {
+		return fmt.Errorf("unable to find container named %s", o.ContainerSelector)
+	}
+	mount := corev1.VolumeMount{Name: o.Name, MountPath: o.MountPath, SubPath: o.SubPath, ReadOnly: o.ReadOnly}
+	for _, c := range containers {
+		i := mountIndex(c.VolumeMounts, o.Name, o.MountPath)
+		switch {
+		case i < 0:
+			c.VolumeMounts = append(c.VolumeMounts, mount)
+		case !o.Overwrite:
+			return fmt.Errorf("container %q already mounts volume %q or path %s, use --overwrite to replace it", c.Name, c.VolumeMounts[i].Name, c.VolumeMounts[i].MountPath)
+		default:
+			c.VolumeMounts[i] = mount
+		}
+	}
+	return nil
+}
+
+func (o *SetVolumeOptions) removeVolume(spec *corev1.PodSpec) error {
+	index := volumeIndex(spec.Volumes, o.Name)
+	if index < 0 {
+		return fmt.Errorf("volume %q not found", o.Name)
+	}
+	containers, _ := selectContainers(spec.Containers, o.ContainerSelector)
+	initContainers, _ := selectContainers(spec.InitContainers, o.ContainerSelector)
+	if len(containers)+len(initContainers) == 0 {
+		return fmt.Errorf("unable to find container named %s", o.ContainerSelector)
+	}
+	for _, c := range append(containers, initContainers...) {
+		var mounts []corev1.VolumeMount
+		for _, mount := range c.VolumeMounts {
+			if mount.Name != o.Name {
+				mounts = append(mounts, mount)
+			}
+		}
+		c.VolumeMounts = mounts
+	}
+
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for _, c := range containers {
+			for _, mount := range c.VolumeMounts {
+				if mount.Name == o.Name {
+					// still mounted by a container that was not selected
+					return nil
+				}
+			}
+		}
+	}
+	spec.Volumes = append(spec.Volumes[:index], spec.Volumes[index+1:]...)
+	return nil
+}
+
+func volumeIndex(volumes []corev1.Volume, name string) int {
+	for i := range volumes {
+		if volumes[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// mountIndex returns the index of the mount of the volume name or at the path, or -1 if none.
+func mountIndex(mounts []corev1.VolumeMount, name, path string) int {
+	for i := range mounts {
+		if mounts[i].Name == name || mounts[i].MountPath == path {
+			return i
+		}
+	}
+	return -1
+}
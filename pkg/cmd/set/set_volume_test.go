@@ -0,0 +1,225 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestSetVolumeLocal(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	tf.Client = &fake.RESTClient{
+		GroupVersion:         schema.GroupVersion{Version: ""},
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %#v\n%#v", req.Method, req.URL, req)
+			return nil, nil
+		}),
+	}
+	tf.ClientConfigVal = &restclient.Config{ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Version: ""}}}
+
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdVolume(tf, streams)
+	cmd.Flags().Set("output", "yaml")
+	opts := NewVolumeOptions(streams)
+	opts.PrintFlags = genericclioptions.NewPrintFlags("").WithDefaultOutput("yaml").WithTypeSetter(scheme.Scheme)
+	opts.FilenameOptions = resource.FilenameOptions{Filenames: []string{"../../../testdata/set/kruise-workloads.yaml"}}
+	opts.Local = true
+	opts.Add = true
+	opts.Name = "cache"
+	opts.Type = "emptyDir"
+	opts.MountPath = "/cache"
+
+	err := opts.Complete(tf, cmd, []string{})
+	assert.NoError(t, err)
+	err = opts.Validate()
+	assert.NoError(t, err)
+	err = opts.Run()
+	assert.NoError(t, err)
+	for _, kind := range []string{"CloneSet", "StatefulSet", "DaemonSet", "UnitedDeployment"} {
+		assert.Contains(t, buf.String(), "kind: "+kind)
+	}
+	assert.Equal(t, 4, strings.Count(buf.String(), "- mountPath: /cache\n"))
+	assert.Equal(t, 4, strings.Count(buf.String(), "- emptyDir: {}\n"))
+}
+
+func volumeSpec() *corev1.PodSpec {
+	return &corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			{Name: "init", VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/init"}}},
+		},
+		Containers: []corev1.Container{
+			{Name: "app", VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}}},
+			{Name: "sidecar"},
+		},
+		Volumes: []corev1.Volume{
+			{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		},
+	}
+}
+
+func TestAddVolume(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            SetVolumeOptions
+		expectedErr     string
+		expectedVolumes []corev1.Volume
+		expectedMounts  map[string][]corev1.VolumeMount
+	}{
+		{
+			name: "add a configmap volume mounted read-only in one container",
+			opts: SetVolumeOptions{Name: "conf", Type: VolumeTypeConfigMap, ConfigMapName: "app-conf", MountPath: "/etc/app", ReadOnly: true, ContainerSelector: "sidecar"},
+			expectedVolumes: []corev1.Volume{
+				{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				{Name: "conf", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-conf"}}}},
+			},
+			expectedMounts: map[string][]corev1.VolumeMount{
+				"app":     {{Name: "data", MountPath: "/data"}},
+				"sidecar": {{Name: "conf", MountPath: "/etc/app", ReadOnly: true}},
+			},
+		},
+		{
+			name: "mount an existing volume",
+			opts: SetVolumeOptions{Name: "data", MountPath: "/shared", ContainerSelector: "sidecar"},
+			expectedVolumes: []corev1.Volume{
+				{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			},
+			expectedMounts: map[string][]corev1.VolumeMount{
+				"app":     {{Name: "data", MountPath: "/data"}},
+				"sidecar": {{Name: "data", MountPath: "/shared"}},
+			},
+		},
+		{
+			name:        "replace an existing volume without --overwrite",
+			opts:        SetVolumeOptions{Name: "data", Type: VolumeTypePVC, ClaimName: "db", ContainerSelector: "*"},
+			expectedErr: `volume "data" already exists, use --overwrite to replace it`,
+		},
+		{
+			name: "replace an existing volume and its mount",
+			opts: SetVolumeOptions{Name: "data", Type: VolumeTypePVC, ClaimName: "db", MountPath: "/var/lib/db", ContainerSelector: "app", Overwrite: true},
+			expectedVolumes: []corev1.Volume{
+				{Name: "data", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "db"}}},
+			},
+			expectedMounts: map[string][]corev1.VolumeMount{
+				"app":     {{Name: "data", MountPath: "/var/lib/db"}},
+				"sidecar": nil,
+			},
+		},
+		{
+			name:        "mount twice without --overwrite",
+			opts:        SetVolumeOptions{Name: "data", MountPath: "/other", ContainerSelector: "app"},
+			expectedErr: `container "app" already mounts volume "data" or path /data, use --overwrite to replace it`,
+		},
+		{
+			name:        "unknown container",
+			opts:        SetVolumeOptions{Name: "cache", MountPath: "/cache", ContainerSelector: "web"},
+			expectedErr: "unable to find container named web",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := volumeSpec()
+			err := test.opts.addVolume(spec)
+			if len(test.expectedErr) > 0 {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedVolumes, spec.Volumes)
+			for _, c := range spec.Containers {
+				assert.Equal(t, test.expectedMounts[c.Name], c.VolumeMounts, c.Name)
+			}
+		})
+	}
+}
+
+func TestRemoveVolume(t *testing.T) {
+	spec := volumeSpec()
+	o := SetVolumeOptions{Name: "data", ContainerSelector: "app"}
+	assert.NoError(t, o.removeVolume(spec))
+	assert.Nil(t, spec.Containers[0].VolumeMounts)
+	assert.Len(t, spec.Volumes, 1, "the volume is still mounted by the init container")
+
+	o.ContainerSelector = "*"
+	assert.NoError(t, o.removeVolume(spec))
+	assert.Nil(t, spec.InitContainers[0].VolumeMounts)
+	assert.Empty(t, spec.Volumes)
+
+	assert.EqualError(t, o.removeVolume(spec), `volume "data" not found`)
+}
+
+func TestSetVolumeValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        SetVolumeOptions
+		expectedErr string
+	}{
+		{
+			name: "add emptyDir",
+			opts: SetVolumeOptions{Add: true, Name: "cache", MountPath: "/cache"},
+		},
+		{
+			name:        "neither add nor remove",
+			opts:        SetVolumeOptions{Name: "cache"},
+			expectedErr: "exactly one of --add or --remove is required",
+		},
+		{
+			name:        "missing source",
+			opts:        SetVolumeOptions{Add: true, Name: "conf", Type: VolumeTypeSecret},
+			expectedErr: "--secret-name is required for a secret volume",
+		},
+		{
+			name:        "source of another type",
+			opts:        SetVolumeOptions{Add: true, Name: "conf", Type: VolumeTypeEmptyDir, ClaimName: "db"},
+			expectedErr: "--claim-name is only valid with --type=persistentVolumeClaim",
+		},
+		{
+			name:        "invalid name",
+			opts:        SetVolumeOptions{Add: true, Name: "Cache", MountPath: "/cache"},
+			expectedErr: `invalid --name "Cache": `,
+		},
+		{
+			name:        "remove with mount path",
+			opts:        SetVolumeOptions{Remove: true, Name: "cache", MountPath: "/cache"},
+			expectedErr: "--remove only takes --name and --containers",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.opts.Validate()
+			if len(test.expectedErr) == 0 {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.True(t, strings.HasPrefix(err.Error(), test.expectedErr), err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarset
+
+import (
+	"fmt"
+	"strings"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const allowedNamespacesFlag = "sidecarset-allowed-namespaces"
+
+// SystemNamespaces are the namespaces a SidecarSet should only be injected into on purpose.
+var SystemNamespaces = sets.NewString("kube-system", "kube-public", "kube-node-lease", "kruise-system")
+
+// CheckNamespaces returns a finding for each way the SidecarSet may be injected into namespaces it
+// is not meant for: all namespaces, a system namespace, or a namespace outside of the allowlist.
+// An allowlist containing "*" allows every namespace.
+func CheckNamespaces(sidecarSet *kruiseappsv1alpha1.SidecarSet, allowed []string) []string {
+	allowedSet := sets.NewString(allowed...)
+	if allowedSet.Has("*") {
+		return nil
+	}
+
+	var findings []string
+	namespace := sidecarSet.Spec.Namespace
+	switch {
+	case len(namespace) == 0:
+		findings = append(findings, fmt.Sprintf("SidecarSet %s has no spec.namespace and is injected into all namespaces, including %s",
+			sidecarSet.Name, strings.Join(SystemNamespaces.List(), ", ")))
+	case SystemNamespaces.Has(namespace) && !allowedSet.Has(namespace):
+		findings = append(findings, fmt.Sprintf("SidecarSet %s is injected into the system namespace %s", sidecarSet.Name, namespace))
+	case allowedSet.Len() > 0 && !allowedSet.Has(namespace):
+		findings = append(findings, fmt.Sprintf("SidecarSet %s is injected into namespace %s, which is not in the allowed namespaces %s",
+			sidecarSet.Name, namespace, strings.Join(allowedSet.List(), ", ")))
+	}
+	return findings
+}
+
+// sidecarSetsFromFiles decodes the SidecarSets among the objects of the given files, ignoring all other objects.
+// If stdin is not nil, it holds the objects of "-", already read from stdin.
+func sidecarSetsFromFiles(f cmdutil.Factory, options *resource.FilenameOptions, stdin []byte) ([]*kruiseappsv1alpha1.SidecarSet, error) {
+	infos, err := internalcmdutil.FilenameParam(f.NewBuilder().Unstructured().Local(), false, options, stdin).
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return nil, err
+	}
+
+	var sidecarSets []*kruiseappsv1alpha1.SidecarSet
+	for _, info := range infos {
+		u, ok := info.Object.(*unstructured.Unstructured)
+		if !ok || u.GroupVersionKind() != kruiseappsv1alpha1.SchemeGroupVersion.WithKind("SidecarSet") {
+			continue
+		}
+		sidecarSet := &kruiseappsv1alpha1.SidecarSet{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, sidecarSet); err != nil {
+			return nil, err
+		}
+		sidecarSets = append(sidecarSets, sidecarSet)
+	}
+	return sidecarSets, nil
+}
+
+// WithNamespaceGuard checks the SidecarSets in the files given to a command like apply or diff before it runs.
+// Findings are printed as warnings, unless the command is strict and --sidecarset-allowed-namespaces is given,
+// in which case they abort the command.
+func WithNamespaceGuard(f cmdutil.Factory, cmd *cobra.Command, strict bool) *cobra.Command {
+	usage := "Namespaces SidecarSets may be injected into, '*' allows all namespaces. SidecarSets in system namespaces or without spec.namespace are reported otherwise."
+	if strict {
+		usage += " When given, the SidecarSets injected into other namespaces are refused."
+	}
+	cmd.Flags().StringSlice(allowedNamespacesFlag, nil, usage)
+
+	preRun := cmd.PreRun
+	cmd.PreRun = func(c *cobra.Command, args []string) {
+		if preRun != nil {
+			preRun(c, args)
+		}
+		options := &resource.FilenameOptions{
+			Filenames: cmdutil.GetFlagStringSlice(c, "filename"),
+			Kustomize: cmdutil.GetFlagString(c, "kustomize"),
+			Recursive: cmdutil.GetFlagBool(c, "recursive"),
+		}
+		if cmdutil.IsFilenameSliceEmpty(options.Filenames, options.Kustomize) {
+			return
+		}
+		var stdin []byte
+		if sets.NewString(options.Filenames...).Has("-") {
+			var err error
+			stdin, err = internalcmdutil.BufferStdin()
+			cmdutil.CheckErr(err)
+		}
+		sidecarSets, err := sidecarSetsFromFiles(f, options, stdin)
+		cmdutil.CheckErr(err)
+
+		allowed := cmdutil.GetFlagStringSlice(c, allowedNamespacesFlag)
+		var findings []string
+		for _, sidecarSet := range sidecarSets {
+			findings = append(findings, CheckNamespaces(sidecarSet, allowed)...)
+		}
+		if len(findings) == 0 {
+			return
+		}
+		if strict && len(allowed) > 0 {
+			cmdutil.CheckErr(fmt.Errorf("%s\nuse --%s to allow these namespaces", strings.Join(findings, "\n"), allowedNamespacesFlag))
+		}
+		for _, finding := range findings {
+			fmt.Fprintf(c.ErrOrStderr(), "Warning: %s\n", finding)
+		}
+	}
+	return cmd
+}
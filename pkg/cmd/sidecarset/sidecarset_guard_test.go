@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarset
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const clusterWideSidecarSet = `apiVersion: apps.kruise.io/v1alpha1
+kind: SidecarSet
+metadata:
+  name: log-agent
+spec:
+  selector:
+    matchLabels:
+      app: web
+  containers:
+  - name: log-agent
+    image: fluent-bit:1.9
+`
+
+// runGuarded runs a command guarded by the namespace guard with the given stdin, and returns what
+// the command read from stdin, what it printed to the error output, and the fatal error if any.
+func runGuarded(t *testing.T, strict bool, allowed, stdin string) (string, string, string) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(stdin)
+	w.Close()
+	original := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = original }()
+
+	var fatal string
+	defer cmdutil.DefaultBehaviorOnFatal()
+	cmdutil.BehaviorOnFatal(func(msg string, code int) {
+		fatal = msg
+		panic(msg)
+	})
+
+	var read []byte
+	cmd := &cobra.Command{
+		Use: "apply",
+		Run: func(cmd *cobra.Command, args []string) {
+			read, _ = ioutil.ReadAll(os.Stdin)
+		},
+	}
+	cmdutil.AddFilenameOptionFlags(cmd, &resource.FilenameOptions{}, "")
+	errOut := &bytes.Buffer{}
+	cmd.SetErr(errOut)
+	WithNamespaceGuard(tf, cmd, strict)
+	cmd.Flags().Set("filename", "-")
+	if len(allowed) > 0 {
+		cmd.Flags().Set(allowedNamespacesFlag, allowed)
+	}
+
+	func() {
+		defer func() { recover() }()
+		cmd.PreRun(cmd, nil)
+		cmd.Run(cmd, nil)
+	}()
+	return string(read), errOut.String(), fatal
+}
+
+func TestNamespaceGuardStdin(t *testing.T) {
+	// the command still reads the objects the guard checked
+	read, errOut, fatal := runGuarded(t, true, "", clusterWideSidecarSet)
+	assert.Empty(t, fatal)
+	assert.Equal(t, clusterWideSidecarSet, read)
+	// a SidecarSet without spec.namespace is only reported without an allowlist
+	assert.Contains(t, errOut, "Warning: SidecarSet log-agent has no spec.namespace")
+
+	read, _, fatal = runGuarded(t, false, "prod", clusterWideSidecarSet)
+	assert.Empty(t, fatal)
+	assert.Equal(t, clusterWideSidecarSet, read)
+}
+
+func TestNamespaceGuardAllowlist(t *testing.T) {
+	read, _, fatal := runGuarded(t, true, "prod", clusterWideSidecarSet)
+	assert.Contains(t, fatal, "SidecarSet log-agent has no spec.namespace")
+	assert.Empty(t, read)
+
+	_, errOut, fatal := runGuarded(t, true, "*", clusterWideSidecarSet)
+	assert.Empty(t, fatal)
+	assert.Empty(t, errOut)
+}
+
+func TestCheckNamespaces(t *testing.T) {
+	inNamespace := func(namespace string) *kruiseappsv1alpha1.SidecarSet {
+		sidecarSet := newSidecarSet("web", "proxy:v1", kruiseappsv1alpha1.SidecarContainerColdUpgrade)
+		sidecarSet.Spec.Namespace = namespace
+		return sidecarSet
+	}
+
+	assert.Len(t, CheckNamespaces(inNamespace(""), nil), 1)
+	assert.Len(t, CheckNamespaces(inNamespace("kube-system"), nil), 1)
+	assert.Empty(t, CheckNamespaces(inNamespace("kube-system"), []string{"kube-system"}))
+	assert.Empty(t, CheckNamespaces(inNamespace("prod"), nil))
+	assert.Len(t, CheckNamespaces(inNamespace("dev"), []string{"prod", "staging"}), 1)
+	assert.Empty(t, CheckNamespaces(inNamespace("prod"), []string{"prod", "staging"}))
+	assert.Empty(t, CheckNamespaces(inNamespace(""), []string{"*"}))
+}
+
+func TestSidecarSetValidate(t *testing.T) {
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewSidecarSetValidateOptions(streams)
+	o.SidecarSets = func() ([]*kruiseappsv1alpha1.SidecarSet, error) {
+		good := newSidecarSet("web", "proxy:v1", kruiseappsv1alpha1.SidecarContainerColdUpgrade)
+		good.Name, good.Spec.Namespace = "good", "prod"
+		bad := newSidecarSet("web", "proxy:v1", kruiseappsv1alpha1.SidecarContainerColdUpgrade)
+		bad.Name = "bad"
+		return []*kruiseappsv1alpha1.SidecarSet{good, bad}, nil
+	}
+
+	assert.Error(t, o.Run())
+	assert.Contains(t, out.String(), "sidecarset/good: ok")
+	assert.Contains(t, out.String(), "sidecarset/bad: SidecarSet bad has no spec.namespace")
+
+	out.Reset()
+	o.AllowedNamespaces = []string{"*"}
+	assert.NoError(t, o.Run())
+}
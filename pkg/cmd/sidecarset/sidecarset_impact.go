@@ -0,0 +1,328 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// podImpact is how a pod picks up a SidecarSet change.
+type podImpact int
+
+const (
+	impactUnchanged podImpact = iota
+	// the sidecar containers are upgraded with a hot upgrade, without interrupting the pod
+	impactHotUpgrade
+	// the sidecar containers are upgraded in place by restarting them
+	impactInPlace
+	// the change only takes effect when the pod is recreated
+	impactRecreate
+)
+
+// ImpactRow counts the pods of one workload by how they pick up a SidecarSet change.
+type ImpactRow struct {
+	Namespace  string
+	Workload   string
+	HotUpgrade int
+	InPlace    int
+	Recreate   int
+	Unchanged  int
+}
+
+// SidecarSetImpactOptions holds the command-line options for 'sidecarset impact' sub command
+type SidecarSetImpactOptions struct {
+	Name string
+
+	Builder func() *resource.Builder
+	Client  kubernetes.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+var (
+	impactLong = templates.LongDesc(`
+		Show which pods would pick up a change of a SidecarSet, before applying it.
+
+		The pods matched by the current or the new SidecarSet are counted by namespace and
+		owning workload: pods whose sidecar containers only change their image are upgraded
+		in place, or with a hot upgrade if the container uses the HotUpgrade upgrade type;
+		any other change, as well as pods that start or stop being matched, only takes
+		effect when the pod is recreated.`)
+
+	impactExample = templates.Examples(`
+		# Show the impact of applying new.yaml to the sidecarset test-sidecarset
+		kubectl-kruise sidecarset impact test-sidecarset -f new.yaml`)
+)
+
+// NewSidecarSetImpactOptions returns an initialized SidecarSetImpactOptions instance
+func NewSidecarSetImpactOptions(streams genericclioptions.IOStreams) *SidecarSetImpactOptions {
+	return &SidecarSetImpactOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdSidecarSetImpact returns a Command instance for 'sidecarset impact' sub command
+func NewCmdSidecarSetImpact(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewSidecarSetImpactOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "impact NAME -f FILENAME",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show which pods would pick up a change of a SidecarSet"),
+		Long:                  impactLong,
+		Example:               impactExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "containing the new SidecarSet."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *SidecarSetImpactOptions) Complete(f cmdutil.Factory, args []string) error {
+	if len(args) > 0 {
+		o.Name = args[0]
+	}
+	o.Builder = f.NewBuilder
+
+	var err error
+	o.Client, err = f.KubernetesClientSet()
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *SidecarSetImpactOptions) Validate() error {
+	if len(o.Name) == 0 {
+		return fmt.Errorf("the name of the SidecarSet is required")
+	}
+	if cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("the new SidecarSet must be given with -f")
+	}
+	return nil
+}
+
+// Run performs the execution of 'sidecarset impact' sub command
+func (o *SidecarSetImpactOptions) Run() error {
+	current, err := o.sidecarSet(o.Builder().ResourceNames("sidecarsets", o.Name).Latest())
+	if err != nil {
+		return err
+	}
+	updated, err := o.sidecarSet(o.Builder().Local().FilenameParam(false, &o.FilenameOptions))
+	if err != nil {
+		return err
+	}
+
+	namespace := metav1.NamespaceAll
+	if current.Spec.Namespace == updated.Spec.Namespace {
+		namespace = current.Spec.Namespace
+	}
+	pods, err := o.Client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	rows, err := ComputeImpact(current, updated, pods.Items)
+	if err != nil {
+		return err
+	}
+	return o.printImpact(updated, rows)
+}
+
+func (o *SidecarSetImpactOptions) sidecarSet(b *resource.Builder) (*kruiseappsv1alpha1.SidecarSet, error) {
+	infos, err := b.WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) != 1 {
+		return nil, fmt.Errorf("expected exactly one SidecarSet, got %d objects", len(infos))
+	}
+	sidecarSet, ok := infos[0].Object.(*kruiseappsv1alpha1.SidecarSet)
+	if !ok {
+		return nil, fmt.Errorf("expected a SidecarSet, got %s", infos[0].Mapping.GroupVersionKind.Kind)
+	}
+	return sidecarSet, nil
+}
+
+func (o *SidecarSetImpactOptions) printImpact(updated *kruiseappsv1alpha1.SidecarSet, rows []ImpactRow) error {
+	w := internalcmdutil.NewTableWriter(o.Out)
+	total := ImpactRow{Namespace: "TOTAL"}
+	fmt.Fprintln(w, "NAMESPACE\tWORKLOAD\tHOT-UPGRADE\tIN-PLACE\tRECREATE\tUNCHANGED")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\n", row.Namespace, row.Workload, row.HotUpgrade, row.InPlace, row.Recreate, row.Unchanged)
+		total.HotUpgrade += row.HotUpgrade
+		total.InPlace += row.InPlace
+		total.Recreate += row.Recreate
+		total.Unchanged += row.Unchanged
+	}
+	fmt.Fprintf(w, "%s\t\t%d\t%d\t%d\t%d\n", total.Namespace, total.HotUpgrade, total.InPlace, total.Recreate, total.Unchanged)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	strategy := updated.Spec.UpdateStrategy
+	switch {
+	case strategy.Type == kruiseappsv1alpha1.NotUpdateSidecarSetStrategyType:
+		fmt.Fprintf(o.Out, "\nThe update strategy is %s, no pod is upgraded until it is recreated.\n", strategy.Type)
+	case strategy.Paused:
+		fmt.Fprintln(o.Out, "\nThe update strategy is paused, no pod is upgraded until it is resumed.")
+	case strategy.Partition != nil:
+		upgraded := total.HotUpgrade + total.InPlace
+		partition, _ := intstr.GetScaledValueFromIntOrPercent(strategy.Partition, upgraded, true)
+		fmt.Fprintf(o.Out, "\nThe partition keeps %d of the %d upgraded pods at the old version.\n", partition, upgraded)
+	}
+	return nil
+}
+
+// ComputeImpact counts how the given pods pick up the change from current to updated, by namespace and owning workload.
+func ComputeImpact(current, updated *kruiseappsv1alpha1.SidecarSet, pods []corev1.Pod) ([]ImpactRow, error) {
+	currentSelector, err := metav1.LabelSelectorAsSelector(current.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	updatedSelector, err := metav1.LabelSelectorAsSelector(updated.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	upgradeSelector := labels.Everything()
+	if updated.Spec.UpdateStrategy.Selector != nil {
+		if upgradeSelector, err = metav1.LabelSelectorAsSelector(updated.Spec.UpdateStrategy.Selector); err != nil {
+			return nil, err
+		}
+	}
+	change := sidecarChange(current, updated)
+
+	rowsByKey := map[string]*ImpactRow{}
+	for i := range pods {
+		pod := &pods[i]
+		matchedBefore := matches(current, currentSelector, pod)
+		matchedAfter := matches(updated, updatedSelector, pod)
+		if !matchedBefore && !matchedAfter {
+			continue
+		}
+
+		impact := change
+		if matchedBefore != matchedAfter {
+			impact = impactRecreate
+		} else if (impact == impactHotUpgrade || impact == impactInPlace) && !upgradeSelector.Matches(labels.Set(pod.Labels)) {
+			impact = impactRecreate
+		}
+
+		workload := "<none>"
+		if owner := metav1.GetControllerOf(pod); owner != nil {
+			workload = owner.Kind + "/" + owner.Name
+		}
+		key := pod.Namespace + "/" + workload
+		row, ok := rowsByKey[key]
+		if !ok {
+			row = &ImpactRow{Namespace: pod.Namespace, Workload: workload}
+			rowsByKey[key] = row
+		}
+		switch impact {
+		case impactHotUpgrade:
+			row.HotUpgrade++
+		case impactInPlace:
+			row.InPlace++
+		case impactRecreate:
+			row.Recreate++
+		default:
+			row.Unchanged++
+		}
+	}
+
+	rows := make([]ImpactRow, 0, len(rowsByKey))
+	for _, row := range rowsByKey {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Namespace != rows[j].Namespace {
+			return rows[i].Namespace < rows[j].Namespace
+		}
+		return rows[i].Workload < rows[j].Workload
+	})
+	return rows, nil
+}
+
+func matches(sidecarSet *kruiseappsv1alpha1.SidecarSet, selector labels.Selector, pod *corev1.Pod) bool {
+	if len(sidecarSet.Spec.Namespace) > 0 && sidecarSet.Spec.Namespace != pod.Namespace {
+		return false
+	}
+	return selector.Matches(labels.Set(pod.Labels))
+}
+
+// sidecarChange returns how the pods matched by both SidecarSets pick up the change. Only image changes of
+// sidecar containers are upgraded in place, everything else needs the pods to be recreated.
+func sidecarChange(current, updated *kruiseappsv1alpha1.SidecarSet) podImpact {
+	if !apiequality.Semantic.DeepEqual(current.Spec.InitContainers, updated.Spec.InitContainers) ||
+		!apiequality.Semantic.DeepEqual(current.Spec.Volumes, updated.Spec.Volumes) ||
+		len(current.Spec.Containers) != len(updated.Spec.Containers) {
+		return impactRecreate
+	}
+
+	impact := impactUnchanged
+	for i := range updated.Spec.Containers {
+		before, after := current.Spec.Containers[i], updated.Spec.Containers[i]
+		if before.Image == after.Image {
+			if !apiequality.Semantic.DeepEqual(before, after) {
+				return impactRecreate
+			}
+			continue
+		}
+		before.Image = after.Image
+		if !apiequality.Semantic.DeepEqual(before, after) {
+			return impactRecreate
+		}
+		if after.UpgradeStrategy.UpgradeType == kruiseappsv1alpha1.SidecarContainerHotUpgrade {
+			if impact == impactUnchanged {
+				impact = impactHotUpgrade
+			}
+		} else {
+			impact = impactInPlace
+		}
+	}
+	if impact != impactUnchanged && updated.Spec.UpdateStrategy.Type == kruiseappsv1alpha1.NotUpdateSidecarSetStrategyType {
+		return impactRecreate
+	}
+	return impact
+}
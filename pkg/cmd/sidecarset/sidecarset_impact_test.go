@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarset
+
+import (
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newSidecarSet(app, image string, upgradeType kruiseappsv1alpha1.SidecarContainerUpgradeType) *kruiseappsv1alpha1.SidecarSet {
+	return &kruiseappsv1alpha1.SidecarSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "sidecar"},
+		Spec: kruiseappsv1alpha1.SidecarSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+			Containers: []kruiseappsv1alpha1.SidecarContainer{{
+				Container:       corev1.Container{Name: "proxy", Image: image},
+				UpgradeStrategy: kruiseappsv1alpha1.SidecarContainerUpgradeStrategy{UpgradeType: upgradeType},
+			}},
+		},
+	}
+}
+
+func newPod(namespace, name, app, owner string) corev1.Pod {
+	controller := true
+	return corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       namespace,
+		Name:            name,
+		Labels:          map[string]string{"app": app},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "CloneSet", Name: owner, Controller: &controller}},
+	}}
+}
+
+func TestComputeImpact(t *testing.T) {
+	pods := []corev1.Pod{
+		newPod("default", "web-1", "web", "web"),
+		newPod("default", "web-2", "web", "web"),
+		newPod("other", "web-1", "web", "web"),
+		newPod("default", "db-1", "db", "db"),
+	}
+
+	current := newSidecarSet("web", "proxy:v1", kruiseappsv1alpha1.SidecarContainerColdUpgrade)
+	rows, err := ComputeImpact(current, newSidecarSet("web", "proxy:v2", kruiseappsv1alpha1.SidecarContainerColdUpgrade), pods)
+	assert.NoError(t, err)
+	assert.Equal(t, []ImpactRow{
+		{Namespace: "default", Workload: "CloneSet/web", InPlace: 2},
+		{Namespace: "other", Workload: "CloneSet/web", InPlace: 1},
+	}, rows)
+
+	hotUpgrade := newSidecarSet("web", "proxy:v1", kruiseappsv1alpha1.SidecarContainerHotUpgrade)
+	rows, err = ComputeImpact(hotUpgrade, newSidecarSet("web", "proxy:v2", kruiseappsv1alpha1.SidecarContainerHotUpgrade), pods[:1])
+	assert.NoError(t, err)
+	assert.Equal(t, []ImpactRow{{Namespace: "default", Workload: "CloneSet/web", HotUpgrade: 1}}, rows)
+
+	// an env change and a new selector both need the pods to be recreated
+	updated := newSidecarSet("db", "proxy:v1", kruiseappsv1alpha1.SidecarContainerColdUpgrade)
+	updated.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "LEVEL", Value: "debug"}}
+	rows, err = ComputeImpact(current, updated, pods)
+	assert.NoError(t, err)
+	assert.Equal(t, []ImpactRow{
+		{Namespace: "default", Workload: "CloneSet/db", Recreate: 1},
+		{Namespace: "default", Workload: "CloneSet/web", Recreate: 2},
+		{Namespace: "other", Workload: "CloneSet/web", Recreate: 1},
+	}, rows)
+}
+
+func TestComputeImpactNamespaceAndStrategy(t *testing.T) {
+	pods := []corev1.Pod{
+		newPod("default", "web-1", "web", "web"),
+		newPod("other", "web-1", "web", "web"),
+	}
+	current := newSidecarSet("web", "proxy:v1", kruiseappsv1alpha1.SidecarContainerColdUpgrade)
+	current.Spec.Namespace = "default"
+	updated := newSidecarSet("web", "proxy:v2", kruiseappsv1alpha1.SidecarContainerColdUpgrade)
+	updated.Spec.Namespace = "default"
+	updated.Spec.UpdateStrategy.Type = kruiseappsv1alpha1.NotUpdateSidecarSetStrategyType
+
+	rows, err := ComputeImpact(current, updated, pods)
+	assert.NoError(t, err)
+	assert.Equal(t, []ImpactRow{{Namespace: "default", Workload: "CloneSet/web", Recreate: 1}}, rows)
+}
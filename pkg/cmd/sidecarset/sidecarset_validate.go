@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarset
+
+import (
+	"fmt"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// SidecarSetValidateOptions holds the command-line options for 'sidecarset validate' sub command
+type SidecarSetValidateOptions struct {
+	AllowedNamespaces []string
+
+	SidecarSets func() ([]*kruiseappsv1alpha1.SidecarSet, error)
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+var (
+	validateLong = templates.LongDesc(`
+		Check that the SidecarSets in the given files are only injected into the namespaces they are meant for.
+
+		SidecarSets without spec.namespace are injected into every namespace, including system
+		namespaces such as kube-system. Such SidecarSets, SidecarSets injected into a system namespace
+		and, if --allowed-namespaces is given, SidecarSets injected into any other namespace are reported.
+		The same check guards 'kubectl-kruise apply' and is reported as a warning by 'kubectl-kruise diff'.`)
+
+	validateExample = templates.Examples(`
+		# Check the SidecarSets in sidecarset.yaml
+		kubectl-kruise sidecarset validate -f sidecarset.yaml
+
+		# Only allow SidecarSets to be injected into the prod and staging namespaces
+		kubectl-kruise sidecarset validate -f manifests/ -R --allowed-namespaces prod,staging`)
+)
+
+// NewSidecarSetValidateOptions returns an initialized SidecarSetValidateOptions instance
+func NewSidecarSetValidateOptions(streams genericclioptions.IOStreams) *SidecarSetValidateOptions {
+	return &SidecarSetValidateOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdSidecarSetValidate returns a Command instance for 'sidecarset validate' sub command
+func NewCmdSidecarSetValidate(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewSidecarSetValidateOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "validate -f FILENAME [--allowed-namespaces=NAMESPACE,...]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Check the namespaces SidecarSets are injected into"),
+		Long:                  validateLong,
+		Example:               validateExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "containing the SidecarSets to check."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringSliceVar(&o.AllowedNamespaces, "allowed-namespaces", o.AllowedNamespaces, "Namespaces SidecarSets may be injected into, '*' allows all namespaces.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *SidecarSetValidateOptions) Complete(f cmdutil.Factory) error {
+	o.SidecarSets = func() ([]*kruiseappsv1alpha1.SidecarSet, error) {
+		return sidecarSetsFromFiles(f, &o.FilenameOptions, nil)
+	}
+	return nil
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *SidecarSetValidateOptions) Validate() error {
+	if cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("the SidecarSets to check must be given with -f or -k")
+	}
+	return nil
+}
+
+// Run performs the execution of 'sidecarset validate' sub command
+func (o *SidecarSetValidateOptions) Run() error {
+	sidecarSets, err := o.SidecarSets()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, sidecarSet := range sidecarSets {
+		findings := CheckNamespaces(sidecarSet, o.AllowedNamespaces)
+		if len(findings) == 0 {
+			fmt.Fprintf(o.Out, "sidecarset/%s: ok\n", sidecarSet.Name)
+			continue
+		}
+		failed++
+		for _, finding := range findings {
+			fmt.Fprintf(o.Out, "sidecarset/%s: %s\n", sidecarSet.Name, finding)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d SidecarSets may be injected into unexpected namespaces", failed, len(sidecarSets))
+	}
+	return nil
+}
@@ -0,0 +1,568 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spread
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	// MatchedWorkloadSpreadAnnotation is set by Kruise on the pods injected by a WorkloadSpread
+	// to the WorkloadSpread and subset they were counted in.
+	MatchedWorkloadSpreadAnnotation = "apps.kruise.io/matched-workloadspread"
+
+	// the domain of the pods not scheduled yet, or on nodes without the topology label
+	noDomain = "<none>"
+)
+
+var (
+	spreadLong = templates.LongDesc(`
+		Show how the pods of a workload are spread over zones and nodes, by revision.
+
+		For each zone and node, the number and the percentage of the pods of the workload are
+		printed, with the number of pods of each revision. The rows of the zones and nodes that
+		break the spread of the workload are marked:
+
+		* topologySpreadConstraints of the pod template: the domains with more pods than the
+		  least loaded domain plus maxSkew, counting the pods of the workload matched by the
+		  label selector of the constraint, over the schedulable nodes matching the nodeSelector
+		  of the pod template
+		* WorkloadSpreads targeting the workload: the subsets with more pods than their
+		  maxReplicas, as counted by the annotation Kruise sets on the pods
+
+		With --fail-on-violation, the command fails if any violation is found, e.g. to stop a
+		traffic shift in a pipeline.`)
+
+	spreadExample = templates.Examples(`
+		# Show the spread of the pods of cloneset demo over zones and nodes
+		kubectl-kruise spread cloneset/demo
+
+		# Only show the zones, read from the label failure-domain.beta.kubernetes.io/zone of the nodes
+		kubectl-kruise spread cloneset/demo --by zone --zone-label failure-domain.beta.kubernetes.io/zone
+
+		# Fail if the pods of advanced statefulset db break its spread constraints
+		kubectl-kruise spread asts/db --fail-on-violation`)
+)
+
+// SpreadOptions holds the command-line options for 'spread' command
+type SpreadOptions struct {
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+	By               []string
+	ZoneLabel        string
+	FailOnViolation  bool
+
+	Builder      func() *resource.Builder
+	Client       kubernetes.Interface
+	KruiseClient kruiseclientsets.Interface
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NewSpreadOptions returns an initialized SpreadOptions instance
+func NewSpreadOptions(streams genericclioptions.IOStreams) *SpreadOptions {
+	return &SpreadOptions{
+		By:        []string{"zone", "node"},
+		ZoneLabel: corev1.LabelTopologyZone,
+		IOStreams: streams,
+	}
+}
+
+// NewCmdSpread returns a Command instance for 'spread' command
+func NewCmdSpread(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewSpreadOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "spread (TYPE/NAME | TYPE NAME) [--by=zone,node]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show the spread of the pods of a workload over zones and nodes"),
+		Long:                  spreadLong,
+		Example:               spreadExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringSliceVar(&o.By, "by", o.By, "The groups to show the pods by: zone, node or both.")
+	cmd.Flags().StringVar(&o.ZoneLabel, "zone-label", o.ZoneLabel, "The label of the nodes holding their zone.")
+	cmd.Flags().BoolVar(&o.FailOnViolation, "fail-on-violation", o.FailOnViolation, "If true, fail if the pods break the topology spread constraints or the WorkloadSpreads of the workload.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *SpreadOptions) Complete(f cmdutil.Factory, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+
+	o.Client, err = f.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	clientConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig)
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *SpreadOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	if len(o.By) == 0 {
+		return fmt.Errorf("--by must not be empty")
+	}
+	for _, by := range o.By {
+		if by != "zone" && by != "node" {
+			return fmt.Errorf("invalid --by %q, must be zone or node", by)
+		}
+	}
+	if len(o.ZoneLabel) == 0 {
+		return fmt.Errorf("--zone-label must not be empty")
+	}
+	return nil
+}
+
+// Run performs the execution of 'spread' command
+func (o *SpreadOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return err
+	}
+
+	nodeList, err := o.Client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	violations := 0
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Fprintln(o.Out)
+		}
+		pods, err := polymorphichelpers.PodsForObject(o.Client.CoreV1(), info.Object)
+		if err != nil {
+			return err
+		}
+		spreads, err := o.workloadSpreadsFor(info.Namespace, info.Mapping.GroupVersionKind, info.Name)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%s/%s", info.Mapping.Resource.GroupResource().String(), info.Name)
+		report := NewReport(pods, nodeList.Items, o.ZoneLabel)
+		if template := podTemplateFor(info.Object); template != nil {
+			report.AddTopologyViolations(template)
+		}
+		report.AddWorkloadSpreadViolations(spreads, replicasFor(info.Object, len(pods)))
+		if err := o.printReport(name, report); err != nil {
+			return err
+		}
+		violations += len(report.Violations)
+	}
+	if o.FailOnViolation && violations > 0 {
+		return fmt.Errorf("%d spread violations found", violations)
+	}
+	return nil
+}
+
+// workloadSpreadsFor returns the WorkloadSpreads in namespace targeting the workload of gvk named name.
+func (o *SpreadOptions) workloadSpreadsFor(namespace string, gvk schema.GroupVersionKind, name string) ([]kruiseappsv1alpha1.WorkloadSpread, error) {
+	list, err := o.KruiseClient.AppsV1alpha1().WorkloadSpreads(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var spreads []kruiseappsv1alpha1.WorkloadSpread
+	for _, ws := range list.Items {
+		target := ws.Spec.TargetReference
+		if target == nil || target.Kind != gvk.Kind || target.Name != name {
+			continue
+		}
+		if gv, err := schema.ParseGroupVersion(target.APIVersion); err != nil || gv.Group != gvk.Group {
+			continue
+		}
+		spreads = append(spreads, ws)
+	}
+	return spreads, nil
+}
+
+// podTemplateFor returns the pod template of workload, or nil if it has none.
+func podTemplateFor(workload runtime.Object) *corev1.PodTemplateSpec {
+	var template *corev1.PodTemplateSpec
+	_, err := polymorphichelpers.UpdatePodTemplateForObjectFn(workload, func(t *corev1.PodTemplateSpec) error {
+		template = t.DeepCopy()
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	return template
+}
+
+// replicasFor returns spec.replicas of workload, or pods if it has none, e.g. for DaemonSets.
+func replicasFor(workload runtime.Object, pods int) int {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(workload)
+	if err != nil {
+		return pods
+	}
+	replicas, found, err := unstructured.NestedInt64(content, "spec", "replicas")
+	if err != nil || !found {
+		return pods
+	}
+	return int(replicas)
+}
+
+// Group is the pods of a workload in a zone or on a node.
+type Group struct {
+	Name       string
+	Zone       string
+	Pods       int
+	ByRevision map[string]int
+	Violations []string
+}
+
+// Violation is a constraint on the spread of the pods that is broken.
+type Violation struct {
+	// Key is the label of the nodes of the domains of a topology spread constraint, empty for a
+	// WorkloadSpread.
+	Key     string
+	Domains []string
+	Message string
+}
+
+// SubsetCount is the number of pods of a subset of a WorkloadSpread.
+type SubsetCount struct {
+	WorkloadSpread string
+	Subset         string
+	Pods           int
+	MaxReplicas    *int
+}
+
+// Report is the spread of the pods of a workload.
+type Report struct {
+	Pods       []corev1.Pod
+	Revisions  []string
+	Zones      []*Group
+	Nodes      []*Group
+	Subsets    []SubsetCount
+	Violations []Violation
+
+	nodes     []corev1.Node
+	nodeNames map[string]*corev1.Node
+	zoneLabel string
+}
+
+// NewReport groups pods by the zones, read from the zoneLabel of nodes, and by the nodes they run on.
+func NewReport(pods []corev1.Pod, nodes []corev1.Node, zoneLabel string) *Report {
+	r := &Report{Pods: pods, nodes: nodes, nodeNames: map[string]*corev1.Node{}, zoneLabel: zoneLabel}
+	for i := range nodes {
+		r.nodeNames[nodes[i].Name] = &nodes[i]
+	}
+
+	zones := map[string]*Group{}
+	byNode := map[string]*Group{}
+	revisions := sets.NewString()
+	for i := range pods {
+		pod := &pods[i]
+		revision := pod.Labels[appsv1.ControllerRevisionHashLabelKey]
+		if len(revision) == 0 {
+			revision = noDomain
+		}
+		revisions.Insert(revision)
+
+		node, zone := noDomain, noDomain
+		if len(pod.Spec.NodeName) > 0 {
+			node = pod.Spec.NodeName
+			if n := r.nodeNames[node]; n != nil && len(n.Labels[zoneLabel]) > 0 {
+				zone = n.Labels[zoneLabel]
+			}
+		}
+		for _, g := range []*Group{groupOf(zones, zone, ""), groupOf(byNode, node, zone)} {
+			g.Pods++
+			g.ByRevision[revision]++
+		}
+	}
+	r.Revisions = revisions.List()
+	r.Zones = sortedGroups(zones)
+	r.Nodes = sortedGroups(byNode)
+	return r
+}
+
+func groupOf(groups map[string]*Group, name, zone string) *Group {
+	g := groups[name]
+	if g == nil {
+		g = &Group{Name: name, Zone: zone, ByRevision: map[string]int{}}
+		groups[name] = g
+	}
+	return g
+}
+
+// sortedGroups returns groups with the most pods first, and by name on ties.
+func sortedGroups(groups map[string]*Group) []*Group {
+	sorted := make([]*Group, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Pods != sorted[j].Pods {
+			return sorted[i].Pods > sorted[j].Pods
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// AddTopologyViolations adds the violations of the topology spread constraints of template, and
+// marks the zones and nodes of the domains with too many pods.
+func (r *Report) AddTopologyViolations(template *corev1.PodTemplateSpec) {
+	nodeSelector := labels.SelectorFromSet(template.Spec.NodeSelector)
+	for _, constraint := range template.Spec.TopologySpreadConstraints {
+		selector, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector)
+		if err != nil {
+			continue
+		}
+		counts := map[string]int{}
+		for _, node := range r.nodes {
+			domain, ok := node.Labels[constraint.TopologyKey]
+			if ok && !node.Spec.Unschedulable && nodeSelector.Matches(labels.Set(node.Labels)) {
+				counts[domain] += 0
+			}
+		}
+		for _, pod := range r.Pods {
+			node := r.nodeNames[pod.Spec.NodeName]
+			if node == nil || !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			// only the pods on the nodes of the domains count
+			if domain, ok := node.Labels[constraint.TopologyKey]; ok && !node.Spec.Unschedulable && nodeSelector.Matches(labels.Set(node.Labels)) {
+				counts[domain]++
+			}
+		}
+		if len(counts) == 0 {
+			continue
+		}
+
+		min := math.MaxInt32
+		for _, count := range counts {
+			if count < min {
+				min = count
+			}
+		}
+		var domains []string
+		max := min
+		for domain, count := range counts {
+			if count > min+int(constraint.MaxSkew) {
+				domains = append(domains, domain)
+			}
+			if count > max {
+				max = count
+			}
+		}
+		if len(domains) == 0 {
+			continue
+		}
+		sort.Strings(domains)
+		message := fmt.Sprintf("skew %d on %s exceeds maxSkew %d (%s)", max-min, constraint.TopologyKey, constraint.MaxSkew, constraint.WhenUnsatisfiable)
+		r.Violations = append(r.Violations, Violation{Key: constraint.TopologyKey, Domains: domains, Message: message})
+
+		mark := sets.NewString(domains...)
+		if constraint.TopologyKey == r.zoneLabel {
+			for _, g := range r.Zones {
+				if mark.Has(g.Name) {
+					g.Violations = append(g.Violations, message)
+				}
+			}
+			continue
+		}
+		for _, g := range r.Nodes {
+			if node := r.nodeNames[g.Name]; node != nil && mark.Has(node.Labels[constraint.TopologyKey]) {
+				g.Violations = append(g.Violations, message)
+			}
+		}
+	}
+}
+
+// AddWorkloadSpreadViolations counts the pods of the subsets of spreads, and adds a violation for
+// each subset with more pods than its maxReplicas, scaled to replicas if a percentage.
+func (r *Report) AddWorkloadSpreadViolations(spreads []kruiseappsv1alpha1.WorkloadSpread, replicas int) {
+	for _, ws := range spreads {
+		counts := map[string]int{}
+		for _, pod := range r.Pods {
+			var matched struct {
+				Name   string `json:"name"`
+				Subset string `json:"subset"`
+			}
+			value := pod.Annotations[MatchedWorkloadSpreadAnnotation]
+			if len(value) == 0 || json.Unmarshal([]byte(value), &matched) != nil || matched.Name != ws.Name {
+				continue
+			}
+			counts[matched.Subset]++
+		}
+		for _, subset := range ws.Spec.Subsets {
+			count := SubsetCount{WorkloadSpread: ws.Name, Subset: subset.Name, Pods: counts[subset.Name]}
+			if subset.MaxReplicas != nil {
+				max, err := intstr.GetScaledValueFromIntOrPercent(subset.MaxReplicas, replicas, true)
+				if err == nil {
+					count.MaxReplicas = &max
+				}
+			}
+			r.Subsets = append(r.Subsets, count)
+			if count.MaxReplicas != nil && count.Pods > *count.MaxReplicas {
+				r.Violations = append(r.Violations, Violation{
+					Domains: []string{subset.Name},
+					Message: fmt.Sprintf("subset %s of workloadspread %s has %d pods, more than its maxReplicas %d", subset.Name, ws.Name, count.Pods, *count.MaxReplicas),
+				})
+			}
+		}
+	}
+}
+
+func (o *SpreadOptions) printReport(name string, r *Report) error {
+	fmt.Fprintf(o.Out, "%s: %d pods in %d zones on %d nodes\n", name, len(r.Pods), domains(r.Zones), domains(r.Nodes))
+	if len(r.Pods) == 0 {
+		return nil
+	}
+
+	revisionHeader := ""
+	for _, revision := range r.Revisions {
+		revisionHeader += "\t" + revision
+	}
+	for _, by := range o.By {
+		fmt.Fprintln(o.Out)
+		w := internalcmdutil.NewTableWriter(o.Out)
+		groups := r.Zones
+		if by == "node" {
+			groups = r.Nodes
+			fmt.Fprintf(w, "NODE\tZONE\tPODS\tPERCENT%s\tVIOLATION\n", revisionHeader)
+		} else {
+			fmt.Fprintf(w, "ZONE\tPODS\tPERCENT%s\tVIOLATION\n", revisionHeader)
+		}
+		for _, g := range groups {
+			row := []string{g.Name}
+			if by == "node" {
+				row = append(row, g.Zone)
+			}
+			row = append(row, fmt.Sprint(g.Pods), percent(g.Pods, len(r.Pods)))
+			for _, revision := range r.Revisions {
+				row = append(row, fmt.Sprint(g.ByRevision[revision]))
+			}
+			violation := "-"
+			if len(g.Violations) > 0 {
+				violation = "! " + strings.Join(g.Violations, "; ")
+			}
+			row = append(row, violation)
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.Subsets) > 0 {
+		fmt.Fprintln(o.Out)
+		w := internalcmdutil.NewTableWriter(o.Out)
+		fmt.Fprintln(w, "WORKLOADSPREAD\tSUBSET\tPODS\tMAX")
+		for _, s := range r.Subsets {
+			max := "-"
+			if s.MaxReplicas != nil {
+				max = fmt.Sprint(*s.MaxReplicas)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", s.WorkloadSpread, s.Subset, s.Pods, max)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(o.Out)
+	if len(r.Violations) == 0 {
+		fmt.Fprintln(o.Out, "No spread violations found")
+		return nil
+	}
+	fmt.Fprintf(o.Out, "%d spread violations found:\n", len(r.Violations))
+	for _, v := range r.Violations {
+		fmt.Fprintf(o.Out, "  %s: %s\n", strings.Join(v.Domains, ", "), v.Message)
+	}
+	return nil
+}
+
+// domains returns the number of groups of a zone or a node, leaving out the pods without one.
+func domains(groups []*Group) int {
+	n := 0
+	for _, g := range groups {
+		if g.Name != noDomain {
+			n++
+		}
+	}
+	return n
+}
+
+// percent returns n of total as a rounded percentage.
+func percent(n, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%d%%", int(math.Round(100*float64(n)/float64(total))))
+}
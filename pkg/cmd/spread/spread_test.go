@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spread
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func newNode(name, zone string) corev1.Node {
+	return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+		corev1.LabelHostname:     name,
+		corev1.LabelTopologyZone: zone,
+	}}}
+}
+
+func newPod(name, node, revision, subset string) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": "demo", appsv1.ControllerRevisionHashLabelKey: revision}},
+		Spec:       corev1.PodSpec{NodeName: node},
+	}
+	if len(subset) > 0 {
+		pod.Annotations = map[string]string{MatchedWorkloadSpreadAnnotation: fmt.Sprintf(`{"name":"demo-spread","subset":"%s"}`, subset)}
+	}
+	return pod
+}
+
+// nodes a-1 and a-2 in zone-a, b-1 in zone-b and c-1 in zone-c
+var nodes = []corev1.Node{newNode("a-1", "zone-a"), newNode("a-2", "zone-a"), newNode("b-1", "zone-b"), newNode("c-1", "zone-c")}
+
+// three pods in zone-a, one in zone-b, none in zone-c and one pending
+var pods = []corev1.Pod{
+	newPod("demo-1", "a-1", "demo-v2", "zone-a"),
+	newPod("demo-2", "a-1", "demo-v1", "zone-a"),
+	newPod("demo-3", "a-2", "demo-v2", "zone-a"),
+	newPod("demo-4", "b-1", "demo-v2", "zone-b"),
+	newPod("demo-5", "", "demo-v2", ""),
+}
+
+func TestNewReport(t *testing.T) {
+	r := NewReport(pods, nodes, corev1.LabelTopologyZone)
+	if !reflect.DeepEqual(r.Revisions, []string{"demo-v1", "demo-v2"}) {
+		t.Errorf("unexpected revisions %v", r.Revisions)
+	}
+	var zones []string
+	for _, g := range r.Zones {
+		zones = append(zones, fmt.Sprintf("%s:%d", g.Name, g.Pods))
+	}
+	if expected := "zone-a:3,<none>:1,zone-b:1"; strings.Join(zones, ",") != expected {
+		t.Errorf("expected zones %s, got %s", expected, strings.Join(zones, ","))
+	}
+	if g := r.Nodes[0]; g.Name != "a-1" || g.Zone != "zone-a" || g.ByRevision["demo-v1"] != 1 || g.ByRevision["demo-v2"] != 1 {
+		t.Errorf("unexpected first node %+v", g)
+	}
+}
+
+func TestAddTopologyViolations(t *testing.T) {
+	template := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+		{MaxSkew: 1, TopologyKey: corev1.LabelTopologyZone, WhenUnsatisfiable: corev1.DoNotSchedule, LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}}},
+		{MaxSkew: 1, TopologyKey: corev1.LabelHostname, WhenUnsatisfiable: corev1.ScheduleAnyway, LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}}},
+		// matches no pod
+		{MaxSkew: 1, TopologyKey: corev1.LabelTopologyZone, WhenUnsatisfiable: corev1.DoNotSchedule, LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}}},
+	}}}
+	r := NewReport(pods, nodes, corev1.LabelTopologyZone)
+	r.AddTopologyViolations(template)
+
+	expected := []Violation{
+		{Key: corev1.LabelTopologyZone, Domains: []string{"zone-a"}, Message: "skew 3 on topology.kubernetes.io/zone exceeds maxSkew 1 (DoNotSchedule)"},
+		{Key: corev1.LabelHostname, Domains: []string{"a-1"}, Message: "skew 2 on kubernetes.io/hostname exceeds maxSkew 1 (ScheduleAnyway)"},
+	}
+	if !reflect.DeepEqual(r.Violations, expected) {
+		t.Errorf("expected violations %+v, got %+v", expected, r.Violations)
+	}
+	if len(r.Zones[0].Violations) != 1 || len(r.Nodes[0].Violations) != 1 || len(r.Nodes[1].Violations) != 0 {
+		t.Errorf("expected zone-a and node a-1 to be marked, got %+v and %+v", r.Zones, r.Nodes)
+	}
+
+	// with the pods restricted to zone-a, only zone-a and its nodes are domains
+	template.Spec.NodeSelector = map[string]string{corev1.LabelTopologyZone: "zone-a"}
+	r = NewReport(pods, nodes, corev1.LabelTopologyZone)
+	r.AddTopologyViolations(template)
+	if len(r.Violations) != 0 {
+		t.Errorf("expected no violations, got %+v", r.Violations)
+	}
+}
+
+func TestAddWorkloadSpreadViolations(t *testing.T) {
+	maxA, maxB := intstr.FromString("50%"), intstr.FromInt(2)
+	ws := kruiseappsv1alpha1.WorkloadSpread{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-spread"},
+		Spec: kruiseappsv1alpha1.WorkloadSpreadSpec{Subsets: []kruiseappsv1alpha1.WorkloadSpreadSubset{
+			{Name: "zone-a", MaxReplicas: &maxA},
+			{Name: "zone-b", MaxReplicas: &maxB},
+			{Name: "elastic"},
+		}},
+	}
+	r := NewReport(pods, nodes, corev1.LabelTopologyZone)
+	r.AddWorkloadSpreadViolations([]kruiseappsv1alpha1.WorkloadSpread{ws}, 4)
+
+	if len(r.Subsets) != 3 || r.Subsets[0].Pods != 3 || *r.Subsets[0].MaxReplicas != 2 || r.Subsets[2].MaxReplicas != nil {
+		t.Errorf("unexpected subsets %+v", r.Subsets)
+	}
+	expected := []Violation{{Domains: []string{"zone-a"}, Message: "subset zone-a of workloadspread demo-spread has 3 pods, more than its maxReplicas 2"}}
+	if !reflect.DeepEqual(r.Violations, expected) {
+		t.Errorf("expected violations %+v, got %+v", expected, r.Violations)
+	}
+}
+
+func TestPrintReport(t *testing.T) {
+	template := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+		{MaxSkew: 1, TopologyKey: corev1.LabelTopologyZone, WhenUnsatisfiable: corev1.DoNotSchedule, LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}}},
+	}}}
+	r := NewReport(pods, nodes, corev1.LabelTopologyZone)
+	r.AddTopologyViolations(template)
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewSpreadOptions(streams)
+	o.By = []string{"zone"}
+	if err := o.printReport("clonesets.apps.kruise.io/demo", r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `clonesets.apps.kruise.io/demo: 5 pods in 2 zones on 3 nodes
+
+ZONE     PODS   PERCENT   demo-v1   demo-v2   VIOLATION
+zone-a   3      60%       1         2         ! skew 3 on topology.kubernetes.io/zone exceeds maxSkew 1 (DoNotSchedule)
+<none>   1      20%       0         1         -
+zone-b   1      20%       0         1         -
+
+1 spread violations found:
+  zone-a: skew 3 on topology.kubernetes.io/zone exceeds maxSkew 1 (DoNotSchedule)
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	o := NewSpreadOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.Resources = []string{"cloneset/demo"}
+	if err := o.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	o.By = []string{"zone", "revision"}
+	if err := o.Validate(); err == nil || err.Error() != `invalid --by "revision", must be zone or node` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tree
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// The labels of the edges of the graph
+const (
+	EdgeRollsOut = "rolls out"
+	EdgeOwns     = "owns"
+	EdgeSelects  = "selects"
+	EdgeRoutes   = "routes"
+)
+
+// Node is a resource of the graph of a workload.
+type Node struct {
+	Kind   string
+	Name   string
+	Status string
+}
+
+// ID returns the unique name of the node in the graph, e.g. CloneSet/demo.
+func (n Node) ID() string {
+	return n.Kind + "/" + n.Name
+}
+
+func (n Node) label() string {
+	if len(n.Status) == 0 {
+		return n.ID()
+	}
+	return n.ID() + " (" + n.Status + ")"
+}
+
+// Edge is a relation between two resources, such as a workload owning a pod.
+type Edge struct {
+	From  string
+	To    string
+	Label string
+}
+
+// Graph is the graph of the ownership and the traffic of workloads: their Rollouts, their pods and
+// the Services and Ingresses routing to them. Its nodes and edges are kept in insertion order.
+type Graph struct {
+	nodes map[string]Node
+	order []string
+	edges []Edge
+}
+
+// NewGraph returns an empty graph.
+func NewGraph() *Graph {
+	return &Graph{nodes: map[string]Node{}}
+}
+
+// AddNode adds n to the graph, updating its status if it is already in.
+func (g *Graph) AddNode(n Node) {
+	if _, ok := g.nodes[n.ID()]; !ok {
+		g.order = append(g.order, n.ID())
+	}
+	g.nodes[n.ID()] = n
+}
+
+// AddEdge adds the nodes from and to, and an edge between them, unless it is already in.
+func (g *Graph) AddEdge(from, to Node, label string) {
+	if _, ok := g.nodes[from.ID()]; !ok {
+		g.AddNode(from)
+	}
+	if _, ok := g.nodes[to.ID()]; !ok {
+		g.AddNode(to)
+	}
+	edge := Edge{From: from.ID(), To: to.ID(), Label: label}
+	for _, e := range g.edges {
+		if e == edge {
+			return
+		}
+	}
+	g.edges = append(g.edges, edge)
+}
+
+// Nodes returns the nodes of the graph, sorted by kind and name.
+func (g *Graph) Nodes() []Node {
+	var nodes []Node
+	for _, id := range g.order {
+		nodes = append(nodes, g.nodes[id])
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].ID() < nodes[j].ID() })
+	return nodes
+}
+
+// Edges returns the edges of the graph, sorted by their nodes.
+func (g *Graph) Edges() []Edge {
+	edges := append([]Edge(nil), g.edges...)
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// WriteText writes the graph as trees, from the resources nothing points to. The resources
+// reached again from another one are not expanded twice.
+func (g *Graph) WriteText(w io.Writer) error {
+	children := map[string][]Edge{}
+	incoming := map[string]bool{}
+	for _, e := range g.Edges() {
+		children[e.From] = append(children[e.From], e)
+		incoming[e.To] = true
+	}
+
+	expanded := map[string]bool{}
+	var write func(id, prefix string) error
+	write = func(id, prefix string) error {
+		expanded[id] = true
+		for i, e := range children[id] {
+			branch, indent := "├─ ", "│  "
+			if i == len(children[id])-1 {
+				branch, indent = "└─ ", "   "
+			}
+			suffix := ""
+			if expanded[e.To] && len(children[e.To]) > 0 {
+				suffix = ", see above"
+			}
+			if _, err := fmt.Fprintf(w, "%s%s%s [%s%s]\n", prefix, branch, g.nodes[e.To].label(), e.Label, suffix); err != nil {
+				return err
+			}
+			if !expanded[e.To] {
+				if err := write(e.To, prefix+indent); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, n := range g.Nodes() {
+		if incoming[n.ID()] {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, n.label()); err != nil {
+			return err
+		}
+		if err := write(n.ID(), ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteDOT writes the graph in the DOT language of Graphviz.
+func (g *Graph) WriteDOT(w io.Writer, name string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(name))
+	b.WriteString("  rankdir=LR;\n  node [shape=box];\n")
+	for _, n := range g.Nodes() {
+		fmt.Fprintf(&b, "  %s [label=%s];\n", dotQuote(n.ID()), dotQuote(n.label()))
+	}
+	for _, e := range g.Edges() {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Label))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart, to be embedded in Markdown documents.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	ids := map[string]string{}
+	for i, n := range g.Nodes() {
+		ids[n.ID()] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[n.ID()], strings.ReplaceAll(n.label(), `"`, "#quot;"))
+	}
+	for _, e := range g.Edges() {
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[e.From], e.Label, ids[e.To])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
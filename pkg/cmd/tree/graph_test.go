@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tree
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newTestGraph() *Graph {
+	g := NewGraph()
+	cloneSet := Node{Kind: "CloneSet", Name: "demo", Status: "1/1 ready"}
+	g.AddEdge(Node{Kind: "Rollout", Name: "demo"}, cloneSet, EdgeRollsOut)
+	g.AddEdge(cloneSet, Node{Kind: "Pod", Name: "demo-a", Status: "Running"}, EdgeOwns)
+	g.AddEdge(Node{Kind: "Service", Name: "demo"}, cloneSet, EdgeSelects)
+	g.AddEdge(Node{Kind: "Service", Name: "demo"}, cloneSet, EdgeSelects)
+	return g
+}
+
+func TestWriteDOT(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := newTestGraph().WriteDOT(buf, "cloneset.apps.kruise.io/demo"); err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph "cloneset.apps.kruise.io/demo" {
+  rankdir=LR;
+  node [shape=box];
+  "CloneSet/demo" [label="CloneSet/demo (1/1 ready)"];
+  "Pod/demo-a" [label="Pod/demo-a (Running)"];
+  "Rollout/demo" [label="Rollout/demo"];
+  "Service/demo" [label="Service/demo"];
+  "CloneSet/demo" -> "Pod/demo-a" [label="owns"];
+  "Rollout/demo" -> "CloneSet/demo" [label="rolls out"];
+  "Service/demo" -> "CloneSet/demo" [label="selects"];
+}
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := newTestGraph().WriteMermaid(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `graph LR
+  n0["CloneSet/demo (1/1 ready)"]
+  n1["Pod/demo-a (Running)"]
+  n2["Rollout/demo"]
+  n3["Service/demo"]
+  n0 -->|owns| n1
+  n2 -->|rolls out| n0
+  n3 -->|selects| n0
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := newTestGraph().WriteText(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Rollout/demo
+└─ CloneSet/demo (1/1 ready) [rolls out]
+   └─ Pod/demo-a (Running) [owns]
+Service/demo
+└─ CloneSet/demo (1/1 ready) [selects, see above]
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
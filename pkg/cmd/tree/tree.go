@@ -0,0 +1,426 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tree
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	treeLong = templates.LongDesc(i18n.T(`
+		Show the graph of the ownership and the traffic of workloads: the Kruise Rollouts
+		rolling them out, the ReplicaSets and pods they own, the Services selecting their pods,
+		and the Ingresses routing to those Services.
+
+		The graph is printed as trees by default, or with --format as a Graphviz DOT graph or
+		a Mermaid flowchart, to embed a live diagram in architecture docs and incident reviews.`))
+
+	treeExample = templates.Examples(i18n.T(`
+		# Show the tree of cloneset demo
+		kubectl-kruise tree cloneset/demo
+
+		# Render the graph of the workload of rollout demo with Graphviz
+		kubectl-kruise tree rollout/demo --format dot | dot -Tsvg > demo.svg
+
+		# Print the graph of cloneset demo as a Mermaid flowchart
+		kubectl-kruise tree cloneset/demo --format mermaid`))
+)
+
+var (
+	rolloutKind      = kruiserolloutsv1apha1.SchemeGroupVersion.WithKind("Rollout").GroupKind()
+	rolloutsResource = kruiserolloutsv1apha1.SchemeGroupVersion.WithResource("rollouts")
+)
+
+// TreeOptions holds the command-line options for 'tree' command
+type TreeOptions struct {
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+	Format           string
+
+	Builder       func() *resource.Builder
+	Client        kubernetes.Interface
+	DynamicClient dynamic.Interface
+	Mapper        meta.RESTMapper
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+// NewTreeOptions returns an initialized TreeOptions instance
+func NewTreeOptions(streams genericclioptions.IOStreams) *TreeOptions {
+	return &TreeOptions{
+		Format:    "text",
+		IOStreams: streams,
+	}
+}
+
+// NewCmdTree returns a Command instance for 'tree' command
+func NewCmdTree(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewTreeOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "tree (TYPE NAME | TYPE/NAME) [--format text|dot|mermaid]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show the graph of the Rollouts, pods, Services and Ingresses of workloads"),
+		Long:                  treeLong,
+		Example:               treeExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "identifying the resource to get the tree of."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringVar(&o.Format, "format", o.Format, "The format of the graph, one of text, dot or mermaid.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *TreeOptions) Complete(f cmdutil.Factory, args []string) error {
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Resources = args
+	o.Builder = f.NewBuilder
+
+	o.Mapper, err = f.ToRESTMapper()
+	if err != nil {
+		return err
+	}
+	o.Client, err = f.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	o.DynamicClient, err = f.DynamicClient()
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *TreeOptions) Validate() error {
+	switch o.Format {
+	case "text", "dot", "mermaid":
+	default:
+		return fmt.Errorf("invalid --format %q, must be text, dot or mermaid", o.Format)
+	}
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+	return nil
+}
+
+// Run performs the execution of 'tree' command
+func (o *TreeOptions) Run() error {
+	infos, err := o.Builder().
+		Unstructured().
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		Latest().
+		Flatten().
+		Do().Infos()
+	if err != nil {
+		return err
+	}
+
+	g := NewGraph()
+	var names []string
+	for _, info := range infos {
+		obj, ok := info.Object.(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("unexpected object %T of %s", info.Object, info.ObjectName())
+		}
+		if err := o.AddToGraph(g, obj); err != nil {
+			return err
+		}
+		names = append(names, info.ObjectName())
+	}
+
+	switch o.Format {
+	case "dot":
+		return g.WriteDOT(o.Out, strings.Join(names, ","))
+	case "mermaid":
+		return g.WriteMermaid(o.Out)
+	}
+	return g.WriteText(o.Out)
+}
+
+// AddToGraph adds obj, a workload or a Kruise Rollout, to g, with the resources related to it.
+func (o *TreeOptions) AddToGraph(g *Graph, obj *unstructured.Unstructured) error {
+	if obj.GroupVersionKind().GroupKind() == rolloutKind {
+		workload, err := o.rolloutWorkload(obj)
+		if err != nil {
+			return err
+		}
+		obj = workload
+	}
+	if _, found, _ := unstructured.NestedMap(obj.Object, "spec", "template"); !found {
+		return fmt.Errorf("%s/%s is neither a workload nor a Kruise Rollout", strings.ToLower(obj.GetKind()), obj.GetName())
+	}
+
+	workload := Node{Kind: obj.GetKind(), Name: obj.GetName(), Status: workloadStatus(obj)}
+	g.AddNode(workload)
+	if err := o.addPods(g, workload, obj); err != nil {
+		return err
+	}
+	services, err := o.addServices(g, workload, obj)
+	if err != nil {
+		return err
+	}
+	if err := o.addIngresses(g, obj.GetNamespace(), services); err != nil {
+		return err
+	}
+	return o.addRollouts(g, workload, obj)
+}
+
+// rolloutWorkload returns the workload of the workloadRef of a Kruise Rollout.
+func (o *TreeOptions) rolloutWorkload(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	rollout := &kruiserolloutsv1apha1.Rollout{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, rollout); err != nil {
+		return nil, err
+	}
+	ref := rollout.Spec.ObjectRef.WorkloadRef
+	if ref == nil {
+		return nil, fmt.Errorf("rollout %s has no workloadRef", rollout.Name)
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := o.Mapper.RESTMapping(gv.WithKind(ref.Kind).GroupKind(), gv.Version)
+	if err != nil {
+		return nil, err
+	}
+	return o.DynamicClient.Resource(mapping.Resource).Namespace(rollout.Namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+}
+
+// addPods adds the pods of the workload, and the ReplicaSets owning them if it owns ReplicaSets,
+// as Deployments do.
+func (o *TreeOptions) addPods(g *Graph, workload Node, obj *unstructured.Unstructured) error {
+	selector, err := workloadSelector(obj)
+	if err != nil {
+		return err
+	}
+	options := metav1.ListOptions{LabelSelector: selector.String()}
+	replicaSets, err := o.Client.AppsV1().ReplicaSets(obj.GetNamespace()).List(context.TODO(), options)
+	if err != nil {
+		return err
+	}
+	owners := map[types.UID]Node{obj.GetUID(): workload}
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if !ownedBy(rs.OwnerReferences, obj.GetUID()) || rs.Status.Replicas == 0 {
+			continue
+		}
+		node := Node{Kind: "ReplicaSet", Name: rs.Name}
+		g.AddEdge(workload, node, EdgeOwns)
+		owners[rs.UID] = node
+	}
+
+	pods, err := o.Client.CoreV1().Pods(obj.GetNamespace()).List(context.TODO(), options)
+	if err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		for _, ref := range pod.OwnerReferences {
+			if owner, ok := owners[ref.UID]; ok {
+				g.AddEdge(owner, Node{Kind: "Pod", Name: pod.Name, Status: podStatus(pod)}, EdgeOwns)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// addServices adds the Services selecting the pods of the workload, and returns their names.
+func (o *TreeOptions) addServices(g *Graph, workload Node, obj *unstructured.Unstructured) (map[string]Node, error) {
+	podLabels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+	services, err := o.Client.CoreV1().Services(obj.GetNamespace()).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	selecting := map[string]Node{}
+	for _, svc := range services.Items {
+		if len(svc.Spec.Selector) == 0 || !labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(podLabels)) {
+			continue
+		}
+		node := Node{Kind: "Service", Name: svc.Name}
+		g.AddEdge(node, workload, EdgeSelects)
+		selecting[svc.Name] = node
+	}
+	return selecting, nil
+}
+
+// addIngresses adds the Ingresses routing to services. Clusters without networking.k8s.io/v1
+// Ingresses have none.
+func (o *TreeOptions) addIngresses(g *Graph, namespace string, services map[string]Node) error {
+	if len(services) == 0 {
+		return nil
+	}
+	ingresses, err := o.Client.NetworkingV1().Ingresses(namespace).List(context.TODO(), metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for i := range ingresses.Items {
+		ingress := &ingresses.Items[i]
+		for _, name := range ingressServices(ingress) {
+			if svc, ok := services[name]; ok {
+				g.AddEdge(Node{Kind: "Ingress", Name: ingress.Name}, svc, EdgeRoutes)
+			}
+		}
+	}
+	return nil
+}
+
+// addRollouts adds the Kruise Rollouts of the workload, and the Services and Ingresses of their
+// traffic routing. Clusters without Kruise Rollouts have none.
+func (o *TreeOptions) addRollouts(g *Graph, workload Node, obj *unstructured.Unstructured) error {
+	list, err := o.DynamicClient.Resource(rolloutsResource).Namespace(obj.GetNamespace()).List(context.TODO(), metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for i := range list.Items {
+		rollout := &kruiserolloutsv1apha1.Rollout{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(list.Items[i].Object, rollout); err != nil {
+			return err
+		}
+		ref := rollout.Spec.ObjectRef.WorkloadRef
+		if ref == nil || ref.Kind != obj.GetKind() || ref.Name != obj.GetName() || apiGroup(ref.APIVersion) != obj.GroupVersionKind().Group {
+			continue
+		}
+		node := Node{Kind: "Rollout", Name: rollout.Name}
+		if status := rollout.Status.Phase; len(status) > 0 {
+			node.Status = string(status)
+		}
+		g.AddEdge(node, workload, EdgeRollsOut)
+
+		canary := rollout.Spec.Strategy.Canary
+		if canary == nil || canary.TrafficRouting == nil {
+			continue
+		}
+		if name := canary.TrafficRouting.Service; len(name) > 0 {
+			g.AddEdge(node, Node{Kind: "Service", Name: name}, EdgeRoutes)
+		}
+		if nginx := canary.TrafficRouting.Nginx; nginx != nil && len(nginx.Ingress) > 0 {
+			g.AddEdge(node, Node{Kind: "Ingress", Name: nginx.Ingress}, EdgeRoutes)
+		}
+	}
+	return nil
+}
+
+// workloadSelector returns the label selector of the pods of a workload.
+func workloadSelector(obj *unstructured.Unstructured) (labels.Selector, error) {
+	content, found, err := unstructured.NestedMap(obj.Object, "spec", "selector")
+	if err != nil || !found {
+		return nil, fmt.Errorf("%s/%s has no selector", strings.ToLower(obj.GetKind()), obj.GetName())
+	}
+	selector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, selector); err != nil {
+		return nil, err
+	}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// workloadStatus returns the ready replicas of a workload, e.g. 3/3 ready.
+func workloadStatus(obj *unstructured.Unstructured) string {
+	state := internalcmdutil.ResourceStateFor(obj)
+	if state.Replicas == nil {
+		return ""
+	}
+	var ready int64
+	if state.ReadyReplicas != nil {
+		ready = *state.ReadyReplicas
+	}
+	return fmt.Sprintf("%d/%d ready", ready, *state.Replicas)
+}
+
+// podStatus returns the phase of a pod, or Terminating.
+func podStatus(pod *corev1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "Terminating"
+	}
+	return string(pod.Status.Phase)
+}
+
+// ingressServices returns the names of the Services the backends of ingress route to.
+func ingressServices(ingress *networkingv1.Ingress) []string {
+	var names []string
+	if backend := ingress.Spec.DefaultBackend; backend != nil && backend.Service != nil {
+		names = append(names, backend.Service.Name)
+	}
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.Service != nil {
+				names = append(names, path.Backend.Service.Name)
+			}
+		}
+	}
+	return names
+}
+
+func ownedBy(refs []metav1.OwnerReference, uid types.UID) bool {
+	for _, ref := range refs {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+func apiGroup(apiVersion string) string {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return ""
+	}
+	return gv.Group
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newPod(name string, labels map[string]string, owner string, phase corev1.PodPhase) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: labels},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+	if len(owner) > 0 {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: "CloneSet", Name: "demo", UID: "cs-uid"}}
+	}
+	return pod
+}
+
+func TestAddToGraph(t *testing.T) {
+	demo := map[string]string{"app": "demo"}
+	cloneSet := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.kruise.io/v1alpha1",
+		"kind":       "CloneSet",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "demo", "uid": "cs-uid", "generation": int64(1)},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "demo"}},
+			"template": map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "demo", "version": "v2"}}},
+		},
+		"status": map[string]interface{}{"observedGeneration": int64(1), "replicas": int64(3), "readyReplicas": int64(2)},
+	}}
+	rollout := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "rollouts.kruise.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "demo"},
+		"spec": map[string]interface{}{
+			"objectRef": map[string]interface{}{"workloadRef": map[string]interface{}{"apiVersion": "apps.kruise.io/v1alpha1", "kind": "CloneSet", "name": "demo"}},
+			"strategy": map[string]interface{}{"canary": map[string]interface{}{
+				"trafficRouting": map[string]interface{}{"service": "demo", "type": "nginx", "nginx": map[string]interface{}{"ingress": "demo"}},
+			}},
+		},
+		"status": map[string]interface{}{"phase": "Healthy"},
+	}}
+	other := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "rollouts.kruise.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "other"},
+		"spec": map[string]interface{}{
+			"objectRef": map[string]interface{}{"workloadRef": map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "demo"}},
+		},
+	}}
+
+	pathType := networkingv1.PathTypePrefix
+	o := NewTreeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.Client = fake.NewSimpleClientset(
+		newPod("demo-a", demo, "cs-uid", corev1.PodRunning),
+		newPod("demo-b", demo, "cs-uid", corev1.PodPending),
+		newPod("orphan", demo, "", corev1.PodRunning),
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"}, Spec: corev1.ServiceSpec{Selector: demo}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "other"}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "other"}}},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+			Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{{Path: "/", PathType: &pathType, Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "demo"}}}},
+			}}}}},
+		},
+	)
+	o.DynamicClient = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{rolloutsResource: "RolloutList"}, rollout, other)
+
+	g := NewGraph()
+	if err := o.AddToGraph(g, cloneSet); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := g.WriteText(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		"Rollout/demo (Healthy)",
+		"├─ CloneSet/demo (2/3 ready) [rolls out]",
+		"│  ├─ Pod/demo-a (Running) [owns]",
+		"│  └─ Pod/demo-b (Pending) [owns]",
+		"├─ Ingress/demo [routes]",
+		"│  └─ Service/demo [routes]",
+		"│     └─ CloneSet/demo (2/3 ready) [selects, see above]",
+		"└─ Service/demo [routes, see above]",
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	o := NewTreeOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.Resources = []string{"cloneset/demo"}
+	if err := o.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	o.Format = "svg"
+	if err := o.Validate(); err == nil || !strings.Contains(err.Error(), "invalid --format") {
+		t.Errorf("expected an invalid --format error, got %v", err)
+	}
+}
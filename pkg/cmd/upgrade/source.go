@@ -0,0 +1,96 @@
+//go:build !offline
+// +build !offline
+
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openkruise/kruise-tools/pkg/offline"
+)
+
+const (
+	githubLatestURL   = "https://api.github.com/repos/openkruise/kruise-tools/releases/latest"
+	githubDownloadURL = "https://github.com/openkruise/kruise-tools/releases/download"
+)
+
+// httpSource gets the releases from GitHub, or from a mirror if set.
+type httpSource struct {
+	client *http.Client
+	mirror string
+}
+
+func newReleaseSource(mirror string) (releaseSource, error) {
+	endpoint := "GitHub"
+	if len(mirror) > 0 {
+		endpoint = mirror
+	}
+	if err := offline.Check("upgrade", endpoint); err != nil {
+		return nil, err
+	}
+	return &httpSource{client: &http.Client{Timeout: 5 * time.Minute}, mirror: mirror}, nil
+}
+
+// Latest implements releaseSource
+func (s *httpSource) Latest() (string, error) {
+	if len(s.mirror) > 0 {
+		body, err := s.get(s.mirror + "/latest")
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(body)), nil
+	}
+
+	body, err := s.get(githubLatestURL)
+	if err != nil {
+		return "", err
+	}
+	release := struct {
+		TagName string `json:"tag_name"`
+	}{}
+	if err := json.Unmarshal(body, &release); err != nil {
+		return "", err
+	}
+	return release.TagName, nil
+}
+
+// Download implements releaseSource
+func (s *httpSource) Download(tag, asset string) ([]byte, error) {
+	if len(s.mirror) > 0 {
+		return s.get(fmt.Sprintf("%s/%s/%s", s.mirror, tag, asset))
+	}
+	return s.get(fmt.Sprintf("%s/%s/%s", githubDownloadURL, tag, asset))
+}
+
+func (s *httpSource) get(url string) ([]byte, error) {
+	resp, err := s.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
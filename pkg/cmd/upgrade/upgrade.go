@@ -0,0 +1,306 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgrade
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+	"github.com/openkruise/kruise-tools/pkg/readonly"
+	"github.com/spf13/cobra"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/component-base/version"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	binaryName = "kubectl-kruise"
+	// checksumsAsset is the release asset with the sha256 checksums of the other assets
+	checksumsAsset = "sha256sums.txt"
+)
+
+var (
+	upgradeLong = templates.LongDesc(`
+		Upgrade kubectl-kruise to the latest release, or to the release given by --version.
+
+		The releases are downloaded from GitHub, or from the mirror given by --mirror or by
+		upgrade.mirror in the config file ~/.kube/kubectl-kruise.yaml. A mirror serves the tag of
+		the latest release at MIRROR/latest, and the assets of each release at MIRROR/TAG/ASSET.
+		The downloaded archive is verified against the sha256sums.txt asset of the release before
+		the running binary is replaced. The checksums come from the same source as the archive:
+		they detect a corrupted download, not a compromised mirror, so the mirror must be trusted
+		and served over https.`)
+
+	upgradeExample = templates.Examples(`
+		# Upgrade kubectl-kruise to the latest release
+		kubectl-kruise upgrade
+
+		# Exit with a non-zero code if there is a newer release, e.g. in CI images
+		kubectl-kruise upgrade --check-only
+
+		# Install the release v1.0.0 from an internal mirror
+		kubectl-kruise upgrade --version v1.0.0 --mirror https://mirror.example.com/kruise-tools`)
+)
+
+// releaseSource gets the releases of kubectl-kruise
+type releaseSource interface {
+	// Latest returns the tag of the latest release
+	Latest() (string, error)
+	// Download returns the content of an asset of the release of tag
+	Download(tag, asset string) ([]byte, error)
+}
+
+// UpgradeOptions holds the command-line options for 'upgrade' command
+type UpgradeOptions struct {
+	CheckOnly bool
+	Mirror    string
+	Version   string
+
+	CurrentVersion string
+	Executable     string
+	Source         releaseSource
+
+	genericclioptions.IOStreams
+}
+
+// NewUpgradeOptions returns an initialized UpgradeOptions instance
+func NewUpgradeOptions(streams genericclioptions.IOStreams) *UpgradeOptions {
+	return &UpgradeOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdUpgrade returns a Command instance for 'upgrade' command
+func NewCmdUpgrade(streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewUpgradeOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "upgrade [--check-only] [--version=TAG] [--mirror=URL]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Upgrade kubectl-kruise to the latest release"),
+		Long:                  upgradeLong,
+		Example:               upgradeExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.CheckOnly, "check-only", o.CheckOnly, "If true, only check for a newer release, and exit with a non-zero code if there is one.")
+	cmd.Flags().StringVar(&o.Version, "version", o.Version, "The tag of the release to install, e.g. v1.0.0. Defaults to the latest release.")
+	cmd.Flags().StringVar(&o.Mirror, "mirror", o.Mirror, "The https URL of a trusted mirror of the GitHub releases. Defaults to upgrade.mirror in the config file, or GitHub.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *UpgradeOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args)
+	}
+	if len(o.Mirror) == 0 {
+		cfg, err := config.Load(config.Path())
+		if err != nil {
+			return err
+		}
+		if cfg.Upgrade != nil {
+			o.Mirror = cfg.Upgrade.Mirror
+		}
+	}
+	o.CurrentVersion = version.Get().GitVersion
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if o.Executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return err
+	}
+
+	o.Source, err = newReleaseSource(strings.TrimSuffix(o.Mirror, "/"))
+	return err
+}
+
+// Validate makes sure all the provided values for command-line options are valid
+func (o *UpgradeOptions) Validate() error {
+	if len(o.Version) > 0 {
+		if _, err := utilversion.ParseGeneric(o.Version); err != nil {
+			return fmt.Errorf("invalid --version %q: %v", o.Version, err)
+		}
+	}
+	if len(o.Mirror) > 0 {
+		// the checksums are downloaded from the mirror too, they do not protect a plain http download
+		if u, err := url.Parse(o.Mirror); err != nil || u.Scheme != "https" || len(u.Host) == 0 {
+			return fmt.Errorf("invalid mirror %q: the mirror must be a trusted https URL", o.Mirror)
+		}
+	}
+	return nil
+}
+
+// Run checks for a newer release, and installs it unless CheckOnly is set
+func (o *UpgradeOptions) Run() error {
+	target := o.Version
+	if len(target) == 0 {
+		latest, err := o.Source.Latest()
+		if err != nil {
+			return fmt.Errorf("cannot get the latest release: %v", err)
+		}
+		target = latest
+	}
+
+	if target == o.CurrentVersion || (len(o.Version) == 0 && !isNewer(target, o.CurrentVersion)) {
+		fmt.Fprintf(o.Out, "kubectl-kruise %s is up to date\n", o.CurrentVersion)
+		return nil
+	}
+	if o.CheckOnly {
+		return fmt.Errorf("kubectl-kruise %s is out of date, %s is available", o.CurrentVersion, target)
+	}
+	if err := readonly.Check("upgrade"); err != nil {
+		return err
+	}
+
+	asset := fmt.Sprintf("%s-%s-%s.tar.gz", binaryName, runtime.GOOS, runtime.GOARCH)
+	checksums, err := o.Source.Download(target, checksumsAsset)
+	if err != nil {
+		return fmt.Errorf("cannot download %s of %s: %v", checksumsAsset, target, err)
+	}
+	archive, err := o.Source.Download(target, asset)
+	if err != nil {
+		return fmt.Errorf("cannot download %s of %s: %v", asset, target, err)
+	}
+	if err := verifyChecksum(checksums, asset, archive); err != nil {
+		return err
+	}
+	binary, err := extractBinary(archive)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", asset, err)
+	}
+	if err := replaceExecutable(o.Executable, binary); err != nil {
+		return fmt.Errorf("cannot replace %s: %v", o.Executable, err)
+	}
+
+	fmt.Fprintf(o.Out, "kubectl-kruise upgraded from %s to %s\n", o.CurrentVersion, target)
+	return nil
+}
+
+// isNewer returns true if target is newer than current. A current version that is not a
+// release, such as a local build, is older than any release.
+func isNewer(target, current string) bool {
+	currentVersion, err := utilversion.ParseGeneric(current)
+	if err != nil {
+		return true
+	}
+	targetVersion, err := utilversion.ParseGeneric(target)
+	if err != nil {
+		return false
+	}
+	return currentVersion.LessThan(targetVersion)
+}
+
+// verifyChecksum verifies the sha256 checksum of the content of asset against checksums, in
+// the format of sha256sum. The checksums are not signed, they only detect corrupted downloads.
+func verifyChecksum(checksums []byte, asset string, content []byte) error {
+	sum := sha256.Sum256(content)
+	actual := hex.EncodeToString(sum[:])
+
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || path.Base(strings.TrimPrefix(fields[1], "*")) != asset {
+			continue
+		}
+		if !strings.EqualFold(fields[0], actual) {
+			return fmt.Errorf("checksum mismatch of %s: expected %s, got %s", asset, fields[0], actual)
+		}
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("no checksum of %s in %s", asset, checksumsAsset)
+}
+
+// extractBinary returns the kubectl-kruise binary in a release archive.
+func extractBinary(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no %s in the archive", binaryName)
+		} else if err != nil {
+			return nil, err
+		}
+		name := path.Base(header.Name)
+		if header.Typeflag == tar.TypeReg && (name == binaryName || name == binaryName+".exe") {
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
+
+// replaceExecutable replaces the executable at path with binary. The new binary is written
+// next to it first, so that a failure leaves the executable intact.
+func replaceExecutable(path string, binary []byte) error {
+	dir, name := filepath.Split(path)
+	tmp, err := ioutil.TempFile(dir, "."+name+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	// a running executable can not be replaced on Windows, but it can be renamed
+	if runtime.GOOS == "windows" {
+		old := path + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
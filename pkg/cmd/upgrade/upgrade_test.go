@@ -0,0 +1,203 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgrade
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+type fakeSource struct {
+	latest string
+	assets map[string][]byte
+}
+
+func (s *fakeSource) Latest() (string, error) {
+	return s.latest, nil
+}
+
+func (s *fakeSource) Download(tag, asset string) ([]byte, error) {
+	content, ok := s.assets[tag+"/"+asset]
+	if !ok {
+		return nil, fmt.Errorf("%s/%s not found", tag, asset)
+	}
+	return content, nil
+}
+
+func releaseArchive(t *testing.T, binary string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	dir := fmt.Sprintf("%s-%s/", runtime.GOOS, runtime.GOARCH)
+	for name, content := range map[string]string{dir + "LICENSE": "license", dir + binaryName: binary} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newFakeSource(t *testing.T, tag string, corrupt bool) *fakeSource {
+	asset := fmt.Sprintf("%s-%s-%s.tar.gz", binaryName, runtime.GOOS, runtime.GOARCH)
+	archive := releaseArchive(t, "new binary")
+	sum := sha256.Sum256(archive)
+	if corrupt {
+		archive = append(archive, 0)
+	}
+	checksums := fmt.Sprintf("%s  kubectl-kruise/%s\n", hex.EncodeToString(sum[:]), asset)
+	return &fakeSource{
+		latest: tag,
+		assets: map[string][]byte{
+			tag + "/" + asset:          archive,
+			tag + "/" + checksumsAsset: []byte(checksums),
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name           string
+		current        string
+		version        string
+		checkOnly      bool
+		corrupt        bool
+		expectedErr    string
+		expectedBinary string
+	}{
+		{
+			name:           "upgrade to latest",
+			current:        "v0.9.0",
+			expectedBinary: "new binary",
+		},
+		{
+			name:           "upgrade local build",
+			current:        "v0.0.0-master+$Format:%h$",
+			expectedBinary: "new binary",
+		},
+		{
+			name:           "up to date",
+			current:        "v1.0.0",
+			expectedBinary: "old binary",
+		},
+		{
+			name:           "newer than latest",
+			current:        "v1.1.0",
+			expectedBinary: "old binary",
+		},
+		{
+			name:           "check only",
+			current:        "v0.9.0",
+			checkOnly:      true,
+			expectedErr:    "kubectl-kruise v0.9.0 is out of date, v1.0.0 is available",
+			expectedBinary: "old binary",
+		},
+		{
+			name:           "downgrade to version",
+			current:        "v1.1.0",
+			version:        "v1.0.0",
+			expectedBinary: "new binary",
+		},
+		{
+			name:           "checksum mismatch",
+			current:        "v0.9.0",
+			corrupt:        true,
+			expectedErr:    "checksum mismatch",
+			expectedBinary: "old binary",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "upgrade")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			executable := filepath.Join(dir, binaryName)
+			if err := ioutil.WriteFile(executable, []byte("old binary"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			streams, _, out, _ := genericclioptions.NewTestIOStreams()
+			o := NewUpgradeOptions(streams)
+			o.CurrentVersion = test.current
+			o.Version = test.version
+			o.CheckOnly = test.checkOnly
+			o.Executable = executable
+			o.Source = newFakeSource(t, "v1.0.0", test.corrupt)
+
+			err = o.Run()
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v, output: %s", err, out.String())
+			}
+
+			binary, err := ioutil.ReadFile(executable)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(binary) != test.expectedBinary {
+				t.Errorf("expected binary %q, got %q", test.expectedBinary, binary)
+			}
+		})
+	}
+}
+
+func TestValidateMirror(t *testing.T) {
+	tests := []struct {
+		mirror      string
+		expectedErr string
+	}{
+		{mirror: ""},
+		{mirror: "https://mirror.example.com/kruise-tools"},
+		{mirror: "http://mirror.example.com/kruise-tools", expectedErr: `invalid mirror "http://mirror.example.com/kruise-tools": the mirror must be a trusted https URL`},
+		{mirror: "mirror.example.com", expectedErr: `invalid mirror "mirror.example.com": the mirror must be a trusted https URL`},
+	}
+	for _, test := range tests {
+		o := &UpgradeOptions{Mirror: test.mirror}
+		err := o.Validate()
+		if len(test.expectedErr) == 0 && err != nil {
+			t.Errorf("%s: unexpected error %v", test.mirror, err)
+		}
+		if len(test.expectedErr) > 0 && (err == nil || err.Error() != test.expectedErr) {
+			t.Errorf("%s: expected error %q, got %v", test.mirror, test.expectedErr, err)
+		}
+	}
+}
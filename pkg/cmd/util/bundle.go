@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+const (
+	// BundleLabel is set on the objects created by a command with --bundle-out to the name of the
+	// bundle, so that they can be listed and deleted as a unit.
+	BundleLabel = "kubectl.kruise.io/bundle"
+	// BundleOwnerAnnotation is set on the objects of a bundle to what they were created for, such
+	// as the workload of 'pull-image --from-workload'.
+	BundleOwnerAnnotation = "kubectl.kruise.io/bundle-owner"
+)
+
+// AddBundleOutFlagVar adds the --bundle-out flag of the commands creating several objects.
+func AddBundleOutFlagVar(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "bundle-out", *p, "Write the manifests of the created objects to this file, with the label "+BundleLabel+" set to the name of the file, so that they can be tracked and deleted as a unit, e.g. with 'kubectl delete -f FILE'.")
+}
+
+// Bundle collects the manifests of the objects created by a command into a file.
+type Bundle struct {
+	path    string
+	name    string
+	owner   string
+	objects []runtime.Object
+}
+
+// NewBundle returns the bundle written to path, named after its file name, of the objects created
+// for owner.
+func NewBundle(path, owner string) (*Bundle, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if errs := validation.IsValidLabelValue(name); len(errs) > 0 || len(name) == 0 {
+		return nil, fmt.Errorf("invalid --bundle-out %q, the file name must be a valid label value: %s", path, strings.Join(errs, ", "))
+	}
+	return &Bundle{path: path, name: name, owner: owner}, nil
+}
+
+// Name returns the name of the bundle, the value of its label.
+func (b *Bundle) Name() string {
+	return b.name
+}
+
+// Label marks obj as part of the bundle, before it is created.
+func (b *Bundle) Label(obj metav1.Object) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[BundleLabel] = b.name
+	obj.SetLabels(labels)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[BundleOwnerAnnotation] = b.owner
+	obj.SetAnnotations(annotations)
+}
+
+// Add adds the manifest of obj, as created or modified on the server, to the bundle.
+func (b *Bundle) Add(obj runtime.Object) {
+	b.objects = append(b.objects, obj.DeepCopyObject())
+}
+
+// Write writes the manifests of the bundle to its file, without their server-side state.
+func (b *Bundle) Write() error {
+	file, err := os.Create(b.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	printer := &printers.YAMLPrinter{}
+	for _, obj := range b.objects {
+		if obj.GetObjectKind().GroupVersionKind().Empty() {
+			if err := setKind(obj); err != nil {
+				return err
+			}
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		u := &unstructured.Unstructured{Object: content}
+		delete(u.Object, "status")
+		for _, field := range []string{"resourceVersion", "uid", "generation", "creationTimestamp", "managedFields", "selfLink"} {
+			unstructured.RemoveNestedField(u.Object, "metadata", field)
+		}
+		if err := printer.PrintObj(u, file); err != nil {
+			return err
+		}
+	}
+	return file.Close()
+}
+
+// setKind sets the kind of obj, which the typed clients leave empty, from the schemes.
+func setKind(obj runtime.Object) error {
+	kinds, _, err := internalapi.GetScheme().ObjectKinds(obj)
+	if err != nil {
+		if kinds, _, err = scheme.Scheme.ObjectKinds(obj); err != nil {
+			return err
+		}
+	}
+	obj.GetObjectKind().SetGroupVersionKind(kinds[0])
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewBundle(t *testing.T) {
+	tests := []struct {
+		path      string
+		name      string
+		expectErr bool
+	}{
+		{path: "bundle.yaml", name: "bundle"},
+		{path: "/tmp/prepull-demo.yml", name: "prepull-demo"},
+		{path: "release.v1.yaml", name: "release.v1"},
+		{path: ".yaml", expectErr: true},
+		{path: "my bundle.yaml", expectErr: true},
+	}
+	for _, test := range tests {
+		bundle, err := NewBundle(test.path, "cloneset/demo")
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got bundle %s", test.path, bundle.Name())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.path, err)
+			continue
+		}
+		if bundle.Name() != test.name {
+			t.Errorf("%s: expected name %s, got %s", test.path, test.name, bundle.Name())
+		}
+	}
+}
+
+func TestBundleWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bundle, err := NewBundle(filepath.Join(dir, "demo.yaml"), "cloneset/demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &kruiseappsv1alpha1.ImagePullJob{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo-app", Labels: map[string]string{"app": "demo"}},
+		Spec:       kruiseappsv1alpha1.ImagePullJobSpec{Image: "nginx:1.21"},
+	}
+	bundle.Label(job)
+	if job.Labels[BundleLabel] != "demo" || job.Labels["app"] != "demo" || job.Annotations[BundleOwnerAnnotation] != "cloneset/demo" {
+		t.Errorf("unexpected metadata %v %v", job.Labels, job.Annotations)
+	}
+	job.ResourceVersion = "42"
+	job.UID = "0a1b"
+	job.Status.Active = 1
+	bundle.Add(job)
+	bundle.Add(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"}})
+	if err := bundle.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "demo.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	for _, expected := range []string{"kind: ImagePullJob", "apiVersion: apps.kruise.io/v1alpha1", "kind: ConfigMap", "kubectl.kruise.io/bundle: demo", "image: nginx:1.21", "\n---\n"} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected bundle to contain %q, got:\n%s", expected, content)
+		}
+	}
+	for _, unexpected := range []string{"resourceVersion", "uid:", "status:", "creationTimestamp"} {
+		if strings.Contains(content, unexpected) {
+			t.Errorf("expected bundle not to contain %q, got:\n%s", unexpected, content)
+		}
+	}
+	if job.ResourceVersion != "42" || job.GetObjectKind().GroupVersionKind().Kind != "" {
+		t.Errorf("expected the added object to be left unchanged, got %+v", job)
+	}
+}
@@ -0,0 +1,335 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// IgnoreCapacityFlag is the flag of the commands that check the capacity for the pods they create.
+const IgnoreCapacityFlag = "ignore-capacity"
+
+// clusterScope is the scope of the capacity checks against the allocatable resources of the nodes.
+const clusterScope = "cluster"
+
+// CapacityCheck compares the resources needed by new pods with what is available in a scope, either
+// a ResourceQuota of the namespace or the nodes of the cluster.
+type CapacityCheck struct {
+	Scope     string
+	Resource  corev1.ResourceName
+	Needed    apiresource.Quantity
+	Available apiresource.Quantity
+}
+
+// OK returns true if the needed resources are available.
+func (c CapacityCheck) OK() bool {
+	return c.Needed.Cmp(c.Available) <= 0
+}
+
+// CapacityReport is the go/no-go summary of the capacity for new pods.
+type CapacityReport struct {
+	Pods    int
+	Checks  []CapacityCheck
+	Skipped []string
+}
+
+// Go returns true if all the checks passed.
+func (r *CapacityReport) Go() bool {
+	for _, check := range r.Checks {
+		if !check.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// Print prints the checks of the report for the named resource, and whether it is a go.
+func (r *CapacityReport) Print(out io.Writer, name string) error {
+	fmt.Fprintf(out, "Capacity for %d more pods of %s:\n", r.Pods, name)
+	w := NewTableWriter(out)
+	fmt.Fprintln(w, "  SCOPE\tRESOURCE\tNEEDED\tAVAILABLE\tSTATUS")
+	for _, check := range r.Checks {
+		status := "ok"
+		if !check.OK() {
+			status = "insufficient"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", check.Scope, check.Resource, check.Needed.String(), check.Available.String(), status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for _, skipped := range r.Skipped {
+		fmt.Fprintf(out, "  skipped %s\n", skipped)
+	}
+	if r.Go() {
+		_, err := fmt.Fprintln(out, "GO")
+		return err
+	}
+	_, err := fmt.Fprintln(out, "NO-GO")
+	return err
+}
+
+// CheckCapacity checks that the ResourceQuotas of namespace and the allocatable resources of the
+// nodes the pods may run on have room for the given number of pods of spec. The nodes are only
+// compared in aggregate, a go does not guarantee that each pod fits on a node. The checks that the
+// client is forbidden to make are skipped.
+func CheckCapacity(client kubernetes.Interface, namespace string, spec *corev1.PodSpec, pods int) (*CapacityReport, error) {
+	report := &CapacityReport{Pods: pods}
+	if pods <= 0 {
+		return report, nil
+	}
+	requests, limits := podResources(spec)
+
+	quotas, err := client.CoreV1().ResourceQuotas(namespace).List(context.TODO(), metav1.ListOptions{})
+	if apierrors.IsForbidden(err) {
+		report.Skipped = append(report.Skipped, "quotas: "+err.Error())
+	} else if err != nil {
+		return nil, err
+	} else {
+		for _, quota := range quotas.Items {
+			report.Checks = append(report.Checks, quotaChecks(&quota, requests, limits, pods)...)
+		}
+	}
+
+	checks, err := clusterChecks(client, spec.NodeSelector, requests, pods)
+	if apierrors.IsForbidden(err) {
+		report.Skipped = append(report.Skipped, "cluster: "+err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	report.Checks = append(report.Checks, checks...)
+	return report, nil
+}
+
+// podResources returns the requests and limits of a pod of spec, the largest of the sum of its
+// containers and of each of its init containers, as the scheduler computes them.
+func podResources(spec *corev1.PodSpec) (corev1.ResourceList, corev1.ResourceList) {
+	requests, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, c := range spec.Containers {
+		addResources(requests, c.Resources.Requests)
+		addResources(limits, c.Resources.Limits)
+	}
+	for _, c := range spec.InitContainers {
+		maxResources(requests, c.Resources.Requests)
+		maxResources(limits, c.Resources.Limits)
+	}
+	return requests, limits
+}
+
+func addResources(total, list corev1.ResourceList) {
+	for name, quantity := range list {
+		if value, ok := total[name]; ok {
+			value.Add(quantity)
+			total[name] = value
+		} else {
+			total[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func maxResources(total, list corev1.ResourceList) {
+	for name, quantity := range list {
+		if value, ok := total[name]; !ok || quantity.Cmp(value) > 0 {
+			total[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// multiplyQuantity returns quantity times n.
+func multiplyQuantity(quantity apiresource.Quantity, n int) apiresource.Quantity {
+	return *apiresource.NewMilliQuantity(quantity.MilliValue()*int64(n), quantity.Format)
+}
+
+// quotaChecks returns the checks of the resources hard limited by quota. Scoped quotas, that only
+// apply to some pods, are ignored.
+func quotaChecks(quota *corev1.ResourceQuota, requests, limits corev1.ResourceList, pods int) []CapacityCheck {
+	if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+		return nil
+	}
+	var checks []CapacityCheck
+	for _, name := range sortedResourceNames(quota.Status.Hard) {
+		var needed apiresource.Quantity
+		switch {
+		case name == corev1.ResourcePods || name == "count/pods":
+			needed = *apiresource.NewQuantity(int64(pods), apiresource.DecimalSI)
+		case strings.HasPrefix(string(name), "requests."):
+			needed = multiplyQuantity(requests[corev1.ResourceName(strings.TrimPrefix(string(name), "requests."))], pods)
+		case strings.HasPrefix(string(name), "limits."):
+			needed = multiplyQuantity(limits[corev1.ResourceName(strings.TrimPrefix(string(name), "limits."))], pods)
+		case name == corev1.ResourceCPU || name == corev1.ResourceMemory || name == corev1.ResourceEphemeralStorage:
+			needed = multiplyQuantity(requests[name], pods)
+		default:
+			continue
+		}
+		if needed.IsZero() {
+			continue
+		}
+		available := quota.Status.Hard[name].DeepCopy()
+		available.Sub(quota.Status.Used[name])
+		checks = append(checks, CapacityCheck{Scope: "quota/" + quota.Name, Resource: name, Needed: needed, Available: available})
+	}
+	return checks
+}
+
+// clusterChecks returns the checks of the pods, cpu and memory left allocatable on the schedulable
+// nodes matching nodeSelector.
+func clusterChecks(client kubernetes.Interface, nodeSelector map[string]string, requests corev1.ResourceList, pods int) ([]CapacityCheck, error) {
+	nodes, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	selector := labels.SelectorFromSet(nodeSelector)
+	nodeNames := sets.NewString()
+	available := corev1.ResourceList{}
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable || !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		nodeNames.Insert(node.Name)
+		addResources(available, node.Status.Allocatable)
+	}
+
+	running, err := client.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	})
+	if err != nil {
+		return nil, err
+	}
+	used := corev1.ResourceList{corev1.ResourcePods: apiresource.Quantity{}}
+	for i := range running.Items {
+		pod := &running.Items[i]
+		if !nodeNames.Has(pod.Spec.NodeName) {
+			continue
+		}
+		podRequests, _ := podResources(&pod.Spec)
+		podRequests[corev1.ResourcePods] = *apiresource.NewQuantity(1, apiresource.DecimalSI)
+		addResources(used, podRequests)
+	}
+
+	needed := corev1.ResourceList{corev1.ResourcePods: *apiresource.NewQuantity(int64(pods), apiresource.DecimalSI)}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if quantity, ok := requests[name]; ok && !quantity.IsZero() {
+			needed[name] = multiplyQuantity(quantity, pods)
+		}
+	}
+	var checks []CapacityCheck
+	for _, name := range sortedResourceNames(needed) {
+		left := available[name].DeepCopy()
+		left.Sub(used[name])
+		checks = append(checks, CapacityCheck{Scope: clusterScope, Resource: name, Needed: needed[name], Available: left})
+	}
+	return checks, nil
+}
+
+func sortedResourceNames(list corev1.ResourceList) []corev1.ResourceName {
+	names := sets.NewString()
+	for name := range list {
+		names.Insert(string(name))
+	}
+	var sorted []corev1.ResourceName
+	for _, name := range names.List() {
+		sorted = append(sorted, corev1.ResourceName(name))
+	}
+	return sorted
+}
+
+// WithCapacityCheck adds the --ignore-capacity flag to the scale command cmd. Before scaling up, the
+// capacity for the additional pods is checked and printed to the error output, and the command
+// fails if there is not enough unless --ignore-capacity is given.
+func WithCapacityCheck(f cmdutil.Factory, cmd *cobra.Command, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd.Flags().Bool(IgnoreCapacityFlag, false, "If true, scale up even if the quotas or the nodes have not enough capacity for the additional pods.")
+
+	run := cmd.Run
+	cmd.Run = func(c *cobra.Command, args []string) {
+		if !cmdutil.GetFlagBool(c, IgnoreCapacityFlag) {
+			cmdutil.CheckErr(checkScaleCapacity(f, c, args, streams.ErrOut))
+		}
+		run(c, args)
+	}
+	return cmd
+}
+
+func checkScaleCapacity(f cmdutil.Factory, cmd *cobra.Command, args []string, out io.Writer) error {
+	replicas := cmdutil.GetFlagInt(cmd, "replicas")
+	if replicas < 0 {
+		// let the scale command report the invalid replicas
+		return nil
+	}
+	stdin, err := bufferFilenameStdin(cmd)
+	if err != nil {
+		return err
+	}
+	infos, err := readyStateInfos(f, cmd, args, stdin)
+	if err != nil {
+		return err
+	}
+	client, err := f.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+
+	var noGo []string
+	for _, info := range infos {
+		u, ok := info.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		current, found, err := unstructured.NestedInt64(u.Object, "spec", "replicas")
+		if err != nil || !found || int64(replicas) <= current {
+			continue
+		}
+		template, found, err := unstructured.NestedMap(u.Object, "spec", "template", "spec")
+		if err != nil || !found {
+			continue
+		}
+		spec := &corev1.PodSpec{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(template, spec); err != nil {
+			return err
+		}
+
+		report, err := CheckCapacity(client, info.Namespace, spec, replicas-int(current))
+		if err != nil {
+			return err
+		}
+		if err := report.Print(out, info.ObjectName()); err != nil {
+			return err
+		}
+		if !report.Go() {
+			noGo = append(noGo, info.ObjectName())
+		}
+	}
+	if len(noGo) > 0 {
+		return fmt.Errorf("not enough capacity to scale up %s, use --%s to scale anyway", strings.Join(noGo, ", "), IgnoreCapacityFlag)
+	}
+	return nil
+}
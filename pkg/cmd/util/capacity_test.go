@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newPodSpec(cpu, memory string) *corev1.PodSpec {
+	return &corev1.PodSpec{Containers: []corev1.Container{{
+		Name: "main",
+		Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    apiresource.MustParse(cpu),
+			corev1.ResourceMemory: apiresource.MustParse(memory),
+		}},
+	}}}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    apiresource.MustParse("2"),
+			corev1.ResourceMemory: apiresource.MustParse("4Gi"),
+			corev1.ResourcePods:   apiresource.MustParse("10"),
+		}},
+	}
+	cordoned := node.DeepCopy()
+	cordoned.Name = "node-2"
+	cordoned.Spec.Unschedulable = true
+	running := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "running"},
+		Spec:       *newPodSpec("1500m", "1Gi"),
+	}
+	running.Spec.NodeName = "node-1"
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "compute"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{"requests.memory": apiresource.MustParse("2Gi"), corev1.ResourcePods: apiresource.MustParse("5")},
+			Used: corev1.ResourceList{"requests.memory": apiresource.MustParse("1Gi"), corev1.ResourcePods: apiresource.MustParse("1")},
+		},
+	}
+	client := fake.NewSimpleClientset(node, cordoned, running, quota)
+
+	report, err := CheckCapacity(client, "default", newPodSpec("200m", "256Mi"), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.Go() {
+		t.Errorf("expected a go, got %+v", report.Checks)
+	}
+	if len(report.Checks) != 5 {
+		t.Errorf("expected 2 quota and 3 cluster checks, got %+v", report.Checks)
+	}
+
+	report, err = CheckCapacity(client, "default", newPodSpec("300m", "600Mi"), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Go() {
+		t.Errorf("expected a no-go, got %+v", report.Checks)
+	}
+	var insufficient []string
+	for _, check := range report.Checks {
+		if !check.OK() {
+			insufficient = append(insufficient, check.Scope+" "+string(check.Resource))
+		}
+	}
+	// 600m of cpu are needed but only 500m are left, 1200Mi of memory but only 1Gi of quota
+	if expected := "quota/compute requests.memory,cluster cpu"; strings.Join(insufficient, ",") != expected {
+		t.Errorf("expected %s to be insufficient, got %v", expected, insufficient)
+	}
+
+	out := &bytes.Buffer{}
+	if err := report.Print(out, "cloneset/sample"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Capacity for 2 more pods of cloneset/sample") || !strings.HasSuffix(out.String(), "NO-GO\n") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestPodResources(t *testing.T) {
+	spec := newPodSpec("100m", "128Mi")
+	spec.Containers = append(spec.Containers, newPodSpec("100m", "128Mi").Containers...)
+	spec.InitContainers = newPodSpec("500m", "64Mi").Containers
+
+	requests, _ := podResources(spec)
+	if cpu := requests[corev1.ResourceCPU]; cpu.String() != "500m" {
+		t.Errorf("expected the cpu of the init container, got %s", cpu.String())
+	}
+	if memory := requests[corev1.ResourceMemory]; memory.String() != "256Mi" {
+		t.Errorf("expected the memory of the containers, got %s", memory.String())
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/openkruise/kruise-tools/pkg/freeze"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// ToRecorder returns the recorder of flags. If the client impersonates another user, with --as and
+// --as-group or in the kubeconfig file, the recorded change cause is suffixed with the impersonated
+// and the actual identity, so that changes made through shared automation accounts can still be
+// attributed. The reason of --break-glass, if any, is recorded the same way.
+func ToRecorder(f genericclioptions.RESTClientGetter, cmd *cobra.Command, flags *genericclioptions.RecordFlags) (genericclioptions.Recorder, error) {
+	recorder, err := flags.ToRecorder()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := recorder.(*genericclioptions.ChangeCauseRecorder); !ok {
+		return recorder, nil
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	var suffix string
+	if len(config.Impersonate.UserName) > 0 || len(config.Impersonate.Groups) > 0 {
+		suffix = identitySuffix(config.Impersonate, actualIdentity(f, cmd, config))
+	}
+	if reason := freeze.BreakGlass(); len(reason) > 0 {
+		suffix += fmt.Sprintf(" [break-glass: %s]", reason)
+	}
+	if len(suffix) == 0 {
+		return recorder, nil
+	}
+	return &suffixRecorder{Recorder: recorder, suffix: suffix}, nil
+}
+
+// identitySuffix returns the suffix of the change cause for a change made by actual impersonating impersonated.
+func identitySuffix(impersonated rest.ImpersonationConfig, actual string) string {
+	var as []string
+	if len(impersonated.UserName) > 0 {
+		as = append(as, "user="+impersonated.UserName)
+	}
+	if len(impersonated.Groups) > 0 {
+		as = append(as, "groups="+strings.Join(impersonated.Groups, ","))
+	}
+	return fmt.Sprintf(" [impersonated %s; actual user=%s]", strings.Join(as, " "), actual)
+}
+
+// actualIdentity returns the user the client authenticates as: the common name of its client
+// certificate or its basic auth user if it has one, or else the name of its user in the kubeconfig
+// file, as the identity behind tokens and exec plugins is only known to the server.
+func actualIdentity(f genericclioptions.RESTClientGetter, cmd *cobra.Command, config *rest.Config) string {
+	if name := certificateCommonName(config.TLSClientConfig); len(name) > 0 {
+		return name
+	}
+	if len(config.Username) > 0 {
+		return config.Username
+	}
+
+	if cmd.Flags().Lookup("user") != nil {
+		if user := cmdutil.GetFlagString(cmd, "user"); len(user) > 0 {
+			return user
+		}
+	}
+	raw, err := f.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return "<unknown>"
+	}
+	contextName := raw.CurrentContext
+	if cmd.Flags().Lookup("context") != nil {
+		if name := cmdutil.GetFlagString(cmd, "context"); len(name) > 0 {
+			contextName = name
+		}
+	}
+	if context, ok := raw.Contexts[contextName]; ok && len(context.AuthInfo) > 0 {
+		return context.AuthInfo
+	}
+	return "<unknown>"
+}
+
+// certificateCommonName returns the common name of the client certificate of config, if any.
+func certificateCommonName(config rest.TLSClientConfig) string {
+	data := config.CertData
+	if len(data) == 0 && len(config.CertFile) > 0 {
+		data, _ = ioutil.ReadFile(config.CertFile)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return ""
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return ""
+	}
+	return cert.Subject.CommonName
+}
+
+// suffixRecorder suffixes the change cause recorded by a ChangeCauseRecorder with the identities
+// and the break-glass reason of the change.
+type suffixRecorder struct {
+	genericclioptions.Recorder
+
+	suffix string
+}
+
+// Record records the change cause and its suffix in the annotations of obj.
+func (r *suffixRecorder) Record(obj runtime.Object) error {
+	if err := r.Recorder.Record(obj); err != nil {
+		return err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	annotations := accessor.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[polymorphichelpers.ChangeCauseAnnotation] += r.suffix
+	accessor.SetAnnotations(annotations)
+	return nil
+}
+
+// MakeRecordMergePatch produces a merge patch for updating the recording annotation.
+func (r *suffixRecorder) MakeRecordMergePatch(obj runtime.Object) ([]byte, error) {
+	// copy so we don't mess with the original
+	objCopy := obj.DeepCopyObject()
+	if err := r.Record(objCopy); err != nil {
+		return nil, err
+	}
+
+	oldData, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	newData, err := json.Marshal(objCopy)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.CreateMergePatch(oldData, newData)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/freeze"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestToRecorder(t *testing.T) {
+	tests := []struct {
+		name        string
+		impersonate rest.ImpersonationConfig
+		breakGlass  string
+		expected    string
+	}{
+		{
+			name:     "no impersonation",
+			expected: "kubectl-kruise set image cloneset/demo main=nginx:2",
+		},
+		{
+			name:        "impersonated user and groups",
+			impersonate: rest.ImpersonationConfig{UserName: "alice", Groups: []string{"sre", "oncall"}},
+			expected:    "kubectl-kruise set image cloneset/demo main=nginx:2 [impersonated user=alice groups=sre,oncall; actual user=ci-bot]",
+		},
+		{
+			name:        "impersonated group",
+			impersonate: rest.ImpersonationConfig{Groups: []string{"sre"}},
+			expected:    "kubectl-kruise set image cloneset/demo main=nginx:2 [impersonated groups=sre; actual user=ci-bot]",
+		},
+		{
+			name:       "break glass",
+			breakGlass: "INC-42 rollback",
+			expected:   "kubectl-kruise set image cloneset/demo main=nginx:2 [break-glass: INC-42 rollback]",
+		},
+		{
+			name:        "impersonated user breaking glass",
+			impersonate: rest.ImpersonationConfig{UserName: "alice"},
+			breakGlass:  "INC-42",
+			expected:    "kubectl-kruise set image cloneset/demo main=nginx:2 [impersonated user=alice; actual user=ci-bot] [break-glass: INC-42]",
+		},
+	}
+	defer freeze.ParseArgs([]string{"--break-glass="})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			freeze.ParseArgs([]string{"--break-glass=" + test.breakGlass})
+			f := cmdtesting.NewTestFactory()
+			defer f.Cleanup()
+			f.ClientConfigVal = &rest.Config{Username: "ci-bot", Impersonate: test.impersonate}
+
+			flags := genericclioptions.NewRecordFlags()
+			*flags.Record = true
+			if err := flags.CompleteWithChangeCause("kubectl-kruise set image cloneset/demo main=nginx:2"); err != nil {
+				t.Fatal(err)
+			}
+			recorder, err := ToRecorder(f, &cobra.Command{}, flags)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			obj := &appsv1.Deployment{}
+			if err := recorder.Record(obj); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := obj.Annotations["kubernetes.io/change-cause"]; got != test.expected {
+				t.Errorf("expected change cause %q, got %q", test.expected, got)
+			}
+
+			patch, err := recorder.MakeRecordMergePatch(&appsv1.Deployment{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := `{"metadata":{"annotations":{"kubernetes.io/change-cause":"` + test.expected + `"}}}`; string(patch) != expected {
+				t.Errorf("expected patch %s, got %s", expected, patch)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// CompletionFunc completes the value of a flag, see cobra.Command.RegisterFlagCompletionFunc.
+type CompletionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// ContainerCompletionFunc completes the names of the containers of the object given in the
+// arguments, both those of its pod template and those of its live pods, such as the sidecars
+// injected by SidecarSets. The working containers of hot-upgrade sidecars are described as such.
+// A single NAME argument is taken as a defaultResource name, if defaultResource is not empty.
+func ContainerCompletionFunc(f cmdutil.Factory, defaultResource string) CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		obj, pods, err := objectForCompletion(f, defaultResource, args)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var template []corev1.Container
+		if _, ok := obj.(*corev1.Pod); !ok {
+			_, _ = polymorphichelpers.UpdatePodSpecForObjectFn(obj, func(spec *corev1.PodSpec) error {
+				template = append(append(template, spec.InitContainers...), spec.Containers...)
+				return nil
+			})
+		}
+		return ContainerCompletions(template, pods, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// SidecarCompletionFunc completes the names of the hot-upgrade sidecars in the pods of the
+// object given in the arguments, with their working containers as description.
+func SidecarCompletionFunc(f cmdutil.Factory, defaultResource string) CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		_, pods, err := objectForCompletion(f, defaultResource, args)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return SidecarCompletions(pods, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// ContainerCompletions returns the names of the template containers and of the containers of
+// pods that start with toComplete, each once. The working containers of hot-upgrade sidecars
+// are completed as "NAME\tworking container of sidecar SIDECAR".
+func ContainerCompletions(template []corev1.Container, pods []corev1.Pod, toComplete string) []string {
+	descriptions := map[string]string{}
+	for _, c := range template {
+		descriptions[c.Name] = ""
+	}
+	for i := range pods {
+		pod := &pods[i]
+		for _, c := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+			if _, ok := descriptions[c.Name]; !ok {
+				descriptions[c.Name] = ""
+			}
+		}
+		for sidecar, working := range GetPodHotUpgradeInfoInAnnotations(pod) {
+			descriptions[working] = fmt.Sprintf("working container of sidecar %s", sidecar)
+		}
+	}
+	return completions(descriptions, toComplete)
+}
+
+// SidecarCompletions returns the names of the hot-upgrade sidecars of pods that start with
+// toComplete, as "SIDECAR\tworking container WORKING" when all pods work on the same container.
+func SidecarCompletions(pods []corev1.Pod, toComplete string) []string {
+	working := map[string]sets.String{}
+	for i := range pods {
+		for sidecar, container := range GetPodHotUpgradeInfoInAnnotations(&pods[i]) {
+			if working[sidecar] == nil {
+				working[sidecar] = sets.NewString()
+			}
+			working[sidecar].Insert(container)
+		}
+	}
+	descriptions := map[string]string{}
+	for sidecar, containers := range working {
+		descriptions[sidecar] = ""
+		if containers.Len() == 1 {
+			descriptions[sidecar] = fmt.Sprintf("working container %s", containers.List()[0])
+		}
+	}
+	return completions(descriptions, toComplete)
+}
+
+// completions returns the names in descriptions that start with toComplete, sorted, with their
+// description appended after a tab if they have one.
+func completions(descriptions map[string]string, toComplete string) []string {
+	var names []string
+	for name := range descriptions {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if description := descriptions[name]; len(description) > 0 {
+			names[i] = name + "\t" + description
+		}
+	}
+	return names
+}
+
+// objectForCompletion returns the object given in args and its live pods.
+func objectForCompletion(f cmdutil.Factory, defaultResource string, args []string) (runtime.Object, []corev1.Pod, error) {
+	args = resourceArgs(args)
+	if len(args) == 0 {
+		return nil, nil, fmt.Errorf("no resource given")
+	}
+	if len(defaultResource) > 0 && len(args) == 1 && !strings.Contains(args[0], "/") {
+		args = []string{defaultResource, args[0]}
+	}
+
+	namespace, _, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, nil, err
+	}
+	infos, err := f.NewBuilder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(namespace).DefaultNamespace().
+		ResourceTypeOrNameArgs(true, args...).
+		SingleResourceType().
+		Latest().
+		Do().Infos()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(infos) != 1 {
+		return nil, nil, fmt.Errorf("expected one resource, got %d", len(infos))
+	}
+
+	clientset, err := f.KubernetesClientSet()
+	if err != nil {
+		return nil, nil, err
+	}
+	pods, err := polymorphichelpers.PodsForObject(clientset.CoreV1(), infos[0].Object)
+	if err != nil {
+		return nil, nil, err
+	}
+	return infos[0].Object, pods, nil
+}
+
+// resourceArgs returns the leading args that name resources, dropping the changes that follow
+// them in set commands, such as KEY=VALUE and KEY-.
+func resourceArgs(args []string) []string {
+	for i, arg := range args {
+		if strings.Contains(arg, "=") || strings.HasSuffix(arg, "-") {
+			return args[:i]
+		}
+	}
+	return args
+}
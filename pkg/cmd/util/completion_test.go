@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func hotUpgradePod(name, working string, containers ...string) corev1.Pod {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if len(working) > 0 {
+		pod.Annotations = map[string]string{SidecarSetWorkingHotUpgradeContainer: `{"sidecar":"` + working + `"}`}
+	}
+	for _, c := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: c})
+	}
+	return pod
+}
+
+func TestContainerCompletions(t *testing.T) {
+	template := []corev1.Container{{Name: "app"}, {Name: "agent"}}
+	pods := []corev1.Pod{
+		hotUpgradePod("pod-0", "sidecar-1", "app", "agent", "sidecar-1", "sidecar-2"),
+		hotUpgradePod("pod-1", "", "app", "agent", "logger"),
+	}
+
+	tests := []struct {
+		name       string
+		toComplete string
+		expected   []string
+	}{
+		{
+			name:     "all containers",
+			expected: []string{"agent", "app", "logger", "sidecar-1\tworking container of sidecar sidecar", "sidecar-2"},
+		},
+		{
+			name:       "prefix",
+			toComplete: "a",
+			expected:   []string{"agent", "app"},
+		},
+		{
+			name:       "no match",
+			toComplete: "x",
+			expected:   nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ContainerCompletions(template, pods, test.toComplete)
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestSidecarCompletions(t *testing.T) {
+	tests := []struct {
+		name     string
+		pods     []corev1.Pod
+		expected []string
+	}{
+		{
+			name:     "same working container",
+			pods:     []corev1.Pod{hotUpgradePod("pod-0", "sidecar-1"), hotUpgradePod("pod-1", "sidecar-1")},
+			expected: []string{"sidecar\tworking container sidecar-1"},
+		},
+		{
+			name:     "upgrade in progress",
+			pods:     []corev1.Pod{hotUpgradePod("pod-0", "sidecar-1"), hotUpgradePod("pod-1", "sidecar-2")},
+			expected: []string{"sidecar"},
+		},
+		{
+			name:     "no hot-upgrade sidecar",
+			pods:     []corev1.Pod{hotUpgradePod("pod-0", "")},
+			expected: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SidecarCompletions(test.pods, "")
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestResourceArgs(t *testing.T) {
+	got := resourceArgs([]string{"cloneset", "demo", "FOO=bar", "BAR-"})
+	if expected := []string{"cloneset", "demo"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
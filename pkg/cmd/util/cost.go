@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+
+	"github.com/openkruise/kruise-tools/pkg/cost"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// WithCostEstimate prints the estimated monthly cost delta of the scale command cmd after its
+// output, when a price table is configured and the output is not printed with -o. The cost of a
+// resource is only printed once the server accepted to scale it.
+func WithCostEstimate(f cmdutil.Factory, cmd *cobra.Command, streams genericclioptions.IOStreams) *cobra.Command {
+	run := cmd.Run
+	cmd.Run = func(c *cobra.Command, args []string) {
+		if !cost.Enabled() || (c.Flags().Lookup("output") != nil && len(cmdutil.GetFlagString(c, "output")) > 0) {
+			run(c, args)
+			return
+		}
+		// the objects of "-" are read again once the command consumed stdin
+		stdin, err := bufferFilenameStdin(c)
+		cmdutil.CheckErr(err)
+		estimates, err := scaleCostEstimates(f, c, args, stdin)
+		cmdutil.CheckErr(err)
+		run(c, args)
+		if len(estimates) == 0 {
+			return
+		}
+		scaled, err := scaledResources(f, c, args, stdin)
+		cmdutil.CheckErr(err)
+		for _, estimate := range estimates {
+			if scaled == nil || scaled.Has(estimate.name) {
+				fmt.Fprintf(streams.Out, "%s %s\n", estimate.name, estimate)
+			}
+		}
+	}
+	return cmd
+}
+
+type namedEstimate struct {
+	*cost.Estimate
+	name string
+}
+
+// scaleCostEstimates returns the estimates of scaling the resources of args to --replicas.
+func scaleCostEstimates(f cmdutil.Factory, cmd *cobra.Command, args []string, stdin []byte) ([]namedEstimate, error) {
+	replicas := cmdutil.GetFlagInt(cmd, "replicas")
+	if replicas < 0 {
+		// let the scale command report the invalid replicas
+		return nil, nil
+	}
+	infos, err := readyStateInfos(f, cmd, args, stdin)
+	if err != nil {
+		return nil, err
+	}
+	var estimates []namedEstimate
+	for _, info := range infos {
+		u, ok := info.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		if _, found, err := unstructured.NestedInt64(u.Object, "spec", "replicas"); err != nil || !found {
+			continue
+		}
+		scaled := u.DeepCopy()
+		if err := unstructured.SetNestedField(scaled.Object, int64(replicas), "spec", "replicas"); err != nil {
+			return nil, err
+		}
+		estimate, err := cost.EstimateObjects(u, scaled)
+		if err != nil {
+			return nil, err
+		}
+		if estimate != nil {
+			estimates = append(estimates, namedEstimate{Estimate: estimate, name: info.ObjectName()})
+		}
+	}
+	return estimates, nil
+}
+
+// scaledResources returns the names of the resources of args the server now has at --replicas, or
+// nil in a dry run, which changes none of them.
+func scaledResources(f cmdutil.Factory, cmd *cobra.Command, args []string, stdin []byte) (sets.String, error) {
+	if cmd.Flags().Lookup("dry-run") != nil {
+		dryRun, err := cmdutil.GetDryRunStrategy(cmd)
+		if err != nil || dryRun != cmdutil.DryRunNone {
+			return nil, err
+		}
+	}
+	infos, err := readyStateInfos(f, cmd, args, stdin)
+	if err != nil {
+		return nil, err
+	}
+	replicas := int64(cmdutil.GetFlagInt(cmd, "replicas"))
+	scaled := sets.NewString()
+	for _, info := range infos {
+		u, ok := info.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		if current, found, err := unstructured.NestedInt64(u.Object, "spec", "replicas"); err == nil && found && current == replicas {
+			scaled.Insert(info.ObjectName())
+		}
+	}
+	return scaled, nil
+}
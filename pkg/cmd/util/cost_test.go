@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+	"github.com/openkruise/kruise-tools/pkg/cost"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+func TestWithCostEstimate(t *testing.T) {
+	cost.Configure(&config.Cost{CPU: 10, Memory: 1, Currency: "USD"})
+	defer cost.Configure(nil)
+
+	tests := []struct {
+		name     string
+		accept   bool
+		dryRun   string
+		expected string
+	}{
+		{name: "accepted", accept: true, expected: "clonesets/web"},
+		{name: "rejected"},
+		{name: "dry run", dryRun: "client", expected: "clonesets/web"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replicas := 2
+			tf := cmdtesting.NewTestFactory().WithNamespace("test")
+			defer tf.Cleanup()
+			tf.UnstructuredClient = &fake.RESTClient{
+				NegotiatedSerializer: resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer,
+				Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+					if req.Method != http.MethodGet || req.URL.Path != "/namespaces/test/clonesets/web" {
+						t.Errorf("unexpected request %s %s", req.Method, req.URL)
+					}
+					body := fmt.Sprintf(`{"apiVersion":"apps.kruise.io/v1alpha1","kind":"CloneSet","metadata":{"name":"web","namespace":"test"},`+
+						`"spec":{"replicas":%d,"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"1"}}}]}}}}`, replicas)
+					return &http.Response{StatusCode: http.StatusOK, Header: cmdtesting.DefaultHeader(), Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+				}),
+			}
+
+			streams, _, out, _ := genericclioptions.NewTestIOStreams()
+			cmd := &cobra.Command{
+				Use: "scale",
+				Run: func(cmd *cobra.Command, args []string) {
+					// the server rejects the change by leaving the replicas unchanged
+					if test.accept {
+						replicas = cmdutil.GetFlagInt(cmd, "replicas")
+					}
+				},
+			}
+			cmd.Flags().Int("replicas", 0, "")
+			cmdutil.AddFilenameOptionFlags(cmd, &resource.FilenameOptions{}, "")
+			cmdutil.AddDryRunFlag(cmd)
+			WithCostEstimate(tf, cmd, streams)
+			cmd.Flags().Set("replicas", "5")
+			if len(test.dryRun) > 0 {
+				cmd.Flags().Set("dry-run", test.dryRun)
+			}
+			cmd.Run(cmd, []string{"cloneset", "web"})
+
+			if len(test.expected) == 0 {
+				if out.Len() > 0 {
+					t.Errorf("expected no estimate, got %q", out.String())
+				}
+			} else if !strings.HasPrefix(out.String(), test.expected+" ") {
+				t.Errorf("expected the estimate of %s, got %q", test.expected, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/spf13/cobra"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// filterExprFields are the top-level fields of the objects declared as variables of the
+// expressions of --filter-expr. The whole object is the variable "object".
+var filterExprFields = []string{"apiVersion", "kind", "metadata", "spec", "status"}
+
+// AddFilterExprFlagVar adds the --filter-expr flag, which filters the resources a command lists
+// on the client with a CEL expression.
+func AddFilterExprFlagVar(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "filter-expr", *p, "CEL expression over the fields of the resources, evaluated on the client to keep the ones it is true for, e.g. --filter-expr 'status.phase == \"Running\" && metadata.labels.zone == \"a\"'. The fields are apiVersion, kind, metadata, spec and status, and object for the whole resource. Resources missing a field of the expression are left out.")
+}
+
+// FilterExpr is a compiled --filter-expr.
+type FilterExpr struct {
+	expr    string
+	program cel.Program
+}
+
+// CompileFilterExpr compiles expr, which must be a boolean CEL expression. It returns nil if expr
+// is empty.
+func CompileFilterExpr(expr string) (*FilterExpr, error) {
+	if len(strings.TrimSpace(expr)) == 0 {
+		return nil, nil
+	}
+	declarations := []*exprpb.Decl{decls.NewVar("object", decls.Dyn)}
+	for _, field := range filterExprFields {
+		declarations = append(declarations, decls.NewVar(field, decls.Dyn))
+	}
+	env, err := cel.NewEnv(cel.Declarations(declarations...))
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid --filter-expr %q: %v", expr, issues.Err())
+	}
+	if t := ast.ResultType(); t.GetPrimitive() != exprpb.Type_BOOL && t.GetDyn() == nil {
+		return nil, fmt.Errorf("invalid --filter-expr %q: must be a boolean expression", expr)
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --filter-expr %q: %v", expr, err)
+	}
+	return &FilterExpr{expr: expr, program: program}, nil
+}
+
+// Matches returns true if the expression is true for obj. It is false if obj misses a field of
+// the expression, and an error if the expression cannot be evaluated otherwise, e.g. when it
+// compares a string to a number.
+func (f *FilterExpr) Matches(obj runtime.Object) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+	content, ok := obj.(runtime.Unstructured)
+	var fields map[string]interface{}
+	if ok {
+		fields = content.UnstructuredContent()
+	} else {
+		var err error
+		if fields, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return false, err
+		}
+	}
+
+	vars := map[string]interface{}{"object": fields}
+	for _, field := range filterExprFields {
+		value, found, _ := unstructured.NestedFieldNoCopy(fields, field)
+		if !found {
+			value = map[string]interface{}{}
+		}
+		vars[field] = value
+	}
+	out, _, err := f.program.Eval(vars)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "no such key") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to evaluate --filter-expr %q: %v", f.expr, err)
+	}
+	matches, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("--filter-expr %q evaluated to %v, not a boolean", f.expr, out.Value())
+	}
+	return matches, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+)
+
+func TestCompileFilterExpr(t *testing.T) {
+	tests := []struct {
+		expr      string
+		expectNil bool
+		expectErr bool
+	}{
+		{expr: "", expectNil: true},
+		{expr: "status.updatedReplicas < spec.replicas"},
+		{expr: "object.kind == 'CloneSet'"},
+		{expr: "status.updatedReplicas <", expectErr: true},
+		{expr: "'a' + 'b'", expectErr: true},
+		{expr: "unknown.field == 1", expectErr: true},
+	}
+	for _, test := range tests {
+		filter, err := CompileFilterExpr(test.expr)
+		if (err != nil) != test.expectErr {
+			t.Errorf("%q: expected error %v, got %v", test.expr, test.expectErr, err)
+		}
+		if err == nil && (filter == nil) != test.expectNil {
+			t.Errorf("%q: expected nil filter %v, got %v", test.expr, test.expectNil, filter)
+		}
+	}
+}
+
+func TestFilterExprMatches(t *testing.T) {
+	cloneSet := &kruiseappsv1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Labels: map[string]string{"zone": "a"}},
+		Spec:       kruiseappsv1alpha1.CloneSetSpec{Replicas: pointer.Int32Ptr(5)},
+		Status:     kruiseappsv1alpha1.CloneSetStatus{UpdatedReplicas: 3},
+	}
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"kind":     "Pod",
+		"metadata": map[string]interface{}{"name": "demo-a"},
+		"status": map[string]interface{}{
+			"phase":             string(corev1.PodRunning),
+			"containerStatuses": []interface{}{map[string]interface{}{"name": "app", "restartCount": int64(4)}},
+		},
+	}}
+
+	tests := []struct {
+		expr      string
+		obj       runtime.Object
+		expected  bool
+		expectErr bool
+	}{
+		{expr: "status.updatedReplicas < spec.replicas", obj: cloneSet, expected: true},
+		{expr: "status.updatedReplicas == spec.replicas", obj: cloneSet, expected: false},
+		{expr: "metadata.labels.zone == 'a' && metadata.name.startsWith('de')", obj: cloneSet, expected: true},
+		{expr: "status.containerStatuses.exists(c, c.restartCount > 3)", obj: pod, expected: true},
+		{expr: "kind == 'Pod' && status.phase == 'Pending'", obj: pod, expected: false},
+		// missing fields do not match
+		{expr: "spec.replicas > 1", obj: pod, expected: false},
+		{expr: "metadata.labels.zone == 'a'", obj: pod, expected: false},
+		{expr: "status.phase > 1", obj: pod, expectErr: true},
+	}
+	for _, test := range tests {
+		filter, err := CompileFilterExpr(test.expr)
+		if err != nil {
+			t.Fatalf("%q: %v", test.expr, err)
+		}
+		matches, err := filter.Matches(test.obj)
+		if (err != nil) != test.expectErr {
+			t.Errorf("%q: expected error %v, got %v", test.expr, test.expectErr, err)
+			continue
+		}
+		if matches != test.expected {
+			t.Errorf("%q: expected %v, got %v", test.expr, test.expected, matches)
+		}
+	}
+
+	var none *FilterExpr
+	if matches, err := none.Matches(cloneSet); !matches || err != nil {
+		t.Errorf("expected no filter to match, got %v, %v", matches, err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/resource"
@@ -76,6 +77,20 @@ func AddFieldManagerFlagVar(cmd *cobra.Command, p *string, defaultFieldManager s
 	cmd.Flags().StringVar(p, "field-manager", defaultFieldManager, "Name of the manager used to track field ownership.")
 }
 
+// AddFieldSelectorFlagVar adds the --field-selector flag, which narrows the resources a command
+// lists on the server instead of filtering them on the client.
+func AddFieldSelectorFlagVar(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "field-selector", *p, "Selector (field query) to filter on, supports '=', '==', and '!='.(e.g. --field-selector metadata.name=demo,status.phase=Running). The server only supports a limited number of field queries per type, such as metadata.name for all resources and status.phase for pods.")
+}
+
+// ValidateFieldSelector returns an error if selector is not a valid field selector.
+func ValidateFieldSelector(selector string) error {
+	if _, err := fields.ParseSelector(selector); err != nil {
+		return fmt.Errorf("invalid --field-selector %q: %v", selector, err)
+	}
+	return nil
+}
+
 func PatchSubResource(RESTClient resource.RESTClient, resource, subResource, namespace, name string, namespaceScoped bool, pt types.PatchType, data []byte, options *metav1.PatchOptions) (runtime.Object, error) {
 	if options == nil {
 		options = &metav1.PatchOptions{}
@@ -91,3 +106,13 @@ func PatchSubResource(RESTClient resource.RESTClient, resource, subResource, nam
 		Get()
 }
 
+// SplitImageTag splits image into its name and tag, as NodeImages key them.
+func SplitImageTag(image string) (string, string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
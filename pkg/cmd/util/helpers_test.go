@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+)
+
+func TestValidateFieldSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		valid    bool
+	}{
+		{selector: "", valid: true},
+		{selector: "metadata.name=demo", valid: true},
+		{selector: "metadata.name=demo,status.phase!=Running", valid: true},
+		{selector: "status.phase==Running", valid: true},
+		{selector: "metadata.name", valid: false},
+		{selector: "status.phase<Running", valid: false},
+	}
+	for _, test := range tests {
+		if err := ValidateFieldSelector(test.selector); (err == nil) != test.valid {
+			t.Errorf("%q: expected valid %v, got error %v", test.selector, test.valid, err)
+		}
+	}
+}
+
+func TestSplitImageTag(t *testing.T) {
+	tests := map[string][2]string{
+		"nginx":                              {"nginx", "latest"},
+		"nginx:1.21":                         {"nginx", "1.21"},
+		"registry:5000/team/app":             {"registry:5000/team/app", "latest"},
+		"registry:5000/team/app:v1@sha256:1": {"registry:5000/team/app", "v1"},
+	}
+	for image, expected := range tests {
+		if name, tag := SplitImageTag(image); name != expected[0] || tag != expected[1] {
+			t.Errorf("%s: expected %v, got %s %s", image, expected, name, tag)
+		}
+	}
+}
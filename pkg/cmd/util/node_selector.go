@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// nodeSelectorOperators are the operators of --node-selector-expr, those of node affinity.
+var nodeSelectorOperators = []corev1.NodeSelectorOperator{
+	corev1.NodeSelectorOpIn,
+	corev1.NodeSelectorOpNotIn,
+	corev1.NodeSelectorOpExists,
+	corev1.NodeSelectorOpDoesNotExist,
+	corev1.NodeSelectorOpGt,
+	corev1.NodeSelectorOpLt,
+}
+
+// AddNodeSelectorExprFlagVar adds the --node-selector-expr flag, which scopes a command to the
+// nodes of a node pool with the expressions of node affinity rather than label equality.
+func AddNodeSelectorExprFlagVar(cmd *cobra.Command, p *[]string) {
+	cmd.Flags().StringArrayVar(p, "node-selector-expr", *p, "Only select the nodes matching this expression of node affinity, KEY OPERATOR [VALUES] with the operators In, NotIn, Exists, DoesNotExist, Gt and Lt, e.g. 'pool In (web,api)' or 'spot DoesNotExist'. Can be repeated, the nodes must match all the expressions.")
+}
+
+// ParseNodeSelectorExprs parses the expressions of --node-selector-expr. The values of In and NotIn
+// are separated by commas, optionally in parentheses.
+func ParseNodeSelectorExprs(exprs []string) ([]corev1.NodeSelectorRequirement, error) {
+	var requirements []corev1.NodeSelectorRequirement
+	for _, expr := range exprs {
+		fields := strings.Fields(expr)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid --node-selector-expr %q, must be KEY OPERATOR [VALUES]", expr)
+		}
+		requirement := corev1.NodeSelectorRequirement{Key: fields[0]}
+		for _, op := range nodeSelectorOperators {
+			if strings.EqualFold(fields[1], string(op)) {
+				requirement.Operator = op
+			}
+		}
+		if len(requirement.Operator) == 0 {
+			return nil, fmt.Errorf("invalid --node-selector-expr %q, unknown operator %s, must be one of In, NotIn, Exists, DoesNotExist, Gt or Lt", expr, fields[1])
+		}
+		values := strings.Trim(strings.Join(fields[2:], ""), "()")
+		if len(values) > 0 {
+			requirement.Values = strings.Split(values, ",")
+		}
+		if _, err := nodeLabelRequirement(requirement); err != nil {
+			return nil, fmt.Errorf("invalid --node-selector-expr %q: %v", expr, err)
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements, nil
+}
+
+// NodeLabelSelector returns the label selector of the nodes matching all the requirements, to
+// list them.
+func NodeLabelSelector(requirements []corev1.NodeSelectorRequirement) (labels.Selector, error) {
+	selector := labels.NewSelector()
+	for _, r := range requirements {
+		requirement, err := nodeLabelRequirement(r)
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*requirement)
+	}
+	return selector, nil
+}
+
+// NodeSelectorRequirementsAsLabelSelector adds the requirements to selector, for the objects that
+// select nodes with a metav1.LabelSelector, which does not support the Gt and Lt operators.
+func NodeSelectorRequirementsAsLabelSelector(selector *metav1.LabelSelector, requirements []corev1.NodeSelectorRequirement) error {
+	for _, r := range requirements {
+		switch r.Operator {
+		case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+			return fmt.Errorf("the operator %s of --node-selector-expr is not supported here", r.Operator)
+		}
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      r.Key,
+			Operator: metav1.LabelSelectorOperator(r.Operator),
+			Values:   r.Values,
+		})
+	}
+	return nil
+}
+
+func nodeLabelRequirement(r corev1.NodeSelectorRequirement) (*labels.Requirement, error) {
+	var op selection.Operator
+	switch r.Operator {
+	case corev1.NodeSelectorOpIn:
+		op = selection.In
+	case corev1.NodeSelectorOpNotIn:
+		op = selection.NotIn
+	case corev1.NodeSelectorOpExists:
+		op = selection.Exists
+	case corev1.NodeSelectorOpDoesNotExist:
+		op = selection.DoesNotExist
+	case corev1.NodeSelectorOpGt:
+		op = selection.GreaterThan
+	case corev1.NodeSelectorOpLt:
+		op = selection.LessThan
+	default:
+		return nil, fmt.Errorf("unknown operator %s", r.Operator)
+	}
+	return labels.NewRequirement(r.Key, op, r.Values)
+}
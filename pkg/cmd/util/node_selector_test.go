@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseNodeSelectorExprs(t *testing.T) {
+	tests := []struct {
+		exprs     []string
+		expected  []corev1.NodeSelectorRequirement
+		selector  string
+		expectErr bool
+	}{
+		{
+			exprs: []string{"pool In (web, api)", "spot doesnotexist"},
+			expected: []corev1.NodeSelectorRequirement{
+				{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"web", "api"}},
+				{Key: "spot", Operator: corev1.NodeSelectorOpDoesNotExist},
+			},
+			selector: "pool in (api,web),!spot",
+		},
+		{
+			exprs: []string{"zone NotIn a,b", "gpu Exists", "cpus Gt 8"},
+			expected: []corev1.NodeSelectorRequirement{
+				{Key: "zone", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"a", "b"}},
+				{Key: "gpu", Operator: corev1.NodeSelectorOpExists},
+				{Key: "cpus", Operator: corev1.NodeSelectorOpGt, Values: []string{"8"}},
+			},
+			selector: "cpus>8,gpu,zone notin (a,b)",
+		},
+		{exprs: []string{"pool"}, expectErr: true},
+		{exprs: []string{"pool = web"}, expectErr: true},
+		{exprs: []string{"pool In"}, expectErr: true},
+		{exprs: []string{"gpu Exists true"}, expectErr: true},
+		{exprs: []string{"cpus Lt many"}, expectErr: true},
+	}
+	for _, test := range tests {
+		requirements, err := ParseNodeSelectorExprs(test.exprs)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%v: expected an error", test.exprs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.exprs, err)
+			continue
+		}
+		if !reflect.DeepEqual(requirements, test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.exprs, test.expected, requirements)
+		}
+		selector, err := NodeLabelSelector(requirements)
+		if err != nil || selector.String() != test.selector {
+			t.Errorf("%v: expected the selector %q, got %q, %v", test.exprs, test.selector, selector, err)
+		}
+	}
+}
+
+func TestNodeSelectorRequirementsAsLabelSelector(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"arch": "arm64"}}
+	requirements := []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"web"}}}
+	if err := NodeSelectorRequirementsAsLabelSelector(selector, requirements); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []metav1.LabelSelectorRequirement{{Key: "pool", Operator: metav1.LabelSelectorOpIn, Values: []string{"web"}}}
+	if !reflect.DeepEqual(selector.MatchExpressions, expected) || selector.MatchLabels["arch"] != "arm64" {
+		t.Errorf("unexpected selector %+v", selector)
+	}
+
+	requirements = []corev1.NodeSelectorRequirement{{Key: "cpus", Operator: corev1.NodeSelectorOpGt, Values: []string{"8"}}}
+	if err := NodeSelectorRequirementsAsLabelSelector(&metav1.LabelSelector{}, requirements); err == nil {
+		t.Errorf("expected an error for the Gt operator")
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/util/homedir"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// OutputTemplatesDirEnv overrides the directory of the user-defined output templates.
+const OutputTemplatesDirEnv = "KUBECTL_KRUISE_TEMPLATES_DIR"
+
+// outputTemplateExt is the extension of the files of the user-defined output templates.
+const outputTemplateExt = ".tmpl"
+
+// outputTemplateName matches the names of output templates, as opposed to inline templates.
+var outputTemplateName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// each template prints the objects of lists as well as single objects
+const listTemplate = `{{if .items}}{{range .items}}{{template "object" .}}{{end}}{{else}}{{template "object" .}}{{end}}`
+
+// BuiltinOutputTemplates are the go-templates that can be selected by name with -o template=NAME.
+var BuiltinOutputTemplates = map[string]string{
+	"rollout-summary": `{{define "object"}}{{.kind}}/{{.metadata.name}}` +
+		`	generation {{.metadata.generation}}, observed {{or .status.observedGeneration 0}}` +
+		`	replicas {{or .spec.replicas 0}}, updated {{or .status.updatedReplicas 0}}, ready {{or .status.readyReplicas 0}}` +
+		`{{with .status.updateRevision}}	revision {{.}}{{end}}` + "\n" +
+		`{{end}}` + listTemplate,
+
+	"image-table": `{{define "object"}}{{$name := printf "%s/%s" .kind .metadata.name}}` +
+		`{{$spec := .spec}}{{with .spec.template}}{{$spec = .spec}}{{end}}` +
+		`{{range $spec.initContainers}}{{$name}}	{{.name}} (init)	{{.image}}` + "\n" + `{{end}}` +
+		`{{range $spec.containers}}{{$name}}	{{.name}}	{{.image}}` + "\n" + `{{end}}` +
+		`{{end}}` + listTemplate,
+
+	"partition-board": `{{define "object"}}{{.kind}}/{{.metadata.name}}	partition ` +
+		`{{with .spec.updateStrategy}}{{with .rollingUpdate}}{{or .partition 0}}{{else}}{{or .partition 0}}{{end}}{{else}}0{{end}}` +
+		` of {{or .spec.replicas 0}}	updated {{or .status.updatedReplicas 0}}, updated ready {{or .status.updatedReadyReplicas 0}}` +
+		`{{with .spec.updateStrategy}}{{with .paused}}	paused{{end}}{{with .rollingUpdate}}{{with .paused}}	paused{{end}}{{end}}{{end}}` + "\n" +
+		`{{end}}` + listTemplate,
+}
+
+// OutputTemplatesDir returns the directory of the user-defined output templates,
+// ~/.config/kubectl-kruise/templates unless overridden by KUBECTL_KRUISE_TEMPLATES_DIR.
+func OutputTemplatesDir() string {
+	if dir := os.Getenv(OutputTemplatesDirEnv); len(dir) > 0 {
+		return dir
+	}
+	return filepath.Join(homedir.HomeDir(), ".config", "kubectl-kruise", "templates")
+}
+
+// OutputTemplates returns the names of the built-in and user-defined output templates.
+func OutputTemplates(dir string) []string {
+	names := map[string]bool{}
+	for name := range BuiltinOutputTemplates {
+		names[name] = true
+	}
+	files, _ := ioutil.ReadDir(dir)
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), outputTemplateExt) {
+			names[strings.TrimSuffix(file.Name(), outputTemplateExt)] = true
+		}
+	}
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// OutputTemplate returns the text of the output template name, a NAME.tmpl file of dir or else a
+// built-in template, so that the user can override the built-in templates.
+func OutputTemplate(dir, name string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, name+outputTemplateExt))
+	if err == nil {
+		return string(data), nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	if text, ok := BuiltinOutputTemplates[name]; ok {
+		return text, nil
+	}
+	return "", fmt.Errorf("unknown output template %q, available templates: %s", name, strings.Join(OutputTemplates(dir), ", "))
+}
+
+// resolveOutputTemplate returns the output format with the template it names replaced by its
+// text, or the output format unchanged if it is not a template format or has an inline template.
+func resolveOutputTemplate(dir, output string) (string, error) {
+	for _, format := range []string{"template=", "go-template="} {
+		if !strings.HasPrefix(output, format) {
+			continue
+		}
+		name := strings.TrimPrefix(output, format)
+		if !outputTemplateName.MatchString(name) {
+			return output, nil
+		}
+		text, err := OutputTemplate(dir, name)
+		if err != nil {
+			return "", err
+		}
+		return format + text, nil
+	}
+	return output, nil
+}
+
+// WithOutputTemplates lets cmd and its sub commands that print objects with -o select an output
+// template by name, with -o template=NAME or -o template --template NAME.
+func WithOutputTemplates(cmd *cobra.Command) *cobra.Command {
+	for _, child := range cmd.Commands() {
+		WithOutputTemplates(child)
+	}
+	if cmd.Run == nil || cmd.Flags().Lookup("output") == nil {
+		return cmd
+	}
+
+	run := cmd.Run
+	cmd.Run = func(c *cobra.Command, args []string) {
+		cmdutil.CheckErr(resolveOutputTemplateFlags(c, OutputTemplatesDir()))
+		run(c, args)
+	}
+	return cmd
+}
+
+func resolveOutputTemplateFlags(cmd *cobra.Command, dir string) error {
+	output := cmd.Flags().Lookup("output")
+	resolved, err := resolveOutputTemplate(dir, output.Value.String())
+	if err != nil {
+		return err
+	}
+	if resolved != output.Value.String() {
+		if err := output.Value.Set(resolved); err != nil {
+			return err
+		}
+	}
+
+	template := cmd.Flags().Lookup("template")
+	if template == nil || !outputTemplateName.MatchString(template.Value.String()) {
+		return nil
+	}
+	switch output.Value.String() {
+	case "template", "go-template":
+	default:
+		return nil
+	}
+	// --template can also be the path of a template file
+	if _, err := os.Stat(template.Value.String()); err == nil {
+		return nil
+	}
+	text, err := OutputTemplate(dir, template.Value.String())
+	if err != nil {
+		return err
+	}
+	return template.Value.Set(text)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+func printOutputTemplate(t *testing.T, name string, obj *unstructured.Unstructured) string {
+	printer, err := printers.NewGoTemplatePrinter([]byte(BuiltinOutputTemplates[name]))
+	if err != nil {
+		t.Fatalf("%s: invalid template: %v", name, err)
+	}
+	printer.AllowMissingKeys(true)
+	out := &bytes.Buffer{}
+	if err := printer.PrintObj(obj, out); err != nil {
+		t.Fatalf("%s: unexpected error: %v", name, err)
+	}
+	return out.String()
+}
+
+func TestBuiltinOutputTemplates(t *testing.T) {
+	containers := []interface{}{map[string]interface{}{"name": "nginx", "image": "nginx:1.21"}}
+	cloneSet := newUnstructured("apps.kruise.io/v1alpha1", "CloneSet", "sample", 2,
+		map[string]interface{}{
+			"replicas":       int64(5),
+			"updateStrategy": map[string]interface{}{"partition": int64(3), "paused": true},
+			"template":       map[string]interface{}{"spec": map[string]interface{}{"containers": containers}},
+		},
+		map[string]interface{}{"observedGeneration": int64(2), "updatedReplicas": int64(2), "updatedReadyReplicas": int64(1), "readyReplicas": int64(5), "updateRevision": "sample-7d9f"})
+	statefulSet := newUnstructured("apps.kruise.io/v1beta1", "StatefulSet", "web", 1,
+		map[string]interface{}{
+			"replicas":       int64(3),
+			"updateStrategy": map[string]interface{}{"rollingUpdate": map[string]interface{}{"partition": int64(1)}},
+		}, nil)
+	pod := newUnstructured("v1", "Pod", "sample-abcde", 1,
+		map[string]interface{}{
+			"initContainers": []interface{}{map[string]interface{}{"name": "init", "image": "busybox"}},
+			"containers":     containers,
+		}, nil)
+	list := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      []interface{}{cloneSet.Object, statefulSet.Object},
+	}}
+
+	tests := []struct {
+		template string
+		obj      *unstructured.Unstructured
+		expected string
+	}{
+		{"rollout-summary", cloneSet, "CloneSet/sample\tgeneration 2, observed 2\treplicas 5, updated 2, ready 5\trevision sample-7d9f\n"},
+		{"rollout-summary", statefulSet, "StatefulSet/web\tgeneration 1, observed 0\treplicas 3, updated 0, ready 0\n"},
+		{"image-table", cloneSet, "CloneSet/sample\tnginx\tnginx:1.21\n"},
+		{"image-table", pod, "Pod/sample-abcde\tinit (init)\tbusybox\nPod/sample-abcde\tnginx\tnginx:1.21\n"},
+		{"partition-board", cloneSet, "CloneSet/sample\tpartition 3 of 5\tupdated 2, updated ready 1\tpaused\n"},
+		{"partition-board", list, "CloneSet/sample\tpartition 3 of 5\tupdated 2, updated ready 1\tpaused\nStatefulSet/web\tpartition 1 of 3\tupdated 0, updated ready 0\n"},
+	}
+	for _, test := range tests {
+		if got := printOutputTemplate(t, test.template, test.obj); got != test.expected {
+			t.Errorf("%s of %s: expected %q, got %q", test.template, test.obj.GetName(), test.expected, got)
+		}
+	}
+}
+
+func TestResolveOutputTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "names.tmpl"), []byte("{{.metadata.name}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "image-table.tmpl"), []byte("{{.kind}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"image-table", "names", "partition-board", "rollout-summary"}
+	if got := OutputTemplates(dir); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	tests := []struct {
+		output   string
+		expected string
+		err      bool
+	}{
+		{output: "yaml", expected: "yaml"},
+		{output: "template={{.kind}}", expected: "template={{.kind}}"},
+		{output: "template=names", expected: "template={{.metadata.name}}\n"},
+		{output: "go-template=rollout-summary", expected: "go-template=" + BuiltinOutputTemplates["rollout-summary"]},
+		{output: "template=image-table", expected: "template={{.kind}}\n"},
+		{output: "template=missing", err: true},
+	}
+	for _, test := range tests {
+		got, err := resolveOutputTemplate(dir, test.output)
+		if test.err != (err != nil) {
+			t.Errorf("%s: unexpected error %v", test.output, err)
+		} else if got != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.output, test.expected, got)
+		}
+	}
+}
+
+func TestResolveOutputTemplateFlags(t *testing.T) {
+	printFlags := genericclioptions.NewPrintFlags("")
+	cmd := &cobra.Command{}
+	printFlags.AddFlags(cmd)
+	cmd.Flags().Set("output", "template")
+	cmd.Flags().Set("template", "partition-board")
+
+	if err := resolveOutputTemplateFlags(cmd, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := *printFlags.TemplatePrinterFlags.TemplateArgument; got != BuiltinOutputTemplates["partition-board"] {
+		t.Errorf("expected the partition-board template, got %q", got)
+	}
+	if _, err := printFlags.ToPrinter(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !strings.Contains(cmd.Flags().Lookup("output").Value.String(), "template") {
+		t.Errorf("expected the output format to be left as is")
+	}
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+	coreclient "k8s.io/client-go/kubernetes/typed/core/v1"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/podutils"
+)
+
+const (
+	// PromotionSnapshotsAnnotation holds the snapshots recorded with --snapshot when a resource was
+	// promoted or approved, as a JSON list, the oldest first.
+	PromotionSnapshotsAnnotation = "kubectl.kruise.io/promotion-snapshots"
+
+	// maxPromotionSnapshots bounds the size of the annotation, the oldest snapshots are dropped first.
+	maxPromotionSnapshots = 20
+)
+
+// PromotionSnapshot is the evidence a promotion was decided on: the state of the workload and of
+// its pods, and the values of the metric queries, at the time of the promotion.
+type PromotionSnapshot struct {
+	Time      string `json:"time"`
+	Operation string `json:"operation"`
+	// Step is the approved step of a Rollout, or the released batch of a BatchRelease.
+	Step int32 `json:"step,omitempty"`
+	// Workload is the workload of a Rollout or a BatchRelease.
+	Workload        string            `json:"workload,omitempty"`
+	Revision        string            `json:"revision,omitempty"`
+	Replicas        *int64            `json:"replicas,omitempty"`
+	ReadyReplicas   *int64            `json:"readyReplicas,omitempty"`
+	UpdatedReplicas *int64            `json:"updatedReplicas,omitempty"`
+	Pods            int32             `json:"pods"`
+	ReadyPods       int32             `json:"readyPods"`
+	Restarts        int32             `json:"restarts"`
+	Metrics         map[string]string `json:"metrics,omitempty"`
+}
+
+// PromotionSnapshotOptions are the options of the commands that record a snapshot of the resources
+// they promote.
+type PromotionSnapshotOptions struct {
+	Snapshot      bool
+	PrometheusURL string
+	Metrics       []string
+	Timeout       time.Duration
+
+	Builder    func() *resource.Builder
+	PodClient  coreclient.PodsGetter
+	HTTPClient *http.Client
+	Now        func() time.Time
+}
+
+// AddPromotionSnapshotFlags adds the --snapshot flags of the commands promoting resources.
+func AddPromotionSnapshotFlags(cmd *cobra.Command, o *PromotionSnapshotOptions) {
+	cmd.Flags().BoolVar(&o.Snapshot, "snapshot", o.Snapshot, "If true, record the state of the workload and its pods, and the values of the --snapshot-metric queries, in the "+PromotionSnapshotsAnnotation+" annotation of the promoted resource.")
+	cmd.Flags().StringArrayVar(&o.Metrics, "snapshot-metric", o.Metrics, "A NAME=QUERY PromQL query whose value is recorded in the snapshot, can be repeated.")
+	cmd.Flags().StringVar(&o.PrometheusURL, "prometheus-url", o.PrometheusURL, "The URL of the Prometheus server the --snapshot-metric queries are run against.")
+	cmd.Flags().DurationVar(&o.Timeout, "snapshot-timeout", 30*time.Second, "The time after which a --snapshot-metric query fails, zero means no timeout.")
+}
+
+// Complete sets the clients used to take the snapshots, if any.
+func (o *PromotionSnapshotOptions) Complete(f cmdutil.Factory) error {
+	if !o.Snapshot {
+		return nil
+	}
+	clientset, err := f.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	o.PodClient = clientset.CoreV1()
+	o.Builder = f.NewBuilder
+	o.HTTPClient = http.DefaultClient
+	o.Now = time.Now
+	return nil
+}
+
+// Validate checks the metric queries of the snapshots.
+func (o *PromotionSnapshotOptions) Validate() error {
+	if len(o.Metrics) == 0 {
+		return nil
+	}
+	if !o.Snapshot {
+		return fmt.Errorf("--snapshot-metric requires --snapshot")
+	}
+	if len(o.PrometheusURL) == 0 {
+		return fmt.Errorf("--snapshot-metric requires --prometheus-url")
+	}
+	names := map[string]bool{}
+	for _, metric := range o.Metrics {
+		name, query := splitMetric(metric)
+		if len(name) == 0 || len(query) == 0 {
+			return fmt.Errorf("invalid --snapshot-metric %q, must be NAME=QUERY", metric)
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate --snapshot-metric %q", name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+func splitMetric(metric string) (string, string) {
+	parts := strings.SplitN(metric, "=", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
+
+// Take takes the snapshot of obj before it is promoted. The state of a Rollout or a BatchRelease
+// is the state of its workload.
+func (o *PromotionSnapshotOptions) Take(operation string, obj runtime.Object) (*PromotionSnapshot, error) {
+	snapshot := &PromotionSnapshot{Time: o.Now().UTC().Format(time.RFC3339), Operation: operation}
+
+	workload := obj
+	switch t := obj.(type) {
+	case *kruiserolloutsv1apha1.Rollout:
+		if t.Status.CanaryStatus != nil {
+			snapshot.Step = t.Status.CanaryStatus.CurrentStepIndex
+		}
+		info, err := o.workload(t.Namespace, t.Spec.ObjectRef.WorkloadRef)
+		if err != nil {
+			return nil, err
+		}
+		workload, snapshot.Workload = info.Object, info.ObjectName()
+	case *kruiserolloutsv1apha1.BatchRelease:
+		snapshot.Step = t.Status.CanaryStatus.CurrentBatch
+		info, err := o.workload(t.Namespace, t.Spec.TargetRef.WorkloadRef)
+		if err != nil {
+			return nil, err
+		}
+		workload, snapshot.Workload = info.Object, info.ObjectName()
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(workload)
+	if err != nil {
+		return nil, err
+	}
+	state := ResourceStateFor(&unstructured.Unstructured{Object: content})
+	snapshot.Revision = state.Revision
+	snapshot.Replicas, snapshot.ReadyReplicas, snapshot.UpdatedReplicas = state.Replicas, state.ReadyReplicas, state.UpdatedReplicas
+
+	pods, err := polymorphichelpers.PodsForObject(o.PodClient, workload)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.AddPods(pods)
+
+	for _, metric := range o.Metrics {
+		name, query := splitMetric(metric)
+		values, err := QueryPrometheus(o.HTTPClient, o.PrometheusURL, query, o.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot metric %s: %v", name, err)
+		}
+		if snapshot.Metrics == nil {
+			snapshot.Metrics = map[string]string{}
+		}
+		snapshot.Metrics[name] = strings.Join(values, ",")
+	}
+	return snapshot, nil
+}
+
+// workload returns the workload referenced by a Rollout or a BatchRelease.
+func (o *PromotionSnapshotOptions) workload(namespace string, ref *kruiserolloutsv1apha1.WorkloadRef) (*resource.Info, error) {
+	if ref == nil {
+		return nil, fmt.Errorf("no workload referenced to take a snapshot of")
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(namespace).
+		ResourceTypeOrNameArgs(true, fmt.Sprintf("%s.%s.%s/%s", strings.ToLower(ref.Kind), gv.Version, gv.Group, ref.Name)).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return nil, err
+	}
+	return infos[0], nil
+}
+
+// AddPods adds the number of pods, ready pods and container restarts of pods to the snapshot.
+func (s *PromotionSnapshot) AddPods(pods []corev1.Pod) {
+	for i := range pods {
+		s.Pods++
+		if podutils.IsPodReady(&pods[i]) {
+			s.ReadyPods++
+		}
+		for _, status := range pods[i].Status.ContainerStatuses {
+			s.Restarts += status.RestartCount
+		}
+	}
+}
+
+// Record appends the snapshot to the annotation of the promoted resource of info.
+func (o *PromotionSnapshotOptions) Record(info *resource.Info, snapshot *PromotionSnapshot) error {
+	accessor, err := meta.Accessor(info.Object)
+	if err != nil {
+		return err
+	}
+	value, err := AppendPromotionSnapshot(accessor.GetAnnotations()[PromotionSnapshotsAnnotation], snapshot)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{PromotionSnapshotsAnnotation: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	obj, err := resource.NewHelper(info.Client, info.Mapping).Patch(info.Namespace, info.Name, types.MergePatchType, patch, nil)
+	if err != nil {
+		return err
+	}
+	return info.Refresh(obj, true)
+}
+
+// AppendPromotionSnapshot appends snapshot to the snapshots of the annotation value, dropping the
+// oldest snapshots beyond the maximum kept.
+func AppendPromotionSnapshot(value string, snapshot *PromotionSnapshot) (string, error) {
+	var snapshots []PromotionSnapshot
+	if len(value) > 0 {
+		if err := json.Unmarshal([]byte(value), &snapshots); err != nil {
+			return "", fmt.Errorf("invalid %s annotation: %v", PromotionSnapshotsAnnotation, err)
+		}
+	}
+	snapshots = append(snapshots, *snapshot)
+	if len(snapshots) > maxPromotionSnapshots {
+		snapshots = snapshots[len(snapshots)-maxPromotionSnapshots:]
+	}
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// QueryPrometheus runs the PromQL query against the Prometheus server at address, and returns the
+// values of the samples of the vector or the scalar it returns.
+func QueryPrometheus(client *http.Client, address, query string, timeout time.Duration) ([]string, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	endpoint := strings.TrimSuffix(address, "/") + "/api/v1/query?" + url.Values{"query": {query}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid response of %s: %v", address, err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("query failed: %s", result.Error)
+	}
+
+	var values []string
+	switch result.Data.ResultType {
+	case "vector":
+		var samples []struct {
+			Value []interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(result.Data.Result, &samples); err != nil {
+			return nil, err
+		}
+		for _, sample := range samples {
+			if len(sample.Value) == 2 {
+				values = append(values, fmt.Sprint(sample.Value[1]))
+			}
+		}
+	case "scalar":
+		var sample []interface{}
+		if err := json.Unmarshal(result.Data.Result, &sample); err != nil {
+			return nil, err
+		}
+		if len(sample) == 2 {
+			values = append(values, fmt.Sprint(sample[1]))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported result type %q, the query must return a vector or a scalar", result.Data.ResultType)
+	}
+	return values, nil
+}
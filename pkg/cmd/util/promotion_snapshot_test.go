@@ -0,0 +1,148 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newSnapshotPod(name string, ready bool, restarts int32) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "demo"}},
+		Status: corev1.PodStatus{
+			Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: restarts}},
+		},
+	}
+}
+
+func TestTakePromotionSnapshot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("query") {
+		case "errors":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1650000000,"0.002"]}]}}`)
+		default:
+			fmt.Fprint(w, `{"status":"error","error":"parse error"}`)
+		}
+	}))
+	defer server.Close()
+
+	replicas := int32(3)
+	cs := &kruiseappsv1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"},
+		Spec: kruiseappsv1alpha1.CloneSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+		},
+		Status: kruiseappsv1alpha1.CloneSetStatus{ReadyReplicas: 2, UpdatedReplicas: 1, UpdateRevision: "demo-v2"},
+	}
+	o := &PromotionSnapshotOptions{
+		Snapshot:      true,
+		PrometheusURL: server.URL,
+		Metrics:       []string{"errors=errors"},
+		PodClient:     fake.NewSimpleClientset(newSnapshotPod("demo-1", true, 0), newSnapshotPod("demo-2", true, 2), newSnapshotPod("demo-3", false, 5)).CoreV1(),
+		HTTPClient:    server.Client(),
+		Now:           func() time.Time { return time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC) },
+	}
+	snapshot, err := o.Take("promote", cs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	three, two, one := int64(3), int64(2), int64(1)
+	expected := &PromotionSnapshot{
+		Time:            "2024-05-01T10:00:00Z",
+		Operation:       "promote",
+		Revision:        "demo-v2",
+		Replicas:        &three,
+		ReadyReplicas:   &two,
+		UpdatedReplicas: &one,
+		Pods:            3,
+		ReadyPods:       2,
+		Restarts:        7,
+		Metrics:         map[string]string{"errors": "0.002"},
+	}
+	if !reflect.DeepEqual(snapshot, expected) {
+		t.Errorf("expected %+v, got %+v", expected, snapshot)
+	}
+
+	o.Metrics = []string{"latency=malformed"}
+	if _, err := o.Take("promote", cs); err == nil || err.Error() != "snapshot metric latency: query failed: parse error" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAppendPromotionSnapshot(t *testing.T) {
+	value := ""
+	for i := 0; i < maxPromotionSnapshots+2; i++ {
+		var err error
+		value, err = AppendPromotionSnapshot(value, &PromotionSnapshot{Operation: "approve", Step: int32(i)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	var snapshots []PromotionSnapshot
+	if err := json.Unmarshal([]byte(value), &snapshots); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snapshots) != maxPromotionSnapshots || snapshots[0].Step != 2 || snapshots[maxPromotionSnapshots-1].Step != maxPromotionSnapshots+1 {
+		t.Errorf("expected the last %d snapshots, got %+v", maxPromotionSnapshots, snapshots)
+	}
+
+	if _, err := AppendPromotionSnapshot("{", &PromotionSnapshot{}); err == nil {
+		t.Errorf("expected an error for an invalid annotation")
+	}
+}
+
+func TestValidatePromotionSnapshot(t *testing.T) {
+	tests := []struct {
+		options  PromotionSnapshotOptions
+		expected string
+	}{
+		{options: PromotionSnapshotOptions{Snapshot: true}},
+		{options: PromotionSnapshotOptions{Metrics: []string{"errors=up"}, PrometheusURL: "http://prometheus"}, expected: "--snapshot-metric requires --snapshot"},
+		{options: PromotionSnapshotOptions{Snapshot: true, Metrics: []string{"errors=up"}}, expected: "--snapshot-metric requires --prometheus-url"},
+		{options: PromotionSnapshotOptions{Snapshot: true, Metrics: []string{"up"}, PrometheusURL: "http://prometheus"}, expected: `invalid --snapshot-metric "up", must be NAME=QUERY`},
+		{options: PromotionSnapshotOptions{Snapshot: true, Metrics: []string{"a=up", "a=down"}, PrometheusURL: "http://prometheus"}, expected: `duplicate --snapshot-metric "a"`},
+	}
+	for i, test := range tests {
+		err := test.options.Validate()
+		if len(test.expected) == 0 {
+			if err != nil {
+				t.Errorf("%d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%d: expected error %q, got %v", i, test.expected, err)
+		}
+	}
+}
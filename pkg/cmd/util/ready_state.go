@@ -0,0 +1,304 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	waitReadyFlag        = "wait-ready"
+	waitReadyTimeoutFlag = "wait-ready-timeout"
+	outputStateFlag      = "output-state"
+
+	readyStatePollInterval = 2 * time.Second
+)
+
+// ErrReadyTimeout is returned by ReadyStates when the resources are not ready in time.
+var ErrReadyTimeout = errors.New("timed out waiting for the resources to be ready")
+
+// ResourceState is the state of a resource printed by --output-state. It only holds fields that
+// do not change once the resource converged, so that the same invocation prints the same state.
+type ResourceState struct {
+	APIVersion         string `json:"apiVersion"`
+	Kind               string `json:"kind"`
+	Namespace          string `json:"namespace,omitempty"`
+	Name               string `json:"name"`
+	Generation         int64  `json:"generation"`
+	ObservedGeneration int64  `json:"observedGeneration"`
+	Replicas           *int64 `json:"replicas,omitempty"`
+	ReadyReplicas      *int64 `json:"readyReplicas,omitempty"`
+	UpdatedReplicas    *int64 `json:"updatedReplicas,omitempty"`
+	Revision           string `json:"revision,omitempty"`
+	Ready              bool   `json:"ready"`
+}
+
+// ReadyStateStreams are the streams of the commands wrapped by WithReadyState. With --output-state,
+// what the commands print to the output is printed to the error output instead, so that the
+// output only holds the state.
+type ReadyStateStreams struct {
+	genericclioptions.IOStreams
+
+	original genericclioptions.IOStreams
+	out      *switchWriter
+}
+
+// NewReadyStateStreams returns the ReadyStateStreams that wrap streams.
+func NewReadyStateStreams(streams genericclioptions.IOStreams) *ReadyStateStreams {
+	out := &switchWriter{Writer: streams.Out}
+	return &ReadyStateStreams{
+		IOStreams: genericclioptions.IOStreams{In: streams.In, Out: out, ErrOut: streams.ErrOut},
+		original:  streams,
+		out:       out,
+	}
+}
+
+type switchWriter struct {
+	io.Writer
+}
+
+// WithReadyState adds the --wait-ready and --output-state flags to cmd if it takes resources with
+// --filename, or else to its sub commands that do. After the command succeeded, --wait-ready waits until
+// the controllers observed the changes and the resources are ready, and --output-state=json
+// prints the final state of the resources, so that infrastructure-as-code tools can treat the
+// invocations as convergent resources. cmd must have been created with the given streams.
+func WithReadyState(f cmdutil.Factory, cmd *cobra.Command, streams *ReadyStateStreams) *cobra.Command {
+	if cmd.Run == nil || cmd.Flags().Lookup("filename") == nil {
+		for _, child := range cmd.Commands() {
+			WithReadyState(f, child, streams)
+		}
+		return cmd
+	}
+
+	cmd.Flags().Bool(waitReadyFlag, false, "If true, wait until the changed resources are observed by their controllers and ready.")
+	cmd.Flags().Duration(waitReadyTimeoutFlag, 5*time.Minute, "The time to wait for with --wait-ready.")
+	cmd.Flags().String(outputStateFlag, "", "If set to json, print the final state of the changed resources, such as their observed generation and ready replicas, instead of the usual output.")
+
+	run := cmd.Run
+	cmd.Run = func(c *cobra.Command, args []string) {
+		waitReady := cmdutil.GetFlagBool(c, waitReadyFlag)
+		outputState := cmdutil.GetFlagString(c, outputStateFlag)
+		if !waitReady && len(outputState) == 0 {
+			run(c, args)
+			return
+		}
+		cmdutil.CheckErr(validateReadyState(c, outputState))
+
+		// the objects of "-" are read again once the command consumed stdin
+		stdin, err := bufferFilenameStdin(c)
+		cmdutil.CheckErr(err)
+		if len(outputState) > 0 {
+			streams.out.Writer = streams.original.ErrOut
+		}
+		run(c, args)
+		streams.out.Writer = streams.original.Out
+
+		infos, err := readyStateInfos(f, c, args, stdin)
+		cmdutil.CheckErr(err)
+		states, err := ReadyStates(infos, waitReady, cmdutil.GetFlagDuration(c, waitReadyTimeoutFlag))
+		if len(outputState) > 0 {
+			cmdutil.CheckErr(PrintReadyStates(streams.original.Out, states))
+		}
+		cmdutil.CheckErr(err)
+	}
+	return cmd
+}
+
+func validateReadyState(cmd *cobra.Command, outputState string) error {
+	if len(outputState) > 0 && outputState != "json" {
+		return fmt.Errorf("invalid --%s %q, only json is supported", outputStateFlag, outputState)
+	}
+	if flag := cmd.Flags().Lookup("local"); flag != nil && flag.Value.String() == "true" {
+		return fmt.Errorf("--%s and --%s can not be used with --local", waitReadyFlag, outputStateFlag)
+	}
+	if flag := cmd.Flags().Lookup("dry-run"); flag != nil && flag.Value.String() != "none" && flag.Value.String() != "false" {
+		return fmt.Errorf("--%s and --%s can not be used with --dry-run", waitReadyFlag, outputStateFlag)
+	}
+	return nil
+}
+
+// readyStateInfos returns the resources given to cmd by files, selector or arguments. If stdin is
+// not nil, it holds the objects of "-", already read with BufferStdin.
+func readyStateInfos(f cmdutil.Factory, cmd *cobra.Command, args []string, stdin []byte) ([]*resource.Info, error) {
+	namespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, err
+	}
+	options := &resource.FilenameOptions{
+		Filenames: cmdutil.GetFlagStringSlice(cmd, "filename"),
+		Recursive: cmd.Flags().Lookup("recursive") != nil && cmdutil.GetFlagBool(cmd, "recursive"),
+	}
+	if cmd.Flags().Lookup("kustomize") != nil {
+		options.Kustomize = cmdutil.GetFlagString(cmd, "kustomize")
+	}
+	builder := FilenameParam(f.NewBuilder().
+		Unstructured().
+		NamespaceParam(namespace).DefaultNamespace(), enforceNamespace, options, stdin).
+		Flatten()
+	if cmd.Flags().Lookup("selector") != nil {
+		builder = builder.LabelSelectorParam(cmdutil.GetFlagString(cmd, "selector"))
+	}
+	if cmd.Flags().Lookup("field-selector") != nil {
+		builder = builder.FieldSelectorParam(cmdutil.GetFlagString(cmd, "field-selector"))
+	}
+	if cmd.Flags().Lookup("all") != nil {
+		builder = builder.SelectAllParam(cmdutil.GetFlagBool(cmd, "all"))
+	}
+	return builder.ResourceTypeOrNameArgs(true, readyStateResourceArgs(args)...).Do().Infos()
+}
+
+// readyStateResourceArgs returns the leading args that name resources, either TYPE/NAME args
+// or TYPE followed by a single NAME, dropping the values that follow them in set commands.
+func readyStateResourceArgs(args []string) []string {
+	args = resourceArgs(args)
+	if len(args) == 0 || !strings.Contains(args[0], "/") {
+		if len(args) > 2 {
+			return args[:2]
+		}
+		return args
+	}
+	for i, arg := range args {
+		if !strings.Contains(arg, "/") {
+			return args[:i]
+		}
+	}
+	return args
+}
+
+// ReadyStates returns the states of the resources of infos, sorted by kind, namespace and name.
+// If waitReady is true, they are polled until all of them are ready or the timeout expired.
+func ReadyStates(infos []*resource.Info, waitReady bool, timeout time.Duration) ([]ResourceState, error) {
+	var states []ResourceState
+	poll := func() (bool, error) {
+		states = nil
+		ready := true
+		for _, info := range infos {
+			obj, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+			if err != nil {
+				return false, err
+			}
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return false, fmt.Errorf("unexpected object %T of %s", obj, info.ObjectName())
+			}
+			state := ResourceStateFor(u)
+			states = append(states, state)
+			ready = ready && state.Ready
+		}
+		sortResourceStates(states)
+		return ready, nil
+	}
+	if !waitReady {
+		_, err := poll()
+		return states, err
+	}
+	if err := wait.PollImmediate(readyStatePollInterval, timeout, poll); err != nil {
+		if err == wait.ErrWaitTimeout {
+			return states, fmt.Errorf("%w after %s", ErrReadyTimeout, timeout)
+		}
+		return states, err
+	}
+	return states, nil
+}
+
+// ResourceStateFor returns the state of obj. Workloads supported by 'rollout status' are ready
+// when their roll out is complete, other resources when their controller observed their
+// generation and their ready replicas, if any, reached their replicas.
+func ResourceStateFor(obj *unstructured.Unstructured) ResourceState {
+	state := ResourceState{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		Generation: obj.GetGeneration(),
+	}
+	observedGeneration, hasObservedGeneration, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	state.ObservedGeneration = observedGeneration
+	state.Replicas = nestedInt64(obj, "spec", "replicas")
+	state.ReadyReplicas = nestedInt64(obj, "status", "readyReplicas")
+	state.UpdatedReplicas = nestedInt64(obj, "status", "updatedReplicas")
+	if state.Replicas == nil {
+		// DaemonSets have no replicas, but the number of nodes they are scheduled on
+		state.Replicas = nestedInt64(obj, "status", "desiredNumberScheduled")
+		state.ReadyReplicas = nestedInt64(obj, "status", "numberReady")
+		state.UpdatedReplicas = nestedInt64(obj, "status", "updatedNumberScheduled")
+	}
+
+	state.Revision, _, _ = unstructured.NestedString(obj.Object, "status", "updateRevision")
+	if len(state.Revision) == 0 {
+		state.Revision = obj.GetAnnotations()["deployment.kubernetes.io/revision"]
+	}
+
+	if viewer, err := polymorphichelpers.StatusViewerFor(obj.GroupVersionKind().GroupKind()); err == nil {
+		_, done, err := viewer.Status(obj, 0)
+		state.Ready = err == nil && done
+		return state
+	}
+	state.Ready = !hasObservedGeneration || observedGeneration >= state.Generation
+	if state.Replicas != nil {
+		state.Ready = state.Ready && state.ReadyReplicas != nil && *state.ReadyReplicas >= *state.Replicas
+	}
+	return state
+}
+
+func nestedInt64(obj *unstructured.Unstructured, fields ...string) *int64 {
+	value, found, err := unstructured.NestedInt64(obj.Object, fields...)
+	if !found || err != nil {
+		return nil
+	}
+	return &value
+}
+
+func sortResourceStates(states []ResourceState) {
+	sort.Slice(states, func(i, j int) bool {
+		a, b := states[i], states[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+}
+
+// PrintReadyStates prints states as a JSON object with a resources list.
+func PrintReadyStates(out io.Writer, states []ResourceState) error {
+	if states == nil {
+		states = []ResourceState{}
+	}
+	data, err := json.MarshalIndent(map[string][]ResourceState{"resources": states}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+func newUnstructured(apiVersion, kind, name string, generation int64, spec, status map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default", "generation": generation},
+	}}
+	if spec != nil {
+		obj.Object["spec"] = spec
+	}
+	if status != nil {
+		obj.Object["status"] = status
+	}
+	return obj
+}
+
+func TestResourceStateFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      *unstructured.Unstructured
+		expected bool
+	}{
+		{
+			name: "cloneset ready",
+			obj: newUnstructured("apps.kruise.io/v1alpha1", "CloneSet", "demo", 2,
+				map[string]interface{}{"replicas": int64(3)},
+				map[string]interface{}{"observedGeneration": int64(2), "readyReplicas": int64(3), "updatedReplicas": int64(3)}),
+			expected: true,
+		},
+		{
+			name: "cloneset generation not observed",
+			obj: newUnstructured("apps.kruise.io/v1alpha1", "CloneSet", "demo", 3,
+				map[string]interface{}{"replicas": int64(3)},
+				map[string]interface{}{"observedGeneration": int64(2), "readyReplicas": int64(3), "updatedReplicas": int64(3)}),
+			expected: false,
+		},
+		{
+			name: "custom workload not ready",
+			obj: newUnstructured("example.com/v1", "Workload", "demo", 1,
+				map[string]interface{}{"replicas": int64(3)},
+				map[string]interface{}{"observedGeneration": int64(1), "readyReplicas": int64(2)}),
+			expected: false,
+		},
+		{
+			name: "custom workload ready",
+			obj: newUnstructured("example.com/v1", "Workload", "demo", 1,
+				map[string]interface{}{"replicas": int64(3)},
+				map[string]interface{}{"observedGeneration": int64(1), "readyReplicas": int64(3)}),
+			expected: true,
+		},
+		{
+			name:     "configmap",
+			obj:      newUnstructured("v1", "ConfigMap", "demo", 0, nil, nil),
+			expected: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if state := ResourceStateFor(test.obj); state.Ready != test.expected {
+				t.Errorf("expected ready %t, got %+v", test.expected, state)
+			}
+		})
+	}
+}
+
+func TestReadyStateResourceArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{args: []string{"cloneset", "demo", "nginx=nginx:1.21"}, expected: []string{"cloneset", "demo"}},
+		{args: []string{"cloneset", "demo", "3"}, expected: []string{"cloneset", "demo"}},
+		{args: []string{"cloneset/a", "cloneset/b", "sa"}, expected: []string{"cloneset/a", "cloneset/b"}},
+		{args: []string{"app=nginx"}, expected: []string{}},
+	}
+	for _, test := range tests {
+		if got := readyStateResourceArgs(test.args); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("expected %q for %q, got %q", test.expected, test.args, got)
+		}
+	}
+}
+
+func TestPrintReadyStates(t *testing.T) {
+	replicas := int64(3)
+	states := []ResourceState{
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "b", Ready: true},
+		{APIVersion: "apps.kruise.io/v1alpha1", Kind: "CloneSet", Namespace: "default", Name: "a", Generation: 2, ObservedGeneration: 2, Replicas: &replicas, ReadyReplicas: &replicas, Ready: true},
+	}
+	sortResourceStates(states)
+
+	out := &bytes.Buffer{}
+	if err := PrintReadyStates(out, states); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "resources": [
+    {
+      "apiVersion": "apps.kruise.io/v1alpha1",
+      "kind": "CloneSet",
+      "namespace": "default",
+      "name": "a",
+      "generation": 2,
+      "observedGeneration": 2,
+      "replicas": 3,
+      "readyReplicas": 3,
+      "ready": true
+    },
+    {
+      "apiVersion": "apps/v1",
+      "kind": "Deployment",
+      "namespace": "default",
+      "name": "b",
+      "generation": 0,
+      "observedGeneration": 0,
+      "ready": true
+    }
+  ]
+}
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestWithReadyStateStdin(t *testing.T) {
+	const manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: demo\n  namespace: test\n"
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	defer tf.Cleanup()
+	tf.UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodGet || req.URL.Path != "/namespaces/test/configmaps/demo" {
+				t.Errorf("unexpected request %s %s", req.Method, req.URL)
+			}
+			body := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"demo","namespace":"test"}}`
+			return &http.Response{StatusCode: http.StatusOK, Header: cmdtesting.DefaultHeader(), Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+		}),
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(manifest)
+	w.Close()
+	original := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = original }()
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	readyStreams := NewReadyStateStreams(streams)
+	var read []byte
+	cmd := &cobra.Command{
+		Use: "apply",
+		Run: func(cmd *cobra.Command, args []string) {
+			read, _ = ioutil.ReadAll(os.Stdin)
+		},
+	}
+	cmdutil.AddFilenameOptionFlags(cmd, &resource.FilenameOptions{}, "")
+	WithReadyState(tf, cmd, readyStreams)
+	cmd.Flags().Set("filename", "-")
+	cmd.Flags().Set(outputStateFlag, "json")
+	cmd.Run(cmd, nil)
+
+	if string(read) != manifest {
+		t.Errorf("expected the command to read the manifest, got %q", read)
+	}
+	if !strings.Contains(out.String(), `"name": "demo"`) || !strings.Contains(out.String(), `"ready": true`) {
+		t.Errorf("expected the state of the configmap of stdin, got %s", out.String())
+	}
+}
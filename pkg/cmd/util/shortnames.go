@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// KruiseShortNames are the short names of the Kruise resources, accepted by all commands even if
+// the CRDs of the cluster do not declare them.
+var KruiseShortNames = map[string]schema.GroupResource{
+	"clone": {Group: "apps.kruise.io", Resource: "clonesets"},
+	"asts":  {Group: "apps.kruise.io", Resource: "statefulsets"},
+	"ads":   {Group: "apps.kruise.io", Resource: "daemonsets"},
+	"scs":   {Group: "apps.kruise.io", Resource: "sidecarsets"},
+	"ud":    {Group: "apps.kruise.io", Resource: "uniteddeployments"},
+	"ws":    {Group: "apps.kruise.io", Resource: "workloadspreads"},
+	"crr":   {Group: "apps.kruise.io", Resource: "containerrecreaterequests"},
+	"ipj":   {Group: "apps.kruise.io", Resource: "imagepulljobs"},
+	"bcj":   {Group: "apps.kruise.io", Resource: "broadcastjobs"},
+	"acj":   {Group: "apps.kruise.io", Resource: "advancedcronjobs"},
+}
+
+// kruiseShortNamesClientGetter returns REST mappers that know the Kruise short names.
+type kruiseShortNamesClientGetter struct {
+	genericclioptions.RESTClientGetter
+}
+
+// WithKruiseShortNames returns a RESTClientGetter whose REST mapper expands the Kruise short names
+// before the ones discovered from the cluster, so that e.g. asts is the Advanced StatefulSets
+// whatever the CRDs declare.
+func WithKruiseShortNames(getter genericclioptions.RESTClientGetter) genericclioptions.RESTClientGetter {
+	return &kruiseShortNamesClientGetter{RESTClientGetter: getter}
+}
+
+// ToRESTMapper implements RESTClientGetter
+func (g *kruiseShortNamesClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	mapper, err := g.RESTClientGetter.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+	return &kruiseShortNamesMapper{RESTMapper: mapper}, nil
+}
+
+// kruiseShortNamesMapper is a RESTMapper that expands the Kruise short names.
+type kruiseShortNamesMapper struct {
+	meta.RESTMapper
+}
+
+func (m *kruiseShortNamesMapper) KindFor(resource schema.GroupVersionResource) (schema.GroupVersionKind, error) {
+	return m.RESTMapper.KindFor(expandKruiseShortName(resource))
+}
+
+func (m *kruiseShortNamesMapper) KindsFor(resource schema.GroupVersionResource) ([]schema.GroupVersionKind, error) {
+	return m.RESTMapper.KindsFor(expandKruiseShortName(resource))
+}
+
+func (m *kruiseShortNamesMapper) ResourceFor(input schema.GroupVersionResource) (schema.GroupVersionResource, error) {
+	return m.RESTMapper.ResourceFor(expandKruiseShortName(input))
+}
+
+func (m *kruiseShortNamesMapper) ResourcesFor(input schema.GroupVersionResource) ([]schema.GroupVersionResource, error) {
+	return m.RESTMapper.ResourcesFor(expandKruiseShortName(input))
+}
+
+func (m *kruiseShortNamesMapper) ResourceSingularizer(resource string) (string, error) {
+	if gr, ok := KruiseShortNames[resource]; ok {
+		resource = gr.Resource
+	}
+	return m.RESTMapper.ResourceSingularizer(resource)
+}
+
+// expandKruiseShortName returns the Kruise resource of a short name given without group and version.
+func expandKruiseShortName(resource schema.GroupVersionResource) schema.GroupVersionResource {
+	if len(resource.Group) > 0 || len(resource.Version) > 0 {
+		return resource
+	}
+	if gr, ok := KruiseShortNames[resource.Resource]; ok {
+		return gr.WithVersion("")
+	}
+	return resource
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestKruiseShortNamesMapper(t *testing.T) {
+	kruise := schema.GroupVersion{Group: "apps.kruise.io", Version: "v1beta1"}
+	apps := schema.GroupVersion{Group: "apps", Version: "v1"}
+	static := meta.NewDefaultRESTMapper([]schema.GroupVersion{apps, kruise})
+	static.Add(apps.WithKind("StatefulSet"), meta.RESTScopeNamespace)
+	static.Add(apps.WithKind("Deployment"), meta.RESTScopeNamespace)
+	static.Add(kruise.WithKind("StatefulSet"), meta.RESTScopeNamespace)
+	static.Add(schema.GroupVersion{Group: "apps.kruise.io", Version: "v1alpha1"}.WithKind("CloneSet"), meta.RESTScopeNamespace)
+	mapper := &kruiseShortNamesMapper{RESTMapper: static}
+
+	tests := []struct {
+		resource string
+		expected schema.GroupKind
+	}{
+		{"asts", schema.GroupKind{Group: "apps.kruise.io", Kind: "StatefulSet"}},
+		{"clone", schema.GroupKind{Group: "apps.kruise.io", Kind: "CloneSet"}},
+		{"deployments", schema.GroupKind{Group: "apps", Kind: "Deployment"}},
+	}
+	for _, test := range tests {
+		gvk, err := mapper.KindFor(schema.GroupVersionResource{Resource: test.resource})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.resource, err)
+		} else if gvk.GroupKind() != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.resource, test.expected, gvk.GroupKind())
+		}
+	}
+
+	// a short name with a group is not a Kruise one
+	if _, err := mapper.KindFor(schema.GroupVersionResource{Group: "apps", Resource: "asts"}); err == nil {
+		t.Errorf("expected asts.apps not to be expanded")
+	}
+	if singular, err := mapper.ResourceSingularizer("asts"); err != nil || singular != "statefulset" {
+		t.Errorf("expected the singular of asts to be statefulset, got %q, %v", singular, err)
+	}
+}
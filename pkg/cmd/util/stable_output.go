@@ -0,0 +1,188 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	showManagedFieldsFlag = "show-managed-fields"
+	showStatusFlag        = "show-status"
+	sortByFlag            = "sort-by"
+)
+
+// StableOutputWriter normalizes the objects printed with -o yaml or -o json by the commands
+// wrapped by WithStableOutput, so that printing the same objects twice gives the same output:
+// the fields are sorted, the managed fields, and the status printed as YAML, are removed unless
+// asked for, and the items of lists are sorted by kind, namespace and name unless they are sorted
+// with --sort-by. The printers of kubectl write each
+// object at once, so the writes that are not a whole object, such as the separators of YAML
+// documents, are written unchanged.
+type StableOutputWriter struct {
+	io.Writer
+
+	format            string
+	showManagedFields bool
+	showStatus        bool
+	sortItems         bool
+}
+
+// NewStableOutputWriter returns the StableOutputWriter that writes to out.
+func NewStableOutputWriter(out io.Writer) *StableOutputWriter {
+	return &StableOutputWriter{Writer: out}
+}
+
+func (w *StableOutputWriter) Write(p []byte) (int, error) {
+	if len(w.format) == 0 {
+		return w.Writer.Write(p)
+	}
+	normalized, err := NormalizeOutput(p, w.format, w.showManagedFields, w.showStatus, w.sortItems)
+	if err != nil {
+		return w.Writer.Write(p)
+	}
+	if _, err := w.Writer.Write(normalized); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WithStableOutput adds the --show-managed-fields and --show-status flags to cmd and its sub
+// commands that print objects with -o, and normalizes what they print with -o yaml or -o json
+// to out. cmd must have been created with out as its output.
+func WithStableOutput(cmd *cobra.Command, out *StableOutputWriter) *cobra.Command {
+	for _, child := range cmd.Commands() {
+		WithStableOutput(child, out)
+	}
+	if cmd.Run == nil || cmd.Flags().Lookup("output") == nil {
+		return cmd
+	}
+
+	if cmd.Flags().Lookup(showManagedFieldsFlag) == nil {
+		cmd.Flags().Bool(showManagedFieldsFlag, false, "If true, keep the managedFields of the objects printed with -o yaml or -o json.")
+	}
+	if cmd.Flags().Lookup(showStatusFlag) == nil {
+		cmd.Flags().Bool(showStatusFlag, false, "If true, keep the status of the objects printed with -o yaml. The objects printed with -o json keep it anyway.")
+	}
+	run := cmd.Run
+	cmd.Run = func(c *cobra.Command, args []string) {
+		switch format := cmdutil.GetFlagString(c, "output"); format {
+		case "yaml", "json":
+			out.format = format
+			out.showManagedFields = cmdutil.GetFlagBool(c, showManagedFieldsFlag)
+			// scripts read the status from JSON, only YAML meant for Git drops it by default
+			out.showStatus = format == "json" || cmdutil.GetFlagBool(c, showStatusFlag)
+			// do not undo the order asked for with --sort-by
+			out.sortItems = c.Flags().Lookup(sortByFlag) == nil || len(cmdutil.GetFlagString(c, sortByFlag)) == 0
+			defer func() { out.format = "" }()
+		}
+		run(c, args)
+	}
+	return cmd
+}
+
+// NormalizeOutput returns the object, list or watch event printed as data in format, yaml or
+// json, normalized. The items of lists are sorted if sortItems is true. It returns an error if
+// data is not a whole object.
+func NormalizeOutput(data []byte, format string, showManagedFields, showStatus, sortItems bool) ([]byte, error) {
+	var obj map[string]interface{}
+	switch format {
+	case "yaml":
+		if err := yaml.Unmarshal(data, &obj); err != nil {
+			return nil, err
+		}
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&obj); err != nil || decoder.More() {
+			return nil, fmt.Errorf("not a single object")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported output format %q", format)
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("not an object")
+	}
+
+	if event, ok := obj["object"].(map[string]interface{}); ok && obj["type"] != nil {
+		normalizeObject(event, showManagedFields, showStatus, sortItems)
+	} else if _, ok := obj["kind"]; ok {
+		normalizeObject(obj, showManagedFields, showStatus, sortItems)
+	} else {
+		return nil, fmt.Errorf("not an object")
+	}
+
+	if format == "yaml" {
+		return yaml.Marshal(obj)
+	}
+	// the JSON printer of kubectl indents the objects with 4 spaces, but not the watch events
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if bytes.Contains(data, []byte("\n    ")) {
+		encoder.SetIndent("", "    ")
+	}
+	if err := encoder.Encode(obj); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// normalizeObject removes the managed fields and status of obj, or of its items if it is a
+// list, and sorts its items if sortItems is true.
+func normalizeObject(obj map[string]interface{}, showManagedFields, showStatus, sortItems bool) {
+	if items, ok := obj["items"].([]interface{}); ok {
+		for _, item := range items {
+			if item, ok := item.(map[string]interface{}); ok {
+				normalizeObject(item, showManagedFields, showStatus, sortItems)
+			}
+		}
+		if !sortItems {
+			return
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return itemKey(items[i]) < itemKey(items[j])
+		})
+		return
+	}
+	if !showManagedFields {
+		unstructured.RemoveNestedField(obj, "metadata", "managedFields")
+	}
+	if !showStatus {
+		delete(obj, "status")
+	}
+}
+
+func itemKey(item interface{}) string {
+	obj, ok := item.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	kind, _, _ := unstructured.NestedString(obj, "kind")
+	namespace, _, _ := unstructured.NestedString(obj, "metadata", "namespace")
+	name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+	return kind + "/" + namespace + "/" + name
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/printers"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const cloneSetYAML = `apiVersion: apps.kruise.io/v1alpha1
+kind: CloneSet
+metadata:
+  managedFields:
+  - manager: kubectl
+  name: demo
+spec:
+  replicas: 3
+status:
+  readyReplicas: 2
+`
+
+func TestNormalizeOutput(t *testing.T) {
+	tests := []struct {
+		name              string
+		data              string
+		format            string
+		showManagedFields bool
+		showStatus        bool
+		keepOrder         bool
+		expected          string
+		expectErr         bool
+	}{
+		{
+			name:     "yaml object",
+			data:     cloneSetYAML,
+			format:   "yaml",
+			expected: "apiVersion: apps.kruise.io/v1alpha1\nkind: CloneSet\nmetadata:\n  name: demo\nspec:\n  replicas: 3\n",
+		},
+		{
+			name:              "yaml object with managed fields and status",
+			data:              cloneSetYAML,
+			format:            "yaml",
+			showManagedFields: true,
+			showStatus:        true,
+			expected:          cloneSetYAML,
+		},
+		{
+			name:     "yaml list",
+			data:     "apiVersion: v1\nkind: List\nitems:\n- kind: Pod\n  metadata: {name: b, namespace: x}\n  status: {phase: Running}\n- kind: Pod\n  metadata: {name: a, namespace: z}\n- kind: Pod\n  metadata: {name: c, namespace: x}\n",
+			format:   "yaml",
+			expected: "apiVersion: v1\nitems:\n- kind: Pod\n  metadata:\n    name: b\n    namespace: x\n- kind: Pod\n  metadata:\n    name: c\n    namespace: x\n- kind: Pod\n  metadata:\n    name: a\n    namespace: z\nkind: List\n",
+		},
+		{
+			name:      "yaml list sorted by the command",
+			data:      "apiVersion: v1\nkind: List\nitems:\n- kind: Pod\n  metadata: {name: b}\n- kind: Pod\n  metadata: {name: a}\n",
+			format:    "yaml",
+			keepOrder: true,
+			expected:  "apiVersion: v1\nitems:\n- kind: Pod\n  metadata:\n    name: b\n- kind: Pod\n  metadata:\n    name: a\nkind: List\n",
+		},
+		{
+			name:     "json object",
+			data:     "{\n    \"kind\": \"CloneSet\",\n    \"apiVersion\": \"apps.kruise.io/v1alpha1\",\n    \"spec\": {\"replicas\": 3, \"minReadySeconds\": 10},\n    \"status\": {}\n}\n",
+			format:   "json",
+			expected: "{\n    \"apiVersion\": \"apps.kruise.io/v1alpha1\",\n    \"kind\": \"CloneSet\",\n    \"spec\": {\n        \"minReadySeconds\": 10,\n        \"replicas\": 3\n    }\n}\n",
+		},
+		{
+			name:     "json watch event",
+			data:     `{"type":"MODIFIED","object":{"kind":"Pod","metadata":{"name":"a"},"status":{"phase":"Running"}}}` + "\n",
+			format:   "json",
+			expected: `{"object":{"kind":"Pod","metadata":{"name":"a"}},"type":"MODIFIED"}` + "\n",
+		},
+		{name: "yaml separator", data: "---\n", format: "yaml", expectErr: true},
+		{name: "yaml text", data: "cloneset.apps.kruise.io/demo created\n", format: "yaml", expectErr: true},
+		{name: "json without kind", data: `{"resources": []}`, format: "json", expectErr: true},
+	}
+	for _, test := range tests {
+		got, err := NormalizeOutput([]byte(test.data), test.format, test.showManagedFields, test.showStatus, !test.keepOrder)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %q", test.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if string(got) != test.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestWithStableOutput(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "a", "managedFields": []interface{}{map[string]interface{}{"manager": "kubectl"}}},
+		"data":       map[string]interface{}{"b": "2", "a": "1"},
+	}}
+	buf := &bytes.Buffer{}
+	out := NewStableOutputWriter(buf)
+	root := &cobra.Command{Use: "root"}
+	cmd := &cobra.Command{
+		Use: "get",
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := &printers.YAMLPrinter{}
+			_ = printer.PrintObj(obj, out)
+			_ = printer.PrintObj(obj, out)
+		},
+	}
+	cmd.Flags().StringP("output", "o", "", "")
+	cmd.Flags().String("sort-by", "", "")
+	root.AddCommand(cmd)
+	WithStableOutput(root, out)
+
+	expected := "apiVersion: v1\ndata:\n  a: \"1\"\n  b: \"2\"\nkind: ConfigMap\nmetadata:\n  name: a\n"
+	root.SetArgs([]string{"get", "-o", "yaml"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != expected+"---\n"+expected {
+		t.Errorf("expected\n%s---\n%s\ngot\n%s", expected, expected, got)
+	}
+
+	buf.Reset()
+	root.SetArgs([]string{"get", "-o", "yaml", "--show-managed-fields"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("managedFields")) {
+		t.Errorf("expected the managed fields with --show-managed-fields, got\n%s", buf.String())
+	}
+	if _, err := out.Write([]byte("status: {}\n")); err != nil || !bytes.HasSuffix(buf.Bytes(), []byte("status: {}\n")) {
+		t.Errorf("expected the output to be written unchanged after the command, got %v\n%s", err, buf.String())
+	}
+
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "List"}}
+	for _, name := range []string{"b", "a"} {
+		list.Items = append(list.Items, unstructured.Unstructured{Object: map[string]interface{}{
+			"kind":     "Pod",
+			"metadata": map[string]interface{}{"name": name},
+			"status":   map[string]interface{}{"phase": "Running"},
+		}})
+	}
+	sorted := &cobra.Command{
+		Use: "list",
+		Run: func(cmd *cobra.Command, args []string) {
+			var printer printers.ResourcePrinter = &printers.JSONPrinter{}
+			if cmdutil.GetFlagString(cmd, "output") == "yaml" {
+				printer = &printers.YAMLPrinter{}
+			}
+			_ = printer.PrintObj(list, out)
+		},
+	}
+	sorted.Flags().StringP("output", "o", "", "")
+	sorted.Flags().String("sort-by", "", "")
+	root.AddCommand(WithStableOutput(sorted, out))
+
+	buf.Reset()
+	root.SetArgs([]string{"list", "-o", "yaml", "--sort-by", "{.status.phase}"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); strings.Index(got, "name: b") > strings.Index(got, "name: a") || strings.Contains(got, "phase") {
+		t.Errorf("expected the order of --sort-by without the status, got\n%s", got)
+	}
+	buf.Reset()
+	root.SetArgs([]string{"list", "-o", "json", "--sort-by", ""})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); strings.Index(got, `"name": "a"`) > strings.Index(got, `"name": "b"`) || !strings.Contains(got, `"phase": "Running"`) {
+		t.Errorf("expected the sorted items with their status, got\n%s", got)
+	}
+}
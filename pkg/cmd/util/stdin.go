@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// stdinFilename is the filename of the objects read from the standard input.
+const stdinFilename = "-"
+
+// BufferStdin reads stdin once and replaces it with a pipe replaying the same bytes, so that a
+// command still reads the objects of "-" after they were read to check or track them.
+func BufferStdin() ([]byte, error) {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		w.Write(data)
+		w.Close()
+	}()
+	os.Stdin = r
+	return data, nil
+}
+
+// FilenameParam adds the files of options to builder. If stdin is not nil, it holds the objects of
+// "-", already read with BufferStdin.
+func FilenameParam(builder *resource.Builder, enforceNamespace bool, options *resource.FilenameOptions, stdin []byte) *resource.Builder {
+	if stdin == nil {
+		return builder.FilenameParam(enforceNamespace, options)
+	}
+	files := *options
+	files.Filenames = nil
+	for _, filename := range options.Filenames {
+		if filename != stdinFilename {
+			files.Filenames = append(files.Filenames, filename)
+		}
+	}
+	return builder.FilenameParam(enforceNamespace, &files).Stream(bytes.NewReader(stdin), "STDIN")
+}
+
+// bufferFilenameStdin buffers stdin with BufferStdin if it is among the --filename of cmd, and
+// returns nil otherwise.
+func bufferFilenameStdin(cmd *cobra.Command) ([]byte, error) {
+	if cmd.Flags().Lookup("filename") == nil || !sets.NewString(cmdutil.GetFlagStringSlice(cmd, "filename")...).Has(stdinFilename) {
+		return nil, nil
+	}
+	return BufferStdin()
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const summaryFlag = "summary"
+
+// Summary counts the objects of a bulk operation printed by --summary.
+type Summary struct {
+	Updated  int
+	Skipped  int
+	Failed   int
+	Failures []string
+}
+
+// summaryFatal is the panic that stops a command wrapped by WithSummary when it fails, instead
+// of exiting, so that the summary can be printed.
+type summaryFatal struct {
+	msg  string
+	code int
+}
+
+// WithSummary adds the --summary flag to cmd if it selects resources with --selector, or else to
+// its sub commands that do. With --summary, the one line the command prints per object is not
+// printed; only the number of updated, skipped and failed objects is, followed by the failures,
+// so that bulk operations do not flood CI logs. cmd must have been created with the given streams.
+func WithSummary(f cmdutil.Factory, cmd *cobra.Command, streams *ReadyStateStreams) *cobra.Command {
+	if cmd.Run == nil || cmd.Flags().Lookup("selector") == nil {
+		for _, child := range cmd.Commands() {
+			WithSummary(f, child, streams)
+		}
+		return cmd
+	}
+
+	cmd.Flags().Bool(summaryFlag, false, "If true, only print the number of updated, skipped and failed objects, and the failures, instead of one line per object.")
+
+	run := cmd.Run
+	cmd.Run = func(c *cobra.Command, args []string) {
+		if !cmdutil.GetFlagBool(c, summaryFlag) {
+			run(c, args)
+			return
+		}
+		cmdutil.CheckErr(validateSummary(c))
+
+		stdin, err := bufferFilenameStdin(c)
+		cmdutil.CheckErr(err)
+		selected := -1
+		if infos, err := readyStateInfos(f, c, args, stdin); err == nil {
+			selected = len(infos)
+		}
+
+		out := &bytes.Buffer{}
+		fatal := runWithSummary(streams, out, func() { run(c, args) })
+		summary := SummaryFor(out.String(), fatal.msg, selected)
+		cmdutil.CheckErr(summary.Print(streams.original.Out, streams.original.ErrOut))
+		if fatal.code != 0 {
+			cmdutil.CheckErr(cmdutil.ErrExit)
+		}
+	}
+	return cmd
+}
+
+func validateSummary(cmd *cobra.Command) error {
+	if flag := cmd.Flags().Lookup("output"); flag != nil && len(flag.Value.String()) > 0 && flag.Value.String() != "name" {
+		return fmt.Errorf("--%s can not be used with --output", summaryFlag)
+	}
+	if flag := cmd.Flags().Lookup(outputStateFlag); flag != nil && len(flag.Value.String()) > 0 {
+		return fmt.Errorf("--%s can not be used with --%s", summaryFlag, outputStateFlag)
+	}
+	return nil
+}
+
+// runWithSummary runs run with the output of streams written to out, and returns the failure
+// run exited with, if any.
+func runWithSummary(streams *ReadyStateStreams, out io.Writer, run func()) (fatal summaryFatal) {
+	streams.out.Writer = out
+	cmdutil.BehaviorOnFatal(func(msg string, code int) {
+		panic(summaryFatal{msg: msg, code: code})
+	})
+	defer func() {
+		streams.out.Writer = streams.original.Out
+		cmdutil.DefaultBehaviorOnFatal()
+		if r := recover(); r != nil {
+			f, ok := r.(summaryFatal)
+			if !ok {
+				panic(r)
+			}
+			fatal = f
+		}
+	}()
+	run()
+	return fatal
+}
+
+// SummaryFor returns the summary of a bulk operation that printed output, one line per object,
+// and failed with failures, one line per failure. Objects printed as unchanged, or selected but
+// not printed, are skipped. selected is the number of selected objects, or -1 if it is unknown.
+func SummaryFor(output, failures string, selected int) *Summary {
+	summary := &Summary{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case len(line) == 0:
+		case strings.HasSuffix(line, " unchanged"):
+			summary.Skipped++
+		default:
+			summary.Updated++
+		}
+	}
+	for _, line := range strings.Split(failures, "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			summary.Failures = append(summary.Failures, line)
+		}
+	}
+	summary.Failed = len(summary.Failures)
+	if unprinted := selected - summary.Updated - summary.Skipped - summary.Failed; unprinted > 0 {
+		summary.Skipped += unprinted
+	}
+	return summary
+}
+
+// Print prints the counts of s to out and its failures to errOut.
+func (s *Summary) Print(out, errOut io.Writer) error {
+	if _, err := fmt.Fprintf(out, "%d updated, %d skipped, %d failed\n", s.Updated, s.Skipped, s.Failed); err != nil {
+		return err
+	}
+	for _, failure := range s.Failures {
+		if !strings.HasPrefix(failure, "error: ") {
+			failure = "error: " + failure
+		}
+		if _, err := fmt.Fprintln(errOut, failure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
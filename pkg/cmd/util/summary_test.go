@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+func TestSummaryFor(t *testing.T) {
+	output := "cloneset.apps.kruise.io/a image updated\ncloneset.apps.kruise.io/b image updated\ncloneset.apps.kruise.io/c unchanged\n"
+	failures := "error: cloneset.apps.kruise.io/d the object has been modified\n\nerror: cloneset.apps.kruise.io/e forbidden\n"
+	expected := &Summary{Updated: 2, Skipped: 3, Failed: 2, Failures: []string{
+		"error: cloneset.apps.kruise.io/d the object has been modified",
+		"error: cloneset.apps.kruise.io/e forbidden",
+	}}
+	if got := SummaryFor(output, failures, 7); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	expected = &Summary{Updated: 2, Skipped: 1}
+	if got := SummaryFor(output, "", -1); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestRunWithSummary(t *testing.T) {
+	original, _, out, _ := genericclioptions.NewTestIOStreams()
+	streams := NewReadyStateStreams(original)
+
+	buf := &bytes.Buffer{}
+	fatal := runWithSummary(streams, buf, func() {
+		fmt.Fprintln(streams.Out, "cloneset.apps.kruise.io/a image updated")
+		cmdutil.CheckErr(errors.New("cloneset.apps.kruise.io/b forbidden"))
+		t.Errorf("expected the command to stop at its failure")
+	})
+	if fatal.code != cmdutil.DefaultErrorExitCode || fatal.msg != "error: cloneset.apps.kruise.io/b forbidden" {
+		t.Errorf("unexpected failure %+v", fatal)
+	}
+	if buf.String() != "cloneset.apps.kruise.io/a image updated\n" || out.Len() != 0 {
+		t.Errorf("expected the output of the command to be summarized, got %q and printed %q", buf.String(), out.String())
+	}
+
+	fmt.Fprintln(streams.Out, "printed")
+	if out.String() != "printed\n" {
+		t.Errorf("expected the output to be restored, got %q", out.String())
+	}
+}
+
+func TestSummaryPrint(t *testing.T) {
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	summary := &Summary{Updated: 40, Skipped: 1, Failed: 1, Failures: []string{"cloneset.apps.kruise.io/d forbidden"}}
+	if err := summary.Print(out, errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "40 updated, 1 skipped, 1 failed\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	if expected := "error: cloneset.apps.kruise.io/d forbidden\n"; errOut.String() != expected {
+		t.Errorf("expected %q, got %q", expected, errOut.String())
+	}
+}
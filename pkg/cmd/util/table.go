@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// MaxColumnWidthEnv sets the default of --max-column-width.
+const MaxColumnWidthEnv = "KUBECTL_KRUISE_MAX_COLUMN_WIDTH"
+
+// tableEllipsis ends the truncated cells of tables.
+const tableEllipsis = "..."
+
+var (
+	maxColumnWidth, _ = strconv.Atoi(os.Getenv(MaxColumnWidthEnv))
+	noTruncate        bool
+)
+
+// AddTableFlags adds the --max-column-width and --no-truncate flags of the tables printed by
+// NewTableWriter to flags.
+func AddTableFlags(flags *pflag.FlagSet) {
+	flags.IntVar(&maxColumnWidth, "max-column-width", maxColumnWidth, "The maximum width of the columns of tables, such as the ones of restarts, events or batchrelease status; longer values are truncated. Zero means no limit. Also set by the "+MaxColumnWidthEnv+" environment variable.")
+	flags.BoolVar(&noTruncate, "no-truncate", noTruncate, "If true, never truncate the columns of tables, even with --max-column-width, so that long image digests and revision hashes can be copied.")
+}
+
+// TableWriter aligns the tab-separated cells of tables, as printers.GetNewTabWriter does, and
+// truncates the cells longer than --max-column-width unless --no-truncate is set.
+type TableWriter struct {
+	tabWriter tabFlusher
+	width     int
+	line      []byte
+}
+
+// tabFlusher is the tab writer returned by printers.GetNewTabWriter.
+type tabFlusher interface {
+	io.Writer
+	Flush() error
+}
+
+// NewTableWriter returns a TableWriter writing to out.
+func NewTableWriter(out io.Writer) *TableWriter {
+	width := maxColumnWidth
+	if noTruncate || width < 0 {
+		width = 0
+	}
+	return &TableWriter{tabWriter: printers.GetNewTabWriter(out), width: width}
+}
+
+// Write writes the complete lines of p, truncating their cells, and keeps the rest until the
+// line is complete or the writer is flushed.
+func (w *TableWriter) Write(p []byte) (int, error) {
+	if w.width == 0 {
+		return w.tabWriter.Write(p)
+	}
+	w.line = append(w.line, p...)
+	for {
+		i := bytes.IndexByte(w.line, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if _, err := w.tabWriter.Write(w.truncate(w.line[:i+1])); err != nil {
+			return 0, err
+		}
+		w.line = w.line[i+1:]
+	}
+}
+
+// Flush writes the table.
+func (w *TableWriter) Flush() error {
+	if len(w.line) > 0 {
+		line := w.truncate(w.line)
+		w.line = nil
+		if _, err := w.tabWriter.Write(line); err != nil {
+			return err
+		}
+	}
+	return w.tabWriter.Flush()
+}
+
+// truncate truncates the cells of line longer than the width of w.
+func (w *TableWriter) truncate(line []byte) []byte {
+	cells := strings.Split(string(line), "\t")
+	for i, cell := range cells {
+		cells[i] = TruncateCell(cell, w.width)
+	}
+	return []byte(strings.Join(cells, "\t"))
+}
+
+// TruncateCell returns cell truncated to width characters, ending with an ellipsis if it is
+// truncated. A width of zero or less does not truncate.
+func TruncateCell(cell string, width int) string {
+	newline := strings.HasSuffix(cell, "\n")
+	text := []rune(strings.TrimSuffix(cell, "\n"))
+	if width <= 0 || len(text) <= width {
+		return cell
+	}
+	if width <= len(tableEllipsis) {
+		text = text[:width]
+	} else {
+		text = append(text[:width-len(tableEllipsis)], []rune(tableEllipsis)...)
+	}
+	if newline {
+		return string(text) + "\n"
+	}
+	return string(text)
+}
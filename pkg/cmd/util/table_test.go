@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestTruncateCell(t *testing.T) {
+	tests := []struct {
+		cell     string
+		width    int
+		expected string
+	}{
+		{cell: "nginx:1.25", width: 0, expected: "nginx:1.25"},
+		{cell: "nginx:1.25", width: 10, expected: "nginx:1.25"},
+		{cell: "nginx@sha256:0123456789abcdef", width: 12, expected: "nginx@sha..."},
+		{cell: "demo-7d9f6c5b\n", width: 8, expected: "demo-...\n"},
+		{cell: "demo", width: 2, expected: "de"},
+	}
+	for _, test := range tests {
+		if got := TruncateCell(test.cell, test.width); got != test.expected {
+			t.Errorf("%q to %d: expected %q, got %q", test.cell, test.width, test.expected, got)
+		}
+	}
+}
+
+func TestTableWriter(t *testing.T) {
+	defer func(oldWidth int, oldNoTruncate bool) { maxColumnWidth, noTruncate = oldWidth, oldNoTruncate }(maxColumnWidth, noTruncate)
+
+	printTable := func(args ...string) string {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		AddTableFlags(flags)
+		if err := flags.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		out := &bytes.Buffer{}
+		w := NewTableWriter(out)
+		fmt.Fprintln(w, "POD\tREVISION")
+		fmt.Fprint(w, "demo-0\tdemo-")
+		fmt.Fprint(w, "7d9f6c5b8\n")
+		fmt.Fprint(w, "demo-1\tdemo-7d9f6c5b8")
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+
+	if expected, got := "POD      REVISION\ndemo-0   demo-7d9f6c5b8\ndemo-1   demo-7d9f6c5b8", printTable(); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+	if expected, got := "POD      REVISION\ndemo-0   demo-...\ndemo-1   demo-...", printTable("--max-column-width=8"); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+	if expected, got := "POD      REVISION\ndemo-0   demo-7d9f6c5b8\ndemo-1   demo-7d9f6c5b8", printTable("--max-column-width=8", "--no-truncate"); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"strings"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// compiledVersionsClientGetter returns REST mappers that prefer the compiled Kruise versions.
+type compiledVersionsClientGetter struct {
+	genericclioptions.RESTClientGetter
+}
+
+// WithCompiledKruiseVersions returns a RESTClientGetter whose REST mapper maps the Kruise resources
+// given without version to the versions compiled into kubectl-kruise, if the cluster serves them,
+// rather than to the versions the cluster prefers. During an upgrade of Kruise adding a version,
+// the typed commands keep working instead of failing to decode the new version. The resources
+// only served at unknown versions are still mapped to them.
+func WithCompiledKruiseVersions(getter genericclioptions.RESTClientGetter) genericclioptions.RESTClientGetter {
+	return &compiledVersionsClientGetter{RESTClientGetter: getter}
+}
+
+// ToRESTMapper implements RESTClientGetter
+func (g *compiledVersionsClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	mapper, err := g.RESTClientGetter.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+	return &compiledVersionsMapper{RESTMapper: mapper}, nil
+}
+
+// compiledVersionsMapper is a RESTMapper that prefers the compiled Kruise versions.
+type compiledVersionsMapper struct {
+	meta.RESTMapper
+}
+
+func (m *compiledVersionsMapper) KindFor(resource schema.GroupVersionResource) (schema.GroupVersionKind, error) {
+	gvk, err := m.RESTMapper.KindFor(resource)
+	if err != nil || len(resource.Version) > 0 || !IsKruiseGroup(gvk.Group) || IsCompiledVersion(gvk) {
+		return gvk, err
+	}
+	gvks, err := m.RESTMapper.KindsFor(resource)
+	if err != nil {
+		return gvk, nil
+	}
+	for _, candidate := range gvks {
+		if candidate.GroupKind() == gvk.GroupKind() && IsCompiledVersion(candidate) {
+			return candidate, nil
+		}
+	}
+	return gvk, nil
+}
+
+func (m *compiledVersionsMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	mapping, err := m.RESTMapper.RESTMapping(gk, versions...)
+	if err != nil || !IsKruiseGroup(gk.Group) || IsCompiledVersion(mapping.GroupVersionKind) {
+		return mapping, err
+	}
+	for _, version := range versions {
+		if len(version) > 0 {
+			return mapping, nil
+		}
+	}
+	mappings, err := m.RESTMapper.RESTMappings(gk)
+	if err != nil {
+		return mapping, nil
+	}
+	for _, candidate := range mappings {
+		if IsCompiledVersion(candidate.GroupVersionKind) {
+			return candidate, nil
+		}
+	}
+	return mapping, nil
+}
+
+// IsKruiseGroup returns true if group is an API group of Kruise, such as apps.kruise.io.
+func IsKruiseGroup(group string) bool {
+	return group == "kruise.io" || strings.HasSuffix(group, ".kruise.io")
+}
+
+// IsCompiledVersion returns true if the kind of gvk is compiled into kubectl-kruise at its
+// version, so that it can be decoded to a typed object.
+func IsCompiledVersion(gvk schema.GroupVersionKind) bool {
+	return internalapi.GetScheme().Recognizes(gvk)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCompiledVersionsMapper(t *testing.T) {
+	// the cluster prefers versions unknown to kubectl-kruise
+	preferred := schema.GroupVersion{Group: "apps.kruise.io", Version: "v1beta9"}
+	compiled := schema.GroupVersion{Group: "apps.kruise.io", Version: "v1alpha1"}
+	delegate := meta.NewDefaultRESTMapper([]schema.GroupVersion{preferred, compiled})
+	delegate.Add(preferred.WithKind("CloneSet"), meta.RESTScopeNamespace)
+	delegate.Add(compiled.WithKind("CloneSet"), meta.RESTScopeNamespace)
+	delegate.Add(preferred.WithKind("FutureSet"), meta.RESTScopeNamespace)
+	// as the mappers of discovery, prefer the versions in the order of the cluster
+	mapper := &compiledVersionsMapper{RESTMapper: meta.PriorityRESTMapper{
+		Delegate: delegate,
+		ResourcePriority: []schema.GroupVersionResource{
+			preferred.WithResource(meta.AnyResource),
+			{Group: "apps.kruise.io", Version: meta.AnyVersion, Resource: meta.AnyResource},
+		},
+		KindPriority: []schema.GroupVersionKind{
+			preferred.WithKind(meta.AnyKind),
+			{Group: "apps.kruise.io", Version: meta.AnyVersion, Kind: meta.AnyKind},
+		},
+	}}
+
+	tests := []struct {
+		resource schema.GroupVersionResource
+		expected schema.GroupVersionKind
+	}{
+		{resource: schema.GroupVersionResource{Resource: "clonesets"}, expected: compiled.WithKind("CloneSet")},
+		{resource: schema.GroupVersionResource{Group: "apps.kruise.io", Resource: "clonesets"}, expected: compiled.WithKind("CloneSet")},
+		{resource: preferred.WithResource("clonesets"), expected: preferred.WithKind("CloneSet")},
+		{resource: schema.GroupVersionResource{Resource: "futuresets"}, expected: preferred.WithKind("FutureSet")},
+	}
+	for _, test := range tests {
+		gvk, err := mapper.KindFor(test.resource)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.resource, err)
+		} else if gvk != test.expected {
+			t.Errorf("%s: expected kind %s, got %s", test.resource, test.expected, gvk)
+		}
+	}
+
+	mapping, err := mapper.RESTMapping(schema.GroupKind{Group: "apps.kruise.io", Kind: "CloneSet"})
+	if err != nil || mapping.GroupVersionKind != compiled.WithKind("CloneSet") {
+		t.Errorf("expected the mapping of %s, got %v, %v", compiled.WithKind("CloneSet"), mapping, err)
+	}
+	mapping, err = mapper.RESTMapping(schema.GroupKind{Group: "apps.kruise.io", Kind: "CloneSet"}, "v1beta9")
+	if err != nil || mapping.GroupVersionKind != preferred.WithKind("CloneSet") {
+		t.Errorf("expected the mapping of the requested version, got %v, %v", mapping, err)
+	}
+}
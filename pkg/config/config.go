@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"k8s.io/client-go/util/homedir"
+	"sigs.k8s.io/yaml"
+)
+
+// PathEnv overrides the path of the kubectl-kruise config file.
+const PathEnv = "KUBECTL_KRUISE_CONFIG"
+
+// Config is the kubectl-kruise config file, by default ~/.kube/kubectl-kruise.yaml.
+type Config struct {
+	// Aliases are the user defined commands by name.
+	Aliases map[string]Alias `json:"aliases,omitempty"`
+	// Upgrade configures the upgrade command.
+	Upgrade *Upgrade `json:"upgrade,omitempty"`
+	// Hooks are run before and after commands.
+	Hooks []Hook `json:"hooks,omitempty"`
+	// Policy gates the changes of the commands.
+	Policy *Policy `json:"policy,omitempty"`
+	// Cost is the price table of the cost estimates of the changes.
+	Cost *Cost `json:"cost,omitempty"`
+	// NamespaceProfiles are the profiles init-namespace provisions namespaces with, by name.
+	NamespaceProfiles map[string]NamespaceProfile `json:"namespaceProfiles,omitempty"`
+	// Conventions are the labels and annotations of the workloads created by the commands.
+	Conventions *Conventions `json:"conventions,omitempty"`
+}
+
+// Alias is a user defined command that runs another kubectl-kruise command.
+type Alias struct {
+	// Command is the command line the alias expands to, e.g. [set image cloneset/demo].
+	Command []string `json:"command"`
+	// DefaultFlags are added to the command line unless the same flags are given.
+	DefaultFlags []string `json:"defaultFlags,omitempty"`
+}
+
+// Upgrade configures where the upgrade command gets the releases of kubectl-kruise.
+type Upgrade struct {
+	// Mirror is the URL of an internal mirror of the GitHub releases, used instead of GitHub.
+	Mirror string `json:"mirror,omitempty"`
+}
+
+// Hook runs executables before and after a command, e.g. to enforce change-management policies.
+// The executables get the planned action as JSON on their standard input.
+type Hook struct {
+	// Command is the command the hook is run for, e.g. "set image", including its sub commands.
+	Command string `json:"command"`
+	// Pre is the executable and its arguments run before the command. A non-zero exit status
+	// blocks the command.
+	Pre []string `json:"pre,omitempty"`
+	// Post is the executable and its arguments run after the command, whether it failed or not.
+	Post []string `json:"post,omitempty"`
+}
+
+// Policy gates the changes of kubectl-kruise with Rego policies, evaluated by the opa binary.
+type Policy struct {
+	// Rego are the paths of the Rego files, or directories, of the policies.
+	Rego []string `json:"rego"`
+	// Query returns the denial messages of a change, data.kubectl_kruise.deny by default.
+	Query string `json:"query,omitempty"`
+	// OPA is the path of the opa binary, opa on PATH by default.
+	OPA string `json:"opa,omitempty"`
+}
+
+// Cost is the price table of the estimates of the monthly cost of the changes to resources and
+// replicas, per CPU and GiB of memory requested.
+type Cost struct {
+	// CPU is the monthly price of a CPU.
+	CPU float64 `json:"cpu"`
+	// Memory is the monthly price of a GiB of memory.
+	Memory float64 `json:"memory"`
+	// Currency is printed after the prices, e.g. USD.
+	Currency string `json:"currency,omitempty"`
+}
+
+// NamespaceProfile is the recommended Kruise settings of the namespaces of a kind of tenant.
+type NamespaceProfile struct {
+	// Labels are set on the namespaces, e.g. policy.kruise.io/delete-protection.
+	Labels map[string]string `json:"labels,omitempty"`
+	// SidecarSets are the paths of the YAML files of the SidecarSets injected into the pods of
+	// the namespaces, relative to the config file. A copy restricted to the namespace is created
+	// from each of them.
+	SidecarSets []string `json:"sidecarSets,omitempty"`
+	// Distributions are the Secrets and ConfigMaps, e.g. shared pull secrets and CA bundles,
+	// distributed to the namespaces with ResourceDistributions.
+	Distributions []Distribution `json:"distributions,omitempty"`
+}
+
+// Distribution is a Secret or a ConfigMap distributed to the namespaces of a profile.
+type Distribution struct {
+	// Kind is Secret or ConfigMap.
+	Kind string `json:"kind"`
+	// Namespace is the namespace of the source of the distributed resource.
+	Namespace string `json:"namespace"`
+	// Name is the name of the distributed resource.
+	Name string `json:"name"`
+}
+
+// Conventions are the organizational labels and annotations, e.g. team, app and owner, of the
+// workloads created by the commands.
+type Conventions struct {
+	// Labels are set on the created workloads unless they already have them.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are set on the created workloads unless they already have them.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// RequiredLabels must be set on the created workloads, the commands fail before creating
+	// them otherwise.
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+}
+
+// Path returns the path of the config file.
+func Path() string {
+	if path := os.Getenv(PathEnv); len(path) > 0 {
+		return path
+	}
+	return filepath.Join(homedir.HomeDir(), ".kube", "kubectl-kruise.yaml")
+}
+
+// Load reads the config file at path. A missing file is an empty Config.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes cfg to the config file at path.
+func Save(path string, cfg *Config) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
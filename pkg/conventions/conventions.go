@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conventions applies the organizational labels and annotations of the config file,
+// e.g. team, app and owner, to the workloads created by the commands, and checks that the
+// required labels are set before they are created. Without conventions, nothing is applied.
+package conventions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var conventions *config.Conventions
+
+// Configure applies the conventions c to the created workloads, or turns them off if c is nil.
+func Configure(c *config.Conventions) {
+	conventions = c
+}
+
+// Apply sets the labels and annotations of the conventions that obj does not have, then the
+// labels, overriding both, and returns an error naming the required labels obj still misses.
+func Apply(obj runtime.Object, labels map[string]string) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	objLabels := accessor.GetLabels()
+	if objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	if conventions != nil {
+		setMissing(objLabels, conventions.Labels)
+		if len(conventions.Annotations) > 0 {
+			annotations := accessor.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			setMissing(annotations, conventions.Annotations)
+			accessor.SetAnnotations(annotations)
+		}
+	}
+	for k, v := range labels {
+		objLabels[k] = v
+	}
+	if len(objLabels) > 0 {
+		accessor.SetLabels(objLabels)
+	}
+
+	if conventions == nil {
+		return nil
+	}
+	var missing []string
+	for _, k := range conventions.RequiredLabels {
+		if len(objLabels[k]) == 0 {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("missing required labels %s, set them with --set-label", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func setMissing(dst, src map[string]string) {
+	for k, v := range src {
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conventions
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/config"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApply(t *testing.T) {
+	defer Configure(nil)
+
+	Configure(nil)
+	cloneSet := &appsv1alpha1.CloneSet{}
+	if err := Apply(cloneSet, nil); err != nil || cloneSet.Labels != nil {
+		t.Errorf("expected nothing applied without conventions, got %v, %v", cloneSet.Labels, err)
+	}
+
+	Configure(&config.Conventions{
+		Labels:         map[string]string{"team": "payments", "app": "checkout"},
+		Annotations:    map[string]string{"owner-contact": "#payments"},
+		RequiredLabels: []string{"team", "app", "owner"},
+	})
+	tests := []struct {
+		name                string
+		labels              map[string]string
+		setLabels           map[string]string
+		expectedLabels      map[string]string
+		expectedAnnotations map[string]string
+		expectedErr         string
+	}{
+		{
+			name:                "missing required label",
+			expectedLabels:      map[string]string{"team": "payments", "app": "checkout"},
+			expectedAnnotations: map[string]string{"owner-contact": "#payments"},
+			expectedErr:         "missing required labels owner, set them with --set-label",
+		},
+		{
+			name:                "set labels override the conventions and the workload",
+			labels:              map[string]string{"app": "cart", "tier": "web"},
+			setLabels:           map[string]string{"owner": "alice", "tier": "backend"},
+			expectedLabels:      map[string]string{"team": "payments", "app": "cart", "owner": "alice", "tier": "backend"},
+			expectedAnnotations: map[string]string{"owner-contact": "#payments"},
+		},
+		{
+			name:                "empty required label",
+			setLabels:           map[string]string{"owner": "", "team": ""},
+			expectedLabels:      map[string]string{"team": "", "app": "checkout", "owner": ""},
+			expectedAnnotations: map[string]string{"owner-contact": "#payments"},
+			expectedErr:         "missing required labels owner, team, set them with --set-label",
+		},
+	}
+	for _, test := range tests {
+		cloneSet := &appsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Labels: test.labels}}
+		err := Apply(cloneSet, test.setLabels)
+		if (err == nil && len(test.expectedErr) > 0) || (err != nil && err.Error() != test.expectedErr) {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.expectedErr, err)
+		}
+		if !reflect.DeepEqual(cloneSet.Labels, test.expectedLabels) {
+			t.Errorf("%s: expected labels %v, got %v", test.name, test.expectedLabels, cloneSet.Labels)
+		}
+		if !reflect.DeepEqual(cloneSet.Annotations, test.expectedAnnotations) {
+			t.Errorf("%s: expected annotations %v, got %v", test.name, test.expectedAnnotations, cloneSet.Annotations)
+		}
+	}
+}
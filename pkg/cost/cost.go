@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cost estimates the monthly cost of the changes to the resources and the replicas of
+// workloads, with the price table of the config file. The cost of a workload is the CPU and the
+// memory requested by its pods times its replicas, and the commands print the difference the
+// change makes along with their usual output. Without a price table, nothing is estimated.
+package cost
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/json"
+)
+
+const bytesPerGiB = 1 << 30
+
+var prices *config.Cost
+
+// Configure estimates the costs with the price table p, or turns the estimates off if p is nil.
+func Configure(p *config.Cost) {
+	prices = p
+}
+
+// Enabled returns true if a price table is configured.
+func Enabled() bool {
+	return prices != nil
+}
+
+// Estimate is the monthly cost of a workload before and after a change.
+type Estimate struct {
+	Before   float64
+	After    float64
+	Currency string
+}
+
+// Delta returns the difference the change makes to the monthly cost.
+func (e *Estimate) Delta() float64 {
+	return e.After - e.Before
+}
+
+// String returns the estimate as printed by the commands, e.g.
+// "estimated cost: +12.00 USD/month (24.00 -> 36.00)".
+func (e *Estimate) String() string {
+	currency := ""
+	if len(e.Currency) > 0 {
+		currency = " " + e.Currency
+	}
+	return fmt.Sprintf("estimated cost: %+.2f%s/month (%.2f -> %.2f)", e.Delta(), currency, e.Before, e.After)
+}
+
+// EstimateObjects returns the estimate of the change of a workload from before to after. It
+// returns nil if no price table is configured or the objects have no pod template.
+func EstimateObjects(before, after runtime.Object) (*Estimate, error) {
+	if prices == nil {
+		return nil, nil
+	}
+	b, err := runtime.DefaultUnstructuredConverter.ToUnstructured(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := runtime.DefaultUnstructuredConverter.ToUnstructured(after)
+	if err != nil {
+		return nil, err
+	}
+	return estimate(b, a)
+}
+
+// EstimateJSON is EstimateObjects with the objects encoded in JSON, as the set commands patch them.
+func EstimateJSON(before, after []byte) (*Estimate, error) {
+	if prices == nil {
+		return nil, nil
+	}
+	var b, a map[string]interface{}
+	if err := json.Unmarshal(before, &b); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &a); err != nil {
+		return nil, err
+	}
+	return estimate(b, a)
+}
+
+func estimate(before, after map[string]interface{}) (*Estimate, error) {
+	b, ok, err := monthly(before)
+	if err != nil || !ok {
+		return nil, err
+	}
+	a, ok, err := monthly(after)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &Estimate{Before: b, After: a, Currency: prices.Currency}, nil
+}
+
+// monthly returns the monthly cost of the workload obj, and false if it has no pod template.
+func monthly(obj map[string]interface{}) (float64, bool, error) {
+	template, found, err := unstructured.NestedMap(obj, "spec", "template", "spec")
+	if err != nil || !found {
+		return 0, false, err
+	}
+	spec := &corev1.PodSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(template, spec); err != nil {
+		return 0, false, err
+	}
+	requests := podRequests(spec)
+	cpu := requests.Cpu().AsApproximateFloat64()
+	memory := requests.Memory().AsApproximateFloat64() / bytesPerGiB
+	return float64(replicas(obj)) * (cpu*prices.CPU + memory*prices.Memory), true, nil
+}
+
+// replicas returns the replicas of the workload obj, the nodes a DaemonSet is scheduled to, or
+// else 1.
+func replicas(obj map[string]interface{}) int64 {
+	if replicas, found, err := unstructured.NestedInt64(obj, "spec", "replicas"); err == nil && found {
+		return replicas
+	}
+	if kind, _, _ := unstructured.NestedString(obj, "kind"); strings.HasSuffix(kind, "DaemonSet") {
+		scheduled, _, _ := unstructured.NestedInt64(obj, "status", "desiredNumberScheduled")
+		return scheduled
+	}
+	return 1
+}
+
+// podRequests returns the requests of a pod of spec, the largest of the sum of its containers and
+// of each of its init containers, as the scheduler computes them.
+func podRequests(spec *corev1.PodSpec) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, c := range spec.Containers {
+		for name, quantity := range c.Resources.Requests {
+			if value, ok := requests[name]; ok {
+				value.Add(quantity)
+				requests[name] = value
+			} else {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	for _, c := range spec.InitContainers {
+		for name, quantity := range c.Resources.Requests {
+			if value, ok := requests[name]; !ok || quantity.Cmp(value) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	return requests
+}
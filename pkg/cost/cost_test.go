@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cost
+
+import (
+	"fmt"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/config"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/pointer"
+)
+
+func newCloneSet(replicas int32, cpu, memory string) *appsv1alpha1.CloneSet {
+	return &appsv1alpha1.CloneSet{
+		Spec: appsv1alpha1.CloneSetSpec{
+			Replicas: pointer.Int32Ptr(replicas),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name: "nginx",
+						Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse(cpu),
+							corev1.ResourceMemory: resource.MustParse(memory),
+						}},
+					}},
+					InitContainers: []corev1.Container{{
+						Name: "init",
+						Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+							corev1.ResourceCPU: resource.MustParse("100m"),
+						}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestEstimateObjects(t *testing.T) {
+	defer Configure(nil)
+
+	Configure(nil)
+	if estimate, err := EstimateObjects(newCloneSet(1, "1", "1Gi"), newCloneSet(2, "1", "1Gi")); err != nil || estimate != nil {
+		t.Errorf("expected no estimate without a price table, got %v, %v", estimate, err)
+	}
+
+	Configure(&config.Cost{CPU: 20, Memory: 4, Currency: "USD"})
+	tests := []struct {
+		name          string
+		before, after *appsv1alpha1.CloneSet
+		expected      string
+	}{
+		{
+			name:     "scale up",
+			before:   newCloneSet(2, "500m", "1Gi"),
+			after:    newCloneSet(4, "500m", "1Gi"),
+			expected: "estimated cost: +28.00 USD/month (28.00 -> 56.00)",
+		},
+		{
+			name:     "smaller requests",
+			before:   newCloneSet(3, "2", "4Gi"),
+			after:    newCloneSet(3, "1", "512Mi"),
+			expected: "estimated cost: -102.00 USD/month (168.00 -> 66.00)",
+		},
+		{
+			name:     "init container larger than the containers",
+			before:   newCloneSet(1, "50m", "0"),
+			after:    newCloneSet(1, "200m", "0"),
+			expected: "estimated cost: +2.00 USD/month (2.00 -> 4.00)",
+		},
+	}
+	for _, test := range tests {
+		estimate, err := EstimateObjects(test.before, test.after)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if estimate == nil || estimate.String() != test.expected {
+			t.Errorf("%s: expected %q, got %v", test.name, test.expected, estimate)
+		}
+	}
+}
+
+func TestEstimateJSON(t *testing.T) {
+	defer Configure(nil)
+	Configure(&config.Cost{CPU: 10, Memory: 1})
+
+	estimate, err := EstimateJSON([]byte(`{"kind":"ConfigMap","data":{"a":"b"}}`), []byte(`{"kind":"ConfigMap","data":{"a":"c"}}`))
+	if err != nil || estimate != nil {
+		t.Errorf("expected no estimate without a pod template, got %v, %v", estimate, err)
+	}
+
+	daemonSet := `{"kind":"DaemonSet","spec":{"template":{"spec":{"containers":[{"name":"a","resources":{"requests":{"cpu":"%s"}}}]}}},"status":{"desiredNumberScheduled":5}}`
+	estimate, err = EstimateJSON([]byte(fmt.Sprintf(daemonSet, "1")), []byte(fmt.Sprintf(daemonSet, "2")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "estimated cost: +50.00/month (50.00 -> 100.00)"; estimate == nil || estimate.String() != expected {
+		t.Errorf("expected %q, got %v", expected, estimate)
+	}
+}
@@ -16,12 +16,23 @@ limitations under the License.
 
 package creation
 
-import "github.com/openkruise/kruise-tools/pkg/api"
+import (
+	"errors"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+)
+
+// ErrDstExists is returned by Create when the dst workload already exists.
+var ErrDstExists = errors.New("already exists")
 
 type Control interface {
 	Create(src api.ResourceRef, dst api.ResourceRef, opts Options) error
+	// SyncTemplate updates the pod template of dst to the one of src, and returns whether it changed.
+	SyncTemplate(src api.ResourceRef, dst api.ResourceRef) (bool, error)
 }
 
 type Options struct {
 	CopyReplicas bool
+	// Labels are set on the created workload, overriding the labels of the conventions.
+	Labels map[string]string
 }
@@ -22,10 +22,12 @@ import (
 
 	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/conventions"
 	"github.com/openkruise/kruise-tools/pkg/conversion"
 	"github.com/openkruise/kruise-tools/pkg/creation"
 
 	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -67,13 +69,47 @@ func (c *control) Create(src api.ResourceRef, dst api.ResourceRef, opts creation
 	}
 
 	dstCloneSet := conversion.DeploymentToCloneSet(srcDeployment, dst.Name)
+	if !opts.CopyReplicas {
+		var zero int32
+		dstCloneSet.Spec.Replicas = &zero
+	}
+	if err := conventions.Apply(dstCloneSet, opts.Labels); err != nil {
+		return err
+	}
 	return c.client.Create(context.TODO(), dstCloneSet)
 }
 
+func (c *control) SyncTemplate(src api.ResourceRef, dst api.ResourceRef) (bool, error) {
+	if src.GetGroupVersionKind() != api.DeploymentKind {
+		return false, fmt.Errorf("invalid src type, currently only support %v", api.DeploymentKind.String())
+	} else if dst.GetGroupVersionKind() != api.CloneSetKind {
+		return false, fmt.Errorf("invalid dst type, must be %v", api.CloneSetKind.String())
+	}
+
+	srcDeployment, err := c.getDeployment(src)
+	if err != nil {
+		return false, err
+	}
+	cs := &appsv1alpha1.CloneSet{}
+	if err := c.client.Get(context.TODO(), dst.GetNamespacedName(), cs); err != nil {
+		return false, fmt.Errorf("failed to get %v: %w", dst, err)
+	}
+
+	template := conversion.DeploymentToCloneSet(srcDeployment, dst.Name).Spec.Template
+	if equality.Semantic.DeepEqual(cs.Spec.Template, template) {
+		return false, nil
+	}
+	cs.Spec.Template = template
+	if err := c.client.Update(context.TODO(), cs); err != nil {
+		return false, fmt.Errorf("failed to update %v: %w", dst, err)
+	}
+	return true, nil
+}
+
 func (c *control) getDeployment(ref api.ResourceRef) (*apps.Deployment, error) {
 	d := &apps.Deployment{}
 	if err := c.client.Get(context.TODO(), ref.GetNamespacedName(), d); err != nil {
-		return nil, fmt.Errorf("failed to get %v: %v", ref, err)
+		return nil, fmt.Errorf("failed to get %v: %w", ref, err)
 	}
 	return d, nil
 }
@@ -81,7 +117,7 @@ func (c *control) getDeployment(ref api.ResourceRef) (*apps.Deployment, error) {
 func (c *control) ensureCloneSetNotExists(ref api.ResourceRef) error {
 	cs := &appsv1alpha1.CloneSet{}
 	if err := c.client.Get(context.TODO(), ref.GetNamespacedName(), cs); err == nil {
-		return fmt.Errorf("cloneset %v already exists", ref.GetNamespacedName())
+		return fmt.Errorf("cloneset %v %w", ref.GetNamespacedName(), creation.ErrDstExists)
 	} else if !errors.IsNotFound(err) {
 		return fmt.Errorf("failed to get %v: %v", ref, err)
 	}
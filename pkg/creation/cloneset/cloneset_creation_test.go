@@ -0,0 +1,89 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloneset
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/creation"
+
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newDeployment(image string) *apps.Deployment {
+	replicas := int32(3)
+	labels := map[string]string{"app": "web"}
+	return &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+			},
+		},
+	}
+}
+
+func TestCreateAndSyncTemplate(t *testing.T) {
+	deployment := newDeployment("nginx:1.20")
+	c := &control{client: fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(deployment).Build()}
+	src, dst := api.NewDeploymentRef("default", "web"), api.NewCloneSetRef("default", "web-cs")
+
+	if err := c.Create(src, dst, creation.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	cs := &appsv1alpha1.CloneSet{}
+	if err := c.client.Get(context.TODO(), dst.GetNamespacedName(), cs); err != nil {
+		t.Fatal(err)
+	}
+	if *cs.Spec.Replicas != 0 {
+		t.Errorf("expected the CloneSet to be created with replicas=0, got %d", *cs.Spec.Replicas)
+	}
+	if err := c.Create(src, dst, creation.Options{}); !errors.Is(err, creation.ErrDstExists) {
+		t.Errorf("expected ErrDstExists, got %v", err)
+	}
+
+	if changed, err := c.SyncTemplate(src, dst); err != nil || changed {
+		t.Errorf("expected the template to be in sync, got changed=%v err=%v", changed, err)
+	}
+
+	deployment.Spec.Template.Spec.Containers[0].Image = "nginx:1.21"
+	if err := c.client.Update(context.TODO(), deployment); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := c.SyncTemplate(src, dst); err != nil || !changed {
+		t.Fatalf("expected the template to be synced, got changed=%v err=%v", changed, err)
+	}
+	if err := c.client.Get(context.TODO(), dst.GetNamespacedName(), cs); err != nil {
+		t.Fatal(err)
+	}
+	if image := cs.Spec.Template.Spec.Containers[0].Image; image != "nginx:1.21" {
+		t.Errorf("expected the synced image nginx:1.21, got %s", image)
+	}
+	if *cs.Spec.Replicas != 0 {
+		t.Errorf("expected the replicas to be left at 0, got %d", *cs.Spec.Replicas)
+	}
+}
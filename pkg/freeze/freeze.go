@@ -0,0 +1,356 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package freeze refuses the changes of kubectl-kruise during the freeze windows the cluster
+// declares in the ConfigMap kruise-system/kubectl-kruise-freeze, such as from Friday 18:00 to
+// Monday 06:00, so that an organization shares one guardrail for all its users. An emergency
+// change is made with --break-glass REASON, or the KUBECTL_KRUISE_BREAK_GLASS environment
+// variable, which is added to the user agent of the requests, recorded in the audit log of the
+// API server, and to the change cause with --record, see BreakGlass. As in read-only mode, the
+// requests to the API server go through the transport installed by WithFreeze, so that no
+// command has to be trusted to check.
+package freeze
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openkruise/kruise-tools/pkg/readonly"
+
+	"github.com/spf13/pflag"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ConfigMapNamespace and ConfigMapName locate the ConfigMap declaring the freeze windows. It
+	// must be readable by all the users of kubectl-kruise: the changes are not refused, only
+	// warned about, when reading it is forbidden.
+	ConfigMapNamespace = "kruise-system"
+	ConfigMapName      = "kubectl-kruise-freeze"
+	// WindowsKey is the key of the ConfigMap holding the YAML list of windows.
+	WindowsKey = "windows"
+
+	// EnvName sets the reason of --break-glass.
+	EnvName = "KUBECTL_KRUISE_BREAK_GLASS"
+
+	flagName = "break-glass"
+)
+
+// exemptSubresources are the subresources whose requests are never refused, so that incidents
+// can still be troubleshot during a freeze.
+var exemptSubresources = []string{"exec", "attach", "portforward", "proxy"}
+
+var (
+	breakGlass = os.Getenv(EnvName)
+
+	// warnOut receives the warning of the changes made with --break-glass during a window.
+	warnOut io.Writer = os.Stderr
+	warned  sync.Once
+
+	// windowsByHost caches the windows of each API server for the life of the process.
+	windowsByHost = map[string][]Window{}
+	windowsLock   sync.Mutex
+)
+
+// Window is a period during which changes are refused.
+type Window struct {
+	// Name is the name of the window in messages, e.g. weekend.
+	Name string `json:"name"`
+	// Start and End are either weekly times, such as "Fri 18:00" and "Mon 06:00", or RFC 3339
+	// times, such as "2022-11-25T00:00:00Z", for a single period.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// TimeZone is the IANA time zone of weekly times, e.g. Europe/Paris. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+	// Namespaces restricts the window to the changes in these namespaces. Defaults to all
+	// changes, including those of cluster-scoped resources.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// AddFlags adds the --break-glass flag to flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&breakGlass, flagName, breakGlass, "Make changes during a freeze window of the cluster for this reason, e.g. an incident number. The reason is recorded in the audit log of the API server, and in the change cause with --record. Also set by the "+EnvName+" environment variable.")
+}
+
+// BreakGlass returns the reason given with --break-glass, if any.
+func BreakGlass() string {
+	return breakGlass
+}
+
+// ParseArgs sets --break-glass if args, a command line parsed by another program such as
+// kubectl, have it, and returns args without it.
+func ParseArgs(args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--"+flagName && i+1 < len(args):
+			breakGlass = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--"+flagName+"="):
+			breakGlass = strings.TrimPrefix(args[i], "--"+flagName+"=")
+		default:
+			result = append(result, args[i])
+		}
+	}
+	return result
+}
+
+// ParseWindows returns the windows of data, the YAML list of the ConfigMap.
+func ParseWindows(data string) ([]Window, error) {
+	var windows []Window
+	if err := yaml.UnmarshalStrict([]byte(data), &windows); err != nil {
+		return nil, fmt.Errorf("invalid freeze windows in %s/%s: %v", ConfigMapNamespace, ConfigMapName, err)
+	}
+	for i := range windows {
+		if len(windows[i].Name) == 0 {
+			windows[i].Name = fmt.Sprintf("#%d", i+1)
+		}
+		if _, _, err := windows[i].Until(time.Now()); err != nil {
+			return nil, fmt.Errorf("invalid freeze windows in %s/%s: %v", ConfigMapNamespace, ConfigMapName, err)
+		}
+	}
+	return windows, nil
+}
+
+// Until returns the end of the occurrence of w that now is in, and false if now is not in w.
+func (w *Window) Until(now time.Time) (time.Time, bool, error) {
+	if start, err := time.Parse(time.RFC3339, w.Start); err == nil {
+		end, err := time.Parse(time.RFC3339, w.End)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("window %s: end %q must be an RFC 3339 time like its start", w.Name, w.End)
+		}
+		return end, !now.Before(start) && now.Before(end), nil
+	}
+
+	location := time.UTC
+	if len(w.TimeZone) > 0 {
+		var err error
+		if location, err = time.LoadLocation(w.TimeZone); err != nil {
+			return time.Time{}, false, fmt.Errorf("window %s: %v", w.Name, err)
+		}
+	}
+	start, err := parseWeekTime(w.Start)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("window %s: %v", w.Name, err)
+	}
+	end, err := parseWeekTime(w.End)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("window %s: %v", w.Name, err)
+	}
+
+	const week = 7 * 24 * 60
+	now = now.In(location)
+	current := (int(now.Weekday())*24+now.Hour())*60 + now.Minute()
+	in := current >= start && current < end
+	if start > end {
+		// the window spans the end of the week, e.g. from Friday to Monday
+		in = current >= start || current < end
+	}
+	if !in {
+		return time.Time{}, false, nil
+	}
+	minutes := (end - current + week) % week
+	return now.Truncate(time.Minute).Add(time.Duration(minutes) * time.Minute), true, nil
+}
+
+// parseWeekTime returns the minutes since Sunday 00:00 of a weekly time, such as "Fri 18:00".
+func parseWeekTime(value string) (int, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("invalid time %q, must be a day and a time such as \"Fri 18:00\", or an RFC 3339 time", value)
+	}
+	day := -1
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(fields[0], d.String()) || strings.EqualFold(fields[0], d.String()[:3]) {
+			day = int(d)
+		}
+	}
+	if day < 0 {
+		return 0, fmt.Errorf("invalid day %q in time %q", fields[0], value)
+	}
+	clock, err := time.Parse("15:04", fields[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q in time %q, must be HH:MM", fields[1], value)
+	}
+	return (day*24+clock.Hour())*60 + clock.Minute(), nil
+}
+
+// ActiveWindow returns the window of windows that refuses the changes in namespace at now, and the
+// end of its current occurrence, or nil. Namespace is empty for cluster-scoped resources.
+func ActiveWindow(windows []Window, namespace string, now time.Time) (*Window, time.Time) {
+	for i := range windows {
+		w := &windows[i]
+		if len(w.Namespaces) > 0 && !contains(w.Namespaces, namespace) {
+			continue
+		}
+		if until, in, err := w.Until(now); err == nil && in {
+			return w, until
+		}
+	}
+	return nil, time.Time{}
+}
+
+// Check returns an error if a freeze window of the cluster of getter refuses the changes in
+// namespace by operation, unless --break-glass is given.
+func Check(getter genericclioptions.RESTClientGetter, namespace, operation string) error {
+	config, err := getter.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	windows, err := windowsFor(config)
+	if err != nil {
+		return err
+	}
+	return check(windows, namespace, operation, time.Now())
+}
+
+func check(windows []Window, namespace, operation string, now time.Time) error {
+	w, until := ActiveWindow(windows, namespace, now)
+	if w == nil {
+		return nil
+	}
+	if len(breakGlass) == 0 {
+		return fmt.Errorf("%s is refused by the freeze window %s until %s, use --break-glass REASON for an emergency change", operation, w.Name, until.Format(time.RFC3339))
+	}
+	warned.Do(func() {
+		fmt.Fprintf(warnOut, "Warning: breaking the freeze window %s, which lasts until %s, for %q\n", w.Name, until.Format(time.RFC3339), breakGlass)
+	})
+	return nil
+}
+
+// windowsFor returns the windows of the API server of config, read once per process.
+func windowsFor(config *rest.Config) ([]Window, error) {
+	windowsLock.Lock()
+	defer windowsLock.Unlock()
+	if windows, ok := windowsByHost[config.Host]; ok {
+		return windows, nil
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	var windows []Window
+	cm, err := client.CoreV1().ConfigMaps(ConfigMapNamespace).Get(context.TODO(), ConfigMapName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		// no window is declared
+	case apierrors.IsForbidden(err):
+		fmt.Fprintf(warnOut, "Warning: the freeze windows of the cluster are not checked, the ConfigMap %s/%s must be readable by all users: %v\n", ConfigMapNamespace, ConfigMapName, err)
+	case err != nil:
+		return nil, fmt.Errorf("unable to read the freeze windows of the cluster: %v", err)
+	default:
+		if windows, err = ParseWindows(cm.Data[WindowsKey]); err != nil {
+			return nil, err
+		}
+	}
+	windowsByHost[config.Host] = windows
+	return windows, nil
+}
+
+// WithFreeze returns getter with a transport that refuses the requests that may change resources
+// during the freeze windows of the cluster.
+func WithFreeze(getter genericclioptions.RESTClientGetter) genericclioptions.RESTClientGetter {
+	return &freezeClientGetter{RESTClientGetter: getter}
+}
+
+type freezeClientGetter struct {
+	genericclioptions.RESTClientGetter
+}
+
+// ToRESTConfig returns the config of the embedded getter with the freeze transport.
+func (g *freezeClientGetter) ToRESTConfig() (*rest.Config, error) {
+	config, err := g.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	base := rest.CopyConfig(config)
+	config = rest.CopyConfig(config)
+	config.WrapTransport = transport.Wrappers(config.WrapTransport, func(rt http.RoundTripper) http.RoundTripper {
+		return &roundTripper{delegate: rt, windows: func() ([]Window, error) { return windowsFor(base) }}
+	})
+	return config, nil
+}
+
+// roundTripper refuses the requests that may change resources during a freeze window, and adds
+// the reason of --break-glass to the user agent of the ones it lets through.
+type roundTripper struct {
+	delegate http.RoundTripper
+	windows  func() ([]Window, error)
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if readonly.ReadOnlyRequest(req) || exemptRequest(req) {
+		return rt.delegate.RoundTrip(req)
+	}
+	windows, err := rt.windows()
+	if err != nil {
+		return nil, err
+	}
+	namespace := requestNamespace(req)
+	if err := check(windows, namespace, req.Method+" "+req.URL.Path, time.Now()); err != nil {
+		return nil, err
+	}
+	if w, _ := ActiveWindow(windows, namespace, time.Now()); w != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", strings.TrimSpace(req.Header.Get("User-Agent")+" break-glass/"+strings.ReplaceAll(breakGlass, " ", "_")))
+	}
+	return rt.delegate.RoundTrip(req)
+}
+
+// requestNamespace returns the namespace of the resource of req, or an empty string for the
+// cluster-scoped resources and the namespaces themselves.
+func requestNamespace(req *http.Request) string {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i := 0; i+2 < len(parts); i++ {
+		if parts[i] == "namespaces" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// exemptRequest returns true if req is for one of exemptSubresources.
+func exemptRequest(req *http.Request) bool {
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	for _, subresource := range exemptSubresources {
+		if strings.HasSuffix(path, "/"+subresource) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
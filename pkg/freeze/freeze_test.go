@@ -0,0 +1,253 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package freeze
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows(`
+- name: weekend
+  start: Fri 18:00
+  end: Monday 06:00
+  timeZone: Europe/Paris
+- start: "2022-11-25T00:00:00Z"
+  end: "2022-11-28T00:00:00Z"
+  namespaces: [prod]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 2 || windows[0].Name != "weekend" || windows[1].Name != "#2" || !reflect.DeepEqual(windows[1].Namespaces, []string{"prod"}) {
+		t.Errorf("unexpected windows %+v", windows)
+	}
+
+	for _, data := range []string{
+		"- {start: Fri 18:00, end: Mon}",
+		"- {start: Fri 18:00, end: Mon 25:00}",
+		"- {start: Someday 18:00, end: Mon 06:00}",
+		"- {start: Fri 18:00, end: Mon 06:00, timeZone: Nowhere/Town}",
+		"- {start: \"2022-11-25T00:00:00Z\", end: Mon 06:00}",
+		"- {start: Fri 18:00, end: Mon 06:00, days: 3}",
+	} {
+		if _, err := ParseWindows(data); err == nil {
+			t.Errorf("%s: expected error", data)
+		}
+	}
+}
+
+func TestUntil(t *testing.T) {
+	weekend := Window{Name: "weekend", Start: "Fri 18:00", End: "Mon 06:00"}
+	night := Window{Name: "night", Start: "Tue 22:00", End: "Wed 02:00", TimeZone: "Asia/Shanghai"}
+	blackFriday := Window{Name: "black-friday", Start: "2022-11-25T00:00:00Z", End: "2022-11-28T00:00:00Z"}
+	// 2022-11-25 is a Friday
+	tests := []struct {
+		window   Window
+		now      string
+		in       bool
+		expected string
+	}{
+		{window: weekend, now: "2022-11-25T17:59:00Z"},
+		{window: weekend, now: "2022-11-25T18:00:00Z", in: true, expected: "2022-11-28T06:00:00Z"},
+		{window: weekend, now: "2022-11-27T23:30:45Z", in: true, expected: "2022-11-28T06:00:00Z"},
+		{window: weekend, now: "2022-11-28T06:00:00Z"},
+		{window: weekend, now: "2022-11-23T12:00:00Z"},
+		// 2022-11-29T15:00:00Z is Tuesday 23:00 in Shanghai
+		{window: night, now: "2022-11-29T15:00:00Z", in: true, expected: "2022-11-29T18:00:00Z"},
+		{window: night, now: "2022-11-29T13:00:00Z"},
+		{window: blackFriday, now: "2022-11-26T10:00:00Z", in: true, expected: "2022-11-28T00:00:00Z"},
+		{window: blackFriday, now: "2022-12-02T18:00:00Z"},
+	}
+	for _, test := range tests {
+		now, _ := time.Parse(time.RFC3339, test.now)
+		until, in, err := test.window.Until(now)
+		if err != nil {
+			t.Errorf("%s at %s: unexpected error: %v", test.window.Name, test.now, err)
+			continue
+		}
+		if in != test.in {
+			t.Errorf("%s at %s: expected in %v, got %v", test.window.Name, test.now, test.in, in)
+		}
+		if in && !until.Equal(mustParse(test.expected)) {
+			t.Errorf("%s at %s: expected until %s, got %s", test.window.Name, test.now, test.expected, until.UTC().Format(time.RFC3339))
+		}
+	}
+}
+
+func mustParse(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
+
+func TestActiveWindow(t *testing.T) {
+	windows := []Window{
+		{Name: "prod-only", Start: "Fri 00:00", End: "Sat 00:00", Namespaces: []string{"prod"}},
+		{Name: "weekend", Start: "Sat 00:00", End: "Mon 00:00"},
+	}
+	friday, saturday := mustParse("2022-11-25T12:00:00Z"), mustParse("2022-11-26T12:00:00Z")
+	if w, _ := ActiveWindow(windows, "prod", friday); w == nil || w.Name != "prod-only" {
+		t.Errorf("expected window prod-only for prod on friday, got %v", w)
+	}
+	if w, _ := ActiveWindow(windows, "dev", friday); w != nil {
+		t.Errorf("expected no window for dev on friday, got %v", w)
+	}
+	if w, _ := ActiveWindow(windows, "", saturday); w == nil || w.Name != "weekend" {
+		t.Errorf("expected window weekend for cluster-scoped resources on saturday, got %v", w)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	defer func(old string) { breakGlass = old }(breakGlass)
+
+	tests := []struct {
+		args       []string
+		expected   []string
+		breakGlass string
+	}{
+		{args: []string{"label", "pod/demo", "a=b"}, expected: []string{"label", "pod/demo", "a=b"}},
+		{args: []string{"--break-glass", "INC-42", "label", "pod/demo", "a=b"}, expected: []string{"label", "pod/demo", "a=b"}, breakGlass: "INC-42"},
+		{args: []string{"label", "pod/demo", "a=b", "--break-glass=INC 42"}, expected: []string{"label", "pod/demo", "a=b"}, breakGlass: "INC 42"},
+	}
+	for _, test := range tests {
+		breakGlass = ""
+		if got := ParseArgs(test.args); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%v: expected args %v, got %v", test.args, test.expected, got)
+		}
+		if breakGlass != test.breakGlass {
+			t.Errorf("%v: expected --break-glass %q, got %q", test.args, test.breakGlass, breakGlass)
+		}
+	}
+}
+
+func TestWithFreeze(t *testing.T) {
+	defer func(old string) { breakGlass = old }(breakGlass)
+	defer func() { windowsByHost = map[string][]Window{} }()
+	warnings := &strings.Builder{}
+	defer func(old interface{ Write([]byte) (int, error) }) { warnOut = old }(warnOut)
+	warnOut = warnings
+	warned = sync.Once{}
+
+	var served []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/api/v1/namespaces/kruise-system/configmaps/kubectl-kruise-freeze" {
+			cm := &corev1.ConfigMap{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: ConfigMapNamespace, Name: ConfigMapName},
+				Data:       map[string]string{WindowsKey: `[{name: release, start: "2000-01-01T00:00:00Z", end: "2100-01-01T00:00:00Z", namespaces: [prod]}]`},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(cm)
+			return
+		}
+		served = append(served, req.Method+" "+req.URL.Path+" "+req.Header.Get("User-Agent"))
+		_, _ = ioutil.ReadAll(req.Body)
+	}))
+	defer server.Close()
+
+	f := cmdtesting.NewTestFactory()
+	defer f.Cleanup()
+	f.ClientConfigVal = &rest.Config{Host: server.URL, UserAgent: "kubectl-kruise"}
+	config, err := WithFreeze(f).ToRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := rest.TransportFor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rt}
+
+	tests := []struct {
+		method     string
+		path       string
+		breakGlass string
+		allowed    bool
+		userAgent  string
+	}{
+		{method: http.MethodGet, path: "/apis/apps.kruise.io/v1alpha1/namespaces/prod/clonesets", allowed: true},
+		{method: http.MethodPatch, path: "/apis/apps.kruise.io/v1alpha1/namespaces/dev/clonesets/demo", allowed: true},
+		{method: http.MethodPost, path: "/api/v1/namespaces/prod/pods/demo-0/exec", allowed: true},
+		{method: http.MethodPatch, path: "/apis/apps.kruise.io/v1alpha1/namespaces/prod/clonesets/demo"},
+		{method: http.MethodDelete, path: "/api/v1/namespaces/prod/pods/demo-0"},
+		{method: http.MethodPatch, path: "/apis/apps.kruise.io/v1alpha1/namespaces/prod/clonesets/demo", breakGlass: "INC 42", allowed: true, userAgent: "kubectl-kruise break-glass/INC_42"},
+	}
+	for _, test := range tests {
+		served = nil
+		breakGlass = test.breakGlass
+		req, _ := http.NewRequest(test.method, server.URL+test.path, nil)
+		resp, err := client.Do(req)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if test.allowed != (err == nil) || test.allowed != (len(served) == 1) {
+			t.Errorf("%s %s: expected allowed %v, got error %v and served %v", test.method, test.path, test.allowed, err, served)
+			continue
+		}
+		if !test.allowed && !strings.Contains(err.Error(), "refused by the freeze window release until 2100-01-01T00:00:00Z") {
+			t.Errorf("%s %s: unexpected error %v", test.method, test.path, err)
+		}
+		if len(test.userAgent) > 0 && !strings.HasSuffix(served[0], " "+test.userAgent) {
+			t.Errorf("%s %s: expected user agent %q, got %v", test.method, test.path, test.userAgent, served)
+		}
+	}
+	if !strings.Contains(warnings.String(), `breaking the freeze window release, which lasts until 2100-01-01T00:00:00Z, for "INC 42"`) {
+		t.Errorf("unexpected warnings %q", warnings.String())
+	}
+}
+
+func TestWindowsForbidden(t *testing.T) {
+	defer func() { windowsByHost = map[string][]Window{} }()
+	warnings := &strings.Builder{}
+	defer func(old interface{ Write([]byte) (int, error) }) { warnOut = old }(warnOut)
+	warnOut = warnings
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		status := &metav1.Status{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"},
+			Status:   metav1.StatusFailure,
+			Reason:   metav1.StatusReasonForbidden,
+			Code:     http.StatusForbidden,
+			Message:  `configmaps "kubectl-kruise-freeze" is forbidden`,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(status)
+	}))
+	defer server.Close()
+
+	windows, err := windowsFor(&rest.Config{Host: server.URL})
+	if err != nil || windows != nil {
+		t.Errorf("expected no window without read access, got %v, %v", windows, err)
+	}
+	if !strings.Contains(warnings.String(), "Warning: the freeze windows of the cluster are not checked, the ConfigMap kruise-system/kubectl-kruise-freeze must be readable by all users") {
+		t.Errorf("unexpected warnings %q", warnings.String())
+	}
+}
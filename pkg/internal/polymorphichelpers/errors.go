@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	deploymentKinds  = []schema.GroupKind{{Group: "apps", Kind: "Deployment"}, {Group: "extensions", Kind: "Deployment"}}
+	daemonSetKinds   = []schema.GroupKind{{Group: "apps", Kind: "DaemonSet"}, {Group: "extensions", Kind: "DaemonSet"}}
+	statefulSetKind  = schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+	cloneSetKind     = schema.GroupKind{Group: "apps.kruise.io", Kind: "CloneSet"}
+	advancedSetKind  = schema.GroupKind{Group: "apps.kruise.io", Kind: "StatefulSet"}
+	advancedDSKind   = schema.GroupKind{Group: "apps.kruise.io", Kind: "DaemonSet"}
+	rolloutKind      = schema.GroupKind{Group: "rollouts.kruise.io", Kind: "Rollout"}
+	batchReleaseKind = schema.GroupKind{Group: "rollouts.kruise.io", Kind: "BatchRelease"}
+	revisionedKinds  = append(append([]schema.GroupKind{statefulSetKind, cloneSetKind, advancedSetKind}, deploymentKinds...), daemonSetKinds...)
+	// ControllerRevisionKinds are the kinds whose history is kept in ControllerRevisions.
+	ControllerRevisionKinds = append([]schema.GroupKind{statefulSetKind, cloneSetKind, advancedSetKind}, daemonSetKinds...)
+	pausableKinds           = append([]schema.GroupKind{cloneSetKind, rolloutKind}, deploymentKinds...)
+	restartableKinds        = append(append([]schema.GroupKind{statefulSetKind, cloneSetKind}, deploymentKinds...), daemonSetKinds...)
+	// PartitionedKinds are the kinds whose updates can be staged by partition.
+	PartitionedKinds = []schema.GroupKind{cloneSetKind, advancedSetKind, advancedDSKind}
+	approvableKinds  = []schema.GroupKind{rolloutKind}
+	promotableKinds  = []schema.GroupKind{rolloutKind, batchReleaseKind, cloneSetKind, advancedSetKind}
+)
+
+// UnsupportedKindError is returned by the polymorphic functions for kinds they do not support.
+type UnsupportedKindError struct {
+	// Operation is what is not supported, e.g. "pausing"
+	Operation string
+	Kind      schema.GroupKind
+	Supported []schema.GroupKind
+}
+
+// Error implements error
+func (e *UnsupportedKindError) Error() string {
+	var supported []string
+	for _, kind := range e.Supported {
+		supported = append(supported, kind.String())
+	}
+	sort.Strings(supported)
+
+	msg := fmt.Sprintf("%s is not supported for kind %s", e.Operation, e.Kind.String())
+	if len(supported) > 0 {
+		msg += fmt.Sprintf(", supported kinds: %s", strings.Join(supported, ", "))
+	}
+	if suggestion, ok := e.Suggestion(); ok {
+		msg += fmt.Sprintf("; did you mean %s?", suggestion.String())
+	}
+	return msg
+}
+
+// Suggestion returns the supported kind the user most likely meant: the same kind in another
+// group, such as the Advanced StatefulSet for a StatefulSet, or a kind with a similar name.
+func (e *UnsupportedKindError) Suggestion() (schema.GroupKind, bool) {
+	var best schema.GroupKind
+	bestDistance := -1
+	for _, kind := range e.Supported {
+		if strings.EqualFold(kind.Kind, e.Kind.Kind) {
+			return kind, true
+		}
+		distance := editDistance(strings.ToLower(kind.Kind), strings.ToLower(e.Kind.Kind))
+		if distance <= 2 && (bestDistance < 0 || distance < bestDistance) {
+			best, bestDistance = kind, distance
+		}
+	}
+	return best, bestDistance >= 0
+}
+
+// IsUnsupportedKind returns true if err is an UnsupportedKindError.
+func IsUnsupportedKind(err error) bool {
+	var unsupported *UnsupportedKindError
+	return errors.As(err, &unsupported)
+}
+
+// NewUnsupportedKindError returns an UnsupportedKindError for the kind of obj.
+func NewUnsupportedKindError(operation string, obj runtime.Object, supported ...schema.GroupKind) error {
+	return &UnsupportedKindError{Operation: operation, Kind: groupKindForObject(obj), Supported: supported}
+}
+
+// newUnsupportedKindError returns an UnsupportedKindError for kind. The kinds registered with a
+// handler for the operation, as reported by registered, are added to the builtin ones.
+func newUnsupportedKindError(operation string, kind schema.GroupKind, builtin []schema.GroupKind, registered func(Handlers) bool) error {
+	supported := append([]schema.GroupKind{}, builtin...)
+	for _, k := range DefaultRegistry.Kinds() {
+		if handlers, _ := DefaultRegistry.Get(k); registered(handlers) {
+			supported = append(supported, k)
+		}
+	}
+	return &UnsupportedKindError{Operation: operation, Kind: kind, Supported: supported}
+}
+
+// groupKindForObject returns the kind of obj. Typed objects usually have no kind set, so it is
+// looked up in the scheme.
+func groupKindForObject(obj runtime.Object) schema.GroupKind {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() {
+		if kinds, _, err := internalapi.GetScheme().ObjectKinds(obj); err == nil && len(kinds) > 0 {
+			gvk = kinds[0]
+		}
+	}
+	if gvk.Empty() {
+		return schema.GroupKind{Kind: fmt.Sprintf("%T", obj)}
+	}
+	return gvk.GroupKind()
+}
+
+// editDistance returns the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestUnsupportedKindError(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func() error
+		expected string
+	}{
+		{
+			name:     "pause a statefulset",
+			fn:       func() error { _, err := ObjectPauserFn(&appsv1.StatefulSet{}); return err },
+			expected: "pausing is not supported for kind StatefulSet.apps, supported kinds: CloneSet.apps.kruise.io, Deployment.apps, Deployment.extensions, Rollout.rollouts.kruise.io",
+		},
+		{
+			name:     "approve a deployment",
+			fn:       func() error { _, err := ObjectApproverFn(&appsv1.Deployment{}); return err },
+			expected: "approving is not supported for kind Deployment.apps, supported kinds: Rollout.rollouts.kruise.io",
+		},
+		{
+			name:     "partition of a native statefulset",
+			fn:       func() error { return UpdatePartitionForObject(&appsv1.StatefulSet{}, 1) },
+			expected: "setting the partition is not supported for kind StatefulSet.apps, supported kinds: CloneSet.apps.kruise.io, DaemonSet.apps.kruise.io, StatefulSet.apps.kruise.io; did you mean StatefulSet.apps.kruise.io?",
+		},
+		{
+			name: "status of a job",
+			fn: func() error {
+				_, err := StatusViewerFor(batchv1.SchemeGroupVersion.WithKind("Job").GroupKind())
+				return err
+			},
+			expected: "viewing rollout status is not supported for kind Job.batch, supported kinds: CloneSet.apps.kruise.io, DaemonSet.apps, DaemonSet.extensions, Deployment.apps, Deployment.extensions, StatefulSet.apps, StatefulSet.apps.kruise.io",
+		},
+		{
+			name: "history of a misspelled kind",
+			fn: func() error {
+				_, err := HistoryViewerFor(schema.GroupKind{Group: "apps.kruise.io", Kind: "CloneSets"}, nil, nil)
+				return err
+			},
+			expected: "viewing history is not supported for kind CloneSets.apps.kruise.io, supported kinds: CloneSet.apps.kruise.io, DaemonSet.apps, DaemonSet.extensions, Deployment.apps, Deployment.extensions, StatefulSet.apps, StatefulSet.apps.kruise.io; did you mean CloneSet.apps.kruise.io?",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.fn()
+			if !IsUnsupportedKind(err) {
+				t.Fatalf("expected an UnsupportedKindError, got %v", err)
+			}
+			if err.Error() != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestUnsupportedKindErrorWithRegisteredKind(t *testing.T) {
+	custom := schema.GroupKind{Group: "apps.example.com", Kind: "Workload"}
+	Register(custom, Handlers{ObjectApprover: func(runtime.Object) ([]byte, error) { return nil, nil }})
+	defer DefaultRegistry.Unregister(custom)
+
+	_, err := ObjectApproverFn(&appsv1.Deployment{})
+	expected := "approving is not supported for kind Deployment.apps, supported kinds: Rollout.rollouts.kruise.io, Workload.apps.example.com"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
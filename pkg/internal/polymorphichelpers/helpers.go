@@ -36,6 +36,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/watch"
 	coreclient "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
 	watchtools "k8s.io/client-go/tools/watch"
 )
 
@@ -62,13 +63,17 @@ func GetFirstPod(client coreclient.PodsGetter, namespace string, selector string
 		return pods[0], len(podList.Items), nil
 	}
 
-	// Watch until we observe a pod
-	options.ResourceVersion = podList.ResourceVersion
-	w, err := client.Pods(namespace).Watch(context.TODO(), options)
-	if err != nil {
-		return nil, 0, err
+	// Watch until we observe a pod, resuming the watch if the API server restarts
+	lw := &cache.ListWatch{
+		ListFunc: func(listOptions metav1.ListOptions) (runtime.Object, error) {
+			listOptions.LabelSelector = selector
+			return client.Pods(namespace).List(context.TODO(), listOptions)
+		},
+		WatchFunc: func(listOptions metav1.ListOptions) (watch.Interface, error) {
+			listOptions.LabelSelector = selector
+			return client.Pods(namespace).Watch(context.TODO(), listOptions)
+		},
 	}
-	defer w.Stop()
 
 	condition := func(event watch.Event) (bool, error) {
 		return event.Type == watch.Added || event.Type == watch.Modified, nil
@@ -76,7 +81,7 @@ func GetFirstPod(client coreclient.PodsGetter, namespace string, selector string
 
 	ctx, cancel := watchtools.ContextWithOptionalTimeout(context.Background(), timeout)
 	defer cancel()
-	event, err := watchtools.UntilWithoutRetry(ctx, w, condition)
+	event, err := UntilWithResume(ctx, lw, nil, condition)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -144,6 +149,24 @@ func SelectorsForObject(object runtime.Object) (namespace string, selector label
 		if err != nil {
 			return "", nil, fmt.Errorf("invalid label selector:%v", err)
 		}
+	case *kruiseappsv1beta1.StatefulSet:
+		namespace = t.Namespace
+		selector, err = metav1.LabelSelectorAsSelector(t.Spec.Selector)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid label selector: %v", err)
+		}
+	case *kruiseappsv1alpha1.StatefulSet:
+		namespace = t.Namespace
+		selector, err = metav1.LabelSelectorAsSelector(t.Spec.Selector)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid label selector: %v", err)
+		}
+	case *kruiseappsv1alpha1.DaemonSet:
+		namespace = t.Namespace
+		selector, err = metav1.LabelSelectorAsSelector(t.Spec.Selector)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid label selector: %v", err)
+		}
 	case *appsv1.DaemonSet:
 		namespace = t.Namespace
 		selector, err = metav1.LabelSelectorAsSelector(t.Spec.Selector)
@@ -203,6 +226,52 @@ func SelectorsForObject(object runtime.Object) (namespace string, selector label
 	return namespace, selector, nil
 }
 
+// PodsForObject returns the pod itself for a pod, or the pods selected by a workload, sorted by name.
+func PodsForObject(client coreclient.PodsGetter, object runtime.Object) ([]corev1.Pod, error) {
+	if pod, ok := object.(*corev1.Pod); ok {
+		return []corev1.Pod{*pod}, nil
+	}
+	namespace, selector, err := SelectorsForObject(object)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the pods of %T: %v", object, err)
+	}
+	// an empty selector would list all pods in the namespace
+	if len(selector.String()) == 0 {
+		return nil, fmt.Errorf("cannot get the pods of %T: it has no pod selector", object)
+	}
+	podList, err := client.Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	pods := podList.Items
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	return pods, nil
+}
+
+// PodUpdatePolicyForObject returns how the pods of a Kruise workload are updated when its
+// pod template changes (ReCreate, InPlaceIfPossible or InPlaceOnly), and false if the object
+// does not support in-place update at all.
+func PodUpdatePolicyForObject(object runtime.Object) (string, bool) {
+	switch t := object.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		if len(t.Spec.UpdateStrategy.Type) == 0 {
+			return string(kruiseappsv1alpha1.RecreateCloneSetUpdateStrategyType), true
+		}
+		return string(t.Spec.UpdateStrategy.Type), true
+	case *kruiseappsv1beta1.StatefulSet:
+		if t.Spec.UpdateStrategy.RollingUpdate == nil || len(t.Spec.UpdateStrategy.RollingUpdate.PodUpdatePolicy) == 0 {
+			return string(kruiseappsv1beta1.RecreatePodUpdateStrategyType), true
+		}
+		return string(t.Spec.UpdateStrategy.RollingUpdate.PodUpdatePolicy), true
+	case *kruiseappsv1alpha1.StatefulSet:
+		if t.Spec.UpdateStrategy.RollingUpdate == nil || len(t.Spec.UpdateStrategy.RollingUpdate.PodUpdatePolicy) == 0 {
+			return string(kruiseappsv1alpha1.RecreatePodUpdateStrategyType), true
+		}
+		return string(t.Spec.UpdateStrategy.RollingUpdate.PodUpdatePolicy), true
+	}
+	return "", false
+}
+
 func findEnv(env []corev1.EnvVar, name string) (corev1.EnvVar, bool) {
 	for _, e := range env {
 		if e.Name == name {
@@ -22,6 +22,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
 	"text/tabwriter"
 
 	internalapps "github.com/openkruise/kruise-tools/pkg/internal/apps"
@@ -91,12 +92,8 @@ func HistoryViewerFor(kind schema.GroupKind, c kubernetes.Interface, kc kruisecl
 	// Determine which HistoryViewer we need here
 	err := elem.Accept(visitor)
 
-	if err != nil {
-		return nil, fmt.Errorf("error retrieving history for %q, %v", kind.String(), err)
-	}
-
-	if visitor.result == nil {
-		return nil, fmt.Errorf("no history viewer has been implemented for %q", kind.String())
+	if err != nil || visitor.result == nil {
+		return nil, newUnsupportedKindError("viewing history", kind, revisionedKinds, func(h Handlers) bool { return h.HistoryViewer != nil })
 	}
 
 	return visitor.result, nil
@@ -249,6 +246,87 @@ func (h *DaemonSetHistoryViewer) ViewHistory(namespace, name string, revision in
 	})
 }
 
+// RevisionTemplate is a ControllerRevision of a workload and the pod template it rolls out.
+type RevisionTemplate struct {
+	Revision *appsv1.ControllerRevision
+	Template *corev1.PodTemplateSpec
+}
+
+// RevisionTemplatesFor returns the ControllerRevisions of the workload of kind named name in namespace,
+// sorted by revision, with the pod templates they roll out. Deployments keep their history in
+// ReplicaSets instead of ControllerRevisions and are not supported.
+func RevisionTemplatesFor(kind schema.GroupKind, c kubernetes.Interface, kc kruiseclientsets.Interface, namespace, name string) ([]RevisionTemplate, error) {
+	var history []*appsv1.ControllerRevision
+	var getPodTemplate func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error)
+	switch {
+	case kind == cloneSetKind:
+		cs, csHistory, err := clonesetHistory(c.AppsV1(), kc.AppsV1alpha1(), namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		history = csHistory
+		getPodTemplate = func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+			csOfHistory, err := applyCloneSetHistory(cs, history)
+			if err != nil {
+				return nil, err
+			}
+			return &csOfHistory.Spec.Template, nil
+		}
+	case kind == advancedSetKind:
+		asts, astsHistory, err := advancedstsHistory(c.AppsV1(), kc.AppsV1beta1(), namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		history = astsHistory
+		getPodTemplate = func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+			astsOfHistory, err := applyAdvancedStatefulSetHistory(asts, history)
+			if err != nil {
+				return nil, err
+			}
+			return &astsOfHistory.Spec.Template, nil
+		}
+	case kind == statefulSetKind:
+		sts, stsHistory, err := statefulSetHistory(c.AppsV1(), namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		history = stsHistory
+		getPodTemplate = func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+			stsOfHistory, err := applyStatefulSetHistory(sts, history)
+			if err != nil {
+				return nil, err
+			}
+			return &stsOfHistory.Spec.Template, nil
+		}
+	case kind == daemonSetKinds[0] || kind == daemonSetKinds[1]:
+		ds, dsHistory, err := daemonSetHistory(c.AppsV1(), namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		history = dsHistory
+		getPodTemplate = func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+			dsOfHistory, err := applyDaemonSetHistory(ds, history)
+			if err != nil {
+				return nil, err
+			}
+			return &dsOfHistory.Spec.Template, nil
+		}
+	default:
+		return nil, &UnsupportedKindError{Operation: "exporting history", Kind: kind, Supported: ControllerRevisionKinds}
+	}
+
+	sort.Slice(history, func(i, j int) bool { return history[i].Revision < history[j].Revision })
+	revisions := make([]RevisionTemplate, 0, len(history))
+	for _, h := range history {
+		template, err := getPodTemplate(h)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse history %s: %v", h.Name, err)
+		}
+		revisions = append(revisions, RevisionTemplate{Revision: h, Template: template})
+	}
+	return revisions, nil
+}
+
 // printHistory returns the podTemplate of the given revision if it is non-zero
 // else returns the overall revisions
 func printHistory(history []*appsv1.ControllerRevision, revision int64, getPodTemplate func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error)) (string, error) {
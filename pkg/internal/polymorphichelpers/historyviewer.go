@@ -25,6 +25,9 @@ import (
 
 // historyViewer Returns a HistoryViewer for viewing change history
 func historyViewer(restClientGetter genericclioptions.RESTClientGetter, mapping *meta.RESTMapping) (HistoryViewer, error) {
+	if fn := handlersForKind(mapping.GroupVersionKind.GroupKind()).HistoryViewer; fn != nil {
+		return fn(restClientGetter, mapping)
+	}
 	clientConfig, err := restClientGetter.ToRESTConfig()
 	if err != nil {
 		return nil, err
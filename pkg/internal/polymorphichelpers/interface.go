@@ -58,6 +58,13 @@ type UpdatePodSpecForObjectFunc func(obj runtime.Object, fn func(*v1.PodSpec) er
 // UpdatePodSpecForObjectFn gives a way to easily override the function for unit testing if needed
 var UpdatePodSpecForObjectFn UpdatePodSpecForObjectFunc = updatePodSpecForObject
 
+// UpdatePodTemplateForObjectFunc will call the provided function on the pod template this object supports,
+// return false if no pod template is supported, or return an error.
+type UpdatePodTemplateForObjectFunc func(obj runtime.Object, fn func(*v1.PodTemplateSpec) error) (bool, error)
+
+// UpdatePodTemplateForObjectFn gives a way to easily override the function for unit testing if needed
+var UpdatePodTemplateForObjectFn UpdatePodTemplateForObjectFunc = updatePodTemplateForObject
+
 // MapBasedSelectorForObjectFunc will call the provided function on mapping the baesd selector for object,
 // return "" if object is not supported, or return an error.
 type MapBasedSelectorForObjectFunc func(object runtime.Object) (string, error)
@@ -117,6 +124,15 @@ var RollbackerFn RollbackerFunc = rollbacker
 // ObjectRestarterFunc is a function type that updates an annotation in a deployment to restart it..
 type ObjectRestarterFunc func(runtime.Object) ([]byte, error)
 
+// ObjectPromoterFunc is a function type that promotes the rollout of the object in a given info
+// to its next step, or to its end if full is true.
+type ObjectPromoterFunc func(obj runtime.Object, full bool) ([]byte, error)
+
+// ObjectPromoterFn gives a way to easily override the function for unit testing if needed.
+// Returns the patched object in bytes and any error that occurred during the encoding or
+// in case the object can not be promoted.
+var ObjectPromoterFn ObjectPromoterFunc = defaultObjectPromoter
+
 // ObjectRestarterFn gives a way to easily override the function for unit testing if needed.
 // Returns the patched object in bytes and any error that occurred during the encoding.
 var ObjectRestarterFn ObjectRestarterFunc = defaultObjectRestarter
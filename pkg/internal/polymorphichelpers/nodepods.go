@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/kubectl/pkg/util/podutils"
+)
+
+// PodsByNodeForObject returns the most active pod of revision of object on each node, by node
+// name, such as the pods of a DaemonSet. The revision is as accepted by PodRevisionFor. Pods that
+// are not scheduled or terminated are ignored.
+func PodsByNodeForObject(restClientGetter genericclioptions.RESTClientGetter, object runtime.Object, revision string) (map[string]*corev1.Pod, error) {
+	podRevision, err := PodRevisionFor(restClientGetter, object, revision)
+	if err != nil {
+		return nil, err
+	}
+	clientConfig, err := restClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := corev1client.NewForConfig(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := PodsForObject(client, object)
+	if err != nil {
+		return nil, err
+	}
+	return podsByNode(pods, podRevision), nil
+}
+
+func podsByNode(pods []corev1.Pod, podRevision *PodRevision) map[string]*corev1.Pod {
+	candidates := map[string][]*corev1.Pod{}
+	for i := range pods {
+		pod := &pods[i]
+		if len(pod.Spec.NodeName) == 0 || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if podRevision.Matches(pod) {
+			candidates[pod.Spec.NodeName] = append(candidates[pod.Spec.NodeName], pod)
+		}
+	}
+	byNode := make(map[string]*corev1.Pod, len(candidates))
+	for node, nodePods := range candidates {
+		sort.Sort(sort.Reverse(podutils.ActivePods(nodePods)))
+		byNode[node] = nodePods[0]
+	}
+	return byNode
+}
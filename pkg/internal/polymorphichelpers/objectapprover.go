@@ -2,14 +2,16 @@ package polymorphichelpers
 
 import (
 	"errors"
-	"fmt"
 
 	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/kubectl/pkg/scheme"
 )
 
-func defaultObjectApprover (obj runtime.Object) ([]byte, error) {
+func defaultObjectApprover(obj runtime.Object) ([]byte, error) {
+	if fn := handlersForObject(obj).ObjectApprover; fn != nil {
+		return fn(obj)
+	}
 	switch obj := obj.(type) {
 	case *kruiserolloutsv1apha1.Rollout:
 		if obj.Status.CanaryStatus == nil || obj.Status.CanaryStatus.CurrentStepState != kruiserolloutsv1apha1.CanaryStepStatePaused {
@@ -19,6 +21,6 @@ func defaultObjectApprover (obj runtime.Object) ([]byte, error) {
 		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiserolloutsv1apha1.GroupVersion), obj)
 
 	default:
-		return nil, fmt.Errorf("approving is not supported")
+		return nil, newUnsupportedKindError("approving", groupKindForObject(obj), approvableKinds, func(h Handlers) bool { return h.ObjectApprover != nil })
 	}
-}
\ No newline at end of file
+}
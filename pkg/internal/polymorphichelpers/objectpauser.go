@@ -19,7 +19,6 @@ package polymorphichelpers
 
 import (
 	"errors"
-	"fmt"
 	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
@@ -33,6 +32,9 @@ import (
 
 // Currently only supports Deployments.
 func defaultObjectPauser(obj runtime.Object) ([]byte, error) {
+	if fn := handlersForObject(obj).ObjectPauser; fn != nil {
+		return fn(obj)
+	}
 	switch obj := obj.(type) {
 	case *extensionsv1beta1.Deployment:
 		if obj.Spec.Paused {
@@ -77,6 +79,6 @@ func defaultObjectPauser(obj runtime.Object) ([]byte, error) {
 		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiserolloutsv1apha1.SchemeGroupVersion), obj)
 
 	default:
-		return nil, fmt.Errorf("pausing is not supported")
+		return nil, newUnsupportedKindError("pausing", groupKindForObject(obj), pausableKinds, func(h Handlers) bool { return h.ObjectPauser != nil })
 	}
 }
@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"errors"
+	"fmt"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// Rollouts are promoted by approving their current step, BatchReleases by releasing their next
+// batch, or all of them if full is true, and partitioned workloads by setting their partition to 0.
+func defaultObjectPromoter(obj runtime.Object, full bool) ([]byte, error) {
+	if fn := handlersForObject(obj).ObjectPromoter; fn != nil {
+		return fn(obj, full)
+	}
+	switch obj := obj.(type) {
+	case *kruiserolloutsv1apha1.Rollout:
+		if full {
+			return nil, errors.New("does not allow to promote fully, each step of a rollout must be approved")
+		}
+		return defaultObjectApprover(obj)
+
+	case *kruiserolloutsv1apha1.BatchRelease:
+		partition := int32(len(obj.Spec.ReleasePlan.Batches) - 1)
+		if !full {
+			var err error
+			if partition, err = NextBatchPartition(obj, false); err != nil {
+				return nil, err
+			}
+		}
+		obj.Spec.ReleasePlan.BatchPartition = &partition
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiserolloutsv1apha1.GroupVersion), obj)
+
+	case *kruiseappsv1alpha1.CloneSet:
+		if err := UpdatePartitionForObject(obj, 0); err != nil {
+			return nil, err
+		}
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1beta1.StatefulSet:
+		if err := UpdatePartitionForObject(obj, 0); err != nil {
+			return nil, err
+		}
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1beta1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1alpha1.StatefulSet:
+		if err := UpdatePartitionForObject(obj, 0); err != nil {
+			return nil, err
+		}
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
+	default:
+		return nil, newUnsupportedKindError("promoting", groupKindForObject(obj), promotableKinds, func(h Handlers) bool { return h.ObjectPromoter != nil })
+	}
+}
+
+// NextBatchPartition returns the batch partition that releases the batch after the current
+// one. Unless force is true, the current batch must be ready.
+func NextBatchPartition(release *kruiserolloutsv1apha1.BatchRelease, force bool) (int32, error) {
+	plan := release.Spec.ReleasePlan
+	status := release.Status.CanaryStatus
+	if len(plan.Batches) == 0 {
+		return 0, fmt.Errorf("batchrelease %s has no batches", release.Name)
+	}
+	if plan.BatchPartition == nil {
+		return 0, fmt.Errorf("batchrelease %s has no batch partition, its batches are released without promotion", release.Name)
+	}
+	partition := *plan.BatchPartition
+	if int(partition) >= len(plan.Batches)-1 {
+		return 0, fmt.Errorf("all batches of batchrelease %s are already promoted", release.Name)
+	}
+	if !force && (status.CurrentBatch < partition || status.ReleasingBatchState != kruiserolloutsv1apha1.ReadyBatchState) {
+		return 0, fmt.Errorf("batch %d of batchrelease %s is not ready yet, use --force to promote anyway", status.CurrentBatch+1, release.Name)
+	}
+	return partition + 1, nil
+}
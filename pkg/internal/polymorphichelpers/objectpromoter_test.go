@@ -0,0 +1,177 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func newBatchRelease(currentBatch int32, state kruiserolloutsv1apha1.ReleasingBatchStateType, partition *int32) *kruiserolloutsv1apha1.BatchRelease {
+	release := &kruiserolloutsv1apha1.BatchRelease{ObjectMeta: metav1.ObjectMeta{Name: "demo"}}
+	release.Spec.ReleasePlan = kruiserolloutsv1apha1.ReleasePlan{
+		Batches: []kruiserolloutsv1apha1.ReleaseBatch{
+			{CanaryReplicas: intstr.FromInt(1)},
+			{CanaryReplicas: intstr.FromString("50%")},
+			{CanaryReplicas: intstr.FromString("90%")},
+		},
+		BatchPartition: partition,
+	}
+	release.Status.CanaryStatus = kruiserolloutsv1apha1.BatchReleaseCanaryStatus{CurrentBatch: currentBatch, ReleasingBatchState: state}
+	return release
+}
+
+func TestNextBatchPartition(t *testing.T) {
+	tests := []struct {
+		name      string
+		release   *kruiserolloutsv1apha1.BatchRelease
+		force     bool
+		expected  int32
+		expectErr bool
+	}{
+		{
+			name:     "current batch ready",
+			release:  newBatchRelease(0, kruiserolloutsv1apha1.ReadyBatchState, int32Ptr(0)),
+			expected: 1,
+		},
+		{
+			name:      "current batch not ready",
+			release:   newBatchRelease(0, kruiserolloutsv1apha1.VerifyBatchState, int32Ptr(0)),
+			expectErr: true,
+		},
+		{
+			name:     "current batch not ready with force",
+			release:  newBatchRelease(0, kruiserolloutsv1apha1.VerifyBatchState, int32Ptr(0)),
+			force:    true,
+			expected: 1,
+		},
+		{
+			name:      "last batch promoted",
+			release:   newBatchRelease(2, kruiserolloutsv1apha1.ReadyBatchState, int32Ptr(2)),
+			expectErr: true,
+		},
+		{
+			name:      "no batch partition",
+			release:   newBatchRelease(0, kruiserolloutsv1apha1.ReadyBatchState, nil),
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NextBatchPartition(test.release, test.force)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error %t, got %v", test.expectErr, err)
+			}
+			if err == nil && got != test.expected {
+				t.Errorf("expected partition %d, got %d", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestDefaultObjectPromoter(t *testing.T) {
+	pausedRollout := &kruiserolloutsv1apha1.Rollout{}
+	pausedRollout.Status.CanaryStatus = &kruiserolloutsv1apha1.CanaryStatus{CurrentStepState: kruiserolloutsv1apha1.CanaryStepStatePaused}
+	partition := intstr.FromInt(3)
+	cloneSet := &kruiseappsv1alpha1.CloneSet{}
+	cloneSet.Spec.UpdateStrategy.Partition = &partition
+	statefulSet := &kruiseappsv1beta1.StatefulSet{}
+	statefulSet.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{Partition: int32Ptr(3)}
+
+	tests := []struct {
+		name      string
+		obj       runtime.Object
+		full      bool
+		check     func(obj runtime.Object) bool
+		expectErr bool
+	}{
+		{
+			name: "rollout step",
+			obj:  pausedRollout.DeepCopy(),
+			check: func(obj runtime.Object) bool {
+				return obj.(*kruiserolloutsv1apha1.Rollout).Status.CanaryStatus.CurrentStepState == kruiserolloutsv1apha1.CanaryStepStateCompleted
+			},
+		},
+		{
+			name:      "rollout full",
+			obj:       pausedRollout.DeepCopy(),
+			full:      true,
+			expectErr: true,
+		},
+		{
+			name: "batchrelease next batch",
+			obj:  newBatchRelease(0, kruiserolloutsv1apha1.ReadyBatchState, int32Ptr(0)),
+			check: func(obj runtime.Object) bool {
+				return *obj.(*kruiserolloutsv1apha1.BatchRelease).Spec.ReleasePlan.BatchPartition == 1
+			},
+		},
+		{
+			name:      "batchrelease batch not ready",
+			obj:       newBatchRelease(0, kruiserolloutsv1apha1.VerifyBatchState, int32Ptr(0)),
+			expectErr: true,
+		},
+		{
+			name: "batchrelease full",
+			obj:  newBatchRelease(0, kruiserolloutsv1apha1.VerifyBatchState, int32Ptr(0)),
+			full: true,
+			check: func(obj runtime.Object) bool {
+				return *obj.(*kruiserolloutsv1apha1.BatchRelease).Spec.ReleasePlan.BatchPartition == 2
+			},
+		},
+		{
+			name: "cloneset",
+			obj:  cloneSet,
+			check: func(obj runtime.Object) bool {
+				return obj.(*kruiseappsv1alpha1.CloneSet).Spec.UpdateStrategy.Partition.IntValue() == 0
+			},
+		},
+		{
+			name: "advanced statefulset",
+			obj:  statefulSet,
+			check: func(obj runtime.Object) bool {
+				return *obj.(*kruiseappsv1beta1.StatefulSet).Spec.UpdateStrategy.RollingUpdate.Partition == 0
+			},
+		},
+		{
+			name:      "deployment",
+			obj:       &appsv1.Deployment{},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ObjectPromoterFn(test.obj, test.full)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error %t, got %v", test.expectErr, err)
+			}
+			if err == nil && !test.check(test.obj) {
+				t.Errorf("unexpected promoted object %#v", test.obj)
+			}
+		})
+	}
+}
@@ -19,7 +19,6 @@ package polymorphichelpers
 
 import (
 	"errors"
-	"fmt"
 	"time"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
@@ -32,6 +31,9 @@ import (
 )
 
 func defaultObjectRestarter(obj runtime.Object) ([]byte, error) {
+	if fn := handlersForObject(obj).ObjectRestarter; fn != nil {
+		return fn(obj)
+	}
 	switch obj := obj.(type) {
 	case *extensionsv1beta1.Deployment:
 		if obj.Spec.Paused {
@@ -123,6 +125,6 @@ func defaultObjectRestarter(obj runtime.Object) ([]byte, error) {
 		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
 
 	default:
-		return nil, fmt.Errorf("restarting is not supported")
+		return nil, newUnsupportedKindError("restarting", groupKindForObject(obj), restartableKinds, func(h Handlers) bool { return h.ObjectRestarter != nil })
 	}
 }
@@ -19,7 +19,6 @@ package polymorphichelpers
 
 import (
 	"errors"
-	"fmt"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruiserolloutsv1apha1 "github.com/openkruise/rollouts/api/v1alpha1"
@@ -32,6 +31,9 @@ import (
 )
 
 func defaultObjectResumer(obj runtime.Object) ([]byte, error) {
+	if fn := handlersForObject(obj).ObjectResumer; fn != nil {
+		return fn(obj)
+	}
 	switch obj := obj.(type) {
 	case *extensionsv1beta1.Deployment:
 		if !obj.Spec.Paused {
@@ -76,6 +78,6 @@ func defaultObjectResumer(obj runtime.Object) ([]byte, error) {
 		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiserolloutsv1apha1.SchemeGroupVersion), obj)
 
 	default:
-		return nil, fmt.Errorf("resuming is not supported")
+		return nil, newUnsupportedKindError("resuming", groupKindForObject(obj), pausableKinds, func(h Handlers) bool { return h.ObjectResumer != nil })
 	}
 }
\ No newline at end of file
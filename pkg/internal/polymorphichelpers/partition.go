@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// PartitionForObject returns the desired replicas, the partition and the updated replicas of a partitioned
+// workload, and false if the object does not support partitions.
+func PartitionForObject(object runtime.Object) (replicas, partition, updated int32, ok bool) {
+	replicas = 1
+	switch t := object.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		if t.Spec.Replicas != nil {
+			replicas = *t.Spec.Replicas
+		}
+		if t.Spec.UpdateStrategy.Partition != nil {
+			value, _ := intstr.GetScaledValueFromIntOrPercent(t.Spec.UpdateStrategy.Partition, int(replicas), true)
+			partition = int32(value)
+		}
+		return replicas, partition, t.Status.UpdatedReplicas, true
+	case *kruiseappsv1beta1.StatefulSet:
+		if t.Spec.Replicas != nil {
+			replicas = *t.Spec.Replicas
+		}
+		if t.Spec.UpdateStrategy.RollingUpdate != nil && t.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+			partition = *t.Spec.UpdateStrategy.RollingUpdate.Partition
+		}
+		return replicas, partition, t.Status.UpdatedReplicas, true
+	case *kruiseappsv1alpha1.StatefulSet:
+		if t.Spec.Replicas != nil {
+			replicas = *t.Spec.Replicas
+		}
+		if t.Spec.UpdateStrategy.RollingUpdate != nil && t.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+			partition = *t.Spec.UpdateStrategy.RollingUpdate.Partition
+		}
+		return replicas, partition, t.Status.UpdatedReplicas, true
+	case *kruiseappsv1alpha1.DaemonSet:
+		// a daemonset has no replicas, it runs one pod on each of the nodes it is scheduled to
+		if t.Spec.UpdateStrategy.RollingUpdate != nil && t.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+			partition = *t.Spec.UpdateStrategy.RollingUpdate.Partition
+		}
+		return t.Status.DesiredNumberScheduled, partition, t.Status.UpdatedNumberScheduled, true
+	}
+	return 0, 0, 0, false
+}
+
+// UpdatePartitionForObject sets the partition of a partitioned workload.
+func UpdatePartitionForObject(object runtime.Object, partition int32) error {
+	switch t := object.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		value := intstr.FromInt(int(partition))
+		t.Spec.UpdateStrategy.Partition = &value
+	case *kruiseappsv1beta1.StatefulSet:
+		if t.Spec.UpdateStrategy.RollingUpdate == nil {
+			t.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{}
+		}
+		t.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+	case *kruiseappsv1alpha1.StatefulSet:
+		if t.Spec.UpdateStrategy.RollingUpdate == nil {
+			t.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1alpha1.RollingUpdateStatefulSetStrategy{}
+		}
+		t.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+	case *kruiseappsv1alpha1.DaemonSet:
+		if t.Spec.UpdateStrategy.RollingUpdate == nil {
+			t.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1alpha1.RollingUpdateDaemonSet{}
+		}
+		t.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+	default:
+		return newUnsupportedKindError("setting the partition", groupKindForObject(object), PartitionedKinds, func(Handlers) bool { return false })
+	}
+	return nil
+}
+
+// PartitionForUpdated returns the partition that updates the given number or percentage of replicas,
+// rounding percentages up.
+func PartitionForUpdated(updated intstr.IntOrString, replicas int32) (int32, error) {
+	value, err := intstr.GetScaledValueFromIntOrPercent(&updated, int(replicas), true)
+	if err != nil {
+		return 0, err
+	}
+	if int32(value) >= replicas {
+		return 0, nil
+	}
+	return replicas - int32(value), nil
+}
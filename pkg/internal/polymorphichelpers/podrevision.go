@@ -0,0 +1,252 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	deploymentutil "k8s.io/kubectl/pkg/util/deployment"
+	"k8s.io/kubectl/pkg/util/podutils"
+)
+
+const (
+	// UpdatedRevision selects the pods of the update revision of a workload, the new code path
+	// during a roll out.
+	UpdatedRevision = "updated"
+	// StableRevision selects the pods of the current revision of a workload, the ones not updated
+	// yet during a roll out.
+	StableRevision = "stable"
+)
+
+// revisionLabels are the labels holding the revision of the pods of the workloads.
+var revisionLabels = []string{appsv1.ControllerRevisionHashLabelKey, appsv1.DefaultDeploymentUniqueLabelKey}
+
+// PodRevision selects the pods of a revision of a workload.
+type PodRevision struct {
+	// Description is the revision in messages, e.g. "updated revision demo-5f7b8c9d".
+	Description string
+	// Matches returns true if the pod is of the revision.
+	Matches func(pod *corev1.Pod) bool
+}
+
+// PodRevisionFor returns the pods of object of revision: UpdatedRevision, StableRevision, a revision
+// number as printed by 'rollout history', or a revision hash. Objects that are pods only support
+// revision hashes.
+func PodRevisionFor(restClientGetter genericclioptions.RESTClientGetter, object runtime.Object, revision string) (*PodRevision, error) {
+	if len(revision) == 0 {
+		return &PodRevision{Description: "any revision", Matches: func(*corev1.Pod) bool { return true }}, nil
+	}
+	number, numberErr := strconv.ParseInt(revision, 10, 64)
+	if revision != UpdatedRevision && revision != StableRevision && numberErr != nil {
+		return podRevisionOfHash("revision "+revision, revision), nil
+	}
+	if _, ok := object.(*corev1.Pod); ok {
+		return nil, fmt.Errorf("--revision=%s requires a workload, not a pod", revision)
+	}
+
+	clientConfig, err := restClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if deployment, ok := object.(*appsv1.Deployment); ok {
+		return deploymentPodRevision(client, deployment, revision, number)
+	}
+
+	if numberErr != nil {
+		updated, err := updateRevisionForObject(object)
+		if err != nil {
+			return nil, err
+		}
+		if revision == UpdatedRevision {
+			return podRevisionOfHash("updated revision "+updated, updated), nil
+		}
+		if current := currentRevisionForObject(object); len(current) > 0 {
+			return podRevisionOfHash("stable revision "+current, current), nil
+		}
+		// DaemonSets do not report their current revision: their stable pods are the ones not updated
+		return &PodRevision{
+			Description: "stable revision",
+			Matches:     func(pod *corev1.Pod) bool { return !IsPodOfRevision(pod, updated) },
+		}, nil
+	}
+
+	kruiseClient, err := kruiseclientsets.NewForConfig(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := RevisionTemplatesFor(groupKindForObject(object), client, kruiseClient, accessor.GetNamespace(), accessor.GetName())
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range revisions {
+		if r.Revision.Revision == number {
+			return podRevisionOfHash(fmt.Sprintf("revision %d (%s)", number, r.Revision.Name), r.Revision.Name), nil
+		}
+	}
+	return nil, revisionNotFoundErr(number)
+}
+
+// deploymentPodRevision returns the pods of the ReplicaSet of deployment of revision: the newest
+// one for UpdatedRevision, the newest older one that still has replicas for StableRevision, or else
+// the one of the revision number.
+func deploymentPodRevision(client kubernetes.Interface, deployment *appsv1.Deployment, revision string, number int64) (*PodRevision, error) {
+	_, oldRSs, newRS, err := deploymentutil.GetAllReplicaSets(deployment, client.AppsV1())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve replica sets from deployment %s: %v", deployment.Name, err)
+	}
+	var rs *appsv1.ReplicaSet
+	switch revision {
+	case UpdatedRevision:
+		rs = newRS
+	case StableRevision:
+		sort.Slice(oldRSs, func(i, j int) bool {
+			a, _ := deploymentutil.Revision(oldRSs[i])
+			b, _ := deploymentutil.Revision(oldRSs[j])
+			return a > b
+		})
+		for _, old := range oldRSs {
+			if old.Status.Replicas > 0 {
+				rs = old
+				break
+			}
+		}
+		// the roll out is complete, the new pods are stable
+		if rs == nil {
+			rs = newRS
+		}
+	default:
+		if rs, err = deploymentRevision(deployment, client, number); err != nil {
+			return nil, err
+		}
+	}
+	if rs == nil {
+		return nil, fmt.Errorf("no %s revision found for deployment %q", revision, deployment.Name)
+	}
+	hash := rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+	return podRevisionOfHash(fmt.Sprintf("%s revision %s", revision, rs.Name), hash), nil
+}
+
+func podRevisionOfHash(description, hash string) *PodRevision {
+	return &PodRevision{Description: description, Matches: func(pod *corev1.Pod) bool { return IsPodOfRevision(pod, hash) }}
+}
+
+// PodOfRevisionForObject returns the most active pod of revision of object, without waiting for one.
+func PodOfRevisionForObject(restClientGetter genericclioptions.RESTClientGetter, object runtime.Object, revision string) (*corev1.Pod, error) {
+	podRevision, err := PodRevisionFor(restClientGetter, object, revision)
+	if err != nil {
+		return nil, err
+	}
+	clientConfig, err := restClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := PodsForObject(client.CoreV1(), object)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []*corev1.Pod
+	for i := range pods {
+		pod := &pods[i]
+		if podRevision.Matches(pod) && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			candidates = append(candidates, pod)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no pod of the %s found, use --wait-ready to wait for one", podRevision.Description)
+	}
+	sort.Sort(sort.Reverse(podutils.ActivePods(candidates)))
+	return candidates[0], nil
+}
+
+// IsPodOfRevision returns true if pod is of revision, the full name or the hash of a revision, or
+// if revision is empty.
+func IsPodOfRevision(pod *corev1.Pod, revision string) bool {
+	if len(revision) == 0 {
+		return true
+	}
+	for _, label := range revisionLabels {
+		value := pod.Labels[label]
+		if len(value) > 0 && (value == revision || strings.HasSuffix(value, "-"+revision) || strings.HasSuffix(revision, "-"+value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateRevisionForObject returns the update revision of a workload whose pods are labeled with it.
+func updateRevisionForObject(object runtime.Object) (string, error) {
+	var revision string
+	switch t := object.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		revision = t.Status.UpdateRevision
+	case *kruiseappsv1beta1.StatefulSet:
+		revision = t.Status.UpdateRevision
+	case *kruiseappsv1alpha1.StatefulSet:
+		revision = t.Status.UpdateRevision
+	case *kruiseappsv1alpha1.DaemonSet:
+		revision = t.Status.DaemonSetHash
+	case *appsv1.StatefulSet:
+		revision = t.Status.UpdateRevision
+	default:
+		return "", fmt.Errorf("the revisions of %T are unknown, specify a revision hash instead", object)
+	}
+	if len(revision) == 0 {
+		return "", fmt.Errorf("the update revision of %T is not reported in its status yet", object)
+	}
+	return revision, nil
+}
+
+// currentRevisionForObject returns the current revision of a workload, or an empty string if it
+// does not report it.
+func currentRevisionForObject(object runtime.Object) string {
+	switch t := object.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		return t.Status.CurrentRevision
+	case *kruiseappsv1beta1.StatefulSet:
+		return t.Status.CurrentRevision
+	case *kruiseappsv1alpha1.StatefulSet:
+		return t.Status.CurrentRevision
+	case *appsv1.StatefulSet:
+		return t.Status.CurrentRevision
+	}
+	return ""
+}
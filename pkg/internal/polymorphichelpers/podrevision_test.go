@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"reflect"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestIsPodOfRevision(t *testing.T) {
+	cloneSetPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{appsv1.ControllerRevisionHashLabelKey: "demo-5f7b8c9d"}}}
+	deploymentPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: "6d4cf56db6"}}}
+	tests := []struct {
+		pod      *corev1.Pod
+		revision string
+		expected bool
+	}{
+		{pod: cloneSetPod, expected: true},
+		{pod: cloneSetPod, revision: "demo-5f7b8c9d", expected: true},
+		{pod: cloneSetPod, revision: "5f7b8c9d", expected: true},
+		{pod: cloneSetPod, revision: "demo-6d4cf56db6"},
+		{pod: deploymentPod, revision: "6d4cf56db6", expected: true},
+		{pod: deploymentPod, revision: "demo-6d4cf56db6", expected: true},
+		{pod: deploymentPod, revision: "5f7b8c9d"},
+		{pod: &corev1.Pod{}, revision: "5f7b8c9d"},
+	}
+	for _, test := range tests {
+		if actual := IsPodOfRevision(test.pod, test.revision); actual != test.expected {
+			t.Errorf("pod %v, revision %q: expected %v, got %v", test.pod.Labels, test.revision, test.expected, actual)
+		}
+	}
+}
+
+func TestUpdateRevisionForObject(t *testing.T) {
+	cs := &kruiseappsv1alpha1.CloneSet{}
+	if _, err := updateRevisionForObject(cs); err == nil {
+		t.Errorf("expected an error for a cloneset without update revision")
+	}
+	cs.Status.UpdateRevision = "demo-5f7b8c9d"
+	if revision, err := updateRevisionForObject(cs); err != nil || revision != "demo-5f7b8c9d" {
+		t.Errorf("expected the update revision of the cloneset, got %q, %v", revision, err)
+	}
+	if _, err := updateRevisionForObject(&appsv1.Deployment{}); err == nil {
+		t.Errorf("expected an error for a deployment")
+	}
+}
+
+func TestPodRevisionFor(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("default")
+	defer tf.Cleanup()
+
+	cs := &kruiseappsv1alpha1.CloneSet{}
+	cs.Status.UpdateRevision = "demo-new"
+	cs.Status.CurrentRevision = "demo-old"
+	newPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{appsv1.ControllerRevisionHashLabelKey: "demo-new"}}}
+	oldPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{appsv1.ControllerRevisionHashLabelKey: "demo-old"}}}
+
+	tests := []struct {
+		object   runtime.Object
+		revision string
+		matches  []bool
+		err      bool
+	}{
+		{object: cs, revision: "", matches: []bool{true, true}},
+		{object: cs, revision: UpdatedRevision, matches: []bool{true, false}},
+		{object: cs, revision: StableRevision, matches: []bool{false, true}},
+		{object: cs, revision: "old", matches: []bool{false, true}},
+		{object: oldPod, revision: "old", matches: []bool{false, true}},
+		{object: oldPod, revision: UpdatedRevision, err: true},
+		{object: oldPod, revision: "3", err: true},
+		{object: &appsv1.ReplicaSet{}, revision: UpdatedRevision, err: true},
+	}
+	for _, test := range tests {
+		podRevision, err := PodRevisionFor(tf, test.object, test.revision)
+		if test.err {
+			if err == nil {
+				t.Errorf("%T, revision %q: expected an error", test.object, test.revision)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%T, revision %q: unexpected error: %v", test.object, test.revision, err)
+			continue
+		}
+		if matches := []bool{podRevision.Matches(newPod), podRevision.Matches(oldPod)}; !reflect.DeepEqual(matches, test.matches) {
+			t.Errorf("%T, revision %q: expected the new and old pods to match %v, got %v", test.object, test.revision, test.matches, matches)
+		}
+	}
+}
+
+func TestPodsByNode(t *testing.T) {
+	pod := func(name, node string, phase corev1.PodPhase, ready bool) corev1.Pod {
+		p := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.PodSpec{NodeName: node}, Status: corev1.PodStatus{Phase: phase}}
+		if ready {
+			p.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		}
+		return p
+	}
+	pods := []corev1.Pod{
+		pod("a-pending", "node-a", corev1.PodPending, false),
+		pod("a-ready", "node-a", corev1.PodRunning, true),
+		pod("b-failed", "node-b", corev1.PodFailed, false),
+		pod("unscheduled", "", corev1.PodPending, false),
+		pod("c-running", "node-c", corev1.PodRunning, false),
+	}
+	byNode := podsByNode(pods, &PodRevision{Matches: func(*corev1.Pod) bool { return true }})
+	names := map[string]string{}
+	for node, p := range byNode {
+		names[node] = p.Name
+	}
+	if expected := map[string]string{"node-a": "a-ready", "node-c": "c-running"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+	"k8s.io/kubectl/pkg/util/podutils"
+)
+
+// ReadyPodForObject returns a ready pod of object, a pod or a workload, waiting up to timeout for
+// one to be ready, so that commands targeting a workload whose pods are still starting, such as a
+// fresh canary, do not fail. If revision is not empty, the pod must be of that revision: a revision
+// as accepted by PodRevisionFor.
+func ReadyPodForObject(restClientGetter genericclioptions.RESTClientGetter, object runtime.Object, revision string, timeout time.Duration) (*corev1.Pod, error) {
+	clientConfig, err := restClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := corev1client.NewForConfig(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var namespace string
+	var options func(*metav1.ListOptions)
+	if pod, ok := object.(*corev1.Pod); ok {
+		namespace = pod.Namespace
+		options = func(o *metav1.ListOptions) {
+			o.FieldSelector = fields.OneTermEqualSelector("metadata.name", pod.Name).String()
+		}
+	} else {
+		var selector fmt.Stringer
+		if namespace, selector, err = SelectorsForObject(object); err != nil {
+			return nil, fmt.Errorf("cannot select the pods of %T: %v", object, err)
+		}
+		options = func(o *metav1.ListOptions) {
+			o.LabelSelector = selector.String()
+		}
+	}
+	podRevision, err := PodRevisionFor(restClientGetter, object, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	lw := &cache.ListWatch{
+		ListFunc: func(listOptions metav1.ListOptions) (runtime.Object, error) {
+			options(&listOptions)
+			return client.Pods(namespace).List(context.TODO(), listOptions)
+		},
+		WatchFunc: func(listOptions metav1.ListOptions) (watch.Interface, error) {
+			options(&listOptions)
+			return client.Pods(namespace).Watch(context.TODO(), listOptions)
+		},
+	}
+	ctx, cancel := watchtools.ContextWithOptionalTimeout(context.Background(), timeout)
+	defer cancel()
+	event, err := UntilWithResume(ctx, lw, nil, func(event watch.Event) (bool, error) {
+		pod, ok := event.Object.(*corev1.Pod)
+		if !ok || event.Type == watch.Deleted {
+			return false, nil
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			return false, nil
+		}
+		return podutils.IsPodReady(pod) && podRevision.Matches(pod), nil
+	})
+	if err == wait.ErrWaitTimeout {
+		if len(revision) > 0 {
+			return nil, fmt.Errorf("timed out after %s waiting for a ready pod of the %s", timeout, podRevision.Description)
+		}
+		return nil, fmt.Errorf("timed out after %s waiting for a ready pod", timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event.Object.(*corev1.Pod), nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Handlers are the polymorphic functions of one kind. The functions left nil fall back to
+// the built-in behavior, so a kind only needs to provide what it supports.
+type Handlers struct {
+	ObjectPauser    ObjectPauserFunc
+	ObjectResumer   ObjectResumerFunc
+	ObjectApprover  ObjectApproverFunc
+	ObjectPromoter  ObjectPromoterFunc
+	ObjectRestarter ObjectRestarterFunc
+	StatusViewer    StatusViewerFunc
+	HistoryViewer   HistoryViewerFunc
+	Rollbacker      RollbackerFunc
+	UpdatePodSpec   UpdatePodSpecForObjectFunc
+}
+
+// Registry holds the Handlers registered for kinds. It is safe for concurrent use.
+type Registry struct {
+	lock     sync.RWMutex
+	handlers map[schema.GroupKind]Handlers
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{handlers: map[schema.GroupKind]Handlers{}}
+}
+
+// Register sets the Handlers of kind, replacing any Handlers registered for it before.
+func (r *Registry) Register(kind schema.GroupKind, handlers Handlers) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.handlers[kind] = handlers
+}
+
+// Unregister removes the Handlers of kind.
+func (r *Registry) Unregister(kind schema.GroupKind) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.handlers, kind)
+}
+
+// Get returns the Handlers registered for kind.
+func (r *Registry) Get(kind schema.GroupKind) (Handlers, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	handlers, ok := r.handlers[kind]
+	return handlers, ok
+}
+
+// Kinds returns the registered kinds, sorted by group and kind.
+func (r *Registry) Kinds() []schema.GroupKind {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	kinds := make([]schema.GroupKind, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].String() < kinds[j].String() })
+	return kinds
+}
+
+// DefaultRegistry is the Registry consulted by the polymorphic functions before their
+// built-in behavior.
+var DefaultRegistry = NewRegistry()
+
+// Register sets the Handlers of kind in the DefaultRegistry.
+func Register(kind schema.GroupKind, handlers Handlers) {
+	DefaultRegistry.Register(kind, handlers)
+}
+
+// handlersForKind returns the Handlers registered for kind, or empty Handlers.
+func handlersForKind(kind schema.GroupKind) Handlers {
+	handlers, _ := DefaultRegistry.Get(kind)
+	return handlers
+}
+
+// handlersForObject returns the Handlers registered for the kind of obj, or empty Handlers.
+func handlersForObject(obj runtime.Object) Handlers {
+	return handlersForKind(groupKindForObject(obj))
+}
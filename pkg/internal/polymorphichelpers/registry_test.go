@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRegistryDispatch(t *testing.T) {
+	custom := schema.GroupKind{Group: "apps.example.com", Kind: "Workload"}
+	Register(custom, Handlers{
+		ObjectPauser: func(runtime.Object) ([]byte, error) { return []byte("paused"), nil },
+		StatusViewer: func(*meta.RESTMapping) (StatusViewer, error) { return &CloneSetStatusViewer{}, nil },
+	})
+	defer DefaultRegistry.Unregister(custom)
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(custom.WithVersion("v1"))
+	patch, err := ObjectPauserFn(obj)
+	if err != nil || string(patch) != "paused" {
+		t.Errorf("expected the registered pauser to be used, got %q, %v", patch, err)
+	}
+	if _, err := ObjectResumerFn(obj); err == nil {
+		t.Errorf("expected the built-in resumer to reject an unknown kind")
+	}
+	if _, err := StatusViewerFn(&meta.RESTMapping{GroupVersionKind: custom.WithVersion("v1")}); err != nil {
+		t.Errorf("expected the registered status viewer to be used, got %v", err)
+	}
+
+	// typed objects without kind are looked up in the scheme
+	cloneSet := schema.GroupKind{Group: kruiseappsv1alpha1.GroupVersion.Group, Kind: "CloneSet"}
+	Register(cloneSet, Handlers{ObjectRestarter: func(runtime.Object) ([]byte, error) { return []byte("restarted"), nil }})
+	defer DefaultRegistry.Unregister(cloneSet)
+	if patch, err := ObjectRestarterFn(&kruiseappsv1alpha1.CloneSet{}); err != nil || string(patch) != "restarted" {
+		t.Errorf("expected the registered restarter to be used for CloneSet, got %q, %v", patch, err)
+	}
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+	r := NewRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			kind := schema.GroupKind{Group: "example.com", Kind: fmt.Sprintf("Kind%d", i%5)}
+			r.Register(kind, Handlers{})
+			r.Get(kind)
+			r.Kinds()
+		}(i)
+	}
+	wg.Wait()
+	if kinds := r.Kinds(); len(kinds) != 5 || kinds[0].Kind != "Kind0" {
+		t.Errorf("expected 5 sorted kinds, got %v", kinds)
+	}
+}
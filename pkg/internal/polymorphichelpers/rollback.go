@@ -95,12 +95,8 @@ func RollbackerFor(kind schema.GroupKind, c kubernetes.Interface, kc kruiseclien
 
 	err := elem.Accept(visitor)
 
-	if err != nil {
-		return nil, fmt.Errorf("error retrieving rollbacker for %q, %v", kind.String(), err)
-	}
-
-	if visitor.result == nil {
-		return nil, fmt.Errorf("no rollbacker has been implemented for %q", kind)
+	if err != nil || visitor.result == nil {
+		return nil, newUnsupportedKindError("rolling back", kind, revisionedKinds, func(h Handlers) bool { return h.Rollbacker != nil })
 	}
 
 	return visitor.result, nil
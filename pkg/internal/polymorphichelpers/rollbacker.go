@@ -25,6 +25,9 @@ import (
 
 // Returns a Rollbacker for changing the rollback version of the specified RESTMapping type or an error
 func rollbacker(restClientGetter genericclioptions.RESTClientGetter, mapping *meta.RESTMapping) (Rollbacker, error) {
+	if fn := handlersForKind(mapping.GroupVersionKind.GroupKind()).Rollbacker; fn != nil {
+		return fn(restClientGetter, mapping)
+	}
 	clientConfig, err := restClientGetter.ToRESTConfig()
 	if err != nil {
 		return nil, err
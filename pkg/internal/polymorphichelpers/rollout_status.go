@@ -22,11 +22,14 @@ import (
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
 
 	appsv1 "k8s.io/api/apps/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	deploymentutil "k8s.io/kubectl/pkg/util/deployment"
 )
 
@@ -52,7 +55,7 @@ func StatusViewerFor(kind schema.GroupKind) (StatusViewer, error) {
 	case kruiseappsv1beta1.SchemeGroupVersion.WithKind("StatefulSet").GroupKind():
 		return &AdvancedStatefulSetStatusViewer{}, nil
 	}
-	return nil, fmt.Errorf("no status viewer has been implemented for %v", kind)
+	return nil, newUnsupportedKindError("viewing rollout status", kind, revisionedKinds, func(h Handlers) bool { return h.StatusViewer != nil })
 }
 
 // DeploymentStatusViewer implements the StatusViewer interface.
@@ -169,6 +172,9 @@ func (s *StatefulSetStatusViewer) Status(obj runtime.Unstructured, revision int6
 // Status returns a message describing cloneset status, and a bool value indicating if the status is considered done.
 func (s *CloneSetStatusViewer) Status(obj runtime.Unstructured, revision int64) (string, bool, error) {
 	cs := &kruiseappsv1alpha1.CloneSet{}
+	if unknownVersion(obj) {
+		return unstructuredStatus(obj, "CloneSet")
+	}
 	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), cs)
 	if err != nil {
 		return "", false, fmt.Errorf("failed to convert %T to %T: %v", obj, cs, err)
@@ -199,6 +205,9 @@ func (s *CloneSetStatusViewer) Status(obj runtime.Unstructured, revision int64)
 // Status returns a message describing advanced statefulset status, and a bool value indicating if the status is considered done.
 func (s *AdvancedStatefulSetStatusViewer) Status(obj runtime.Unstructured, revision int64) (string, bool, error) {
 	asts := &kruiseappsv1beta1.StatefulSet{}
+	if unknownVersion(obj) {
+		return unstructuredStatus(obj, "Advanced StatefulSet")
+	}
 	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), asts)
 	if err != nil {
 		return "", false, fmt.Errorf("failed to convert %T to %T: %v", obj, asts, err)
@@ -224,3 +233,67 @@ func (s *AdvancedStatefulSetStatusViewer) Status(obj runtime.Unstructured, revis
 	return fmt.Sprintf("Advanced StatefulSet rolling update complete %d pods at revision %s...\n", asts.Status.AvailableReplicas, asts.Status.UpdateRevision), true, nil
 
 }
+
+// unknownVersion returns true if obj is at a version of its kind that is not compiled into
+// kubectl-kruise, such as a version added by an upgrade of Kruise, whose fields may differ.
+func unknownVersion(obj runtime.Unstructured) bool {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	return !gvk.Empty() && !internalapi.GetScheme().Recognizes(gvk)
+}
+
+// unstructuredStatus returns a message describing the status of a Kruise workload at an unknown
+// version, and a bool value indicating if the status is considered done. The status is read on a
+// best-effort basis from the fields shared by the versions of the Kruise workloads.
+func unstructuredStatus(obj runtime.Unstructured, kind string) (string, bool, error) {
+	content := obj.UnstructuredContent()
+	generation, _, _ := unstructured.NestedInt64(content, "metadata", "generation")
+	observedGeneration, _, _ := unstructured.NestedInt64(content, "status", "observedGeneration")
+	if observedGeneration == 0 || generation > observedGeneration {
+		return fmt.Sprintf("Waiting for %s spec update to be observed...\n", kind), false, nil
+	}
+
+	replicas, hasReplicas, _ := unstructured.NestedInt64(content, "spec", "replicas")
+	readyReplicas, _, _ := unstructured.NestedInt64(content, "status", "readyReplicas")
+	updatedReplicas, _, _ := unstructured.NestedInt64(content, "status", "updatedReplicas")
+	availableReplicas, _, _ := unstructured.NestedInt64(content, "status", "availableReplicas")
+	updateRevision, _, _ := unstructured.NestedString(content, "status", "updateRevision")
+	if hasReplicas {
+		if partition, ok := unstructuredPartition(content, int(replicas)); ok && updatedReplicas < replicas-int64(partition) {
+			return fmt.Sprintf("Waiting for partitioned roll out to finish: %d out of %d new pods have been updated...\n",
+				updatedReplicas, replicas-int64(partition)), false, nil
+		}
+		if readyReplicas < replicas {
+			return fmt.Sprintf("Waiting for %d pods to be ready...\n", replicas-readyReplicas), false, nil
+		}
+	}
+	return fmt.Sprintf("%s rolling update complete %d pods at revision %s...\n", kind, availableReplicas, updateRevision), true, nil
+}
+
+// unstructuredPartition returns the partition of the update strategy of content, set on the
+// strategy as for CloneSets or on its rolling update as for Advanced StatefulSets, scaled to
+// replicas if it is a percentage.
+func unstructuredPartition(content map[string]interface{}, replicas int) (int, bool) {
+	for _, fields := range [][]string{
+		{"spec", "updateStrategy", "partition"},
+		{"spec", "updateStrategy", "rollingUpdate", "partition"},
+	} {
+		value, found, _ := unstructured.NestedFieldNoCopy(content, fields...)
+		if !found {
+			continue
+		}
+		var partition intstr.IntOrString
+		switch v := value.(type) {
+		case int64:
+			partition = intstr.FromInt(int(v))
+		case float64:
+			partition = intstr.FromInt(int(v))
+		case string:
+			partition = intstr.FromString(v)
+		default:
+			return 0, false
+		}
+		scaled, err := intstr.GetScaledValueFromIntOrPercent(&partition, replicas, true)
+		return scaled, err == nil
+	}
+	return 0, false
+}
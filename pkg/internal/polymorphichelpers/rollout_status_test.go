@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newUnknownVersionCloneSet(partition interface{}, replicas, observed, ready, updated int64) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.kruise.io/v1beta9",
+		"kind":       "CloneSet",
+		"metadata":   map[string]interface{}{"name": "demo", "generation": int64(2)},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			// a field whose type changed in the unknown version
+			"updateStrategy": map[string]interface{}{"type": []interface{}{"InPlaceIfPossible"}},
+		},
+		"status": map[string]interface{}{
+			"observedGeneration": observed,
+			"readyReplicas":      ready,
+			"availableReplicas":  ready,
+			"updatedReplicas":    updated,
+			"updateRevision":     "demo-7d9f",
+		},
+	}}
+	if partition != nil {
+		_ = unstructured.SetNestedField(obj.Object, partition, "spec", "updateStrategy", "partition")
+	}
+	return obj
+}
+
+func TestCloneSetStatusOfUnknownVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      *unstructured.Unstructured
+		expected string
+		done     bool
+	}{
+		{name: "not observed", obj: newUnknownVersionCloneSet(nil, 4, 1, 4, 4), expected: "Waiting for CloneSet spec update to be observed...\n"},
+		{name: "partitioned", obj: newUnknownVersionCloneSet("50%", 4, 2, 4, 1), expected: "Waiting for partitioned roll out to finish: 1 out of 2 new pods have been updated...\n"},
+		{name: "not ready", obj: newUnknownVersionCloneSet(int64(2), 4, 2, 3, 2), expected: "Waiting for 1 pods to be ready...\n"},
+		{name: "done", obj: newUnknownVersionCloneSet(nil, 4, 2, 4, 4), expected: "CloneSet rolling update complete 4 pods at revision demo-7d9f...\n", done: true},
+	}
+	for _, test := range tests {
+		status, done, err := (&CloneSetStatusViewer{}).Status(test.obj, 0)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if status != test.expected || done != test.done {
+			t.Errorf("%s: expected %q, %v, got %q, %v", test.name, test.expected, test.done, status, done)
+		}
+	}
+}
@@ -22,5 +22,8 @@ import (
 
 // statusViewer returns a StatusViewer for printing rollout status.
 func statusViewer(mapping *meta.RESTMapping) (StatusViewer, error) {
+	if fn := handlersForKind(mapping.GroupVersionKind.GroupKind()).StatusViewer; fn != nil {
+		return fn(mapping)
+	}
 	return StatusViewerFor(mapping.GroupVersionKind.GroupKind())
 }
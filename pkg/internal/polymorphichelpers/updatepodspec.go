@@ -33,12 +33,41 @@ import (
 )
 
 func updatePodSpecForObject(obj runtime.Object, fn func(*v1.PodSpec) error) (bool, error) {
+	if update := handlersForObject(obj).UpdatePodSpec; update != nil {
+		return update(obj, fn)
+	}
 	switch t := obj.(type) {
 
 	case *kruiseappsv1alpha1.CloneSet:
 		return true, fn(&t.Spec.Template.Spec)
 	case *kruiseappsv1beta1.StatefulSet:
 		return true, fn(&t.Spec.Template.Spec)
+	case *kruiseappsv1alpha1.StatefulSet:
+		return true, fn(&t.Spec.Template.Spec)
+	case *kruiseappsv1alpha1.DaemonSet:
+		return true, fn(&t.Spec.Template.Spec)
+	case *kruiseappsv1alpha1.BroadcastJob:
+		return true, fn(&t.Spec.Template.Spec)
+	case *kruiseappsv1alpha1.AdvancedCronJob:
+		switch {
+		case t.Spec.Template.JobTemplate != nil:
+			return true, fn(&t.Spec.Template.JobTemplate.Spec.Template.Spec)
+		case t.Spec.Template.BroadcastJobTemplate != nil:
+			return true, fn(&t.Spec.Template.BroadcastJobTemplate.Spec.Template.Spec)
+		}
+		return false, fmt.Errorf("the AdvancedCronJob %s has neither a job template nor a broadcast job template", t.Name)
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		switch subset := t.Spec.Template; {
+		case subset.CloneSetTemplate != nil:
+			return true, fn(&subset.CloneSetTemplate.Spec.Template.Spec)
+		case subset.AdvancedStatefulSetTemplate != nil:
+			return true, fn(&subset.AdvancedStatefulSetTemplate.Spec.Template.Spec)
+		case subset.StatefulSetTemplate != nil:
+			return true, fn(&subset.StatefulSetTemplate.Spec.Template.Spec)
+		case subset.DeploymentTemplate != nil:
+			return true, fn(&subset.DeploymentTemplate.Spec.Template.Spec)
+		}
+		return false, fmt.Errorf("the UnitedDeployment %s has no subset template", t.Name)
 	case *v1.Pod:
 		return true, fn(&t.Spec)
 		// ReplicationController
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"fmt"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	appsv1beta2 "k8s.io/api/apps/v1beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	batchv2alpha1 "k8s.io/api/batch/v2alpha1"
+	v1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func updatePodTemplateForObject(obj runtime.Object, fn func(*v1.PodTemplateSpec) error) (bool, error) {
+	switch t := obj.(type) {
+
+	case *kruiseappsv1alpha1.CloneSet:
+		return true, fn(&t.Spec.Template)
+	case *kruiseappsv1beta1.StatefulSet:
+		return true, fn(&t.Spec.Template)
+	case *kruiseappsv1alpha1.StatefulSet:
+		return true, fn(&t.Spec.Template)
+	case *kruiseappsv1alpha1.DaemonSet:
+		return true, fn(&t.Spec.Template)
+	case *kruiseappsv1alpha1.BroadcastJob:
+		return true, fn(&t.Spec.Template)
+	case *kruiseappsv1alpha1.AdvancedCronJob:
+		switch {
+		case t.Spec.Template.JobTemplate != nil:
+			return true, fn(&t.Spec.Template.JobTemplate.Spec.Template)
+		case t.Spec.Template.BroadcastJobTemplate != nil:
+			return true, fn(&t.Spec.Template.BroadcastJobTemplate.Spec.Template)
+		}
+		return false, fmt.Errorf("the AdvancedCronJob %s has neither a job template nor a broadcast job template", t.Name)
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		switch subset := t.Spec.Template; {
+		case subset.CloneSetTemplate != nil:
+			return true, fn(&subset.CloneSetTemplate.Spec.Template)
+		case subset.AdvancedStatefulSetTemplate != nil:
+			return true, fn(&subset.AdvancedStatefulSetTemplate.Spec.Template)
+		case subset.StatefulSetTemplate != nil:
+			return true, fn(&subset.StatefulSetTemplate.Spec.Template)
+		case subset.DeploymentTemplate != nil:
+			return true, fn(&subset.DeploymentTemplate.Spec.Template)
+		}
+		return false, fmt.Errorf("the UnitedDeployment %s has no subset template", t.Name)
+		// ReplicationController
+	case *v1.ReplicationController:
+		if t.Spec.Template == nil {
+			t.Spec.Template = &v1.PodTemplateSpec{}
+		}
+		return true, fn(t.Spec.Template)
+
+		// Deployment
+	case *extensionsv1beta1.Deployment:
+		return true, fn(&t.Spec.Template)
+	case *appsv1beta1.Deployment:
+		return true, fn(&t.Spec.Template)
+	case *appsv1beta2.Deployment:
+		return true, fn(&t.Spec.Template)
+	case *appsv1.Deployment:
+		return true, fn(&t.Spec.Template)
+
+		// DaemonSet
+	case *extensionsv1beta1.DaemonSet:
+		return true, fn(&t.Spec.Template)
+	case *appsv1beta2.DaemonSet:
+		return true, fn(&t.Spec.Template)
+	case *appsv1.DaemonSet:
+		return true, fn(&t.Spec.Template)
+
+		// ReplicaSet
+	case *extensionsv1beta1.ReplicaSet:
+		return true, fn(&t.Spec.Template)
+	case *appsv1beta2.ReplicaSet:
+		return true, fn(&t.Spec.Template)
+	case *appsv1.ReplicaSet:
+		return true, fn(&t.Spec.Template)
+
+		// StatefulSet
+	case *appsv1beta1.StatefulSet:
+		return true, fn(&t.Spec.Template)
+	case *appsv1beta2.StatefulSet:
+		return true, fn(&t.Spec.Template)
+	case *appsv1.StatefulSet:
+		return true, fn(&t.Spec.Template)
+
+		// Job
+	case *batchv1.Job:
+		return true, fn(&t.Spec.Template)
+
+		// CronJob
+	case *batchv1beta1.CronJob:
+		return true, fn(&t.Spec.JobTemplate.Spec.Template)
+	case *batchv2alpha1.CronJob:
+		return true, fn(&t.Spec.JobTemplate.Spec.Template)
+
+	default:
+		return false, fmt.Errorf("the object does not have a pod template: %T", t)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+	"k8s.io/klog/v2"
+)
+
+// NewWatchBackoff returns the backoff between the reconnections of the watches of UntilWithResume,
+// which grows from 1s to 30s and is reset after 2m without reconnection.
+var NewWatchBackoff = func() wait.BackoffManager {
+	return wait.NewExponentialBackoffManager(time.Second, 30*time.Second, 2*time.Minute, 2.0, 0.1, clock.RealClock{})
+}
+
+// ListPreconditionFunc is called with the objects listed by UntilWithResume before it watches
+// them. It returns true to stop waiting.
+type ListPreconditionFunc func(objects []runtime.Object) (bool, error)
+
+// UntilWithResume lists the objects of lw and watches them, calling condition with an Added event
+// for each listed object and with each watched event, until condition returns true or ctx is done.
+//
+// Unlike watchtools.UntilWithoutRetry, long waits survive API server restarts: when the watch is
+// closed or a request fails with a transient error, the watch is resumed from the resourceVersion
+// of the last event or bookmark, after a growing backoff. When that resourceVersion is too old
+// (410 Gone), the objects are listed again and condition is called with a Modified event for each
+// of them and a Deleted event for each object that is gone.
+func UntilWithResume(ctx context.Context, lw cache.ListerWatcher, precondition ListPreconditionFunc, condition watchtools.ConditionFunc) (*watch.Event, error) {
+	backoff := NewWatchBackoff()
+	w := &resumingWatch{lw: lw, known: map[types.UID]runtime.Object{}}
+
+	objects, err := w.list(ctx, backoff)
+	if err != nil {
+		return nil, err
+	}
+	if precondition != nil {
+		if done, err := precondition(objects); done || err != nil {
+			return nil, err
+		}
+	}
+	if event, done, err := w.deliver(objects, watch.Added, condition); done || err != nil {
+		return event, err
+	}
+
+	for {
+		watcher, err := lw.Watch(metav1.ListOptions{ResourceVersion: w.resourceVersion, AllowWatchBookmarks: true})
+		if err == nil {
+			var event *watch.Event
+			var done bool
+			event, done, err = w.consume(ctx, watcher, condition)
+			watcher.Stop()
+			if done {
+				return event, err
+			}
+		}
+		if err != nil {
+			if !retriableWatchError(err) {
+				return nil, err
+			}
+			klog.V(2).Infof("Resuming the watch from resourceVersion %s: %v", w.resourceVersion, err)
+		}
+		if err := waitBackoff(ctx, backoff); err != nil {
+			return nil, err
+		}
+		if apierrors.IsGone(err) || apierrors.IsResourceExpired(err) {
+			if event, done, err := w.relist(ctx, backoff, condition); done || err != nil {
+				return event, err
+			}
+		}
+	}
+}
+
+// resumingWatch is the state of UntilWithResume: the resourceVersion to resume the watch from and
+// the objects seen, to tell which ones were deleted when they are listed again.
+type resumingWatch struct {
+	lw              cache.ListerWatcher
+	resourceVersion string
+	known           map[types.UID]runtime.Object
+}
+
+// list lists the objects, retrying transient errors, and returns them.
+func (w *resumingWatch) list(ctx context.Context, backoff wait.BackoffManager) ([]runtime.Object, error) {
+	for {
+		list, err := w.lw.List(metav1.ListOptions{})
+		if err == nil {
+			listMeta, err := meta.ListAccessor(list)
+			if err != nil {
+				return nil, err
+			}
+			objects, err := meta.ExtractList(list)
+			if err != nil {
+				return nil, err
+			}
+			w.resourceVersion = listMeta.GetResourceVersion()
+			return objects, nil
+		}
+		if !retriableWatchError(err) {
+			return nil, err
+		}
+		klog.V(2).Infof("Retrying the list: %v", err)
+		if err := waitBackoff(ctx, backoff); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// relist lists the objects again, when the resourceVersion to resume the watch from is too old, and
+// calls condition with a Deleted event for each object that is gone and a Modified event for the others.
+func (w *resumingWatch) relist(ctx context.Context, backoff wait.BackoffManager, condition watchtools.ConditionFunc) (*watch.Event, bool, error) {
+	objects, err := w.list(ctx, backoff)
+	if err != nil {
+		return nil, true, err
+	}
+	listed := map[types.UID]bool{}
+	for _, object := range objects {
+		if accessor, err := meta.Accessor(object); err == nil {
+			listed[accessor.GetUID()] = true
+		}
+	}
+	var deleted []runtime.Object
+	for uid, object := range w.known {
+		if !listed[uid] {
+			deleted = append(deleted, object)
+		}
+	}
+	if event, done, err := w.deliver(deleted, watch.Deleted, condition); done || err != nil {
+		return event, true, err
+	}
+	return w.deliver(objects, watch.Modified, condition)
+}
+
+// consume calls condition with the events of watcher until the watch is closed or fails.
+func (w *resumingWatch) consume(ctx context.Context, watcher watch.Interface, condition watchtools.ConditionFunc) (*watch.Event, bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, true, wait.ErrWaitTimeout
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil, false, nil
+			}
+			switch event.Type {
+			case watch.Error:
+				return nil, false, apierrors.FromObject(event.Object)
+			case watch.Bookmark:
+				if accessor, err := meta.Accessor(event.Object); err == nil {
+					w.resourceVersion = accessor.GetResourceVersion()
+				}
+				continue
+			}
+			if accessor, err := meta.Accessor(event.Object); err == nil {
+				w.resourceVersion = accessor.GetResourceVersion()
+			}
+			if event, done, err := w.deliver([]runtime.Object{event.Object}, event.Type, condition); done || err != nil {
+				return event, true, err
+			}
+		}
+	}
+}
+
+// deliver calls condition with an event of eventType for each of objects, and records the objects.
+func (w *resumingWatch) deliver(objects []runtime.Object, eventType watch.EventType, condition watchtools.ConditionFunc) (*watch.Event, bool, error) {
+	for _, object := range objects {
+		if accessor, err := meta.Accessor(object); err == nil {
+			if eventType == watch.Deleted {
+				delete(w.known, accessor.GetUID())
+			} else {
+				w.known[accessor.GetUID()] = object
+			}
+		}
+		event := watch.Event{Type: eventType, Object: object}
+		done, err := condition(event)
+		if done || err != nil {
+			return &event, true, err
+		}
+	}
+	return nil, false, nil
+}
+
+// waitBackoff waits for the next backoff of backoff, or returns wait.ErrWaitTimeout once ctx is done.
+func waitBackoff(ctx context.Context, backoff wait.BackoffManager) error {
+	timer := backoff.Backoff()
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return wait.ErrWaitTimeout
+	case <-timer.C():
+		return nil
+	}
+}
+
+// retriableWatchError returns true if err is transient, such as when the API server restarts or is
+// overloaded, so that the list or watch may be retried.
+func retriableWatchError(err error) bool {
+	switch {
+	case apierrors.IsUnauthorized(err), apierrors.IsForbidden(err), apierrors.IsNotFound(err),
+		apierrors.IsBadRequest(err), apierrors.IsInvalid(err), apierrors.IsMethodNotSupported(err):
+		return false
+	}
+	return true
+}
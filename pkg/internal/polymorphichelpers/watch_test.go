@@ -0,0 +1,163 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newWatchedPod(name, resourceVersion string, ready bool) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID("uid-" + name), ResourceVersion: resourceVersion}}
+	if ready {
+		pod.Status.Phase = corev1.PodRunning
+	}
+	return pod
+}
+
+// watchFromEvents returns a watch that sends events and is then closed.
+func watchFromEvents(events ...watch.Event) watch.Interface {
+	ch := make(chan watch.Event, len(events))
+	for _, event := range events {
+		ch <- event
+	}
+	close(ch)
+	return watch.NewProxyWatcher(ch)
+}
+
+func TestUntilWithResume(t *testing.T) {
+	defer func(old func() wait.BackoffManager) { NewWatchBackoff = old }(NewWatchBackoff)
+	NewWatchBackoff = func() wait.BackoffManager {
+		return wait.NewExponentialBackoffManager(0, 0, 0, 1, 0, clock.RealClock{})
+	}
+
+	gone := apierrors.NewResourceExpired("too old resource version: 5 (8)")
+	lists := []runtime.Object{
+		&corev1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}, Items: []corev1.Pod{*newWatchedPod("demo", "1", false)}},
+		&corev1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "9"}, Items: []corev1.Pod{*newWatchedPod("demo", "9", true)}},
+	}
+	// the API server is not ready yet
+	listErrors := []error{apierrors.NewServiceUnavailable("starting")}
+	watches := []func() (watch.Interface, error){
+		// the API server restarts
+		func() (watch.Interface, error) { return nil, errors.New("connection refused") },
+		func() (watch.Interface, error) {
+			return watchFromEvents(
+				watch.Event{Type: watch.Modified, Object: newWatchedPod("demo", "3", false)},
+				watch.Event{Type: watch.Bookmark, Object: newWatchedPod("", "5", false)},
+			), nil
+		},
+		// the resourceVersion of the bookmark is compacted
+		func() (watch.Interface, error) {
+			return watchFromEvents(watch.Event{Type: watch.Error, Object: &gone.ErrStatus}), nil
+		},
+	}
+	var resourceVersions []string
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			if len(listErrors) > 0 {
+				err := listErrors[0]
+				listErrors = listErrors[1:]
+				return nil, err
+			}
+			list := lists[0]
+			lists = lists[1:]
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			if !options.AllowWatchBookmarks {
+				t.Errorf("expected watches to allow bookmarks")
+			}
+			resourceVersions = append(resourceVersions, options.ResourceVersion)
+			if len(watches) == 0 {
+				t.Fatalf("unexpected watch from resourceVersion %s", options.ResourceVersion)
+			}
+			w := watches[0]
+			watches = watches[1:]
+			return w()
+		},
+	}
+
+	var events []string
+	event, err := UntilWithResume(context.Background(), lw, nil, func(event watch.Event) (bool, error) {
+		pod := event.Object.(*corev1.Pod)
+		events = append(events, string(event.Type)+" "+pod.ResourceVersion)
+		return pod.Status.Phase == corev1.PodRunning, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event == nil || event.Type != watch.Modified {
+		t.Errorf("expected the Modified event of the relisted pod, got %v", event)
+	}
+	if expected := []string{"ADDED 1", "MODIFIED 3", "MODIFIED 9"}; !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %v, got %v", expected, events)
+	}
+	if expected := []string{"1", "1", "5"}; !reflect.DeepEqual(resourceVersions, expected) {
+		t.Errorf("expected watches from resourceVersions %v, got %v", expected, resourceVersions)
+	}
+}
+
+func TestUntilWithResumeErrors(t *testing.T) {
+	defer func(old func() wait.BackoffManager) { NewWatchBackoff = old }(NewWatchBackoff)
+	NewWatchBackoff = func() wait.BackoffManager {
+		return wait.NewExponentialBackoffManager(0, 0, 0, 1, 0, clock.RealClock{})
+	}
+
+	forbidden := apierrors.NewForbidden(corev1.Resource("pods"), "", errors.New("denied"))
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &corev1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return nil, forbidden
+		},
+	}
+	never := func(watch.Event) (bool, error) { return false, nil }
+	if _, err := UntilWithResume(context.Background(), lw, nil, never); err != forbidden {
+		t.Errorf("expected the forbidden error, got %v", err)
+	}
+
+	notFound := errors.New("not found")
+	precondition := func(objects []runtime.Object) (bool, error) {
+		return true, notFound
+	}
+	if _, err := UntilWithResume(context.Background(), lw, precondition, never); err != notFound {
+		t.Errorf("expected the error of the precondition, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	lw.WatchFunc = func(options metav1.ListOptions) (watch.Interface, error) {
+		return nil, errors.New("connection refused")
+	}
+	if _, err := UntilWithResume(ctx, lw, nil, never); err != wait.ErrWaitTimeout {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package offline guards the extra features of kubectl-kruise that contact endpoints other
+// than the Kubernetes API server, such as Prometheus autodiscovery, notification webhooks and
+// update checks. Such features call Check before any outbound call, so that none is made in
+// air-gapped mode, which is turned on by --offline-extras, by the KUBECTL_KRUISE_OFFLINE_EXTRAS
+// environment variable, or at compile time by the offline build tag. Features keep their
+// outbound clients in files built with the !offline tag, so that the offline build does not
+// contain them at all.
+package offline
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/pflag"
+)
+
+// EnvName turns on air-gapped mode when set to true.
+const EnvName = "KUBECTL_KRUISE_OFFLINE_EXTRAS"
+
+var enabled, _ = strconv.ParseBool(os.Getenv(EnvName))
+
+// AddFlags adds the --offline-extras flag to flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&enabled, "offline-extras", enabled, "If true, never contact endpoints other than the API server, such as Prometheus, notification webhooks and update checks. Also set by the "+EnvName+" environment variable.")
+}
+
+// Enabled returns true in air-gapped mode.
+func Enabled() bool {
+	return enabled || Stripped
+}
+
+// Check returns an error in air-gapped mode, for feature that would contact endpoint.
+func Check(feature, endpoint string) error {
+	if Stripped {
+		return fmt.Errorf("%s is not available: kubectl-kruise is built without the features that contact %s", feature, endpoint)
+	}
+	if enabled {
+		return fmt.Errorf("%s is disabled by --offline-extras: it would contact %s", feature, endpoint)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package offline
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestCheck(t *testing.T) {
+	defer func(old bool) { enabled = old }(enabled)
+	enabled = false
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	AddFlags(flags)
+	if err := Check("update check", "github.com"); (err != nil) != Stripped {
+		t.Errorf("unexpected error before --offline-extras: %v", err)
+	}
+
+	if err := flags.Parse([]string{"--offline-extras"}); err != nil {
+		t.Fatal(err)
+	}
+	if !Enabled() {
+		t.Errorf("expected air-gapped mode with --offline-extras")
+	}
+	if err := Check("update check", "github.com"); err == nil {
+		t.Errorf("expected an error with --offline-extras")
+	}
+}
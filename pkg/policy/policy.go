@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy gates the changes of kubectl-kruise with the Rego policies of the config file,
+// as an admission webhook would, but before the changes leave the client. The commands call Check
+// with the object before and after each change they generate, and the policies are evaluated by
+// the opa binary with the objects, the user and the context as input. Denials block the change
+// with the messages of the policies.
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+)
+
+// DefaultQuery returns the denial messages of a change, as a set of strings.
+const DefaultQuery = "data.kubectl_kruise.deny"
+
+// Identity is who makes the changes and where.
+type Identity struct {
+	// User is the user of the kubeconfig context.
+	User string `json:"user,omitempty"`
+	// Impersonate is the user impersonated with --as.
+	Impersonate string `json:"impersonate,omitempty"`
+	// Context is the kubeconfig context.
+	Context string `json:"context,omitempty"`
+}
+
+// Input is the input of the policies for a change.
+type Input struct {
+	Old         json.RawMessage `json:"old"`
+	New         json.RawMessage `json:"new"`
+	User        string          `json:"user,omitempty"`
+	Impersonate string          `json:"impersonate,omitempty"`
+	Context     string          `json:"context,omitempty"`
+}
+
+var (
+	policy   *config.Policy
+	identity = func() Identity { return Identity{} }
+
+	// loadErr is the error of loading the policies, which refuses all the changes.
+	loadErr error
+
+	// runOPA runs the opa binary with args and input on its standard input, and returns its output.
+	runOPA = func(opa string, args []string, input []byte) ([]byte, error) {
+		cmd := exec.Command(opa, args...)
+		cmd.Stdin = bytes.NewReader(input)
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		out, err := cmd.Output()
+		if err != nil && stderr.Len() > 0 {
+			return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return out, err
+	}
+)
+
+// Configure gates the changes with p, or turns the gate off if p is nil or has no policy.
+func Configure(p *config.Policy) {
+	loadErr = nil
+	if p == nil || len(p.Rego) == 0 {
+		policy = nil
+		return
+	}
+	policy = p
+}
+
+// ConfigureError refuses all the changes with err, the error of loading the config file, rather
+// than let them through without its policies.
+func ConfigureError(err error) {
+	policy, loadErr = nil, err
+}
+
+// SetIdentity sets the function returning who makes the changes, called when they are checked,
+// once the flags are parsed.
+func SetIdentity(fn func() Identity) {
+	identity = fn
+}
+
+// Check evaluates the policies for the change of an object from before to after, both JSON encoded,
+// and returns an error with the denial messages if any policy denies it.
+func Check(before, after []byte) error {
+	if loadErr != nil {
+		return fmt.Errorf("the policies can not be evaluated: %v", loadErr)
+	}
+	if policy == nil {
+		return nil
+	}
+	who := identity()
+	input, err := json.Marshal(Input{Old: before, New: after, User: who.User, Impersonate: who.Impersonate, Context: who.Context})
+	if err != nil {
+		return err
+	}
+
+	query := policy.Query
+	if len(query) == 0 {
+		query = DefaultQuery
+	}
+	opa := policy.OPA
+	if len(opa) == 0 {
+		opa = "opa"
+	}
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, rego := range policy.Rego {
+		args = append(args, "--data", rego)
+	}
+	out, err := runOPA(opa, append(args, query), input)
+	if err != nil {
+		return fmt.Errorf("unable to evaluate the policies: %v", err)
+	}
+	denials, err := denialsFrom(out)
+	if err != nil {
+		return fmt.Errorf("unable to evaluate the policies: %v", err)
+	}
+	if len(denials) > 0 {
+		return fmt.Errorf("denied by policy: %s", strings.Join(denials, "; "))
+	}
+	return nil
+}
+
+// denialsFrom returns the denial messages of the output of 'opa eval --format json': the strings
+// of the value of the query, which is undefined if no policy denies the change.
+func denialsFrom(out []byte) ([]string, error) {
+	var result struct {
+		Result []struct {
+			Expressions []struct {
+				Value interface{} `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, err
+	}
+	var denials []string
+	for _, r := range result.Result {
+		for _, expression := range r.Expressions {
+			switch value := expression.Value.(type) {
+			case []interface{}:
+				for _, v := range value {
+					denials = append(denials, fmt.Sprint(v))
+				}
+			case string:
+				denials = append(denials, value)
+			case bool:
+				if value {
+					denials = append(denials, "denied")
+				}
+			}
+		}
+	}
+	return denials, nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/config"
+)
+
+func TestCheck(t *testing.T) {
+	defer func(old func(string, []string, []byte) ([]byte, error)) { runOPA = old }(runOPA)
+	defer Configure(nil)
+	defer SetIdentity(func() Identity { return Identity{} })
+
+	var gotArgs []string
+	var gotInput Input
+	output := `{"result":[{"expressions":[{"value":["image nginx:latest is not pinned","prod changes need a ticket"]}]}]}`
+	runOPA = func(opa string, args []string, input []byte) ([]byte, error) {
+		gotArgs = append([]string{opa}, args...)
+		if err := json.Unmarshal(input, &gotInput); err != nil {
+			t.Fatal(err)
+		}
+		return []byte(output), nil
+	}
+	before, after := []byte(`{"kind":"CloneSet","spec":{"replicas":1}}`), []byte(`{"kind":"CloneSet","spec":{"replicas":2}}`)
+
+	Configure(nil)
+	if Check(before, after) != nil || gotArgs != nil {
+		t.Errorf("expected no policy to be evaluated without config")
+	}
+
+	Configure(&config.Policy{Rego: []string{"policies/", "extra.rego"}})
+	SetIdentity(func() Identity { return Identity{User: "alice", Context: "prod"} })
+	err := Check(before, after)
+	if err == nil || err.Error() != "denied by policy: image nginx:latest is not pinned; prod changes need a ticket" {
+		t.Errorf("unexpected error %v", err)
+	}
+	expectedArgs := []string{"opa", "eval", "--format", "json", "--stdin-input", "--data", "policies/", "--data", "extra.rego", DefaultQuery}
+	if !reflect.DeepEqual(gotArgs, expectedArgs) {
+		t.Errorf("expected opa %v, got %v", expectedArgs, gotArgs)
+	}
+	if string(gotInput.Old) != string(before) || string(gotInput.New) != string(after) || gotInput.User != "alice" || gotInput.Context != "prod" {
+		t.Errorf("unexpected input %+v", gotInput)
+	}
+
+	// an undefined query allows the change
+	output = `{}`
+	if err := Check(before, after); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	runOPA = func(string, []string, []byte) ([]byte, error) {
+		return nil, errors.New("exec: \"opa\": executable file not found in $PATH")
+	}
+	if err := Check(before, after); err == nil {
+		t.Errorf("expected the change to be blocked if the policies can not be evaluated")
+	}
+}
+
+func TestCheckConfigureError(t *testing.T) {
+	defer Configure(nil)
+
+	ConfigureError(errors.New("invalid config file kubectl-kruise.yaml: unknown field"))
+	err := Check([]byte(`{"spec":{"replicas":1}}`), []byte(`{"spec":{"replicas":2}}`))
+	if err == nil || err.Error() != "the policies can not be evaluated: invalid config file kubectl-kruise.yaml: unknown field" {
+		t.Errorf("expected the change to be refused, got %v", err)
+	}
+
+	Configure(nil)
+	if err := Check([]byte(`{"spec":{"replicas":1}}`), []byte(`{"spec":{"replicas":2}}`)); err != nil {
+		t.Errorf("expected the change to be allowed once configured, got %v", err)
+	}
+}
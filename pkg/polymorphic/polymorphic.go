@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package polymorphic lets builds of kubectl-kruise support their own workload kinds in
+// the rollout and set commands, by registering the handlers of those kinds from an init
+// function instead of changing every polymorphic helper.
+package polymorphic
+
+import (
+	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type (
+	// Handlers are the polymorphic functions of one kind, see polymorphichelpers.Handlers.
+	Handlers = polymorphichelpers.Handlers
+	// Registry holds the Handlers registered for kinds.
+	Registry = polymorphichelpers.Registry
+
+	ObjectPauserFunc           = polymorphichelpers.ObjectPauserFunc
+	ObjectResumerFunc          = polymorphichelpers.ObjectResumerFunc
+	ObjectApproverFunc         = polymorphichelpers.ObjectApproverFunc
+	ObjectPromoterFunc         = polymorphichelpers.ObjectPromoterFunc
+	ObjectRestarterFunc        = polymorphichelpers.ObjectRestarterFunc
+	StatusViewerFunc           = polymorphichelpers.StatusViewerFunc
+	HistoryViewerFunc          = polymorphichelpers.HistoryViewerFunc
+	RollbackerFunc             = polymorphichelpers.RollbackerFunc
+	UpdatePodSpecForObjectFunc = polymorphichelpers.UpdatePodSpecForObjectFunc
+
+	StatusViewer  = polymorphichelpers.StatusViewer
+	HistoryViewer = polymorphichelpers.HistoryViewer
+	Rollbacker    = polymorphichelpers.Rollbacker
+)
+
+// Register sets the Handlers of kind, replacing the built-in behavior for the functions it
+// provides. It is safe to call concurrently, usually from an init function.
+func Register(kind schema.GroupKind, handlers Handlers) {
+	polymorphichelpers.Register(kind, handlers)
+}
+
+// Unregister removes the Handlers registered for kind.
+func Unregister(kind schema.GroupKind) {
+	polymorphichelpers.DefaultRegistry.Unregister(kind)
+}
+
+// RegisteredKinds returns the kinds that have registered Handlers.
+func RegisteredKinds() []schema.GroupKind {
+	return polymorphichelpers.DefaultRegistry.Kinds()
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package readonly guards kubectl-kruise against changing anything in read-only mode, which is
+// turned on by --read-only or by the KUBECTL_KRUISE_READ_ONLY environment variable, so that the
+// binary can be embedded in dashboards and viewer bastion images. The requests to the API server
+// go through the transport installed by WithReadOnly, which refuses the ones that may change
+// resources, so that no command has to be trusted to check. Features that change anything else,
+// such as the binary itself, call Check.
+package readonly
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// EnvName turns on read-only mode when set to true.
+const EnvName = "KUBECTL_KRUISE_READ_ONLY"
+
+const flagName = "read-only"
+
+var enabled, _ = strconv.ParseBool(os.Getenv(EnvName))
+
+// reviewResources are created by POST requests that only read the authorization of the user.
+var reviewResources = []string{"selfsubjectaccessreviews", "selfsubjectrulesreviews", "subjectaccessreviews", "localsubjectaccessreviews", "tokenreviews"}
+
+// AddFlags adds the --read-only flag to flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&enabled, flagName, enabled, "If true, refuse any operation that may change resources, such as set, scale or rollout undo. Also set by the "+EnvName+" environment variable.")
+}
+
+// Enabled returns true in read-only mode.
+func Enabled() bool {
+	return enabled
+}
+
+// Check returns an error in read-only mode, for operation that may change something.
+func Check(operation string) error {
+	if enabled {
+		return fmt.Errorf("%s is disabled by --read-only: it may change resources", operation)
+	}
+	return nil
+}
+
+// ParseArgs turns read-only mode on if args, a command line parsed by another program such as
+// kubectl, have the --read-only flag, and returns args without it.
+func ParseArgs(args []string) []string {
+	var result []string
+	for _, arg := range args {
+		if arg == "--"+flagName {
+			enabled = true
+			continue
+		}
+		if strings.HasPrefix(arg, "--"+flagName+"=") {
+			if value, err := strconv.ParseBool(strings.TrimPrefix(arg, "--"+flagName+"=")); err == nil {
+				enabled = value
+			}
+			continue
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// WithReadOnly returns getter with a transport that refuses the requests that may change resources
+// in read-only mode.
+func WithReadOnly(getter genericclioptions.RESTClientGetter) genericclioptions.RESTClientGetter {
+	return &readOnlyClientGetter{RESTClientGetter: getter}
+}
+
+type readOnlyClientGetter struct {
+	genericclioptions.RESTClientGetter
+}
+
+// ToRESTConfig returns the config of the embedded getter with the read-only transport.
+func (g *readOnlyClientGetter) ToRESTConfig() (*rest.Config, error) {
+	config, err := g.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	config = rest.CopyConfig(config)
+	config.WrapTransport = transport.Wrappers(config.WrapTransport, func(rt http.RoundTripper) http.RoundTripper {
+		return &roundTripper{delegate: rt}
+	})
+	return config, nil
+}
+
+// roundTripper refuses the requests that may change resources in read-only mode. Mode is checked
+// for each request, as clients may be created before the flags are parsed.
+type roundTripper struct {
+	delegate http.RoundTripper
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if enabled && !ReadOnlyRequest(req) {
+		return nil, fmt.Errorf("refused by --read-only, as %s requests may change resources", req.Method)
+	}
+	return rt.delegate.RoundTrip(req)
+}
+
+// ReadOnlyRequest returns true if req cannot change resources: it reads, it is a server-side dry
+// run, or it creates a review of the authorization of the user.
+func ReadOnlyRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	if req.URL.Query().Get("dryRun") == "All" {
+		return true
+	}
+	if req.Method == http.MethodPost {
+		for _, resource := range reviewResources {
+			if strings.HasSuffix(strings.TrimSuffix(req.URL.Path, "/"), "/"+resource) {
+				return true
+			}
+		}
+	}
+	return false
+}
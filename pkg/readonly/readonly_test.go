@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readonly
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/rest"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestCheck(t *testing.T) {
+	defer func(old bool) { enabled = old }(enabled)
+	enabled = false
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	AddFlags(flags)
+	if err := Check("upgrade"); err != nil {
+		t.Errorf("unexpected error before --read-only: %v", err)
+	}
+
+	if err := flags.Parse([]string{"--read-only"}); err != nil {
+		t.Fatal(err)
+	}
+	if !Enabled() {
+		t.Errorf("expected read-only mode with --read-only")
+	}
+	if err := Check("upgrade"); err == nil || err.Error() != "upgrade is disabled by --read-only: it may change resources" {
+		t.Errorf("unexpected error with --read-only: %v", err)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	defer func(old bool) { enabled = old }(enabled)
+
+	tests := []struct {
+		args     []string
+		expected []string
+		enabled  bool
+	}{
+		{args: []string{"get", "pods"}, expected: []string{"get", "pods"}},
+		{args: []string{"--read-only", "get", "pods"}, expected: []string{"get", "pods"}, enabled: true},
+		{args: []string{"delete", "pod/demo", "--read-only=true"}, expected: []string{"delete", "pod/demo"}, enabled: true},
+		{args: []string{"delete", "pod/demo", "--read-only=false"}, expected: []string{"delete", "pod/demo"}},
+	}
+	for _, test := range tests {
+		enabled = false
+		if got := ParseArgs(test.args); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%v: expected args %v, got %v", test.args, test.expected, got)
+		}
+		if enabled != test.enabled {
+			t.Errorf("%v: expected read-only mode %v, got %v", test.args, test.enabled, enabled)
+		}
+	}
+}
+
+func TestWithReadOnly(t *testing.T) {
+	defer func(old bool) { enabled = old }(enabled)
+
+	var served []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		served = append(served, req.Method+" "+req.URL.RequestURI())
+	}))
+	defer server.Close()
+
+	f := cmdtesting.NewTestFactory()
+	defer f.Cleanup()
+	f.ClientConfigVal = &rest.Config{Host: server.URL}
+	config, err := WithReadOnly(f).ToRESTConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rt, err := rest.TransportFor(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := &http.Client{Transport: rt}
+
+	tests := []struct {
+		method  string
+		path    string
+		allowed bool
+	}{
+		{method: http.MethodGet, path: "/apis/apps.kruise.io/v1alpha1/namespaces/default/clonesets", allowed: true},
+		{method: http.MethodGet, path: "/apis/apps.kruise.io/v1alpha1/namespaces/default/clonesets?watch=true", allowed: true},
+		{method: http.MethodPatch, path: "/apis/apps.kruise.io/v1alpha1/namespaces/default/clonesets/demo?dryRun=All", allowed: true},
+		{method: http.MethodPost, path: "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews", allowed: true},
+		{method: http.MethodPatch, path: "/apis/apps.kruise.io/v1alpha1/namespaces/default/clonesets/demo"},
+		{method: http.MethodPut, path: "/apis/apps.kruise.io/v1alpha1/namespaces/default/clonesets/demo/scale"},
+		{method: http.MethodDelete, path: "/api/v1/namespaces/default/pods/demo-0"},
+		{method: http.MethodPost, path: "/api/v1/namespaces/default/pods/demo-0/exec"},
+	}
+	for _, mode := range []bool{false, true} {
+		enabled = mode
+		for _, test := range tests {
+			served = nil
+			req, _ := http.NewRequest(test.method, server.URL+test.path, nil)
+			resp, err := client.Do(req)
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if allowed := !mode || test.allowed; allowed != (err == nil) || allowed != (len(served) == 1) {
+				t.Errorf("read-only %v, %s %s: expected allowed %v, got error %v and served %v", mode, test.method, test.path, allowed, err, served)
+			}
+		}
+	}
+}
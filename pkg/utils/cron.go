@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed standard five field cron spec: minute, hour, day of month, month and day of week.
+// Each field supports '*', single values, ranges (a-b), lists (a,b) and steps (*/n, a-b/n).
+type CronSchedule struct {
+	spec   string
+	fields [5]map[int]bool
+	// whether day of month and day of week were restricted, see Matches
+	domStar, dowStar bool
+}
+
+var cronFieldBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// ParseCron parses a five field cron spec.
+func ParseCron(spec string) (*CronSchedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("invalid cron spec %q: expected 5 fields, got %d", spec, len(parts))
+	}
+	s := &CronSchedule{spec: spec, domStar: parts[2] == "*", dowStar: parts[4] == "*"}
+	for i, part := range parts {
+		values, err := parseCronField(part, cronFieldBounds[i][0], cronFieldBounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %v", spec, err)
+		}
+		s.fields[i] = values
+	}
+	// 7 is a common alias for sunday
+	if s.fields[4][7] {
+		s.fields[4][0] = true
+	}
+	return s, nil
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", item)
+			}
+			item = item[:i]
+		}
+		low, high := min, max
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", item)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", item)
+				}
+			}
+			// day of week accepts 7 as sunday
+			if max == 6 && high == 7 {
+				max = 7
+			}
+			if low < min || high > max || low > high {
+				return nil, fmt.Errorf("value %q out of range %d-%d", item, min, max)
+			}
+		}
+		for v := low; v <= high; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// String returns the spec the schedule was parsed from.
+func (s *CronSchedule) String() string {
+	return s.spec
+}
+
+// Matches returns true if the schedule fires in the minute of t. Like cron, when both day of month and
+// day of week are restricted, a day matching either of them matches.
+func (s *CronSchedule) Matches(t time.Time) bool {
+	if !s.fields[0][t.Minute()] || !s.fields[1][t.Hour()] || !s.fields[3][int(t.Month())] {
+		return false
+	}
+	dom, dow := s.fields[2][t.Day()], s.fields[4][int(t.Weekday())]
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// InWindow returns true if t falls into a window of the given duration that starts whenever the schedule fires.
+func (s *CronSchedule) InWindow(t time.Time, duration time.Duration) bool {
+	start := t.Truncate(time.Minute)
+	for m := start; t.Sub(m) < duration; m = m.Add(-time.Minute) {
+		if s.Matches(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the first time after t the schedule fires, or the zero time if it does not fire within 4 years.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	for end := t.AddDate(4, 0, 0); next.Before(end); next = next.Add(time.Minute) {
+		if s.Matches(next) {
+			return next
+		}
+	}
+	return time.Time{}
+}
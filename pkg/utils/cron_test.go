@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, spec := range []string{"* * * * *", "0 22 * * 1-5", "*/15 0-6/2 1,15 * 7"} {
+		_, err := ParseCron(spec)
+		assert.NoError(t, err, spec)
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := ParseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestCronWindow(t *testing.T) {
+	// 22:00 to 02:00 on weekdays
+	s, err := ParseCron("0 22 * * 1-5")
+	assert.NoError(t, err)
+
+	// 2022-03-07 is a monday
+	monday := time.Date(2022, 3, 7, 0, 0, 0, 0, time.UTC)
+	assert.True(t, s.Matches(monday.Add(22*time.Hour)))
+	assert.False(t, s.InWindow(monday.Add(21*time.Hour+59*time.Minute), 4*time.Hour))
+	assert.True(t, s.InWindow(monday.Add(23*time.Hour+30*time.Minute), 4*time.Hour))
+	assert.True(t, s.InWindow(monday.Add(25*time.Hour+59*time.Minute), 4*time.Hour))
+	assert.False(t, s.InWindow(monday.Add(26*time.Hour), 4*time.Hour))
+	// saturday night is not a window
+	assert.False(t, s.InWindow(monday.AddDate(0, 0, 5).Add(23*time.Hour), 4*time.Hour))
+
+	assert.Equal(t, monday.Add(22*time.Hour), s.Next(monday))
+	assert.Equal(t, monday.AddDate(0, 0, 7).Add(22*time.Hour), s.Next(monday.AddDate(0, 0, 4).Add(23*time.Hour)))
+}
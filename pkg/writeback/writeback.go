@@ -0,0 +1,430 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package writeback writes the changes of kubectl-kruise back to the manifests of a Git
+// repository and commits them, so that GitOps repositories stay the source of truth. The commands
+// wrapped by WithWriteBack call Record with each change they generate; the change is then applied
+// to the manifest of the same group, kind, namespace and name in the repository, keeping its
+// comments and the order of its fields. With --write-back-only, the cluster is not changed.
+package writeback
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	writeBackFlag       = "write-back"
+	writeBackOnlyFlag   = "write-back-only"
+	writeBackBranchFlag = "write-back-branch"
+)
+
+// Change is a change of an object recorded for write-back.
+type Change struct {
+	GroupKind schema.GroupKind
+	Namespace string
+	Name      string
+	// Before and After are the object before and after the change, in JSON.
+	Before []byte
+	After  []byte
+	// Patch is the strategic merge patch of the change for DataStruct, the type of the object.
+	Patch      []byte
+	DataStruct runtime.Object
+}
+
+// recording is the state of the command run with --write-back, nil otherwise.
+var recording *struct {
+	only    bool
+	changes []Change
+}
+
+// Recording returns true if the changes are recorded, and whether the cluster must not be changed.
+func Recording() (bool, bool) {
+	if recording == nil {
+		return false, false
+	}
+	return true, recording.only
+}
+
+// Record records change for write-back, if the changes are recorded.
+func Record(change Change) {
+	if recording != nil {
+		recording.changes = append(recording.changes, change)
+	}
+}
+
+// WithWriteBack adds the --write-back, --write-back-only and --write-back-branch flags to cmd, or
+// to its sub commands if it has any.
+func WithWriteBack(cmd *cobra.Command, out io.Writer) *cobra.Command {
+	if cmd.HasSubCommands() || cmd.Run == nil {
+		for _, child := range cmd.Commands() {
+			WithWriteBack(child, out)
+		}
+		return cmd
+	}
+
+	cmd.Flags().String(writeBackFlag, "", "The path of a Git repository to write the changes back to: the manifests of the changed resources are updated and committed.")
+	cmd.Flags().Bool(writeBackOnlyFlag, false, "If true, only write the changes back to the repository of --write-back, without changing the cluster.")
+	cmd.Flags().String(writeBackBranchFlag, "", "The branch to create and commit the changes of --write-back to, instead of the current branch.")
+
+	run := cmd.Run
+	cmd.Run = func(c *cobra.Command, args []string) {
+		repo := cmdutil.GetFlagString(c, writeBackFlag)
+		only := cmdutil.GetFlagBool(c, writeBackOnlyFlag)
+		if len(repo) == 0 {
+			if only || len(cmdutil.GetFlagString(c, writeBackBranchFlag)) > 0 {
+				cmdutil.CheckErr(fmt.Errorf("--%s and --%s require --%s", writeBackOnlyFlag, writeBackBranchFlag, writeBackFlag))
+			}
+			run(c, args)
+			return
+		}
+
+		recording = &struct {
+			only    bool
+			changes []Change
+		}{only: only}
+		run(c, args)
+		changes := recording.changes
+		recording = nil
+
+		message := strings.Join(append([]string{c.CommandPath()}, args...), " ")
+		cmdutil.CheckErr(WriteBack(repo, cmdutil.GetFlagString(c, writeBackBranchFlag), message, changes, out))
+	}
+	return cmd
+}
+
+// WriteBack applies changes to the manifests of repo and commits them with message, on a new
+// branch if branch is set.
+func WriteBack(repo, branch, message string, changes []Change, out io.Writer) error {
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "nothing to write back")
+		return nil
+	}
+	files, err := ApplyChanges(repo, changes, out)
+	if err != nil {
+		return err
+	}
+	if len(branch) > 0 {
+		if err := git(repo, "checkout", "-b", branch); err != nil {
+			return err
+		}
+	}
+	if err := git(repo, append([]string{"add", "--"}, files...)...); err != nil {
+		return err
+	}
+	if err := git(repo, "commit", "-m", message); err != nil {
+		return err
+	}
+	if len(branch) > 0 {
+		fmt.Fprintf(out, "changes committed to branch %s of %s\n", branch, repo)
+	} else {
+		fmt.Fprintf(out, "changes committed to %s\n", repo)
+	}
+	return nil
+}
+
+func git(repo string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// manifest is a YAML document of a file of the repository.
+type manifest struct {
+	file  string
+	index int
+	node  *yaml.Node
+}
+
+// ApplyChanges applies changes to the manifests of repo, and returns the changed files.
+func ApplyChanges(repo string, changes []Change, out io.Writer) ([]string, error) {
+	documents, err := readManifests(repo)
+	if err != nil {
+		return nil, err
+	}
+	changed := map[string]bool{}
+	for _, change := range changes {
+		target, err := findManifest(repo, documents, change)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyChange(target.node, change); err != nil {
+			return nil, fmt.Errorf("%s: %v", target.file, err)
+		}
+		changed[target.file] = true
+		fmt.Fprintf(out, "%s/%s written back to %s\n", strings.ToLower(change.GroupKind.Kind), change.Name, target.file)
+	}
+
+	var files []string
+	for file := range changed {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		if err := writeManifests(filepath.Join(repo, file), documents[file]); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// readManifests returns the YAML documents of the files of repo by path relative to repo. Files
+// that are not valid YAML, such as Helm templates, are skipped.
+func readManifests(repo string) (map[string][]*yaml.Node, error) {
+	documents := map[string][]*yaml.Node{}
+	err := filepath.Walk(repo, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != repo && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var nodes []*yaml.Node
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			node := &yaml.Node{}
+			if err := decoder.Decode(node); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil
+			}
+			nodes = append(nodes, node)
+		}
+		rel, err := filepath.Rel(repo, path)
+		if err != nil {
+			return err
+		}
+		documents[rel] = nodes
+		return nil
+	})
+	return documents, err
+}
+
+// findManifest returns the only manifest of documents of the object of change. Manifests without
+// namespace match any namespace, as kustomize or the namespace of kubectl apply sets it.
+func findManifest(repo string, documents map[string][]*yaml.Node, change Change) (*manifest, error) {
+	var matches []*manifest
+	for file, nodes := range documents {
+		for i, node := range nodes {
+			var object struct {
+				APIVersion string `yaml:"apiVersion"`
+				Kind       string `yaml:"kind"`
+				Metadata   struct {
+					Name      string `yaml:"name"`
+					Namespace string `yaml:"namespace"`
+				} `yaml:"metadata"`
+			}
+			if err := node.Decode(&object); err != nil {
+				continue
+			}
+			gv, err := schema.ParseGroupVersion(object.APIVersion)
+			if err != nil || gv.Group != change.GroupKind.Group || object.Kind != change.GroupKind.Kind || object.Metadata.Name != change.Name {
+				continue
+			}
+			if len(object.Metadata.Namespace) > 0 && object.Metadata.Namespace != change.Namespace {
+				continue
+			}
+			matches = append(matches, &manifest{file: file, index: i, node: node})
+		}
+	}
+	name := fmt.Sprintf("%s/%s", strings.ToLower(change.GroupKind.Kind), change.Name)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no manifest of %s found in %s", name, repo)
+	case 1:
+		return matches[0], nil
+	}
+	var files []string
+	for _, match := range matches {
+		files = append(files, fmt.Sprintf("%s (document %d)", match.file, match.index+1))
+	}
+	sort.Strings(files)
+	return nil, fmt.Errorf("%d manifests of %s found in %s, expected one: %s", len(matches), name, repo, strings.Join(files, ", "))
+}
+
+// applyChange applies change to the document node: with a strategic merge patch for typed objects,
+// so that lists such as containers are merged by name, or else with a JSON merge patch.
+func applyChange(node *yaml.Node, change Change) error {
+	var current interface{}
+	if err := node.Decode(&current); err != nil {
+		return err
+	}
+	original, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	var patched []byte
+	if _, unstructured := change.DataStruct.(runtime.Unstructured); change.DataStruct != nil && !unstructured {
+		patched, err = strategicpatch.StrategicMergePatch(original, change.Patch, change.DataStruct)
+	} else {
+		var patch []byte
+		if patch, err = jsonpatch.CreateMergePatch(change.Before, change.After); err == nil {
+			patched, err = jsonpatch.MergePatch(original, patch)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	if err := json.Unmarshal(patched, &value); err != nil {
+		return err
+	}
+	return syncNode(node, value)
+}
+
+// syncNode changes node to hold value, keeping the comments and the order of the fields that are
+// not changed.
+func syncNode(node *yaml.Node, value interface{}) error {
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		return syncNode(node.Content[0], value)
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if node.Kind != yaml.MappingNode {
+			return replaceNode(node, value)
+		}
+		seen := map[string]bool{}
+		var content []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			fieldValue, ok := v[key]
+			if !ok {
+				continue
+			}
+			seen[key] = true
+			if err := syncNode(node.Content[i+1], fieldValue); err != nil {
+				return err
+			}
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+		var added []string
+		for key := range v {
+			if !seen[key] {
+				added = append(added, key)
+			}
+		}
+		sort.Strings(added)
+		for _, key := range added {
+			child := &yaml.Node{}
+			if err := replaceNode(child, v[key]); err != nil {
+				return err
+			}
+			content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+		node.Content = content
+		return nil
+	case []interface{}:
+		if node.Kind != yaml.SequenceNode {
+			return replaceNode(node, value)
+		}
+		for i, item := range v {
+			if i < len(node.Content) {
+				if err := syncNode(node.Content[i], item); err != nil {
+					return err
+				}
+				continue
+			}
+			child := &yaml.Node{}
+			if err := replaceNode(child, item); err != nil {
+				return err
+			}
+			node.Content = append(node.Content, child)
+		}
+		if len(node.Content) > len(v) {
+			node.Content = node.Content[:len(v)]
+		}
+		return nil
+	default:
+		var current interface{}
+		if err := node.Decode(&current); err == nil && equalScalars(current, value) {
+			return nil
+		}
+		return replaceNode(node, value)
+	}
+}
+
+// equalScalars returns true if the YAML scalar a equals the JSON scalar b, whose numbers are floats.
+func equalScalars(a, b interface{}) bool {
+	switch n := a.(type) {
+	case int:
+		a = float64(n)
+	case int64:
+		a = float64(n)
+	case uint64:
+		a = float64(n)
+	}
+	return a == b
+}
+
+// replaceNode replaces node with a new node for value, keeping its comments.
+func replaceNode(node *yaml.Node, value interface{}) error {
+	replacement := &yaml.Node{}
+	if err := replacement.Encode(value); err != nil {
+		return err
+	}
+	replacement.HeadComment, replacement.LineComment, replacement.FootComment = node.HeadComment, node.LineComment, node.FootComment
+	*node = *replacement
+	return nil
+}
+
+// writeManifests writes the YAML documents of nodes to path.
+func writeManifests(path string, nodes []*yaml.Node) error {
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	for _, node := range nodes {
+		if err := encoder.Encode(node); err != nil {
+			return err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), info.Mode())
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writeback
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const cloneSetManifest = `# the demo app
+apiVersion: apps.kruise.io/v1alpha1
+kind: CloneSet
+metadata:
+  name: demo
+spec:
+  replicas: 2 # scaled by the HPA
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.24
+      - name: sidecar
+        image: busybox
+`
+
+func writeRepo(t *testing.T, files map[string]string) string {
+	repo, err := ioutil.TempDir("", "writeback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(repo) })
+	for name, content := range files {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func imageChange(namespace string) Change {
+	return Change{
+		GroupKind:  schema.GroupKind{Group: "apps.kruise.io", Kind: "CloneSet"},
+		Namespace:  namespace,
+		Name:       "demo",
+		Patch:      []byte(`{"spec":{"template":{"spec":{"containers":[{"name":"nginx","image":"nginx:1.25"}]}}}}`),
+		DataStruct: &kruiseappsv1alpha1.CloneSet{},
+	}
+}
+
+func TestApplyChanges(t *testing.T) {
+	repo := writeRepo(t, map[string]string{
+		"apps/demo.yaml":             cloneSetManifest,
+		"charts/templates/demo.yaml": "{{ .Values.name }}: [\n",
+		".git/objects/demo.yaml":     cloneSetManifest,
+		"apps/other.yaml":            "apiVersion: v1\nkind: Service\nmetadata:\n  name: demo\n",
+		"apps/kustomization.yml":     "resources:\n- demo.yaml\n",
+	})
+
+	out := &bytes.Buffer{}
+	files, err := ApplyChanges(repo, []Change{imageChange("default")}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != filepath.Join("apps", "demo.yaml") {
+		t.Errorf("expected apps/demo.yaml to be changed, got %v", files)
+	}
+	if !strings.Contains(out.String(), "cloneset/demo written back to") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(repo, "apps", "demo.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := string(data)
+	for _, expected := range []string{"# the demo app", "replicas: 2 # scaled by the HPA", "image: nginx:1.25", "image: busybox"} {
+		if !strings.Contains(manifest, expected) {
+			t.Errorf("expected %q in the manifest:\n%s", expected, manifest)
+		}
+	}
+	if strings.Contains(manifest, "nginx:1.24") {
+		t.Errorf("expected the image to be changed:\n%s", manifest)
+	}
+	if strings.Index(manifest, "name: nginx") > strings.Index(manifest, "name: sidecar") {
+		t.Errorf("expected the order of the containers to be kept:\n%s", manifest)
+	}
+}
+
+func TestApplyChangesMergePatch(t *testing.T) {
+	repo := writeRepo(t, map[string]string{"demo.yaml": cloneSetManifest})
+	change := Change{
+		GroupKind: schema.GroupKind{Group: "apps.kruise.io", Kind: "CloneSet"},
+		Name:      "demo",
+		Before:    []byte(`{"spec":{"replicas":2}}`),
+		After:     []byte(`{"spec":{"replicas":5}}`),
+	}
+	if _, err := ApplyChanges(repo, []Change{change}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(repo, "demo.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "replicas: 5") || !strings.Contains(string(data), "image: nginx:1.24") {
+		t.Errorf("unexpected manifest:\n%s", data)
+	}
+}
+
+func TestApplyChangesErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		change   Change
+		expected string
+	}{
+		{
+			name:     "not found",
+			files:    map[string]string{"demo.yaml": strings.Replace(cloneSetManifest, "name: demo", "name: other", 1)},
+			change:   imageChange("default"),
+			expected: "no manifest of cloneset/demo found",
+		},
+		{
+			name:     "other namespace",
+			files:    map[string]string{"demo.yaml": strings.Replace(cloneSetManifest, "name: demo", "name: demo\n  namespace: prod", 1)},
+			change:   imageChange("default"),
+			expected: "no manifest of cloneset/demo found",
+		},
+		{
+			name:     "ambiguous",
+			files:    map[string]string{"a/demo.yaml": cloneSetManifest, "b/demo.yaml": cloneSetManifest},
+			change:   imageChange("default"),
+			expected: "2 manifests of cloneset/demo found",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := writeRepo(t, tc.files)
+			_, err := ApplyChanges(repo, []Change{tc.change}, &bytes.Buffer{})
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected error %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}